@@ -0,0 +1,75 @@
+package calendar
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/elorenzorodz/event-mrs/internal/ical"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (calendarAPIConfig *CalendarAPIConfig) CreateCalendarFeed(ginContext *gin.Context) {
+	userID := ginContext.MustGet("userId").(uuid.UUID)
+
+	calendarFeed, createFeedError := calendarAPIConfig.Service.CreateFeed(ginContext.Request.Context(), userID)
+
+	if createFeedError != nil {
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "error creating calendar feed, please try again in a few minutes"})
+
+		return
+	}
+
+	ginContext.JSON(http.StatusCreated, gin.H{
+		"feed":    calendarFeed,
+		"message": "subscribe to the URL in your calendar app, it is only shown once and creating a new one replaces it",
+	})
+}
+
+func (calendarAPIConfig *CalendarAPIConfig) DeleteCalendarFeed(ginContext *gin.Context) {
+	userID := ginContext.MustGet("userId").(uuid.UUID)
+
+	if deleteFeedError := calendarAPIConfig.Service.DeleteFeed(ginContext.Request.Context(), userID); deleteFeedError != nil {
+		if errors.Is(deleteFeedError, ErrCalendarFeedNotFound) {
+			ginContext.JSON(http.StatusNotFound, gin.H{"error": deleteFeedError.Error()})
+
+			return
+		}
+
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "error deleting calendar feed, please try again in a few minutes"})
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"message": "calendar feed deleted"})
+}
+
+// GetCalendarFeed serves GET /calendar/:token.ics, calendar apps can't send an access token so the secret token in the
+// URL is what authenticates the request.
+func (calendarAPIConfig *CalendarAPIConfig) GetCalendarFeed(ginContext *gin.Context) {
+	feedToken, isICS := strings.CutSuffix(ginContext.Param("token"), ".ics")
+
+	if !isICS || feedToken == "" {
+		ginContext.JSON(http.StatusNotFound, gin.H{"error": ErrCalendarFeedNotFound.Error()})
+
+		return
+	}
+
+	reservationsCalendar, getFeedError := calendarAPIConfig.Service.GetFeed(ginContext.Request.Context(), feedToken)
+
+	if getFeedError != nil {
+		if errors.Is(getFeedError, ErrCalendarFeedNotFound) {
+			ginContext.JSON(http.StatusNotFound, gin.H{"error": getFeedError.Error()})
+
+			return
+		}
+
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "error retrieving calendar feed, please try again in a few minutes"})
+
+		return
+	}
+
+	ginContext.Header("Cache-Control", "private, no-cache")
+	ginContext.Data(http.StatusOK, ical.ContentType, reservationsCalendar)
+}
@@ -0,0 +1,42 @@
+package calendar
+
+import (
+	"context"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/google/uuid"
+)
+
+type CalendarAPIConfig struct {
+	Service CalendarService
+}
+
+type CalendarService interface {
+	CreateFeed(ctx context.Context, userID uuid.UUID) (*CalendarFeed, error)
+	DeleteFeed(ctx context.Context, userID uuid.UUID) error
+	GetFeed(ctx context.Context, feedToken string) ([]byte, error)
+}
+
+// CalendarQueries is what the calendar feeds are stored and built with, *database.Queries in the app.
+type CalendarQueries interface {
+	UpsertCalendarFeed(ctx context.Context, arg database.UpsertCalendarFeedParams) (database.CalendarFeed, error)
+	GetCalendarFeedByTokenHash(ctx context.Context, tokenHash string) (database.CalendarFeed, error)
+	DeleteCalendarFeed(ctx context.Context, userID uuid.UUID) (int64, error)
+	GetCalendarFeedShows(ctx context.Context, userID uuid.UUID) ([]database.GetCalendarFeedShowsRow, error)
+}
+
+// Service builds the calendar feeds, the shows in them are organized by SenderName <SenderEmail> like in the emails.
+type Service struct {
+	DBQueries   CalendarQueries
+	AppBaseURL  string
+	SenderName  string
+	SenderEmail string
+}
+
+// CalendarFeed is only returned when the feed is created, the URL can't be shown again as just the hash of its token is
+// stored.
+type CalendarFeed struct {
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+}
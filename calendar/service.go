@@ -0,0 +1,126 @@
+package calendar
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/auth"
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/ical"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCalendarFeedNotFound = errors.New("calendar feed not found")
+	ErrDatabase             = errors.New("internal database error")
+)
+
+func NewService(dbQueries CalendarQueries, appBaseURL string, senderName string, senderEmail string) CalendarService {
+	return &Service{
+		DBQueries:   dbQueries,
+		AppBaseURL:  appBaseURL,
+		SenderName:  senderName,
+		SenderEmail: senderEmail,
+	}
+}
+
+// CreateFeed gives the user a new feed URL, the URL they had before stops working.
+func (service *Service) CreateFeed(ctx context.Context, userID uuid.UUID) (*CalendarFeed, error) {
+	feedToken, feedTokenHash, generateTokenError := auth.GenerateOpaqueToken()
+
+	if generateTokenError != nil {
+		log.Printf("Error generating calendar feed token of user %s: %v", userID, generateTokenError)
+
+		return nil, errors.New("error creating calendar feed")
+	}
+
+	calendarFeed, upsertCalendarFeedError := service.DBQueries.UpsertCalendarFeed(ctx, database.UpsertCalendarFeedParams{
+		TokenHash: feedTokenHash,
+		UserID:    userID,
+	})
+
+	if upsertCalendarFeedError != nil {
+		log.Printf("Error storing calendar feed of user %s: %v", userID, upsertCalendarFeedError)
+
+		return nil, ErrDatabase
+	}
+
+	return &CalendarFeed{
+		URL:       fmt.Sprintf("%s/calendar/%s.ics", service.AppBaseURL, feedToken),
+		CreatedAt: calendarFeed.CreatedAt,
+	}, nil
+}
+
+func (service *Service) DeleteFeed(ctx context.Context, userID uuid.UUID) error {
+	deletedRows, deleteCalendarFeedError := service.DBQueries.DeleteCalendarFeed(ctx, userID)
+
+	if deleteCalendarFeedError != nil {
+		log.Printf("Error deleting calendar feed of user %s: %v", userID, deleteCalendarFeedError)
+
+		return ErrDatabase
+	}
+
+	if deletedRows == 0 {
+		return ErrCalendarFeedNotFound
+	}
+
+	return nil
+}
+
+// GetFeed returns the upcoming shows the owner of feedToken has paid tickets for as an .ics file.
+func (service *Service) GetFeed(ctx context.Context, feedToken string) ([]byte, error) {
+	calendarFeed, getCalendarFeedError := service.DBQueries.GetCalendarFeedByTokenHash(ctx, auth.HashOpaqueToken(feedToken))
+
+	if errors.Is(getCalendarFeedError, sql.ErrNoRows) {
+		return nil, ErrCalendarFeedNotFound
+	}
+
+	if getCalendarFeedError != nil {
+		log.Printf("Error getting calendar feed: %v", getCalendarFeedError)
+
+		return nil, ErrDatabase
+	}
+
+	calendarFeedShows, getCalendarFeedShowsError := service.DBQueries.GetCalendarFeedShows(ctx, calendarFeed.UserID)
+
+	if getCalendarFeedShowsError != nil {
+		log.Printf("Error getting calendar feed shows of user %s: %v", calendarFeed.UserID, getCalendarFeedShowsError)
+
+		return nil, ErrDatabase
+	}
+
+	reservationsCalendar := ical.Calendar{
+		Name: fmt.Sprintf("%s reservations", service.SenderName),
+	}
+
+	for _, calendarFeedShow := range calendarFeedShows {
+		organizerName := service.SenderName
+
+		if calendarFeedShow.Organizer.Valid && calendarFeedShow.Organizer.String != "" {
+			organizerName = calendarFeedShow.Organizer.String
+		}
+
+		reservationsCalendar.Events = append(reservationsCalendar.Events, ical.Event{
+			UID:            ical.UID(calendarFeedShow.EventDetailID.String(), service.SenderEmail),
+			Sequence:       calendarFeedShow.CalendarSequence,
+			Start:          calendarFeedShow.ShowDate,
+			Summary:        fmt.Sprintf("%s - %s", calendarFeedShow.Title, calendarFeedShow.TicketDescription),
+			Description:    fmt.Sprintf("%s\n\nTicket/s: %d", calendarFeedShow.Description, calendarFeedShow.Tickets),
+			Status:         ical.StatusConfirmed,
+			OrganizerName:  organizerName,
+			OrganizerEmail: service.SenderEmail,
+		})
+	}
+
+	return ical.Encode(reservationsCalendar, time.Now()), nil
+}
+
+// ShowChanged reports whether an update of a show changes its calendar event, its start or summary. The sequence of
+// the show's event has to be bumped for calendars to take the change.
+func ShowChanged(previous database.EventDetail, updated database.EventDetail) bool {
+	return !previous.ShowDate.Equal(updated.ShowDate) || previous.TicketDescription != updated.TicketDescription
+}
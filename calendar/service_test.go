@@ -0,0 +1,198 @@
+package calendar_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/calendar"
+	"github.com/elorenzorodz/event-mrs/internal/auth"
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/google/uuid"
+)
+
+const testAppBaseURL = "http://localhost:8080/api/v1"
+
+type MockCalendarQueries struct {
+	calendarFeeds     map[string]database.CalendarFeed
+	calendarFeedShows []database.GetCalendarFeedShowsRow
+}
+
+func NewMockCalendarQueries() *MockCalendarQueries {
+	return &MockCalendarQueries{calendarFeeds: map[string]database.CalendarFeed{}}
+}
+
+func (mockQueries *MockCalendarQueries) UpsertCalendarFeed(ctx context.Context, arg database.UpsertCalendarFeedParams) (database.CalendarFeed, error) {
+	for tokenHash, calendarFeed := range mockQueries.calendarFeeds {
+		if calendarFeed.UserID == arg.UserID {
+			delete(mockQueries.calendarFeeds, tokenHash)
+		}
+	}
+
+	calendarFeed := database.CalendarFeed{TokenHash: arg.TokenHash, CreatedAt: time.Now(), UserID: arg.UserID}
+	mockQueries.calendarFeeds[arg.TokenHash] = calendarFeed
+
+	return calendarFeed, nil
+}
+
+func (mockQueries *MockCalendarQueries) GetCalendarFeedByTokenHash(ctx context.Context, tokenHash string) (database.CalendarFeed, error) {
+	calendarFeed, found := mockQueries.calendarFeeds[tokenHash]
+
+	if !found {
+		return database.CalendarFeed{}, sql.ErrNoRows
+	}
+
+	return calendarFeed, nil
+}
+
+func (mockQueries *MockCalendarQueries) DeleteCalendarFeed(ctx context.Context, userID uuid.UUID) (int64, error) {
+	deletedRows := int64(0)
+
+	for tokenHash, calendarFeed := range mockQueries.calendarFeeds {
+		if calendarFeed.UserID == userID {
+			delete(mockQueries.calendarFeeds, tokenHash)
+			deletedRows++
+		}
+	}
+
+	return deletedRows, nil
+}
+
+func (mockQueries *MockCalendarQueries) GetCalendarFeedShows(ctx context.Context, userID uuid.UUID) ([]database.GetCalendarFeedShowsRow, error) {
+	return mockQueries.calendarFeedShows, nil
+}
+
+// feedToken returns the token of a feed URL, the part GET /calendar/:token.ics is called with.
+func feedToken(t *testing.T, feedURL string) string {
+	token, found := strings.CutPrefix(feedURL, testAppBaseURL+"/calendar/")
+
+	if !found || !strings.HasSuffix(token, ".ics") {
+		t.Fatalf("CreateFeed: expected a URL like %s/calendar/<token>.ics, got %q", testAppBaseURL, feedURL)
+	}
+
+	return strings.TrimSuffix(token, ".ics")
+}
+
+func TestCreateFeedReplacesPreviousFeed(t *testing.T) {
+	mockQueries := NewMockCalendarQueries()
+	service := calendar.NewService(mockQueries, testAppBaseURL, "Event MRS", "noreply@event-mrs.test")
+	userID := uuid.New()
+
+	firstFeed, err := service.CreateFeed(context.Background(), userID)
+
+	if err != nil {
+		t.Fatalf("CreateFeed: expected no error, got: %v", err)
+	}
+
+	secondFeed, err := service.CreateFeed(context.Background(), userID)
+
+	if err != nil {
+		t.Fatalf("CreateFeed: expected no error, got: %v", err)
+	}
+
+	if _, stored := mockQueries.calendarFeeds[auth.HashOpaqueToken(feedToken(t, secondFeed.URL))]; !stored || len(mockQueries.calendarFeeds) != 1 {
+		t.Fatalf("CreateFeed: expected only the hash of the new token stored, got %+v", mockQueries.calendarFeeds)
+	}
+
+	if _, err := service.GetFeed(context.Background(), feedToken(t, firstFeed.URL)); !errors.Is(err, calendar.ErrCalendarFeedNotFound) {
+		t.Errorf("GetFeed: expected the previous URL to stop working, got: %v", err)
+	}
+}
+
+func TestGetFeed(t *testing.T) {
+	mockQueries := NewMockCalendarQueries()
+	service := calendar.NewService(mockQueries, testAppBaseURL, "Event MRS", "noreply@event-mrs.test")
+	eventDetailID := uuid.MustParse("0f8e4a36-2b57-4d8c-9a53-3f1f0f3b8f10")
+
+	mockQueries.calendarFeedShows = []database.GetCalendarFeedShowsRow{
+		{
+			EventDetailID:     eventDetailID,
+			ShowDate:          time.Date(2025, 6, 14, 19, 30, 0, 0, time.UTC),
+			TicketDescription: "VIP",
+			Title:             "Jazz Night",
+			Description:       "An evening of live jazz",
+			CalendarSequence:  4,
+			Tickets:           2,
+		},
+	}
+
+	calendarFeed, err := service.CreateFeed(context.Background(), uuid.New())
+
+	if err != nil {
+		t.Fatalf("CreateFeed: expected no error, got: %v", err)
+	}
+
+	reservationsCalendar, err := service.GetFeed(context.Background(), feedToken(t, calendarFeed.URL))
+
+	if err != nil {
+		t.Fatalf("GetFeed: expected no error, got: %v", err)
+	}
+
+	for _, expectedLine := range []string{
+		"X-WR-CALNAME:Event MRS reservations\r\n",
+		"UID:0f8e4a36-2b57-4d8c-9a53-3f1f0f3b8f10@event-mrs.test\r\n",
+		"SEQUENCE:4\r\n",
+		"DTSTART:20250614T193000Z\r\n",
+		"SUMMARY:Jazz Night - VIP\r\n",
+		`DESCRIPTION:An evening of live jazz\n\nTicket/s: 2` + "\r\n",
+		// Shows of events without an organizer name are organized by the sender.
+		`ORGANIZER;CN="Event MRS":mailto:noreply@event-mrs.test` + "\r\n",
+	} {
+		if !strings.Contains(string(reservationsCalendar), expectedLine) {
+			t.Errorf("GetFeed: expected line %q, got:\n%s", expectedLine, reservationsCalendar)
+		}
+	}
+
+	if _, err := service.GetFeed(context.Background(), "unknown"); !errors.Is(err, calendar.ErrCalendarFeedNotFound) {
+		t.Errorf("GetFeed: expected ErrCalendarFeedNotFound for an unknown token, got: %v", err)
+	}
+}
+
+func TestDeleteFeed(t *testing.T) {
+	mockQueries := NewMockCalendarQueries()
+	service := calendar.NewService(mockQueries, testAppBaseURL, "Event MRS", "noreply@event-mrs.test")
+	userID := uuid.New()
+
+	if _, err := service.CreateFeed(context.Background(), userID); err != nil {
+		t.Fatalf("CreateFeed: expected no error, got: %v", err)
+	}
+
+	if err := service.DeleteFeed(context.Background(), userID); err != nil {
+		t.Fatalf("DeleteFeed: expected no error, got: %v", err)
+	}
+
+	if err := service.DeleteFeed(context.Background(), userID); !errors.Is(err, calendar.ErrCalendarFeedNotFound) {
+		t.Errorf("DeleteFeed: expected ErrCalendarFeedNotFound without a feed, got: %v", err)
+	}
+}
+
+func TestShowChanged(t *testing.T) {
+	showDate := time.Date(2025, 6, 14, 19, 30, 0, 0, time.UTC)
+	previous := database.EventDetail{ShowDate: showDate, TicketDescription: "VIP", Price: 5000, NumberOfTickets: 100}
+
+	testCases := []struct {
+		name     string
+		update   func(updated *database.EventDetail)
+		expected bool
+	}{
+		{name: "ShowDateMoved", update: func(updated *database.EventDetail) { updated.ShowDate = showDate.Add(24 * time.Hour) }, expected: true},
+		{name: "DescriptionChanged", update: func(updated *database.EventDetail) { updated.TicketDescription = "VIP Lounge" }, expected: true},
+		{name: "SameShowDateInOtherZone", update: func(updated *database.EventDetail) { updated.ShowDate = showDate.In(time.FixedZone("UTC+2", 2*60*60)) }, expected: false},
+		// Prices and capacity aren't in the calendar event.
+		{name: "PriceAndCapacityChanged", update: func(updated *database.EventDetail) { updated.Price, updated.NumberOfTickets = 7500, 150 }, expected: false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			updated := previous
+			testCase.update(&updated)
+
+			if changed := calendar.ShowChanged(previous, updated); changed != testCase.expected {
+				t.Errorf("ShowChanged: expected %t, got %t", testCase.expected, changed)
+			}
+		})
+	}
+}
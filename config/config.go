@@ -3,6 +3,12 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/elorenzorodz/event-mrs/internal/paymentgateway"
 )
 
 type AppConfig struct {
@@ -10,15 +16,37 @@ type AppConfig struct {
 	Port                      string
 	DBURL                     string
 	GinMode                   string
+	MailTransport             string
 	MailgunAPIKey             string
 	MailgunSendingDomain      string
+	SMTPHost                  string
+	SMTPPort                  string
+	SMTPUsername              string
+	SMTPPassword              string
+	MailDirectory             string
 	SenderName                string
 	SenderEmail               string
+	PaymentGateway            string
 	StripeSecretKey           string
 	StripeSigningSecret       string
 	StripeRefundSigningSecret string
 	TeamName                  string
 	TeamEmail                 string
+	EmailOutboxInterval       time.Duration
+	EmailOutboxMaxAttempts    int32
+	EmailOutboxBatchSize      int32
+	PaymentSweepInterval      time.Duration
+	PaymentSweepBatchSize     int32
+	WaitlistOfferTTL          time.Duration
+	WaitlistSweepInterval     time.Duration
+	WaitlistSweepBatchSize    int32
+	ReconciliationTime        time.Duration
+	ReconciliationLookback    time.Duration
+	ReconcileBatchSize        int32
+	ShowReminderInterval      time.Duration
+	ReminderBatchSize         int32
+	AdminEmails               []string
+	AppBaseURL                string
 }
 
 func getEnvironmentVariable(key string) (string, error) {
@@ -31,6 +59,16 @@ func getEnvironmentVariable(key string) (string, error) {
 	return value, nil
 }
 
+func getEnvironmentVariableOrDefault(key string, defaultValue string) string {
+	value := os.Getenv(key)
+
+	if value == "" {
+		return defaultValue
+	}
+
+	return value
+}
+
 func LoadEnvironmentVariables() (AppConfig, error) {
 	appConfig := AppConfig{}
 
@@ -48,27 +86,58 @@ func LoadEnvironmentVariables() (AppConfig, error) {
 	if appConfig.GinMode, err = getEnvironmentVariable("GIN_MODE"); err != nil {
 		return appConfig, err
 	}
-	if appConfig.MailgunAPIKey, err = getEnvironmentVariable("MAILGUN_API_KEY"); err != nil {
-		return appConfig, err
-	}
-	if appConfig.MailgunSendingDomain, err = getEnvironmentVariable("MAILGUN_SENDING_DOMAIN"); err != nil {
-		return appConfig, err
+
+	// MAIL_TRANSPORT=smtp works with a local MailHog, file writes .eml files and log only logs the emails.
+	appConfig.MailTransport = strings.ToLower(getEnvironmentVariableOrDefault("MAIL_TRANSPORT", mailer.TransportMailgun))
+
+	switch appConfig.MailTransport {
+	case mailer.TransportMailgun:
+		if appConfig.MailgunAPIKey, err = getEnvironmentVariable("MAILGUN_API_KEY"); err != nil {
+			return appConfig, err
+		}
+		if appConfig.MailgunSendingDomain, err = getEnvironmentVariable("MAILGUN_SENDING_DOMAIN"); err != nil {
+			return appConfig, err
+		}
+	case mailer.TransportSMTP:
+		appConfig.SMTPHost = getEnvironmentVariableOrDefault("SMTP_HOST", "localhost")
+		appConfig.SMTPPort = getEnvironmentVariableOrDefault("SMTP_PORT", "1025")
+		appConfig.SMTPUsername = os.Getenv("SMTP_USERNAME")
+		appConfig.SMTPPassword = os.Getenv("SMTP_PASSWORD")
+	case mailer.TransportFile:
+		appConfig.MailDirectory = getEnvironmentVariableOrDefault("MAIL_DIRECTORY", "mail")
+	case mailer.TransportLog:
+	default:
+		return appConfig, fmt.Errorf("environment variable MAIL_TRANSPORT must be %s, %s, %s or %s, got '%s'", mailer.TransportMailgun, mailer.TransportSMTP, mailer.TransportFile, mailer.TransportLog, appConfig.MailTransport)
 	}
+
 	if appConfig.SenderName, err = getEnvironmentVariable("SENDER_NAME"); err != nil {
 		return appConfig, err
 	}
 	if appConfig.SenderEmail, err = getEnvironmentVariable("SENDER_EMAIL"); err != nil {
 		return appConfig, err
 	}
-	if appConfig.StripeSecretKey, err = getEnvironmentVariable("STRIPE_SECRET_KEY"); err != nil {
-		return appConfig, err
-	}
-	if appConfig.StripeSigningSecret, err = getEnvironmentVariable("STRIPE_SIGNING_SECRET"); err != nil {
-		return appConfig, err
-	}
-	if appConfig.StripeRefundSigningSecret, err = getEnvironmentVariable("STRIPE_REFUND_SIGNING_SECRET"); err != nil {
-		return appConfig, err
+
+	// PAYMENT_GATEWAY=fake charges through the in-process fake provider, it doesn't need Stripe keys and never touches the network.
+	appConfig.PaymentGateway = strings.ToLower(getEnvironmentVariableOrDefault("PAYMENT_GATEWAY", paymentgateway.ProviderStripe))
+
+	switch appConfig.PaymentGateway {
+	case paymentgateway.ProviderStripe:
+		if appConfig.StripeSecretKey, err = getEnvironmentVariable("STRIPE_SECRET_KEY"); err != nil {
+			return appConfig, err
+		}
+		if appConfig.StripeSigningSecret, err = getEnvironmentVariable("STRIPE_SIGNING_SECRET"); err != nil {
+			return appConfig, err
+		}
+		if appConfig.StripeRefundSigningSecret, err = getEnvironmentVariable("STRIPE_REFUND_SIGNING_SECRET"); err != nil {
+			return appConfig, err
+		}
+	case paymentgateway.ProviderFake:
+		appConfig.StripeSigningSecret = getEnvironmentVariableOrDefault("STRIPE_SIGNING_SECRET", "whsec_fake_payment")
+		appConfig.StripeRefundSigningSecret = getEnvironmentVariableOrDefault("STRIPE_REFUND_SIGNING_SECRET", "whsec_fake_refund")
+	default:
+		return appConfig, fmt.Errorf("environment variable PAYMENT_GATEWAY must be %s or %s, got '%s'", paymentgateway.ProviderStripe, paymentgateway.ProviderFake, appConfig.PaymentGateway)
 	}
+
 	if appConfig.TeamName, err = getEnvironmentVariable("TEAM_NAME"); err != nil {
 		return appConfig, err
 	}
@@ -76,5 +145,120 @@ func LoadEnvironmentVariables() (AppConfig, error) {
 		return appConfig, err
 	}
 
+	// Emails are written to the outbox and sent by a worker every EMAIL_OUTBOX_INTERVAL, EMAIL_OUTBOX_BATCH_SIZE at a time.
+	// Failed emails are retried with backoff until EMAIL_OUTBOX_MAX_ATTEMPTS and then wait for an admin to resend them.
+	emailOutboxInterval := getEnvironmentVariableOrDefault("EMAIL_OUTBOX_INTERVAL", "10s")
+
+	if appConfig.EmailOutboxInterval, err = time.ParseDuration(emailOutboxInterval); err != nil || appConfig.EmailOutboxInterval <= 0 {
+		return appConfig, fmt.Errorf("environment variable EMAIL_OUTBOX_INTERVAL must be a positive duration, got '%s'", emailOutboxInterval)
+	}
+
+	emailOutboxMaxAttempts := getEnvironmentVariableOrDefault("EMAIL_OUTBOX_MAX_ATTEMPTS", "8")
+	maxAttempts, parseMaxAttemptsError := strconv.ParseInt(emailOutboxMaxAttempts, 10, 32)
+
+	if parseMaxAttemptsError != nil || maxAttempts <= 0 {
+		return appConfig, fmt.Errorf("environment variable EMAIL_OUTBOX_MAX_ATTEMPTS must be a positive integer, got '%s'", emailOutboxMaxAttempts)
+	}
+
+	appConfig.EmailOutboxMaxAttempts = int32(maxAttempts)
+
+	emailOutboxBatchSize := getEnvironmentVariableOrDefault("EMAIL_OUTBOX_BATCH_SIZE", "50")
+	outboxBatchSize, parseOutboxBatchSizeError := strconv.ParseInt(emailOutboxBatchSize, 10, 32)
+
+	if parseOutboxBatchSizeError != nil || outboxBatchSize <= 0 {
+		return appConfig, fmt.Errorf("environment variable EMAIL_OUTBOX_BATCH_SIZE must be a positive integer, got '%s'", emailOutboxBatchSize)
+	}
+
+	appConfig.EmailOutboxBatchSize = int32(outboxBatchSize)
+
+	paymentSweepInterval := getEnvironmentVariableOrDefault("PAYMENT_SWEEP_INTERVAL", "1m")
+
+	if appConfig.PaymentSweepInterval, err = time.ParseDuration(paymentSweepInterval); err != nil || appConfig.PaymentSweepInterval <= 0 {
+		return appConfig, fmt.Errorf("environment variable PAYMENT_SWEEP_INTERVAL must be a positive duration, got '%s'", paymentSweepInterval)
+	}
+
+	paymentSweepBatchSize := getEnvironmentVariableOrDefault("PAYMENT_SWEEP_BATCH_SIZE", "50")
+	batchSize, parseBatchSizeError := strconv.ParseInt(paymentSweepBatchSize, 10, 32)
+
+	if parseBatchSizeError != nil || batchSize <= 0 {
+		return appConfig, fmt.Errorf("environment variable PAYMENT_SWEEP_BATCH_SIZE must be a positive integer, got '%s'", paymentSweepBatchSize)
+	}
+
+	appConfig.PaymentSweepBatchSize = int32(batchSize)
+
+	// Tickets offered to someone on the waitlist are held for this long before moving to the next person.
+	waitlistOfferTTL := getEnvironmentVariableOrDefault("WAITLIST_OFFER_TTL", "30m")
+
+	if appConfig.WaitlistOfferTTL, err = time.ParseDuration(waitlistOfferTTL); err != nil || appConfig.WaitlistOfferTTL <= 0 {
+		return appConfig, fmt.Errorf("environment variable WAITLIST_OFFER_TTL must be a positive duration, got '%s'", waitlistOfferTTL)
+	}
+
+	// Offers that ran out are moved on to the next person in line every WAITLIST_SWEEP_INTERVAL.
+	waitlistSweepInterval := getEnvironmentVariableOrDefault("WAITLIST_SWEEP_INTERVAL", "1m")
+
+	if appConfig.WaitlistSweepInterval, err = time.ParseDuration(waitlistSweepInterval); err != nil || appConfig.WaitlistSweepInterval <= 0 {
+		return appConfig, fmt.Errorf("environment variable WAITLIST_SWEEP_INTERVAL must be a positive duration, got '%s'", waitlistSweepInterval)
+	}
+
+	waitlistSweepBatchSize := getEnvironmentVariableOrDefault("WAITLIST_SWEEP_BATCH_SIZE", "50")
+	waitlistBatchSize, parseWaitlistBatchSizeError := strconv.ParseInt(waitlistSweepBatchSize, 10, 32)
+
+	if parseWaitlistBatchSizeError != nil || waitlistBatchSize <= 0 {
+		return appConfig, fmt.Errorf("environment variable WAITLIST_SWEEP_BATCH_SIZE must be a positive integer, got '%s'", waitlistSweepBatchSize)
+	}
+
+	appConfig.WaitlistSweepBatchSize = int32(waitlistBatchSize)
+
+	// The payment reconciliation runs once a day at this time (UTC) and checks payments that changed within the lookback.
+	reconciliationTime := getEnvironmentVariableOrDefault("RECONCILIATION_TIME", "03:00")
+	reconciliationTimeOfDay, parseReconciliationTimeError := time.Parse("15:04", reconciliationTime)
+
+	if parseReconciliationTimeError != nil {
+		return appConfig, fmt.Errorf("environment variable RECONCILIATION_TIME must be a time of day like 03:00, got '%s'", reconciliationTime)
+	}
+
+	appConfig.ReconciliationTime = time.Duration(reconciliationTimeOfDay.Hour())*time.Hour + time.Duration(reconciliationTimeOfDay.Minute())*time.Minute
+
+	reconciliationLookback := getEnvironmentVariableOrDefault("RECONCILIATION_LOOKBACK", "48h")
+
+	if appConfig.ReconciliationLookback, err = time.ParseDuration(reconciliationLookback); err != nil || appConfig.ReconciliationLookback <= 0 {
+		return appConfig, fmt.Errorf("environment variable RECONCILIATION_LOOKBACK must be a positive duration, got '%s'", reconciliationLookback)
+	}
+
+	reconcileBatchSize := getEnvironmentVariableOrDefault("RECONCILE_BATCH_SIZE", "100")
+	reconcileBatch, parseReconcileBatchSizeError := strconv.ParseInt(reconcileBatchSize, 10, 32)
+
+	if parseReconcileBatchSizeError != nil || reconcileBatch <= 0 {
+		return appConfig, fmt.Errorf("environment variable RECONCILE_BATCH_SIZE must be a positive integer, got '%s'", reconcileBatchSize)
+	}
+
+	appConfig.ReconcileBatchSize = int32(reconcileBatch)
+
+	// Due pre-show reminders are looked for this often, the offsets themselves are set per event by its organizer.
+	showReminderInterval := getEnvironmentVariableOrDefault("SHOW_REMINDER_INTERVAL", "1m")
+
+	if appConfig.ShowReminderInterval, err = time.ParseDuration(showReminderInterval); err != nil || appConfig.ShowReminderInterval <= 0 {
+		return appConfig, fmt.Errorf("environment variable SHOW_REMINDER_INTERVAL must be a positive duration, got '%s'", showReminderInterval)
+	}
+
+	reminderBatchSize := getEnvironmentVariableOrDefault("REMINDER_BATCH_SIZE", "100")
+	reminderBatch, parseReminderBatchSizeError := strconv.ParseInt(reminderBatchSize, 10, 32)
+
+	if parseReminderBatchSizeError != nil || reminderBatch <= 0 {
+		return appConfig, fmt.Errorf("environment variable REMINDER_BATCH_SIZE must be a positive integer, got '%s'", reminderBatchSize)
+	}
+
+	appConfig.ReminderBatchSize = int32(reminderBatch)
+
+	// Links in emails point here, e.g. https://api.example.com/api/v1.
+	appConfig.AppBaseURL = strings.TrimRight(getEnvironmentVariableOrDefault("APP_BASE_URL", fmt.Sprintf("http://localhost:%s/api/%s", appConfig.Port, appConfig.APIVersion)), "/")
+
+	// ADMIN_EMAILS is optional, registered users with these emails are promoted to admin on startup.
+	for _, adminEmail := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if trimmedEmail := strings.ToLower(strings.TrimSpace(adminEmail)); trimmedEmail != "" {
+			appConfig.AdminEmails = append(appConfig.AdminEmails, trimmedEmail)
+		}
+	}
+
 	return appConfig, nil
 }
\ No newline at end of file
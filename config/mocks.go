@@ -22,6 +22,30 @@ func (userMock *UserMock) GetUserById(ctx context.Context, id uuid.UUID) (databa
 	return database.User{}, sql.ErrNoRows
 }
 
+func (userMock *UserMock) GetUserPreferences(ctx context.Context, userID uuid.UUID) (database.UserPreference, error) {
+	return database.UserPreference{}, sql.ErrNoRows
+}
+
+func (userMock *UserMock) GetUsers(ctx context.Context, arg database.GetUsersParams) ([]database.User, error) {
+	return []database.User{}, nil
+}
+
+func (userMock *UserMock) MarkUserEmailVerified(ctx context.Context, id uuid.UUID) (database.User, error) {
+	panic("MarkUserEmailVerified not implemented for this test (BaseMock)")
+}
+
+func (userMock *UserMock) UpdateUserPassword(ctx context.Context, arg database.UpdateUserPasswordParams) error {
+	panic("UpdateUserPassword not implemented for this test (BaseMock)")
+}
+
+func (userMock *UserMock) UpdateUserRole(ctx context.Context, arg database.UpdateUserRoleParams) (database.User, error) {
+	panic("UpdateUserRole not implemented for this test (BaseMock)")
+}
+
+func (userMock *UserMock) UpsertUserPreferences(ctx context.Context, arg database.UpsertUserPreferencesParams) (database.UserPreference, error) {
+	panic("UpsertUserPreferences not implemented for this test (BaseMock)")
+}
+
 type EventMock struct{}
 
 func (eventMock *EventMock) CreateEvent(ctx context.Context, arg database.CreateEventParams) (database.Event, error) {
@@ -88,6 +112,10 @@ func (paymentMock *PaymentMock) CreatePaymentLog(ctx context.Context, arg databa
 	panic("CreatePaymentLog not implemented for this test (BaseMock)")
 }
 
+func (paymentMock *PaymentMock) GetExpiredPendingPayments(ctx context.Context, arg database.GetExpiredPendingPaymentsParams) ([]database.Payment, error) {
+	return []database.Payment{}, nil
+}
+
 func (paymentMock *PaymentMock) GetMultiplePayments(ctx context.Context, id []uuid.UUID) ([]database.Payment, error) {
 	return []database.Payment{}, nil
 }
@@ -146,24 +174,74 @@ func (reservationMock *ReservationMock) RefundPaymentAndRestoreTickets(ctx conte
 	panic("RefundPaymentAndRestoreTickets not implemented for this test (BaseMock)")
 }
 
-func (reservationMock *ReservationMock) ReserveTicket(ctx context.Context, arg database.ReserveTicketParams) (database.Reservation, error) {
-	panic("ReserveTicket not implemented for this test (BaseMock)")
+func (reservationMock *ReservationMock) ReleasePaymentReservations(ctx context.Context, arg database.ReleasePaymentReservationsParams) error {
+	panic("ReleasePaymentReservations not implemented for this test (BaseMock)")
 }
 
-func (reservationMock *ReservationMock) RestoreTicketsAndDeletePayment(ctx context.Context, arg database.RestoreTicketsAndDeletePaymentParams) error {
-	panic("RestoreTicketsAndDeletePayment not implemented for this test (BaseMock)")
+func (reservationMock *ReservationMock) ReserveTicket(ctx context.Context, arg database.ReserveTicketParams) (database.Reservation, error) {
+	panic("ReserveTicket not implemented for this test (BaseMock)")
 }
 
 func (reservationMock *ReservationMock) UpdateUserReservationEmail(ctx context.Context, arg database.UpdateUserReservationEmailParams) (database.Reservation, error) {
 	panic("UpdateUserReservationEmail not implemented for this test (BaseMock)")
 }
 
+type RefreshTokenMock struct{}
+
+func (refreshTokenMock *RefreshTokenMock) CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error) {
+	panic("CreateRefreshToken not implemented for this test (BaseMock)")
+}
+
+func (refreshTokenMock *RefreshTokenMock) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (database.RefreshToken, error) {
+	return database.RefreshToken{}, sql.ErrNoRows
+}
+
+func (refreshTokenMock *RefreshTokenMock) RevokeAccessToken(ctx context.Context, arg database.RevokeAccessTokenParams) error {
+	return nil
+}
+
+func (refreshTokenMock *RefreshTokenMock) RevokeFamilyAccessTokens(ctx context.Context, familyID uuid.UUID) error {
+	return nil
+}
+
+func (refreshTokenMock *RefreshTokenMock) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	return nil
+}
+
+func (refreshTokenMock *RefreshTokenMock) RevokeUserAccessTokens(ctx context.Context, userID uuid.UUID) error {
+	return nil
+}
+
+func (refreshTokenMock *RefreshTokenMock) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	return nil
+}
+
+func (refreshTokenMock *RefreshTokenMock) UseRefreshToken(ctx context.Context, id uuid.UUID) (database.RefreshToken, error) {
+	panic("UseRefreshToken not implemented for this test (BaseMock)")
+}
+
+type UserActionTokenMock struct{}
+
+func (userActionTokenMock *UserActionTokenMock) ConsumeUserActionToken(ctx context.Context, arg database.ConsumeUserActionTokenParams) (database.UserActionToken, error) {
+	return database.UserActionToken{}, sql.ErrNoRows
+}
+
+func (userActionTokenMock *UserActionTokenMock) CreateUserActionToken(ctx context.Context, arg database.CreateUserActionTokenParams) (database.UserActionToken, error) {
+	panic("CreateUserActionToken not implemented for this test (BaseMock)")
+}
+
+func (userActionTokenMock *UserActionTokenMock) InvalidateUserActionTokens(ctx context.Context, arg database.InvalidateUserActionTokensParams) error {
+	return nil
+}
+
 type BaseMock struct {
 	*UserMock
 	*EventMock
 	*EventDetailMock
 	*ReservationMock
 	*PaymentMock
+	*RefreshTokenMock
+	*UserActionTokenMock
 }
 
 func NewBaseMock() *BaseMock {
@@ -173,5 +251,7 @@ func NewBaseMock() *BaseMock {
 		EventDetailMock: &EventDetailMock{},
 		ReservationMock: &ReservationMock{},
 		PaymentMock: &PaymentMock{},
+		RefreshTokenMock: &RefreshTokenMock{},
+		UserActionTokenMock: &UserActionTokenMock{},
 	}
 }
\ No newline at end of file
@@ -9,11 +9,14 @@ import (
 )
 
 type DBQueries interface {
+	ConsumeUserActionToken(ctx context.Context, arg database.ConsumeUserActionTokenParams) (database.UserActionToken, error)
 	CreateEvent(ctx context.Context, arg database.CreateEventParams) (database.Event, error)
 	CreateEventDetail(ctx context.Context, arg database.CreateEventDetailParams) (database.EventDetail, error)
 	CreatePayment(ctx context.Context, arg database.CreatePaymentParams) (database.Payment, error)
 	CreatePaymentLog(ctx context.Context, arg database.CreatePaymentLogParams) (database.PaymentLog, error)
+	CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error)
 	CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error)
+	CreateUserActionToken(ctx context.Context, arg database.CreateUserActionTokenParams) (database.UserActionToken, error)
 	DeleteEvent(ctx context.Context, arg database.DeleteEventParams) error
 	DeleteEventDetail(ctx context.Context, arg database.DeleteEventDetailParams) error
 	GetEventConfirmedUserReservations(ctx context.Context, id uuid.UUID) ([]database.GetEventConfirmedUserReservationsRow, error)
@@ -21,6 +24,7 @@ type DBQueries interface {
 	GetEventDetailsById(ctx context.Context, id uuid.UUID) (database.EventDetail, error)
 	GetEventDetailsWithTitleByIds(ctx context.Context, id []uuid.UUID) ([]database.GetEventDetailsWithTitleByIdsRow, error)
 	GetEvents(ctx context.Context, arg database.GetEventsParams) ([]database.GetEventsRow, error)
+	GetExpiredPendingPayments(ctx context.Context, arg database.GetExpiredPendingPaymentsParams) ([]database.Payment, error)
 	GetMultiplePayments(ctx context.Context, id []uuid.UUID) ([]database.Payment, error)
 	GetPaidEventDetailForRefund(ctx context.Context, arg database.GetPaidEventDetailForRefundParams) ([]database.GetPaidEventDetailForRefundRow, error)
 	GetPaidEventForRefund(ctx context.Context, arg database.GetPaidEventForRefundParams) ([]database.GetPaidEventForRefundRow, error)
@@ -28,20 +32,34 @@ type DBQueries interface {
 	GetPaymentById(ctx context.Context, arg database.GetPaymentByIdParams) (database.Payment, error)
 	GetPaymentByIdOnly(ctx context.Context, id uuid.UUID) (database.Payment, error)
 	GetPaymentByPaymentIntentId(ctx context.Context, paymentIntentID sql.NullString) (database.Payment, error)
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (database.RefreshToken, error)
 	GetUserByEmail(ctx context.Context, email string) (database.User, error)
 	GetUserById(ctx context.Context, id uuid.UUID) (database.User, error)
 	GetUserEventById(ctx context.Context, arg database.GetUserEventByIdParams) (database.Event, error)
 	GetUserEvents(ctx context.Context, userID uuid.UUID) ([]database.Event, error)
 	GetUserPayments(ctx context.Context, userID uuid.UUID) ([]database.Payment, error)
+	GetUserPreferences(ctx context.Context, userID uuid.UUID) (database.UserPreference, error)
 	GetUserReservationById(ctx context.Context, arg database.GetUserReservationByIdParams) (database.Reservation, error)
 	GetUserReservations(ctx context.Context, userID uuid.UUID) ([]database.Reservation, error)
 	GetUserReservationsByPaymentId(ctx context.Context, arg database.GetUserReservationsByPaymentIdParams) ([]database.Reservation, error)
+	GetUsers(ctx context.Context, arg database.GetUsersParams) ([]database.User, error)
+	InvalidateUserActionTokens(ctx context.Context, arg database.InvalidateUserActionTokensParams) error
+	MarkUserEmailVerified(ctx context.Context, id uuid.UUID) (database.User, error)
 	RefundPaymentAndRestoreTickets(ctx context.Context, arg database.RefundPaymentAndRestoreTicketsParams) error
+	ReleasePaymentReservations(ctx context.Context, arg database.ReleasePaymentReservationsParams) error
 	ReserveTicket(ctx context.Context, arg database.ReserveTicketParams) (database.Reservation, error)
-	RestoreTicketsAndDeletePayment(ctx context.Context, arg database.RestoreTicketsAndDeletePaymentParams) error
+	RevokeAccessToken(ctx context.Context, arg database.RevokeAccessTokenParams) error
+	RevokeFamilyAccessTokens(ctx context.Context, familyID uuid.UUID) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error
+	RevokeUserAccessTokens(ctx context.Context, userID uuid.UUID) error
+	RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error
 	UpdateEvent(ctx context.Context, arg database.UpdateEventParams) (database.Event, error)
 	UpdateEventDetail(ctx context.Context, arg database.UpdateEventDetailParams) (database.EventDetail, error)
 	UpdatePayment(ctx context.Context, arg database.UpdatePaymentParams) (database.Payment, error)
 	UpdateTicketsRemaining(ctx context.Context, arg database.UpdateTicketsRemainingParams) (database.EventDetail, error)
+	UpdateUserPassword(ctx context.Context, arg database.UpdateUserPasswordParams) error
 	UpdateUserReservationEmail(ctx context.Context, arg database.UpdateUserReservationEmailParams) (database.Reservation, error)
+	UpdateUserRole(ctx context.Context, arg database.UpdateUserRoleParams) (database.User, error)
+	UpsertUserPreferences(ctx context.Context, arg database.UpsertUserPreferencesParams) (database.UserPreference, error)
+	UseRefreshToken(ctx context.Context, id uuid.UUID) (database.RefreshToken, error)
 }
\ No newline at end of file
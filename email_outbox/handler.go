@@ -0,0 +1,91 @@
+package email_outbox
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (emailOutboxAPIConfig *EmailOutboxAPIConfig) GetOutboxEmails(ginContext *gin.Context) {
+	emails, listError := emailOutboxAPIConfig.Service.List(
+		ginContext.Request.Context(),
+		ginContext.Query("status"),
+		ginContext.Query("limit"),
+		ginContext.Query("offset"),
+	)
+
+	if listError != nil {
+		if strings.Contains(listError.Error(), "invalid") {
+			ginContext.JSON(http.StatusBadRequest, gin.H{"error": listError.Error()})
+
+			return
+		}
+
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "error retrieving emails, please try again in a few minutes"})
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"emails": emails})
+}
+
+func (emailOutboxAPIConfig *EmailOutboxAPIConfig) GetOutboxEmailById(ginContext *gin.Context) {
+	emailID, parseEmailIdError := uuid.Parse(ginContext.Param("emailId"))
+
+	if parseEmailIdError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid email ID"})
+
+		return
+	}
+
+	email, getEmailError := emailOutboxAPIConfig.Service.GetByID(ginContext.Request.Context(), emailID)
+
+	if getEmailError != nil {
+		if errors.Is(getEmailError, ErrEmailNotFound) {
+			ginContext.JSON(http.StatusNotFound, gin.H{"error": getEmailError.Error()})
+
+			return
+		}
+
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "error retrieving email, please try again in a few minutes"})
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, email)
+}
+
+func (emailOutboxAPIConfig *EmailOutboxAPIConfig) ResendOutboxEmail(ginContext *gin.Context) {
+	emailID, parseEmailIdError := uuid.Parse(ginContext.Param("emailId"))
+
+	if parseEmailIdError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid email ID"})
+
+		return
+	}
+
+	email, resendError := emailOutboxAPIConfig.Service.Resend(ginContext.Request.Context(), emailID)
+
+	if resendError != nil {
+		if errors.Is(resendError, ErrEmailNotFound) {
+			ginContext.JSON(http.StatusNotFound, gin.H{"error": resendError.Error()})
+
+			return
+		}
+
+		if errors.Is(resendError, ErrEmailNotDead) {
+			ginContext.JSON(http.StatusConflict, gin.H{"error": resendError.Error()})
+
+			return
+		}
+
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "error resending email, please try again in a few minutes"})
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, email)
+}
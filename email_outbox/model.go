@@ -0,0 +1,60 @@
+package email_outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/clock"
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/google/uuid"
+)
+
+type EmailOutboxAPIConfig struct {
+	Service EmailOutboxService
+}
+
+type EmailOutboxService interface {
+	List(ctx context.Context, status string, limitQuery string, offsetQuery string) ([]OutboxEmail, error)
+	GetByID(ctx context.Context, emailID uuid.UUID) (*OutboxEmail, error)
+	Resend(ctx context.Context, emailID uuid.UUID) (*OutboxEmail, error)
+}
+
+type Service struct {
+	DBQueries database.Queries
+}
+
+// OutboxQueries is what the outbox worker claims and updates emails with, *database.Queries in the app.
+type OutboxQueries interface {
+	ClaimDueEmails(ctx context.Context, arg database.ClaimDueEmailsParams) ([]database.EmailOutbox, error)
+	MarkEmailSent(ctx context.Context, id uuid.UUID) error
+	MarkEmailFailed(ctx context.Context, arg database.MarkEmailFailedParams) error
+}
+
+// OutboxWorker sends the emails of the outbox through Transport, failed emails are retried until MaxAttempts.
+type OutboxWorker struct {
+	DB          OutboxQueries
+	Transport   mailer.Transport
+	Clock       clock.Clock
+	Interval    time.Duration
+	BatchSize   int32
+	MaxAttempts int32
+}
+
+type OutboxEmail struct {
+	ID            uuid.UUID  `json:"id"`
+	Sender        string     `json:"sender"`
+	Recipient     string     `json:"recipient"`
+	Subject       string     `json:"subject"`
+	Status        string     `json:"status"`
+	Attempts      int32      `json:"attempts"`
+	LastError     string     `json:"last_error"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	SentAt        *time.Time `json:"sent_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     *time.Time `json:"updated_at"`
+	// The bodies and attachment filenames are only returned for a single email.
+	Text        string   `json:"text,omitempty"`
+	HTML        string   `json:"html,omitempty"`
+	Attachments []string `json:"attachments,omitempty"`
+}
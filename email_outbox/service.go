@@ -0,0 +1,159 @@
+package email_outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/elorenzorodz/event-mrs/internal/pagination"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEmailNotFound = errors.New("email not found")
+	ErrEmailNotDead  = errors.New("only dead emails can be resent")
+	ErrDatabase      = errors.New("internal database error")
+)
+
+func NewService(dbQueries database.Queries) EmailOutboxService {
+	return &Service{
+		DBQueries: dbQueries,
+	}
+}
+
+// List returns the newest emails of the outbox, status filters them, e.g. dead for the ones that need an admin.
+func (service *Service) List(ctx context.Context, status string, limitQuery string, offsetQuery string) ([]OutboxEmail, error) {
+	status = strings.ToLower(strings.TrimSpace(status))
+
+	if status != "" && !slices.Contains(emailStatuses, status) {
+		return nil, fmt.Errorf("invalid status, must be one of: %s", strings.Join(emailStatuses, ", "))
+	}
+
+	limit, parseLimitError := pagination.ParseLimit(limitQuery, 50, 100)
+
+	if parseLimitError != nil {
+		return nil, errors.New("invalid limit, must be between 1 and 100")
+	}
+
+	offset := int64(0)
+
+	if strings.TrimSpace(offsetQuery) != "" {
+		parsedOffset, parseOffsetError := strconv.ParseInt(offsetQuery, 10, 32)
+
+		if parseOffsetError != nil || parsedOffset < 0 {
+			return nil, errors.New("invalid offset, must be zero or greater")
+		}
+
+		offset = parsedOffset
+	}
+
+	outboxEmails, getOutboxEmailsError := service.DBQueries.GetOutboxEmails(ctx, database.GetOutboxEmailsParams{
+		Status:    status,
+		RowLimit:  limit,
+		RowOffset: int32(offset),
+	})
+
+	if getOutboxEmailsError != nil {
+		log.Printf("Error getting outbox emails: %v", getOutboxEmailsError)
+
+		return nil, ErrDatabase
+	}
+
+	emails := make([]OutboxEmail, len(outboxEmails))
+
+	for i, outboxEmail := range outboxEmails {
+		emails[i] = DatabaseOutboxEmailToOutboxEmailJSON(outboxEmail, false)
+	}
+
+	return emails, nil
+}
+
+func (service *Service) GetByID(ctx context.Context, emailID uuid.UUID) (*OutboxEmail, error) {
+	outboxEmail, getOutboxEmailError := service.DBQueries.GetOutboxEmailById(ctx, emailID)
+
+	if errors.Is(getOutboxEmailError, sql.ErrNoRows) {
+		return nil, ErrEmailNotFound
+	}
+
+	if getOutboxEmailError != nil {
+		log.Printf("Error getting outbox email %s: %v", emailID, getOutboxEmailError)
+
+		return nil, ErrDatabase
+	}
+
+	email := DatabaseOutboxEmailToOutboxEmailJSON(outboxEmail, true)
+
+	return &email, nil
+}
+
+// Resend moves a dead email back to pending, the worker sends it on its next run with a fresh set of attempts.
+func (service *Service) Resend(ctx context.Context, emailID uuid.UUID) (*OutboxEmail, error) {
+	outboxEmail, resendOutboxEmailError := service.DBQueries.ResendOutboxEmail(ctx, emailID)
+
+	if errors.Is(resendOutboxEmailError, sql.ErrNoRows) {
+		if _, getOutboxEmailError := service.GetByID(ctx, emailID); getOutboxEmailError != nil {
+			return nil, getOutboxEmailError
+		}
+
+		return nil, ErrEmailNotDead
+	}
+
+	if resendOutboxEmailError != nil {
+		log.Printf("Error resending outbox email %s: %v", emailID, resendOutboxEmailError)
+
+		return nil, ErrDatabase
+	}
+
+	log.Printf("Email outbox: email %s to %s was queued again after %d attempt/s", outboxEmail.ID, outboxEmail.Recipient, outboxEmail.Attempts)
+
+	email := DatabaseOutboxEmailToOutboxEmailJSON(outboxEmail, true)
+
+	return &email, nil
+}
+
+func DatabaseOutboxEmailToOutboxEmailJSON(outboxEmail database.EmailOutbox, includeContent bool) OutboxEmail {
+	email := OutboxEmail{
+		ID:            outboxEmail.ID,
+		Sender:        outboxEmail.Sender,
+		Recipient:     outboxEmail.Recipient,
+		Subject:       outboxEmail.Subject,
+		Status:        outboxEmail.Status,
+		Attempts:      outboxEmail.Attempts,
+		LastError:     outboxEmail.LastError.String,
+		NextAttemptAt: outboxEmail.NextAttemptAt,
+		CreatedAt:     outboxEmail.CreatedAt,
+		SentAt:        nullTimeToPointer(outboxEmail.SentAt),
+		UpdatedAt:     nullTimeToPointer(outboxEmail.UpdatedAt),
+	}
+
+	if !includeContent {
+		return email
+	}
+
+	email.Text = outboxEmail.TextBody
+	email.HTML = outboxEmail.HtmlBody
+
+	if message, outboxMessageError := mailer.OutboxMessage(outboxEmail); outboxMessageError == nil {
+		for _, attachment := range message.Attachments {
+			email.Attachments = append(email.Attachments, attachment.Filename)
+		}
+	}
+
+	return email
+}
+
+func nullTimeToPointer(nullTime sql.NullTime) *time.Time {
+	if !nullTime.Valid {
+		return nil
+	}
+
+	return &nullTime.Time
+}
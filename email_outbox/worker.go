@@ -0,0 +1,149 @@
+package email_outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/clock"
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/elorenzorodz/event-mrs/internal/sqlutil"
+)
+
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusDead    = "dead"
+)
+
+var emailStatuses = []string{StatusPending, StatusSent, StatusDead}
+
+const (
+	// The delay before the first retry, it doubles with every failed attempt up to maxRetryDelay.
+	firstRetryDelay = time.Minute
+	maxRetryDelay   = 6 * time.Hour
+	// sendTimeout is how long the transport gets to hand over one email.
+	sendTimeout = 30 * time.Second
+	// sendLease keeps a claimed email from other workers, it has to be longer than sending a batch takes.
+	sendLease = 10 * time.Minute
+)
+
+func NewOutboxWorker(dbQueries OutboxQueries, transport mailer.Transport, workerClock clock.Clock, interval time.Duration, batchSize int32, maxAttempts int32) *OutboxWorker {
+	return &OutboxWorker{
+		DB:          dbQueries,
+		Transport:   transport,
+		Clock:       workerClock,
+		Interval:    interval,
+		BatchSize:   batchSize,
+		MaxAttempts: maxAttempts,
+	}
+}
+
+// Start sends the due emails every Interval until the context is cancelled.
+func (worker *OutboxWorker) Start(ctx context.Context) {
+	log.Printf("Email outbox worker started, interval: %s, batch size: %d, max attempts: %d", worker.Interval, worker.BatchSize, worker.MaxAttempts)
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("Email outbox worker stopped: %v", ctx.Err())
+
+			return
+		case <-worker.Clock.After(worker.Interval):
+			sentCount, deliverError := worker.Deliver(ctx)
+
+			if deliverError != nil {
+				log.Printf("Email outbox worker error: %v", deliverError)
+			}
+
+			if sentCount > 0 {
+				log.Printf("Email outbox worker sent %d email/s", sentCount)
+			}
+		}
+	}
+}
+
+// Deliver sends one batch of due emails and returns how many were sent.
+func (worker *OutboxWorker) Deliver(ctx context.Context) (int, error) {
+	now := worker.Clock.Now()
+
+	dueEmails, claimDueEmailsError := worker.DB.ClaimDueEmails(ctx, database.ClaimDueEmailsParams{
+		LeaseUntil: now.Add(sendLease),
+		DueBefore:  now,
+		BatchSize:  worker.BatchSize,
+	})
+
+	if claimDueEmailsError != nil {
+		return 0, fmt.Errorf("failed to claim due emails: %w", claimDueEmailsError)
+	}
+
+	sentCount := 0
+
+	for _, dueEmail := range dueEmails {
+		if sendError := worker.send(ctx, dueEmail); sendError != nil {
+			worker.markFailed(ctx, dueEmail, sendError)
+
+			continue
+		}
+
+		// The email is sent again once its lease ends if it can't be marked, a duplicate beats a lost email.
+		if markEmailSentError := worker.DB.MarkEmailSent(ctx, dueEmail.ID); markEmailSentError != nil {
+			log.Printf("Email outbox worker: failed to mark email %s sent: %v", dueEmail.ID, markEmailSentError)
+		}
+
+		sentCount++
+	}
+
+	return sentCount, nil
+}
+
+func (worker *OutboxWorker) send(ctx context.Context, dueEmail database.EmailOutbox) error {
+	message, outboxMessageError := mailer.OutboxMessage(dueEmail)
+
+	if outboxMessageError != nil {
+		return outboxMessageError
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	return worker.Transport.Send(sendCtx, message)
+}
+
+// markFailed schedules the next attempt of the email, or moves it to the dead letters after the last attempt.
+func (worker *OutboxWorker) markFailed(ctx context.Context, dueEmail database.EmailOutbox, sendError error) {
+	attempts := dueEmail.Attempts + 1
+	status := StatusPending
+
+	if attempts >= worker.MaxAttempts {
+		status = StatusDead
+
+		log.Printf("Email outbox worker: email %s to %s is dead after %d attempt/s: %v", dueEmail.ID, dueEmail.Recipient, attempts, sendError)
+	} else {
+		log.Printf("Email outbox worker: attempt %d of email %s to %s failed: %v", attempts, dueEmail.ID, dueEmail.Recipient, sendError)
+	}
+
+	markEmailFailedError := worker.DB.MarkEmailFailed(ctx, database.MarkEmailFailedParams{
+		Status:        status,
+		LastError:     sqlutil.StringToNullString(sendError.Error()),
+		NextAttemptAt: worker.Clock.Now().Add(RetryDelay(attempts)),
+		ID:            dueEmail.ID,
+	})
+
+	if markEmailFailedError != nil {
+		log.Printf("Email outbox worker: failed to mark email %s failed: %v", dueEmail.ID, markEmailFailedError)
+	}
+}
+
+// RetryDelay is how long an email waits after its attempts failed, 1 minute after the first and doubling up to 6 hours.
+func RetryDelay(attempts int32) time.Duration {
+	retryDelay := firstRetryDelay
+
+	for attempt := int32(1); attempt < attempts && retryDelay < maxRetryDelay; attempt++ {
+		retryDelay *= 2
+	}
+
+	return min(retryDelay, maxRetryDelay)
+}
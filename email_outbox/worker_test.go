@@ -0,0 +1,177 @@
+package email_outbox_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/email_outbox"
+	"github.com/elorenzorodz/event-mrs/internal/clock/clocktest"
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/google/uuid"
+)
+
+type MockOutboxQueries struct {
+	dueEmails    []database.EmailOutbox
+	claimParams  database.ClaimDueEmailsParams
+	sentIDs      []uuid.UUID
+	failedParams []database.MarkEmailFailedParams
+}
+
+func (mockQueries *MockOutboxQueries) ClaimDueEmails(ctx context.Context, arg database.ClaimDueEmailsParams) ([]database.EmailOutbox, error) {
+	mockQueries.claimParams = arg
+
+	return mockQueries.dueEmails, nil
+}
+
+func (mockQueries *MockOutboxQueries) MarkEmailSent(ctx context.Context, id uuid.UUID) error {
+	mockQueries.sentIDs = append(mockQueries.sentIDs, id)
+
+	return nil
+}
+
+func (mockQueries *MockOutboxQueries) MarkEmailFailed(ctx context.Context, arg database.MarkEmailFailedParams) error {
+	mockQueries.failedParams = append(mockQueries.failedParams, arg)
+
+	return nil
+}
+
+func newDueEmail(t *testing.T, attempts int32) database.EmailOutbox {
+	attachments, err := json.Marshal([]mailer.Attachment{{Filename: "ticket.png", ContentType: "image/png", Content: []byte("png")}})
+
+	if err != nil {
+		t.Fatalf("Marshal: expected no error, got: %v", err)
+	}
+
+	return database.EmailOutbox{
+		ID:          uuid.New(),
+		Sender:      "Event MRS <noreply@event-mrs.test>",
+		Recipient:   "Jane Doe <jane@example.com>",
+		Subject:     "Your payment and ticket reservation is confirmed",
+		TextBody:    "Hi Jane Doe,",
+		HtmlBody:    "<p>Hi Jane Doe,</p>",
+		Attachments: attachments,
+		Status:      email_outbox.StatusPending,
+		Attempts:    attempts,
+	}
+}
+
+func TestOutboxWorkerDeliver(tTesting *testing.T) {
+	now := time.Date(2025, 6, 14, 19, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                  string
+		attempts              int32
+		transportError        error
+		expectedSent          int
+		expectedStatus        string
+		expectedNextAttemptAt time.Time
+	}{
+		{
+			name:         "Sent",
+			attempts:     0,
+			expectedSent: 1,
+		},
+		{
+			name:                  "FirstFailureIsRetried",
+			attempts:              0,
+			transportError:        errors.New("connection refused"),
+			expectedStatus:        email_outbox.StatusPending,
+			expectedNextAttemptAt: now.Add(time.Minute),
+		},
+		{
+			name:                  "RetriesBackOff",
+			attempts:              3,
+			transportError:        errors.New("connection refused"),
+			expectedStatus:        email_outbox.StatusPending,
+			expectedNextAttemptAt: now.Add(8 * time.Minute),
+		},
+		{
+			name:                  "LastAttemptIsDead",
+			attempts:              4,
+			transportError:        errors.New("connection refused"),
+			expectedStatus:        email_outbox.StatusDead,
+			expectedNextAttemptAt: now.Add(16 * time.Minute),
+		},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.name, func(t *testing.T) {
+			dueEmail := newDueEmail(t, tc.attempts)
+			mockQueries := &MockOutboxQueries{dueEmails: []database.EmailOutbox{dueEmail}}
+			recorder := mailer.NewRecorder()
+			recorder.Err = tc.transportError
+
+			worker := email_outbox.NewOutboxWorker(mockQueries, recorder, clocktest.NewFakeClock(now), time.Minute, 10, 5)
+
+			sentCount, err := worker.Deliver(context.Background())
+
+			if err != nil {
+				t.Fatalf("Deliver: expected no error, got: %v", err)
+			}
+
+			if sentCount != tc.expectedSent {
+				t.Errorf("Deliver: expected %d sent, got %d", tc.expectedSent, sentCount)
+			}
+
+			if !mockQueries.claimParams.DueBefore.Equal(now) || !mockQueries.claimParams.LeaseUntil.After(now) || mockQueries.claimParams.BatchSize != 10 {
+				t.Errorf("ClaimDueEmails: expected emails due at %s leased past it, got %+v", now, mockQueries.claimParams)
+			}
+
+			if tc.transportError == nil {
+				if len(mockQueries.sentIDs) != 1 || mockQueries.sentIDs[0] != dueEmail.ID || len(mockQueries.failedParams) != 0 {
+					t.Fatalf("MarkEmailSent: expected email %s sent, got sent %v failed %+v", dueEmail.ID, mockQueries.sentIDs, mockQueries.failedParams)
+				}
+
+				messages := recorder.Messages()
+
+				if len(messages) != 1 || messages[0].To != dueEmail.Recipient || messages[0].HTML != dueEmail.HtmlBody {
+					t.Fatalf("Send: expected the outbox email, got %+v", messages)
+				}
+
+				if len(messages[0].Attachments) != 1 || string(messages[0].Attachments[0].Content) != "png" {
+					t.Errorf("Send: expected ticket.png attached, got %+v", messages[0].Attachments)
+				}
+
+				return
+			}
+
+			if len(mockQueries.sentIDs) != 0 || len(mockQueries.failedParams) != 1 {
+				t.Fatalf("MarkEmailFailed: expected email %s failed, got sent %v failed %+v", dueEmail.ID, mockQueries.sentIDs, mockQueries.failedParams)
+			}
+
+			failedParams := mockQueries.failedParams[0]
+
+			if failedParams.ID != dueEmail.ID || failedParams.Status != tc.expectedStatus || !failedParams.NextAttemptAt.Equal(tc.expectedNextAttemptAt) {
+				t.Errorf("MarkEmailFailed: expected %s retried at %s, got %s at %s", tc.expectedStatus, tc.expectedNextAttemptAt, failedParams.Status, failedParams.NextAttemptAt)
+			}
+
+			if failedParams.LastError.String != tc.transportError.Error() {
+				t.Errorf("MarkEmailFailed: expected last error %q, got %q", tc.transportError, failedParams.LastError.String)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(tTesting *testing.T) {
+	tests := []struct {
+		attempts int32
+		expected time.Duration
+	}{
+		{attempts: 1, expected: time.Minute},
+		{attempts: 2, expected: 2 * time.Minute},
+		{attempts: 5, expected: 16 * time.Minute},
+		{attempts: 9, expected: 256 * time.Minute},
+		{attempts: 10, expected: 6 * time.Hour},
+		{attempts: 100, expected: 6 * time.Hour},
+	}
+
+	for _, tc := range tests {
+		if retryDelay := email_outbox.RetryDelay(tc.attempts); retryDelay != tc.expected {
+			tTesting.Errorf("RetryDelay(%d): expected %s, got %s", tc.attempts, tc.expected, retryDelay)
+		}
+	}
+}
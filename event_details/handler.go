@@ -2,9 +2,11 @@ package event_details
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 
+	"github.com/elorenzorodz/event-mrs/event_staff"
 	"github.com/elorenzorodz/event-mrs/internal/convert"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -27,7 +29,9 @@ func (eventDetailAPIConfig *EventDetailAPIConfig) CreateEventDetail(ginContext *
 		return
 	}
 
-	createdEventDetail, createEventDetailError := eventDetailAPIConfig.Service.Create(ginContext.Request.Context(), eventID, eventDetailParams)
+	userID := ginContext.MustGet("userId").(uuid.UUID)
+
+	createdEventDetail, createEventDetailError := eventDetailAPIConfig.Service.Create(ginContext.Request.Context(), eventID, userID, ginContext.GetString("role"), eventDetailParams)
 
 	if createEventDetailError != nil {
 		if _, _, parseError := convert.StringToTime(eventDetailParams.ShowDate); parseError != nil {
@@ -36,6 +40,24 @@ func (eventDetailAPIConfig *EventDetailAPIConfig) CreateEventDetail(ginContext *
 			return
 		}
 
+		if errors.Is(createEventDetailError, ErrInvalidSalesWindow) || errors.Is(createEventDetailError, ErrInvalidPriceSchedule) || errors.Is(createEventDetailError, ErrInvalidUnlocksAfter) {
+			ginContext.JSON(http.StatusBadRequest, gin.H{"error": createEventDetailError.Error()})
+
+			return
+		}
+
+		if errors.Is(createEventDetailError, sql.ErrNoRows) {
+			ginContext.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+
+			return
+		}
+
+		if errors.Is(createEventDetailError, event_staff.ErrEventAccessDenied) {
+			ginContext.JSON(http.StatusForbidden, gin.H{"error": createEventDetailError.Error()})
+
+			return
+		}
+
 		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": createEventDetailError.Error()})
 
 		return
@@ -69,7 +91,9 @@ func (eventDetailAPIConfig *EventDetailAPIConfig) UpdateEventDetail(ginContext *
 		return
 	}
 
-	updatedEventDetail, updateEventDetailError := eventDetailAPIConfig.Service.Update(ginContext.Request.Context(), eventID, eventDetailID, eventDetailParams)
+	userID := ginContext.MustGet("userId").(uuid.UUID)
+
+	updatedEventDetail, updateEventDetailError := eventDetailAPIConfig.Service.Update(ginContext.Request.Context(), eventID, eventDetailID, userID, ginContext.GetString("role"), eventDetailParams)
 
 	if updateEventDetailError != nil {
 		if _, _, parseError := convert.StringToTime(eventDetailParams.ShowDate); parseError != nil {
@@ -78,12 +102,30 @@ func (eventDetailAPIConfig *EventDetailAPIConfig) UpdateEventDetail(ginContext *
 			return
 		}
 
+		if errors.Is(updateEventDetailError, ErrInvalidSalesWindow) || errors.Is(updateEventDetailError, ErrInvalidPriceSchedule) || errors.Is(updateEventDetailError, ErrInvalidUnlocksAfter) {
+			ginContext.JSON(http.StatusBadRequest, gin.H{"error": updateEventDetailError.Error()})
+
+			return
+		}
+
 		if updateEventDetailError == sql.ErrNoRows {
 			ginContext.JSON(http.StatusNotFound, gin.H{"error": "event detail not found"})
 
 			return
 		}
 
+		if errors.Is(updateEventDetailError, ErrNumberOfTicketsBelowSold) {
+			ginContext.JSON(http.StatusConflict, gin.H{"error": updateEventDetailError.Error()})
+
+			return
+		}
+
+		if errors.Is(updateEventDetailError, event_staff.ErrEventAccessDenied) {
+			ginContext.JSON(http.StatusForbidden, gin.H{"error": updateEventDetailError.Error()})
+
+			return
+		}
+
 		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": updateEventDetailError.Error()})
 
 		return
@@ -108,7 +150,7 @@ func (eventDetailAPIConfig *EventDetailAPIConfig) DeleteEventDetail(ginContext *
 	userID, _ := ginContext.MustGet("userId").(uuid.UUID)
 	userEmail := ginContext.MustGet("email").(string)
 
-	eventDetailFailedRefundOrCancels, failedNotificationEmails, deleteError := eventDetailAPIConfig.Service.Delete(ginContext.Request.Context(), eventID, eventDetailID, userID, userEmail)
+	eventDetailFailedRefundOrCancels, failedNotificationEmails, deleteError := eventDetailAPIConfig.Service.Delete(ginContext.Request.Context(), eventID, eventDetailID, userID, ginContext.GetString("role"), userEmail)
 
 	if deleteError != nil {
 		if deleteError == sql.ErrNoRows {
@@ -117,6 +159,12 @@ func (eventDetailAPIConfig *EventDetailAPIConfig) DeleteEventDetail(ginContext *
 			return
 		}
 
+		if errors.Is(deleteError, event_staff.ErrEventAccessDenied) {
+			ginContext.JSON(http.StatusForbidden, gin.H{"error": deleteError.Error()})
+
+			return
+		}
+
 		// Check if delete was unsuccessful but async ops occurred (MultiStatus scenario for failure)
 		if len(eventDetailFailedRefundOrCancels) != 0 || len(failedNotificationEmails) != 0 {
 			ginContext.JSON(http.StatusMultiStatus,
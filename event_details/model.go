@@ -2,12 +2,15 @@ package event_details
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/elorenzorodz/event-mrs/internal/database"
 	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/elorenzorodz/event-mrs/internal/money"
+	"github.com/elorenzorodz/event-mrs/internal/paymentgateway"
+	"github.com/elorenzorodz/event-mrs/waitlist"
 	"github.com/google/uuid"
-	"github.com/stripe/stripe-go/v83"
 )
 
 type EventDetailAPIConfig struct {
@@ -15,41 +18,58 @@ type EventDetailAPIConfig struct {
 }
 
 type EventDetailService interface {
-	Create(ctx context.Context, eventID uuid.UUID, req EventDetailParameters) (*EventDetail, error)
-	Update(ctx context.Context, eventID, eventDetailID uuid.UUID, req EventDetailParameters) (*EventDetail, error)
-	Delete(ctx context.Context, eventID, eventDetailID, ownerID uuid.UUID, userEmail string) ([]EventDetailFailedRefundOrCancel, []FailedNotificationEmail, error)
+	Create(ctx context.Context, eventID, userID uuid.UUID, userRole string, req EventDetailParameters) (*EventDetail, error)
+	Update(ctx context.Context, eventID, eventDetailID, userID uuid.UUID, userRole string, req EventDetailParameters) (*EventDetail, error)
+	Delete(ctx context.Context, eventID, eventDetailID, userID uuid.UUID, userRole string, userEmail string) ([]EventDetailFailedRefundOrCancel, []FailedNotificationEmail, error)
 }
 
 type Service struct {
-	DBQueries database.Queries
-	Stripe StripeClient
-	Mailer    *mailer.Mailer
+	DBQueries      database.Queries
+	DBConnection   *sql.DB
+	PaymentGateway paymentgateway.Gateway
+	Mailer         mailer.Mailer
+	Waitlist       *waitlist.Service
 }
 
-type StripeClient interface {
-	Refund(amount int64, paymentIntentID string) (*stripe.Refund, error)
-	CancelPaymentIntent(paymentIntentID string, cancellationReason string) error
+type EventDetail struct {
+	ID                        uuid.UUID       `json:"id"`
+	ShowDate                  time.Time       `json:"show_date"`
+	Price                     money.Money     `json:"price"`
+	NumberOfTickets           int32           `json:"number_of_tickets"`
+	TicketsRemaining          int32           `json:"tickets_remaining"`
+	TicketDescription         string          `json:"ticket_description"`
+	SalesStartAt              *time.Time      `json:"sales_start_at"`
+	SalesEndAt                *time.Time      `json:"sales_end_at"`
+	PriceSchedule             []PriceSchedule `json:"price_schedule"`
+	UnlocksAfterEventDetailID *uuid.UUID      `json:"unlocks_after_event_detail_id"`
+	CreatedAt                 time.Time       `json:"created_at"`
+	UpdatedAt                 string          `json:"updated_at"`
+	EventID                   uuid.UUID       `json:"event_id"`
 }
 
-type StripeAPIClient struct{}
-
-type EventDetail struct {
-	ID                uuid.UUID `json:"id"`
-	ShowDate          time.Time `json:"show_date"`
-	Price             float32   `json:"price"`
-	NumberOfTickets   int32     `json:"number_of_tickets"`
-	TicketsRemaining  int32     `json:"tickets_remaining"`
-	TicketDescription string    `json:"ticket_description"`
-	CreatedAt         time.Time `json:"created_at"`
-	UpdatedAt         string    `json:"updated_at"`
-	EventID           uuid.UUID `json:"event_id"`
+// PriceSchedule is an early price of a ticket type, e.g. early-bird, the regular price applies once every entry ended.
+type PriceSchedule struct {
+	Price  money.Money `json:"price"`
+	EndsAt time.Time   `json:"ends_at"`
 }
 
 type EventDetailParameters struct {
-	ShowDate          string  `json:"show_date" binding:"required"`
-	TicketDescription string  `json:"description" binding:"required"`
-	Price             float32 `json:"price"`
-	NumberOfTickets   int32   `json:"number_of_tickets" binding:"required"`
+	ShowDate          string `json:"show_date" binding:"required"`
+	TicketDescription string `json:"description" binding:"required"`
+	// Price is in the minor unit of the currency, e.g. 1250 for 12.50 USD.
+	Price           int64 `json:"price"`
+	NumberOfTickets int32 `json:"number_of_tickets" binding:"required"`
+	// Sales open and close at these times when set, otherwise tickets are on sale until the show starts.
+	SalesStartAt  string                    `json:"sales_start_at"`
+	SalesEndAt    string                    `json:"sales_end_at"`
+	PriceSchedule []PriceScheduleParameters `json:"price_schedule" binding:"dive"`
+	// UnlocksAfterEventDetailID keeps this ticket type hidden until the given ticket type of the same event sells out.
+	UnlocksAfterEventDetailID *uuid.UUID `json:"unlocks_after_event_detail_id"`
+}
+
+type PriceScheduleParameters struct {
+	Price  int64  `json:"price"`
+	EndsAt string `json:"ends_at" binding:"required"`
 }
 
 type EventDetailFailedRefundOrCancel struct {
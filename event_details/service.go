@@ -8,60 +8,69 @@ import (
 	"log"
 	"sync"
 
+	"github.com/elorenzorodz/event-mrs/calendar"
+	"github.com/elorenzorodz/event-mrs/event_staff"
 	"github.com/elorenzorodz/event-mrs/internal/convert"
 	"github.com/elorenzorodz/event-mrs/internal/database"
 	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/elorenzorodz/event-mrs/internal/money"
+	"github.com/elorenzorodz/event-mrs/internal/paymentgateway"
+	"github.com/elorenzorodz/event-mrs/internal/paymentstatus"
 	"github.com/elorenzorodz/event-mrs/internal/sqlutil"
+	"github.com/elorenzorodz/event-mrs/waitlist"
 	"github.com/google/uuid"
-	"github.com/stripe/stripe-go/v83"
-	"github.com/stripe/stripe-go/v83/paymentintent"
-	"github.com/stripe/stripe-go/v83/refund"
 )
 
-func NewService(dbQueries database.Queries, mMailer *mailer.Mailer, stripeClient StripeClient) EventDetailService {
+var ErrNumberOfTicketsBelowSold = errors.New("number of tickets can't be lower than the tickets already sold or held")
+
+func NewService(dbQueries database.Queries, dbConn *sql.DB, mMailer mailer.Mailer, paymentGateway paymentgateway.Gateway, waitlistService *waitlist.Service) EventDetailService {
 	return &Service{
-		DBQueries: dbQueries,
-		Mailer:    mMailer,
-		Stripe:    stripeClient,
+		DBQueries:      dbQueries,
+		DBConnection:   dbConn,
+		Mailer:         mMailer,
+		PaymentGateway: paymentGateway,
+		Waitlist:       waitlistService,
 	}
 }
 
-func (stripeAPIClient *StripeAPIClient) Refund(amount int64, paymentIntentID string) (*stripe.Refund, error) {
-	refundParams := &stripe.RefundParams{
-		Amount:        stripe.Int64(amount),
-		PaymentIntent: stripe.String(paymentIntentID),
+func (service *Service) Create(ctx context.Context, eventID, userID uuid.UUID, userRole string, req EventDetailParameters) (*EventDetail, error) {
+	showDate, _, parseShowDateError := convert.StringToTime(req.ShowDate)
+
+	if parseShowDateError != nil {
+		return nil, parseShowDateError
 	}
 
-	return refund.New(refundParams)
-}
+	event, authorizeError := event_staff.AuthorizeEvent(ctx, &service.DBQueries, eventID, userID, userRole, event_staff.PermissionManageTickets)
 
-func (stripeAPIClient *StripeAPIClient) CancelPaymentIntent(paymentIntentID string, cancellationReason string) error {
-	paymentIntentCancelParams := &stripe.PaymentIntentCancelParams{
-		CancellationReason: stripe.String("abandoned"),
+	if authorizeError != nil {
+		return nil, authorizeError
 	}
 
-	_, err := paymentintent.Cancel(paymentIntentID, paymentIntentCancelParams)
+	ticketTier, parseTicketTierError := ParseTicketTier(req, showDate)
 
-	return err
-}
+	if parseTicketTierError != nil {
+		return nil, parseTicketTierError
+	}
 
-func (service *Service) Create(ctx context.Context, eventID uuid.UUID, req EventDetailParameters) (*EventDetail, error) {
-	showDate, _, parseShowDateError := convert.StringToTime(req.ShowDate)
+	eventDetailID := uuid.New()
+	unlocksAfterEventDetailID, checkUnlocksAfterError := service.checkUnlocksAfter(ctx, eventID, eventDetailID, req.UnlocksAfterEventDetailID)
 
-	if parseShowDateError != nil {
-		return nil, parseShowDateError
+	if checkUnlocksAfterError != nil {
+		return nil, checkUnlocksAfterError
 	}
 
-	priceString := fmt.Sprintf("%.2f", req.Price)
-
 	createEventDetailParams := database.CreateEventDetailParams{
-		ID:                uuid.New(),
-		ShowDate:          showDate,
-		Price:             priceString,
-		NumberOfTickets:   req.NumberOfTickets,
-		TicketsRemaining:  req.NumberOfTickets,
-		TicketDescription: req.TicketDescription,
-		EventID:           eventID,
+		ID:                        eventDetailID,
+		ShowDate:                  showDate,
+		Price:                     req.Price,
+		NumberOfTickets:           req.NumberOfTickets,
+		TicketsRemaining:          req.NumberOfTickets,
+		TicketDescription:         req.TicketDescription,
+		EventID:                   eventID,
+		SalesStartAt:              ticketTier.SalesStartAt,
+		SalesEndAt:                ticketTier.SalesEndAt,
+		PriceSchedule:             ticketTier.PriceSchedule,
+		UnlocksAfterEventDetailID: unlocksAfterEventDetailID,
 	}
 
 	createdEventDetail, createEventDetailError := service.DBQueries.CreateEventDetail(ctx, createEventDetailParams)
@@ -72,44 +81,118 @@ func (service *Service) Create(ctx context.Context, eventID uuid.UUID, req Event
 		return nil, errors.New("error creating event detail")
 	}
 
-	eventDetail := DatabaseEventDetailToEventDetailJSON(createdEventDetail)
+	eventDetail := DatabaseEventDetailToEventDetailJSON(createdEventDetail, event.Currency)
 
 	return &eventDetail, nil
 }
 
-func (service *Service) Update(ctx context.Context, eventID, eventDetailID uuid.UUID, req EventDetailParameters) (*EventDetail, error) {
+func (service *Service) Update(ctx context.Context, eventID, eventDetailID, userID uuid.UUID, userRole string, req EventDetailParameters) (*EventDetail, error) {
 	showDate, _, parseShowDateError := convert.StringToTime(req.ShowDate)
 
 	if parseShowDateError != nil {
 		return nil, parseShowDateError
 	}
 
-	priceString := fmt.Sprintf("%.2f", req.Price)
+	event, authorizeError := event_staff.AuthorizeEvent(ctx, &service.DBQueries, eventID, userID, userRole, event_staff.PermissionManageTickets)
+
+	if authorizeError != nil {
+		return nil, authorizeError
+	}
+
+	ticketTier, parseTicketTierError := ParseTicketTier(req, showDate)
+
+	if parseTicketTierError != nil {
+		return nil, parseTicketTierError
+	}
+
+	unlocksAfterEventDetailID, checkUnlocksAfterError := service.checkUnlocksAfter(ctx, eventID, eventDetailID, req.UnlocksAfterEventDetailID)
+
+	if checkUnlocksAfterError != nil {
+		return nil, checkUnlocksAfterError
+	}
 
 	updateEventDetailParams := database.UpdateEventDetailParams{
-		ShowDate:          showDate,
-		Price:             priceString,
-		NumberOfTickets:   req.NumberOfTickets,
-		TicketDescription: req.TicketDescription,
-		ID:                eventDetailID,
-		EventID:           eventID,
+		ShowDate:                  showDate,
+		Price:                     req.Price,
+		NumberOfTickets:           req.NumberOfTickets,
+		TicketDescription:         req.TicketDescription,
+		SalesStartAt:              ticketTier.SalesStartAt,
+		SalesEndAt:                ticketTier.SalesEndAt,
+		PriceSchedule:             ticketTier.PriceSchedule,
+		UnlocksAfterEventDetailID: unlocksAfterEventDetailID,
+		ID:                        eventDetailID,
+		EventID:                   eventID,
+	}
+
+	tx, beginTxError := service.DBConnection.BeginTx(ctx, nil)
+
+	if beginTxError != nil {
+		log.Printf("error starting transaction to update event detail %s: %v", eventDetailID, beginTxError)
+
+		return nil, errors.New("error updating event detail")
 	}
 
-	updatedEventDetail, updateEventDetailError := service.DBQueries.UpdateEventDetail(ctx, updateEventDetailParams)
+	defer tx.Rollback()
+	qtx := service.DBQueries.WithTx(tx)
+
+	previousEventDetail, getEventDetailError := qtx.GetEventDetailsById(ctx, eventDetailID)
+
+	if getEventDetailError != nil || previousEventDetail.EventID != eventID {
+		if getEventDetailError != nil && !errors.Is(getEventDetailError, sql.ErrNoRows) {
+			log.Printf("error getting event detail %s: %v", eventDetailID, getEventDetailError)
+
+			return nil, getEventDetailError
+		}
+
+		return nil, sql.ErrNoRows
+	}
+
+	updatedEventDetail, updateEventDetailError := qtx.UpdateEventDetail(ctx, updateEventDetailParams)
 
 	if updateEventDetailError != nil {
+		// The update matches no row when the new capacity is below what was sold.
+		if errors.Is(updateEventDetailError, sql.ErrNoRows) {
+			return nil, ErrNumberOfTicketsBelowSold
+		}
+
 		log.Printf("error updating event detail: %v", updateEventDetailError)
 
 		return nil, updateEventDetailError
 	}
 
-	eventDetail := DatabaseEventDetailToEventDetailJSON(updatedEventDetail)
+	// Calendars that already have the show only take the new date or description when the sequence went up.
+	if calendar.ShowChanged(previousEventDetail, updatedEventDetail) {
+		if bumpSequenceError := qtx.BumpEventCalendarSequence(ctx, eventID); bumpSequenceError != nil {
+			log.Printf("error bumping calendar sequence of event %s: %v", eventID, bumpSequenceError)
+
+			return nil, bumpSequenceError
+		}
+	}
+
+	if commitError := tx.Commit(); commitError != nil {
+		log.Printf("error committing update of event detail %s: %v", eventDetailID, commitError)
+
+		return nil, commitError
+	}
+
+	// Added capacity goes to the waitlist first.
+	service.Waitlist.OfferReleasedTickets(ctx, []uuid.UUID{updatedEventDetail.ID})
+
+	eventDetail := DatabaseEventDetailToEventDetailJSON(updatedEventDetail, event.Currency)
 
 	return &eventDetail, nil
 }
 
-func (service *Service) Delete(ctx context.Context, eventID, eventDetailID, ownerID uuid.UUID, userEmail string) ([]EventDetailFailedRefundOrCancel, []FailedNotificationEmail, error) {
-	failedRefunds, failedEmails, refundCancelPaymentErrors := service.EventDetailRefundOrCancelPayment(ctx, eventDetailID, ownerID, userEmail)
+func (service *Service) Delete(ctx context.Context, eventID, eventDetailID, userID uuid.UUID, userRole string, userEmail string) ([]EventDetailFailedRefundOrCancel, []FailedNotificationEmail, error) {
+	event, authorizeError := event_staff.AuthorizeEvent(ctx, &service.DBQueries, eventID, userID, userRole, event_staff.PermissionManageTickets)
+
+	if authorizeError != nil {
+		return []EventDetailFailedRefundOrCancel{}, []FailedNotificationEmail{}, authorizeError
+	}
+
+	// Refunds are looked up through the event owner, staff with manage_tickets act on their behalf.
+	branding := mailer.LoadBranding(ctx, &service.DBQueries, event.Organizer.String, event.UserID)
+	failedRefunds, failedEmails, refundCancelPaymentErrors := service.EventDetailRefundOrCancelPayment(ctx, eventDetailID, event.UserID, userEmail, branding)
 
 	if refundCancelPaymentErrors != nil {
 		return []EventDetailFailedRefundOrCancel{}, []FailedNotificationEmail{}, refundCancelPaymentErrors
@@ -135,7 +218,42 @@ func (service *Service) Delete(ctx context.Context, eventID, eventDetailID, owne
 	return failedRefunds, failedEmails, nil
 }
 
-func (service *Service) EventDetailRefundOrCancelPayment(ctx context.Context, eventDetailID uuid.UUID, userID uuid.UUID, userEmail string) ([]EventDetailFailedRefundOrCancel, []FailedNotificationEmail, error) {
+// checkUnlocksAfter makes sure the ticket type this one waits for belongs to the same event and doesn't end up waiting for this one.
+func (service *Service) checkUnlocksAfter(ctx context.Context, eventID, eventDetailID uuid.UUID, unlocksAfterEventDetailID *uuid.UUID) (uuid.NullUUID, error) {
+	if unlocksAfterEventDetailID == nil {
+		return uuid.NullUUID{}, nil
+	}
+
+	nextEventDetailID := *unlocksAfterEventDetailID
+
+	for nextEventDetailID != uuid.Nil {
+		if nextEventDetailID == eventDetailID {
+			return uuid.NullUUID{}, ErrInvalidUnlocksAfter
+		}
+
+		nextEventDetail, getEventDetailError := service.DBQueries.GetEventDetailsById(ctx, nextEventDetailID)
+
+		if getEventDetailError != nil {
+			if errors.Is(getEventDetailError, sql.ErrNoRows) {
+				return uuid.NullUUID{}, ErrInvalidUnlocksAfter
+			}
+
+			log.Printf("error fetching event detail %s: %v", nextEventDetailID, getEventDetailError)
+
+			return uuid.NullUUID{}, errors.New("error checking unlocks after ticket type")
+		}
+
+		if nextEventDetail.EventID != eventID {
+			return uuid.NullUUID{}, ErrInvalidUnlocksAfter
+		}
+
+		nextEventDetailID = nextEventDetail.UnlocksAfterEventDetailID.UUID
+	}
+
+	return uuid.NullUUID{UUID: *unlocksAfterEventDetailID, Valid: true}, nil
+}
+
+func (service *Service) EventDetailRefundOrCancelPayment(ctx context.Context, eventDetailID uuid.UUID, userID uuid.UUID, userEmail string, branding mailer.Branding) ([]EventDetailFailedRefundOrCancel, []FailedNotificationEmail, error) {
 	getPaidEventDetailForRefundParams := database.GetPaidEventDetailForRefundParams {
 		EventDetailID: eventDetailID,
 		UserID: userID,
@@ -154,7 +272,7 @@ func (service *Service) EventDetailRefundOrCancelPayment(ctx context.Context, ev
 	uniquePaymentsToProcess := make(map[string]database.GetPaidEventDetailForRefundRow)
 
 	for _, detail := range paidEventDetailForRefunds {
-		// Use the PaymentIntentID as the unique key for Stripe actions.
+		// Use the PaymentIntentID as the unique key for payment gateway actions.
 		if detail.PaymentIntentID.Valid {
 			uniquePaymentsToProcess[detail.PaymentIntentID.String] = detail
 		}
@@ -164,37 +282,71 @@ func (service *Service) EventDetailRefundOrCancelPayment(ctx context.Context, ev
 		return nil, nil, nil
 	}
 
+	// NOTE: We rely on the first entry in paidEventDetailForRefunds for event/ticket details for the email,
+	// which is potentially inaccurate if the refund covers multiple events.
+	eventTitle := paidEventDetailForRefunds[0].Title
+	ticketDescription := paidEventDetailForRefunds[0].TicketDescription
+
+	// notifyPayer enqueues the refund or cancellation email of the payer with dbQueries.
+	notifyPayer := func(dbQueries *database.Queries, payerUserID uuid.UUID, amount money.Money) error {
+		user, getUserByIdError := dbQueries.GetUserById(ctx, payerUserID)
+
+		if getUserByIdError != nil {
+			return fmt.Errorf("failed to get user email of payer %s: %w", payerUserID, getUserByIdError)
+		}
+
+		recipientName := fmt.Sprintf("%s %s", user.Firstname, user.Lastname)
+		refundOrCancelledNotifMessage := fmt.Sprintf(`Hi %s,
+
+The event reservation you've booked: %s - %s, was cancelled and your payment was refunded. 
+If you didn't pay yet, the pending payment is now cancelled.
+Sorry for the inconvencience.`, recipientName, eventTitle, ticketDescription)
+
+		return service.Mailer.WithQueries(dbQueries).SendRefundOrCancelledEmail(recipientName, user.Email, eventTitle, refundOrCancelledNotifMessage, amount, branding)
+	}
+
 	var (
-		PaymentIDs []uuid.UUID
 		mutex sync.Mutex
 		waitGroup sync.WaitGroup
 		eventDetailFailedRefundOrCancels []EventDetailFailedRefundOrCancel
+		failedNotificationEmails = []FailedNotificationEmail{}
 	)
 
+	addFailedNotificationEmail := func(notifyError error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		failedNotificationEmails = append(failedNotificationEmails, FailedNotificationEmail{
+			SendRefundCancelNotificationError: notifyError.Error(),
+		})
+	}
+
 	// Iterate over the map of unique payment intents.
 	for _, eventDetailForRefund := range uniquePaymentsToProcess {
 		paidEventDetailForRefund := eventDetailForRefund
 		
-		amount, _ := convert.PriceStringToCents(paidEventDetailForRefund.Amount)
-		ticketPrice, _ := convert.PriceStringToCents(paidEventDetailForRefund.TicketPrice)
+		amount := paidEventDetailForRefund.Amount
+		ticketPrice := paidEventDetailForRefund.TicketPrice
 		isErrorOccured := false
 
-		if paidEventDetailForRefund.Status == "refunded" || paidEventDetailForRefund.Status == "cancelled" {
+		paymentStatus := paymentstatus.Status(paidEventDetailForRefund.Status)
+
+		if paymentStatus == paymentstatus.Refunded || paymentStatus == paymentstatus.RefundPending || paymentStatus == paymentstatus.Cancelled {
 			continue
 		}
 
-		// Handle free/unpaid tickets outside the goroutine as it involves no Stripe action.
+		// Handle free/unpaid tickets outside the goroutine as it involves no payment gateway action.
 		if amount == 0 || ticketPrice == 0 {
-			mutex.Lock()
-			PaymentIDs = append(PaymentIDs, paidEventDetailForRefund.PaymentID)
-			mutex.Unlock()
+			if notifyError := notifyPayer(&service.DBQueries, paidEventDetailForRefund.PayerUserID, money.New(0, paidEventDetailForRefund.Currency)); notifyError != nil {
+				addFailedNotificationEmail(notifyError)
+			}
 
 			continue
-		} else {
-			// This is the partial refund amount for this specific ticket price.
-			if amount != ticketPrice {
-				amount = ticketPrice
-			}
+		}
+
+		// This is the partial refund amount for this specific ticket price.
+		if amount != ticketPrice {
+			amount = ticketPrice
 		}
 
 		waitGroup.Go(func() {
@@ -202,33 +354,39 @@ func (service *Service) EventDetailRefundOrCancelPayment(ctx context.Context, ev
 
 			updatePaymentParams := database.UpdatePaymentParams{
 				ID: paidEventDetailForRefund.PaymentID,
-				Amount: fmt.Sprintf("%.2f", float64(amount)/100.0),
+				Amount: amount,
 				PaymentIntentID: paidEventDetailForRefund.PaymentIntentID,
-				UserID: userID,
+				UserID: paidEventDetailForRefund.PayerUserID,
+				CurrentStatus: paidEventDetailForRefund.Status,
 			}
 
 			createPaymentLogParams := database.CreatePaymentLogParams{
 				ID: uuid.New(),
 				PaymentIntentID: paidEventDetailForRefund.PaymentIntentID.String, 
-				Amount: fmt.Sprintf("%.2f", float64(amount)/100.0),
+				Amount: amount,
 				UserEmail: userEmail,
 				PaymentID: paidEventDetailForRefund.PaymentID,
+				Currency: paidEventDetailForRefund.Currency,
 			}
 
-			if paidEventDetailForRefund.Status == string(stripe.PaymentIntentStatusSucceeded) {
-				refundResult, refundError := service.Stripe.Refund(amount, paidEventDetailForRefund.PaymentIntentID.String)
+			// Paid payments, and ones whose refund failed before, are refunded. Unpaid ones are cancelled.
+			if paymentStatus.CanTransitionTo(paymentstatus.RefundPending) {
+				refundResult, refundError := service.PaymentGateway.Refund(ctx, paymentgateway.RefundParams{
+					PaymentIntentID: paidEventDetailForRefund.PaymentIntentID.String,
+					Amount:          amount,
+				})
 
 				if refundError != nil {
 					service.Mailer.SendRefundErrorNotification()
 
-					if stripeError, ok := refundError.(*stripe.Error); ok {
-						createPaymentLogParams.Status = string(stripeError.Code)
-						createPaymentLogParams.Description = sqlutil.StringToNullString(stripeError.Msg)
+					if gatewayError, ok := paymentgateway.AsError(refundError); ok {
+						createPaymentLogParams.Status = gatewayError.Code
+						createPaymentLogParams.Description = sqlutil.StringToNullString(gatewayError.Message)
 
 						eventFailedRefundOrCancel.PaymentID = paidEventDetailForRefund.PaymentID
-						eventFailedRefundOrCancel.Action = "stripe refund request"
-						eventFailedRefundOrCancel.Code = string(stripeError.Code)
-						eventFailedRefundOrCancel.Message = stripeError.Msg
+						eventFailedRefundOrCancel.Action = "refund request"
+						eventFailedRefundOrCancel.Code = gatewayError.Code
+						eventFailedRefundOrCancel.Message = gatewayError.Message
 
 						isErrorOccured = true
 					}
@@ -238,33 +396,34 @@ func (service *Service) EventDetailRefundOrCancelPayment(ctx context.Context, ev
 					createPaymentLogParams.Status = string(refundResult.Status)
 
 					switch refundResult.Status {
-						case stripe.RefundStatusFailed:
-							createPaymentLogParams.Description = sqlutil.StringToNullString(string(refundResult.FailureReason))
-						case stripe.RefundStatusPending:
+						case paymentgateway.RefundStatusFailed:
+							createPaymentLogParams.Description = sqlutil.StringToNullString(refundResult.FailureReason)
+							updatePaymentParams.Status = string(paymentstatus.RefundFailed)
+						case paymentgateway.RefundStatusPending:
 							createPaymentLogParams.Description = sqlutil.StringToNullString("refund pending")
-							updatePaymentParams.Status = "refund pending"
-						case stripe.RefundStatusSucceeded:
+							updatePaymentParams.Status = string(paymentstatus.RefundPending)
+						case paymentgateway.RefundStatusSucceeded:
 							createPaymentLogParams.Description = sqlutil.StringToNullString("refund succeeded")
-							updatePaymentParams.Status = "refunded"
+							updatePaymentParams.Status = string(paymentstatus.Refunded)
 					}
 				}
 			} else {
-				paymentIntentCancelError := service.Stripe.CancelPaymentIntent(paidEventDetailForRefund.PaymentIntentID.String, "abandoned")
+				_, paymentIntentCancelError := service.PaymentGateway.CancelIntent(ctx, paidEventDetailForRefund.PaymentIntentID.String)
 
 				if paymentIntentCancelError != nil {
-					if stripeError, ok := paymentIntentCancelError.(*stripe.Error); ok {
-						createPaymentLogParams.Status = string(stripeError.Code)
-						createPaymentLogParams.Description = sqlutil.StringToNullString(stripeError.Msg)
+					if gatewayError, ok := paymentgateway.AsError(paymentIntentCancelError); ok {
+						createPaymentLogParams.Status = gatewayError.Code
+						createPaymentLogParams.Description = sqlutil.StringToNullString(gatewayError.Message)
 
 						eventFailedRefundOrCancel.PaymentID = paidEventDetailForRefund.PaymentID
-						eventFailedRefundOrCancel.Action = "stripe cancel request"
-						eventFailedRefundOrCancel.Code = string(stripeError.Code)
-						eventFailedRefundOrCancel.Message = stripeError.Msg
+						eventFailedRefundOrCancel.Action = "cancel request"
+						eventFailedRefundOrCancel.Code = gatewayError.Code
+						eventFailedRefundOrCancel.Message = gatewayError.Message
 						isErrorOccured = true
 					}
 				} else {
-					updatePaymentParams.Status = "cancelled"
-					createPaymentLogParams.Status = "cancelled"
+					updatePaymentParams.Status = string(paymentstatus.Cancelled)
+					createPaymentLogParams.Status = string(paymentstatus.Cancelled)
 					createPaymentLogParams.Description = sqlutil.StringToNullString("event detail deleted")
 				}
 			}
@@ -279,101 +438,80 @@ func (service *Service) EventDetailRefundOrCancelPayment(ctx context.Context, ev
 				return
 			}
 
-			// Update payment.
-			service.DBQueries.UpdatePayment(ctx, updatePaymentParams)
+			// Update payment and notify its payer.
+			updatePaymentError := service.updatePaymentAndNotifyPayer(ctx, updatePaymentParams, func(qtx *database.Queries) error {
+				return notifyPayer(qtx, paidEventDetailForRefund.PayerUserID, money.New(amount, paidEventDetailForRefund.Currency))
+			})
 
-			mutex.Lock()
-			PaymentIDs = append(PaymentIDs, paidEventDetailForRefund.PaymentID)
-			mutex.Unlock()
+			if updatePaymentError != nil {
+				log.Printf("error: update payment - %s", updatePaymentError)
+				addFailedNotificationEmail(updatePaymentError)
+			}
 		})
 	}
 
 	waitGroup.Wait()
-	
-	if len(PaymentIDs) == 0 {
-		return eventDetailFailedRefundOrCancels, nil, nil
-	}
-
-	// Get payments for notification. Only pass unique IDs.
-	payments, _ := service.DBQueries.GetMultiplePayments(ctx, PaymentIDs)
-
-	var (
-		sendRefundCanceWaitGroup sync.WaitGroup
-		sendRefundCancelNotifErrorChannel = make(chan error, len(payments))
-	)
-
-	for _, pymnt := range payments {
-		payment := pymnt
-
-		sendRefundCanceWaitGroup.Go(func() {
-			
-			user, getUserByIdError := service.DBQueries.GetUserById(ctx, payment.UserID)
-
-			if getUserByIdError != nil {
-				sendRefundCancelNotifErrorChannel <- fmt.Errorf("failed to get user email for payment %s: %w", payment.ID, getUserByIdError)
-
-				return
-			}
-
-			// NOTE: We rely on the first entry in paidEventDetailForRefunds for event/ticket details for the email,
-			// which is potentially inaccurate if the refund covers multiple events.
-			// Using the first entry from the original query results for generic details.
-			eventTitle := paidEventDetailForRefunds[0].Title
-			ticketDescription := paidEventDetailForRefunds[0].TicketDescription
-			recipientName := fmt.Sprintf("%s %s", user.Firstname, user.Lastname)
-			refundOrCancelledNotifMessage := fmt.Sprintf(`Hi %s,
 
-The event reservation you've booked: %s - %s, was cancelled and your payment was refunded. 
-If you didn't pay yet, the pending payment is now cancelled.
-Sorry for the inconvencience.
-
-- Event - MRS Team`, recipientName, eventTitle, ticketDescription)
+	return eventDetailFailedRefundOrCancels, failedNotificationEmails, nil
+}
 
-			sendRefundCancelError := service.Mailer.SendRefundOrCancelledEmail(recipientName, user.Email, eventTitle, refundOrCancelledNotifMessage)
+// updatePaymentAndNotifyPayer saves the refund or cancellation of a payment and enqueues the email of its payer in one
+// transaction, payers are only told about changes that were saved.
+func (service *Service) updatePaymentAndNotifyPayer(ctx context.Context, updatePaymentParams database.UpdatePaymentParams, notifyPayer func(qtx *database.Queries) error) error {
+	tx, beginTxError := service.DBConnection.BeginTx(ctx, nil)
 
-			if sendRefundCancelError != nil {
-				sendRefundCancelNotifErrorChannel <- sendRefundCancelError
-			}
-		})
+	if beginTxError != nil {
+		return fmt.Errorf("failed to start transaction for payment %s: %w", updatePaymentParams.ID, beginTxError)
 	}
 
-	sendRefundCanceWaitGroup.Wait()
-	close(sendRefundCancelNotifErrorChannel)
+	defer tx.Rollback()
+	qtx := service.DBQueries.WithTx(tx)
 
-	failedNotificationEmails := []FailedNotificationEmail{}
+	if _, updatePaymentError := paymentstatus.Update(ctx, qtx, updatePaymentParams); updatePaymentError != nil {
+		return fmt.Errorf("failed to update payment %s: %w", updatePaymentParams.ID, updatePaymentError)
+	}
 
-	for errorMessage := range sendRefundCancelNotifErrorChannel {
-		if errorMessage != nil {
-			failedNotificationEmails = append(failedNotificationEmails, FailedNotificationEmail{
-				SendRefundCancelNotificationError: errorMessage.Error(),
-			})
-		}
+	if notifyPayerError := notifyPayer(qtx); notifyPayerError != nil {
+		return fmt.Errorf("failed to notify payer of payment %s: %w", updatePaymentParams.ID, notifyPayerError)
 	}
 
-	return eventDetailFailedRefundOrCancels, failedNotificationEmails, nil
+	return tx.Commit()
 }
 
-func DatabaseEventDetailToEventDetailJSON(databaseEventDetail database.EventDetail) EventDetail {
-	priceFloat, _ := convert.StringToFloat32(databaseEventDetail.Price)
-
-	return EventDetail{
+func DatabaseEventDetailToEventDetailJSON(databaseEventDetail database.EventDetail, currency string) EventDetail {
+	eventDetail := EventDetail{
 		ID:                databaseEventDetail.ID,
 		ShowDate:          databaseEventDetail.ShowDate,
-		Price:             priceFloat,
+		Price:             money.New(databaseEventDetail.Price, currency),
 		NumberOfTickets:   databaseEventDetail.NumberOfTickets,
 		TicketsRemaining:  databaseEventDetail.TicketsRemaining,
 		TicketDescription: databaseEventDetail.TicketDescription,
+		PriceSchedule:     DatabasePriceScheduleToPriceSchedule(databaseEventDetail.PriceSchedule, currency),
 		CreatedAt:         databaseEventDetail.CreatedAt,
 		UpdatedAt:         sqlutil.NullTimeToString(databaseEventDetail.UpdatedAt),
 		EventID:           databaseEventDetail.EventID,
 	}
+
+	if databaseEventDetail.SalesStartAt.Valid {
+		eventDetail.SalesStartAt = &databaseEventDetail.SalesStartAt.Time
+	}
+
+	if databaseEventDetail.SalesEndAt.Valid {
+		eventDetail.SalesEndAt = &databaseEventDetail.SalesEndAt.Time
+	}
+
+	if databaseEventDetail.UnlocksAfterEventDetailID.Valid {
+		eventDetail.UnlocksAfterEventDetailID = &databaseEventDetail.UnlocksAfterEventDetailID.UUID
+	}
+
+	return eventDetail
 }
 
-func DatabaseEventDetailsToEventDetailsJSON(databaseEventDetails []database.EventDetail) []EventDetail {
+func DatabaseEventDetailsToEventDetailsJSON(databaseEventDetails []database.EventDetail, currency string) []EventDetail {
 	eventDetails := make([]EventDetail, len(databaseEventDetails))
 
 	for i, databaseEventDetail := range databaseEventDetails {
-		eventDetails[i] = DatabaseEventDetailToEventDetailJSON(databaseEventDetail)
+		eventDetails[i] = DatabaseEventDetailToEventDetailJSON(databaseEventDetail, currency)
 	}
 
 	return eventDetails
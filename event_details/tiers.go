@@ -0,0 +1,177 @@
+package event_details
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/convert"
+	"github.com/elorenzorodz/event-mrs/internal/money"
+)
+
+var (
+	ErrInvalidSalesWindow    = errors.New("invalid sales window")
+	ErrInvalidPriceSchedule  = errors.New("invalid price schedule")
+	ErrInvalidUnlocksAfter   = errors.New("unlocks_after_event_detail_id must be another ticket type of the same event")
+	ErrTicketSalesNotStarted = errors.New("ticket sales have not started")
+	ErrTicketSalesEnded      = errors.New("ticket sales have ended")
+	ErrTicketTierLocked      = errors.New("ticket type goes on sale once another ticket type sells out")
+)
+
+// priceScheduleEntry is how one entry of event_details.price_schedule is stored, prices are in minor units like the price column.
+type priceScheduleEntry struct {
+	Price  int64     `json:"price"`
+	EndsAt time.Time `json:"ends_at"`
+}
+
+// TicketTier is the parsed sales window and price schedule of a ticket type, ready to be stored.
+type TicketTier struct {
+	SalesStartAt  sql.NullTime
+	SalesEndAt    sql.NullTime
+	PriceSchedule json.RawMessage
+}
+
+// ParseTicketTier validates the sales window and price schedule of the request against the show date.
+func ParseTicketTier(req EventDetailParameters, showDate time.Time) (TicketTier, error) {
+	salesStartAt, parseSalesStartError := parseOptionalTime(req.SalesStartAt)
+
+	if parseSalesStartError != nil {
+		return TicketTier{}, fmt.Errorf("%w: error parsing sales start: %v", ErrInvalidSalesWindow, parseSalesStartError)
+	}
+
+	salesEndAt, parseSalesEndError := parseOptionalTime(req.SalesEndAt)
+
+	if parseSalesEndError != nil {
+		return TicketTier{}, fmt.Errorf("%w: error parsing sales end: %v", ErrInvalidSalesWindow, parseSalesEndError)
+	}
+
+	if salesStartAt.Valid && salesEndAt.Valid && !salesStartAt.Time.Before(salesEndAt.Time) {
+		return TicketTier{}, fmt.Errorf("%w: sales start must be before sales end", ErrInvalidSalesWindow)
+	}
+
+	if salesEndAt.Valid && salesEndAt.Time.After(showDate) {
+		return TicketTier{}, fmt.Errorf("%w: sales end can't be after the show date", ErrInvalidSalesWindow)
+	}
+
+	priceSchedule := make([]priceScheduleEntry, 0, len(req.PriceSchedule))
+
+	for _, priceScheduleParams := range req.PriceSchedule {
+		endsAt, _, parseEndsAtError := convert.StringToTime(priceScheduleParams.EndsAt)
+
+		if parseEndsAtError != nil {
+			return TicketTier{}, fmt.Errorf("%w: error parsing ends at '%s'", ErrInvalidPriceSchedule, priceScheduleParams.EndsAt)
+		}
+
+		if priceScheduleParams.Price < 0 {
+			return TicketTier{}, fmt.Errorf("%w: price can't be negative", ErrInvalidPriceSchedule)
+		}
+
+		if !endsAt.Before(showDate) {
+			return TicketTier{}, fmt.Errorf("%w: ends at must be before the show date", ErrInvalidPriceSchedule)
+		}
+
+		priceSchedule = append(priceSchedule, priceScheduleEntry{
+			Price:  priceScheduleParams.Price,
+			EndsAt: endsAt,
+		})
+	}
+
+	slices.SortFunc(priceSchedule, func(a, b priceScheduleEntry) int {
+		return a.EndsAt.Compare(b.EndsAt)
+	})
+
+	for i := 1; i < len(priceSchedule); i++ {
+		if priceSchedule[i].EndsAt.Equal(priceSchedule[i-1].EndsAt) {
+			return TicketTier{}, fmt.Errorf("%w: two prices end at %s", ErrInvalidPriceSchedule, priceSchedule[i].EndsAt.Format("2006-01-02 15:04"))
+		}
+	}
+
+	priceScheduleJSON, marshalError := json.Marshal(priceSchedule)
+
+	if marshalError != nil {
+		return TicketTier{}, marshalError
+	}
+
+	return TicketTier{
+		SalesStartAt:  salesStartAt,
+		SalesEndAt:    salesEndAt,
+		PriceSchedule: priceScheduleJSON,
+	}, nil
+}
+
+// CurrentPrice returns the price charged at the given time and when it ends, the end is zero once the regular price applies.
+func CurrentPrice(price int64, priceScheduleJSON json.RawMessage, at time.Time) (int64, time.Time, error) {
+	priceSchedule, unmarshalError := unmarshalPriceSchedule(priceScheduleJSON)
+
+	if unmarshalError != nil {
+		return 0, time.Time{}, unmarshalError
+	}
+
+	// Entries are stored ordered by ends_at so the first one that hasn't ended is in effect.
+	for _, entry := range priceSchedule {
+		if at.Before(entry.EndsAt) {
+			return entry.Price, entry.EndsAt, nil
+		}
+	}
+
+	return price, time.Time{}, nil
+}
+
+// CheckSalesWindow reports whether the ticket type can be bought at the given time.
+func CheckSalesWindow(salesStartAt, salesEndAt sql.NullTime, at time.Time) error {
+	if salesStartAt.Valid && at.Before(salesStartAt.Time) {
+		return fmt.Errorf("%w, sales start at %s", ErrTicketSalesNotStarted, salesStartAt.Time.Format("2006-01-02 15:04"))
+	}
+
+	if salesEndAt.Valid && !at.Before(salesEndAt.Time) {
+		return ErrTicketSalesEnded
+	}
+
+	return nil
+}
+
+func DatabasePriceScheduleToPriceSchedule(priceScheduleJSON json.RawMessage, currency string) []PriceSchedule {
+	priceSchedule, _ := unmarshalPriceSchedule(priceScheduleJSON)
+	schedule := make([]PriceSchedule, len(priceSchedule))
+
+	for i, entry := range priceSchedule {
+		schedule[i] = PriceSchedule{
+			Price:  money.New(entry.Price, currency),
+			EndsAt: entry.EndsAt,
+		}
+	}
+
+	return schedule
+}
+
+func unmarshalPriceSchedule(priceScheduleJSON json.RawMessage) ([]priceScheduleEntry, error) {
+	var priceSchedule []priceScheduleEntry
+
+	if len(priceScheduleJSON) == 0 {
+		return priceSchedule, nil
+	}
+
+	if unmarshalError := json.Unmarshal(priceScheduleJSON, &priceSchedule); unmarshalError != nil {
+		return nil, fmt.Errorf("error reading price schedule: %w", unmarshalError)
+	}
+
+	return priceSchedule, nil
+}
+
+func parseOptionalTime(dateTime string) (sql.NullTime, error) {
+	if strings.TrimSpace(dateTime) == "" {
+		return sql.NullTime{}, nil
+	}
+
+	parsedTime, _, parseError := convert.StringToTime(dateTime)
+
+	if parseError != nil {
+		return sql.NullTime{}, parseError
+	}
+
+	return sql.NullTime{Time: parsedTime, Valid: true}, nil
+}
@@ -0,0 +1,191 @@
+package event_details_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/event_details"
+)
+
+func TestParseTicketTier(tTesting *testing.T) {
+	showDate := time.Date(2026, 12, 20, 19, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		req           event_details.EventDetailParameters
+		expectedError error
+	}{
+		{
+			name: "NoTier",
+			req:  event_details.EventDetailParameters{},
+		},
+		{
+			name: "SalesWindowAndEarlyBird",
+			req: event_details.EventDetailParameters{
+				SalesStartAt:  "2026-11-01 09:00",
+				SalesEndAt:    "2026-12-20 18:00",
+				PriceSchedule: []event_details.PriceScheduleParameters{{Price: 1500, EndsAt: "2026-11-15 00:00"}},
+			},
+		},
+		{
+			name:          "SalesStartAfterSalesEnd",
+			req:           event_details.EventDetailParameters{SalesStartAt: "2026-12-01 00:00", SalesEndAt: "2026-11-01 00:00"},
+			expectedError: event_details.ErrInvalidSalesWindow,
+		},
+		{
+			name:          "SalesEndAfterShowDate",
+			req:           event_details.EventDetailParameters{SalesEndAt: "2026-12-21 00:00"},
+			expectedError: event_details.ErrInvalidSalesWindow,
+		},
+		{
+			name:          "InvalidSalesStartFormat",
+			req:           event_details.EventDetailParameters{SalesStartAt: "2026-11-01"},
+			expectedError: event_details.ErrInvalidSalesWindow,
+		},
+		{
+			name:          "PriceEndsAfterShowDate",
+			req:           event_details.EventDetailParameters{PriceSchedule: []event_details.PriceScheduleParameters{{Price: 1500, EndsAt: "2026-12-21 00:00"}}},
+			expectedError: event_details.ErrInvalidPriceSchedule,
+		},
+		{
+			name:          "NegativePrice",
+			req:           event_details.EventDetailParameters{PriceSchedule: []event_details.PriceScheduleParameters{{Price: -1, EndsAt: "2026-11-15 00:00"}}},
+			expectedError: event_details.ErrInvalidPriceSchedule,
+		},
+		{
+			name: "DuplicateEndsAt",
+			req: event_details.EventDetailParameters{PriceSchedule: []event_details.PriceScheduleParameters{
+				{Price: 1000, EndsAt: "2026-11-15 00:00"},
+				{Price: 1500, EndsAt: "2026-11-15 00:00"},
+			}},
+			expectedError: event_details.ErrInvalidPriceSchedule,
+		},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.name, func(t *testing.T) {
+			_, err := event_details.ParseTicketTier(tc.req, showDate)
+
+			if tc.expectedError == nil && err != nil {
+				t.Fatalf("ParseTicketTier: expected no error, got: %v", err)
+			}
+
+			if tc.expectedError != nil && !errors.Is(err, tc.expectedError) {
+				t.Fatalf("ParseTicketTier: expected error %v, got: %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestCurrentPrice(tTesting *testing.T) {
+	showDate := time.Date(2026, 12, 20, 19, 0, 0, 0, time.UTC)
+
+	// Entries are given out of order to check they are stored sorted.
+	ticketTier, parseError := event_details.ParseTicketTier(event_details.EventDetailParameters{
+		PriceSchedule: []event_details.PriceScheduleParameters{
+			{Price: 2000, EndsAt: "2026-12-01 00:00"},
+			{Price: 1500, EndsAt: "2026-11-15 00:00"},
+		},
+	}, showDate)
+
+	if parseError != nil {
+		tTesting.Fatalf("ParseTicketTier: expected no error, got: %v", parseError)
+	}
+
+	tests := []struct {
+		name           string
+		at             time.Time
+		expectedPrice  int64
+		expectedEndsAt time.Time
+	}{
+		{
+			name:           "EarlyBird",
+			at:             time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+			expectedPrice:  1500,
+			expectedEndsAt: time.Date(2026, 11, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:           "SecondPhaseStartsWhenEarlyBirdEnds",
+			at:             time.Date(2026, 11, 15, 0, 0, 0, 0, time.UTC),
+			expectedPrice:  2000,
+			expectedEndsAt: time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "RegularPrice",
+			at:            time.Date(2026, 12, 10, 0, 0, 0, 0, time.UTC),
+			expectedPrice: 2500,
+		},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.name, func(t *testing.T) {
+			price, endsAt, err := event_details.CurrentPrice(2500, ticketTier.PriceSchedule, tc.at)
+
+			if err != nil {
+				t.Fatalf("CurrentPrice: expected no error, got: %v", err)
+			}
+
+			if price != tc.expectedPrice {
+				t.Errorf("CurrentPrice: expected price %d, got %d", tc.expectedPrice, price)
+			}
+
+			if !endsAt.Equal(tc.expectedEndsAt) {
+				t.Errorf("CurrentPrice: expected ends at %v, got %v", tc.expectedEndsAt, endsAt)
+			}
+		})
+	}
+}
+
+func TestCheckSalesWindow(tTesting *testing.T) {
+	salesStartAt := sql.NullTime{Time: time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC), Valid: true}
+	salesEndAt := sql.NullTime{Time: time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+
+	tests := []struct {
+		name          string
+		salesStartAt  sql.NullTime
+		salesEndAt    sql.NullTime
+		at            time.Time
+		expectedError error
+	}{
+		{
+			name: "NoWindow",
+			at:   time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "BeforeSalesStart",
+			salesStartAt:  salesStartAt,
+			salesEndAt:    salesEndAt,
+			at:            time.Date(2026, 10, 31, 0, 0, 0, 0, time.UTC),
+			expectedError: event_details.ErrTicketSalesNotStarted,
+		},
+		{
+			name:         "OnSale",
+			salesStartAt: salesStartAt,
+			salesEndAt:   salesEndAt,
+			at:           salesStartAt.Time,
+		},
+		{
+			name:          "SalesEnded",
+			salesStartAt:  salesStartAt,
+			salesEndAt:    salesEndAt,
+			at:            salesEndAt.Time,
+			expectedError: event_details.ErrTicketSalesEnded,
+		},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.name, func(t *testing.T) {
+			err := event_details.CheckSalesWindow(tc.salesStartAt, tc.salesEndAt, tc.at)
+
+			if tc.expectedError == nil && err != nil {
+				t.Fatalf("CheckSalesWindow: expected no error, got: %v", err)
+			}
+
+			if tc.expectedError != nil && !errors.Is(err, tc.expectedError) {
+				t.Fatalf("CheckSalesWindow: expected error %v, got: %v", tc.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package event_staff_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/elorenzorodz/event-mrs/event_staff"
+	"github.com/elorenzorodz/event-mrs/internal/auth"
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/google/uuid"
+)
+
+// MockEventAccessQueries knows a set of events and the staff added to each of them.
+type MockEventAccessQueries struct {
+	events     map[uuid.UUID]database.Event
+	staffRoles map[database.GetEventStaffRoleParams]string
+}
+
+func NewMockEventAccessQueries() *MockEventAccessQueries {
+	return &MockEventAccessQueries{
+		events:     map[uuid.UUID]database.Event{},
+		staffRoles: map[database.GetEventStaffRoleParams]string{},
+	}
+}
+
+func (mockQueries *MockEventAccessQueries) addEvent(ownerID uuid.UUID) uuid.UUID {
+	eventID := uuid.New()
+
+	mockQueries.events[eventID] = database.Event{ID: eventID, Title: "Jazz Night", UserID: ownerID}
+
+	return eventID
+}
+
+func (mockQueries *MockEventAccessQueries) addStaff(eventID, userID uuid.UUID, staffRole string) {
+	mockQueries.staffRoles[database.GetEventStaffRoleParams{EventID: eventID, UserID: userID}] = staffRole
+}
+
+func (mockQueries *MockEventAccessQueries) GetEventById(ctx context.Context, id uuid.UUID) (database.Event, error) {
+	event, ok := mockQueries.events[id]
+
+	if !ok {
+		return database.Event{}, sql.ErrNoRows
+	}
+
+	return event, nil
+}
+
+func (mockQueries *MockEventAccessQueries) GetEventStaffRole(ctx context.Context, arg database.GetEventStaffRoleParams) (string, error) {
+	staffRole, ok := mockQueries.staffRoles[arg]
+
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+
+	return staffRole, nil
+}
+
+func TestAuthorizeEvent(tTesting *testing.T) {
+	ownerID := uuid.New()
+	checkInStaffID := uuid.New()
+	ticketStaffID := uuid.New()
+	attendeeStaffID := uuid.New()
+
+	mockQueries := NewMockEventAccessQueries()
+	staffedEventID := mockQueries.addEvent(ownerID)
+	otherEventID := mockQueries.addEvent(ownerID)
+
+	mockQueries.addStaff(staffedEventID, checkInStaffID, event_staff.StaffRoleCheckIn)
+	mockQueries.addStaff(staffedEventID, ticketStaffID, event_staff.StaffRoleManageTickets)
+	mockQueries.addStaff(staffedEventID, attendeeStaffID, event_staff.StaffRoleViewAttendees)
+
+	tests := []struct {
+		name          string
+		eventID       uuid.UUID
+		userID        uuid.UUID
+		userRole      string
+		permission    event_staff.Permission
+		expectedError error
+	}{
+		{
+			name:       "Success_CheckInStaffChecksInOwnEvent",
+			eventID:    staffedEventID,
+			userID:     checkInStaffID,
+			userRole:   auth.RoleAttendee,
+			permission: event_staff.PermissionCheckIn,
+		},
+		{
+			name:       "Success_CheckInStaffViewsOwnEvent",
+			eventID:    staffedEventID,
+			userID:     checkInStaffID,
+			userRole:   auth.RoleAttendee,
+			permission: event_staff.PermissionViewEvent,
+		},
+		{
+			name:       "Success_TicketStaffChecksInOwnEvent",
+			eventID:    staffedEventID,
+			userID:     ticketStaffID,
+			userRole:   auth.RoleAttendee,
+			permission: event_staff.PermissionCheckIn,
+		},
+		{
+			name:          "NotFound_CheckInStaffChecksInOtherEvent",
+			eventID:       otherEventID,
+			userID:        checkInStaffID,
+			userRole:      auth.RoleAttendee,
+			permission:    event_staff.PermissionCheckIn,
+			expectedError: sql.ErrNoRows,
+		},
+		{
+			name:          "NotFound_TicketStaffManagesTicketsOfOtherEvent",
+			eventID:       otherEventID,
+			userID:        ticketStaffID,
+			userRole:      auth.RoleOrganizer,
+			permission:    event_staff.PermissionManageTickets,
+			expectedError: sql.ErrNoRows,
+		},
+		{
+			name:          "Denied_CheckInStaffManagesOwnEvent",
+			eventID:       staffedEventID,
+			userID:        checkInStaffID,
+			userRole:      auth.RoleAttendee,
+			permission:    event_staff.PermissionManageEvent,
+			expectedError: event_staff.ErrEventAccessDenied,
+		},
+		{
+			// Adding and removing staff needs PermissionManageEvent, no staff role grants it.
+			name:          "Denied_TicketStaffManagesStaff",
+			eventID:       staffedEventID,
+			userID:        ticketStaffID,
+			userRole:      auth.RoleOrganizer,
+			permission:    event_staff.PermissionManageEvent,
+			expectedError: event_staff.ErrEventAccessDenied,
+		},
+		{
+			name:          "Denied_CheckInStaffManagesTickets",
+			eventID:       staffedEventID,
+			userID:        checkInStaffID,
+			userRole:      auth.RoleAttendee,
+			permission:    event_staff.PermissionManageTickets,
+			expectedError: event_staff.ErrEventAccessDenied,
+		},
+		{
+			name:          "Denied_AttendeeStaffChecksIn",
+			eventID:       staffedEventID,
+			userID:        attendeeStaffID,
+			userRole:      auth.RoleAttendee,
+			permission:    event_staff.PermissionCheckIn,
+			expectedError: event_staff.ErrEventAccessDenied,
+		},
+		{
+			name:       "Success_OwnerManagesEvent",
+			eventID:    otherEventID,
+			userID:     ownerID,
+			userRole:   auth.RoleOrganizer,
+			permission: event_staff.PermissionManageEvent,
+		},
+		{
+			name:       "Success_AdminManagesAnyEvent",
+			eventID:    otherEventID,
+			userID:     uuid.New(),
+			userRole:   auth.RoleAdmin,
+			permission: event_staff.PermissionManageEvent,
+		},
+		{
+			name:          "NotFound_EventDoesNotExist",
+			eventID:       uuid.New(),
+			userID:        ownerID,
+			userRole:      auth.RoleOrganizer,
+			permission:    event_staff.PermissionViewEvent,
+			expectedError: sql.ErrNoRows,
+		},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.name, func(t *testing.T) {
+			event, err := event_staff.AuthorizeEvent(context.Background(), mockQueries, tc.eventID, tc.userID, tc.userRole, tc.permission)
+
+			if tc.expectedError != nil {
+				if !errors.Is(err, tc.expectedError) {
+					t.Fatalf("Expected error %v, got %v", tc.expectedError, err)
+				}
+
+				if event.ID != uuid.Nil {
+					t.Errorf("Expected no event when access is refused, got %s", event.ID)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("AuthorizeEvent: expected no error, got: %v", err)
+			}
+
+			if event.ID != tc.eventID {
+				t.Errorf("Expected event %s, got %s", tc.eventID, event.ID)
+			}
+		})
+	}
+}
\ No newline at end of file
@@ -0,0 +1,145 @@
+package event_staff
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (eventStaffAPIConfig *EventStaffAPIConfig) GetEventStaff(ginContext *gin.Context) {
+	eventID, parseEventIDError := uuid.Parse(ginContext.Param("eventId"))
+
+	if parseEventIDError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+
+		return
+	}
+
+	userID := ginContext.MustGet("userId").(uuid.UUID)
+
+	eventStaff, getEventStaffError := eventStaffAPIConfig.Service.List(ginContext.Request.Context(), eventID, userID, ginContext.GetString("role"))
+
+	if getEventStaffError != nil {
+		writeEventStaffError(ginContext, getEventStaffError, "event not found")
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, eventStaff)
+}
+
+func (eventStaffAPIConfig *EventStaffAPIConfig) InviteEventStaff(ginContext *gin.Context) {
+	eventID, parseEventIDError := uuid.Parse(ginContext.Param("eventId"))
+
+	if parseEventIDError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+
+		return
+	}
+
+	inviteEventStaffParams := InviteEventStaffParameters{}
+
+	if parameterBindError := ginContext.ShouldBindJSON(&inviteEventStaffParams); parameterBindError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "error parsing JSON, please check all required fields are present and email is valid"})
+
+		return
+	}
+
+	userID := ginContext.MustGet("userId").(uuid.UUID)
+
+	invitedEventStaff, inviteError := eventStaffAPIConfig.Service.Invite(ginContext.Request.Context(), eventID, userID, ginContext.GetString("role"), inviteEventStaffParams)
+
+	if inviteError != nil {
+		writeEventStaffError(ginContext, inviteError, "event not found")
+
+		return
+	}
+
+	ginContext.JSON(http.StatusCreated, invitedEventStaff)
+}
+
+func (eventStaffAPIConfig *EventStaffAPIConfig) UpdateEventStaff(ginContext *gin.Context) {
+	eventID, parseEventIDError := uuid.Parse(ginContext.Param("eventId"))
+
+	if parseEventIDError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+
+		return
+	}
+
+	staffID, parseStaffIDError := uuid.Parse(ginContext.Param("staffId"))
+
+	if parseStaffIDError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid staff ID"})
+
+		return
+	}
+
+	updateEventStaffParams := UpdateEventStaffParameters{}
+
+	if parameterBindError := ginContext.ShouldBindJSON(&updateEventStaffParams); parameterBindError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "error parsing JSON, please check all required fields are present"})
+
+		return
+	}
+
+	userID := ginContext.MustGet("userId").(uuid.UUID)
+
+	updatedEventStaff, updateError := eventStaffAPIConfig.Service.UpdateRole(ginContext.Request.Context(), eventID, staffID, userID, ginContext.GetString("role"), updateEventStaffParams)
+
+	if updateError != nil {
+		writeEventStaffError(ginContext, updateError, "event or staff member not found")
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, updatedEventStaff)
+}
+
+func (eventStaffAPIConfig *EventStaffAPIConfig) RemoveEventStaff(ginContext *gin.Context) {
+	eventID, parseEventIDError := uuid.Parse(ginContext.Param("eventId"))
+
+	if parseEventIDError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+
+		return
+	}
+
+	staffID, parseStaffIDError := uuid.Parse(ginContext.Param("staffId"))
+
+	if parseStaffIDError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid staff ID"})
+
+		return
+	}
+
+	userID := ginContext.MustGet("userId").(uuid.UUID)
+
+	removeError := eventStaffAPIConfig.Service.Remove(ginContext.Request.Context(), eventID, staffID, userID, ginContext.GetString("role"))
+
+	if removeError != nil {
+		writeEventStaffError(ginContext, removeError, "event or staff member not found")
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"message": "staff member removed successfully"})
+}
+
+func writeEventStaffError(ginContext *gin.Context, eventStaffError error, notFoundMessage string) {
+	switch {
+	case errors.Is(eventStaffError, sql.ErrNoRows):
+		ginContext.JSON(http.StatusNotFound, gin.H{"error": notFoundMessage})
+	case errors.Is(eventStaffError, ErrEventAccessDenied):
+		ginContext.JSON(http.StatusForbidden, gin.H{"error": eventStaffError.Error()})
+	case errors.Is(eventStaffError, ErrInvalidStaffRole):
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": eventStaffError.Error()})
+	case errors.Is(eventStaffError, ErrStaffAlreadyInvited):
+		ginContext.JSON(http.StatusConflict, gin.H{"error": eventStaffError.Error()})
+	default:
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": eventStaffError.Error()})
+	}
+}
\ No newline at end of file
@@ -0,0 +1,45 @@
+package event_staff
+
+import (
+	"context"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/google/uuid"
+)
+
+type EventStaffAPIConfig struct {
+	Service EventStaffService
+}
+
+type EventStaffService interface {
+	List(ctx context.Context, eventID, userID uuid.UUID, userRole string) ([]EventStaff, error)
+	Invite(ctx context.Context, eventID, userID uuid.UUID, userRole string, req InviteEventStaffParameters) (*EventStaff, error)
+	UpdateRole(ctx context.Context, eventID, staffID, userID uuid.UUID, userRole string, req UpdateEventStaffParameters) (*EventStaff, error)
+	Remove(ctx context.Context, eventID, staffID, userID uuid.UUID, userRole string) error
+}
+
+type Service struct {
+	DBQueries database.Queries
+	Mailer    mailer.Mailer
+}
+
+type EventStaff struct {
+	ID        uuid.UUID `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt string    `json:"updated_at"`
+	EventID   uuid.UUID `json:"event_id"`
+	InvitedBy uuid.UUID `json:"invited_by"`
+}
+
+type InviteEventStaffParameters struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required"`
+}
+
+type UpdateEventStaffParameters struct {
+	Role string `json:"role" binding:"required"`
+}
\ No newline at end of file
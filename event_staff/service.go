@@ -0,0 +1,165 @@
+package event_staff
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/elorenzorodz/event-mrs/internal/sqlutil"
+	"github.com/google/uuid"
+)
+
+var ErrStaffAlreadyInvited = errors.New("email is already on the staff of this event")
+
+func NewService(dbQueries database.Queries, mMailer mailer.Mailer) EventStaffService {
+	return &Service{
+		DBQueries: dbQueries,
+		Mailer:    mMailer,
+	}
+}
+
+func (service *Service) List(ctx context.Context, eventID, userID uuid.UUID, userRole string) ([]EventStaff, error) {
+	if _, authorizeError := AuthorizeEvent(ctx, &service.DBQueries, eventID, userID, userRole, PermissionManageEvent); authorizeError != nil {
+		return nil, authorizeError
+	}
+
+	eventStaff, getEventStaffError := service.DBQueries.GetEventStaff(ctx, eventID)
+
+	if getEventStaffError != nil {
+		log.Printf("error fetching event staff: %v", getEventStaffError)
+
+		return nil, errors.New("error fetching event staff")
+	}
+
+	return DatabaseEventStaffListToEventStaffListJSON(eventStaff), nil
+}
+
+func (service *Service) Invite(ctx context.Context, eventID, userID uuid.UUID, userRole string, req InviteEventStaffParameters) (*EventStaff, error) {
+	staffRole := strings.ToLower(strings.TrimSpace(req.Role))
+
+	if !IsValidStaffRole(staffRole) {
+		return nil, ErrInvalidStaffRole
+	}
+
+	event, authorizeError := AuthorizeEvent(ctx, &service.DBQueries, eventID, userID, userRole, PermissionManageEvent)
+
+	if authorizeError != nil {
+		return nil, authorizeError
+	}
+
+	createdEventStaff, createEventStaffError := service.DBQueries.CreateEventStaff(ctx, database.CreateEventStaffParams{
+		ID:        uuid.New(),
+		Email:     strings.ToLower(strings.TrimSpace(req.Email)),
+		Role:      staffRole,
+		EventID:   eventID,
+		InvitedBy: userID,
+	})
+
+	if createEventStaffError != nil {
+		// Nothing is returned when the email is already on the staff.
+		if errors.Is(createEventStaffError, sql.ErrNoRows) {
+			return nil, ErrStaffAlreadyInvited
+		}
+
+		log.Printf("error creating event staff: %v", createEventStaffError)
+
+		return nil, errors.New("error inviting event staff")
+	}
+
+	inviterName := "The event organizer"
+
+	if inviter, getInviterError := service.DBQueries.GetUserById(ctx, userID); getInviterError == nil {
+		inviterName = fmt.Sprintf("%s %s", inviter.Firstname, inviter.Lastname)
+	}
+
+	// The staff entry already grants access, a failed email only means the organizer has to tell them.
+	if sendEmailError := service.Mailer.SendEventStaffInvitation(createdEventStaff.Email, event.Title, createdEventStaff.Role, inviterName, mailer.LoadBranding(ctx, &service.DBQueries, event.Organizer.String, event.UserID)); sendEmailError != nil {
+		log.Printf("error sending event staff invitation: %v", sendEmailError)
+	}
+
+	eventStaff := DatabaseEventStaffToEventStaffJSON(createdEventStaff)
+
+	return &eventStaff, nil
+}
+
+func (service *Service) UpdateRole(ctx context.Context, eventID, staffID, userID uuid.UUID, userRole string, req UpdateEventStaffParameters) (*EventStaff, error) {
+	staffRole := strings.ToLower(strings.TrimSpace(req.Role))
+
+	if !IsValidStaffRole(staffRole) {
+		return nil, ErrInvalidStaffRole
+	}
+
+	if _, authorizeError := AuthorizeEvent(ctx, &service.DBQueries, eventID, userID, userRole, PermissionManageEvent); authorizeError != nil {
+		return nil, authorizeError
+	}
+
+	updatedEventStaff, updateEventStaffError := service.DBQueries.UpdateEventStaffRole(ctx, database.UpdateEventStaffRoleParams{
+		Role:    staffRole,
+		ID:      staffID,
+		EventID: eventID,
+	})
+
+	if updateEventStaffError != nil {
+		if errors.Is(updateEventStaffError, sql.ErrNoRows) {
+			return nil, updateEventStaffError
+		}
+
+		log.Printf("error updating event staff role: %v", updateEventStaffError)
+
+		return nil, errors.New("error updating event staff role")
+	}
+
+	eventStaff := DatabaseEventStaffToEventStaffJSON(updatedEventStaff)
+
+	return &eventStaff, nil
+}
+
+func (service *Service) Remove(ctx context.Context, eventID, staffID, userID uuid.UUID, userRole string) error {
+	if _, authorizeError := AuthorizeEvent(ctx, &service.DBQueries, eventID, userID, userRole, PermissionManageEvent); authorizeError != nil {
+		return authorizeError
+	}
+
+	deletedRows, deleteEventStaffError := service.DBQueries.DeleteEventStaff(ctx, database.DeleteEventStaffParams{
+		ID:      staffID,
+		EventID: eventID,
+	})
+
+	if deleteEventStaffError != nil {
+		log.Printf("error deleting event staff: %v", deleteEventStaffError)
+
+		return errors.New("error removing event staff")
+	}
+
+	if deletedRows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func DatabaseEventStaffToEventStaffJSON(databaseEventStaff database.EventStaff) EventStaff {
+	return EventStaff{
+		ID:        databaseEventStaff.ID,
+		Email:     databaseEventStaff.Email,
+		Role:      databaseEventStaff.Role,
+		CreatedAt: databaseEventStaff.CreatedAt,
+		UpdatedAt: sqlutil.NullTimeToString(databaseEventStaff.UpdatedAt),
+		EventID:   databaseEventStaff.EventID,
+		InvitedBy: databaseEventStaff.InvitedBy,
+	}
+}
+
+func DatabaseEventStaffListToEventStaffListJSON(databaseEventStaffList []database.EventStaff) []EventStaff {
+	eventStaffList := make([]EventStaff, len(databaseEventStaffList))
+
+	for i, databaseEventStaff := range databaseEventStaffList {
+		eventStaffList[i] = DatabaseEventStaffToEventStaffJSON(databaseEventStaff)
+	}
+
+	return eventStaffList
+}
\ No newline at end of file
@@ -1,15 +1,23 @@
 package events
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 
+	"github.com/elorenzorodz/event-mrs/event_staff"
+	"github.com/elorenzorodz/event-mrs/internal/money"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// Public responses can be served from cache for this many seconds.
+const publicCacheMaxAge = 30
+
 func getOwnerIDFromContext(ginContext *gin.Context) (uuid.UUID, error) {
 	ownerID, exists := ginContext.Get("userId")
 
@@ -50,6 +58,12 @@ func (eventAPIConfig *EventAPIConfig) CreateEvent(ginContext *gin.Context) {
 	event, createEventError := eventAPIConfig.Service.Create(ginContext.Request.Context(), ownerID, createEventRequest)
 	
 	if createEventError != nil {
+		if errors.Is(createEventError, money.ErrInvalidCurrency) {
+			ginContext.JSON(http.StatusBadRequest, gin.H{"error": createEventError.Error()})
+
+			return
+		}
+
 		if strings.Contains(createEventError.Error(), "encountered errors") {
 			ginContext.JSON(http.StatusMultiStatus, gin.H{"event": NewEventResponse(event), "error": fmt.Sprintf("error creating some details/tickets: %v", createEventError.Error())})
 
@@ -107,7 +121,7 @@ func (eventAPIConfig *EventAPIConfig) GetUserEventById(ginContext *gin.Context)
 		return
 	}
 
-	event, getEventByIdError := eventAPIConfig.Service.GetEventByID(ginContext.Request.Context(), eventID, ownerID)
+	event, getEventByIdError := eventAPIConfig.Service.GetEventByID(ginContext.Request.Context(), eventID, ownerID, ginContext.GetString("role"))
 
 	if getEventByIdError != nil {
 		if errors.Is(getEventByIdError, ErrEventNotFound) {
@@ -116,6 +130,12 @@ func (eventAPIConfig *EventAPIConfig) GetUserEventById(ginContext *gin.Context)
 			return
 		}
 
+		if errors.Is(getEventByIdError, event_staff.ErrEventAccessDenied) {
+			ginContext.JSON(http.StatusForbidden, gin.H{"error": getEventByIdError.Error()})
+
+			return
+		}
+
 		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "error retrieving user event, please try again in a few minutes"})
 
 		return
@@ -124,6 +144,72 @@ func (eventAPIConfig *EventAPIConfig) GetUserEventById(ginContext *gin.Context)
 	ginContext.JSON(http.StatusOK, gin.H{"event": NewEventResponse(event)})
 }
 
+func (eventAPIConfig *EventAPIConfig) GetEventAttendees(ginContext *gin.Context) {
+	eventID, parseEventIdError := uuid.Parse(ginContext.Param("eventId"))
+
+	if parseEventIdError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+
+		return
+	}
+
+	userID, getUserIDError := getOwnerIDFromContext(ginContext)
+
+	if getUserIDError != nil {
+		ginContext.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user ID from context"})
+
+		return
+	}
+
+	attendees, getEventAttendeesError := eventAPIConfig.Service.GetEventAttendees(ginContext.Request.Context(), eventID, userID, ginContext.GetString("role"))
+
+	if getEventAttendeesError != nil {
+		if errors.Is(getEventAttendeesError, ErrEventNotFound) {
+			ginContext.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+
+			return
+		}
+
+		if errors.Is(getEventAttendeesError, event_staff.ErrEventAccessDenied) {
+			ginContext.JSON(http.StatusForbidden, gin.H{"error": getEventAttendeesError.Error()})
+
+			return
+		}
+
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "error retrieving event attendees, please try again in a few minutes"})
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"attendees": attendees})
+}
+
+func (eventAPIConfig *EventAPIConfig) GetEventById(ginContext *gin.Context) {
+	eventID, parseEventIdError := uuid.Parse(ginContext.Param("eventId"))
+
+	if parseEventIdError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+
+		return
+	}
+
+	event, getEventByIdError := eventAPIConfig.Service.GetAnyEventByID(ginContext.Request.Context(), eventID)
+
+	if getEventByIdError != nil {
+		if errors.Is(getEventByIdError, ErrEventNotFound) {
+			ginContext.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+
+			return
+		}
+
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "error retrieving event, please try again in a few minutes"})
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"event": NewEventResponse(event)})
+}
+
 func (eventAPIConfig *EventAPIConfig) UpdateEvent(ginContext *gin.Context) {
 	eventID, parseEventIdError := uuid.Parse(ginContext.Param("eventId"))
 
@@ -180,6 +266,73 @@ func (eventAPIConfig *EventAPIConfig) DeleteEvent(ginContext *gin.Context) {
 
 		return
 	}
+
+	deleteEventError := eventAPIConfig.Service.Delete(ginContext.Request.Context(), eventID, ownerID)
+
+	if deleteEventError != nil {
+		if errors.Is(deleteEventError, ErrEventNotFound) {
+			ginContext.JSON(http.StatusNotFound, gin.H{"error": "event not found or not owned by user"})
+			return
+		}
+
+		if errors.Is(deleteEventError, ErrEventNotDraft) {
+			ginContext.JSON(http.StatusConflict, gin.H{"error": deleteEventError.Error()})
+
+			return
+		}
+
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "error deleting event, please try again in a few minutes"})
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"message": "event deleted successfully"})
+}
+
+func (eventAPIConfig *EventAPIConfig) PublishEvent(ginContext *gin.Context) {
+	eventID, parseEventIdError := uuid.Parse(ginContext.Param("eventId"))
+
+	if parseEventIdError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+
+		return
+	}
+
+	ownerID, getOwnerIDError := getOwnerIDFromContext(ginContext)
+
+	if getOwnerIDError != nil {
+		ginContext.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user ID from context"})
+
+		return
+	}
+
+	publishedEvent, publishEventError := eventAPIConfig.Service.Publish(ginContext.Request.Context(), eventID, ownerID)
+
+	if publishEventError != nil {
+		writeEventStatusError(ginContext, publishEventError, "error publishing event, please try again in a few minutes")
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"event": NewEventResponse(publishedEvent)})
+}
+
+func (eventAPIConfig *EventAPIConfig) CancelEvent(ginContext *gin.Context) {
+	eventID, parseEventIdError := uuid.Parse(ginContext.Param("eventId"))
+
+	if parseEventIdError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+
+		return
+	}
+
+	ownerID, getOwnerIDError := getOwnerIDFromContext(ginContext)
+
+	if getOwnerIDError != nil {
+		ginContext.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user ID from context"})
+
+		return
+	}
 	
 	userEmail, ok := ginContext.MustGet("email").(string)
 
@@ -189,38 +342,277 @@ func (eventAPIConfig *EventAPIConfig) DeleteEvent(ginContext *gin.Context) {
 		return
 	}
 
-	summary, deleteEventError := eventAPIConfig.Service.Delete(ginContext.Request.Context(), eventID, ownerID, userEmail)
+	cancelledEvent, summary, cancelEventError := eventAPIConfig.Service.Cancel(ginContext.Request.Context(), eventID, ownerID, userEmail)
+
+	if cancelEventError != nil {
+		writeEventStatusError(ginContext, cancelEventError, "error cancelling event, please try again in a few minutes")
+
+		return
+	}
+
+	if len(summary.EventFailedRefundOrCancels) != 0 || len(summary.FailedNotificationEmails) != 0 {
+		ginContext.JSON(http.StatusMultiStatus, gin.H{
+			"event":                                NewEventResponse(cancelledEvent),
+			"payments_failed_refund_or_cancelled":  summary.EventFailedRefundOrCancels,
+			"failed_refund_cancelled_notif_emails": summary.FailedNotificationEmails,
+		})
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"event": NewEventResponse(cancelledEvent)})
+}
+
+func (eventAPIConfig *EventAPIConfig) PostponeEvent(ginContext *gin.Context) {
+	eventID, parseEventIdError := uuid.Parse(ginContext.Param("eventId"))
+
+	if parseEventIdError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+
+		return
+	}
+
+	var postponeEventRequest PostponeEventRequest
+
+	if err := ginContext.ShouldBindJSON(&postponeEventRequest); err != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "error parsing JSON, please check all required fields are present"})
+
+		return
+	}
+
+	ownerID, getOwnerIDError := getOwnerIDFromContext(ginContext)
+
+	if getOwnerIDError != nil {
+		ginContext.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user ID from context"})
+
+		return
+	}
+
+	postponedEvent, postponeEventError := eventAPIConfig.Service.Postpone(ginContext.Request.Context(), eventID, ownerID, postponeEventRequest)
+
+	if postponeEventError != nil {
+		writeEventStatusError(ginContext, postponeEventError, "error postponing event, please try again in a few minutes")
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"event": NewEventResponse(postponedEvent)})
+}
+
+func (eventAPIConfig *EventAPIConfig) RequestEventRefund(ginContext *gin.Context) {
+	eventID, parseEventIdError := uuid.Parse(ginContext.Param("eventId"))
+
+	if parseEventIdError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+
+		return
+	}
+
+	userID, getUserIDError := getOwnerIDFromContext(ginContext)
+
+	if getUserIDError != nil {
+		ginContext.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user ID from context"})
+
+		return
+	}
+
+	userEmail, ok := ginContext.MustGet("email").(string)
+
+	if !ok {
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "user email not found in context"})
+
+		return
+	}
+
+	summary, requestRefundError := eventAPIConfig.Service.RequestRefund(ginContext.Request.Context(), eventID, userID, userEmail)
+
+	if requestRefundError != nil {
+		if errors.Is(requestRefundError, ErrEventNotFound) {
+			ginContext.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
 
-	if deleteEventError != nil {
-		if errors.Is(deleteEventError, ErrEventNotFound) {
-			ginContext.JSON(http.StatusNotFound, gin.H{"error": "event not found or not owned by user"})
 			return
 		}
-		
-		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "error deleting event, please try again in a few minutes"})
+
+		if errors.Is(requestRefundError, ErrRefundWindowClosed) {
+			ginContext.JSON(http.StatusConflict, gin.H{"error": requestRefundError.Error()})
+
+			return
+		}
+
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "error requesting refund, please try again in a few minutes"})
 
 		return
 	}
 
 	if len(summary.EventFailedRefundOrCancels) != 0 || len(summary.FailedNotificationEmails) != 0 {
 		ginContext.JSON(http.StatusMultiStatus, gin.H{
-			"message": "event deleted successfully",
-			"payments_failed_refund_or_cancelled": summary.EventFailedRefundOrCancels,
+			"message":                              "refund requested",
+			"payments_failed_refund_or_cancelled":  summary.EventFailedRefundOrCancels,
 			"failed_refund_cancelled_notif_emails": summary.FailedNotificationEmails,
 		})
 
 		return
 	}
 
-	ginContext.JSON(http.StatusOK, gin.H{"message": "event deleted successfully"})
+	ginContext.JSON(http.StatusOK, gin.H{"message": "refund requested"})
+}
+
+// writeEventStatusError maps the errors shared by the publish, cancel, postpone and reminders endpoints.
+func writeEventStatusError(ginContext *gin.Context, eventStatusError error, internalErrorMessage string) {
+	switch {
+	case errors.Is(eventStatusError, ErrEventNotFound):
+		ginContext.JSON(http.StatusNotFound, gin.H{"error": "event not found or not owned by user"})
+	case errors.Is(eventStatusError, ErrEventStatusTransition):
+		ginContext.JSON(http.StatusConflict, gin.H{"error": eventStatusError.Error()})
+	case strings.Contains(eventStatusError.Error(), "invalid"):
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": eventStatusError.Error()})
+	default:
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": internalErrorMessage})
+	}
+}
+
+func (eventAPIConfig *EventAPIConfig) GetOrganizerBranding(ginContext *gin.Context) {
+	ownerID, getOwnerIDError := getOwnerIDFromContext(ginContext)
+
+	if getOwnerIDError != nil {
+		ginContext.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user ID from context"})
+
+		return
+	}
+
+	branding, getBrandingError := eventAPIConfig.Service.GetBranding(ginContext.Request.Context(), ownerID)
+
+	if getBrandingError != nil {
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "error retrieving branding, please try again in a few minutes"})
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"branding": branding})
+}
+
+func (eventAPIConfig *EventAPIConfig) UpdateOrganizerBranding(ginContext *gin.Context) {
+	var updateBrandingRequest UpdateBrandingRequest
+
+	if err := ginContext.ShouldBindJSON(&updateBrandingRequest); err != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "error parsing JSON, please check the branding fields"})
+
+		return
+	}
+
+	ownerID, getOwnerIDError := getOwnerIDFromContext(ginContext)
+
+	if getOwnerIDError != nil {
+		ginContext.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user ID from context"})
+
+		return
+	}
+
+	branding, updateBrandingError := eventAPIConfig.Service.UpdateBranding(ginContext.Request.Context(), ownerID, updateBrandingRequest)
+
+	if updateBrandingError != nil {
+		if errors.Is(updateBrandingError, ErrInvalidBranding) {
+			ginContext.JSON(http.StatusBadRequest, gin.H{"error": updateBrandingError.Error()})
+
+			return
+		}
+
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "error updating branding, please try again in a few minutes"})
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"branding": branding})
+}
+
+func (eventAPIConfig *EventAPIConfig) GetEventReminders(ginContext *gin.Context) {
+	eventID, parseEventIdError := uuid.Parse(ginContext.Param("eventId"))
+
+	if parseEventIdError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+
+		return
+	}
+
+	ownerID, getOwnerIDError := getOwnerIDFromContext(ginContext)
+
+	if getOwnerIDError != nil {
+		ginContext.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user ID from context"})
+
+		return
+	}
+
+	reminders, getRemindersError := eventAPIConfig.Service.GetReminders(ginContext.Request.Context(), eventID, ownerID)
+
+	if getRemindersError != nil {
+		writeEventStatusError(ginContext, getRemindersError, "error retrieving reminders, please try again in a few minutes")
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"reminders": reminders})
+}
+
+func (eventAPIConfig *EventAPIConfig) UpdateEventReminders(ginContext *gin.Context) {
+	eventID, parseEventIdError := uuid.Parse(ginContext.Param("eventId"))
+
+	if parseEventIdError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+
+		return
+	}
+
+	var updateRemindersRequest UpdateRemindersRequest
+
+	if err := ginContext.ShouldBindJSON(&updateRemindersRequest); err != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "error parsing JSON, please check offset_minutes is a list of minutes"})
+
+		return
+	}
+
+	ownerID, getOwnerIDError := getOwnerIDFromContext(ginContext)
+
+	if getOwnerIDError != nil {
+		ginContext.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user ID from context"})
+
+		return
+	}
+
+	reminders, updateRemindersError := eventAPIConfig.Service.UpdateReminders(ginContext.Request.Context(), eventID, ownerID, updateRemindersRequest)
+
+	if updateRemindersError != nil {
+		writeEventStatusError(ginContext, updateRemindersError, "error updating reminders, please try again in a few minutes")
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"reminders": reminders})
 }
 
 func (eventAPIConfig *EventAPIConfig) GetEvents(ginContext *gin.Context) {
-	searchQuery := ginContext.Query("search")
-	startShowDateQuery := ginContext.Query("startShowDate")
-	endShowDateQuery := ginContext.Query("endShowDate")
+	searchEventsRequest := SearchEventsRequest{
+		Search:              ginContext.Query("search"),
+		Organizer:           ginContext.Query("organizer"),
+		Currency:            ginContext.Query("currency"),
+		StartShowDate:       ginContext.Query("startShowDate"),
+		EndShowDate:         ginContext.Query("endShowDate"),
+		MinPrice:            ginContext.Query("minPrice"),
+		MaxPrice:            ginContext.Query("maxPrice"),
+		HasTicketsRemaining: ginContext.Query("hasTicketsRemaining"),
+		FreeOnly:            ginContext.Query("freeOnly"),
+		Sort:                ginContext.Query("sort"),
+		Cursor:              ginContext.Query("cursor"),
+		Limit:               ginContext.Query("limit"),
+	}
+
+	// Prices are in the minor unit of each event's currency, they can only be compared within one currency.
+	if strings.TrimSpace(searchEventsRequest.Currency) == "" && searchesByPrice(searchEventsRequest) {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "currency is required to filter or sort by price"})
 
-	searchEvents, searchEventsError := eventAPIConfig.Service.SearchEvents(ginContext.Request.Context(), searchQuery, startShowDateQuery, endShowDateQuery)
+		return
+	}
+
+	searchEvents, searchEventsError := eventAPIConfig.Service.SearchEvents(ginContext.Request.Context(), searchEventsRequest)
 
 	if searchEventsError != nil {
 		if strings.Contains(searchEventsError.Error(), "invalid") {
@@ -234,5 +626,90 @@ func (eventAPIConfig *EventAPIConfig) GetEvents(ginContext *gin.Context) {
 		return
 	}
 
-	ginContext.JSON(http.StatusOK, gin.H{"events": searchEvents})
+	ginContext.JSON(http.StatusOK, searchEvents)
+}
+
+func searchesByPrice(searchEventsRequest SearchEventsRequest) bool {
+	sortBy := strings.ToLower(strings.TrimSpace(searchEventsRequest.Sort))
+
+	return strings.TrimSpace(searchEventsRequest.MinPrice) != "" ||
+		strings.TrimSpace(searchEventsRequest.MaxPrice) != "" ||
+		sortBy == SortByPrice ||
+		sortBy == SortByPriceDesc
+}
+
+func (eventAPIConfig *EventAPIConfig) GetPublicEvents(ginContext *gin.Context) {
+	searchQuery := ginContext.Query("search")
+	startShowDateQuery := ginContext.Query("startShowDate")
+	endShowDateQuery := ginContext.Query("endShowDate")
+
+	publicEvents, getPublicEventsError := eventAPIConfig.Service.GetPublicEvents(ginContext.Request.Context(), searchQuery, startShowDateQuery, endShowDateQuery)
+
+	if getPublicEventsError != nil {
+		if strings.Contains(getPublicEventsError.Error(), "invalid") {
+			ginContext.JSON(http.StatusBadRequest, gin.H{"error": getPublicEventsError.Error()})
+
+			return
+		}
+
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "error retrieving events, please try again in a few minutes"})
+
+		return
+	}
+
+	writeCacheableJSON(ginContext, gin.H{"events": publicEvents})
+}
+
+func (eventAPIConfig *EventAPIConfig) GetPublicEventById(ginContext *gin.Context) {
+	eventID, parseEventIdError := uuid.Parse(ginContext.Param("eventId"))
+
+	if parseEventIdError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+
+		return
+	}
+
+	publicEvent, getPublicEventError := eventAPIConfig.Service.GetPublicEventByID(ginContext.Request.Context(), eventID)
+
+	if getPublicEventError != nil {
+		if errors.Is(getPublicEventError, ErrEventNotFound) {
+			ginContext.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+
+			return
+		}
+
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "error retrieving event, please try again in a few minutes"})
+
+		return
+	}
+
+	writeCacheableJSON(ginContext, gin.H{"event": publicEvent})
+}
+
+// writeCacheableJSON lets clients and shared caches keep public responses briefly.
+// The max age is short because tickets_remaining changes with every reservation, the ETag lets clients revalidate cheaply.
+func writeCacheableJSON(ginContext *gin.Context, body any) {
+	responseBody, marshalError := json.Marshal(body)
+
+	if marshalError != nil {
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "error encoding response"})
+
+		return
+	}
+
+	bodyHash := sha256.Sum256(responseBody)
+	entityTag := fmt.Sprintf(`W/"%s"`, hex.EncodeToString(bodyHash[:16]))
+
+	ginContext.Header("Cache-Control", fmt.Sprintf("public, max-age=%d, stale-while-revalidate=%d", publicCacheMaxAge, publicCacheMaxAge))
+	ginContext.Header("ETag", entityTag)
+
+	for _, requestTag := range strings.Split(ginContext.GetHeader("If-None-Match"), ",") {
+		if requestTag = strings.TrimSpace(requestTag); requestTag == entityTag || requestTag == "*" {
+			ginContext.Status(http.StatusNotModified)
+
+			return
+		}
+	}
+
+	ginContext.Data(http.StatusOK, "application/json; charset=utf-8", responseBody)
 }
\ No newline at end of file
@@ -2,31 +2,39 @@ package events
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/elorenzorodz/event-mrs/event_details"
 	"github.com/elorenzorodz/event-mrs/internal/database"
 	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/elorenzorodz/event-mrs/internal/money"
+	"github.com/elorenzorodz/event-mrs/internal/paymentgateway"
+	"github.com/elorenzorodz/event-mrs/waitlist"
 	"github.com/google/uuid"
-	"github.com/stripe/stripe-go/v83"
 )
 
 type Event struct {
-	ID          uuid.UUID
-	UserID      uuid.UUID
-	Title       string
-	Description string
-	Organizer   string
-	CreatedAt   time.Time
-	UpdatedAt   *time.Time
-	Tickets     []event_details.EventDetail
+	ID             uuid.UUID
+	UserID         uuid.UUID
+	Title          string
+	Description    string
+	Organizer      string
+	Status         string
+	Currency       string
+	RefundDeadline *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      *time.Time
+	Tickets        []event_details.EventDetail
 }
 
 type CreateEventRequest struct {
-	Title       string                                `json:"title" binding:"required"`
-	Description string                                `json:"description" binding:"required"`
-	Organizer   string                                `json:"organizer"`
-	Tickets     []event_details.EventDetailParameters `json:"tickets" binding:"required"`
+	Title       string `json:"title" binding:"required"`
+	Description string `json:"description" binding:"required"`
+	Organizer   string `json:"organizer"`
+	// Currency is the ISO 4217 code every ticket of the event is charged in, defaults to USD. It can't be changed later.
+	Currency string                                `json:"currency"`
+	Tickets  []event_details.EventDetailParameters `json:"tickets" binding:"required"`
 }
 
 type UpdateEventRequest struct {
@@ -35,27 +43,93 @@ type UpdateEventRequest struct {
 	Organizer   string `json:"organizer"`
 }
 
+// PostponeEventRequest moves shows of the event to new dates, shows that are not listed keep their date.
+type PostponeEventRequest struct {
+	Shows []PostponedShowRequest `json:"shows" binding:"required,min=1,dive"`
+	// Attendees can ask for a refund for this many days after the postponement, defaults to 14.
+	RefundWindowDays int32 `json:"refund_window_days"`
+}
+
+type PostponedShowRequest struct {
+	EventDetailID uuid.UUID `json:"event_detail_id" binding:"required"`
+	ShowDate      string    `json:"show_date" binding:"required"`
+}
+
 type EventResponse struct {
-	ID          uuid.UUID                   `json:"id"`
-	Title       string                      `json:"title"`
-	Description string                      `json:"description"`
-	Organizer   string                      `json:"organizer"`
-	CreatedAt   time.Time                   `json:"created_at"`
-	UpdatedAt   *time.Time                  `json:"updated_at"`
-	UserID      uuid.UUID                   `json:"user_id"`
-	Tickets     []event_details.EventDetail `json:"tickets"`
+	ID             uuid.UUID                   `json:"id"`
+	Title          string                      `json:"title"`
+	Description    string                      `json:"description"`
+	Organizer      string                      `json:"organizer"`
+	Status         string                      `json:"status"`
+	Currency       string                      `json:"currency"`
+	RefundDeadline *time.Time                  `json:"refund_deadline,omitempty"`
+	CreatedAt      time.Time                   `json:"created_at"`
+	UpdatedAt      *time.Time                  `json:"updated_at"`
+	UserID         uuid.UUID                   `json:"user_id"`
+	Tickets        []event_details.EventDetail `json:"tickets"`
 }
 
+// SearchEventResponse is a ticket type that matched the search, Price is the price in effect when it was searched.
 type SearchEventResponse struct {
-	EventID           uuid.UUID `json:"event_id"`
-	Title             string    `json:"title"`
-	Description       string    `json:"desription"`
-	Organizer         string    `json:"organizer"`
-	EventDetailID     uuid.UUID `json:"event_detail_id"`
-	ShowDate          time.Time `json:"show_date"`
-	Price             float32   `json:"price"`
-	NumberOfTickets   int32     `json:"number_of_tickets"`
-	TicketDescription string    `json:"ticket_description"`
+	EventID           uuid.UUID   `json:"event_id"`
+	Title             string      `json:"title"`
+	Description       string      `json:"desription"`
+	Organizer         string      `json:"organizer"`
+	Status            string      `json:"status"`
+	EventDetailID     uuid.UUID   `json:"event_detail_id"`
+	ShowDate          time.Time   `json:"show_date"`
+	Price             money.Money `json:"price"`
+	NumberOfTickets   int32       `json:"number_of_tickets"`
+	TicketsRemaining  int32       `json:"tickets_remaining"`
+	TicketDescription string      `json:"ticket_description"`
+	// Rank and the highlighted fields are only filled in when the search term matched.
+	Rank               float32 `json:"rank,omitempty"`
+	TitleHighlight     string  `json:"title_highlight,omitempty"`
+	DescriptionSnippet string  `json:"description_snippet,omitempty"`
+}
+
+// SearchEventsRequest holds the raw query parameters, SearchEvents validates them.
+type SearchEventsRequest struct {
+	Search              string
+	Organizer           string
+	Currency            string
+	StartShowDate       string
+	EndShowDate         string
+	MinPrice            string
+	MaxPrice            string
+	HasTicketsRemaining string
+	FreeOnly            string
+	Sort                string
+	Cursor              string
+	Limit               string
+}
+
+type SearchEventsResponse struct {
+	Events     []SearchEventResponse `json:"events"`
+	NextCursor *string               `json:"next_cursor"`
+	TotalCount int64                 `json:"total_count"`
+}
+
+// PublicEventResponse only exposes what anonymous visitors need, owner IDs and internal timestamps are left out.
+type PublicEventResponse struct {
+	ID          uuid.UUID              `json:"id"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description"`
+	Organizer   string                 `json:"organizer"`
+	Status      string                 `json:"status"`
+	Tickets     []PublicTicketResponse `json:"tickets"`
+}
+
+// PublicTicketResponse has the price in effect right now, PriceEndsAt is set while an early price like early-bird applies.
+type PublicTicketResponse struct {
+	ID                uuid.UUID   `json:"id"`
+	ShowDate          time.Time   `json:"show_date"`
+	Price             money.Money `json:"price"`
+	PriceEndsAt       *time.Time  `json:"price_ends_at"`
+	SalesStartAt      *time.Time  `json:"sales_start_at"`
+	SalesEndAt        *time.Time  `json:"sales_end_at"`
+	TicketsRemaining  int32       `json:"tickets_remaining"`
+	TicketDescription string      `json:"ticket_description"`
 }
 
 type EventFailedRefundOrCancel struct {
@@ -69,44 +143,88 @@ type FailedNotificationEmail struct {
 	SendRefundCancelNotificationError string `json:"send_refund_cancel_notification_error"`
 }
 
-type DeleteSummary struct {
+type AttendeeResponse struct {
+	ReservationID     uuid.UUID  `json:"reservation_id"`
+	Email             string     `json:"email"`
+	EventDetailID     uuid.UUID  `json:"event_detail_id"`
+	TicketDescription string     `json:"ticket_description"`
+	ShowDate          time.Time  `json:"show_date"`
+	CheckedInAt       *time.Time `json:"checked_in_at"`
+}
+
+// UpdateBrandingRequest is the look of the emails about the organizer's events, empty fields use the default look.
+type UpdateBrandingRequest struct {
+	LogoURL     string `json:"logo_url"`
+	AccentColor string `json:"accent_color"`
+	Footer      string `json:"footer"`
+}
+
+type BrandingResponse struct {
+	LogoURL     string     `json:"logo_url"`
+	AccentColor string     `json:"accent_color"`
+	Footer      string     `json:"footer"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+}
+
+// UpdateRemindersRequest sets how many minutes before each show its attendees are reminded, an empty list turns the
+// reminders of the event off.
+type UpdateRemindersRequest struct {
+	OffsetMinutes []int32 `json:"offset_minutes" binding:"required"`
+}
+
+type RemindersResponse struct {
+	OffsetMinutes []int32 `json:"offset_minutes"`
+}
+
+// RefundSummary lists the payments that could not be refunded or cancelled and the payers that could not be notified.
+type RefundSummary struct {
 	EventFailedRefundOrCancels []EventFailedRefundOrCancel
 	FailedNotificationEmails   []FailedNotificationEmail
 }
 
 func NewEventResponse(event *Event) EventResponse {
 	return EventResponse{
-		ID:          event.ID,
-		UserID:      event.UserID,
-		Title:       event.Title,
-		Description: event.Description,
-		Organizer:   event.Organizer,
-		CreatedAt:   event.CreatedAt,
-		UpdatedAt:   event.UpdatedAt,
-		Tickets:     event.Tickets,
+		ID:             event.ID,
+		UserID:         event.UserID,
+		Title:          event.Title,
+		Description:    event.Description,
+		Organizer:      event.Organizer,
+		Status:         event.Status,
+		Currency:       event.Currency,
+		RefundDeadline: event.RefundDeadline,
+		CreatedAt:      event.CreatedAt,
+		UpdatedAt:      event.UpdatedAt,
+		Tickets:        event.Tickets,
 	}
 }
 
-type StripeClient interface {
-	Refund(amount int64, paymentIntentID string) (*stripe.Refund, error)
-	CancelPaymentIntent(paymentIntentID string) error
-}
-
-type StripeAPIClient struct{}
-
 type EventService interface {
 	Create(ctx context.Context, ownerID uuid.UUID, req CreateEventRequest) (*Event, error)
 	GetEventsByOwner(ctx context.Context, ownerID uuid.UUID) ([]Event, error)
-	GetEventByID(ctx context.Context, eventID, ownerID uuid.UUID) (*Event, error)
+	GetEventByID(ctx context.Context, eventID, userID uuid.UUID, userRole string) (*Event, error)
+	GetEventAttendees(ctx context.Context, eventID, userID uuid.UUID, userRole string) ([]AttendeeResponse, error)
+	GetAnyEventByID(ctx context.Context, eventID uuid.UUID) (*Event, error)
 	Update(ctx context.Context, eventID, ownerID uuid.UUID, req UpdateEventRequest) (*Event, error)
-	Delete(ctx context.Context, eventID, ownerID uuid.UUID, userEmail string) (*DeleteSummary, error)
-	SearchEvents(ctx context.Context, searchQuery, startShowDateQuery, endShowDateQuery string) ([]SearchEventResponse, error)
+	Delete(ctx context.Context, eventID, ownerID uuid.UUID) error
+	Publish(ctx context.Context, eventID, ownerID uuid.UUID) (*Event, error)
+	Cancel(ctx context.Context, eventID, ownerID uuid.UUID, userEmail string) (*Event, *RefundSummary, error)
+	Postpone(ctx context.Context, eventID, ownerID uuid.UUID, req PostponeEventRequest) (*Event, error)
+	RequestRefund(ctx context.Context, eventID, userID uuid.UUID, userEmail string) (*RefundSummary, error)
+	SearchEvents(ctx context.Context, req SearchEventsRequest) (*SearchEventsResponse, error)
+	GetPublicEvents(ctx context.Context, searchQuery, startShowDateQuery, endShowDateQuery string) ([]PublicEventResponse, error)
+	GetPublicEventByID(ctx context.Context, eventID uuid.UUID) (*PublicEventResponse, error)
+	GetBranding(ctx context.Context, ownerID uuid.UUID) (*BrandingResponse, error)
+	UpdateBranding(ctx context.Context, ownerID uuid.UUID, req UpdateBrandingRequest) (*BrandingResponse, error)
+	GetReminders(ctx context.Context, eventID, ownerID uuid.UUID) (*RemindersResponse, error)
+	UpdateReminders(ctx context.Context, eventID, ownerID uuid.UUID, req UpdateRemindersRequest) (*RemindersResponse, error)
 }
 
 type Service struct {
-	DBQueries database.Queries
-	Mailer    *mailer.Mailer
-	Stripe    StripeClient
+	DBQueries      database.Queries
+	DBConnection   *sql.DB
+	Mailer         mailer.Mailer
+	PaymentGateway paymentgateway.Gateway
+	Waitlist       *waitlist.Service
 }
 
 type EventAPIConfig struct {
@@ -6,59 +6,103 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/elorenzorodz/event-mrs/event_details"
+	"github.com/elorenzorodz/event-mrs/event_staff"
 	"github.com/elorenzorodz/event-mrs/internal/convert"
 	"github.com/elorenzorodz/event-mrs/internal/database"
 	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/elorenzorodz/event-mrs/internal/money"
+	"github.com/elorenzorodz/event-mrs/internal/pagination"
+	"github.com/elorenzorodz/event-mrs/internal/paymentgateway"
+	"github.com/elorenzorodz/event-mrs/internal/paymentstatus"
 	"github.com/elorenzorodz/event-mrs/internal/sqlutil"
+	"github.com/elorenzorodz/event-mrs/waitlist"
 	"github.com/google/uuid"
-	"github.com/stripe/stripe-go/v83"
-	"github.com/stripe/stripe-go/v83/paymentintent"
-	"github.com/stripe/stripe-go/v83/refund"
 )
 
 var (
-	ErrEventNotFound = errors.New("event not found or unauthorized")
-	ErrDatabase      = errors.New("internal database error")
+	ErrEventNotFound         = errors.New("event not found or unauthorized")
+	ErrDatabase              = errors.New("internal database error")
+	ErrEventStatusTransition = errors.New("event status change not allowed")
+	ErrEventNotDraft         = errors.New("only draft events can be deleted, cancel the event instead")
+	ErrRefundWindowClosed    = errors.New("refunds are not available for this event")
+	ErrInvalidBranding       = errors.New("invalid branding")
+	ErrInvalidReminders      = errors.New("invalid reminders")
 )
 
-func (stripeAPIClient *StripeAPIClient) Refund(amount int64, paymentIntentID string) (*stripe.Refund, error) {
-	refundParams := &stripe.RefundParams{
-		Amount:        stripe.Int64(amount),
-		PaymentIntent: stripe.String(paymentIntentID),
-	}
-	return refund.New(refundParams)
+const (
+	EventStatusDraft     = "draft"
+	EventStatusPublished = "published"
+	EventStatusCancelled = "cancelled"
+	EventStatusPostponed = "postponed"
+)
+
+// eventStatusTransitions lists the statuses an event can move to, cancelled events can't be changed anymore.
+var eventStatusTransitions = map[string][]string{
+	EventStatusDraft:     {EventStatusPublished, EventStatusCancelled},
+	EventStatusPublished: {EventStatusPostponed, EventStatusCancelled},
+	EventStatusPostponed: {EventStatusPublished, EventStatusPostponed, EventStatusCancelled},
 }
 
-func (stripeAPIClient *StripeAPIClient) CancelPaymentIntent(paymentIntentID string) error {
-	paymentIntentCancelParams := &stripe.PaymentIntentCancelParams{
-		CancellationReason: stripe.String("abandoned"),
-	}
+const (
+	defaultRefundWindowDays = 14
+	maxRefundWindowDays     = 90
+)
 
-	_, err := paymentintent.Cancel(paymentIntentID, paymentIntentCancelParams)
+const (
+	SortByShowDate  = "show_date"
+	SortByPrice     = "price"
+	SortByPriceDesc = "price_desc"
+	SortByNewest    = "newest"
+	SortByRelevance = "relevance"
+)
 
-	return err
-}
+var searchEventSorts = []string{SortByShowDate, SortByPrice, SortByPriceDesc, SortByNewest, SortByRelevance}
+
+// The footer is shown under every email about the organizer's events.
+const maxBrandingFooterLength = 500
+
+// An event can remind its attendees at most this many times per show, at most 30 days before it.
+const (
+	maxReminderOffsets       = 5
+	maxReminderOffsetMinutes = 30 * 24 * 60
+)
+
+// accentColorPattern matches the colors organizer_brandings accepts.
+var accentColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
 
-func NewService(dbQueries database.Queries, mMailer *mailer.Mailer, stripeClient StripeClient) EventService {
+func NewService(dbQueries database.Queries, dbConn *sql.DB, mMailer mailer.Mailer, paymentGateway paymentgateway.Gateway, waitlistService *waitlist.Service) EventService {
 	return &Service{
-		DBQueries: dbQueries,
-		Mailer:    mMailer,
-		Stripe:    stripeClient,
+		DBQueries:      dbQueries,
+		DBConnection:   dbConn,
+		Mailer:         mMailer,
+		PaymentGateway: paymentGateway,
+		Waitlist:       waitlistService,
 	}
 }
 
 func (service *Service) Create(ctx context.Context, userID uuid.UUID, createEventRequest CreateEventRequest) (*Event, error) {
+	currency := money.NormalizeCurrency(createEventRequest.Currency)
+
+	if validateCurrencyError := money.ValidateCurrency(currency); validateCurrencyError != nil {
+		return nil, validateCurrencyError
+	}
+
 	createEventParams := database.CreateEventParams{
 		ID:          uuid.New(),
 		UserID:      userID,
 		Title:       createEventRequest.Title,
 		Description: createEventRequest.Description,
 		Organizer:   sqlutil.StringToNullString(createEventRequest.Organizer),
+		Currency:    currency,
 	}
 
 	newEvent, createEventError := service.DBQueries.CreateEvent(ctx, createEventParams)
@@ -69,7 +113,7 @@ func (service *Service) Create(ctx context.Context, userID uuid.UUID, createEven
 		return nil, ErrDatabase
 	}
 
-	newTickets, createTicketsError := service.saveEventTickets(ctx, newEvent.ID, createEventRequest.Tickets)
+	newTickets, createTicketsError := service.saveEventTickets(ctx, newEvent.ID, newEvent.Currency, createEventRequest.Tickets)
 
 	if createTicketsError != nil {
 		log.Printf("Partial error creating event tickets for event %s: %v", newEvent.ID, createTicketsError)
@@ -89,9 +133,11 @@ func (service *Service) GetEventsByOwner(ctx context.Context, userID uuid.UUID)
 	}
 
 	eventIdArray := make([]uuid.UUID, len(userEvents))
+	eventCurrencies := make(map[uuid.UUID]string, len(userEvents))
 
 	for i, event := range userEvents {
 		eventIdArray[i] = event.ID
+		eventCurrencies[event.ID] = event.Currency
 	}
 
 	eventDetails, getEventDetailsError := service.DBQueries.GetEventDetailsByEventId(ctx, eventIdArray)
@@ -102,7 +148,7 @@ func (service *Service) GetEventsByOwner(ctx context.Context, userID uuid.UUID)
 		log.Printf("error retrieving event details: %v", getEventDetailsError)
 	} else {
 		for _, detail := range eventDetails {
-			eventDetailJSON := databaseEventDetailToEventDetailJSON(detail)
+			eventDetailJSON := databaseEventDetailToEventDetailJSON(detail, eventCurrencies[detail.EventID])
 			eventDetailsMap[detail.EventID] = append(eventDetailsMap[detail.EventID], eventDetailJSON)
 		}
 	}
@@ -118,7 +164,74 @@ func (service *Service) GetEventsByOwner(ctx context.Context, userID uuid.UUID)
 	return events, nil
 }
 
-func (service *Service) GetEventByID(ctx context.Context, eventID, userID uuid.UUID) (*Event, error) {
+// GetEventByID returns the event to its owner, admins and any of its staff.
+func (service *Service) GetEventByID(ctx context.Context, eventID, userID uuid.UUID, userRole string) (*Event, error) {
+	getEvent, authorizeError := service.authorizeEvent(ctx, eventID, userID, userRole, event_staff.PermissionViewEvent)
+
+	if authorizeError != nil {
+		return nil, authorizeError
+	}
+
+	return service.eventWithTickets(ctx, getEvent), nil
+}
+
+func (service *Service) GetEventAttendees(ctx context.Context, eventID, userID uuid.UUID, userRole string) ([]AttendeeResponse, error) {
+	if _, authorizeError := service.authorizeEvent(ctx, eventID, userID, userRole, event_staff.PermissionViewAttendees); authorizeError != nil {
+		return nil, authorizeError
+	}
+
+	eventAttendees, getEventAttendeesError := service.DBQueries.GetEventAttendees(ctx, eventID)
+
+	if getEventAttendeesError != nil {
+		log.Printf("error retrieving event attendees: %v", getEventAttendeesError)
+
+		return nil, ErrDatabase
+	}
+
+	attendees := make([]AttendeeResponse, len(eventAttendees))
+
+	for i, eventAttendee := range eventAttendees {
+		var checkedInAt *time.Time
+
+		if eventAttendee.CheckedInAt.Valid {
+			checkedInAt = &eventAttendee.CheckedInAt.Time
+		}
+
+		attendees[i] = AttendeeResponse{
+			ReservationID:     eventAttendee.ReservationID,
+			Email:             eventAttendee.Email,
+			EventDetailID:     eventAttendee.EventDetailID,
+			TicketDescription: eventAttendee.TicketDescription,
+			ShowDate:          eventAttendee.ShowDate,
+			CheckedInAt:       checkedInAt,
+		}
+	}
+
+	return attendees, nil
+}
+
+// authorizeEvent is getUserEvent for the endpoints that event staff can use too.
+func (service *Service) authorizeEvent(ctx context.Context, eventID, userID uuid.UUID, userRole string, permission event_staff.Permission) (database.Event, error) {
+	getEvent, authorizeError := event_staff.AuthorizeEvent(ctx, &service.DBQueries, eventID, userID, userRole, permission)
+
+	if authorizeError == nil {
+		return getEvent, nil
+	}
+
+	if errors.Is(authorizeError, sql.ErrNoRows) {
+		return database.Event{}, ErrEventNotFound
+	}
+
+	if errors.Is(authorizeError, event_staff.ErrEventAccessDenied) {
+		return database.Event{}, authorizeError
+	}
+
+	log.Printf("error authorizing event access: %v", authorizeError)
+
+	return database.Event{}, ErrDatabase
+}
+
+func (service *Service) getUserEvent(ctx context.Context, eventID, userID uuid.UUID) (database.Event, error) {
 	getUserEventByIdParams := database.GetUserEventByIdParams{
 		ID:     eventID,
 		UserID: userID,
@@ -127,14 +240,33 @@ func (service *Service) GetEventByID(ctx context.Context, eventID, userID uuid.U
 	getUserEvent, getUserEventByIdError := service.DBQueries.GetUserEventById(ctx, getUserEventByIdParams)
 
 	if getUserEventByIdError == sql.ErrNoRows {
-		return nil, ErrEventNotFound
+		return database.Event{}, ErrEventNotFound
 	}
 
 	if getUserEventByIdError != nil {
+		return database.Event{}, ErrDatabase
+	}
+
+	return getUserEvent, nil
+}
+
+// GetAnyEventByID returns an event regardless of its owner, for admins.
+func (service *Service) GetAnyEventByID(ctx context.Context, eventID uuid.UUID) (*Event, error) {
+	getEvent, getEventByIdError := service.DBQueries.GetEventById(ctx, eventID)
+
+	if getEventByIdError == sql.ErrNoRows {
+		return nil, ErrEventNotFound
+	}
+
+	if getEventByIdError != nil {
 		return nil, ErrDatabase
 	}
 
-	eventDetails, getEventDetailsError := service.DBQueries.GetEventDetailsByEventId(ctx, []uuid.UUID{getUserEvent.ID})
+	return service.eventWithTickets(ctx, getEvent), nil
+}
+
+func (service *Service) eventWithTickets(ctx context.Context, databaseEvent database.Event) *Event {
+	eventDetails, getEventDetailsError := service.DBQueries.GetEventDetailsByEventId(ctx, []uuid.UUID{databaseEvent.ID})
 
 	eventDetailsList := []event_details.EventDetail{}
 
@@ -142,14 +274,14 @@ func (service *Service) GetEventByID(ctx context.Context, eventID, userID uuid.U
 		log.Printf("error retrieving event details: %v", getEventDetailsError)
 	} else {
 		for _, detail := range eventDetails {
-			eventDetailsList = append(eventDetailsList, databaseEventDetailToEventDetailJSON(detail))
+			eventDetailsList = append(eventDetailsList, databaseEventDetailToEventDetailJSON(detail, databaseEvent.Currency))
 		}
 	}
 
-	event := databaseEventToDomain(getUserEvent)
+	event := databaseEventToDomain(databaseEvent)
 	event.Tickets = eventDetailsList
 
-	return event, nil
+	return event
 }
 
 func (service *Service) Update(ctx context.Context, eventID, ownerID uuid.UUID, req UpdateEventRequest) (*Event, error) {
@@ -161,7 +293,18 @@ func (service *Service) Update(ctx context.Context, eventID, ownerID uuid.UUID,
 		UserID:      ownerID,
 	}
 
-	updatedEvent, updatedEventError := service.DBQueries.UpdateEvent(ctx, updateEventParams)
+	tx, beginTxError := service.DBConnection.BeginTx(ctx, nil)
+
+	if beginTxError != nil {
+		log.Printf("Error starting transaction to update event %s: %v", eventID, beginTxError)
+
+		return nil, ErrDatabase
+	}
+
+	defer tx.Rollback()
+	qtx := service.DBQueries.WithTx(tx)
+
+	updatedEvent, updatedEventError := qtx.UpdateEvent(ctx, updateEventParams)
 
 	if updatedEventError == sql.ErrNoRows {
 		return nil, ErrEventNotFound
@@ -171,73 +314,550 @@ func (service *Service) Update(ctx context.Context, eventID, ownerID uuid.UUID,
 		return nil, ErrDatabase
 	}
 
-	eventConfirmedUserReservations, getEventConfirmedUserReservations := service.DBQueries.GetEventConfirmedUserReservations(ctx, eventID)
-
-	var waitGroup sync.WaitGroup
+	// Attendees are notified with the update, the emails are enqueued in its transaction.
+	eventConfirmedUserReservations, getEventConfirmedUserReservations := qtx.GetEventConfirmedUserReservations(ctx, eventID)
 
 	if getEventConfirmedUserReservations != nil {
 		log.Printf("error getting users reserved for the event: %v", getEventConfirmedUserReservations)
-	} else {
-		for _, res := range eventConfirmedUserReservations {
-			reservation := res
-			recipientName := reservation.Fullname.String
-
-			waitGroup.Go(func() {
-				sendUpdatedEventNotificationError := service.Mailer.SendUpdatedEventNotification(
-					recipientName,
-					reservation.Email,
-					updatedEvent.Title,
-					updatedEvent.Description,
-					updatedEvent.Organizer.String,
-				)
-
-				if sendUpdatedEventNotificationError != nil {
-					log.Printf("error sending updated event notification to %s: %v", reservation.Email, sendUpdatedEventNotificationError)
-				}
-			})
+
+		return nil, ErrDatabase
+	}
+
+	branding := mailer.LoadBranding(ctx, qtx, updatedEvent.Organizer.String, updatedEvent.UserID)
+	updatedEventMailer := service.Mailer.WithQueries(qtx)
+
+	for _, reservation := range eventConfirmedUserReservations {
+		sendUpdatedEventNotificationError := updatedEventMailer.SendUpdatedEventNotification(
+			reservation.Fullname.String,
+			reservation.Email,
+			updatedEvent.Title,
+			updatedEvent.Description,
+			updatedEvent.Organizer.String,
+			branding,
+		)
+
+		if sendUpdatedEventNotificationError != nil {
+			log.Printf("error enqueuing updated event notification to %s: %v", reservation.Email, sendUpdatedEventNotificationError)
+
+			return nil, ErrDatabase
 		}
+	}
+
+	if commitError := tx.Commit(); commitError != nil {
+		log.Printf("Error committing update of event %s: %v", eventID, commitError)
+
+		return nil, ErrDatabase
+	}
+
+	return databaseEventToDomain(updatedEvent), nil
+}
+
+// Delete removes a draft event. Drafts can't be booked, events that could have reservations are cancelled instead so their payment history is kept.
+func (service *Service) Delete(ctx context.Context, eventID, ownerID uuid.UUID) error {
+	getUserEvent, getUserEventError := service.getUserEvent(ctx, eventID, ownerID)
+
+	if getUserEventError != nil {
+		return getUserEventError
+	}
+
+	if getUserEvent.Status != EventStatusDraft {
+		return ErrEventNotDraft
+	}
+
+	deleteEventParams := database.DeleteEventParams{
+		ID:     eventID,
+		UserID: ownerID,
+	}
+
+	deleteEventError := service.DBQueries.DeleteEvent(ctx, deleteEventParams)
+
+	if deleteEventError != nil {
+		log.Printf("Error deleting event %s: %v", eventID, deleteEventError)
+
+		return ErrDatabase
+	}
+
+	return nil
+}
+
+func (service *Service) Publish(ctx context.Context, eventID, ownerID uuid.UUID) (*Event, error) {
+	getUserEvent, getUserEventError := service.getUserEvent(ctx, eventID, ownerID)
+
+	if getUserEventError != nil {
+		return nil, getUserEventError
+	}
+
+	// A postponed event that is published again keeps the refund window its attendees were promised.
+	publishedEvent, changeStatusError := changeEventStatus(ctx, &service.DBQueries, getUserEvent, EventStatusPublished, getUserEvent.RefundDeadline)
+
+	if changeStatusError != nil {
+		return nil, changeStatusError
+	}
+
+	return service.eventWithTickets(ctx, publishedEvent), nil
+}
+
+// Cancel stops the event and refunds or cancels every payment for it. Reservations and payments are kept for history.
+func (service *Service) Cancel(ctx context.Context, eventID, ownerID uuid.UUID, userEmail string) (*Event, *RefundSummary, error) {
+	getUserEvent, getUserEventError := service.getUserEvent(ctx, eventID, ownerID)
+
+	if getUserEventError != nil {
+		return nil, nil, getUserEventError
+	}
+
+	// The status changes first so no new reservations come in while payments are refunded.
+	cancelledEvent, changeStatusError := changeEventStatus(ctx, &service.DBQueries, getUserEvent, EventStatusCancelled, sql.NullTime{})
+
+	if changeStatusError != nil {
+		return nil, nil, changeStatusError
+	}
+
+	eventFailedRefundOrCancels, failedNotificationEmails, refundCancelPaymentErrors := service.eventRefundOrCancelPayment(
+		ctx,
+		eventID,
+		ownerID,
+		uuid.NullUUID{},
+		userEmail,
+		"event cancelled",
+		fmt.Sprintf("The event: %s, that you booked was cancelled and your payment was refunded. If you didn't pay yet, the pending payment is now cancelled.", cancelledEvent.Title),
+		mailer.LoadBranding(ctx, &service.DBQueries, cancelledEvent.Organizer.String, cancelledEvent.UserID),
+		false,
+	)
+
+	if refundCancelPaymentErrors != nil {
+		log.Printf("Error during refund/cancellation process: %v", refundCancelPaymentErrors)
+	}
+
+	return service.eventWithTickets(ctx, cancelledEvent), &RefundSummary{
+		EventFailedRefundOrCancels: eventFailedRefundOrCancels,
+		FailedNotificationEmails:   failedNotificationEmails,
+	}, nil
+}
+
+// Postpone moves the listed shows to their new dates and opens a refund window for attendees who can't make it.
+func (service *Service) Postpone(ctx context.Context, eventID, ownerID uuid.UUID, req PostponeEventRequest) (*Event, error) {
+	refundWindowDays := req.RefundWindowDays
+
+	if refundWindowDays == 0 {
+		refundWindowDays = defaultRefundWindowDays
+	}
+
+	if refundWindowDays < 1 || refundWindowDays > maxRefundWindowDays {
+		return nil, fmt.Errorf("invalid refund_window_days, must be between 1 and %d", maxRefundWindowDays)
+	}
+
+	currentDateTime := time.Now()
+	newShowDates := make([]time.Time, len(req.Shows))
+
+	for i, postponedShow := range req.Shows {
+		showDate, referenceFormat, parseShowDateError := convert.StringToTime(postponedShow.ShowDate)
+
+		if parseShowDateError != nil {
+			return nil, fmt.Errorf("invalid show date '%s': expected format %s", postponedShow.ShowDate, referenceFormat)
+		}
+
+		if !showDate.After(currentDateTime) {
+			return nil, fmt.Errorf("invalid show date '%s': must be in the future", postponedShow.ShowDate)
+		}
+
+		newShowDates[i] = showDate
+	}
+
+	getUserEvent, getUserEventError := service.getUserEvent(ctx, eventID, ownerID)
+
+	if getUserEventError != nil {
+		return nil, getUserEventError
+	}
+
+	tx, beginTxError := service.DBConnection.BeginTx(ctx, nil)
+
+	if beginTxError != nil {
+		log.Printf("Error starting transaction to postpone event %s: %v", eventID, beginTxError)
+
+		return nil, ErrDatabase
+	}
+
+	defer tx.Rollback()
+	qtx := service.DBQueries.WithTx(tx)
+
+	for i, postponedShow := range req.Shows {
+		updateShowDateParams := database.UpdateEventDetailShowDateParams{
+			ShowDate: newShowDates[i],
+			ID:       postponedShow.EventDetailID,
+			EventID:  eventID,
+		}
+
+		_, updateShowDateError := qtx.UpdateEventDetailShowDate(ctx, updateShowDateParams)
+
+		if updateShowDateError == sql.ErrNoRows {
+			return nil, fmt.Errorf("invalid event detail ID %s, it is not a show of this event", postponedShow.EventDetailID)
+		}
+
+		if updateShowDateError != nil {
+			log.Printf("Error updating show date of event detail %s: %v", postponedShow.EventDetailID, updateShowDateError)
+
+			return nil, ErrDatabase
+		}
+	}
+
+	refundDeadline := sql.NullTime{Time: currentDateTime.AddDate(0, 0, int(refundWindowDays)), Valid: true}
+
+	postponedEvent, changeStatusError := changeEventStatus(ctx, qtx, getUserEvent, EventStatusPostponed, refundDeadline)
+
+	if changeStatusError != nil {
+		return nil, changeStatusError
+	}
+
+	if enqueueNotificationsError := service.enqueueEventPostponedNotifications(ctx, qtx, postponedEvent); enqueueNotificationsError != nil {
+		log.Printf("Error enqueuing postponement notifications of event %s: %v", eventID, enqueueNotificationsError)
+
+		return nil, ErrDatabase
+	}
+
+	if commitError := tx.Commit(); commitError != nil {
+		log.Printf("Error committing postponement of event %s: %v", eventID, commitError)
+
+		return nil, ErrDatabase
+	}
+
+	return service.eventWithTickets(ctx, postponedEvent), nil
+}
+
+// RequestRefund refunds the tickets the user bought for a postponed event while its refund window is open, the tickets
+// go back on sale and to the waitlist. Tickets for other events paid with the same payment stay paid.
+func (service *Service) RequestRefund(ctx context.Context, eventID, userID uuid.UUID, userEmail string) (*RefundSummary, error) {
+	getEvent, getEventByIdError := service.DBQueries.GetEventById(ctx, eventID)
+
+	if getEventByIdError == sql.ErrNoRows {
+		return nil, ErrEventNotFound
+	}
+
+	if getEventByIdError != nil {
+		return nil, ErrDatabase
+	}
+
+	// Cancelled events were already refunded by the organizer.
+	if getEvent.Status == EventStatusCancelled || !getEvent.RefundDeadline.Valid || time.Now().After(getEvent.RefundDeadline.Time) {
+		return nil, ErrRefundWindowClosed
+	}
+
+	eventFailedRefundOrCancels, failedNotificationEmails, refundCancelPaymentErrors := service.eventRefundOrCancelPayment(
+		ctx,
+		eventID,
+		getEvent.UserID,
+		uuid.NullUUID{UUID: userID, Valid: true},
+		userEmail,
+		"refund requested after event postponement",
+		fmt.Sprintf("As requested, your tickets for the postponed event: %s were cancelled and your payment was refunded. If you didn't pay yet, the pending payment is now cancelled.", getEvent.Title),
+		mailer.LoadBranding(ctx, &service.DBQueries, getEvent.Organizer.String, getEvent.UserID),
+		true,
+	)
+
+	if refundCancelPaymentErrors != nil {
+		log.Printf("Error during refund/cancellation process: %v", refundCancelPaymentErrors)
+
+		return nil, ErrDatabase
+	}
+
+	return &RefundSummary{
+		EventFailedRefundOrCancels: eventFailedRefundOrCancels,
+		FailedNotificationEmails:   failedNotificationEmails,
+	}, nil
+}
+
+// changeEventStatus only updates the event if nobody changed its status since it was read.
+func changeEventStatus(ctx context.Context, dbQueries *database.Queries, event database.Event, newStatus string, refundDeadline sql.NullTime) (database.Event, error) {
+	if !slices.Contains(eventStatusTransitions[event.Status], newStatus) {
+		return database.Event{}, fmt.Errorf("%w: %s event can't be %s", ErrEventStatusTransition, event.Status, newStatus)
+	}
+
+	updatedEvent, updateEventStatusError := dbQueries.UpdateEventStatus(ctx, database.UpdateEventStatusParams{
+		NewStatus:      newStatus,
+		RefundDeadline: refundDeadline,
+		ID:             event.ID,
+		UserID:         event.UserID,
+		CurrentStatus:  event.Status,
+	})
+
+	if updateEventStatusError == sql.ErrNoRows {
+		return database.Event{}, fmt.Errorf("%w: the event status was changed in the meantime", ErrEventStatusTransition)
+	}
+
+	if updateEventStatusError != nil {
+		log.Printf("Error changing status of event %s to %s: %v", event.ID, newStatus, updateEventStatusError)
+
+		return database.Event{}, ErrDatabase
+	}
+
+	return updatedEvent, nil
+}
+
+// enqueueEventPostponedNotifications tells the attendees the new show dates, dbQueries are the queries of the
+// postponement's transaction so the emails are only sent if it commits.
+func (service *Service) enqueueEventPostponedNotifications(ctx context.Context, dbQueries *database.Queries, event database.Event) error {
+	eventConfirmedUserReservations, getEventConfirmedUserReservationsError := dbQueries.GetEventConfirmedUserReservations(ctx, event.ID)
+
+	if getEventConfirmedUserReservationsError != nil {
+		return fmt.Errorf("failed to get users reserved for the event: %w", getEventConfirmedUserReservationsError)
+	}
+
+	if len(eventConfirmedUserReservations) == 0 {
+		return nil
+	}
+
+	eventDetails, getEventDetailsError := dbQueries.GetEventDetailsByEventId(ctx, []uuid.UUID{event.ID})
+
+	if getEventDetailsError != nil {
+		return fmt.Errorf("failed to get event details: %w", getEventDetailsError)
+	}
+
+	newShowDates := ""
+
+	for _, eventDetail := range eventDetails {
+		newShowDates += fmt.Sprintf(`%s - %s
+`, eventDetail.TicketDescription, eventDetail.ShowDate.Format("2006-01-02 15:04"))
+	}
+
+	branding := mailer.LoadBranding(ctx, dbQueries, event.Organizer.String, event.UserID)
+	postponedEventMailer := service.Mailer.WithQueries(dbQueries)
+
+	for _, reservation := range eventConfirmedUserReservations {
+		sendEventPostponedNotificationError := postponedEventMailer.SendEventPostponedNotification(
+			reservation.Fullname.String,
+			reservation.Email,
+			event.Title,
+			newShowDates,
+			event.RefundDeadline.Time,
+			branding,
+		)
+
+		if sendEventPostponedNotificationError != nil {
+			return fmt.Errorf("failed to enqueue event postponed notification to %s: %w", reservation.Email, sendEventPostponedNotificationError)
+		}
+	}
+
+	return nil
+}
+
+func (service *Service) SearchEvents(ctx context.Context, req SearchEventsRequest) (*SearchEventsResponse, error) {
+	searchQuery := strings.TrimSpace(req.Search)
+	sortBy := strings.ToLower(strings.TrimSpace(req.Sort))
+
+	// Searches are ordered by how well they match unless another order was asked for.
+	if sortBy == "" && searchQuery != "" {
+		sortBy = SortByRelevance
+	} else if sortBy == "" {
+		sortBy = SortByShowDate
+	}
+
+	if !slices.Contains(searchEventSorts, sortBy) {
+		return nil, fmt.Errorf("invalid sort, must be one of: %s", strings.Join(searchEventSorts, ", "))
+	}
+
+	if sortBy == SortByRelevance && searchQuery == "" {
+		return nil, errors.New("invalid sort, relevance requires a search term")
+	}
+
+	limit, parseLimitError := pagination.ParseLimit(req.Limit, 20, 100)
+
+	if parseLimitError != nil {
+		return nil, errors.New("invalid limit, must be between 1 and 100")
+	}
+
+	organizerQuery := ""
+
+	if strings.TrimSpace(req.Organizer) != "" {
+		organizerQuery = fmt.Sprintf("%s%s%s", "%", strings.ToLower(strings.TrimSpace(req.Organizer)), "%")
+	}
+
+	startShowDate, endShowDate, parseShowDateRangeError := parseShowDateRange(req.StartShowDate, req.EndShowDate)
+
+	if parseShowDateRangeError != nil {
+		return nil, parseShowDateRangeError
+	}
+
+	currency := ""
+
+	if strings.TrimSpace(req.Currency) != "" {
+		if validateCurrencyError := money.ValidateCurrency(req.Currency); validateCurrencyError != nil {
+			return nil, validateCurrencyError
+		}
+
+		currency = money.NormalizeCurrency(req.Currency)
+	}
+
+	minPrice, parseMinPriceError := parsePriceFilter(req.MinPrice, "min_price")
+
+	if parseMinPriceError != nil {
+		return nil, parseMinPriceError
+	}
+
+	maxPrice, parseMaxPriceError := parsePriceFilter(req.MaxPrice, "max_price")
+
+	if parseMaxPriceError != nil {
+		return nil, parseMaxPriceError
+	}
+
+	if minPrice.Valid && maxPrice.Valid {
+		if minPrice.Int64 > maxPrice.Int64 {
+			return nil, errors.New("invalid price range, min_price must not be greater than max_price")
+		}
+	}
+
+	hasTicketsRemaining, parseHasTicketsRemainingError := parseBoolFilter(req.HasTicketsRemaining, "has_tickets_remaining")
+
+	if parseHasTicketsRemainingError != nil {
+		return nil, parseHasTicketsRemainingError
+	}
+
+	freeOnly, parseFreeOnlyError := parseBoolFilter(req.FreeOnly, "free_only")
+
+	if parseFreeOnlyError != nil {
+		return nil, parseFreeOnlyError
+	}
+
+	// Prices are compared at the current early price and ticket types that can't be bought are left out, like for
+	// visitors.
+	countEventsParams := database.CountEventsParams{
+		CurrentDateTime: time.Now().UTC(),
+		Search:          searchQuery,
+		Organizer:       organizerQuery,
+		Currency:        currency,
+		StartShowDate:   startShowDate,
+		// Without an end date every upcoming show matches, the page size keeps the response small.
+		EndShowDate:         sql.NullTime{Time: endShowDate, Valid: strings.TrimSpace(req.EndShowDate) != ""},
+		MinPrice:            minPrice,
+		MaxPrice:            maxPrice,
+		HasTicketsRemaining: hasTicketsRemaining,
+		FreeOnly:            freeOnly,
+	}
+
+	getEventsParams := database.GetEventsParams{
+		Search:              countEventsParams.Search,
+		CurrentDateTime:     countEventsParams.CurrentDateTime,
+		Organizer:           countEventsParams.Organizer,
+		Currency:            countEventsParams.Currency,
+		StartShowDate:       countEventsParams.StartShowDate,
+		EndShowDate:         countEventsParams.EndShowDate,
+		MinPrice:            countEventsParams.MinPrice,
+		MaxPrice:            countEventsParams.MaxPrice,
+		HasTicketsRemaining: countEventsParams.HasTicketsRemaining,
+		FreeOnly:            countEventsParams.FreeOnly,
+		SortBy:              sortBy,
+		CursorPrice:         0,
+		// One extra row tells whether there is a next page.
+		RowLimit: limit + 1,
+	}
+
+	if strings.TrimSpace(req.Cursor) != "" {
+		if applyCursorError := applySearchEventsCursor(&getEventsParams, req.Cursor); applyCursorError != nil {
+			return nil, applyCursorError
+		}
+	}
+
+	getSearchEvents, getEventsError := service.DBQueries.GetEvents(ctx, getEventsParams)
+
+	if getEventsError != nil {
+		log.Printf("Error searching events: %v", getEventsError)
+
+		return nil, ErrDatabase
+	}
+
+	totalCount, countEventsError := service.DBQueries.CountEvents(ctx, countEventsParams)
+
+	if countEventsError != nil {
+		log.Printf("Error counting events: %v", countEventsError)
+
+		return nil, ErrDatabase
+	}
+
+	var nextCursor *string
+
+	if len(getSearchEvents) > int(limit) {
+		getSearchEvents = getSearchEvents[:limit]
+		encodedCursor := pagination.EncodeCursor(searchEventsCursor(sortBy, getSearchEvents[len(getSearchEvents)-1]))
+		nextCursor = &encodedCursor
+	}
+
+	return &SearchEventsResponse{
+		Events:     databaseSearchEventsToSearchEventsResponse(getSearchEvents),
+		NextCursor: nextCursor,
+		TotalCount: totalCount,
+	}, nil
+}
+
+// GetPublicEvents lists upcoming shows for anonymous visitors, one entry per event with its shows ordered by date.
+func (service *Service) GetPublicEvents(ctx context.Context, searchQuery, startShowDateQuery, endShowDateQuery string) ([]PublicEventResponse, error) {
+	startShowDate, endShowDate, parseShowDateRangeError := parseShowDateRange(startShowDateQuery, endShowDateQuery)
+
+	if parseShowDateRangeError != nil {
+		return nil, parseShowDateRangeError
+	}
 
-		waitGroup.Wait()
+	// Shows that already started can't be booked anymore.
+	if currentDateTime := time.Now().UTC(); startShowDate.Before(currentDateTime) {
+		startShowDate = currentDateTime
 	}
 
-	return databaseEventToDomain(updatedEvent), nil
-}
+	getPublicEvents, getPublicEventsError := service.DBQueries.GetPublicEvents(ctx, database.GetPublicEventsParams{
+		Search:          strings.TrimSpace(searchQuery),
+		StartShowDate:   startShowDate,
+		EndShowDate:     endShowDate,
+		CurrentDateTime: time.Now().UTC(),
+	})
 
-func (service *Service) Delete(ctx context.Context, eventID, ownerID uuid.UUID, userEmail string) (*DeleteSummary, error) {
-	eventFailedRefundOrCancels, failedNotificationEmails, refundCancelPaymentErrors := service.eventRefundOrCancelPayment(ctx, eventID, ownerID, userEmail)
+	if getPublicEventsError != nil {
+		log.Printf("Error retrieving public events: %v", getPublicEventsError)
 
-	if refundCancelPaymentErrors != nil {
-		log.Printf("Error during refund/cancellation process: %v", refundCancelPaymentErrors)
+		return nil, ErrDatabase
 	}
 
-	deleteEventParams := database.DeleteEventParams{
-		ID:     eventID,
-		UserID: ownerID,
-	}
+	return databasePublicEventsToPublicEventsResponse(getPublicEvents, time.Now().UTC()), nil
+}
 
-	deleteEventError := service.DBQueries.DeleteEvent(ctx, deleteEventParams)
+func (service *Service) GetPublicEventByID(ctx context.Context, eventID uuid.UUID) (*PublicEventResponse, error) {
+	getEvent, getEventByIdError := service.DBQueries.GetEventById(ctx, eventID)
 
-	if deleteEventError == sql.ErrNoRows {
+	// Drafts are only visible to their organizer.
+	if getEventByIdError == sql.ErrNoRows || (getEventByIdError == nil && getEvent.Status == EventStatusDraft) {
 		return nil, ErrEventNotFound
 	}
-	if deleteEventError != nil {
-		log.Printf("Error deleting event %s: %v", eventID, deleteEventError)
+
+	if getEventByIdError != nil {
 		return nil, ErrDatabase
 	}
 
-	return &DeleteSummary{
-		EventFailedRefundOrCancels: eventFailedRefundOrCancels,
-		FailedNotificationEmails:   failedNotificationEmails,
-	}, nil
-}
+	currentDateTime := time.Now().UTC()
 
-func (service *Service) SearchEvents(ctx context.Context, searchQuery, startShowDateQuery, endShowDateQuery string) ([]SearchEventResponse, error) {
-	if strings.TrimSpace(searchQuery) == "" {
-		searchQuery = "%%"
-	} else {
-		searchQuery = fmt.Sprintf("%s%s%s", "%", strings.ToLower(searchQuery), "%")
+	upcomingEventDetails, getEventDetailsError := service.DBQueries.GetUpcomingEventDetailsByEventId(ctx, database.GetUpcomingEventDetailsByEventIdParams{
+		EventID:  getEvent.ID,
+		ShowDate: currentDateTime,
+	})
+
+	if getEventDetailsError != nil {
+		log.Printf("Error retrieving upcoming event details of event %s: %v", eventID, getEventDetailsError)
+
+		return nil, ErrDatabase
+	}
+
+	publicEvent := PublicEventResponse{
+		ID:          getEvent.ID,
+		Title:       getEvent.Title,
+		Description: getEvent.Description,
+		Organizer:   getEvent.Organizer.String,
+		Status:      getEvent.Status,
+		Tickets:     make([]PublicTicketResponse, len(upcomingEventDetails)),
+	}
+
+	for i, upcomingEventDetail := range upcomingEventDetails {
+		publicEvent.Tickets[i] = newPublicTicketResponse(upcomingEventDetail, getEvent.Currency, currentDateTime)
 	}
 
+	return &publicEvent, nil
+}
+
+// parseShowDateRange defaults to today until the end of the current month.
+func parseShowDateRange(startShowDateQuery, endShowDateQuery string) (time.Time, time.Time, error) {
 	currentDateTime := time.Now().UTC()
 
 	var startShowDate time.Time
@@ -248,7 +868,7 @@ func (service *Service) SearchEvents(ctx context.Context, searchQuery, startShow
 		parsedShowDate, _, parseShowDateError := convert.StringToTime(startShowDateQuery)
 
 		if parseShowDateError != nil {
-			return nil, errors.New("invalid start show date format")
+			return time.Time{}, time.Time{}, errors.New("invalid start show date format")
 		}
 
 		startShowDate = parsedShowDate
@@ -264,29 +884,147 @@ func (service *Service) SearchEvents(ctx context.Context, searchQuery, startShow
 		parsedEndDate, _, parseEndDateError := convert.StringToTime(endShowDateQuery)
 
 		if parseEndDateError != nil {
-			return nil, errors.New("invalid end show date format")
+			return time.Time{}, time.Time{}, errors.New("invalid end show date format")
 		}
 
 		endShowDate = parsedEndDate
 	}
 
-	getEventsParam := database.GetEventsParams{
-		Title:       searchQuery,
-		Description: searchQuery,
-		Organizer:   sqlutil.StringToNullString(searchQuery),
-		ShowDate:    startShowDate,
-		ShowDate_2:  endShowDate,
+	return startShowDate, endShowDate, nil
+}
+
+// GetBranding returns the branding of the organizer's emails, organizers without one get the empty default.
+func (service *Service) GetBranding(ctx context.Context, ownerID uuid.UUID) (*BrandingResponse, error) {
+	organizerBranding, getOrganizerBrandingError := service.DBQueries.GetOrganizerBranding(ctx, ownerID)
+
+	if getOrganizerBrandingError != nil {
+		if errors.Is(getOrganizerBrandingError, sql.ErrNoRows) {
+			return &BrandingResponse{}, nil
+		}
+
+		log.Printf("Error getting branding of organizer %s: %v", ownerID, getOrganizerBrandingError)
+
+		return nil, ErrDatabase
+	}
+
+	return databaseBrandingToResponse(organizerBranding), nil
+}
+
+func (service *Service) UpdateBranding(ctx context.Context, ownerID uuid.UUID, updateBrandingRequest UpdateBrandingRequest) (*BrandingResponse, error) {
+	logoURL := strings.TrimSpace(updateBrandingRequest.LogoURL)
+	accentColor := strings.TrimSpace(updateBrandingRequest.AccentColor)
+	footer := strings.TrimSpace(updateBrandingRequest.Footer)
+
+	if validateBrandingError := validateBranding(logoURL, accentColor, footer); validateBrandingError != nil {
+		return nil, validateBrandingError
 	}
 
-	getSearchEvents, getEventsError := service.DBQueries.GetEvents(ctx, getEventsParam)
+	organizerBranding, upsertOrganizerBrandingError := service.DBQueries.UpsertOrganizerBranding(ctx, database.UpsertOrganizerBrandingParams{
+		LogoUrl:     sqlutil.StringToNullString(logoURL),
+		AccentColor: sqlutil.StringToNullString(accentColor),
+		Footer:      sqlutil.StringToNullString(footer),
+		UserID:      ownerID,
+	})
+
+	if upsertOrganizerBrandingError != nil {
+		log.Printf("Error updating branding of organizer %s: %v", ownerID, upsertOrganizerBrandingError)
 
-	if getEventsError != nil {
-		log.Printf("Error searching events: %v", getEventsError)
+		return nil, ErrDatabase
+	}
+
+	return databaseBrandingToResponse(organizerBranding), nil
+}
+
+func validateBranding(logoURL string, accentColor string, footer string) error {
+	if logoURL != "" {
+		parsedLogoURL, parseLogoURLError := url.Parse(logoURL)
+
+		if parseLogoURLError != nil || (parsedLogoURL.Scheme != "http" && parsedLogoURL.Scheme != "https") || parsedLogoURL.Host == "" {
+			return fmt.Errorf("%w: logo_url must be an absolute http or https URL", ErrInvalidBranding)
+		}
+	}
+
+	if accentColor != "" && !accentColorPattern.MatchString(accentColor) {
+		return fmt.Errorf("%w: accent_color must be a hex color like #4f46e5", ErrInvalidBranding)
+	}
+
+	if len([]rune(footer)) > maxBrandingFooterLength {
+		return fmt.Errorf("%w: footer can't be longer than %d characters", ErrInvalidBranding, maxBrandingFooterLength)
+	}
+
+	return nil
+}
+
+func databaseBrandingToResponse(organizerBranding database.OrganizerBranding) *BrandingResponse {
+	var updatedAt *time.Time
+
+	if organizerBranding.UpdatedAt.Valid {
+		updatedAt = &organizerBranding.UpdatedAt.Time
+	}
+
+	return &BrandingResponse{
+		LogoURL:     organizerBranding.LogoUrl.String,
+		AccentColor: organizerBranding.AccentColor.String,
+		Footer:      organizerBranding.Footer.String,
+		UpdatedAt:   updatedAt,
+	}
+}
+
+func (service *Service) GetReminders(ctx context.Context, eventID, ownerID uuid.UUID) (*RemindersResponse, error) {
+	getUserEvent, getUserEventError := service.getUserEvent(ctx, eventID, ownerID)
+
+	if getUserEventError != nil {
+		return nil, getUserEventError
+	}
+
+	return &RemindersResponse{OffsetMinutes: getUserEvent.ReminderOffsetMinutes}, nil
+}
+
+// UpdateReminders replaces the reminder offsets of the event. Reminders that were already sent aren't sent again.
+func (service *Service) UpdateReminders(ctx context.Context, eventID, ownerID uuid.UUID, updateRemindersRequest UpdateRemindersRequest) (*RemindersResponse, error) {
+	if validateRemindersError := validateReminderOffsets(updateRemindersRequest.OffsetMinutes); validateRemindersError != nil {
+		return nil, validateRemindersError
+	}
+
+	offsetMinutes := slices.Clone(updateRemindersRequest.OffsetMinutes)
+	slices.Sort(offsetMinutes)
+	slices.Reverse(offsetMinutes)
+
+	updatedEvent, updateRemindersError := service.DBQueries.UpdateEventReminderOffsets(ctx, database.UpdateEventReminderOffsetsParams{
+		ReminderOffsetMinutes: offsetMinutes,
+		ID:                    eventID,
+		UserID:                ownerID,
+	})
+
+	if updateRemindersError == sql.ErrNoRows {
+		return nil, ErrEventNotFound
+	}
+
+	if updateRemindersError != nil {
+		log.Printf("Error updating reminders of event %s: %v", eventID, updateRemindersError)
 
 		return nil, ErrDatabase
 	}
 
-	return databaseSearchEventsToSearchEventsResponse(getSearchEvents), nil
+	return &RemindersResponse{OffsetMinutes: updatedEvent.ReminderOffsetMinutes}, nil
+}
+
+func validateReminderOffsets(offsetMinutes []int32) error {
+	if len(offsetMinutes) > maxReminderOffsets {
+		return fmt.Errorf("%w: an event can have at most %d reminders", ErrInvalidReminders, maxReminderOffsets)
+	}
+
+	for i, offset := range offsetMinutes {
+		if offset < 1 || offset > maxReminderOffsetMinutes {
+			return fmt.Errorf("%w: offset_minutes must be between 1 and %d", ErrInvalidReminders, maxReminderOffsetMinutes)
+		}
+
+		if slices.Contains(offsetMinutes[:i], offset) {
+			return fmt.Errorf("%w: offset_minutes can't contain %d twice", ErrInvalidReminders, offset)
+		}
+	}
+
+	return nil
 }
 
 func databaseEventToDomain(databaseEvent database.Event) *Event {
@@ -296,56 +1034,243 @@ func databaseEventToDomain(databaseEvent database.Event) *Event {
 		updatedAt = &databaseEvent.UpdatedAt.Time
 	}
 
+	var refundDeadline *time.Time
+
+	if databaseEvent.RefundDeadline.Valid {
+		refundDeadline = &databaseEvent.RefundDeadline.Time
+	}
+
 	return &Event{
-		ID:          databaseEvent.ID,
-		UserID:      databaseEvent.UserID,
-		Title:       databaseEvent.Title,
-		Description: databaseEvent.Description,
-		Organizer:   databaseEvent.Organizer.String,
-		CreatedAt:   databaseEvent.CreatedAt,
-		UpdatedAt:   updatedAt,
+		ID:             databaseEvent.ID,
+		UserID:         databaseEvent.UserID,
+		Title:          databaseEvent.Title,
+		Description:    databaseEvent.Description,
+		Organizer:      databaseEvent.Organizer.String,
+		Status:         databaseEvent.Status,
+		Currency:       databaseEvent.Currency,
+		RefundDeadline: refundDeadline,
+		CreatedAt:      databaseEvent.CreatedAt,
+		UpdatedAt:      updatedAt,
 	}
 }
 
-func databaseEventDetailToEventDetailJSON(detail database.EventDetail) event_details.EventDetail {
-	priceFloat, _ := convert.StringToFloat32(detail.Price)
-
-	return event_details.EventDetail{
+func databaseEventDetailToEventDetailJSON(detail database.EventDetail, currency string) event_details.EventDetail {
+	eventDetail := event_details.EventDetail{
 		ID:                detail.ID,
 		ShowDate:          detail.ShowDate,
-		Price:             priceFloat,
+		Price:             money.New(detail.Price, currency),
 		NumberOfTickets:   detail.NumberOfTickets,
 		TicketDescription: detail.TicketDescription,
+		PriceSchedule:     event_details.DatabasePriceScheduleToPriceSchedule(detail.PriceSchedule, currency),
 		CreatedAt:         detail.CreatedAt,
 		UpdatedAt:         sqlutil.NullTimeToString(detail.UpdatedAt),
 		EventID:           detail.EventID,
 	}
+
+	if detail.SalesStartAt.Valid {
+		eventDetail.SalesStartAt = &detail.SalesStartAt.Time
+	}
+
+	if detail.SalesEndAt.Valid {
+		eventDetail.SalesEndAt = &detail.SalesEndAt.Time
+	}
+
+	if detail.UnlocksAfterEventDetailID.Valid {
+		eventDetail.UnlocksAfterEventDetailID = &detail.UnlocksAfterEventDetailID.UUID
+	}
+
+	return eventDetail
 }
 
 func databaseSearchEventsToSearchEventsResponse(databaseSearchEvents []database.GetEventsRow) []SearchEventResponse {
 	searchEvents := make([]SearchEventResponse, len(databaseSearchEvents))
 
 	for i, databaseSearchEvent := range databaseSearchEvents {
-
-		price, _ := convert.StringToFloat32(databaseSearchEvent.Price.String)
-
 		searchEvents[i] = SearchEventResponse{
-			EventID:           databaseSearchEvent.EventID,
-			Title:             databaseSearchEvent.Title,
-			Description:       databaseSearchEvent.Description,
-			Organizer:         databaseSearchEvent.Organizer.String,
-			EventDetailID:     databaseSearchEvent.EventDetailID.UUID,
-			ShowDate:          databaseSearchEvent.ShowDate.Time,
-			Price:             price,
-			NumberOfTickets:   databaseSearchEvent.NumberOfTickets.Int32,
-			TicketDescription: databaseSearchEvent.TicketDescription.String,
+			EventID:            databaseSearchEvent.EventID,
+			Title:              databaseSearchEvent.Title,
+			Description:        databaseSearchEvent.Description,
+			Organizer:          databaseSearchEvent.Organizer.String,
+			Status:             databaseSearchEvent.Status,
+			EventDetailID:      databaseSearchEvent.EventDetailID,
+			ShowDate:           databaseSearchEvent.ShowDate,
+			Price:              money.New(databaseSearchEvent.Price, databaseSearchEvent.Currency),
+			NumberOfTickets:    databaseSearchEvent.NumberOfTickets,
+			TicketsRemaining:   databaseSearchEvent.TicketsRemaining,
+			TicketDescription:  databaseSearchEvent.TicketDescription,
+			Rank:               databaseSearchEvent.Rank,
+			TitleHighlight:     databaseSearchEvent.TitleHighlight,
+			DescriptionSnippet: databaseSearchEvent.DescriptionSnippet,
 		}
 	}
 
 	return searchEvents
 }
 
-func (service *Service) saveEventTickets(ctx context.Context, eventId uuid.UUID, tickets []event_details.EventDetailParameters) ([]event_details.EventDetail, error) {
+// searchEventsCursor stores the sort column of the last row so the next page continues right after it.
+func searchEventsCursor(sortBy string, lastSearchEvent database.GetEventsRow) pagination.Cursor {
+	cursor := pagination.Cursor{Sort: sortBy, ID: lastSearchEvent.EventDetailID}
+
+	switch sortBy {
+	case SortByPrice, SortByPriceDesc:
+		cursor.Value = strconv.FormatInt(lastSearchEvent.Price, 10)
+	case SortByNewest:
+		cursor.Value = lastSearchEvent.CreatedAt.Format(time.RFC3339Nano)
+	case SortByRelevance:
+		cursor.Value = strconv.FormatFloat(float64(lastSearchEvent.Rank), 'g', -1, 32)
+	default:
+		cursor.Value = lastSearchEvent.ShowDate.Format(time.RFC3339Nano)
+	}
+
+	return cursor
+}
+
+func applySearchEventsCursor(getEventsParams *database.GetEventsParams, encodedCursor string) error {
+	cursor, decodeCursorError := pagination.DecodeCursor(encodedCursor, getEventsParams.SortBy)
+
+	if decodeCursorError != nil {
+		return decodeCursorError
+	}
+
+	switch getEventsParams.SortBy {
+	case SortByPrice, SortByPriceDesc:
+		price, parsePriceError := strconv.ParseInt(cursor.Value, 10, 64)
+
+		if parsePriceError != nil {
+			return pagination.ErrInvalidCursor
+		}
+
+		getEventsParams.CursorPrice = price
+	case SortByNewest:
+		createdAt, parseCreatedAtError := time.Parse(time.RFC3339Nano, cursor.Value)
+
+		if parseCreatedAtError != nil {
+			return pagination.ErrInvalidCursor
+		}
+
+		getEventsParams.CursorCreatedAt = createdAt
+	case SortByRelevance:
+		rank, parseRankError := strconv.ParseFloat(cursor.Value, 32)
+
+		if parseRankError != nil {
+			return pagination.ErrInvalidCursor
+		}
+
+		getEventsParams.CursorRank = float32(rank)
+	default:
+		showDate, parseShowDateError := time.Parse(time.RFC3339Nano, cursor.Value)
+
+		if parseShowDateError != nil {
+			return pagination.ErrInvalidCursor
+		}
+
+		getEventsParams.CursorShowDate = showDate
+	}
+
+	getEventsParams.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+
+	return nil
+}
+
+// parsePriceFilter reads a price in minor units, like the prices of ticket types.
+func parsePriceFilter(priceQuery string, filterName string) (sql.NullInt64, error) {
+	if strings.TrimSpace(priceQuery) == "" {
+		return sql.NullInt64{}, nil
+	}
+
+	price, parsePriceError := strconv.ParseInt(strings.TrimSpace(priceQuery), 10, 64)
+
+	if parsePriceError != nil || price < 0 {
+		return sql.NullInt64{}, fmt.Errorf("invalid %s, must be a whole number zero or greater", filterName)
+	}
+
+	return sql.NullInt64{Int64: price, Valid: true}, nil
+}
+
+func parseBoolFilter(boolQuery string, filterName string) (bool, error) {
+	if strings.TrimSpace(boolQuery) == "" {
+		return false, nil
+	}
+
+	parsedBool, parseBoolError := strconv.ParseBool(strings.TrimSpace(boolQuery))
+
+	if parseBoolError != nil {
+		return false, fmt.Errorf("invalid %s, must be true or false", filterName)
+	}
+
+	return parsedBool, nil
+}
+
+func databasePublicEventsToPublicEventsResponse(databasePublicEvents []database.GetPublicEventsRow, currentDateTime time.Time) []PublicEventResponse {
+	publicEvents := []PublicEventResponse{}
+	eventIndexes := make(map[uuid.UUID]int)
+
+	for _, databasePublicEvent := range databasePublicEvents {
+		eventIndex, exists := eventIndexes[databasePublicEvent.EventID]
+
+		if !exists {
+			eventIndex = len(publicEvents)
+			eventIndexes[databasePublicEvent.EventID] = eventIndex
+
+			publicEvents = append(publicEvents, PublicEventResponse{
+				ID:          databasePublicEvent.EventID,
+				Title:       databasePublicEvent.Title,
+				Description: databasePublicEvent.Description,
+				Organizer:   databasePublicEvent.Organizer.String,
+				Status:      databasePublicEvent.Status,
+				Tickets:     []PublicTicketResponse{},
+			})
+		}
+
+		publicEvents[eventIndex].Tickets = append(publicEvents[eventIndex].Tickets, newPublicTicketResponse(database.EventDetail{
+			ID:                databasePublicEvent.EventDetailID,
+			ShowDate:          databasePublicEvent.ShowDate,
+			Price:             databasePublicEvent.Price,
+			TicketsRemaining:  databasePublicEvent.TicketsRemaining,
+			TicketDescription: databasePublicEvent.TicketDescription,
+			SalesStartAt:      databasePublicEvent.SalesStartAt,
+			SalesEndAt:        databasePublicEvent.SalesEndAt,
+			PriceSchedule:     databasePublicEvent.PriceSchedule,
+		}, databasePublicEvent.Currency, currentDateTime))
+	}
+
+	return publicEvents
+}
+
+// newPublicTicketResponse shows the price in effect right now, e.g. the early-bird price and when it ends.
+func newPublicTicketResponse(eventDetail database.EventDetail, currency string, currentDateTime time.Time) PublicTicketResponse {
+	currentPrice, currentPriceEndsAt, currentPriceError := event_details.CurrentPrice(eventDetail.Price, eventDetail.PriceSchedule, currentDateTime)
+
+	if currentPriceError != nil {
+		log.Printf("Error reading price schedule of event detail %s: %v", eventDetail.ID, currentPriceError)
+
+		currentPrice = eventDetail.Price
+	}
+
+	publicTicket := PublicTicketResponse{
+		ID:                eventDetail.ID,
+		ShowDate:          eventDetail.ShowDate,
+		Price:             money.New(currentPrice, currency),
+		TicketsRemaining:  eventDetail.TicketsRemaining,
+		TicketDescription: eventDetail.TicketDescription,
+	}
+
+	if !currentPriceEndsAt.IsZero() {
+		publicTicket.PriceEndsAt = &currentPriceEndsAt
+	}
+
+	if eventDetail.SalesStartAt.Valid {
+		publicTicket.SalesStartAt = &eventDetail.SalesStartAt.Time
+	}
+
+	if eventDetail.SalesEndAt.Valid {
+		publicTicket.SalesEndAt = &eventDetail.SalesEndAt.Time
+	}
+
+	return publicTicket
+}
+
+func (service *Service) saveEventTickets(ctx context.Context, eventId uuid.UUID, currency string, tickets []event_details.EventDetailParameters) ([]event_details.EventDetail, error) {
 	var (
 		newTickets   []event_details.EventDetail
 		mutex        sync.Mutex
@@ -365,14 +1290,32 @@ func (service *Service) saveEventTickets(ctx context.Context, eventId uuid.UUID,
 				return
 			}
 
+			// Ticket types of a new event don't exist yet, so none can be waited for.
+			if tkt.UnlocksAfterEventDetailID != nil {
+				errorChannel <- fmt.Errorf("error creating %s: %w", tkt.TicketDescription, event_details.ErrInvalidUnlocksAfter)
+
+				return
+			}
+
+			ticketTier, parseTicketTierError := event_details.ParseTicketTier(tkt, showDate)
+
+			if parseTicketTierError != nil {
+				errorChannel <- fmt.Errorf("error creating %s: %w", tkt.TicketDescription, parseTicketTierError)
+
+				return
+			}
+
 			createEventDetailParams := database.CreateEventDetailParams{
 				ID:                uuid.New(),
 				ShowDate:          showDate,
-				Price:             fmt.Sprintf("%.2f", tkt.Price),
+				Price:             tkt.Price,
 				NumberOfTickets:   tkt.NumberOfTickets,
 				TicketsRemaining:  tkt.NumberOfTickets,
 				TicketDescription: tkt.TicketDescription,
 				EventID:           eventId,
+				SalesStartAt:      ticketTier.SalesStartAt,
+				SalesEndAt:        ticketTier.SalesEndAt,
+				PriceSchedule:     ticketTier.PriceSchedule,
 			}
 
 			newEventDetail, createEventDetailError := service.DBQueries.CreateEventDetail(ctx, createEventDetailParams)
@@ -384,7 +1327,7 @@ func (service *Service) saveEventTickets(ctx context.Context, eventId uuid.UUID,
 			}
 
 			mutex.Lock()
-			newTickets = append(newTickets, databaseEventDetailToEventDetailJSON(newEventDetail))
+			newTickets = append(newTickets, databaseEventDetailToEventDetailJSON(newEventDetail, currency))
 			mutex.Unlock()
 		})
 	}
@@ -407,10 +1350,14 @@ func (service *Service) saveEventTickets(ctx context.Context, eventId uuid.UUID,
 	return newTickets, nil
 }
 
-func (service *Service) eventRefundOrCancelPayment(ctx context.Context, eventId uuid.UUID, userId uuid.UUID, userEmail string) ([]EventFailedRefundOrCancel, []FailedNotificationEmail, error) {
+// eventRefundOrCancelPayment refunds every payment for the event, or only the payer's when payerUserId is set. With
+// releaseReservations the refunded reservations are removed and their tickets put back on sale, for events that still
+// take place.
+func (service *Service) eventRefundOrCancelPayment(ctx context.Context, eventId uuid.UUID, userId uuid.UUID, payerUserId uuid.NullUUID, userEmail string, reason string, notificationMessage string, branding mailer.Branding, releaseReservations bool) ([]EventFailedRefundOrCancel, []FailedNotificationEmail, error) {
 	getPaidEventForRefundParams := database.GetPaidEventForRefundParams{
-		EventID: eventId,
-		UserID:  userId,
+		EventID:     eventId,
+		UserID:      userId,
+		PayerUserID: payerUserId,
 	}
 
 	paidEventForRefunds, getRefundEventPaymentError := service.DBQueries.GetPaidEventForRefund(ctx, getPaidEventForRefundParams)
@@ -423,29 +1370,110 @@ func (service *Service) eventRefundOrCancelPayment(ctx context.Context, eventId
 		return nil, nil, nil
 	}
 
+	eventTitle := paidEventForRefunds[0].Title
+
+	// notifyPayer enqueues the refund or cancellation email of the payer with dbQueries.
+	notifyPayer := func(dbQueries *database.Queries, payerUserID uuid.UUID, amount money.Money) error {
+		user, getUserByIdError := dbQueries.GetUserById(ctx, payerUserID)
+
+		if getUserByIdError != nil {
+			return fmt.Errorf("failed to get user email of payer %s: %w", payerUserID, getUserByIdError)
+		}
+
+		recipientName := fmt.Sprintf("%s %s", user.Firstname, user.Lastname)
+
+		return service.Mailer.WithQueries(dbQueries).SendRefundOrCancelledEmail(
+			recipientName,
+			user.Email,
+			eventTitle,
+			notificationMessage,
+			amount,
+			branding,
+		)
+	}
+
+	// releasePaymentReservations puts the payment's tickets for the event back on sale with dbQueries, the queries of
+	// the transaction that saves the refund or cancellation.
+	releasePaymentReservations := func(dbQueries *database.Queries, paymentID uuid.UUID) ([]uuid.UUID, error) {
+		if !releaseReservations {
+			return nil, nil
+		}
+
+		releasedEventDetailIDs, releaseReservationsError := dbQueries.ReleaseEventPaymentReservations(ctx, database.ReleaseEventPaymentReservationsParams{
+			PaymentID: paymentID,
+			EventID:   eventId,
+		})
+
+		if releaseReservationsError != nil {
+			return nil, fmt.Errorf("failed to release reservations of payment %s: %w", paymentID, releaseReservationsError)
+		}
+
+		return releasedEventDetailIDs, nil
+	}
+
 	var (
-		PaymentIDs                 []uuid.UUID
 		mutex                      sync.Mutex
 		waitGroup                  sync.WaitGroup
 		eventFailedRefundOrCancels []EventFailedRefundOrCancel
+		failedNotificationEmails   = []FailedNotificationEmail{}
+		releasedEventDetailIDs     []uuid.UUID
 	)
 
+	addReleasedEventDetailIDs := func(eventDetailIDs []uuid.UUID) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		releasedEventDetailIDs = append(releasedEventDetailIDs, eventDetailIDs...)
+	}
+
+	addFailedNotificationEmail := func(notifyError error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		failedNotificationEmails = append(failedNotificationEmails, FailedNotificationEmail{
+			SendRefundCancelNotificationError: notifyError.Error(),
+		})
+	}
+
 	for _, paidEvent := range paidEventForRefunds {
 		paidEventForRefund := paidEvent
-		amount, _ := convert.PriceStringToCents(paidEventForRefund.Amount)
-		ticketPrice, _ := convert.PriceStringToCents(paidEventForRefund.TicketPrice)
+		amount := paidEventForRefund.Amount
+		ticketPrice := paidEventForRefund.TicketPrice
 		isErrorOccured := false
 
-		if paidEventForRefund.Status == "refunded" || paidEventForRefund.Status == "cancelled" {
+		paymentStatus := paymentstatus.Status(paidEventForRefund.Status)
+
+		if paymentStatus == paymentstatus.Refunded || paymentStatus == paymentstatus.RefundPending || paymentStatus == paymentstatus.Cancelled {
 			continue
 		}
 
+		// Free tickets involve no payment gateway action, the payer is only notified.
 		if amount == 0 {
-			PaymentIDs = append(PaymentIDs, paidEventForRefund.PaymentID)
-		} else {
-			if amount != ticketPrice {
-				amount = ticketPrice
+			var freeEventDetailIDs []uuid.UUID
+
+			notifyError := service.inTransaction(ctx, func(qtx *database.Queries) error {
+				var releaseReservationsError error
+
+				if freeEventDetailIDs, releaseReservationsError = releasePaymentReservations(qtx, paidEventForRefund.PaymentID); releaseReservationsError != nil {
+					return releaseReservationsError
+				}
+
+				return notifyPayer(qtx, paidEventForRefund.PayerUserID, money.New(0, paidEventForRefund.Currency))
+			})
+
+			if notifyError != nil {
+				addFailedNotificationEmail(notifyError)
+
+				continue
 			}
+
+			addReleasedEventDetailIDs(freeEventDetailIDs)
+
+			continue
+		}
+
+		if amount != ticketPrice {
+			amount = ticketPrice
 		}
 
 		waitGroup.Go(func() {
@@ -453,67 +1481,61 @@ func (service *Service) eventRefundOrCancelPayment(ctx context.Context, eventId
 			createPaymentLogParams := database.CreatePaymentLogParams{
 				ID:              uuid.New(),
 				PaymentIntentID: paidEventForRefund.PaymentIntentID.String,
-				Amount:          fmt.Sprintf("%.2f", float64(amount)/100.0),
+				Amount:          amount,
 				UserEmail:       userEmail,
 				PaymentID:       paidEventForRefund.PaymentID,
+				Currency:        paidEventForRefund.Currency,
 			}
 
 			updatePaymentParams := database.UpdatePaymentParams{
 				ID:              paidEventForRefund.PaymentID,
-				Amount:          fmt.Sprintf("%.2f", float64(amount)/100.0),
+				Amount:          amount,
 				PaymentIntentID: paidEventForRefund.PaymentIntentID,
 				UserID:          paidEventForRefund.PayerUserID,
+				CurrentStatus:   paidEventForRefund.Status,
 			}
 
-			if paidEventForRefund.Status == string(stripe.PaymentIntentStatusSucceeded) {
-				refundResult, refundError := service.Stripe.Refund(amount, paidEventForRefund.PaymentIntentID.String)
+			// Paid payments, and ones whose refund failed before, are refunded. Unpaid ones are cancelled.
+			if paymentStatus.CanTransitionTo(paymentstatus.RefundPending) {
+				refundResult, refundError := service.PaymentGateway.Refund(ctx, paymentgateway.RefundParams{
+					PaymentIntentID: paidEventForRefund.PaymentIntentID.String,
+					Amount:          amount,
+				})
 
 				if refundError != nil {
-					log.Printf("Stripe refund error: %v", refundError)
+					log.Printf("Payment gateway refund error: %v", refundError)
 
-					if stripeError, ok := refundError.(*stripe.Error); ok {
-						createPaymentLogParams.Status = string(stripeError.Code)
-						createPaymentLogParams.Description = sqlutil.StringToNullString(stripeError.Msg)
-
-						eventFailedRefundOrCancel.PaymentID = paidEventForRefund.PaymentID
-						eventFailedRefundOrCancel.Action = "stripe refund request"
-						eventFailedRefundOrCancel.Code = string(stripeError.Code)
-						eventFailedRefundOrCancel.Message = stripeError.Msg
-
-						isErrorOccured = true
-					}
+					eventFailedRefundOrCancel = newEventFailedRefundOrCancel(paidEventForRefund.PaymentID, "refund request", refundError)
+					createPaymentLogParams.Status = eventFailedRefundOrCancel.Code
+					createPaymentLogParams.Description = sqlutil.StringToNullString(eventFailedRefundOrCancel.Message)
+					isErrorOccured = true
 				} else {
 					createPaymentLogParams.Status = string(refundResult.Status)
 
 					switch refundResult.Status {
-						case stripe.RefundStatusFailed:
-							createPaymentLogParams.Description = sqlutil.StringToNullString(string(refundResult.FailureReason))
-						case stripe.RefundStatusPending:
+						case paymentgateway.RefundStatusFailed:
+							createPaymentLogParams.Description = sqlutil.StringToNullString(refundResult.FailureReason)
+							updatePaymentParams.Status = string(paymentstatus.RefundFailed)
+						case paymentgateway.RefundStatusPending:
 							createPaymentLogParams.Description = sqlutil.StringToNullString("refund pending")
-							updatePaymentParams.Status = "refund pending"
-						case stripe.RefundStatusSucceeded:
+							updatePaymentParams.Status = string(paymentstatus.RefundPending)
+						case paymentgateway.RefundStatusSucceeded:
 							createPaymentLogParams.Description = sqlutil.StringToNullString("refund succeeded")
-							updatePaymentParams.Status = "refunded"
+							updatePaymentParams.Status = string(paymentstatus.Refunded)
 					}
 				}
 			} else {
-				paymentIntentCancelError := service.Stripe.CancelPaymentIntent(paidEventForRefund.PaymentIntentID.String)
+				_, paymentIntentCancelError := service.PaymentGateway.CancelIntent(ctx, paidEventForRefund.PaymentIntentID.String)
 
 				if paymentIntentCancelError != nil {
-					if stripeError, ok := paymentIntentCancelError.(*stripe.Error); ok {
-						createPaymentLogParams.Status = string(stripeError.Code)
-						createPaymentLogParams.Description = sqlutil.StringToNullString(stripeError.Msg)
-
-						eventFailedRefundOrCancel.PaymentID = paidEventForRefund.PaymentID
-						eventFailedRefundOrCancel.Action = "stripe cancel request"
-						eventFailedRefundOrCancel.Code = string(stripeError.Code)
-						eventFailedRefundOrCancel.Message = stripeError.Msg
-						isErrorOccured = true
-					}
+					eventFailedRefundOrCancel = newEventFailedRefundOrCancel(paidEventForRefund.PaymentID, "cancel request", paymentIntentCancelError)
+					createPaymentLogParams.Status = eventFailedRefundOrCancel.Code
+					createPaymentLogParams.Description = sqlutil.StringToNullString(eventFailedRefundOrCancel.Message)
+					isErrorOccured = true
 				} else {
-					updatePaymentParams.Status = "cancelled"
-					createPaymentLogParams.Status = "cancelled"
-					createPaymentLogParams.Description = sqlutil.StringToNullString("event deleted")
+					updatePaymentParams.Status = string(paymentstatus.Cancelled)
+					createPaymentLogParams.Status = string(paymentstatus.Cancelled)
+					createPaymentLogParams.Description = sqlutil.StringToNullString(reason)
 				}
 			}
 
@@ -531,71 +1553,107 @@ func (service *Service) eventRefundOrCancelPayment(ctx context.Context, eventId
 				return
 			}
 
-			_, updatePaymentError := service.DBQueries.UpdatePayment(ctx, updatePaymentParams)
+			var paymentEventDetailIDs []uuid.UUID
+
+			// The refund or cancellation is saved with the released reservations and the email of the payer, payers are
+			// only told about changes that were saved. A payment that can't be saved after the gateway refunded it is
+			// reported and fixed by the refund webhook or the payment reconciliation.
+			updatePaymentError := service.inTransaction(ctx, func(qtx *database.Queries) error {
+				refundFailed := updatePaymentParams.Status == string(paymentstatus.RefundFailed)
+
+				// A failed refund is retried on the next request, which only finds the payment through its reservations.
+				if !refundFailed {
+					var releaseReservationsError error
+
+					if paymentEventDetailIDs, releaseReservationsError = releasePaymentReservations(qtx, paidEventForRefund.PaymentID); releaseReservationsError != nil {
+						return releaseReservationsError
+					}
+				}
+
+				// A payer's refund leaves the tickets of other events on the payment paid, only the refunded part comes
+				// off its amount like a partial refund.
+				if payerUserId.Valid && paymentStatus.CanTransitionTo(paymentstatus.RefundPending) {
+					otherEventReservations, countReservationsError := qtx.CountOtherEventPaymentReservations(ctx, database.CountOtherEventPaymentReservationsParams{
+						PaymentID: paidEventForRefund.PaymentID,
+						EventID:   eventId,
+					})
+
+					if countReservationsError != nil {
+						return fmt.Errorf("failed to count other reservations of payment %s: %w", paidEventForRefund.PaymentID, countReservationsError)
+					}
+
+					if otherEventReservations > 0 {
+						updatePaymentParams.Status = paidEventForRefund.Status
+						updatePaymentParams.Amount = paidEventForRefund.Amount
+
+						if !refundFailed {
+							updatePaymentParams.Amount -= amount
+						}
+					}
+				}
+
+				if _, updatePaymentError := paymentstatus.Update(ctx, qtx, updatePaymentParams); updatePaymentError != nil {
+					return fmt.Errorf("failed to update payment %s: %w", updatePaymentParams.ID, updatePaymentError)
+				}
+
+				if notifyPayerError := notifyPayer(qtx, paidEventForRefund.PayerUserID, money.New(amount, paidEventForRefund.Currency)); notifyPayerError != nil {
+					return fmt.Errorf("failed to notify payer of payment %s: %w", updatePaymentParams.ID, notifyPayerError)
+				}
+
+				return nil
+			})
 
 			if updatePaymentError != nil {
 				log.Printf("error: update payment - %s", updatePaymentError)
+				addFailedNotificationEmail(updatePaymentError)
+
+				return
 			}
 
-			mutex.Lock()
-			PaymentIDs = append(PaymentIDs, paidEventForRefund.PaymentID)
-			mutex.Unlock()
+			addReleasedEventDetailIDs(paymentEventDetailIDs)
 		})
 	}
 
 	waitGroup.Wait()
 
-	if len(PaymentIDs) == 0 {
-		return eventFailedRefundOrCancels, nil, nil
+	if len(releasedEventDetailIDs) > 0 {
+		service.Waitlist.OfferReleasedTickets(ctx, releasedEventDetailIDs)
 	}
 
-	payments, _ := service.DBQueries.GetMultiplePayments(ctx, PaymentIDs)
-
-	var (
-		sendRefundCanceWaitGroup          sync.WaitGroup
-		sendRefundCancelNotifErrorChannel = make(chan error, len(payments))
-	)
-
-	for _, pymnt := range payments {
-		payment := pymnt
-
-		sendRefundCanceWaitGroup.Go(func() { 
+	return eventFailedRefundOrCancels, failedNotificationEmails, nil
+}
 
-			user, getUserByIdError := service.DBQueries.GetUserById(ctx, payment.UserID)
+// newEventFailedRefundOrCancel reports a refund or cancellation the gateway didn't do, with the gateway's code and
+// message or with the error itself when the request failed before reaching the gateway.
+func newEventFailedRefundOrCancel(paymentID uuid.UUID, action string, actionError error) EventFailedRefundOrCancel {
+	eventFailedRefundOrCancel := EventFailedRefundOrCancel{
+		PaymentID: paymentID,
+		Action:    action,
+		Code:      "error",
+		Message:   actionError.Error(),
+	}
 
-			if getUserByIdError != nil {
-				sendRefundCancelNotifErrorChannel <- fmt.Errorf("failed to get user email for payment %s: %w", payment.ID, getUserByIdError)
-				return
-			}
+	if gatewayError, ok := paymentgateway.AsError(actionError); ok {
+		eventFailedRefundOrCancel.Code = gatewayError.Code
+		eventFailedRefundOrCancel.Message = gatewayError.Message
+	}
 
-			eventTitle := paidEventForRefunds[0].Title
-			recipientName := fmt.Sprintf("%s %s", user.Firstname, user.Lastname)
+	return eventFailedRefundOrCancel
+}
 
-			sendRefundCancelError := service.Mailer.SendRefundOrCancelledEmail(
-				recipientName,
-				user.Email,
-				eventTitle,
-				fmt.Sprintf("The event: %s, that you booked was cancelled and your payment was refunded. If you didn't pay yet, the pending payment is now cancelled.", eventTitle),
-			)
+// inTransaction runs fn with the queries of a transaction that is committed when fn returns nil.
+func (service *Service) inTransaction(ctx context.Context, fn func(qtx *database.Queries) error) error {
+	tx, beginTxError := service.DBConnection.BeginTx(ctx, nil)
 
-			if sendRefundCancelError != nil {
-				sendRefundCancelNotifErrorChannel <- sendRefundCancelError
-			}
-		})
+	if beginTxError != nil {
+		return fmt.Errorf("failed to start transaction: %w", beginTxError)
 	}
 
-	sendRefundCanceWaitGroup.Wait()
-	close(sendRefundCancelNotifErrorChannel)
+	defer tx.Rollback()
 
-	failedNotificationEmails := []FailedNotificationEmail{}
-
-	for errorMessage := range sendRefundCancelNotifErrorChannel {
-		if errorMessage != nil {
-			failedNotificationEmails = append(failedNotificationEmails, FailedNotificationEmail{
-				SendRefundCancelNotificationError: errorMessage.Error(),
-			})
-		}
+	if fnError := fn(service.DBQueries.WithTx(tx)); fnError != nil {
+		return fnError
 	}
 
-	return eventFailedRefundOrCancels, failedNotificationEmails, nil
+	return tx.Commit()
 }
\ No newline at end of file
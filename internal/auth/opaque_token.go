@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+const RefreshTokenTTL = 30 * 24 * time.Hour
+
+// GenerateOpaqueToken returns a random token for the client and the hash that is stored in the database.
+// It is used for refresh, email verification, password reset and waitlist claim tokens.
+func GenerateOpaqueToken() (string, string, error) {
+	tokenBytes := make([]byte, 32)
+
+	if _, readRandomError := rand.Read(tokenBytes); readRandomError != nil {
+		return "", "", readRandomError
+	}
+
+	opaqueToken := base64.RawURLEncoding.EncodeToString(tokenBytes)
+
+	return opaqueToken, HashOpaqueToken(opaqueToken), nil
+}
+
+func HashOpaqueToken(opaqueToken string) string {
+	tokenHash := sha256.Sum256([]byte(opaqueToken))
+
+	return hex.EncodeToString(tokenHash[:])
+}
\ No newline at end of file
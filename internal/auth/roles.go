@@ -0,0 +1,15 @@
+package auth
+
+import "slices"
+
+const (
+	RoleAdmin     = "admin"
+	RoleOrganizer = "organizer"
+	RoleAttendee  = "attendee"
+)
+
+var Roles = []string{RoleAdmin, RoleOrganizer, RoleAttendee}
+
+func IsValidRole(role string) bool {
+	return slices.Contains(Roles, role)
+}
\ No newline at end of file
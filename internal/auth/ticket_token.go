@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+)
+
+const ticketTokenType = "ticket"
+
+var ErrInvalidTicket = errors.New("invalid ticket")
+
+// TicketSigner signs reservation IDs with the same ECDSA key pair as access tokens.
+// Ticket tokens don't expire, whether a ticket can still be used is decided at check-in.
+type TicketSigner struct {
+	signingKey interface{}
+	publicKey  *ecdsa.PublicKey
+}
+
+func NewTicketSigner(signingKey interface{}, tokenValidator *TokenValidator) *TicketSigner {
+	return &TicketSigner{
+		signingKey: signingKey,
+		publicKey:  tokenValidator.publicKey,
+	}
+}
+
+func (ticketSigner *TicketSigner) Sign(reservationID uuid.UUID) (string, error) {
+	if ticketSigner.signingKey == nil {
+		return "", errors.New("ticket signing key is not configured")
+	}
+
+	ticketToken := jwt.NewWithClaims(
+		jwt.SigningMethodES256,
+		jwt.MapClaims{
+			"sub": reservationID.String(),
+			"typ": ticketTokenType,
+			"iss": TokenIssuer,
+			"iat": time.Now().Unix(),
+		})
+
+	return ticketToken.SignedString(ticketSigner.signingKey)
+}
+
+// Verify returns the reservation ID of a ticket token signed by Sign.
+func (ticketSigner *TicketSigner) Verify(signedTicket string) (uuid.UUID, error) {
+	parsedToken, parsedTokenError := jwt.Parse(signedTicket, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %s", token.Header["alg"])
+		}
+
+		return ticketSigner.publicKey, nil
+	})
+
+	if parsedTokenError != nil || !parsedToken.Valid {
+		return uuid.Nil, ErrInvalidTicket
+	}
+
+	claims, ok := parsedToken.Claims.(jwt.MapClaims)
+
+	if !ok || !claims.VerifyIssuer(TokenIssuer, true) {
+		return uuid.Nil, ErrInvalidTicket
+	}
+
+	// Access tokens are signed with the same key, the type claim keeps them from being scanned as tickets.
+	tokenType, _ := claims["typ"].(string)
+	subject, subjectOk := claims["sub"].(string)
+
+	if tokenType != ticketTokenType || !subjectOk {
+		return uuid.Nil, ErrInvalidTicket
+	}
+
+	reservationID, parseReservationIDError := uuid.Parse(subject)
+
+	if parseReservationIDError != nil {
+		return uuid.Nil, ErrInvalidTicket
+	}
+
+	return reservationID, nil
+}
\ No newline at end of file
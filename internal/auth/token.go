@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
 )
 
 var ErrInvalidToken = errors.New("invalid or expired token")
@@ -47,7 +49,15 @@ func NewTokenValidator(publicKeyPath string) (*TokenValidator, error) {
 	return &TokenValidator{publicKey: publicKey}, nil
 }
 
-func (tokenValidator *TokenValidator) ValidateAndGetEmailClaim(signedToken string) (string, error) {
+type AccessTokenClaims struct {
+	ID        string
+	UserID    uuid.UUID
+	Email     string
+	Role      string
+	ExpiresAt time.Time
+}
+
+func (tokenValidator *TokenValidator) ValidateAccessToken(signedToken string) (*AccessTokenClaims, error) {
 	parsedToken, parsedTokenError := jwt.Parse(signedToken, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %s", token.Header["alg"])
@@ -57,26 +67,39 @@ func (tokenValidator *TokenValidator) ValidateAndGetEmailClaim(signedToken strin
 	})
 
 	if parsedTokenError != nil || !parsedToken.Valid {
-		return "", ErrInvalidToken
+		return nil, ErrInvalidToken
 	}
 
 	claims, ok := parsedToken.Claims.(jwt.MapClaims)
-	
-	if !ok {
-		return "", ErrInvalidToken
+
+	if !ok || !claims.VerifyIssuer(TokenIssuer, true) {
+		return nil, ErrInvalidToken
 	}
-	
-	emailClaim, exists := claims["email"]
 
-	if !exists {
-		return "", ErrInvalidToken
+	tokenID, tokenIDOk := claims["jti"].(string)
+	subject, subjectOk := claims["sub"].(string)
+	email, emailOk := claims["email"].(string)
+	expiresAt, expiresAtOk := claims["exp"].(float64)
+
+	// The role claim is informational, authorization always uses the role stored on the user.
+	role, _ := claims["role"].(string)
+
+	// Tokens issued before jti and sub were added can't be revoked, so they are rejected.
+	if !tokenIDOk || !subjectOk || !emailOk || !expiresAtOk || tokenID == "" {
+		return nil, ErrInvalidToken
 	}
 
-	email, ok := emailClaim.(string)
+	userID, parseUserIDError := uuid.Parse(subject)
 
-	if !ok {
-		return "", ErrInvalidToken
+	if parseUserIDError != nil {
+		return nil, ErrInvalidToken
 	}
 
-	return email, nil
+	return &AccessTokenClaims{
+		ID:        tokenID,
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		ExpiresAt: time.Unix(int64(expiresAt), 0),
+	}, nil
 }
\ No newline at end of file
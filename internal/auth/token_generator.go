@@ -5,10 +5,22 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
 )
 
+const (
+	TokenIssuer    = "event-mrs"
+	AccessTokenTTL = time.Hour
+)
+
+type AccessToken struct {
+	Token     string
+	ID        string
+	ExpiresAt time.Time
+}
+
 type TokenGenerator interface {
-	Generate(email string) (string, error)
+	Generate(userID uuid.UUID, email string, role string) (AccessToken, error)
 }
 
 type TokenServiceImpl struct {
@@ -21,24 +33,36 @@ func NewTokenGenerator(signingKey interface{}) TokenGenerator {
 	}
 }
 
-func (tokenServiceImpl *TokenServiceImpl) Generate(email string) (string, error) {
+func (tokenServiceImpl *TokenServiceImpl) Generate(userID uuid.UUID, email string, role string) (AccessToken, error) {
 	if tokenServiceImpl.SigningKey == nil {
-		return "", errors.New("JWT signing key is not configured")
+		return AccessToken{}, errors.New("JWT signing key is not configured")
 	}
 
+	issuedAt := time.Now()
+	expiresAt := issuedAt.Add(AccessTokenTTL)
+	tokenID := uuid.NewString()
+
 	newAccessToken := jwt.NewWithClaims(
 		jwt.SigningMethodES256,
 		jwt.MapClaims{
+			"jti":   tokenID,
+			"sub":   userID.String(),
 			"email": email,
-			// Token expires in 1 hour
-			"exp": time.Now().Add(time.Hour * 1).Unix(),
+			"role":  role,
+			"iss":   TokenIssuer,
+			"iat":   issuedAt.Unix(),
+			"exp":   expiresAt.Unix(),
 		})
 
 	signedToken, signedTokenError := newAccessToken.SignedString(tokenServiceImpl.SigningKey)
 
 	if signedTokenError != nil {
-		return "", signedTokenError
+		return AccessToken{}, signedTokenError
 	}
 
-	return signedToken, nil
+	return AccessToken{
+		Token:     signedToken,
+		ID:        tokenID,
+		ExpiresAt: time.Unix(expiresAt.Unix(), 0),
+	}, nil
 }
\ No newline at end of file
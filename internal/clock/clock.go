@@ -0,0 +1,23 @@
+package clock
+
+import "time"
+
+// Clock lets background workers be driven by a fake time source in tests.
+type Clock interface {
+	Now() time.Time
+	After(duration time.Duration) <-chan time.Time
+}
+
+type RealClock struct{}
+
+func New() Clock {
+	return RealClock{}
+}
+
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+func (RealClock) After(duration time.Duration) <-chan time.Time {
+	return time.After(duration)
+}
\ No newline at end of file
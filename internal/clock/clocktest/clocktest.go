@@ -0,0 +1,84 @@
+// Package clocktest has the fake clock the background workers are tested with.
+package clocktest
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// FakeClock only moves forward when Advance is called.
+type FakeClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	waiters []fakeClockWaiter
+}
+
+type fakeClockWaiter struct {
+	deadline time.Time
+	channel  chan time.Time
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (fakeClock *FakeClock) Now() time.Time {
+	fakeClock.mutex.Lock()
+	defer fakeClock.mutex.Unlock()
+
+	return fakeClock.now
+}
+
+func (fakeClock *FakeClock) After(duration time.Duration) <-chan time.Time {
+	fakeClock.mutex.Lock()
+	defer fakeClock.mutex.Unlock()
+
+	channel := make(chan time.Time, 1)
+	fakeClock.waiters = append(fakeClock.waiters, fakeClockWaiter{deadline: fakeClock.now.Add(duration), channel: channel})
+
+	return channel
+}
+
+func (fakeClock *FakeClock) Advance(duration time.Duration) {
+	fakeClock.mutex.Lock()
+	defer fakeClock.mutex.Unlock()
+
+	fakeClock.now = fakeClock.now.Add(duration)
+
+	pendingWaiters := fakeClock.waiters[:0]
+
+	for _, waiter := range fakeClock.waiters {
+		if waiter.deadline.After(fakeClock.now) {
+			pendingWaiters = append(pendingWaiters, waiter)
+
+			continue
+		}
+
+		waiter.channel <- fakeClock.now
+	}
+
+	fakeClock.waiters = pendingWaiters
+}
+
+func (fakeClock *FakeClock) WaiterCount() int {
+	fakeClock.mutex.Lock()
+	defer fakeClock.mutex.Unlock()
+
+	return len(fakeClock.waiters)
+}
+
+// WaitForWaiter returns once a worker waits on the clock again, i.e. the run before finished.
+func WaitForWaiter(t testing.TB, fakeClock *FakeClock) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+
+	for fakeClock.WaiterCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Worker never waited on the clock")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -8,6 +8,7 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,19 +16,23 @@ import (
 )
 
 const createEventDetail = `-- name: CreateEventDetail :one
-INSERT INTO event_details (id, show_date, price, number_of_tickets, tickets_remaining, ticket_description, event_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING id, show_date, price, number_of_tickets, tickets_remaining, ticket_description, created_at, updated_at, event_id
+INSERT INTO event_details (id, show_date, price, number_of_tickets, tickets_remaining, ticket_description, event_id, sales_start_at, sales_end_at, price_schedule, unlocks_after_event_detail_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING id, show_date, price, number_of_tickets, tickets_remaining, ticket_description, created_at, updated_at, event_id, sales_start_at, sales_end_at, price_schedule, unlocks_after_event_detail_id
 `
 
 type CreateEventDetailParams struct {
-	ID                uuid.UUID
-	ShowDate          time.Time
-	Price             string
-	NumberOfTickets   int32
-	TicketsRemaining  int32
-	TicketDescription string
-	EventID           uuid.UUID
+	ID                        uuid.UUID
+	ShowDate                  time.Time
+	Price                     int64
+	NumberOfTickets           int32
+	TicketsRemaining          int32
+	TicketDescription         string
+	EventID                   uuid.UUID
+	SalesStartAt              sql.NullTime
+	SalesEndAt                sql.NullTime
+	PriceSchedule             json.RawMessage
+	UnlocksAfterEventDetailID uuid.NullUUID
 }
 
 func (q *Queries) CreateEventDetail(ctx context.Context, arg CreateEventDetailParams) (EventDetail, error) {
@@ -39,6 +44,10 @@ func (q *Queries) CreateEventDetail(ctx context.Context, arg CreateEventDetailPa
 		arg.TicketsRemaining,
 		arg.TicketDescription,
 		arg.EventID,
+		arg.SalesStartAt,
+		arg.SalesEndAt,
+		arg.PriceSchedule,
+		arg.UnlocksAfterEventDetailID,
 	)
 	var i EventDetail
 	err := row.Scan(
@@ -51,6 +60,10 @@ func (q *Queries) CreateEventDetail(ctx context.Context, arg CreateEventDetailPa
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.EventID,
+		&i.SalesStartAt,
+		&i.SalesEndAt,
+		&i.PriceSchedule,
+		&i.UnlocksAfterEventDetailID,
 	)
 	return i, err
 }
@@ -70,7 +83,7 @@ func (q *Queries) DeleteEventDetail(ctx context.Context, arg DeleteEventDetailPa
 }
 
 const getEventDetailsByEventId = `-- name: GetEventDetailsByEventId :many
-SELECT id, show_date, price, number_of_tickets, tickets_remaining, ticket_description, created_at, updated_at, event_id FROM event_details WHERE event_id = ANY($1)
+SELECT id, show_date, price, number_of_tickets, tickets_remaining, ticket_description, created_at, updated_at, event_id, sales_start_at, sales_end_at, price_schedule, unlocks_after_event_detail_id FROM event_details WHERE event_id = ANY($1)
 `
 
 func (q *Queries) GetEventDetailsByEventId(ctx context.Context, eventID []uuid.UUID) ([]EventDetail, error) {
@@ -92,6 +105,10 @@ func (q *Queries) GetEventDetailsByEventId(ctx context.Context, eventID []uuid.U
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.EventID,
+			&i.SalesStartAt,
+			&i.SalesEndAt,
+			&i.PriceSchedule,
+			&i.UnlocksAfterEventDetailID,
 		); err != nil {
 			return nil, err
 		}
@@ -107,7 +124,7 @@ func (q *Queries) GetEventDetailsByEventId(ctx context.Context, eventID []uuid.U
 }
 
 const getEventDetailsById = `-- name: GetEventDetailsById :one
-SELECT id, show_date, price, number_of_tickets, tickets_remaining, ticket_description, created_at, updated_at, event_id FROM event_details WHERE id = $1
+SELECT id, show_date, price, number_of_tickets, tickets_remaining, ticket_description, created_at, updated_at, event_id, sales_start_at, sales_end_at, price_schedule, unlocks_after_event_detail_id FROM event_details WHERE id = $1
 `
 
 func (q *Queries) GetEventDetailsById(ctx context.Context, id uuid.UUID) (EventDetail, error) {
@@ -123,6 +140,10 @@ func (q *Queries) GetEventDetailsById(ctx context.Context, id uuid.UUID) (EventD
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.EventID,
+		&i.SalesStartAt,
+		&i.SalesEndAt,
+		&i.PriceSchedule,
+		&i.UnlocksAfterEventDetailID,
 	)
 	return i, err
 }
@@ -134,20 +155,50 @@ SELECT
 	ed.ticket_description,
 	ed.show_date,
     ed.tickets_remaining,
-    ed.price
+    ed.price,
+	e.status AS event_status,
+	ed.event_id,
+	ed.sales_start_at,
+	ed.sales_end_at,
+	ed.price_schedule,
+	unlocks_after.tickets_remaining AS unlocks_after_tickets_remaining,
+	e.currency,
+	e.organizer,
+	e.user_id AS organizer_user_id,
+	ob.logo_url AS branding_logo_url,
+	ob.accent_color AS branding_accent_color,
+	ob.footer AS branding_footer,
+	e.calendar_sequence
 FROM event_details AS ed
 JOIN events AS e
 	ON e.id = ed.event_id
+LEFT JOIN event_details AS unlocks_after
+	ON unlocks_after.id = ed.unlocks_after_event_detail_id
+LEFT JOIN organizer_brandings AS ob
+	ON ob.user_id = e.user_id
 WHERE ed.id = ANY($1)
 `
 
 type GetEventDetailsWithTitleByIdsRow struct {
-	ID                uuid.UUID
-	Title             string
-	TicketDescription string
-	ShowDate          time.Time
-	TicketsRemaining  int32
-	Price             string
+	ID                           uuid.UUID
+	Title                        string
+	TicketDescription            string
+	ShowDate                     time.Time
+	TicketsRemaining             int32
+	Price                        int64
+	EventStatus                  string
+	EventID                      uuid.UUID
+	SalesStartAt                 sql.NullTime
+	SalesEndAt                   sql.NullTime
+	PriceSchedule                json.RawMessage
+	UnlocksAfterTicketsRemaining sql.NullInt32
+	Currency                     string
+	Organizer                    sql.NullString
+	OrganizerUserID              uuid.UUID
+	BrandingLogoUrl              sql.NullString
+	BrandingAccentColor          sql.NullString
+	BrandingFooter               sql.NullString
+	CalendarSequence             int32
 }
 
 func (q *Queries) GetEventDetailsWithTitleByIds(ctx context.Context, id []uuid.UUID) ([]GetEventDetailsWithTitleByIdsRow, error) {
@@ -166,6 +217,19 @@ func (q *Queries) GetEventDetailsWithTitleByIds(ctx context.Context, id []uuid.U
 			&i.ShowDate,
 			&i.TicketsRemaining,
 			&i.Price,
+			&i.EventStatus,
+			&i.EventID,
+			&i.SalesStartAt,
+			&i.SalesEndAt,
+			&i.PriceSchedule,
+			&i.UnlocksAfterTicketsRemaining,
+			&i.Currency,
+			&i.Organizer,
+			&i.OrganizerUserID,
+			&i.BrandingLogoUrl,
+			&i.BrandingAccentColor,
+			&i.BrandingFooter,
+			&i.CalendarSequence,
 		); err != nil {
 			return nil, err
 		}
@@ -184,11 +248,13 @@ const getPaidEventDetailForRefund = `-- name: GetPaidEventDetailForRefund :many
 SELECT
     p.id AS payment_id,
     p.payment_intent_id,
+	p.user_id AS payer_user_id,
     p.amount,
     p.status,
+    p.currency,
 	e.title,
     ed.ticket_description,
-	SUM(ed.price) AS ticket_price 
+	SUM(r.price - r.discount_amount)::bigint AS ticket_price 
 FROM events AS e
 JOIN event_details AS ed
     ON ed.event_id = e.id
@@ -199,7 +265,7 @@ JOIN payments AS p
 JOIN users AS u
     ON u.id = p.user_id
 WHERE ed.id = $1::uuid AND e.user_id = $2::uuid
-GROUP BY p.id, p.payment_intent_id, p.amount, p.status, e.title, ed.ticket_description, ed.price
+GROUP BY p.id, p.payment_intent_id, p.user_id, p.amount, p.status, p.currency, e.title, ed.ticket_description
 `
 
 type GetPaidEventDetailForRefundParams struct {
@@ -210,11 +276,13 @@ type GetPaidEventDetailForRefundParams struct {
 type GetPaidEventDetailForRefundRow struct {
 	PaymentID         uuid.UUID
 	PaymentIntentID   sql.NullString
-	Amount            string
+	PayerUserID       uuid.UUID
+	Amount            int64
 	Status            string
+	Currency          string
 	Title             string
 	TicketDescription string
-	TicketPrice       string
+	TicketPrice       int64
 }
 
 func (q *Queries) GetPaidEventDetailForRefund(ctx context.Context, arg GetPaidEventDetailForRefundParams) ([]GetPaidEventDetailForRefundRow, error) {
@@ -229,8 +297,10 @@ func (q *Queries) GetPaidEventDetailForRefund(ctx context.Context, arg GetPaidEv
 		if err := rows.Scan(
 			&i.PaymentID,
 			&i.PaymentIntentID,
+			&i.PayerUserID,
 			&i.Amount,
 			&i.Status,
+			&i.Currency,
 			&i.Title,
 			&i.TicketDescription,
 			&i.TicketPrice,
@@ -248,20 +318,78 @@ func (q *Queries) GetPaidEventDetailForRefund(ctx context.Context, arg GetPaidEv
 	return items, nil
 }
 
+const getUpcomingEventDetailsByEventId = `-- name: GetUpcomingEventDetailsByEventId :many
+SELECT ed.id, ed.show_date, ed.price, ed.number_of_tickets, ed.tickets_remaining, ed.ticket_description, ed.created_at, ed.updated_at, ed.event_id, ed.sales_start_at, ed.sales_end_at, ed.price_schedule, ed.unlocks_after_event_detail_id FROM event_details AS ed
+WHERE ed.event_id = $1 AND ed.show_date >= $2
+	AND (ed.sales_end_at IS NULL OR ed.sales_end_at > $2)
+	AND NOT EXISTS (
+		SELECT 1 FROM event_details AS unlocks_after
+		WHERE unlocks_after.id = ed.unlocks_after_event_detail_id AND unlocks_after.tickets_remaining > 0
+	)
+ORDER BY ed.show_date, ed.id
+`
+
+type GetUpcomingEventDetailsByEventIdParams struct {
+	EventID  uuid.UUID
+	ShowDate time.Time
+}
+
+// Ticket types whose sales ended or that wait for another ticket type to sell out aren't listed.
+func (q *Queries) GetUpcomingEventDetailsByEventId(ctx context.Context, arg GetUpcomingEventDetailsByEventIdParams) ([]EventDetail, error) {
+	rows, err := q.db.QueryContext(ctx, getUpcomingEventDetailsByEventId, arg.EventID, arg.ShowDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EventDetail
+	for rows.Next() {
+		var i EventDetail
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShowDate,
+			&i.Price,
+			&i.NumberOfTickets,
+			&i.TicketsRemaining,
+			&i.TicketDescription,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.EventID,
+			&i.SalesStartAt,
+			&i.SalesEndAt,
+			&i.PriceSchedule,
+			&i.UnlocksAfterEventDetailID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateEventDetail = `-- name: UpdateEventDetail :one
 UPDATE event_details
-SET show_date = $1, price = $2, number_of_tickets = $3, ticket_description = $4, updated_at = NOW()
-WHERE id = $5 AND event_id = $6
-RETURNING id, show_date, price, number_of_tickets, tickets_remaining, ticket_description, created_at, updated_at, event_id
+SET show_date = $1, price = $2, number_of_tickets = $3, tickets_remaining = tickets_remaining + ($3 - number_of_tickets), ticket_description = $4, sales_start_at = $5, sales_end_at = $6, price_schedule = $7, unlocks_after_event_detail_id = $8, updated_at = NOW()
+WHERE id = $9 AND event_id = $10 AND number_of_tickets - tickets_remaining <= $3
+RETURNING id, show_date, price, number_of_tickets, tickets_remaining, ticket_description, created_at, updated_at, event_id, sales_start_at, sales_end_at, price_schedule, unlocks_after_event_detail_id
 `
 
 type UpdateEventDetailParams struct {
-	ShowDate          time.Time
-	Price             string
-	NumberOfTickets   int32
-	TicketDescription string
-	ID                uuid.UUID
-	EventID           uuid.UUID
+	ShowDate                  time.Time
+	Price                     int64
+	NumberOfTickets           int32
+	TicketDescription         string
+	SalesStartAt              sql.NullTime
+	SalesEndAt                sql.NullTime
+	PriceSchedule             json.RawMessage
+	UnlocksAfterEventDetailID uuid.NullUUID
+	ID                        uuid.UUID
+	EventID                   uuid.UUID
 }
 
 func (q *Queries) UpdateEventDetail(ctx context.Context, arg UpdateEventDetailParams) (EventDetail, error) {
@@ -270,6 +398,10 @@ func (q *Queries) UpdateEventDetail(ctx context.Context, arg UpdateEventDetailPa
 		arg.Price,
 		arg.NumberOfTickets,
 		arg.TicketDescription,
+		arg.SalesStartAt,
+		arg.SalesEndAt,
+		arg.PriceSchedule,
+		arg.UnlocksAfterEventDetailID,
 		arg.ID,
 		arg.EventID,
 	)
@@ -284,6 +416,44 @@ func (q *Queries) UpdateEventDetail(ctx context.Context, arg UpdateEventDetailPa
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.EventID,
+		&i.SalesStartAt,
+		&i.SalesEndAt,
+		&i.PriceSchedule,
+		&i.UnlocksAfterEventDetailID,
+	)
+	return i, err
+}
+
+const updateEventDetailShowDate = `-- name: UpdateEventDetailShowDate :one
+UPDATE event_details
+SET show_date = $1, updated_at = NOW()
+WHERE id = $2 AND event_id = $3
+RETURNING id, show_date, price, number_of_tickets, tickets_remaining, ticket_description, created_at, updated_at, event_id, sales_start_at, sales_end_at, price_schedule, unlocks_after_event_detail_id
+`
+
+type UpdateEventDetailShowDateParams struct {
+	ShowDate time.Time
+	ID       uuid.UUID
+	EventID  uuid.UUID
+}
+
+func (q *Queries) UpdateEventDetailShowDate(ctx context.Context, arg UpdateEventDetailShowDateParams) (EventDetail, error) {
+	row := q.db.QueryRowContext(ctx, updateEventDetailShowDate, arg.ShowDate, arg.ID, arg.EventID)
+	var i EventDetail
+	err := row.Scan(
+		&i.ID,
+		&i.ShowDate,
+		&i.Price,
+		&i.NumberOfTickets,
+		&i.TicketsRemaining,
+		&i.TicketDescription,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EventID,
+		&i.SalesStartAt,
+		&i.SalesEndAt,
+		&i.PriceSchedule,
+		&i.UnlocksAfterEventDetailID,
 	)
 	return i, err
 }
@@ -292,7 +462,7 @@ const updateTicketsRemaining = `-- name: UpdateTicketsRemaining :one
 UPDATE event_details
 SET tickets_remaining = $1, updated_at = NOW()
 WHERE id = $2
-RETURNING id, show_date, price, number_of_tickets, tickets_remaining, ticket_description, created_at, updated_at, event_id
+RETURNING id, show_date, price, number_of_tickets, tickets_remaining, ticket_description, created_at, updated_at, event_id, sales_start_at, sales_end_at, price_schedule, unlocks_after_event_detail_id
 `
 
 type UpdateTicketsRemainingParams struct {
@@ -313,6 +483,10 @@ func (q *Queries) UpdateTicketsRemaining(ctx context.Context, arg UpdateTicketsR
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.EventID,
+		&i.SalesStartAt,
+		&i.SalesEndAt,
+		&i.PriceSchedule,
+		&i.UnlocksAfterEventDetailID,
 	)
 	return i, err
 }
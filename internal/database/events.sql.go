@@ -8,15 +8,113 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const bumpEventCalendarSequence = `-- name: BumpEventCalendarSequence :exec
+UPDATE events
+SET calendar_sequence = calendar_sequence + 1
+WHERE id = $1
+`
+
+func (q *Queries) BumpEventCalendarSequence(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, bumpEventCalendarSequence, id)
+	return err
+}
+
+const countEvents = `-- name: CountEvents :one
+SELECT COUNT(*)
+FROM events AS e
+JOIN event_details AS ed
+	ON ed.event_id = e.id
+-- Ticket types are found and sorted by the price in effect, the first early price that hasn't ended or the list price.
+CROSS JOIN LATERAL (
+	SELECT COALESCE((
+		SELECT (schedule.entry->>'price')::bigint
+		FROM jsonb_array_elements(ed.price_schedule) WITH ORDINALITY AS schedule(entry, position)
+		WHERE ((schedule.entry->>'ends_at')::timestamptz AT TIME ZONE 'UTC') > $1::timestamp
+		ORDER BY schedule.position
+		LIMIT 1
+	), ed.price)::bigint AS price
+) AS current_price
+WHERE
+	e.status IN ('published', 'postponed')
+	AND ($2::text = '' OR e.search_vector @@ websearch_to_tsquery('english', $2::text))
+	AND ($3::text = '' OR LOWER(e.organizer) LIKE $3::text)
+	AND ($4::text = '' OR e.currency = $4::text)
+	AND ed.show_date >= $5::timestamp
+	AND ($6::timestamp IS NULL OR ed.show_date <= $6::timestamp)
+	AND ($7::bigint IS NULL OR current_price.price >= $7::bigint)
+	AND ($8::bigint IS NULL OR current_price.price <= $8::bigint)
+	AND (NOT $9::boolean OR ed.tickets_remaining > 0)
+	AND (NOT $10::boolean OR current_price.price = 0)
+	AND (ed.sales_end_at IS NULL OR ed.sales_end_at > $1::timestamp)
+	AND NOT EXISTS (
+		SELECT 1 FROM event_details AS unlocks_after
+		WHERE unlocks_after.id = ed.unlocks_after_event_detail_id AND unlocks_after.tickets_remaining > 0
+	)
+`
+
+type CountEventsParams struct {
+	CurrentDateTime     time.Time
+	Search              string
+	Organizer           string
+	Currency            string
+	StartShowDate       time.Time
+	EndShowDate         sql.NullTime
+	MinPrice            sql.NullInt64
+	MaxPrice            sql.NullInt64
+	HasTicketsRemaining bool
+	FreeOnly            bool
+}
+
+func (q *Queries) CountEvents(ctx context.Context, arg CountEventsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countEvents,
+		arg.CurrentDateTime,
+		arg.Search,
+		arg.Organizer,
+		arg.Currency,
+		arg.StartShowDate,
+		arg.EndShowDate,
+		arg.MinPrice,
+		arg.MaxPrice,
+		arg.HasTicketsRemaining,
+		arg.FreeOnly,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countOtherEventPaymentReservations = `-- name: CountOtherEventPaymentReservations :one
+SELECT COUNT(*)
+FROM reservations AS r
+JOIN event_details AS ed
+	ON ed.id = r.event_detail_id
+WHERE r.payment_id = $1::uuid
+	AND ed.event_id <> $2::uuid
+`
+
+type CountOtherEventPaymentReservationsParams struct {
+	PaymentID uuid.UUID
+	EventID   uuid.UUID
+}
+
+func (q *Queries) CountOtherEventPaymentReservations(ctx context.Context, arg CountOtherEventPaymentReservationsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countOtherEventPaymentReservations, arg.PaymentID, arg.EventID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createEvent = `-- name: CreateEvent :one
-INSERT INTO events (id, title, description, organizer, user_id)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, title, description, organizer, created_at, updated_at, user_id
+INSERT INTO events (id, title, description, organizer, user_id, currency)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, title, description, organizer, created_at, updated_at, user_id, search_vector, status, refund_deadline, status_updated_at, currency, calendar_sequence, reminder_offset_minutes
 `
 
 type CreateEventParams struct {
@@ -25,6 +123,7 @@ type CreateEventParams struct {
 	Description string
 	Organizer   sql.NullString
 	UserID      uuid.UUID
+	Currency    string
 }
 
 func (q *Queries) CreateEvent(ctx context.Context, arg CreateEventParams) (Event, error) {
@@ -34,6 +133,7 @@ func (q *Queries) CreateEvent(ctx context.Context, arg CreateEventParams) (Event
 		arg.Description,
 		arg.Organizer,
 		arg.UserID,
+		arg.Currency,
 	)
 	var i Event
 	err := row.Scan(
@@ -44,12 +144,19 @@ func (q *Queries) CreateEvent(ctx context.Context, arg CreateEventParams) (Event
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserID,
+		&i.SearchVector,
+		&i.Status,
+		&i.RefundDeadline,
+		&i.StatusUpdatedAt,
+		&i.Currency,
+		&i.CalendarSequence,
+		pq.Array(&i.ReminderOffsetMinutes),
 	)
 	return i, err
 }
 
 const deleteEvent = `-- name: DeleteEvent :exec
-DELETE FROM events WHERE id = $1 AND user_id = $2
+DELETE FROM events WHERE id = $1 AND user_id = $2 AND status = 'draft'
 `
 
 type DeleteEventParams struct {
@@ -62,6 +169,91 @@ func (q *Queries) DeleteEvent(ctx context.Context, arg DeleteEventParams) error
 	return err
 }
 
+const getEventAttendees = `-- name: GetEventAttendees :many
+SELECT
+	r.id AS reservation_id,
+	r.email,
+	ed.id AS event_detail_id,
+	ed.ticket_description,
+	ed.show_date,
+	r.checked_in_at
+FROM event_details AS ed
+JOIN reservations AS r
+	ON r.event_detail_id = ed.id
+JOIN payments AS p
+	ON p.id = r.payment_id
+WHERE ed.event_id = $1
+	AND p.status = 'succeeded'
+ORDER BY ed.show_date, r.email, r.id
+`
+
+type GetEventAttendeesRow struct {
+	ReservationID     uuid.UUID
+	Email             string
+	EventDetailID     uuid.UUID
+	TicketDescription string
+	ShowDate          time.Time
+	CheckedInAt       sql.NullTime
+}
+
+func (q *Queries) GetEventAttendees(ctx context.Context, eventID uuid.UUID) ([]GetEventAttendeesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getEventAttendees, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEventAttendeesRow
+	for rows.Next() {
+		var i GetEventAttendeesRow
+		if err := rows.Scan(
+			&i.ReservationID,
+			&i.Email,
+			&i.EventDetailID,
+			&i.TicketDescription,
+			&i.ShowDate,
+			&i.CheckedInAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEventById = `-- name: GetEventById :one
+SELECT id, title, description, organizer, created_at, updated_at, user_id, search_vector, status, refund_deadline, status_updated_at, currency, calendar_sequence, reminder_offset_minutes
+FROM events
+WHERE id = $1
+`
+
+func (q *Queries) GetEventById(ctx context.Context, id uuid.UUID) (Event, error) {
+	row := q.db.QueryRowContext(ctx, getEventById, id)
+	var i Event
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Description,
+		&i.Organizer,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.SearchVector,
+		&i.Status,
+		&i.RefundDeadline,
+		&i.StatusUpdatedAt,
+		&i.Currency,
+		&i.CalendarSequence,
+		pq.Array(&i.ReminderOffsetMinutes),
+	)
+	return i, err
+}
+
 const getEventConfirmedUserReservations = `-- name: GetEventConfirmedUserReservations :many
 SELECT 
 	p.user_id,
@@ -111,53 +303,131 @@ func (q *Queries) GetEventConfirmedUserReservations(ctx context.Context, id uuid
 }
 
 const getEvents = `-- name: GetEvents :many
-SELECT 
+SELECT
 	e.id AS event_id,
 	e.title,
 	e.description,
 	e.organizer,
+	e.status,
+	e.currency,
 	ed.id AS event_detail_id,
 	ed.show_date,
-	ed.price,
+	current_price.price,
 	ed.number_of_tickets,
-	ed.ticket_description
+	ed.tickets_remaining,
+	ed.ticket_description,
+	ed.created_at,
+	ts_rank(e.search_vector, websearch_to_tsquery('english', $1::text))::real AS rank,
+	(CASE WHEN $1::text = '' THEN ''
+		ELSE ts_headline('english', e.title, websearch_to_tsquery('english', $1::text), 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true')
+	END)::text AS title_highlight,
+	(CASE WHEN $1::text = '' THEN ''
+		ELSE ts_headline('english', e.description, websearch_to_tsquery('english', $1::text), 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MinWords=5, MaxWords=20')
+	END)::text AS description_snippet
 FROM events AS e
-LEFT JOIN event_details AS ed
-ON ed.event_id = e.id
-WHERE 
-(LOWER(e.title) LIKE $1 
-OR LOWER(e.description) LIKE $2 
-OR LOWER(e.organizer) LIKE $3) 
-AND (ed.show_date >= $4 AND ed.show_date <= $5)
+JOIN event_details AS ed
+	ON ed.event_id = e.id
+-- Ticket types are found and sorted by the price in effect, the first early price that hasn't ended or the list price.
+CROSS JOIN LATERAL (
+	SELECT COALESCE((
+		SELECT (schedule.entry->>'price')::bigint
+		FROM jsonb_array_elements(ed.price_schedule) WITH ORDINALITY AS schedule(entry, position)
+		WHERE ((schedule.entry->>'ends_at')::timestamptz AT TIME ZONE 'UTC') > $2::timestamp
+		ORDER BY schedule.position
+		LIMIT 1
+	), ed.price)::bigint AS price
+) AS current_price
+WHERE
+	e.status IN ('published', 'postponed')
+	AND ($1::text = '' OR e.search_vector @@ websearch_to_tsquery('english', $1::text))
+	AND ($3::text = '' OR LOWER(e.organizer) LIKE $3::text)
+	AND ($4::text = '' OR e.currency = $4::text)
+	AND ed.show_date >= $5::timestamp
+	AND ($6::timestamp IS NULL OR ed.show_date <= $6::timestamp)
+	AND ($7::bigint IS NULL OR current_price.price >= $7::bigint)
+	AND ($8::bigint IS NULL OR current_price.price <= $8::bigint)
+	AND (NOT $9::boolean OR ed.tickets_remaining > 0)
+	AND (NOT $10::boolean OR current_price.price = 0)
+	AND (ed.sales_end_at IS NULL OR ed.sales_end_at > $2::timestamp)
+	AND NOT EXISTS (
+		SELECT 1 FROM event_details AS unlocks_after
+		WHERE unlocks_after.id = ed.unlocks_after_event_detail_id AND unlocks_after.tickets_remaining > 0
+	)
+	AND ($11::uuid IS NULL
+		OR ($12::text = 'show_date' AND (ed.show_date, ed.id) > ($13::timestamp, $11::uuid))
+		OR ($12::text = 'price' AND (current_price.price, ed.id) > ($14::bigint, $11::uuid))
+		OR ($12::text = 'price_desc' AND (current_price.price, ed.id) < ($14::bigint, $11::uuid))
+		OR ($12::text = 'newest' AND (ed.created_at, ed.id) < ($15::timestamp, $11::uuid))
+		OR ($12::text = 'relevance' AND (ts_rank(e.search_vector, websearch_to_tsquery('english', $1::text)), ed.id) < ($16::real, $11::uuid)))
+ORDER BY
+	CASE WHEN $12::text = 'show_date' THEN ed.show_date END ASC,
+	CASE WHEN $12::text = 'price' THEN current_price.price END ASC,
+	CASE WHEN $12::text = 'price_desc' THEN current_price.price END DESC,
+	CASE WHEN $12::text = 'newest' THEN ed.created_at END DESC,
+	CASE WHEN $12::text = 'relevance' THEN ts_rank(e.search_vector, websearch_to_tsquery('english', $1::text)) END DESC,
+	CASE WHEN $12::text IN ('show_date', 'price') THEN ed.id END ASC,
+	ed.id DESC
+LIMIT $17
 `
 
 type GetEventsParams struct {
-	Title       string
-	Description string
-	Organizer   sql.NullString
-	ShowDate    time.Time
-	ShowDate_2  time.Time
+	Search              string
+	CurrentDateTime     time.Time
+	Organizer           string
+	Currency            string
+	StartShowDate       time.Time
+	EndShowDate         sql.NullTime
+	MinPrice            sql.NullInt64
+	MaxPrice            sql.NullInt64
+	HasTicketsRemaining bool
+	FreeOnly            bool
+	CursorID            uuid.NullUUID
+	SortBy              string
+	CursorShowDate      time.Time
+	CursorPrice         int64
+	CursorCreatedAt     time.Time
+	CursorRank          float32
+	RowLimit            int32
 }
 
 type GetEventsRow struct {
-	EventID           uuid.UUID
-	Title             string
-	Description       string
-	Organizer         sql.NullString
-	EventDetailID     uuid.NullUUID
-	ShowDate          sql.NullTime
-	Price             sql.NullString
-	NumberOfTickets   sql.NullInt32
-	TicketDescription sql.NullString
+	EventID            uuid.UUID
+	Title              string
+	Description        string
+	Organizer          sql.NullString
+	Status             string
+	Currency           string
+	EventDetailID      uuid.UUID
+	ShowDate           time.Time
+	Price              int64
+	NumberOfTickets    int32
+	TicketsRemaining   int32
+	TicketDescription  string
+	CreatedAt          time.Time
+	Rank               float32
+	TitleHighlight     string
+	DescriptionSnippet string
 }
 
 func (q *Queries) GetEvents(ctx context.Context, arg GetEventsParams) ([]GetEventsRow, error) {
 	rows, err := q.db.QueryContext(ctx, getEvents,
-		arg.Title,
-		arg.Description,
+		arg.Search,
+		arg.CurrentDateTime,
 		arg.Organizer,
-		arg.ShowDate,
-		arg.ShowDate_2,
+		arg.Currency,
+		arg.StartShowDate,
+		arg.EndShowDate,
+		arg.MinPrice,
+		arg.MaxPrice,
+		arg.HasTicketsRemaining,
+		arg.FreeOnly,
+		arg.CursorID,
+		arg.SortBy,
+		arg.CursorShowDate,
+		arg.CursorPrice,
+		arg.CursorCreatedAt,
+		arg.CursorRank,
+		arg.RowLimit,
 	)
 	if err != nil {
 		return nil, err
@@ -171,11 +441,18 @@ func (q *Queries) GetEvents(ctx context.Context, arg GetEventsParams) ([]GetEven
 			&i.Title,
 			&i.Description,
 			&i.Organizer,
+			&i.Status,
+			&i.Currency,
 			&i.EventDetailID,
 			&i.ShowDate,
 			&i.Price,
 			&i.NumberOfTickets,
+			&i.TicketsRemaining,
 			&i.TicketDescription,
+			&i.CreatedAt,
+			&i.Rank,
+			&i.TitleHighlight,
+			&i.DescriptionSnippet,
 		); err != nil {
 			return nil, err
 		}
@@ -197,8 +474,9 @@ SELECT
 	p.user_id AS payer_user_id,
     p.amount,
     p.status,
+    p.currency,
 	e.title,
-	SUM(ed.price) AS ticket_price 
+	SUM(r.price - r.discount_amount)::bigint AS ticket_price 
 FROM events AS e
 JOIN event_details AS ed
     ON ed.event_id = e.id
@@ -207,26 +485,29 @@ JOIN reservations AS r
 JOIN payments AS p
     ON p.id = r.payment_id
 WHERE e.id = $1::uuid AND e.user_id = $2::uuid
-GROUP BY p.id, p.payment_intent_id, p.user_id, p.amount, p.status, e.title, ed.price
+	AND ($3::uuid IS NULL OR p.user_id = $3::uuid)
+GROUP BY p.id, p.payment_intent_id, p.user_id, p.amount, p.status, p.currency, e.title
 `
 
 type GetPaidEventForRefundParams struct {
-	EventID uuid.UUID
-	UserID  uuid.UUID
+	EventID     uuid.UUID
+	UserID      uuid.UUID
+	PayerUserID uuid.NullUUID
 }
 
 type GetPaidEventForRefundRow struct {
 	PaymentID       uuid.UUID
 	PaymentIntentID sql.NullString
 	PayerUserID     uuid.UUID
-	Amount          string
+	Amount          int64
 	Status          string
+	Currency        string
 	Title           string
-	TicketPrice     string
+	TicketPrice     int64
 }
 
 func (q *Queries) GetPaidEventForRefund(ctx context.Context, arg GetPaidEventForRefundParams) ([]GetPaidEventForRefundRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPaidEventForRefund, arg.EventID, arg.UserID)
+	rows, err := q.db.QueryContext(ctx, getPaidEventForRefund, arg.EventID, arg.UserID, arg.PayerUserID)
 	if err != nil {
 		return nil, err
 	}
@@ -240,6 +521,7 @@ func (q *Queries) GetPaidEventForRefund(ctx context.Context, arg GetPaidEventFor
 			&i.PayerUserID,
 			&i.Amount,
 			&i.Status,
+			&i.Currency,
 			&i.Title,
 			&i.TicketPrice,
 		); err != nil {
@@ -256,8 +538,107 @@ func (q *Queries) GetPaidEventForRefund(ctx context.Context, arg GetPaidEventFor
 	return items, nil
 }
 
+const getPublicEvents = `-- name: GetPublicEvents :many
+SELECT
+	e.id AS event_id,
+	e.title,
+	e.description,
+	e.organizer,
+	e.status,
+	e.currency,
+	ed.id AS event_detail_id,
+	ed.show_date,
+	ed.price,
+	ed.tickets_remaining,
+	ed.ticket_description,
+	ed.sales_start_at,
+	ed.sales_end_at,
+	ed.price_schedule
+FROM events AS e
+JOIN event_details AS ed
+	ON ed.event_id = e.id
+WHERE
+	e.status IN ('published', 'postponed')
+	AND ($1::text = '' OR e.search_vector @@ websearch_to_tsquery('english', $1::text))
+	AND ed.show_date >= $2::timestamp
+	AND ed.show_date <= $3::timestamp
+	AND (ed.sales_end_at IS NULL OR ed.sales_end_at > $4::timestamp)
+	AND NOT EXISTS (
+		SELECT 1 FROM event_details AS unlocks_after
+		WHERE unlocks_after.id = ed.unlocks_after_event_detail_id AND unlocks_after.tickets_remaining > 0
+	)
+ORDER BY ed.show_date, ed.id
+`
+
+type GetPublicEventsParams struct {
+	Search          string
+	StartShowDate   time.Time
+	EndShowDate     time.Time
+	CurrentDateTime time.Time
+}
+
+type GetPublicEventsRow struct {
+	EventID           uuid.UUID
+	Title             string
+	Description       string
+	Organizer         sql.NullString
+	Status            string
+	Currency          string
+	EventDetailID     uuid.UUID
+	ShowDate          time.Time
+	Price             int64
+	TicketsRemaining  int32
+	TicketDescription string
+	SalesStartAt      sql.NullTime
+	SalesEndAt        sql.NullTime
+	PriceSchedule     json.RawMessage
+}
+
+func (q *Queries) GetPublicEvents(ctx context.Context, arg GetPublicEventsParams) ([]GetPublicEventsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPublicEvents,
+		arg.Search,
+		arg.StartShowDate,
+		arg.EndShowDate,
+		arg.CurrentDateTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPublicEventsRow
+	for rows.Next() {
+		var i GetPublicEventsRow
+		if err := rows.Scan(
+			&i.EventID,
+			&i.Title,
+			&i.Description,
+			&i.Organizer,
+			&i.Status,
+			&i.Currency,
+			&i.EventDetailID,
+			&i.ShowDate,
+			&i.Price,
+			&i.TicketsRemaining,
+			&i.TicketDescription,
+			&i.SalesStartAt,
+			&i.SalesEndAt,
+			&i.PriceSchedule,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUserEventById = `-- name: GetUserEventById :one
-SELECT id, title, description, organizer, created_at, updated_at, user_id
+SELECT id, title, description, organizer, created_at, updated_at, user_id, search_vector, status, refund_deadline, status_updated_at, currency, calendar_sequence, reminder_offset_minutes
 FROM events
 WHERE id = $1 AND user_id = $2
 `
@@ -278,12 +659,19 @@ func (q *Queries) GetUserEventById(ctx context.Context, arg GetUserEventByIdPara
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserID,
+		&i.SearchVector,
+		&i.Status,
+		&i.RefundDeadline,
+		&i.StatusUpdatedAt,
+		&i.Currency,
+		&i.CalendarSequence,
+		pq.Array(&i.ReminderOffsetMinutes),
 	)
 	return i, err
 }
 
 const getUserEvents = `-- name: GetUserEvents :many
-SELECT id, title, description, organizer, created_at, updated_at, user_id 
+SELECT id, title, description, organizer, created_at, updated_at, user_id, search_vector, status, refund_deadline, status_updated_at, currency, calendar_sequence, reminder_offset_minutes 
 FROM events
 WHERE user_id = $1
 `
@@ -305,6 +693,13 @@ func (q *Queries) GetUserEvents(ctx context.Context, userID uuid.UUID) ([]Event,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.UserID,
+			&i.SearchVector,
+			&i.Status,
+			&i.RefundDeadline,
+			&i.StatusUpdatedAt,
+			&i.Currency,
+			&i.CalendarSequence,
+			pq.Array(&i.ReminderOffsetMinutes),
 		); err != nil {
 			return nil, err
 		}
@@ -319,11 +714,60 @@ func (q *Queries) GetUserEvents(ctx context.Context, userID uuid.UUID) ([]Event,
 	return items, nil
 }
 
+const releaseEventPaymentReservations = `-- name: ReleaseEventPaymentReservations :many
+WITH released AS (
+	DELETE FROM reservations AS r
+	USING event_details AS ed
+	WHERE r.payment_id = @payment_id::uuid
+		AND r.event_detail_id = ed.id
+		AND ed.event_id = @event_id::uuid
+	RETURNING r.event_detail_id
+),
+counts AS (
+	SELECT event_detail_id, COUNT(*) AS cnt
+	FROM released
+	GROUP BY event_detail_id
+)
+UPDATE event_details AS ed
+SET tickets_remaining = ed.tickets_remaining + c.cnt
+FROM counts AS c
+WHERE ed.id = c.event_detail_id
+RETURNING ed.id
+`
+
+type ReleaseEventPaymentReservationsParams struct {
+	PaymentID uuid.UUID
+	EventID   uuid.UUID
+}
+
+func (q *Queries) ReleaseEventPaymentReservations(ctx context.Context, arg ReleaseEventPaymentReservationsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, releaseEventPaymentReservations, arg.PaymentID, arg.EventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateEvent = `-- name: UpdateEvent :one
 UPDATE events
-SET title = $1, description = $2, organizer = $3, updated_at = NOW()
+SET title = $1, description = $2, organizer = $3, calendar_sequence = calendar_sequence + 1, updated_at = NOW()
 WHERE id = $4 AND user_id= $5
-RETURNING id, title, description, organizer, created_at, updated_at, user_id
+RETURNING id, title, description, organizer, created_at, updated_at, user_id, search_vector, status, refund_deadline, status_updated_at, currency, calendar_sequence, reminder_offset_minutes
 `
 
 type UpdateEventParams struct {
@@ -351,6 +795,91 @@ func (q *Queries) UpdateEvent(ctx context.Context, arg UpdateEventParams) (Event
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserID,
+		&i.SearchVector,
+		&i.Status,
+		&i.RefundDeadline,
+		&i.StatusUpdatedAt,
+		&i.Currency,
+		&i.CalendarSequence,
+		pq.Array(&i.ReminderOffsetMinutes),
+	)
+	return i, err
+}
+
+const updateEventReminderOffsets = `-- name: UpdateEventReminderOffsets :one
+UPDATE events
+SET reminder_offset_minutes = $1, updated_at = NOW()
+WHERE id = $2 AND user_id = $3
+RETURNING id, title, description, organizer, created_at, updated_at, user_id, search_vector, status, refund_deadline, status_updated_at, currency, calendar_sequence, reminder_offset_minutes
+`
+
+type UpdateEventReminderOffsetsParams struct {
+	ReminderOffsetMinutes []int32
+	ID                    uuid.UUID
+	UserID                uuid.UUID
+}
+
+func (q *Queries) UpdateEventReminderOffsets(ctx context.Context, arg UpdateEventReminderOffsetsParams) (Event, error) {
+	row := q.db.QueryRowContext(ctx, updateEventReminderOffsets, pq.Array(arg.ReminderOffsetMinutes), arg.ID, arg.UserID)
+	var i Event
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Description,
+		&i.Organizer,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.SearchVector,
+		&i.Status,
+		&i.RefundDeadline,
+		&i.StatusUpdatedAt,
+		&i.Currency,
+		&i.CalendarSequence,
+		pq.Array(&i.ReminderOffsetMinutes),
+	)
+	return i, err
+}
+
+const updateEventStatus = `-- name: UpdateEventStatus :one
+UPDATE events
+SET status = $1::text, refund_deadline = $2::timestamp, status_updated_at = NOW(), calendar_sequence = calendar_sequence + 1, updated_at = NOW()
+WHERE id = $3 AND user_id = $4 AND status = $5::text
+RETURNING id, title, description, organizer, created_at, updated_at, user_id, search_vector, status, refund_deadline, status_updated_at, currency, calendar_sequence, reminder_offset_minutes
+`
+
+type UpdateEventStatusParams struct {
+	NewStatus      string
+	RefundDeadline sql.NullTime
+	ID             uuid.UUID
+	UserID         uuid.UUID
+	CurrentStatus  string
+}
+
+func (q *Queries) UpdateEventStatus(ctx context.Context, arg UpdateEventStatusParams) (Event, error) {
+	row := q.db.QueryRowContext(ctx, updateEventStatus,
+		arg.NewStatus,
+		arg.RefundDeadline,
+		arg.ID,
+		arg.UserID,
+		arg.CurrentStatus,
+	)
+	var i Event
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Description,
+		&i.Organizer,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.SearchVector,
+		&i.Status,
+		&i.RefundDeadline,
+		&i.StatusUpdatedAt,
+		&i.Currency,
+		&i.CalendarSequence,
+		pq.Array(&i.ReminderOffsetMinutes),
 	)
 	return i, err
 }
@@ -6,43 +6,113 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type CalendarFeed struct {
+	TokenHash string
+	CreatedAt time.Time
+	UserID    uuid.UUID
+}
+
+type EmailOutbox struct {
+	ID            uuid.UUID
+	Sender        string
+	Recipient     string
+	Subject       string
+	TextBody      string
+	HtmlBody      string
+	Attachments   json.RawMessage
+	Status        string
+	Attempts      int32
+	LastError     sql.NullString
+	NextAttemptAt time.Time
+	SentAt        sql.NullTime
+	CreatedAt     time.Time
+	UpdatedAt     sql.NullTime
+}
+
 type Event struct {
-	ID          uuid.UUID
-	Title       string
-	Description string
-	Organizer   sql.NullString
-	CreatedAt   time.Time
-	UpdatedAt   sql.NullTime
-	UserID      uuid.UUID
+	ID                    uuid.UUID
+	Title                 string
+	Description           string
+	Organizer             sql.NullString
+	CreatedAt             time.Time
+	UpdatedAt             sql.NullTime
+	UserID                uuid.UUID
+	SearchVector          interface{}
+	Status                string
+	RefundDeadline        sql.NullTime
+	StatusUpdatedAt       sql.NullTime
+	Currency              string
+	CalendarSequence      int32
+	ReminderOffsetMinutes []int32
 }
 
 type EventDetail struct {
-	ID                uuid.UUID
-	ShowDate          time.Time
-	Price             string
-	NumberOfTickets   int32
-	TicketsRemaining  int32
-	TicketDescription string
-	CreatedAt         time.Time
-	UpdatedAt         sql.NullTime
-	EventID           uuid.UUID
+	ID                        uuid.UUID
+	ShowDate                  time.Time
+	Price                     int64
+	NumberOfTickets           int32
+	TicketsRemaining          int32
+	TicketDescription         string
+	CreatedAt                 time.Time
+	UpdatedAt                 sql.NullTime
+	EventID                   uuid.UUID
+	SalesStartAt              sql.NullTime
+	SalesEndAt                sql.NullTime
+	PriceSchedule             json.RawMessage
+	UnlocksAfterEventDetailID uuid.NullUUID
+}
+
+type EventStaff struct {
+	ID        uuid.UUID
+	Email     string
+	Role      string
+	CreatedAt time.Time
+	UpdatedAt sql.NullTime
+	EventID   uuid.UUID
+	InvitedBy uuid.UUID
+}
+
+type IdempotencyKey struct {
+	ID                 uuid.UUID
+	IdempotencyKey     string
+	RequestMethod      string
+	RequestPath        string
+	RequestFingerprint string
+	ResponseStatus     sql.NullInt32
+	ResponseBody       sql.NullString
+	CreatedAt          time.Time
+	CompletedAt        sql.NullTime
+	ExpiresAt          time.Time
+	UserID             uuid.UUID
+}
+
+type OrganizerBranding struct {
+	LogoUrl     sql.NullString
+	AccentColor sql.NullString
+	Footer      sql.NullString
+	CreatedAt   time.Time
+	UpdatedAt   sql.NullTime
+	UserID      uuid.UUID
 }
 
 type Payment struct {
 	ID              uuid.UUID
 	PaymentIntentID sql.NullString
-	Amount          string
+	Amount          int64
 	Currency        string
 	Status          string
 	ExpiresAt       time.Time
 	CreatedAt       time.Time
 	UpdatedAt       sql.NullTime
 	UserID          uuid.UUID
+	DiscountAmount  int64
+	PromoCodeID     uuid.NullUUID
 }
 
 type PaymentLog struct {
@@ -51,28 +121,144 @@ type PaymentLog struct {
 	Description     sql.NullString
 	PaymentIntentID string
 	PaymentMethodID sql.NullString
-	Amount          string
+	Amount          int64
 	CreatedAt       time.Time
 	UserEmail       string
 	PaymentID       uuid.UUID
+	Currency        string
+}
+
+type PaymentStatusHistory struct {
+	ID         uuid.UUID
+	FromStatus sql.NullString
+	ToStatus   string
+	ChangedAt  time.Time
+	PaymentID  uuid.UUID
+}
+
+type PromoCode struct {
+	ID             uuid.UUID
+	Code           string
+	DiscountType   string
+	DiscountValue  int64
+	MaxUses        sql.NullInt32
+	MaxUsesPerUser sql.NullInt32
+	MinQuantity    int32
+	ValidFrom      sql.NullTime
+	ValidUntil     sql.NullTime
+	CreatedAt      time.Time
+	UpdatedAt      sql.NullTime
+	EventID        uuid.UUID
+	EventDetailID  uuid.NullUUID
+}
+
+type ReconciliationIssue struct {
+	ID              uuid.UUID
+	PaymentIntentID string
+	IssueType       string
+	Description     string
+	PaymentStatus   string
+	GatewayStatus   string
+	Resolution      sql.NullString
+	Occurrences     int32
+	CreatedAt       time.Time
+	LastSeenAt      time.Time
+	ResolvedAt      sql.NullTime
+	PaymentID       uuid.NullUUID
+}
+
+type RefreshToken struct {
+	ID                   uuid.UUID
+	TokenHash            string
+	FamilyID             uuid.UUID
+	AccessTokenID        string
+	AccessTokenExpiresAt time.Time
+	ExpiresAt            time.Time
+	CreatedAt            time.Time
+	UsedAt               sql.NullTime
+	RevokedAt            sql.NullTime
+	UserID               uuid.UUID
 }
 
 type Reservation struct {
-	ID            uuid.UUID
-	Email         string
-	CreatedAt     time.Time
-	UpdatedAt     sql.NullTime
-	EventDetailID uuid.UUID
-	UserID        uuid.UUID
-	PaymentID     uuid.UUID
+	ID             uuid.UUID
+	Email          string
+	CreatedAt      time.Time
+	UpdatedAt      sql.NullTime
+	EventDetailID  uuid.UUID
+	UserID         uuid.UUID
+	PaymentID      uuid.UUID
+	CheckedInAt    sql.NullTime
+	CheckedInBy    uuid.NullUUID
+	DiscountAmount int64
+	Price          int64
+}
+
+type RevokedAccessToken struct {
+	Jti       string
+	ExpiresAt time.Time
+	RevokedAt time.Time
+	UserID    uuid.UUID
+}
+
+type ShowReminder struct {
+	OffsetMinutes int32
+	ShowDate      time.Time
+	SentAt        time.Time
+	ReservationID uuid.UUID
+}
+
+type StripeWebhookEvent struct {
+	ID                  string
+	EventType           string
+	WebhookType         string
+	Payload             string
+	Attempts            int32
+	ProcessingError     sql.NullString
+	ReceivedAt          time.Time
+	ProcessedAt         sql.NullTime
+	UpdatedAt           sql.NullTime
+	ProcessingStartedAt sql.NullTime
 }
 
 type User struct {
+	ID              uuid.UUID
+	Firstname       string
+	Lastname        string
+	Email           string
+	Password        string
+	CreatedAt       time.Time
+	UpdatedAt       sql.NullTime
+	EmailVerifiedAt sql.NullTime
+	Role            string
+}
+
+type UserActionToken struct {
 	ID        uuid.UUID
-	Firstname string
-	Lastname  string
-	Email     string
-	Password  string
+	TokenHash string
+	Purpose   string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
 	CreatedAt time.Time
-	UpdatedAt sql.NullTime
+	UserID    uuid.UUID
+}
+
+type UserPreference struct {
+	ShowReminders bool
+	CreatedAt     time.Time
+	UpdatedAt     sql.NullTime
+	UserID        uuid.UUID
+}
+
+type WaitlistEntry struct {
+	ID             uuid.UUID
+	Quantity       int32
+	Status         string
+	ClaimTokenHash sql.NullString
+	OfferExpiresAt sql.NullTime
+	OfferedAt      sql.NullTime
+	CreatedAt      time.Time
+	UpdatedAt      sql.NullTime
+	EventDetailID  uuid.UUID
+	UserID         uuid.UUID
 }
@@ -13,9 +13,9 @@ import (
 )
 
 const createPaymentLog = `-- name: CreatePaymentLog :one
-INSERT INTO payment_logs (id, status, description, payment_intent_id, payment_method_id, amount, user_email, payment_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, status, description, payment_intent_id, payment_method_id, amount, created_at, user_email, payment_id
+INSERT INTO payment_logs (id, status, description, payment_intent_id, payment_method_id, amount, user_email, payment_id, currency)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, status, description, payment_intent_id, payment_method_id, amount, created_at, user_email, payment_id, currency
 `
 
 type CreatePaymentLogParams struct {
@@ -24,9 +24,10 @@ type CreatePaymentLogParams struct {
 	Description     sql.NullString
 	PaymentIntentID string
 	PaymentMethodID sql.NullString
-	Amount          string
+	Amount          int64
 	UserEmail       string
 	PaymentID       uuid.UUID
+	Currency        string
 }
 
 func (q *Queries) CreatePaymentLog(ctx context.Context, arg CreatePaymentLogParams) (PaymentLog, error) {
@@ -39,6 +40,7 @@ func (q *Queries) CreatePaymentLog(ctx context.Context, arg CreatePaymentLogPara
 		arg.Amount,
 		arg.UserEmail,
 		arg.PaymentID,
+		arg.Currency,
 	)
 	var i PaymentLog
 	err := row.Scan(
@@ -51,6 +53,7 @@ func (q *Queries) CreatePaymentLog(ctx context.Context, arg CreatePaymentLogPara
 		&i.CreatedAt,
 		&i.UserEmail,
 		&i.PaymentID,
+		&i.Currency,
 	)
 	return i, err
 }
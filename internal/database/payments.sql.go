@@ -15,18 +15,20 @@ import (
 )
 
 const createPayment = `-- name: CreatePayment :one
-INSERT INTO payments (id, amount, currency, status, expires_at, user_id)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, payment_intent_id, amount, currency, status, expires_at, created_at, updated_at, user_id
+INSERT INTO payments (id, amount, currency, status, expires_at, user_id, discount_amount, promo_code_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, payment_intent_id, amount, currency, status, expires_at, created_at, updated_at, user_id, discount_amount, promo_code_id
 `
 
 type CreatePaymentParams struct {
-	ID        uuid.UUID
-	Amount    string
-	Currency  string
-	Status    string
-	ExpiresAt time.Time
-	UserID    uuid.UUID
+	ID             uuid.UUID
+	Amount         int64
+	Currency       string
+	Status         string
+	ExpiresAt      time.Time
+	UserID         uuid.UUID
+	DiscountAmount int64
+	PromoCodeID    uuid.NullUUID
 }
 
 func (q *Queries) CreatePayment(ctx context.Context, arg CreatePaymentParams) (Payment, error) {
@@ -37,6 +39,8 @@ func (q *Queries) CreatePayment(ctx context.Context, arg CreatePaymentParams) (P
 		arg.Status,
 		arg.ExpiresAt,
 		arg.UserID,
+		arg.DiscountAmount,
+		arg.PromoCodeID,
 	)
 	var i Payment
 	err := row.Scan(
@@ -49,12 +53,62 @@ func (q *Queries) CreatePayment(ctx context.Context, arg CreatePaymentParams) (P
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserID,
+		&i.DiscountAmount,
+		&i.PromoCodeID,
 	)
 	return i, err
 }
 
+const getExpiredPendingPayments = `-- name: GetExpiredPendingPayments :many
+SELECT id, payment_intent_id, amount, currency, status, expires_at, created_at, updated_at, user_id, discount_amount, promo_code_id FROM payments
+WHERE expires_at < $1::timestamp
+	AND status IN ('pending', 'requires_action', 'requires_payment_method', 'payment_failed')
+ORDER BY expires_at
+LIMIT $2
+`
+
+type GetExpiredPendingPaymentsParams struct {
+	ExpiredBefore time.Time
+	BatchSize     int32
+}
+
+func (q *Queries) GetExpiredPendingPayments(ctx context.Context, arg GetExpiredPendingPaymentsParams) ([]Payment, error) {
+	rows, err := q.db.QueryContext(ctx, getExpiredPendingPayments, arg.ExpiredBefore, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Payment
+	for rows.Next() {
+		var i Payment
+		if err := rows.Scan(
+			&i.ID,
+			&i.PaymentIntentID,
+			&i.Amount,
+			&i.Currency,
+			&i.Status,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.DiscountAmount,
+			&i.PromoCodeID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getMultiplePayments = `-- name: GetMultiplePayments :many
-SELECT id, payment_intent_id, amount, currency, status, expires_at, created_at, updated_at, user_id FROM payments WHERE id = ANY($1)
+SELECT id, payment_intent_id, amount, currency, status, expires_at, created_at, updated_at, user_id, discount_amount, promo_code_id FROM payments WHERE id = ANY($1)
 `
 
 func (q *Queries) GetMultiplePayments(ctx context.Context, id []uuid.UUID) ([]Payment, error) {
@@ -76,6 +130,8 @@ func (q *Queries) GetMultiplePayments(ctx context.Context, id []uuid.UUID) ([]Pa
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.UserID,
+			&i.DiscountAmount,
+			&i.PromoCodeID,
 		); err != nil {
 			return nil, err
 		}
@@ -96,14 +152,16 @@ SELECT
 	p.payment_intent_id,
   	p.user_id, 
 	p.amount,
+	p.currency,
 	p.status,
 	r.id AS reservation_id,
 	r.email,
+	r.discount_amount,
 	ed.id AS event_detail_id,
 	e.title,
 	ed.ticket_description,
 	ed.show_date,
-	ed.price 
+	r.price 
 FROM payments AS p 
 LEFT JOIN reservations AS r
 ON r.payment_id = p.id 
@@ -125,15 +183,17 @@ type GetPaymentAndReservationDetailsRow struct {
 	PaymentID         uuid.UUID
 	PaymentIntentID   sql.NullString
 	UserID            uuid.UUID
-	Amount            string
+	Amount            int64
+	Currency          string
 	Status            string
 	ReservationID     uuid.NullUUID
 	Email             sql.NullString
+	DiscountAmount    sql.NullInt64
 	EventDetailID     uuid.NullUUID
 	Title             sql.NullString
 	TicketDescription sql.NullString
 	ShowDate          sql.NullTime
-	Price             sql.NullString
+	Price             sql.NullInt64
 }
 
 func (q *Queries) GetPaymentAndReservationDetails(ctx context.Context, arg GetPaymentAndReservationDetailsParams) ([]GetPaymentAndReservationDetailsRow, error) {
@@ -150,9 +210,11 @@ func (q *Queries) GetPaymentAndReservationDetails(ctx context.Context, arg GetPa
 			&i.PaymentIntentID,
 			&i.UserID,
 			&i.Amount,
+			&i.Currency,
 			&i.Status,
 			&i.ReservationID,
 			&i.Email,
+			&i.DiscountAmount,
 			&i.EventDetailID,
 			&i.Title,
 			&i.TicketDescription,
@@ -173,7 +235,7 @@ func (q *Queries) GetPaymentAndReservationDetails(ctx context.Context, arg GetPa
 }
 
 const getPaymentById = `-- name: GetPaymentById :one
-SELECT id, payment_intent_id, amount, currency, status, expires_at, created_at, updated_at, user_id FROM payments WHERE id = $1 AND user_id = $2
+SELECT id, payment_intent_id, amount, currency, status, expires_at, created_at, updated_at, user_id, discount_amount, promo_code_id FROM payments WHERE id = $1 AND user_id = $2
 `
 
 type GetPaymentByIdParams struct {
@@ -194,12 +256,14 @@ func (q *Queries) GetPaymentById(ctx context.Context, arg GetPaymentByIdParams)
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserID,
+		&i.DiscountAmount,
+		&i.PromoCodeID,
 	)
 	return i, err
 }
 
 const getPaymentByIdOnly = `-- name: GetPaymentByIdOnly :one
-SELECT id, payment_intent_id, amount, currency, status, expires_at, created_at, updated_at, user_id FROM payments WHERE id = $1
+SELECT id, payment_intent_id, amount, currency, status, expires_at, created_at, updated_at, user_id, discount_amount, promo_code_id FROM payments WHERE id = $1
 `
 
 func (q *Queries) GetPaymentByIdOnly(ctx context.Context, id uuid.UUID) (Payment, error) {
@@ -215,12 +279,14 @@ func (q *Queries) GetPaymentByIdOnly(ctx context.Context, id uuid.UUID) (Payment
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserID,
+		&i.DiscountAmount,
+		&i.PromoCodeID,
 	)
 	return i, err
 }
 
 const getPaymentByPaymentIntentId = `-- name: GetPaymentByPaymentIntentId :one
-SELECT id, payment_intent_id, amount, currency, status, expires_at, created_at, updated_at, user_id FROM payments WHERE payment_intent_id = $1
+SELECT id, payment_intent_id, amount, currency, status, expires_at, created_at, updated_at, user_id, discount_amount, promo_code_id FROM payments WHERE payment_intent_id = $1
 `
 
 func (q *Queries) GetPaymentByPaymentIntentId(ctx context.Context, paymentIntentID sql.NullString) (Payment, error) {
@@ -236,12 +302,68 @@ func (q *Queries) GetPaymentByPaymentIntentId(ctx context.Context, paymentIntent
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserID,
+		&i.DiscountAmount,
+		&i.PromoCodeID,
 	)
 	return i, err
 }
 
+const getPaymentsToReconcile = `-- name: GetPaymentsToReconcile :many
+SELECT id, payment_intent_id, amount, currency, status, expires_at, created_at, updated_at, user_id, discount_amount, promo_code_id FROM payments
+WHERE payment_intent_id IS NOT NULL
+	AND id > $1::uuid
+	AND (
+		status IN ('pending', 'requires_action', 'requires_payment_method', 'processing', 'payment_failed', 'refund_pending')
+		OR created_at >= $2::timestamp
+		OR updated_at >= $2::timestamp
+	)
+ORDER BY id
+LIMIT $3
+`
+
+type GetPaymentsToReconcileParams struct {
+	AfterID      uuid.UUID
+	ChangedSince time.Time
+	BatchSize    int32
+}
+
+func (q *Queries) GetPaymentsToReconcile(ctx context.Context, arg GetPaymentsToReconcileParams) ([]Payment, error) {
+	rows, err := q.db.QueryContext(ctx, getPaymentsToReconcile, arg.AfterID, arg.ChangedSince, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Payment
+	for rows.Next() {
+		var i Payment
+		if err := rows.Scan(
+			&i.ID,
+			&i.PaymentIntentID,
+			&i.Amount,
+			&i.Currency,
+			&i.Status,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.DiscountAmount,
+			&i.PromoCodeID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUserPayments = `-- name: GetUserPayments :many
-SELECT id, payment_intent_id, amount, currency, status, expires_at, created_at, updated_at, user_id FROM payments WHERE user_id = $1
+SELECT id, payment_intent_id, amount, currency, status, expires_at, created_at, updated_at, user_id, discount_amount, promo_code_id FROM payments WHERE user_id = $1
 `
 
 func (q *Queries) GetUserPayments(ctx context.Context, userID uuid.UUID) ([]Payment, error) {
@@ -263,6 +385,8 @@ func (q *Queries) GetUserPayments(ctx context.Context, userID uuid.UUID) ([]Paym
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.UserID,
+			&i.DiscountAmount,
+			&i.PromoCodeID,
 		); err != nil {
 			return nil, err
 		}
@@ -286,7 +410,7 @@ WITH event_details_update AS (
 ),
 payment_update AS (
 	UPDATE payments AS p
-	SET amount = p.amount - $3::numeric
+	SET amount = p.amount - $3::bigint
 	WHERE id = $4::uuid AND user_id = $5::uuid
   RETURNING p.id
 )
@@ -299,7 +423,7 @@ WHERE id = $1::uuid
 type RefundPaymentAndRestoreTicketsParams struct {
 	ReservationID uuid.UUID
 	EventDetailID uuid.UUID
-	Amount        string
+	Amount        int64
 	PaymentID     uuid.UUID
 	UserID        uuid.UUID
 }
@@ -315,49 +439,48 @@ func (q *Queries) RefundPaymentAndRestoreTickets(ctx context.Context, arg Refund
 	return err
 }
 
-const restoreTicketsAndDeletePayment = `-- name: RestoreTicketsAndDeletePayment :exec
-WITH counts AS (
-  SELECT event_detail_id, COUNT(*) AS cnt
-  FROM reservations
+const releasePaymentReservations = `-- name: ReleasePaymentReservations :exec
+WITH released AS (
+  DELETE FROM reservations
   WHERE payment_id = $1::uuid
-  GROUP BY event_detail_id
+    AND user_id = $2::uuid
+  RETURNING event_detail_id
 ),
-updated AS (
-  UPDATE event_details ed
-  SET tickets_remaining = ed.tickets_remaining + c.cnt
-  FROM counts c
-  WHERE ed.id = c.event_detail_id
-  RETURNING ed.id
+counts AS (
+  SELECT event_detail_id, COUNT(*) AS cnt
+  FROM released
+  GROUP BY event_detail_id
 )
-DELETE FROM payments
-WHERE id = $1::uuid
-  AND user_id = $2::uuid
-  AND EXISTS (SELECT 1 FROM updated)
+UPDATE event_details ed
+SET tickets_remaining = ed.tickets_remaining + c.cnt
+FROM counts c
+WHERE ed.id = c.event_detail_id
 `
 
-type RestoreTicketsAndDeletePaymentParams struct {
+type ReleasePaymentReservationsParams struct {
 	PaymentID uuid.UUID
 	UserID    uuid.UUID
 }
 
-func (q *Queries) RestoreTicketsAndDeletePayment(ctx context.Context, arg RestoreTicketsAndDeletePaymentParams) error {
-	_, err := q.db.ExecContext(ctx, restoreTicketsAndDeletePayment, arg.PaymentID, arg.UserID)
+func (q *Queries) ReleasePaymentReservations(ctx context.Context, arg ReleasePaymentReservationsParams) error {
+	_, err := q.db.ExecContext(ctx, releasePaymentReservations, arg.PaymentID, arg.UserID)
 	return err
 }
 
 const updatePayment = `-- name: UpdatePayment :one
 UPDATE payments
 SET amount = $1, status = $2, updated_at = NOW(), payment_intent_id = $3
-WHERE id = $4 AND user_id = $5
-RETURNING id, payment_intent_id, amount, currency, status, expires_at, created_at, updated_at, user_id
+WHERE id = $4 AND user_id = $5 AND status = $6::text
+RETURNING id, payment_intent_id, amount, currency, status, expires_at, created_at, updated_at, user_id, discount_amount, promo_code_id
 `
 
 type UpdatePaymentParams struct {
-	Amount          string
+	Amount          int64
 	Status          string
 	PaymentIntentID sql.NullString
 	ID              uuid.UUID
 	UserID          uuid.UUID
+	CurrentStatus   string
 }
 
 func (q *Queries) UpdatePayment(ctx context.Context, arg UpdatePaymentParams) (Payment, error) {
@@ -367,6 +490,7 @@ func (q *Queries) UpdatePayment(ctx context.Context, arg UpdatePaymentParams) (P
 		arg.PaymentIntentID,
 		arg.ID,
 		arg.UserID,
+		arg.CurrentStatus,
 	)
 	var i Payment
 	err := row.Scan(
@@ -379,6 +503,8 @@ func (q *Queries) UpdatePayment(ctx context.Context, arg UpdatePaymentParams) (P
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserID,
+		&i.DiscountAmount,
+		&i.PromoCodeID,
 	)
 	return i, err
 }
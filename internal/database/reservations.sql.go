@@ -7,12 +7,87 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const checkInReservation = `-- name: CheckInReservation :one
+UPDATE reservations
+SET checked_in_at = NOW(), checked_in_by = $1
+WHERE id = $2 AND checked_in_at IS NULL
+RETURNING id, email, created_at, updated_at, event_detail_id, user_id, payment_id, checked_in_at, checked_in_by, discount_amount, price
+`
+
+type CheckInReservationParams struct {
+	CheckedInBy uuid.NullUUID
+	ID          uuid.UUID
+}
+
+func (q *Queries) CheckInReservation(ctx context.Context, arg CheckInReservationParams) (Reservation, error) {
+	row := q.db.QueryRowContext(ctx, checkInReservation, arg.CheckedInBy, arg.ID)
+	var i Reservation
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EventDetailID,
+		&i.UserID,
+		&i.PaymentID,
+		&i.CheckedInAt,
+		&i.CheckedInBy,
+		&i.DiscountAmount,
+		&i.Price,
+	)
+	return i, err
+}
+
+const getReservationForCheckIn = `-- name: GetReservationForCheckIn :one
+SELECT
+	r.id,
+	r.email,
+	r.checked_in_at,
+	ed.event_id,
+	ed.show_date,
+	ed.ticket_description,
+	p.status AS payment_status
+FROM reservations AS r
+JOIN event_details AS ed
+	ON ed.id = r.event_detail_id
+JOIN payments AS p
+	ON p.id = r.payment_id
+WHERE r.id = $1
+`
+
+type GetReservationForCheckInRow struct {
+	ID                uuid.UUID
+	Email             string
+	CheckedInAt       sql.NullTime
+	EventID           uuid.UUID
+	ShowDate          time.Time
+	TicketDescription string
+	PaymentStatus     string
+}
+
+func (q *Queries) GetReservationForCheckIn(ctx context.Context, id uuid.UUID) (GetReservationForCheckInRow, error) {
+	row := q.db.QueryRowContext(ctx, getReservationForCheckIn, id)
+	var i GetReservationForCheckInRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CheckedInAt,
+		&i.EventID,
+		&i.ShowDate,
+		&i.TicketDescription,
+		&i.PaymentStatus,
+	)
+	return i, err
+}
+
 const getUserReservationById = `-- name: GetUserReservationById :one
-SELECT id, email, created_at, updated_at, event_detail_id, user_id, payment_id FROM reservations WHERE id = $1 AND user_id = $2
+SELECT id, email, created_at, updated_at, event_detail_id, user_id, payment_id, checked_in_at, checked_in_by, discount_amount, price FROM reservations WHERE id = $1 AND user_id = $2
 `
 
 type GetUserReservationByIdParams struct {
@@ -31,12 +106,16 @@ func (q *Queries) GetUserReservationById(ctx context.Context, arg GetUserReserva
 		&i.EventDetailID,
 		&i.UserID,
 		&i.PaymentID,
+		&i.CheckedInAt,
+		&i.CheckedInBy,
+		&i.DiscountAmount,
+		&i.Price,
 	)
 	return i, err
 }
 
 const getUserReservations = `-- name: GetUserReservations :many
-SELECT id, email, created_at, updated_at, event_detail_id, user_id, payment_id FROM reservations WHERE user_id = $1
+SELECT id, email, created_at, updated_at, event_detail_id, user_id, payment_id, checked_in_at, checked_in_by, discount_amount, price FROM reservations WHERE user_id = $1
 `
 
 func (q *Queries) GetUserReservations(ctx context.Context, userID uuid.UUID) ([]Reservation, error) {
@@ -56,6 +135,10 @@ func (q *Queries) GetUserReservations(ctx context.Context, userID uuid.UUID) ([]
 			&i.EventDetailID,
 			&i.UserID,
 			&i.PaymentID,
+			&i.CheckedInAt,
+			&i.CheckedInBy,
+			&i.DiscountAmount,
+			&i.Price,
 		); err != nil {
 			return nil, err
 		}
@@ -71,7 +154,7 @@ func (q *Queries) GetUserReservations(ctx context.Context, userID uuid.UUID) ([]
 }
 
 const getUserReservationsByPaymentId = `-- name: GetUserReservationsByPaymentId :many
-SELECT id, email, created_at, updated_at, event_detail_id, user_id, payment_id FROM reservations WHERE user_id = $1 AND payment_id = $2
+SELECT id, email, created_at, updated_at, event_detail_id, user_id, payment_id, checked_in_at, checked_in_by, discount_amount, price FROM reservations WHERE user_id = $1 AND payment_id = $2
 `
 
 type GetUserReservationsByPaymentIdParams struct {
@@ -96,6 +179,10 @@ func (q *Queries) GetUserReservationsByPaymentId(ctx context.Context, arg GetUse
 			&i.EventDetailID,
 			&i.UserID,
 			&i.PaymentID,
+			&i.CheckedInAt,
+			&i.CheckedInBy,
+			&i.DiscountAmount,
+			&i.Price,
 		); err != nil {
 			return nil, err
 		}
@@ -110,14 +197,16 @@ func (q *Queries) GetUserReservationsByPaymentId(ctx context.Context, arg GetUse
 	return items, nil
 }
 
-const reserveTicket = `-- name: ReserveTicket :one
+const reserveTicket = `-- name: ReserveTicket :one 
 WITH params AS (
     SELECT 
         $1::uuid AS event_detail_id, 
         $2::uuid AS reservation_id, 
         $3::text AS email,
         $4::uuid AS user_id,
-        $5::uuid AS payment_id), 
+        $5::uuid AS payment_id,
+        $6::bigint AS discount_amount,
+        $7::bigint AS price), 
 updated_event_detail AS ( 
     UPDATE event_details ed 
     SET tickets_remaining = ed.tickets_remaining - 1 
@@ -125,24 +214,28 @@ updated_event_detail AS (
     WHERE ed.id = p.event_detail_id AND ed.tickets_remaining > 0 
     RETURNING ed.id AS event_detail_id ) 
 
-INSERT INTO reservations (id, email, event_detail_id, user_id, payment_id) 
+INSERT INTO reservations (id, email, event_detail_id, user_id, payment_id, discount_amount, price) 
 SELECT 
     p.reservation_id AS id, 
     p.email AS email, 
     u.event_detail_id,
     p.user_id AS user_id,
-    p.payment_id AS payment_id
+    p.payment_id AS payment_id,
+    p.discount_amount AS discount_amount,
+    p.price AS price
 FROM params p 
 CROSS JOIN updated_event_detail u 
-RETURNING id AS id, email AS email, created_at AS created_at, updated_at AS updated_at, event_detail_id AS event_detail_id, user_id AS user_id, payment_id AS payment_id
+RETURNING id AS id, email AS email, created_at AS created_at, updated_at AS updated_at, event_detail_id AS event_detail_id, user_id AS user_id, payment_id AS payment_id, checked_in_at AS checked_in_at, checked_in_by AS checked_in_by, discount_amount AS discount_amount, price AS price
 `
 
 type ReserveTicketParams struct {
-	EventDetailID uuid.UUID
-	ReservationID uuid.UUID
-	Email         string
-	UserID        uuid.UUID
-	PaymentID     uuid.UUID
+	EventDetailID  uuid.UUID
+	ReservationID  uuid.UUID
+	Email          string
+	UserID         uuid.UUID
+	PaymentID      uuid.UUID
+	DiscountAmount int64
+	Price          int64
 }
 
 func (q *Queries) ReserveTicket(ctx context.Context, arg ReserveTicketParams) (Reservation, error) {
@@ -152,6 +245,8 @@ func (q *Queries) ReserveTicket(ctx context.Context, arg ReserveTicketParams) (R
 		arg.Email,
 		arg.UserID,
 		arg.PaymentID,
+		arg.DiscountAmount,
+		arg.Price,
 	)
 	var i Reservation
 	err := row.Scan(
@@ -162,6 +257,10 @@ func (q *Queries) ReserveTicket(ctx context.Context, arg ReserveTicketParams) (R
 		&i.EventDetailID,
 		&i.UserID,
 		&i.PaymentID,
+		&i.CheckedInAt,
+		&i.CheckedInBy,
+		&i.DiscountAmount,
+		&i.Price,
 	)
 	return i, err
 }
@@ -170,7 +269,7 @@ const updateUserReservationEmail = `-- name: UpdateUserReservationEmail :one
 UPDATE reservations
 SET email = $1, updated_at = NOW()
 WHERE id = $2 AND user_id = $3
-RETURNING id, email, created_at, updated_at, event_detail_id, user_id, payment_id
+RETURNING id, email, created_at, updated_at, event_detail_id, user_id, payment_id, checked_in_at, checked_in_by, discount_amount, price
 `
 
 type UpdateUserReservationEmailParams struct {
@@ -190,6 +289,10 @@ func (q *Queries) UpdateUserReservationEmail(ctx context.Context, arg UpdateUser
 		&i.EventDetailID,
 		&i.UserID,
 		&i.PaymentID,
+		&i.CheckedInAt,
+		&i.CheckedInBy,
+		&i.DiscountAmount,
+		&i.Price,
 	)
 	return i, err
 }
@@ -0,0 +1,154 @@
+package ical
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	productID = "-//Event MRS//Event MRS//EN"
+	// Content lines longer than this many octets are folded, RFC 5545 section 3.1.
+	maxLineOctets = 75
+	dateTimeUTC   = "20060102T150405Z"
+)
+
+// Methods of a calendar. Emails attach a published calendar, it is added to the calendar without asking for an RSVP.
+const (
+	MethodPublish = "PUBLISH"
+)
+
+// Statuses of an event.
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+// ContentType is the media type of an encoded calendar.
+const ContentType = "text/calendar; charset=utf-8"
+
+// Calendar is a VCALENDAR, Name is shown by the calendar clients that subscribe to it.
+type Calendar struct {
+	Name   string
+	Method string
+	Events []Event
+}
+
+// Event is a VEVENT. UID has to stay the same for the life of the event and Sequence has to go up every time it
+// changes, calendar clients only replace an event they already have with a higher sequence.
+type Event struct {
+	UID            string
+	Sequence       int32
+	Start          time.Time
+	Summary        string
+	Description    string
+	Status         string
+	OrganizerName  string
+	OrganizerEmail string
+}
+
+// UID returns a globally unique UID for id, it is scoped to the domain of email, e.g. the sender of the app's emails.
+func UID(id string, email string) string {
+	domain := "event-mrs"
+
+	if at := strings.LastIndex(email, "@"); at >= 0 && at < len(email)-1 {
+		domain = email[at+1:]
+	}
+
+	return id + "@" + domain
+}
+
+// Encode returns the calendar as an RFC 5545 .ics file, stamp is when it was created.
+func Encode(calendar Calendar, stamp time.Time) []byte {
+	var buffer bytes.Buffer
+
+	writeLine(&buffer, "BEGIN:VCALENDAR")
+	writeLine(&buffer, "VERSION:2.0")
+	writeLine(&buffer, "PRODID:"+productID)
+	writeLine(&buffer, "CALSCALE:GREGORIAN")
+
+	if calendar.Method != "" {
+		writeLine(&buffer, "METHOD:"+calendar.Method)
+	}
+
+	if calendar.Name != "" {
+		writeLine(&buffer, "X-WR-CALNAME:"+escapeText(calendar.Name))
+	}
+
+	for _, event := range calendar.Events {
+		writeLine(&buffer, "BEGIN:VEVENT")
+		writeLine(&buffer, "UID:"+escapeText(event.UID))
+		writeLine(&buffer, fmt.Sprintf("SEQUENCE:%d", event.Sequence))
+		writeLine(&buffer, "DTSTAMP:"+stamp.UTC().Format(dateTimeUTC))
+		writeLine(&buffer, "DTSTART:"+event.Start.UTC().Format(dateTimeUTC))
+		writeLine(&buffer, "SUMMARY:"+escapeText(event.Summary))
+
+		if event.Description != "" {
+			writeLine(&buffer, "DESCRIPTION:"+escapeText(event.Description))
+		}
+
+		if event.Status != "" {
+			writeLine(&buffer, "STATUS:"+event.Status)
+		}
+
+		if event.OrganizerEmail != "" {
+			organizer := "ORGANIZER"
+
+			if event.OrganizerName != "" {
+				organizer += ";CN=" + quoteParameter(event.OrganizerName)
+			}
+
+			writeLine(&buffer, organizer+":mailto:"+event.OrganizerEmail)
+		}
+
+		writeLine(&buffer, "END:VEVENT")
+	}
+
+	writeLine(&buffer, "END:VCALENDAR")
+
+	return buffer.Bytes()
+}
+
+// writeLine writes a content line ending in CRLF, folding it so no line is longer than 75 octets. A multi-byte character
+// is never split across lines.
+func writeLine(buffer *bytes.Buffer, line string) {
+	lineOctets := 0
+
+	for _, character := range line {
+		characterOctets := utf8.RuneLen(character)
+
+		if lineOctets+characterOctets > maxLineOctets {
+			buffer.WriteString("\r\n ")
+			// The space that starts a folded line counts towards its length.
+			lineOctets = 1
+		}
+
+		buffer.WriteRune(character)
+		lineOctets += characterOctets
+	}
+
+	buffer.WriteString("\r\n")
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+// escapeText escapes a TEXT value, RFC 5545 section 3.3.11.
+func escapeText(text string) string {
+	return textEscaper.Replace(text)
+}
+
+// quoteParameter quotes a parameter value, double quotes can't be escaped in a parameter so they are dropped.
+func quoteParameter(value string) string {
+	value = strings.NewReplacer(`"`, "", "\r", " ", "\n", " ").Replace(value)
+
+	return `"` + value + `"`
+}
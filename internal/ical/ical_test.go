@@ -0,0 +1,115 @@
+package ical_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/elorenzorodz/event-mrs/internal/ical"
+)
+
+var testStamp = time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+
+func TestEncode(t *testing.T) {
+	manila := time.FixedZone("PHT", 8*60*60)
+
+	calendar := ical.Calendar{
+		Name:   "Jane Doe's reservations",
+		Method: ical.MethodPublish,
+		Events: []ical.Event{
+			{
+				UID:            "0f8e4a36-2b57-4d8c-9a53-3f1f0f3b8f10@event-mrs.test",
+				Sequence:       2,
+				Start:          time.Date(2025, 6, 15, 3, 30, 0, 0, manila),
+				Summary:        "Jazz Night - VIP",
+				Description:    "Doors open at 7; bring your ticket, ID\nand a smile",
+				Status:         ical.StatusConfirmed,
+				OrganizerName:  `Blue Note "Live"`,
+				OrganizerEmail: "noreply@event-mrs.test",
+			},
+		},
+	}
+
+	encoded := string(ical.Encode(calendar, testStamp))
+
+	expectedLines := []string{
+		"BEGIN:VCALENDAR\r\n",
+		"VERSION:2.0\r\n",
+		"METHOD:PUBLISH\r\n",
+		"X-WR-CALNAME:Jane Doe's reservations\r\n",
+		"BEGIN:VEVENT\r\n",
+		"UID:0f8e4a36-2b57-4d8c-9a53-3f1f0f3b8f10@event-mrs.test\r\n",
+		"SEQUENCE:2\r\n",
+		"DTSTAMP:20250601T080000Z\r\n",
+		// The start is converted to UTC.
+		"DTSTART:20250614T193000Z\r\n",
+		"SUMMARY:Jazz Night - VIP\r\n",
+		`DESCRIPTION:Doors open at 7\; bring your ticket\, ID\nand a smile` + "\r\n",
+		"STATUS:CONFIRMED\r\n",
+		`ORGANIZER;CN="Blue Note Live":mailto:noreply@event-mrs.test` + "\r\n",
+		"END:VEVENT\r\n",
+	}
+
+	for _, expectedLine := range expectedLines {
+		if !strings.Contains(encoded, expectedLine) {
+			t.Errorf("Encode: expected line %q, got:\n%s", expectedLine, encoded)
+		}
+	}
+
+	if !strings.HasSuffix(encoded, "END:VCALENDAR\r\n") {
+		t.Errorf("Encode: expected the calendar to end with END:VCALENDAR, got:\n%s", encoded)
+	}
+}
+
+func TestEncodeFoldsLongLines(tTesting *testing.T) {
+	tests := []struct {
+		name    string
+		summary string
+	}{
+		{name: "ASCII", summary: strings.Repeat("Jazz Night ", 20)},
+		{name: "MultiByte", summary: strings.Repeat("Café Señorita ", 20)},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.name, func(t *testing.T) {
+			encoded := string(ical.Encode(ical.Calendar{Events: []ical.Event{{UID: "1@event-mrs.test", Summary: tc.summary}}}, testStamp))
+
+			for _, line := range strings.Split(strings.TrimSuffix(encoded, "\r\n"), "\r\n") {
+				if len(line) > 75 {
+					t.Errorf("Encode: expected lines of at most 75 octets, got %d: %q", len(line), line)
+				}
+
+				if !utf8.ValidString(line) {
+					t.Errorf("Encode: expected a character to never be split across lines, got: %q", line)
+				}
+			}
+
+			unfolded := strings.ReplaceAll(encoded, "\r\n ", "")
+
+			if !strings.Contains(unfolded, "SUMMARY:"+tc.summary+"\r\n") {
+				t.Errorf("Encode: expected the unfolded summary %q, got:\n%s", tc.summary, unfolded)
+			}
+		})
+	}
+}
+
+func TestUID(tTesting *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		expected string
+	}{
+		{name: "SenderDomain", email: "noreply@event-mrs.test", expected: "42@event-mrs.test"},
+		{name: "NoDomain", email: "noreply", expected: "42@event-mrs"},
+		{name: "Empty", email: "", expected: "42@event-mrs"},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.name, func(t *testing.T) {
+			if uid := ical.UID("42", tc.email); uid != tc.expected {
+				t.Errorf("UID: expected %q, got %q", tc.expected, uid)
+			}
+		})
+	}
+}
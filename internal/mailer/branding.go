@@ -0,0 +1,96 @@
+package mailer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultBrandName   = "Event - MRS"
+	defaultAccentColor = "#4f46e5"
+	defaultFooter      = "- Event - MRS Team"
+)
+
+// Branding is how the emails about an organizer's events look. Empty fields use the default Event - MRS branding, so
+// the zero value is the default.
+type Branding struct {
+	OrganizerName string
+	LogoURL       string
+	AccentColor   string
+	Footer        string
+}
+
+// BrandingQuerier is the query organizer brandings are loaded with, *database.Queries in the services.
+type BrandingQuerier interface {
+	GetOrganizerBranding(ctx context.Context, userID uuid.UUID) (database.OrganizerBranding, error)
+}
+
+func NewBranding(organizerName string, organizerBranding database.OrganizerBranding) Branding {
+	return Branding{
+		OrganizerName: organizerName,
+		LogoURL:       organizerBranding.LogoUrl.String,
+		AccentColor:   organizerBranding.AccentColor.String,
+		Footer:        organizerBranding.Footer.String,
+	}
+}
+
+// LoadBranding returns the branding of the organizer with organizerUserID. It falls back to the default branding when the
+// organizer has none or it can't be loaded, a branding is never a reason to not send an email.
+func LoadBranding(ctx context.Context, querier BrandingQuerier, organizerName string, organizerUserID uuid.UUID) Branding {
+	organizerBranding, getOrganizerBrandingError := querier.GetOrganizerBranding(ctx, organizerUserID)
+
+	if getOrganizerBrandingError != nil && !errors.Is(getOrganizerBrandingError, sql.ErrNoRows) {
+		log.Printf("error getting branding of organizer %s: %v", organizerUserID, getOrganizerBrandingError)
+	}
+
+	return NewBranding(organizerName, organizerBranding)
+}
+
+// DisplayName is shown in place of a logo.
+func (branding Branding) DisplayName() string {
+	if branding.OrganizerName != "" {
+		return branding.OrganizerName
+	}
+
+	return defaultBrandName
+}
+
+func (branding Branding) withDefaults() Branding {
+	if branding.AccentColor == "" {
+		branding.AccentColor = defaultAccentColor
+	}
+
+	if branding.Footer == "" {
+		branding.Footer = defaultFooter
+	}
+
+	return branding
+}
+
+// brandingOfEventDetails returns the branding of the organizer of the shows, an email about shows of different
+// organizers uses the default branding.
+func brandingOfEventDetails(eventDetailsWithEventTitle []database.GetEventDetailsWithTitleByIdsRow) Branding {
+	if len(eventDetailsWithEventTitle) == 0 {
+		return Branding{}
+	}
+
+	firstEventDetail := eventDetailsWithEventTitle[0]
+
+	for _, eventDetail := range eventDetailsWithEventTitle[1:] {
+		if eventDetail.OrganizerUserID != firstEventDetail.OrganizerUserID {
+			return Branding{}
+		}
+	}
+
+	return Branding{
+		OrganizerName: firstEventDetail.Organizer.String,
+		LogoURL:       firstEventDetail.BrandingLogoUrl.String,
+		AccentColor:   firstEventDetail.BrandingAccentColor.String,
+		Footer:        firstEventDetail.BrandingFooter.String,
+	}
+}
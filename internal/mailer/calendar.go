@@ -0,0 +1,41 @@
+package mailer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/ical"
+)
+
+const showsCalendarFilename = "shows.ics"
+
+// showsCalendarAttachment returns the reserved shows as an .ics file. The UID of a show is its event detail ID, the
+// same as in the calendar feed, so calendar clients don't add a show twice.
+func (m *TransportMailer) showsCalendarAttachment(eventDetailsWithEventTitle []database.GetEventDetailsWithTitleByIdsRow) Attachment {
+	calendar := ical.Calendar{Method: ical.MethodPublish}
+
+	for _, eventDetail := range eventDetailsWithEventTitle {
+		organizerName := m.senderName
+
+		if eventDetail.Organizer.Valid && eventDetail.Organizer.String != "" {
+			organizerName = eventDetail.Organizer.String
+		}
+
+		calendar.Events = append(calendar.Events, ical.Event{
+			UID:            ical.UID(eventDetail.ID.String(), m.senderEmail),
+			Sequence:       eventDetail.CalendarSequence,
+			Start:          eventDetail.ShowDate,
+			Summary:        fmt.Sprintf("%s - %s", eventDetail.Title, eventDetail.TicketDescription),
+			Status:         ical.StatusConfirmed,
+			OrganizerName:  organizerName,
+			OrganizerEmail: m.senderEmail,
+		})
+	}
+
+	return Attachment{
+		Filename:    showsCalendarFilename,
+		ContentType: ical.ContentType + "; method=" + ical.MethodPublish,
+		Content:     ical.Encode(calendar, time.Now()),
+	}
+}
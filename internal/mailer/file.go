@@ -0,0 +1,56 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FileTransport writes every email as an .eml file into a directory instead of sending it, they open in any mail client.
+type FileTransport struct {
+	directory string
+}
+
+func NewFileTransport(directory string) (*FileTransport, error) {
+	if makeDirectoryError := os.MkdirAll(directory, 0o755); makeDirectoryError != nil {
+		return nil, fmt.Errorf("failed to create mail directory %s: %w", directory, makeDirectoryError)
+	}
+
+	return &FileTransport{directory: directory}, nil
+}
+
+func (transport *FileTransport) Send(ctx context.Context, message Message) error {
+	now := time.Now()
+	messageBytes, buildMessageError := message.Bytes(now)
+
+	if buildMessageError != nil {
+		return buildMessageError
+	}
+
+	// Timestamped names keep the files in the order they were sent.
+	filename := filepath.Join(transport.directory, fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), uuid.New()))
+
+	if writeFileError := os.WriteFile(filename, messageBytes, 0o644); writeFileError != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, writeFileError)
+	}
+
+	return nil
+}
+
+// LogTransport only logs the emails, it is meant for development when nobody needs to read them.
+type LogTransport struct{}
+
+func NewLogTransport() *LogTransport {
+	return &LogTransport{}
+}
+
+func (transport *LogTransport) Send(ctx context.Context, message Message) error {
+	log.Printf("Mail | From: %s | To: %s | Subject: %s | Attachments: %d\n%s", message.From, message.To, message.Subject, len(message.Attachments), message.Text)
+
+	return nil
+}
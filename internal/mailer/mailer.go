@@ -4,35 +4,91 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"strings"
 	"time"
 
 	"github.com/elorenzorodz/event-mrs/internal/database"
-	"github.com/mailgun/mailgun-go/v4"
+	"github.com/elorenzorodz/event-mrs/internal/money"
 )
 
+// Transports that can be selected with MAIL_TRANSPORT.
+const (
+	TransportMailgun = "mailgun"
+	TransportSMTP    = "smtp"
+	TransportFile    = "file"
+	TransportLog     = "log"
+)
+
+// sendTimeout is how long a transport gets to hand over one email.
+const sendTimeout = 30 * time.Second
+
+// Mailer sends the emails of the app, the services depend on this instead of a provider. Emails about shows that come
+// with their event details use the branding of the organizer, the others take it as an argument.
+type Mailer interface {
+	SendPaymentConfirmationAndTicketReservation(recipientName string, recipientEmail string, eventDetailsWithEventTitle []database.GetEventDetailsWithTitleByIdsRow, amountPaid money.Money, tickets []TicketAttachment) error
+	SendRefundOrCancelledEmail(recipientName string, recipientEmail string, eventTitle string, message string, amount money.Money, branding Branding) error
+	SendRefundErrorNotification() error
+	SendReconciliationReport(subject string, report string) error
+	SendPaymentFailedNotification(recipientName string, recipientEmail string, errorMessage string, eventDetailsWithEventTitle []database.GetEventDetailsWithTitleByIdsRow, amount money.Money) error
+	SendUpdatedEventNotification(recipientName string, recipientEmail string, eventTitle string, eventDescription string, eventOrganizer string, branding Branding) error
+	SendPaymentExpiredNotification(recipientName string, recipientEmail string, eventDetailsWithEventTitle []database.GetEventDetailsWithTitleByIdsRow) error
+	SendEmailVerification(recipientName string, recipientEmail string, verificationLink string) error
+	SendPasswordReset(recipientName string, recipientEmail string, resetLink string) error
+	SendEventPostponedNotification(recipientName string, recipientEmail string, eventTitle string, newShowDates string, refundDeadline time.Time, branding Branding) error
+	SendEventStaffInvitation(recipientEmail string, eventTitle string, staffRole string, inviterName string, branding Branding) error
+	SendWaitlistOffer(recipientName string, recipientEmail string, eventDetail database.GetEventDetailsWithTitleByIdsRow, quantity int32, claimLink string, offerExpiresAt time.Time) error
+	SendShowReminder(recipientEmail string, eventTitle string, ticketDescription string, showDate time.Time, tickets int, branding Branding) error
+	// WithQueries returns a Mailer that enqueues its emails with querier, pass the queries of a transaction to only send
+	// the emails if it commits. Mailers that don't send through the outbox are returned as they are.
+	WithQueries(querier OutboxQuerier) Mailer
+}
+
+// Transport delivers a composed email, e.g. through the Mailgun API, an SMTP server or to disk.
+type Transport interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// Message is an email as it is handed to a transport. From and To are addresses like "Name <email>", Text is the
+// fallback for clients that don't show HTML.
+type Message struct {
+	From        string
+	To          string
+	Subject     string
+	Text        string
+	HTML        string
+	Attachments []Attachment
+}
+
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Content     []byte `json:"content"`
+}
+
 type MailerConfig struct {
-	Domain      string
-	APIKey      string
 	SenderName  string
 	SenderEmail string
 	TeamName    string
 	TeamEmail   string
 }
 
-type Mailer struct {
-	mg          *mailgun.MailgunImpl
+// TicketAttachment is a ticket QR code attached to the confirmation email.
+type TicketAttachment struct {
+	Filename string
+	PNG      []byte
+}
+
+// TransportMailer renders the emails from the templates and sends them through a Transport.
+type TransportMailer struct {
+	transport   Transport
 	senderName  string
 	senderEmail string
 	teamName    string
 	teamEmail   string
 }
 
-func NewMailer(mailerConfig MailerConfig) *Mailer {
-	mg := mailgun.NewMailgun(mailerConfig.Domain, mailerConfig.APIKey)
-
-	return &Mailer{
-		mg:          mg,
+func NewMailer(mailerConfig MailerConfig, transport Transport) *TransportMailer {
+	return &TransportMailer{
+		transport:   transport,
 		senderName:  mailerConfig.SenderName,
 		senderEmail: mailerConfig.SenderEmail,
 		teamName:    mailerConfig.TeamName,
@@ -40,147 +96,235 @@ func NewMailer(mailerConfig MailerConfig) *Mailer {
 	}
 }
 
-func (m *Mailer) buildSender() string {
+func (m *TransportMailer) WithQueries(querier OutboxQuerier) Mailer {
+	outboxTransport, ok := m.transport.(*OutboxTransport)
+
+	if !ok {
+		return m
+	}
+
+	queriesMailer := *m
+	queriesMailer.transport = outboxTransport.WithQueries(querier)
+
+	return &queriesMailer
+}
+
+func (m *TransportMailer) buildSender() string {
 	return fmt.Sprintf("%s <%s>", m.senderName, m.senderEmail)
 }
 
-func (m *Mailer) SendPaymentConfirmationAndTicketReservation(recipientName string, recipientEmail string, eventDetailsWithEventTitle []database.GetEventDetailsWithTitleByIdsRow) error {
-	eventConcat := ""
-	for _, eventDetail := range eventDetailsWithEventTitle {
-		eventConcat += fmt.Sprintf(`%s - %s - %s
-`, eventDetail.Title, eventDetail.TicketDescription, eventDetail.ShowDate)
-	}
+func (m *TransportMailer) buildTeamRecipient() string {
+	return fmt.Sprintf("%s <%s>", m.teamName, m.teamEmail)
+}
 
-	mailgunMessage := mailgun.NewMessage(
-		m.buildSender(),
-		"Your payment and ticket reservation is confirmed",
-		fmt.Sprintf(`Hi %s,
+// sendTemplate renders the email template called templateName into the message and sends it.
+func (m *TransportMailer) sendTemplate(emailMessage Message, templateName string, branding Branding, data any) error {
+	html, text, renderError := renderTemplate(templateName, emailMessage.Subject, branding, data)
 
-You've successfully booked your events. Enjoy!
-%s
+	if renderError != nil {
+		log.Printf("Mailer error | Template: %s | Recipient: %s | Error: %s", templateName, emailMessage.To, renderError)
+		return renderError
+	}
 
-- Event - MRS Team`, recipientName, eventConcat),
-		fmt.Sprintf("%s <%s>", recipientName, recipientEmail),
-	)
+	emailMessage.HTML = html
+	emailMessage.Text = text
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
 	defer cancel()
 
-	sendMessage, id, sendError := m.mg.Send(ctx, mailgunMessage)
-
-	if sendError != nil {
-		log.Printf("Mailgun error | Sender: %s <%s> | Recipient: %s <%s> | ID: %s | Message: %s | Error: %s", m.senderName, m.senderEmail, recipientName, recipientEmail, id, sendMessage, sendError)
-		return fmt.Errorf("sender: %s <%s> | recipient: %s <%s> | ID: %s | message: %s | error: %s", m.senderName, m.senderEmail, recipientName, recipientEmail, id, sendMessage, sendError)
+	if sendError := m.transport.Send(ctx, emailMessage); sendError != nil {
+		log.Printf("Mailer error | Sender: %s | Recipient: %s | Subject: %s | Error: %s", emailMessage.From, emailMessage.To, emailMessage.Subject, sendError)
+		return fmt.Errorf("sender: %s | recipient: %s | subject: %s | error: %w", emailMessage.From, emailMessage.To, emailMessage.Subject, sendError)
 	}
 
 	return nil
 }
 
-func (m *Mailer) SendRefundOrCancelledEmail(recipientName string, recipientEmail string, eventTitle string, message string) error {
-	mailgunMessage := mailgun.NewMessage(
-		m.buildSender(),
-		fmt.Sprintf("Your payment for %s was refunded/cancelled", eventTitle),
-		message,
-		fmt.Sprintf("%s <%s>", recipientName, recipientEmail),
-	)
-
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
-	defer cancel()
+func (m *TransportMailer) SendPaymentConfirmationAndTicketReservation(recipientName string, recipientEmail string, eventDetailsWithEventTitle []database.GetEventDetailsWithTitleByIdsRow, amountPaid money.Money, tickets []TicketAttachment) error {
+	emailMessage := Message{
+		From:    m.buildSender(),
+		To:      fmt.Sprintf("%s <%s>", recipientName, recipientEmail),
+		Subject: "Your payment and ticket reservation is confirmed",
+	}
 
-	sendMessage, id, sendError := m.mg.Send(ctx, mailgunMessage)
+	for _, ticket := range tickets {
+		emailMessage.Attachments = append(emailMessage.Attachments, Attachment{Filename: ticket.Filename, ContentType: "image/png", Content: ticket.PNG})
+	}
 
-	if sendError != nil {
-		log.Printf("Mailgun error | Sender: %s <%s> | Recipient: %s <%s> | ID: %s | Message: %s | Error: %s", m.senderName, m.senderEmail, recipientName, recipientEmail, id, sendMessage, sendError)
-		return fmt.Errorf("sender: %s <%s> | recipient: %s <%s> | ID: %s | message: %s | error: %s", m.senderName, m.senderEmail, recipientName, recipientEmail, id, sendMessage, sendError)
+	if len(eventDetailsWithEventTitle) > 0 {
+		emailMessage.Attachments = append(emailMessage.Attachments, m.showsCalendarAttachment(eventDetailsWithEventTitle))
 	}
 
-	return nil
+	return m.sendTemplate(emailMessage, "payment_confirmation", brandingOfEventDetails(eventDetailsWithEventTitle), map[string]any{
+		"RecipientName": recipientName,
+		"EventDetails":  eventDetailsWithEventTitle,
+		"AmountPaid":    amountPaid,
+		"HasTickets":    len(tickets) > 0,
+	})
 }
 
-func (m *Mailer) SendRefundErrorNotification() error {
-	mailgunMessage := mailgun.NewMessage(
-		m.buildSender(),
-		"A refund request has failed",
-		"A refund request has failed. Please check logs.",
-		fmt.Sprintf("%s <%s>", m.teamName, m.teamEmail),
-	)
+func (m *TransportMailer) SendRefundOrCancelledEmail(recipientName string, recipientEmail string, eventTitle string, message string, amount money.Money, branding Branding) error {
+	emailMessage := Message{
+		From:    m.buildSender(),
+		To:      fmt.Sprintf("%s <%s>", recipientName, recipientEmail),
+		Subject: fmt.Sprintf("Your payment for %s was refunded/cancelled", eventTitle),
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
-	defer cancel()
+	return m.sendTemplate(emailMessage, "refund_or_cancelled", branding, map[string]any{
+		"Message": message,
+		"Amount":  amount,
+	})
+}
 
-	sendMessage, id, sendError := m.mg.Send(ctx, mailgunMessage)
+func (m *TransportMailer) SendRefundErrorNotification() error {
+	emailMessage := Message{
+		From:    m.buildSender(),
+		To:      m.buildTeamRecipient(),
+		Subject: "A refund request has failed",
+	}
+
+	return m.sendTemplate(emailMessage, "refund_error", Branding{}, nil)
+}
 
-	if sendError != nil {
-		log.Printf("Mailgun error | Sender: %s <%s> | Recipient: %s <%s> | ID: %s | Message: %s | Error: %s", m.senderName, m.senderEmail, m.teamName, m.teamEmail, id, sendMessage, sendError)
-		return fmt.Errorf("sender: %s <%s> | recipient: %s <%s> | ID: %s | message: %s | error: %s", m.senderName, m.senderEmail, m.teamName, m.teamEmail, id, sendMessage, sendError)
+func (m *TransportMailer) SendReconciliationReport(subject string, report string) error {
+	emailMessage := Message{
+		From:    m.buildSender(),
+		To:      m.buildTeamRecipient(),
+		Subject: subject,
 	}
 
-	return nil
+	return m.sendTemplate(emailMessage, "reconciliation_report", Branding{}, map[string]any{
+		"Report": report,
+	})
 }
 
-func (m *Mailer) SendPaymentFailedNotification(recipientName string, recipientEmail string, errorMessage string, eventDetailsWithEventTitle []database.GetEventDetailsWithTitleByIdsRow) error {
-	eventConcat := ""
-	for _, eventDetail := range eventDetailsWithEventTitle {
-		eventConcat += fmt.Sprintf(`%s - %s - %s
-`, eventDetail.Title, eventDetail.TicketDescription, eventDetail.ShowDate)
+func (m *TransportMailer) SendPaymentFailedNotification(recipientName string, recipientEmail string, errorMessage string, eventDetailsWithEventTitle []database.GetEventDetailsWithTitleByIdsRow, amount money.Money) error {
+	emailMessage := Message{
+		From:    m.buildSender(),
+		To:      fmt.Sprintf("%s <%s>", recipientName, recipientEmail),
+		Subject: "Your payment failed",
 	}
 
-	mailgunMessage := mailgun.NewMessage(
-		m.buildSender(),
-		"Your payment failed",
-		fmt.Sprintf(`Hi %s,
+	return m.sendTemplate(emailMessage, "payment_failed", brandingOfEventDetails(eventDetailsWithEventTitle), map[string]any{
+		"RecipientName": recipientName,
+		"ErrorMessage":  errorMessage,
+		"EventDetails":  eventDetailsWithEventTitle,
+		"Amount":        amount,
+	})
+}
 
-Your payment failed with the following issue: %s
+func (m *TransportMailer) SendUpdatedEventNotification(recipientName string, recipientEmail string, eventTitle string, eventDescription string, eventOrganizer string, branding Branding) error {
+	emailMessage := Message{
+		From:    m.buildSender(),
+		To:      fmt.Sprintf("%s <%s>", recipientName, recipientEmail),
+		Subject: "Your booked event was updated",
+	}
 
-Reservation/s attached with the payment:
-%s
+	return m.sendTemplate(emailMessage, "event_updated", branding, map[string]any{
+		"RecipientName":    recipientName,
+		"EventTitle":       eventTitle,
+		"EventDescription": eventDescription,
+		"EventOrganizer":   eventOrganizer,
+	})
+}
 
-- Event - MRS Team`, recipientName, errorMessage, eventConcat),
-		fmt.Sprintf("%s <%s>", recipientName, recipientEmail),
-	)
+func (m *TransportMailer) SendPaymentExpiredNotification(recipientName string, recipientEmail string, eventDetailsWithEventTitle []database.GetEventDetailsWithTitleByIdsRow) error {
+	emailMessage := Message{
+		From:    m.buildSender(),
+		To:      fmt.Sprintf("%s <%s>", recipientName, recipientEmail),
+		Subject: "Your ticket reservation has expired",
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
-	defer cancel()
+	return m.sendTemplate(emailMessage, "payment_expired", brandingOfEventDetails(eventDetailsWithEventTitle), map[string]any{
+		"RecipientName": recipientName,
+		"EventDetails":  eventDetailsWithEventTitle,
+	})
+}
 
-	sendMessage, id, sendError := m.mg.Send(ctx, mailgunMessage)
+func (m *TransportMailer) SendEmailVerification(recipientName string, recipientEmail string, verificationLink string) error {
+	emailMessage := Message{
+		From:    m.buildSender(),
+		To:      fmt.Sprintf("%s <%s>", recipientName, recipientEmail),
+		Subject: "Please verify your email address",
+	}
+
+	return m.sendTemplate(emailMessage, "email_verification", Branding{}, map[string]any{
+		"RecipientName":    recipientName,
+		"VerificationLink": verificationLink,
+	})
+}
 
-	if sendError != nil {
-		log.Printf("Mailgun error | Sender: %s <%s> | Recipient: %s <%s> | ID: %s | Message: %s | Error: %s", m.senderName, m.senderEmail, recipientName, recipientEmail, id, sendMessage, sendError)
-		return fmt.Errorf("sender: %s <%s> | recipient: %s <%s> | ID: %s | message: %s | error: %s", m.senderName, m.senderEmail, recipientName, recipientEmail, id, sendMessage, sendError)
+func (m *TransportMailer) SendPasswordReset(recipientName string, recipientEmail string, resetLink string) error {
+	emailMessage := Message{
+		From:    m.buildSender(),
+		To:      fmt.Sprintf("%s <%s>", recipientName, recipientEmail),
+		Subject: "Reset your password",
 	}
 
-	return nil
+	return m.sendTemplate(emailMessage, "password_reset", Branding{}, map[string]any{
+		"RecipientName": recipientName,
+		"ResetLink":     resetLink,
+	})
 }
 
-func (m *Mailer) SendUpdatedEventNotification(recipientName string, recipientEmail string, eventTitle string, eventDescription string, eventOrganizer string) error {
-	organizerText := ""
-	if strings.TrimSpace(eventOrganizer) != "" {
-		organizerText = fmt.Sprintf("Organizer: %s\r\n", eventOrganizer)
+func (m *TransportMailer) SendEventPostponedNotification(recipientName string, recipientEmail string, eventTitle string, newShowDates string, refundDeadline time.Time, branding Branding) error {
+	emailMessage := Message{
+		From:    m.buildSender(),
+		To:      fmt.Sprintf("%s <%s>", recipientName, recipientEmail),
+		Subject: fmt.Sprintf("%s has been postponed", eventTitle),
 	}
 
-	mailgunMessage := mailgun.NewMessage(
-		m.buildSender(),
-		"Your booked event was updated",
-		fmt.Sprintf(`Hi %s,
+	return m.sendTemplate(emailMessage, "event_postponed", branding, map[string]any{
+		"RecipientName":  recipientName,
+		"EventTitle":     eventTitle,
+		"NewShowDates":   newShowDates,
+		"RefundDeadline": refundDeadline,
+	})
+}
 
-You are receiving this email because your booked event has been updated. Please refer to details below.
+func (m *TransportMailer) SendEventStaffInvitation(recipientEmail string, eventTitle string, staffRole string, inviterName string, branding Branding) error {
+	emailMessage := Message{
+		From:    m.buildSender(),
+		To:      recipientEmail,
+		Subject: fmt.Sprintf("You've been added to the staff of %s", eventTitle),
+	}
 
-Title: %s
-Description: %s
-%s
-- Event - MRS Team`, recipientName, eventTitle, eventDescription, organizerText),
-		fmt.Sprintf("%s <%s>", recipientName, recipientEmail),
-	)
+	return m.sendTemplate(emailMessage, "event_staff_invitation", branding, map[string]any{
+		"InviterName": inviterName,
+		"EventTitle":  eventTitle,
+		"StaffRole":   staffRole,
+	})
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
-	defer cancel()
+func (m *TransportMailer) SendWaitlistOffer(recipientName string, recipientEmail string, eventDetail database.GetEventDetailsWithTitleByIdsRow, quantity int32, claimLink string, offerExpiresAt time.Time) error {
+	eventDetails := []database.GetEventDetailsWithTitleByIdsRow{eventDetail}
+
+	emailMessage := Message{
+		From:    m.buildSender(),
+		To:      fmt.Sprintf("%s <%s>", recipientName, recipientEmail),
+		Subject: fmt.Sprintf("Tickets for %s are available for you", eventDetail.Title),
+	}
 
-	sendMessage, id, sendError := m.mg.Send(ctx, mailgunMessage)
+	return m.sendTemplate(emailMessage, "waitlist_offer", brandingOfEventDetails(eventDetails), map[string]any{
+		"RecipientName":  recipientName,
+		"Quantity":       quantity,
+		"EventDetails":   eventDetails,
+		"ClaimLink":      claimLink,
+		"OfferExpiresAt": offerExpiresAt,
+	})
+}
 
-	if sendError != nil {
-		log.Printf("Mailgun error | Sender: %s <%s> | Recipient: %s <%s> | ID: %s | Message: %s | Error: %s", m.senderName, m.senderEmail, recipientName, recipientEmail, id, sendMessage, sendError)
-		return fmt.Errorf("sender: %s <%s> | recipient: %s <%s> | ID: %s | message: %s | error: %s", m.senderName, m.senderEmail, recipientName, recipientEmail, id, sendMessage, sendError)
+func (m *TransportMailer) SendShowReminder(recipientEmail string, eventTitle string, ticketDescription string, showDate time.Time, tickets int, branding Branding) error {
+	emailMessage := Message{
+		From:    m.buildSender(),
+		To:      recipientEmail,
+		Subject: fmt.Sprintf("Reminder: %s is on %s", eventTitle, formatDate(showDate)),
 	}
 
-	return nil
-}
\ No newline at end of file
+	return m.sendTemplate(emailMessage, "show_reminder", branding, map[string]any{
+		"EventTitle":        eventTitle,
+		"TicketDescription": ticketDescription,
+		"ShowDate":          showDate,
+		"Tickets":           tickets,
+	})
+}
@@ -0,0 +1,284 @@
+package mailer_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/elorenzorodz/event-mrs/internal/money"
+	"github.com/google/uuid"
+)
+
+var testMailerConfig = mailer.MailerConfig{
+	SenderName:  "Event MRS",
+	SenderEmail: "noreply@event-mrs.test",
+	TeamName:    "Event MRS Team",
+	TeamEmail:   "team@event-mrs.test",
+}
+
+func TestMailerSendsThroughTransport(tTesting *testing.T) {
+	tests := []struct {
+		name                string
+		send                func(emailMailer mailer.Mailer) error
+		expectedTo          string
+		expectedSubject     string
+		expectedText        string
+		expectedAttachments int
+	}{
+		{
+			name: "PasswordReset",
+			send: func(emailMailer mailer.Mailer) error {
+				return emailMailer.SendPasswordReset("Jane Doe", "jane@example.com", "https://example.com/reset?token=abc")
+			},
+			expectedTo:      "Jane Doe <jane@example.com>",
+			expectedSubject: "Reset your password",
+			expectedText:    "https://example.com/reset?token=abc",
+		},
+		{
+			name: "PaymentConfirmationWithTickets",
+			send: func(emailMailer mailer.Mailer) error {
+				return emailMailer.SendPaymentConfirmationAndTicketReservation("Jane Doe", "jane@example.com", nil, money.New(2500, "USD"), []mailer.TicketAttachment{
+					{Filename: "ticket-1.png", PNG: []byte("png")},
+					{Filename: "ticket-2.png", PNG: []byte("png")},
+				})
+			},
+			expectedTo:          "Jane Doe <jane@example.com>",
+			expectedSubject:     "Your payment and ticket reservation is confirmed",
+			expectedText:        "Your ticket QR code/s are attached",
+			expectedAttachments: 2,
+		},
+		{
+			name: "RefundErrorToTeam",
+			send: func(emailMailer mailer.Mailer) error {
+				return emailMailer.SendRefundErrorNotification()
+			},
+			expectedTo:      "Event MRS Team <team@event-mrs.test>",
+			expectedSubject: "A refund request has failed",
+			expectedText:    "Please check logs.",
+		},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.name, func(t *testing.T) {
+			recorder := mailer.NewRecorder()
+
+			if err := tc.send(mailer.NewMailer(testMailerConfig, recorder)); err != nil {
+				t.Fatalf("Send: expected no error, got: %v", err)
+			}
+
+			messages := recorder.Messages()
+
+			if len(messages) != 1 {
+				t.Fatalf("Messages: expected 1, got %d", len(messages))
+			}
+
+			message := messages[0]
+
+			if message.From != "Event MRS <noreply@event-mrs.test>" || message.To != tc.expectedTo || message.Subject != tc.expectedSubject {
+				t.Errorf("Message: expected %q to %q, got %q from %q to %q", tc.expectedSubject, tc.expectedTo, message.Subject, message.From, message.To)
+			}
+
+			if !strings.Contains(message.Text, tc.expectedText) {
+				t.Errorf("Message: expected text to contain %q, got %q", tc.expectedText, message.Text)
+			}
+
+			if len(message.Attachments) != tc.expectedAttachments {
+				t.Errorf("Message: expected %d attachments, got %d", tc.expectedAttachments, len(message.Attachments))
+			}
+		})
+	}
+}
+
+func TestMailerReturnsTransportError(t *testing.T) {
+	transportError := errors.New("connection refused")
+	recorder := mailer.NewRecorder()
+	recorder.Err = transportError
+
+	err := mailer.NewMailer(testMailerConfig, recorder).SendEmailVerification("Jane Doe", "jane@example.com", "https://example.com/verify")
+
+	if !errors.Is(err, transportError) {
+		t.Fatalf("SendEmailVerification: expected the transport error, got: %v", err)
+	}
+}
+
+func TestPaymentConfirmationAttachesShowsCalendar(t *testing.T) {
+	recorder := mailer.NewRecorder()
+	eventDetail := database.GetEventDetailsWithTitleByIdsRow{
+		ID:                uuid.MustParse("0f8e4a36-2b57-4d8c-9a53-3f1f0f3b8f10"),
+		Title:             "Jazz Night",
+		TicketDescription: "VIP",
+		ShowDate:          time.Date(2025, 6, 14, 19, 30, 0, 0, time.UTC),
+		Organizer:         sql.NullString{String: "Blue Note Live", Valid: true},
+		CalendarSequence:  3,
+	}
+
+	err := mailer.NewMailer(testMailerConfig, recorder).SendPaymentConfirmationAndTicketReservation("Jane Doe", "jane@example.com", []database.GetEventDetailsWithTitleByIdsRow{eventDetail}, money.New(2500, "USD"), []mailer.TicketAttachment{{Filename: "ticket-1.png", PNG: []byte("png")}})
+
+	if err != nil {
+		t.Fatalf("SendPaymentConfirmationAndTicketReservation: expected no error, got: %v", err)
+	}
+
+	attachments := recorder.Messages()[0].Attachments
+
+	if len(attachments) != 2 || attachments[1].Filename != "shows.ics" || !strings.HasPrefix(attachments[1].ContentType, "text/calendar") {
+		t.Fatalf("Attachments: expected the tickets and shows.ics, got %+v", attachments)
+	}
+
+	showsCalendar := string(attachments[1].Content)
+
+	for _, expectedLine := range []string{
+		"UID:0f8e4a36-2b57-4d8c-9a53-3f1f0f3b8f10@event-mrs.test\r\n",
+		"SEQUENCE:3\r\n",
+		"DTSTART:20250614T193000Z\r\n",
+		"SUMMARY:Jazz Night - VIP\r\n",
+		"ORGANIZER;CN=\"Blue Note Live\":mailto:noreply@event-mrs.test\r\n",
+	} {
+		if !strings.Contains(showsCalendar, expectedLine) {
+			t.Errorf("shows.ics: expected line %q, got:\n%s", expectedLine, showsCalendar)
+		}
+	}
+}
+
+func TestFileTransportWritesEML(t *testing.T) {
+	directory := filepath.Join(t.TempDir(), "mail")
+	fileTransport, err := mailer.NewFileTransport(directory)
+
+	if err != nil {
+		t.Fatalf("NewFileTransport: expected no error, got: %v", err)
+	}
+
+	sentMessage := mailer.Message{
+		From:        "Event MRS <noreply@event-mrs.test>",
+		To:          "jane@example.com",
+		Subject:     "Your tickets for Café Night",
+		Text:        "Hi Jane,\n\nSee you there!",
+		Attachments: []mailer.Attachment{{Filename: "ticket.png", ContentType: "image/png", Content: []byte(strings.Repeat("qr", 100))}},
+	}
+
+	if err := fileTransport.Send(context.Background(), sentMessage); err != nil {
+		t.Fatalf("Send: expected no error, got: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(directory, "*.eml"))
+
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Glob: expected 1 .eml file, got %v (%v)", files, err)
+	}
+
+	emlFile, err := os.Open(files[0])
+
+	if err != nil {
+		t.Fatalf("Open: expected no error, got: %v", err)
+	}
+
+	defer emlFile.Close()
+
+	parsedMessage, err := mail.ReadMessage(emlFile)
+
+	if err != nil {
+		t.Fatalf("ReadMessage: expected no error, got: %v", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsedMessage.Header.Get("Subject"))
+
+	if err != nil || subject != sentMessage.Subject {
+		t.Errorf("Subject: expected %q, got %q (%v)", sentMessage.Subject, subject, err)
+	}
+
+	if _, err := parsedMessage.Header.Date(); err != nil {
+		t.Errorf("Date: expected a valid date, got: %v", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsedMessage.Header.Get("Content-Type"))
+
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type: expected multipart/mixed, got %q (%v)", mediaType, err)
+	}
+
+	multipartReader := multipart.NewReader(parsedMessage.Body, params["boundary"])
+	var partFilenames []string
+
+	for {
+		part, err := multipartReader.NextPart()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatalf("NextPart: expected no error, got: %v", err)
+		}
+
+		content, err := io.ReadAll(part)
+
+		if err != nil {
+			t.Fatalf("ReadAll: expected no error, got: %v", err)
+		}
+
+		// multipart decodes the quoted-printable text, its line breaks are CRLF in the email.
+		if part.FileName() == "" && strings.ReplaceAll(string(content), "\r\n", "\n") != sentMessage.Text {
+			t.Errorf("Text: expected %q, got %q", sentMessage.Text, content)
+		}
+
+		partFilenames = append(partFilenames, part.FileName())
+	}
+
+	if len(partFilenames) != 2 || partFilenames[1] != "ticket.png" {
+		t.Errorf("Parts: expected the text and ticket.png, got %v", partFilenames)
+	}
+}
+
+type MockOutboxQuerier struct {
+	enqueued []database.EnqueueEmailParams
+}
+
+func (mockQuerier *MockOutboxQuerier) EnqueueEmail(ctx context.Context, arg database.EnqueueEmailParams) (database.EmailOutbox, error) {
+	mockQuerier.enqueued = append(mockQuerier.enqueued, arg)
+
+	return database.EmailOutbox{Sender: arg.Sender, Recipient: arg.Recipient, Subject: arg.Subject, TextBody: arg.TextBody, HtmlBody: arg.HtmlBody, Attachments: arg.Attachments}, nil
+}
+
+func TestOutboxTransportEnqueuesWithQueries(t *testing.T) {
+	appQuerier := &MockOutboxQuerier{}
+	txQuerier := &MockOutboxQuerier{}
+	outboxMailer := mailer.NewMailer(testMailerConfig, mailer.NewOutboxTransport(appQuerier))
+
+	err := outboxMailer.WithQueries(txQuerier).SendPaymentConfirmationAndTicketReservation("Jane Doe", "jane@example.com", nil, money.New(2500, "USD"), []mailer.TicketAttachment{{Filename: "ticket-1.png", PNG: []byte("png")}})
+
+	if err != nil {
+		t.Fatalf("Send: expected no error, got: %v", err)
+	}
+
+	if len(appQuerier.enqueued) != 0 || len(txQuerier.enqueued) != 1 {
+		t.Fatalf("EnqueueEmail: expected 1 email enqueued with the transaction, got %d and %d without", len(txQuerier.enqueued), len(appQuerier.enqueued))
+	}
+
+	enqueued := txQuerier.enqueued[0]
+	message, err := mailer.OutboxMessage(database.EmailOutbox{Sender: enqueued.Sender, Recipient: enqueued.Recipient, Subject: enqueued.Subject, TextBody: enqueued.TextBody, HtmlBody: enqueued.HtmlBody, Attachments: enqueued.Attachments})
+
+	if err != nil {
+		t.Fatalf("OutboxMessage: expected no error, got: %v", err)
+	}
+
+	if message.To != "Jane Doe <jane@example.com>" || message.HTML == "" || len(message.Attachments) != 1 || string(message.Attachments[0].Content) != "png" {
+		t.Errorf("OutboxMessage: expected the confirmation with ticket-1.png, got %+v", message)
+	}
+
+	recorder := mailer.NewRecorder()
+	recorderMailer := mailer.NewMailer(testMailerConfig, recorder)
+
+	if recorderMailer.WithQueries(txQuerier) != mailer.Mailer(recorderMailer) {
+		t.Errorf("WithQueries: expected mailers without the outbox to be returned as they are")
+	}
+}
@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mailgun/mailgun-go/v4"
+)
+
+// MailgunTransport sends emails through the Mailgun API.
+type MailgunTransport struct {
+	mg *mailgun.MailgunImpl
+}
+
+func NewMailgunTransport(domain string, apiKey string) *MailgunTransport {
+	return &MailgunTransport{mg: mailgun.NewMailgun(domain, apiKey)}
+}
+
+func (transport *MailgunTransport) Send(ctx context.Context, message Message) error {
+	mailgunMessage := mailgun.NewMessage(message.From, message.Subject, message.Text, message.To)
+
+	if message.HTML != "" {
+		mailgunMessage.SetHTML(message.HTML)
+	}
+
+	for _, attachment := range message.Attachments {
+		mailgunMessage.AddBufferAttachment(attachment.Filename, attachment.Content)
+	}
+
+	sendMessage, id, sendError := transport.mg.Send(ctx, mailgunMessage)
+
+	if sendError != nil {
+		return fmt.Errorf("mailgun | ID: %s | message: %s | %w", id, sendMessage, sendError)
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// base64LineLength keeps encoded attachments within the line length limit of RFC 5322.
+const base64LineLength = 76
+
+// Bytes renders the message as a MIME email (RFC 5322), the format SMTP servers accept and .eml files are stored in.
+// Messages with HTML get a multipart/alternative body so clients that don't show HTML use the text.
+func (message Message) Bytes(date time.Time) ([]byte, error) {
+	fromAddress, parseFromError := mail.ParseAddress(message.From)
+
+	if parseFromError != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", message.From, parseFromError)
+	}
+
+	toAddress, parseToError := mail.ParseAddress(message.To)
+
+	if parseToError != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %w", message.To, parseToError)
+	}
+
+	bodyHeader, body, buildBodyError := message.body()
+
+	if buildBodyError != nil {
+		return nil, buildBodyError
+	}
+
+	var buffer bytes.Buffer
+
+	fmt.Fprintf(&buffer, "From: %s\r\n", fromAddress.String())
+	fmt.Fprintf(&buffer, "To: %s\r\n", toAddress.String())
+	fmt.Fprintf(&buffer, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buffer, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&buffer, "Message-ID: <%s@%s>\r\n", uuid.New(), addressDomain(fromAddress.Address))
+	buffer.WriteString("MIME-Version: 1.0\r\n")
+
+	if len(message.Attachments) == 0 {
+		writeHeader(&buffer, bodyHeader)
+		buffer.Write(body)
+
+		return buffer.Bytes(), nil
+	}
+
+	multipartWriter := multipart.NewWriter(&buffer)
+
+	fmt.Fprintf(&buffer, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", multipartWriter.Boundary())
+
+	bodyPart, createBodyPartError := multipartWriter.CreatePart(bodyHeader)
+
+	if createBodyPartError != nil {
+		return nil, createBodyPartError
+	}
+
+	if _, writeBodyError := bodyPart.Write(body); writeBodyError != nil {
+		return nil, writeBodyError
+	}
+
+	for _, attachment := range message.Attachments {
+		contentType := attachment.ContentType
+
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		attachmentPart, createAttachmentPartError := multipartWriter.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(contentType, map[string]string{"name": attachment.Filename})},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+		})
+
+		if createAttachmentPartError != nil {
+			return nil, createAttachmentPartError
+		}
+
+		encoded := base64.StdEncoding.EncodeToString(attachment.Content)
+
+		for len(encoded) > base64LineLength {
+			fmt.Fprintf(attachmentPart, "%s\r\n", encoded[:base64LineLength])
+			encoded = encoded[base64LineLength:]
+		}
+
+		fmt.Fprintf(attachmentPart, "%s\r\n", encoded)
+	}
+
+	if closeError := multipartWriter.Close(); closeError != nil {
+		return nil, closeError
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// body returns the text, or the text and HTML as alternatives, with the headers describing them.
+func (message Message) body() (textproto.MIMEHeader, []byte, error) {
+	var textBuffer bytes.Buffer
+
+	if writeTextError := writeQuotedPrintable(&textBuffer, message.Text); writeTextError != nil {
+		return nil, nil, writeTextError
+	}
+
+	textHeader := textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	}
+
+	if message.HTML == "" {
+		return textHeader, textBuffer.Bytes(), nil
+	}
+
+	var alternativeBuffer bytes.Buffer
+
+	alternativeWriter := multipart.NewWriter(&alternativeBuffer)
+
+	textPart, createTextPartError := alternativeWriter.CreatePart(textHeader)
+
+	if createTextPartError != nil {
+		return nil, nil, createTextPartError
+	}
+
+	if _, writeTextError := textPart.Write(textBuffer.Bytes()); writeTextError != nil {
+		return nil, nil, writeTextError
+	}
+
+	htmlPart, createHTMLPartError := alternativeWriter.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/html; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+
+	if createHTMLPartError != nil {
+		return nil, nil, createHTMLPartError
+	}
+
+	if writeHTMLError := writeQuotedPrintable(htmlPart, message.HTML); writeHTMLError != nil {
+		return nil, nil, writeHTMLError
+	}
+
+	if closeError := alternativeWriter.Close(); closeError != nil {
+		return nil, nil, closeError
+	}
+
+	alternativeHeader := textproto.MIMEHeader{
+		"Content-Type": {mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": alternativeWriter.Boundary()})},
+	}
+
+	return alternativeHeader, alternativeBuffer.Bytes(), nil
+}
+
+func writeHeader(writer io.Writer, header textproto.MIMEHeader) {
+	for _, key := range slices.Sorted(maps.Keys(header)) {
+		for _, value := range header[key] {
+			fmt.Fprintf(writer, "%s: %s\r\n", key, value)
+		}
+	}
+
+	fmt.Fprint(writer, "\r\n")
+}
+
+func writeQuotedPrintable(writer io.Writer, text string) error {
+	quotedPrintableWriter := quotedprintable.NewWriter(writer)
+
+	if _, writeError := quotedPrintableWriter.Write([]byte(text)); writeError != nil {
+		return writeError
+	}
+
+	return quotedPrintableWriter.Close()
+}
+
+func addressDomain(address string) string {
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		return address[at+1:]
+	}
+
+	return "localhost"
+}
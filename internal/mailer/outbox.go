@@ -0,0 +1,76 @@
+package mailer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/elorenzorodz/event-mrs/internal/database"
+)
+
+// OutboxQuerier is the query emails are enqueued with, *database.Queries or the queries of a transaction.
+type OutboxQuerier interface {
+	EnqueueEmail(ctx context.Context, arg database.EnqueueEmailParams) (database.EmailOutbox, error)
+}
+
+// OutboxTransport doesn't send emails, it writes them to the email_outbox table and the outbox worker sends them with
+// the configured transport. Emails enqueued with the queries of a transaction are only sent if it commits.
+type OutboxTransport struct {
+	querier OutboxQuerier
+}
+
+func NewOutboxTransport(querier OutboxQuerier) *OutboxTransport {
+	return &OutboxTransport{querier: querier}
+}
+
+// WithQueries returns a transport that enqueues with querier instead.
+func (outboxTransport *OutboxTransport) WithQueries(querier OutboxQuerier) *OutboxTransport {
+	return &OutboxTransport{querier: querier}
+}
+
+func (outboxTransport *OutboxTransport) Send(ctx context.Context, message Message) error {
+	attachments := message.Attachments
+
+	if attachments == nil {
+		attachments = []Attachment{}
+	}
+
+	attachmentsJSON, marshalError := json.Marshal(attachments)
+
+	if marshalError != nil {
+		return fmt.Errorf("failed to encode attachments: %w", marshalError)
+	}
+
+	_, enqueueError := outboxTransport.querier.EnqueueEmail(ctx, database.EnqueueEmailParams{
+		Sender:      message.From,
+		Recipient:   message.To,
+		Subject:     message.Subject,
+		TextBody:    message.Text,
+		HtmlBody:    message.HTML,
+		Attachments: attachmentsJSON,
+	})
+
+	if enqueueError != nil {
+		return fmt.Errorf("failed to enqueue email: %w", enqueueError)
+	}
+
+	return nil
+}
+
+// OutboxMessage returns the message an email in the outbox was enqueued with.
+func OutboxMessage(outboxEmail database.EmailOutbox) (Message, error) {
+	var attachments []Attachment
+
+	if unmarshalError := json.Unmarshal(outboxEmail.Attachments, &attachments); unmarshalError != nil {
+		return Message{}, fmt.Errorf("failed to decode attachments of email %s: %w", outboxEmail.ID, unmarshalError)
+	}
+
+	return Message{
+		From:        outboxEmail.Sender,
+		To:          outboxEmail.Recipient,
+		Subject:     outboxEmail.Subject,
+		Text:        outboxEmail.TextBody,
+		HTML:        outboxEmail.HtmlBody,
+		Attachments: attachments,
+	}, nil
+}
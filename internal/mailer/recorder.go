@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+)
+
+// Recorder keeps the emails in memory so tests can assert what was sent. Err makes every send fail.
+type Recorder struct {
+	mutex    sync.Mutex
+	messages []Message
+	Err      error
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (recorder *Recorder) Send(ctx context.Context, message Message) error {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	if recorder.Err != nil {
+		return recorder.Err
+	}
+
+	recorder.messages = append(recorder.messages, message)
+
+	return nil
+}
+
+// Messages returns the emails sent so far, oldest first.
+func (recorder *Recorder) Messages() []Message {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	return append([]Message(nil), recorder.messages...)
+}
+
+// Reset forgets the emails sent so far.
+func (recorder *Recorder) Reset() {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.messages = nil
+}
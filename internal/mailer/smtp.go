@@ -0,0 +1,72 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+// SMTPTransport sends emails to an SMTP server, e.g. a local MailHog while developing. Without a username the server is
+// used without authentication.
+type SMTPTransport struct {
+	address  string
+	host     string
+	username string
+	password string
+}
+
+func NewSMTPTransport(host string, port string, username string, password string) *SMTPTransport {
+	return &SMTPTransport{
+		address:  net.JoinHostPort(host, port),
+		host:     host,
+		username: username,
+		password: password,
+	}
+}
+
+func (transport *SMTPTransport) Send(ctx context.Context, message Message) error {
+	fromAddress, parseFromError := mail.ParseAddress(message.From)
+
+	if parseFromError != nil {
+		return fmt.Errorf("invalid sender %q: %w", message.From, parseFromError)
+	}
+
+	toAddress, parseToError := mail.ParseAddress(message.To)
+
+	if parseToError != nil {
+		return fmt.Errorf("invalid recipient %q: %w", message.To, parseToError)
+	}
+
+	messageBytes, buildMessageError := message.Bytes(time.Now())
+
+	if buildMessageError != nil {
+		return buildMessageError
+	}
+
+	var smtpAuth smtp.Auth
+
+	if transport.username != "" {
+		smtpAuth = smtp.PlainAuth("", transport.username, transport.password, transport.host)
+	}
+
+	// net/smtp doesn't take a context, the send runs in the background so the caller's deadline still applies.
+	sendResult := make(chan error, 1)
+
+	go func() {
+		sendResult <- smtp.SendMail(transport.address, smtpAuth, fromAddress.Address, []string{toAddress.Address}, messageBytes)
+	}()
+
+	select {
+	case sendError := <-sendResult:
+		if sendError != nil {
+			return fmt.Errorf("smtp %s: %w", transport.address, sendError)
+		}
+
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("smtp %s: %w", transport.address, ctx.Err())
+	}
+}
@@ -0,0 +1,104 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Every email is templates/emails/<name>.html and <name>.txt, both define "content" and are rendered inside the
+// "layout" of templates/layouts with the partials of templates/partials.
+//
+//go:embed templates
+var templateFiles embed.FS
+
+var templateFuncs = map[string]any{
+	"date":   formatDate,
+	"button": newButton,
+}
+
+type emailTemplate struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// emailTemplates are parsed once on startup, the files are embedded so a broken template fails every mailer test.
+var emailTemplates = mustParseEmailTemplates()
+
+// templateData is what the layouts and the email templates are executed with, Data holds the fields of the email.
+type templateData struct {
+	Subject  string
+	Branding Branding
+	Data     any
+}
+
+type button struct {
+	URL   string
+	Label string
+	Color string
+}
+
+func newButton(url string, label string, color string) button {
+	return button{URL: url, Label: label, Color: color}
+}
+
+func formatDate(date time.Time) string {
+	return date.Format("2006-01-02 15:04")
+}
+
+func mustParseEmailTemplates() map[string]emailTemplate {
+	htmlBase := htmltemplate.Must(htmltemplate.New("base").Funcs(templateFuncs).ParseFS(templateFiles, "templates/layouts/*.html", "templates/partials/*.html"))
+	textBase := texttemplate.Must(texttemplate.New("base").Funcs(templateFuncs).ParseFS(templateFiles, "templates/layouts/*.txt", "templates/partials/*.txt"))
+
+	emailFiles, globError := fs.Glob(templateFiles, "templates/emails/*.html")
+
+	if globError != nil {
+		panic(globError)
+	}
+
+	parsedTemplates := make(map[string]emailTemplate, len(emailFiles))
+
+	for _, emailFile := range emailFiles {
+		name := strings.TrimSuffix(path.Base(emailFile), ".html")
+
+		parsedTemplates[name] = emailTemplate{
+			html: htmltemplate.Must(htmltemplate.Must(htmlBase.Clone()).ParseFS(templateFiles, emailFile)),
+			text: texttemplate.Must(texttemplate.Must(textBase.Clone()).ParseFS(templateFiles, fmt.Sprintf("templates/emails/%s.txt", name))),
+		}
+	}
+
+	return parsedTemplates
+}
+
+// renderTemplate renders the HTML and text body of the email template called name.
+func renderTemplate(name string, subject string, branding Branding, data any) (string, string, error) {
+	parsedTemplate, ok := emailTemplates[name]
+
+	if !ok {
+		return "", "", fmt.Errorf("email template %s not found", name)
+	}
+
+	executeData := templateData{
+		Subject:  subject,
+		Branding: branding.withDefaults(),
+		Data:     data,
+	}
+
+	var htmlBuffer, textBuffer bytes.Buffer
+
+	if executeError := parsedTemplate.html.ExecuteTemplate(&htmlBuffer, "layout", executeData); executeError != nil {
+		return "", "", fmt.Errorf("error rendering %s.html: %w", name, executeError)
+	}
+
+	if executeError := parsedTemplate.text.ExecuteTemplate(&textBuffer, "layout", executeData); executeError != nil {
+		return "", "", fmt.Errorf("error rendering %s.txt: %w", name, executeError)
+	}
+
+	return htmlBuffer.String(), textBuffer.String(), nil
+}
@@ -0,0 +1,182 @@
+package mailer_test
+
+import (
+	"database/sql"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/elorenzorodz/event-mrs/internal/money"
+	"github.com/google/uuid"
+)
+
+// Run go test ./internal/mailer -update after changing a template and review the diff of testdata/golden.
+var updateGolden = flag.Bool("update", false, "update the golden files of the email templates")
+
+var (
+	testOrganizerID = uuid.MustParse("8c5d4f63-95a4-4f57-9a53-3f1f0f3b8f10")
+	testShowDate    = time.Date(2025, 6, 14, 19, 30, 0, 0, time.UTC)
+
+	testBranding = mailer.Branding{
+		OrganizerName: "Blue Note Live",
+		LogoURL:       "https://cdn.example.com/blue-note.png",
+		AccentColor:   "#1d4ed8",
+		Footer:        "Blue Note Live\n12 Harbour Street, Manila",
+	}
+
+	testEventDetails = []database.GetEventDetailsWithTitleByIdsRow{
+		{
+			Title:               "Jazz Night",
+			TicketDescription:   "VIP",
+			ShowDate:            testShowDate,
+			Organizer:           sql.NullString{String: "Blue Note Live", Valid: true},
+			OrganizerUserID:     testOrganizerID,
+			BrandingLogoUrl:     sql.NullString{String: "https://cdn.example.com/blue-note.png", Valid: true},
+			BrandingAccentColor: sql.NullString{String: "#1d4ed8", Valid: true},
+			BrandingFooter:      sql.NullString{String: "Blue Note Live\n12 Harbour Street, Manila", Valid: true},
+		},
+		{
+			Title:             "Jazz Night",
+			TicketDescription: "General Admission",
+			ShowDate:          testShowDate.Add(24 * time.Hour),
+			Organizer:         sql.NullString{String: "Blue Note Live", Valid: true},
+			OrganizerUserID:   testOrganizerID,
+			// Rows of the same organizer carry the same branding, the first row is used.
+		},
+	}
+)
+
+func TestEmailTemplatesGolden(tTesting *testing.T) {
+	tests := []struct {
+		name string
+		send func(emailMailer mailer.Mailer) error
+	}{
+		{
+			name: "payment_confirmation",
+			send: func(emailMailer mailer.Mailer) error {
+				return emailMailer.SendPaymentConfirmationAndTicketReservation("Jane Doe", "jane@example.com", testEventDetails, money.New(12550, "PHP"), []mailer.TicketAttachment{{Filename: "ticket.png", PNG: []byte("png")}})
+			},
+		},
+		{
+			name: "refund_or_cancelled",
+			send: func(emailMailer mailer.Mailer) error {
+				return emailMailer.SendRefundOrCancelledEmail("Jane Doe", "jane@example.com", "Jazz Night", "Jazz Night was cancelled by the organizer.\nWe're sorry for the inconvenience.", money.New(12550, "PHP"), testBranding)
+			},
+		},
+		{
+			name: "refund_error",
+			send: func(emailMailer mailer.Mailer) error {
+				return emailMailer.SendRefundErrorNotification()
+			},
+		},
+		{
+			name: "reconciliation_report",
+			send: func(emailMailer mailer.Mailer) error {
+				return emailMailer.SendReconciliationReport("Payment reconciliation: 1 issue", "Checked: 12\nFlagged: 1\n\n- pi_123 amount_mismatch: <payment 2500, gateway 2000>")
+			},
+		},
+		{
+			name: "payment_failed",
+			send: func(emailMailer mailer.Mailer) error {
+				return emailMailer.SendPaymentFailedNotification("Jane Doe", "jane@example.com", "Your card was declined.", testEventDetails, money.New(12550, "PHP"))
+			},
+		},
+		{
+			name: "event_updated",
+			send: func(emailMailer mailer.Mailer) error {
+				return emailMailer.SendUpdatedEventNotification("Jane Doe", "jane@example.com", "Jazz Night", "An evening of <live> jazz & blues.", "Blue Note Live", testBranding)
+			},
+		},
+		{
+			name: "payment_expired",
+			send: func(emailMailer mailer.Mailer) error {
+				return emailMailer.SendPaymentExpiredNotification("Jane Doe", "jane@example.com", testEventDetails)
+			},
+		},
+		{
+			name: "email_verification",
+			send: func(emailMailer mailer.Mailer) error {
+				return emailMailer.SendEmailVerification("Jane Doe", "jane@example.com", "https://api.example.com/api/v1/account/verify?token=abc")
+			},
+		},
+		{
+			name: "password_reset",
+			send: func(emailMailer mailer.Mailer) error {
+				return emailMailer.SendPasswordReset("Jane Doe", "jane@example.com", "https://api.example.com/api/v1/account/password/reset?token=abc")
+			},
+		},
+		{
+			name: "event_postponed",
+			send: func(emailMailer mailer.Mailer) error {
+				return emailMailer.SendEventPostponedNotification("Jane Doe", "jane@example.com", "Jazz Night", "VIP - 2025-07-12 19:30\nGeneral Admission - 2025-07-13 19:30\n", testShowDate.Add(14*24*time.Hour), testBranding)
+			},
+		},
+		{
+			name: "event_staff_invitation",
+			send: func(emailMailer mailer.Mailer) error {
+				return emailMailer.SendEventStaffInvitation("staff@example.com", "Jazz Night", "scanner", "John Smith", mailer.Branding{OrganizerName: "Blue Note Live"})
+			},
+		},
+		{
+			name: "waitlist_offer",
+			send: func(emailMailer mailer.Mailer) error {
+				return emailMailer.SendWaitlistOffer("Jane Doe", "jane@example.com", testEventDetails[0], 2, "https://api.example.com/api/v1/waitlist/claim?token=abc", testShowDate.Add(-48*time.Hour))
+			},
+		},
+		{
+			name: "show_reminder",
+			send: func(emailMailer mailer.Mailer) error {
+				return emailMailer.SendShowReminder("jane@example.com", "Jazz Night", "VIP", testShowDate, 2, testBranding)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.name, func(t *testing.T) {
+			recorder := mailer.NewRecorder()
+
+			if err := tc.send(mailer.NewMailer(testMailerConfig, recorder)); err != nil {
+				t.Fatalf("Send: expected no error, got: %v", err)
+			}
+
+			messages := recorder.Messages()
+
+			if len(messages) != 1 {
+				t.Fatalf("Messages: expected 1, got %d", len(messages))
+			}
+
+			assertGolden(t, filepath.Join("testdata", "golden", tc.name+".html"), messages[0].HTML)
+			assertGolden(t, filepath.Join("testdata", "golden", tc.name+".txt"), messages[0].Text)
+		})
+	}
+}
+
+func assertGolden(t *testing.T, goldenFile string, actual string) {
+	t.Helper()
+
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(goldenFile), 0o755); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+
+		if err := os.WriteFile(goldenFile, []byte(actual), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+
+		return
+	}
+
+	expected, err := os.ReadFile(goldenFile)
+
+	if err != nil {
+		t.Fatalf("ReadFile: expected golden file %s, got: %v (run with -update to create it)", goldenFile, err)
+	}
+
+	if string(expected) != actual {
+		t.Errorf("%s doesn't match the rendered email (run with -update if the change is intended):\n%s", goldenFile, actual)
+	}
+}
@@ -0,0 +1,191 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is charged when nothing else decides the currency.
+const DefaultCurrency = "USD"
+
+var (
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrInvalidCurrency  = errors.New("invalid currency")
+	ErrCurrencyMismatch = errors.New("amounts are in different currencies")
+)
+
+// currencyExponents lists the ISO 4217 currencies that don't have two decimals, like JPY with none or KWD with three.
+var currencyExponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// Money is an amount in the minor unit of its currency, e.g. cents for USD and yen for JPY.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: NormalizeCurrency(currency)}
+}
+
+// NormalizeCurrency upper cases the code, an empty code becomes DefaultCurrency.
+func NormalizeCurrency(currency string) string {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+
+	if currency == "" {
+		return DefaultCurrency
+	}
+
+	return currency
+}
+
+// ValidateCurrency accepts three letter codes, the payment provider rejects codes it doesn't support.
+func ValidateCurrency(currency string) error {
+	currency = NormalizeCurrency(currency)
+
+	if len(currency) != 3 {
+		return fmt.Errorf("%w: %s", ErrInvalidCurrency, currency)
+	}
+
+	for _, letter := range currency {
+		if letter < 'A' || letter > 'Z' {
+			return fmt.Errorf("%w: %s", ErrInvalidCurrency, currency)
+		}
+	}
+
+	return nil
+}
+
+// Exponent is the number of decimals of the currency's minor unit.
+func Exponent(currency string) int {
+	if exponent, ok := currencyExponents[NormalizeCurrency(currency)]; ok {
+		return exponent
+	}
+
+	return 2
+}
+
+// Parse reads a decimal amount like "12.50" into minor units, more decimals than the currency has are rejected instead of rounded.
+func Parse(decimal string, currency string) (Money, error) {
+	decimal = strings.TrimSpace(decimal)
+	exponent := Exponent(currency)
+
+	rationalAmount, ok := new(big.Rat).SetString(decimal)
+
+	if !ok || strings.ContainsAny(decimal, "eE/") {
+		return Money{}, fmt.Errorf("%w: %s", ErrInvalidAmount, decimal)
+	}
+
+	minorUnits := rationalAmount.Mul(rationalAmount, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)))
+
+	if !minorUnits.IsInt() {
+		return Money{}, fmt.Errorf("%w: %s has more than %d decimals for %s", ErrInvalidAmount, decimal, exponent, NormalizeCurrency(currency))
+	}
+
+	if !minorUnits.Num().IsInt64() {
+		return Money{}, fmt.Errorf("%w: %s is too large", ErrInvalidAmount, decimal)
+	}
+
+	return New(minorUnits.Num().Int64(), currency), nil
+}
+
+// Decimal formats the amount with the currency's decimals, e.g. "12.50" for USD and "1250" for JPY.
+func (m Money) Decimal() string {
+	exponent := Exponent(m.Currency)
+	amount := m.Amount
+	sign := ""
+
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	digits := strconv.FormatInt(amount, 10)
+
+	if exponent == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+func (m Money) String() string {
+	return fmt.Sprintf("%s %s", m.Decimal(), NormalizeCurrency(m.Currency))
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if NormalizeCurrency(m.Currency) != NormalizeCurrency(other.Currency) {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+
+	return New(m.Amount+other.Amount, m.Currency), nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	return m.Add(New(-other.Amount, other.Currency))
+}
+
+func (m Money) Mul(quantity int64) Money {
+	return New(m.Amount*quantity, m.Currency)
+}
+
+// Allocate splits the amount in proportion to the weights, the minor units lost to rounding go to the largest remainders
+// so the parts always add up to the amount. Earlier weights win ties. Amount and weights must not be negative.
+func (m Money) Allocate(weights []int64) []Money {
+	parts := make([]Money, len(weights))
+
+	var totalWeight int64
+
+	for i, weight := range weights {
+		parts[i] = New(0, m.Currency)
+		totalWeight += weight
+	}
+
+	if totalWeight <= 0 {
+		return parts
+	}
+
+	type partRemainder struct {
+		index     int
+		remainder int64
+	}
+
+	var (
+		allocated  int64
+		remainders = make([]partRemainder, 0, len(weights))
+	)
+
+	for i, weight := range weights {
+		share := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(weight))
+		quotient, remainder := new(big.Int).QuoRem(share, big.NewInt(totalWeight), new(big.Int))
+
+		parts[i].Amount = quotient.Int64()
+		allocated += quotient.Int64()
+		remainders = append(remainders, partRemainder{index: i, remainder: remainder.Int64()})
+	}
+
+	sort.SliceStable(remainders, func(a, b int) bool {
+		return remainders[a].remainder > remainders[b].remainder
+	})
+
+	for _, partRemainder := range remainders[:m.Amount-allocated] {
+		parts[partRemainder.index].Amount++
+	}
+
+	return parts
+}
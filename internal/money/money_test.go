@@ -0,0 +1,135 @@
+package money_test
+
+import (
+	"errors"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/elorenzorodz/event-mrs/internal/money"
+)
+
+func TestParse(tTesting *testing.T) {
+	tests := []struct {
+		name           string
+		decimal        string
+		currency       string
+		expectedAmount int64
+		expectedError  error
+	}{
+		{name: "USD", decimal: "12.50", currency: "usd", expectedAmount: 1250},
+		{name: "USDWholeNumber", decimal: "12", currency: "USD", expectedAmount: 1200},
+		{name: "USDOneDecimal", decimal: "0.1", currency: "USD", expectedAmount: 10},
+		{name: "JPY", decimal: "1500", currency: "JPY", expectedAmount: 1500},
+		{name: "KWD", decimal: "1.234", currency: "KWD", expectedAmount: 1234},
+		{name: "DefaultCurrency", decimal: "3.99", currency: "", expectedAmount: 399},
+		{name: "USDTooManyDecimals", decimal: "0.015", currency: "USD", expectedError: money.ErrInvalidAmount},
+		{name: "JPYWithDecimals", decimal: "1500.5", currency: "JPY", expectedError: money.ErrInvalidAmount},
+		{name: "Exponent", decimal: "1e3", currency: "USD", expectedError: money.ErrInvalidAmount},
+		{name: "NotANumber", decimal: "ten", currency: "USD", expectedError: money.ErrInvalidAmount},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.name, func(t *testing.T) {
+			parsedMoney, err := money.Parse(tc.decimal, tc.currency)
+
+			if tc.expectedError != nil {
+				if !errors.Is(err, tc.expectedError) {
+					t.Fatalf("Parse: expected error %v, got: %v", tc.expectedError, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Parse: expected no error, got: %v", err)
+			}
+
+			if parsedMoney.Amount != tc.expectedAmount {
+				t.Errorf("Parse: expected amount %d, got %d", tc.expectedAmount, parsedMoney.Amount)
+			}
+		})
+	}
+}
+
+func TestDecimal(tTesting *testing.T) {
+	tests := []struct {
+		money    money.Money
+		expected string
+	}{
+		{money: money.New(1250, "USD"), expected: "12.50"},
+		{money: money.New(5, "USD"), expected: "0.05"},
+		{money: money.New(-5, "USD"), expected: "-0.05"},
+		{money: money.New(1500, "JPY"), expected: "1500"},
+		{money: money.New(1, "KWD"), expected: "0.001"},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.money.String(), func(t *testing.T) {
+			if decimal := tc.money.Decimal(); decimal != tc.expected {
+				t.Errorf("Decimal: expected %s, got %s", tc.expected, decimal)
+			}
+		})
+	}
+}
+
+func TestAddRejectsDifferentCurrencies(t *testing.T) {
+	if _, err := money.New(100, "USD").Add(money.New(100, "JPY")); !errors.Is(err, money.ErrCurrencyMismatch) {
+		t.Fatalf("Add: expected ErrCurrencyMismatch, got: %v", err)
+	}
+}
+
+func TestParseDecimalRoundTripProperty(t *testing.T) {
+	random := rand.New(rand.NewPCG(1, 2))
+	currencies := []string{"USD", "JPY", "KWD", "EUR"}
+
+	for i := 0; i < 10000; i++ {
+		original := money.New(random.Int64N(2_000_000_000)-1_000_000_000, currencies[random.IntN(len(currencies))])
+
+		parsedMoney, err := money.Parse(original.Decimal(), original.Currency)
+
+		if err != nil {
+			t.Fatalf("Parse(%s): expected no error, got: %v", original.Decimal(), err)
+		}
+
+		if parsedMoney != original {
+			t.Fatalf("Parse(Decimal()): expected %v, got %v", original, parsedMoney)
+		}
+	}
+}
+
+func TestAllocateProperty(t *testing.T) {
+	random := rand.New(rand.NewPCG(3, 4))
+
+	for i := 0; i < 10000; i++ {
+		amount := money.New(random.Int64N(1_000_000), "USD")
+		weights := make([]int64, 1+random.IntN(20))
+
+		for w := range weights {
+			weights[w] = random.Int64N(100_000)
+		}
+
+		parts := amount.Allocate(weights)
+
+		var (
+			allocated   int64
+			totalWeight int64
+		)
+
+		for w, part := range parts {
+			allocated += part.Amount
+			totalWeight += weights[w]
+
+			if part.Amount < 0 {
+				t.Fatalf("Allocate(%d, %v): negative part %d", amount.Amount, weights, part.Amount)
+			}
+
+			if weights[w] == 0 && part.Amount != 0 {
+				t.Fatalf("Allocate(%d, %v): part with zero weight got %d", amount.Amount, weights, part.Amount)
+			}
+		}
+
+		if totalWeight > 0 && allocated != amount.Amount {
+			t.Fatalf("Allocate(%d, %v): parts add up to %d", amount.Amount, weights, allocated)
+		}
+	}
+}
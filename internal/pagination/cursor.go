@@ -0,0 +1,66 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidLimit  = errors.New("invalid limit")
+)
+
+// Cursor points at the last row of a page. Value holds the sort column of that row and ID breaks ties between equal values.
+type Cursor struct {
+	Sort  string    `json:"s"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+// EncodeCursor turns a cursor into an opaque URL safe string clients send back as is.
+func EncodeCursor(cursor Cursor) string {
+	cursorJSON, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(cursorJSON)
+}
+
+// DecodeCursor reverses EncodeCursor. A cursor created for a different sort is rejected because its value wouldn't line up.
+func DecodeCursor(encodedCursor string, sort string) (Cursor, error) {
+	cursorJSON, decodeError := base64.RawURLEncoding.DecodeString(encodedCursor)
+
+	if decodeError != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+
+	if unmarshalError := json.Unmarshal(cursorJSON, &cursor); unmarshalError != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	if cursor.Sort != sort || cursor.ID == uuid.Nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+// ParseLimit returns defaultLimit for an empty query and rejects anything outside 1 to maxLimit.
+func ParseLimit(limitQuery string, defaultLimit int32, maxLimit int32) (int32, error) {
+	if strings.TrimSpace(limitQuery) == "" {
+		return defaultLimit, nil
+	}
+
+	parsedLimit, parseLimitError := strconv.ParseInt(limitQuery, 10, 32)
+
+	if parseLimitError != nil || parsedLimit < 1 || parsedLimit > int64(maxLimit) {
+		return 0, ErrInvalidLimit
+	}
+
+	return int32(parsedLimit), nil
+}
\ No newline at end of file
@@ -0,0 +1,69 @@
+package pagination_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/elorenzorodz/event-mrs/internal/pagination"
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := pagination.Cursor{Sort: "price", Value: "1250", ID: uuid.New()}
+
+	decodedCursor, decodeError := pagination.DecodeCursor(pagination.EncodeCursor(cursor), "price")
+
+	if decodeError != nil {
+		t.Fatalf("Expected no error, got: %v", decodeError)
+	}
+
+	if decodedCursor != cursor {
+		t.Errorf("Expected cursor %+v, got %+v", cursor, decodedCursor)
+	}
+}
+
+func TestDecodeCursorRejectsInvalidInput(t *testing.T) {
+	validCursor := pagination.EncodeCursor(pagination.Cursor{Sort: "price", Value: "1250", ID: uuid.New()})
+
+	tests := []struct {
+		name          string
+		encodedCursor string
+		sort          string
+	}{
+		{name: "NotBase64", encodedCursor: "%%%", sort: "price"},
+		{name: "NotJSON", encodedCursor: "bm90LWpzb24", sort: "price"},
+		{name: "DifferentSort", encodedCursor: validCursor, sort: "show_date"},
+		{name: "MissingID", encodedCursor: pagination.EncodeCursor(pagination.Cursor{Sort: "price", Value: "1"}), sort: "price"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, decodeError := pagination.DecodeCursor(tc.encodedCursor, tc.sort); !errors.Is(decodeError, pagination.ErrInvalidCursor) {
+				t.Errorf("Expected ErrInvalidCursor, got: %v", decodeError)
+			}
+		})
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		limitQuery    string
+		expectedLimit int32
+		expectedError error
+	}{
+		{limitQuery: "", expectedLimit: 20},
+		{limitQuery: "5", expectedLimit: 5},
+		{limitQuery: "100", expectedLimit: 100},
+		{limitQuery: "0", expectedError: pagination.ErrInvalidLimit},
+		{limitQuery: "101", expectedError: pagination.ErrInvalidLimit},
+		{limitQuery: "ten", expectedError: pagination.ErrInvalidLimit},
+	}
+
+	for _, tc := range tests {
+		limit, parseLimitError := pagination.ParseLimit(tc.limitQuery, 20, 100)
+
+		if !errors.Is(parseLimitError, tc.expectedError) || limit != tc.expectedLimit {
+			t.Errorf("ParseLimit(%q): expected %d, %v, got %d, %v", tc.limitQuery, tc.expectedLimit, tc.expectedError, limit, parseLimitError)
+		}
+	}
+}
\ No newline at end of file
@@ -0,0 +1,429 @@
+package paymentgateway
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/clock"
+)
+
+// Payment methods the fake understands, named after Stripe's test payment methods so clients work against both.
+// Any other payment method is charged like FakeCardSucceeds.
+const (
+	FakeCardSucceeds       = "pm_card_visa"
+	FakeCardRequiresAction = "pm_card_authenticationRequired"
+	FakeCardDeclined       = "pm_card_chargeDeclined"
+	FakeCardDelayedRefund  = "pm_fake_delayedRefund"
+	FakeCardRefundFails    = "pm_card_refundFail"
+)
+
+// Delivery is a webhook the fake sends, WebhookType is "payment" or "refund" like the two webhook endpoints.
+type Delivery struct {
+	WebhookType string
+	Payload     []byte
+	Signature   string
+}
+
+// Fake is an in-process provider for development and tests. It never touches the network, every outcome is decided by
+// the payment method and ids come from a counter, so the same calls always give the same results.
+type Fake struct {
+	mutex               sync.Mutex
+	signingSecret       string
+	refundSigningSecret string
+	sequence            int
+	intents             map[string]*Intent
+	refunds             map[string]*Refund
+	refundedAmounts     map[string]int64
+	pendingRefundIDs    []string
+	idempotentIntentIDs map[string]string
+	idempotentRefundIDs map[string]string
+	deliveries          []Delivery
+}
+
+func NewFake(signingSecret string, refundSigningSecret string) *Fake {
+	return &Fake{
+		signingSecret:       signingSecret,
+		refundSigningSecret: refundSigningSecret,
+		intents:             make(map[string]*Intent),
+		refunds:             make(map[string]*Refund),
+		refundedAmounts:     make(map[string]int64),
+		idempotentIntentIDs: make(map[string]string),
+		idempotentRefundIDs: make(map[string]string),
+	}
+}
+
+func (fake *Fake) CreateIntent(ctx context.Context, params CreateIntentParams) (*Intent, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	if intentID, ok := fake.idempotentIntentIDs[params.IdempotencyKey]; ok && params.IdempotencyKey != "" {
+		intent := *fake.intents[intentID]
+
+		return &intent, nil
+	}
+
+	intent := &Intent{
+		ID:        fake.nextID("pi"),
+		Amount:    params.Amount,
+		Currency:  strings.ToUpper(params.Currency),
+		PaymentID: params.PaymentID.String(),
+	}
+
+	fake.intents[intent.ID] = intent
+
+	if params.IdempotencyKey != "" {
+		fake.idempotentIntentIDs[params.IdempotencyKey] = intent.ID
+	}
+
+	return fake.confirm(intent, params.PaymentMethodID)
+}
+
+func (fake *Fake) ConfirmIntent(ctx context.Context, intentID string, paymentMethodID string) (*Intent, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	intent, getIntentError := fake.getUnpaidIntent(intentID)
+
+	if getIntentError != nil {
+		return nil, getIntentError
+	}
+
+	return fake.confirm(intent, paymentMethodID)
+}
+
+func (fake *Fake) CancelIntent(ctx context.Context, intentID string) (*Intent, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	intent, getIntentError := fake.getUnpaidIntent(intentID)
+
+	if getIntentError != nil {
+		return nil, getIntentError
+	}
+
+	intent.Status = IntentStatusCanceled
+	intent.NextAction = ""
+
+	canceledIntent := *intent
+
+	return &canceledIntent, nil
+}
+
+func (fake *Fake) GetIntent(ctx context.Context, intentID string) (*Intent, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	intent, ok := fake.intents[intentID]
+
+	if !ok {
+		return nil, missingIntentError(intentID)
+	}
+
+	currentIntent := *intent
+
+	// Like Stripe, only refunds that went through count as refunded.
+	for _, refundResult := range fake.refunds {
+		if refundResult.PaymentIntentID == intentID && refundResult.Status == RefundStatusSucceeded {
+			currentIntent.AmountRefunded += refundResult.Amount
+		}
+	}
+
+	return &currentIntent, nil
+}
+
+// CompleteAction finishes the 3D Secure check of an intent that requires action, as if the customer passed or failed it.
+func (fake *Fake) CompleteAction(intentID string, authenticated bool) (*Intent, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	intent, ok := fake.intents[intentID]
+
+	if !ok {
+		return nil, missingIntentError(intentID)
+	}
+
+	if intent.Status != IntentStatusRequiresAction {
+		return nil, unexpectedStateError(intent)
+	}
+
+	intent.NextAction = ""
+
+	if authenticated {
+		intent.Status = IntentStatusSucceeded
+		fake.queueEvent(EventIntentSucceeded, intent, nil)
+	} else {
+		intent.Status = IntentStatusRequiresPaymentMethod
+		intent.LastError = "The customer failed to authenticate the payment."
+		fake.queueEvent(EventIntentPaymentFailed, intent, nil)
+	}
+
+	completedIntent := *intent
+
+	return &completedIntent, nil
+}
+
+// Refund refunds a paid intent. Refunds of FakeCardDelayedRefund and FakeCardRefundFails payments stay pending until
+// SettleRefunds, every other refund succeeds right away.
+func (fake *Fake) Refund(ctx context.Context, params RefundParams) (*Refund, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	if refundID, ok := fake.idempotentRefundIDs[params.IdempotencyKey]; ok && params.IdempotencyKey != "" {
+		refundResult := *fake.refunds[refundID]
+
+		return &refundResult, nil
+	}
+
+	intent, ok := fake.intents[params.PaymentIntentID]
+
+	if !ok {
+		return nil, missingIntentError(params.PaymentIntentID)
+	}
+
+	if intent.Status != IntentStatusSucceeded {
+		return nil, unexpectedStateError(intent)
+	}
+
+	remainingAmount := intent.Amount - fake.refundedAmounts[intent.ID]
+	amount := params.Amount
+
+	if amount == 0 {
+		amount = remainingAmount
+	}
+
+	if amount <= 0 || amount > remainingAmount {
+		return nil, &Error{
+			Code:    ErrorCodeChargeAlreadyRefunded,
+			Message: fmt.Sprintf("refund of %d is more than the %d left on %s", amount, remainingAmount, intent.ID),
+		}
+	}
+
+	refundResult := &Refund{
+		ID:              fake.nextID("re"),
+		PaymentIntentID: intent.ID,
+		Amount:          amount,
+		Status:          RefundStatusPending,
+	}
+
+	fake.refunds[refundResult.ID] = refundResult
+	fake.refundedAmounts[intent.ID] += amount
+
+	if params.IdempotencyKey != "" {
+		fake.idempotentRefundIDs[params.IdempotencyKey] = refundResult.ID
+	}
+
+	switch intent.PaymentMethodID {
+	case FakeCardDelayedRefund, FakeCardRefundFails:
+		fake.pendingRefundIDs = append(fake.pendingRefundIDs, refundResult.ID)
+	default:
+		refundResult.Status = RefundStatusSucceeded
+		fake.queueChargeRefunded(intent.ID)
+	}
+
+	createdRefund := *refundResult
+
+	return &createdRefund, nil
+}
+
+// SettleRefunds completes the pending refunds, like the bank answering a few days later.
+func (fake *Fake) SettleRefunds() {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	for _, refundID := range fake.pendingRefundIDs {
+		refundResult := fake.refunds[refundID]
+
+		if fake.intents[refundResult.PaymentIntentID].PaymentMethodID == FakeCardRefundFails {
+			refundResult.Status = RefundStatusFailed
+			refundResult.FailureReason = "declined"
+			fake.refundedAmounts[refundResult.PaymentIntentID] -= refundResult.Amount
+			fake.queueEvent(EventRefundFailed, nil, refundResult)
+
+			continue
+		}
+
+		refundResult.Status = RefundStatusSucceeded
+		fake.queueChargeRefunded(refundResult.PaymentIntentID)
+	}
+
+	fake.pendingRefundIDs = nil
+}
+
+// Deliveries returns the webhooks queued since the last call.
+func (fake *Fake) Deliveries() []Delivery {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	deliveries := fake.deliveries
+	fake.deliveries = nil
+
+	return deliveries
+}
+
+// Start stands in for the provider calling the webhook endpoints. Every interval it hands the queued deliveries to
+// deliver and then settles the pending refunds, so a refund is confirmed one interval after it settles.
+// Failed deliveries are only logged, they can be replayed from the stored webhook events.
+func (fake *Fake) Start(ctx context.Context, fakeClock clock.Clock, interval time.Duration, deliver func(ctx context.Context, delivery Delivery) error) {
+	log.Printf("Fake payment gateway started, delivery interval: %s", interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("Fake payment gateway stopped: %v", ctx.Err())
+
+			return
+		case <-fakeClock.After(interval):
+			deliveries := fake.Deliveries()
+
+			fake.SettleRefunds()
+
+			for _, delivery := range deliveries {
+				if deliverError := deliver(ctx, delivery); deliverError != nil {
+					log.Printf("Fake payment gateway: failed to deliver %s webhook: %v", delivery.WebhookType, deliverError)
+				}
+			}
+		}
+	}
+}
+
+func (fake *Fake) VerifyWebhook(payload []byte, signature string, secret string) (Event, error) {
+	if !hmac.Equal([]byte(signature), []byte(signPayload(payload, secret))) {
+		return Event{}, fmt.Errorf("%w: signature doesn't match the payload", ErrInvalidSignature)
+	}
+
+	return fake.ParseEvent(payload)
+}
+
+func (fake *Fake) ParseEvent(payload []byte) (Event, error) {
+	var event Event
+
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return Event{}, fmt.Errorf("error decoding fake event: %w", err)
+	}
+
+	if event.ID == "" {
+		return Event{}, errors.New("fake event has no id")
+	}
+
+	return event, nil
+}
+
+func (fake *Fake) confirm(intent *Intent, paymentMethodID string) (*Intent, error) {
+	intent.PaymentMethodID = paymentMethodID
+	intent.NextAction = ""
+	intent.ClientSecret = ""
+	intent.LastError = ""
+
+	switch paymentMethodID {
+	case "":
+		intent.Status = IntentStatusRequiresPaymentMethod
+	case FakeCardRequiresAction:
+		intent.Status = IntentStatusRequiresAction
+		intent.NextAction = "use_stripe_sdk"
+		intent.ClientSecret = intent.ID + "_secret_fake"
+		fake.queueEvent(EventIntentRequiresAction, intent, nil)
+	case FakeCardDeclined:
+		intent.Status = IntentStatusRequiresPaymentMethod
+		intent.LastError = "Your card was declined."
+		fake.queueEvent(EventIntentPaymentFailed, intent, nil)
+
+		declinedIntent := *intent
+
+		return nil, &Error{Code: ErrorCodeCardDeclined, Message: intent.LastError, Intent: &declinedIntent}
+	default:
+		intent.Status = IntentStatusSucceeded
+		fake.queueEvent(EventIntentSucceeded, intent, nil)
+	}
+
+	confirmedIntent := *intent
+
+	return &confirmedIntent, nil
+}
+
+func (fake *Fake) getUnpaidIntent(intentID string) (*Intent, error) {
+	intent, ok := fake.intents[intentID]
+
+	if !ok {
+		return nil, missingIntentError(intentID)
+	}
+
+	if intent.Status == IntentStatusSucceeded || intent.Status == IntentStatusCanceled {
+		return nil, unexpectedStateError(intent)
+	}
+
+	return intent, nil
+}
+
+// queueChargeRefunded reports the total refunded so far, like Stripe's charge.refunded.
+func (fake *Fake) queueChargeRefunded(intentID string) {
+	fake.queueEvent(EventChargeRefunded, nil, &Refund{
+		PaymentIntentID: intentID,
+		Amount:          fake.refundedAmounts[intentID],
+		Status:          RefundStatusSucceeded,
+	})
+}
+
+func (fake *Fake) queueEvent(eventType string, intent *Intent, refundResult *Refund) {
+	event := Event{
+		ID:   fake.nextID("evt"),
+		Type: eventType,
+	}
+
+	webhookType, signingSecret := "payment", fake.signingSecret
+
+	if intent != nil {
+		eventIntent := *intent
+		event.Intent = &eventIntent
+	}
+
+	if refundResult != nil {
+		eventRefund := *refundResult
+		event.Refund = &eventRefund
+		webhookType, signingSecret = "refund", fake.refundSigningSecret
+	}
+
+	// Event only holds strings and numbers, so marshaling can't fail.
+	payload, _ := json.Marshal(event)
+
+	fake.deliveries = append(fake.deliveries, Delivery{
+		WebhookType: webhookType,
+		Payload:     payload,
+		Signature:   signPayload(payload, signingSecret),
+	})
+}
+
+func (fake *Fake) nextID(prefix string) string {
+	fake.sequence++
+
+	return fmt.Sprintf("%s_fake_%d", prefix, fake.sequence)
+}
+
+func signPayload(payload []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func missingIntentError(intentID string) *Error {
+	return &Error{Code: ErrorCodeResourceMissing, Message: fmt.Sprintf("no such payment intent: %s", intentID)}
+}
+
+func unexpectedStateError(intent *Intent) *Error {
+	currentIntent := *intent
+
+	return &Error{
+		Code:    ErrorCodeIntentUnexpectedState,
+		Message: fmt.Sprintf("payment intent %s is %s", intent.ID, intent.Status),
+		Intent:  &currentIntent,
+	}
+}
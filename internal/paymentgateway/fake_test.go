@@ -0,0 +1,222 @@
+package paymentgateway_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/elorenzorodz/event-mrs/internal/paymentgateway"
+	"github.com/google/uuid"
+)
+
+func TestFakeCreateIntent(tTesting *testing.T) {
+	tests := []struct {
+		name               string
+		paymentMethodID    string
+		expectedStatus     paymentgateway.IntentStatus
+		expectedErrorCode  string
+		expectedEventTypes []string
+	}{
+		{
+			name:               "Succeeds",
+			paymentMethodID:    paymentgateway.FakeCardSucceeds,
+			expectedStatus:     paymentgateway.IntentStatusSucceeded,
+			expectedEventTypes: []string{paymentgateway.EventIntentSucceeded},
+		},
+		{
+			name:               "RequiresAction",
+			paymentMethodID:    paymentgateway.FakeCardRequiresAction,
+			expectedStatus:     paymentgateway.IntentStatusRequiresAction,
+			expectedEventTypes: []string{paymentgateway.EventIntentRequiresAction},
+		},
+		{
+			name:               "Declined",
+			paymentMethodID:    paymentgateway.FakeCardDeclined,
+			expectedStatus:     paymentgateway.IntentStatusRequiresPaymentMethod,
+			expectedErrorCode:  paymentgateway.ErrorCodeCardDeclined,
+			expectedEventTypes: []string{paymentgateway.EventIntentPaymentFailed},
+		},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.name, func(t *testing.T) {
+			fake := paymentgateway.NewFake("whsec_payment", "whsec_refund")
+
+			intent, err := fake.CreateIntent(context.Background(), paymentgateway.CreateIntentParams{
+				Amount:          2500,
+				Currency:        "usd",
+				PaymentMethodID: tc.paymentMethodID,
+				PaymentID:       uuid.New(),
+			})
+
+			if tc.expectedErrorCode != "" {
+				gatewayError, ok := paymentgateway.AsError(err)
+
+				if !ok || gatewayError.Code != tc.expectedErrorCode {
+					t.Fatalf("CreateIntent: expected error code %s, got: %v", tc.expectedErrorCode, err)
+				}
+
+				intent = gatewayError.Intent
+			} else if err != nil {
+				t.Fatalf("CreateIntent: expected no error, got: %v", err)
+			}
+
+			if intent.Status != tc.expectedStatus {
+				t.Errorf("CreateIntent: expected status %s, got %s", tc.expectedStatus, intent.Status)
+			}
+
+			deliveries := fake.Deliveries()
+
+			if len(deliveries) != len(tc.expectedEventTypes) {
+				t.Fatalf("Deliveries: expected %d, got %d", len(tc.expectedEventTypes), len(deliveries))
+			}
+
+			for i, delivery := range deliveries {
+				event, verifyError := fake.VerifyWebhook(delivery.Payload, delivery.Signature, "whsec_payment")
+
+				if verifyError != nil {
+					t.Fatalf("VerifyWebhook: expected no error, got: %v", verifyError)
+				}
+
+				if event.Type != tc.expectedEventTypes[i] || event.Intent == nil || event.Intent.ID != intent.ID {
+					t.Errorf("VerifyWebhook: expected %s for %s, got %+v", tc.expectedEventTypes[i], intent.ID, event)
+				}
+			}
+		})
+	}
+}
+
+func TestFakeRefund(tTesting *testing.T) {
+	tests := []struct {
+		name                 string
+		paymentMethodID      string
+		expectedStatus       paymentgateway.RefundStatus
+		expectedSettledEvent string
+	}{
+		{
+			name:            "Immediate",
+			paymentMethodID: paymentgateway.FakeCardSucceeds,
+			expectedStatus:  paymentgateway.RefundStatusSucceeded,
+		},
+		{
+			name:                 "Delayed",
+			paymentMethodID:      paymentgateway.FakeCardDelayedRefund,
+			expectedStatus:       paymentgateway.RefundStatusPending,
+			expectedSettledEvent: paymentgateway.EventChargeRefunded,
+		},
+		{
+			name:                 "FailsLater",
+			paymentMethodID:      paymentgateway.FakeCardRefundFails,
+			expectedStatus:       paymentgateway.RefundStatusPending,
+			expectedSettledEvent: paymentgateway.EventRefundFailed,
+		},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			fake := paymentgateway.NewFake("whsec_payment", "whsec_refund")
+
+			intent, err := fake.CreateIntent(ctx, paymentgateway.CreateIntentParams{Amount: 2500, Currency: "USD", PaymentMethodID: tc.paymentMethodID})
+
+			if err != nil {
+				t.Fatalf("CreateIntent: expected no error, got: %v", err)
+			}
+
+			fake.Deliveries()
+
+			refundResult, err := fake.Refund(ctx, paymentgateway.RefundParams{PaymentIntentID: intent.ID, Amount: 1000})
+
+			if err != nil {
+				t.Fatalf("Refund: expected no error, got: %v", err)
+			}
+
+			if refundResult.Status != tc.expectedStatus {
+				t.Errorf("Refund: expected status %s, got %s", tc.expectedStatus, refundResult.Status)
+			}
+
+			if tc.expectedSettledEvent == "" {
+				return
+			}
+
+			if deliveries := fake.Deliveries(); len(deliveries) != 0 {
+				t.Fatalf("Deliveries: expected none before the refund settles, got %d", len(deliveries))
+			}
+
+			fake.SettleRefunds()
+
+			deliveries := fake.Deliveries()
+
+			if len(deliveries) != 1 || deliveries[0].WebhookType != "refund" {
+				t.Fatalf("Deliveries: expected one refund webhook, got %+v", deliveries)
+			}
+
+			event, verifyError := fake.VerifyWebhook(deliveries[0].Payload, deliveries[0].Signature, "whsec_refund")
+
+			if verifyError != nil {
+				t.Fatalf("VerifyWebhook: expected no error, got: %v", verifyError)
+			}
+
+			if event.Type != tc.expectedSettledEvent || event.Refund == nil || event.Refund.PaymentIntentID != intent.ID {
+				t.Errorf("VerifyWebhook: expected %s for %s, got %+v", tc.expectedSettledEvent, intent.ID, event)
+			}
+
+			expectedAmountRefunded := int64(1000)
+
+			if tc.expectedSettledEvent == paymentgateway.EventRefundFailed {
+				expectedAmountRefunded = 0
+			}
+
+			settledIntent, getIntentError := fake.GetIntent(ctx, intent.ID)
+
+			if getIntentError != nil {
+				t.Fatalf("GetIntent: expected no error, got: %v", getIntentError)
+			}
+
+			if settledIntent.AmountRefunded != expectedAmountRefunded {
+				t.Errorf("GetIntent: expected %d refunded, got %d", expectedAmountRefunded, settledIntent.AmountRefunded)
+			}
+		})
+	}
+}
+
+func TestFakeCompleteAction(t *testing.T) {
+	fake := paymentgateway.NewFake("whsec_payment", "whsec_refund")
+
+	intent, err := fake.CreateIntent(context.Background(), paymentgateway.CreateIntentParams{Amount: 2500, Currency: "USD", PaymentMethodID: paymentgateway.FakeCardRequiresAction})
+
+	if err != nil {
+		t.Fatalf("CreateIntent: expected no error, got: %v", err)
+	}
+
+	completedIntent, err := fake.CompleteAction(intent.ID, true)
+
+	if err != nil {
+		t.Fatalf("CompleteAction: expected no error, got: %v", err)
+	}
+
+	if completedIntent.Status != paymentgateway.IntentStatusSucceeded {
+		t.Errorf("CompleteAction: expected status %s, got %s", paymentgateway.IntentStatusSucceeded, completedIntent.Status)
+	}
+
+	_, cancelError := fake.CancelIntent(context.Background(), intent.ID)
+	gatewayError, ok := paymentgateway.AsError(cancelError)
+
+	if !ok || gatewayError.Code != paymentgateway.ErrorCodeIntentUnexpectedState || gatewayError.Intent.Status != paymentgateway.IntentStatusSucceeded {
+		t.Fatalf("CancelIntent: expected unexpected state error for a paid intent, got: %v", cancelError)
+	}
+}
+
+func TestFakeVerifyWebhookRejectsWrongSignature(t *testing.T) {
+	fake := paymentgateway.NewFake("whsec_payment", "whsec_refund")
+
+	if _, err := fake.CreateIntent(context.Background(), paymentgateway.CreateIntentParams{Amount: 2500, Currency: "USD", PaymentMethodID: paymentgateway.FakeCardSucceeds}); err != nil {
+		t.Fatalf("CreateIntent: expected no error, got: %v", err)
+	}
+
+	delivery := fake.Deliveries()[0]
+
+	if _, err := fake.VerifyWebhook(delivery.Payload, delivery.Signature, "whsec_refund"); !errors.Is(err, paymentgateway.ErrInvalidSignature) {
+		t.Fatalf("VerifyWebhook: expected ErrInvalidSignature, got: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package paymentgateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Providers that can be selected with PAYMENT_GATEWAY.
+const (
+	ProviderStripe = "stripe"
+	ProviderFake   = "fake"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Gateway is the payment provider tickets are charged, cancelled and refunded through.
+type Gateway interface {
+	// CreateIntent creates and confirms an intent, a declined payment returns an *Error that still carries the intent.
+	CreateIntent(ctx context.Context, params CreateIntentParams) (*Intent, error)
+	// ConfirmIntent confirms an existing intent again with a new payment method.
+	ConfirmIntent(ctx context.Context, intentID string, paymentMethodID string) (*Intent, error)
+	// CancelIntent cancels an intent that hasn't been paid yet.
+	CancelIntent(ctx context.Context, intentID string) (*Intent, error)
+	// GetIntent fetches the current state of an intent, including how much of it was refunded.
+	GetIntent(ctx context.Context, intentID string) (*Intent, error)
+	Refund(ctx context.Context, params RefundParams) (*Refund, error)
+	// VerifyWebhook checks the signature of a webhook delivery and decodes its event.
+	VerifyWebhook(payload []byte, signature string, secret string) (Event, error)
+	// ParseEvent decodes a delivery that was verified before, e.g. a stored event being replayed.
+	ParseEvent(payload []byte) (Event, error)
+}
+
+type IntentStatus string
+
+const (
+	IntentStatusSucceeded             IntentStatus = "succeeded"
+	IntentStatusRequiresAction        IntentStatus = "requires_action"
+	IntentStatusRequiresPaymentMethod IntentStatus = "requires_payment_method"
+	IntentStatusProcessing            IntentStatus = "processing"
+	IntentStatusCanceled              IntentStatus = "canceled"
+)
+
+type RefundStatus string
+
+const (
+	RefundStatusPending   RefundStatus = "pending"
+	RefundStatusSucceeded RefundStatus = "succeeded"
+	RefundStatusFailed    RefundStatus = "failed"
+)
+
+// Event types handled by the payment webhooks.
+const (
+	EventIntentSucceeded      = "payment_intent.succeeded"
+	EventIntentPaymentFailed  = "payment_intent.payment_failed"
+	EventIntentRequiresAction = "payment_intent.requires_action"
+	EventChargeRefunded       = "charge.refunded"
+	EventRefundFailed         = "refund.failed"
+)
+
+// Error codes the services act on.
+const (
+	ErrorCodeCardDeclined          = "card_declined"
+	ErrorCodeIntentUnexpectedState = "payment_intent_unexpected_state"
+	ErrorCodeResourceMissing       = "resource_missing"
+	ErrorCodeChargeAlreadyRefunded = "charge_already_refunded"
+)
+
+type CreateIntentParams struct {
+	Amount          int64
+	Currency        string
+	PaymentMethodID string
+	PaymentID       uuid.UUID
+	// IdempotencyKey lets the provider return the original intent when the same payment is sent again.
+	IdempotencyKey string
+}
+
+type RefundParams struct {
+	PaymentIntentID string
+	// Amount is in minor units, zero refunds whatever is left of the intent.
+	Amount         int64
+	IdempotencyKey string
+}
+
+type Intent struct {
+	ID              string       `json:"id"`
+	Status          IntentStatus `json:"status"`
+	Amount          int64        `json:"amount"`
+	AmountRefunded  int64        `json:"amount_refunded"`
+	Currency        string       `json:"currency"`
+	ClientSecret    string       `json:"client_secret"`
+	NextAction      string       `json:"next_action"`
+	PaymentMethodID string       `json:"payment_method_id"`
+	// PaymentID is the payment the intent was created for, empty for intents created outside this app.
+	PaymentID string `json:"payment_id"`
+	LastError string `json:"last_error"`
+}
+
+type Refund struct {
+	ID              string       `json:"id"`
+	PaymentIntentID string       `json:"payment_intent_id"`
+	Amount          int64        `json:"amount"`
+	Status          RefundStatus `json:"status"`
+	FailureReason   string       `json:"failure_reason"`
+}
+
+// Event is a verified webhook delivery, Intent is set for payment_intent events and Refund for refund events.
+type Event struct {
+	ID     string  `json:"id"`
+	Type   string  `json:"type"`
+	Intent *Intent `json:"intent,omitempty"`
+	Refund *Refund `json:"refund,omitempty"`
+}
+
+// Error is returned when the provider rejects a request, e.g. a declined card or cancelling a paid intent.
+type Error struct {
+	Code    string
+	Message string
+	// Intent is the state of the intent the request was about, when the provider returned it.
+	Intent *Intent
+}
+
+func (gatewayError *Error) Error() string {
+	return fmt.Sprintf("%s: %s", gatewayError.Code, gatewayError.Message)
+}
+
+// AsError returns the provider error wrapped in err, if there is one.
+func AsError(err error) (*Error, bool) {
+	var gatewayError *Error
+
+	if !errors.As(err, &gatewayError) {
+		return nil, false
+	}
+
+	return gatewayError, true
+}
@@ -0,0 +1,243 @@
+package paymentgateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/stripe/stripe-go/v83"
+	"github.com/stripe/stripe-go/v83/paymentintent"
+	"github.com/stripe/stripe-go/v83/refund"
+	"github.com/stripe/stripe-go/v83/webhook"
+)
+
+// Stripe charges through the Stripe API.
+type Stripe struct{}
+
+func NewStripe(secretKey string) *Stripe {
+	stripe.Key = secretKey
+
+	return &Stripe{}
+}
+
+func (stripeGateway *Stripe) CreateIntent(ctx context.Context, params CreateIntentParams) (*Intent, error) {
+	paymentIntentParams := &stripe.PaymentIntentParams{
+		Amount:        stripe.Int64(params.Amount),
+		Currency:      stripe.String(strings.ToLower(params.Currency)),
+		Confirm:       stripe.Bool(true),
+		PaymentMethod: stripe.String(params.PaymentMethodID),
+		AutomaticPaymentMethods: &stripe.PaymentIntentAutomaticPaymentMethodsParams{
+			Enabled:        stripe.Bool(true),
+			AllowRedirects: stripe.String("never"),
+		},
+		Metadata: map[string]string{"payment_id": params.PaymentID.String()},
+	}
+
+	if params.IdempotencyKey != "" {
+		paymentIntentParams.SetIdempotencyKey(params.IdempotencyKey)
+	}
+
+	paymentIntent, err := paymentintent.New(paymentIntentParams)
+
+	if err != nil {
+		return nil, stripeErrorToError(err)
+	}
+
+	return stripeIntentToIntent(paymentIntent), nil
+}
+
+func (stripeGateway *Stripe) ConfirmIntent(ctx context.Context, intentID string, paymentMethodID string) (*Intent, error) {
+	paymentIntent, err := paymentintent.Update(intentID, &stripe.PaymentIntentParams{
+		PaymentMethod: stripe.String(paymentMethodID),
+		Confirm:       stripe.Bool(true),
+	})
+
+	if err != nil {
+		return nil, stripeErrorToError(err)
+	}
+
+	return stripeIntentToIntent(paymentIntent), nil
+}
+
+func (stripeGateway *Stripe) CancelIntent(ctx context.Context, intentID string) (*Intent, error) {
+	paymentIntent, err := paymentintent.Cancel(intentID, &stripe.PaymentIntentCancelParams{
+		CancellationReason: stripe.String(string(stripe.PaymentIntentCancellationReasonAbandoned)),
+	})
+
+	if err != nil {
+		return nil, stripeErrorToError(err)
+	}
+
+	return stripeIntentToIntent(paymentIntent), nil
+}
+
+func (stripeGateway *Stripe) GetIntent(ctx context.Context, intentID string) (*Intent, error) {
+	paymentIntentParams := &stripe.PaymentIntentParams{}
+	// The refunded amount is only on the charge.
+	paymentIntentParams.AddExpand("latest_charge")
+
+	paymentIntent, err := paymentintent.Get(intentID, paymentIntentParams)
+
+	if err != nil {
+		return nil, stripeErrorToError(err)
+	}
+
+	return stripeIntentToIntent(paymentIntent), nil
+}
+
+func (stripeGateway *Stripe) Refund(ctx context.Context, params RefundParams) (*Refund, error) {
+	refundParams := &stripe.RefundParams{
+		PaymentIntent: stripe.String(params.PaymentIntentID),
+	}
+
+	if params.Amount > 0 {
+		refundParams.Amount = stripe.Int64(params.Amount)
+	}
+
+	if params.IdempotencyKey != "" {
+		refundParams.SetIdempotencyKey(params.IdempotencyKey)
+	}
+
+	refundResult, err := refund.New(refundParams)
+
+	if err != nil {
+		return nil, stripeErrorToError(err)
+	}
+
+	return stripeRefundToRefund(refundResult), nil
+}
+
+func (stripeGateway *Stripe) VerifyWebhook(payload []byte, signature string, secret string) (Event, error) {
+	stripeEvent, err := webhook.ConstructEvent(payload, signature, secret)
+
+	if err != nil {
+		return Event{}, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	return stripeEventToEvent(stripeEvent)
+}
+
+func (stripeGateway *Stripe) ParseEvent(payload []byte) (Event, error) {
+	var stripeEvent stripe.Event
+
+	if err := json.Unmarshal(payload, &stripeEvent); err != nil {
+		return Event{}, fmt.Errorf("error decoding Stripe event: %w", err)
+	}
+
+	return stripeEventToEvent(stripeEvent)
+}
+
+func stripeEventToEvent(stripeEvent stripe.Event) (Event, error) {
+	event := Event{
+		ID:   stripeEvent.ID,
+		Type: string(stripeEvent.Type),
+	}
+
+	if stripeEvent.Data == nil {
+		return event, nil
+	}
+
+	switch event.Type {
+	case EventIntentSucceeded, EventIntentPaymentFailed, EventIntentRequiresAction:
+		var paymentIntent stripe.PaymentIntent
+
+		if err := json.Unmarshal(stripeEvent.Data.Raw, &paymentIntent); err != nil {
+			return Event{}, fmt.Errorf("error unmarshaling %s: %w", event.Type, err)
+		}
+
+		event.Intent = stripeIntentToIntent(&paymentIntent)
+
+	case EventChargeRefunded:
+		var charge stripe.Charge
+
+		if err := json.Unmarshal(stripeEvent.Data.Raw, &charge); err != nil {
+			return Event{}, fmt.Errorf("error unmarshaling %s: %w", event.Type, err)
+		}
+
+		event.Refund = &Refund{
+			Amount: charge.AmountRefunded,
+			Status: RefundStatusSucceeded,
+		}
+
+		if charge.PaymentIntent != nil {
+			event.Refund.PaymentIntentID = charge.PaymentIntent.ID
+		}
+
+	case EventRefundFailed:
+		var stripeRefund stripe.Refund
+
+		if err := json.Unmarshal(stripeEvent.Data.Raw, &stripeRefund); err != nil {
+			return Event{}, fmt.Errorf("error unmarshaling %s: %w", event.Type, err)
+		}
+
+		event.Refund = stripeRefundToRefund(&stripeRefund)
+	}
+
+	return event, nil
+}
+
+func stripeIntentToIntent(paymentIntent *stripe.PaymentIntent) *Intent {
+	intent := &Intent{
+		ID:           paymentIntent.ID,
+		Status:       IntentStatus(paymentIntent.Status),
+		Amount:       paymentIntent.Amount,
+		Currency:     strings.ToUpper(string(paymentIntent.Currency)),
+		ClientSecret: paymentIntent.ClientSecret,
+		PaymentID:    paymentIntent.Metadata["payment_id"],
+	}
+
+	if paymentIntent.NextAction != nil {
+		intent.NextAction = string(paymentIntent.NextAction.Type)
+	}
+
+	if paymentIntent.LatestCharge != nil {
+		intent.AmountRefunded = paymentIntent.LatestCharge.AmountRefunded
+	}
+
+	if paymentIntent.PaymentMethod != nil {
+		intent.PaymentMethodID = paymentIntent.PaymentMethod.ID
+	}
+
+	if paymentIntent.LastPaymentError != nil {
+		intent.LastError = paymentIntent.LastPaymentError.Msg
+	}
+
+	return intent
+}
+
+func stripeRefundToRefund(stripeRefund *stripe.Refund) *Refund {
+	refundResult := &Refund{
+		ID:            stripeRefund.ID,
+		Amount:        stripeRefund.Amount,
+		Status:        RefundStatus(stripeRefund.Status),
+		FailureReason: string(stripeRefund.FailureReason),
+	}
+
+	if stripeRefund.PaymentIntent != nil {
+		refundResult.PaymentIntentID = stripeRefund.PaymentIntent.ID
+	}
+
+	return refundResult
+}
+
+// stripeErrorToError turns API errors into *Error, network and other errors are returned as they are.
+func stripeErrorToError(err error) error {
+	var stripeError *stripe.Error
+
+	if !errors.As(err, &stripeError) {
+		return err
+	}
+
+	gatewayError := &Error{
+		Code:    string(stripeError.Code),
+		Message: stripeError.Msg,
+	}
+
+	if stripeError.PaymentIntent != nil {
+		gatewayError.Intent = stripeIntentToIntent(stripeError.PaymentIntent)
+	}
+
+	return gatewayError
+}
@@ -0,0 +1,110 @@
+package paymentstatus
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/paymentgateway"
+)
+
+// Status is the state of a row in the payments table, the database only accepts these values.
+type Status string
+
+const (
+	Pending               Status = "pending"
+	RequiresAction        Status = "requires_action"
+	RequiresPaymentMethod Status = "requires_payment_method"
+	Processing            Status = "processing"
+	Failed                Status = "payment_failed"
+	Succeeded             Status = "succeeded"
+	RefundPending         Status = "refund_pending"
+	RefundFailed          Status = "refund_failed"
+	Refunded              Status = "refunded"
+	Cancelled             Status = "cancelled"
+)
+
+var ErrTransition = errors.New("payment status change not allowed")
+
+// transitions lists the statuses a payment can move to. Unpaid payments can be retried until they are paid or
+// cancelled, paid payments only move on to refunds and a refund can still fail after the gateway reported it done.
+// Cancelled payments can't be changed anymore.
+var transitions = map[Status][]Status{
+	Pending:               {RequiresAction, RequiresPaymentMethod, Processing, Failed, Succeeded, Cancelled},
+	RequiresAction:        {RequiresPaymentMethod, Processing, Failed, Succeeded, Cancelled},
+	RequiresPaymentMethod: {RequiresAction, Processing, Failed, Succeeded, Cancelled},
+	Processing:            {RequiresAction, RequiresPaymentMethod, Failed, Succeeded, Cancelled},
+	Failed:                {RequiresAction, RequiresPaymentMethod, Processing, Succeeded, Cancelled},
+	Succeeded:             {RefundPending, RefundFailed, Refunded},
+	RefundPending:         {RefundFailed, Refunded},
+	RefundFailed:          {RefundPending, Refunded},
+	Refunded:              {RefundFailed},
+}
+
+// Updater is the query payments are updated with, *database.Queries in the services.
+type Updater interface {
+	UpdatePayment(ctx context.Context, arg database.UpdatePaymentParams) (database.Payment, error)
+}
+
+func (status Status) IsValid() bool {
+	_, ok := transitions[status]
+
+	return ok || status == Cancelled
+}
+
+// AwaitingPayment reports whether the payment can still be paid, i.e. it wasn't paid, refunded or cancelled yet.
+func (status Status) AwaitingPayment() bool {
+	return slices.Contains(transitions[status], Succeeded)
+}
+
+// CanTransitionTo reports whether a payment in status can be moved to newStatus. Staying in the same status is allowed
+// so the amount and intent can be updated, e.g. when a payment is confirmed again with another card that fails too.
+func (status Status) CanTransitionTo(newStatus Status) bool {
+	if !status.IsValid() || !newStatus.IsValid() {
+		return false
+	}
+
+	return status == newStatus || slices.Contains(transitions[status], newStatus)
+}
+
+// FromIntentStatus returns the payment status for an intent status. Anything else, like the error code of a declined
+// card, means the payment failed.
+func FromIntentStatus(intentStatus string) Status {
+	switch paymentgateway.IntentStatus(intentStatus) {
+	case paymentgateway.IntentStatusSucceeded:
+		return Succeeded
+	case paymentgateway.IntentStatusRequiresAction:
+		return RequiresAction
+	case paymentgateway.IntentStatusRequiresPaymentMethod:
+		return RequiresPaymentMethod
+	case paymentgateway.IntentStatusProcessing:
+		return Processing
+	case paymentgateway.IntentStatusCanceled:
+		return Cancelled
+	}
+
+	return Failed
+}
+
+// Update is how every service changes a payment. The transition from params.CurrentStatus to params.Status is checked
+// first and the update only applies if nobody changed the status since it was read, both fail with ErrTransition.
+func Update(ctx context.Context, updater Updater, params database.UpdatePaymentParams) (database.Payment, error) {
+	if !Status(params.CurrentStatus).CanTransitionTo(Status(params.Status)) {
+		return database.Payment{}, fmt.Errorf("%w: %s payment can't be %s", ErrTransition, params.CurrentStatus, params.Status)
+	}
+
+	updatedPayment, updatePaymentError := updater.UpdatePayment(ctx, params)
+
+	if errors.Is(updatePaymentError, sql.ErrNoRows) {
+		return database.Payment{}, fmt.Errorf("%w: the status of payment %s was changed in the meantime", ErrTransition, params.ID)
+	}
+
+	if updatePaymentError != nil {
+		return database.Payment{}, updatePaymentError
+	}
+
+	return updatedPayment, nil
+}
@@ -0,0 +1,128 @@
+package paymentstatus_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/paymentstatus"
+	"github.com/google/uuid"
+)
+
+type mockUpdater struct {
+	calls int
+	err   error
+}
+
+func (mock *mockUpdater) UpdatePayment(ctx context.Context, arg database.UpdatePaymentParams) (database.Payment, error) {
+	mock.calls++
+
+	if mock.err != nil {
+		return database.Payment{}, mock.err
+	}
+
+	return database.Payment{ID: arg.ID, Status: arg.Status}, nil
+}
+
+func TestCanTransitionTo(tTesting *testing.T) {
+	tests := []struct {
+		name      string
+		from      paymentstatus.Status
+		to        paymentstatus.Status
+		isAllowed bool
+	}{
+		{name: "PendingToSucceeded", from: paymentstatus.Pending, to: paymentstatus.Succeeded, isAllowed: true},
+		{name: "FailedRetried", from: paymentstatus.Failed, to: paymentstatus.RequiresAction, isAllowed: true},
+		{name: "FailedAgain", from: paymentstatus.Failed, to: paymentstatus.Failed, isAllowed: true},
+		{name: "SucceededToRefundPending", from: paymentstatus.Succeeded, to: paymentstatus.RefundPending, isAllowed: true},
+		{name: "RefundedThenFailed", from: paymentstatus.Refunded, to: paymentstatus.RefundFailed, isAllowed: true},
+		{name: "SucceededToPending", from: paymentstatus.Succeeded, to: paymentstatus.Pending, isAllowed: false},
+		{name: "SucceededToCancelled", from: paymentstatus.Succeeded, to: paymentstatus.Cancelled, isAllowed: false},
+		{name: "PendingToRefunded", from: paymentstatus.Pending, to: paymentstatus.Refunded, isAllowed: false},
+		{name: "CancelledIsFinal", from: paymentstatus.Cancelled, to: paymentstatus.Succeeded, isAllowed: false},
+		{name: "UnknownStatus", from: paymentstatus.Pending, to: "card_declined", isAllowed: false},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.name, func(t *testing.T) {
+			if isAllowed := tc.from.CanTransitionTo(tc.to); isAllowed != tc.isAllowed {
+				t.Errorf("CanTransitionTo: expected %t for %s to %s, got %t", tc.isAllowed, tc.from, tc.to, isAllowed)
+			}
+		})
+	}
+}
+
+func TestFromIntentStatus(tTesting *testing.T) {
+	tests := []struct {
+		intentStatus string
+		expected     paymentstatus.Status
+	}{
+		{intentStatus: "succeeded", expected: paymentstatus.Succeeded},
+		{intentStatus: "requires_action", expected: paymentstatus.RequiresAction},
+		{intentStatus: "processing", expected: paymentstatus.Processing},
+		{intentStatus: "canceled", expected: paymentstatus.Cancelled},
+		{intentStatus: "card_declined", expected: paymentstatus.Failed},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.intentStatus, func(t *testing.T) {
+			if status := paymentstatus.FromIntentStatus(tc.intentStatus); status != tc.expected {
+				t.Errorf("FromIntentStatus: expected %s, got %s", tc.expected, status)
+			}
+		})
+	}
+}
+
+func TestUpdate(tTesting *testing.T) {
+	tests := []struct {
+		name          string
+		currentStatus paymentstatus.Status
+		newStatus     paymentstatus.Status
+		updateError   error
+		expectedCalls int
+		expectedError error
+	}{
+		{
+			name:          "Allowed",
+			currentStatus: paymentstatus.Succeeded,
+			newStatus:     paymentstatus.Refunded,
+			expectedCalls: 1,
+		},
+		{
+			name:          "NotAllowed",
+			currentStatus: paymentstatus.Refunded,
+			newStatus:     paymentstatus.Succeeded,
+			expectedError: paymentstatus.ErrTransition,
+		},
+		{
+			name:          "ChangedInTheMeantime",
+			currentStatus: paymentstatus.Pending,
+			newStatus:     paymentstatus.Succeeded,
+			updateError:   sql.ErrNoRows,
+			expectedCalls: 1,
+			expectedError: paymentstatus.ErrTransition,
+		},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.name, func(t *testing.T) {
+			updater := &mockUpdater{err: tc.updateError}
+
+			_, err := paymentstatus.Update(context.Background(), updater, database.UpdatePaymentParams{
+				ID:            uuid.New(),
+				Status:        string(tc.newStatus),
+				CurrentStatus: string(tc.currentStatus),
+			})
+
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("Update: expected error %v, got: %v", tc.expectedError, err)
+			}
+
+			if updater.calls != tc.expectedCalls {
+				t.Errorf("Update: expected %d UpdatePayment calls, got %d", tc.expectedCalls, updater.calls)
+			}
+		})
+	}
+}
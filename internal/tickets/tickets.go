@@ -0,0 +1,70 @@
+package tickets
+
+import (
+	"fmt"
+
+	"github.com/elorenzorodz/event-mrs/internal/auth"
+	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/google/uuid"
+	"github.com/skip2/go-qrcode"
+)
+
+// QR codes are rendered large enough to be scanned from a phone screen or a printout.
+const qrCodeSize = 512
+
+// Issuer turns reservations into signed tickets that door staff scan at check-in.
+type Issuer struct {
+	signer *auth.TicketSigner
+}
+
+func NewIssuer(ticketSigner *auth.TicketSigner) *Issuer {
+	return &Issuer{signer: ticketSigner}
+}
+
+// QRCode returns the signed ticket of the reservation as a PNG image.
+func (issuer *Issuer) QRCode(reservationID uuid.UUID) ([]byte, error) {
+	ticketToken, signTicketError := issuer.signer.Sign(reservationID)
+
+	if signTicketError != nil {
+		return nil, fmt.Errorf("failed to sign ticket for reservation %s: %w", reservationID, signTicketError)
+	}
+
+	qrCode, encodeQRCodeError := qrcode.Encode(ticketToken, qrcode.Medium, qrCodeSize)
+
+	if encodeQRCodeError != nil {
+		return nil, fmt.Errorf("failed to render ticket for reservation %s: %w", reservationID, encodeQRCodeError)
+	}
+
+	return qrCode, nil
+}
+
+// Attachments renders one QR code per reservation for the confirmation email, tickets that fail are left out so the email still goes out.
+func (issuer *Issuer) Attachments(reservationIDs []uuid.UUID) ([]mailer.TicketAttachment, error) {
+	ticketAttachments := make([]mailer.TicketAttachment, 0, len(reservationIDs))
+
+	var firstError error
+
+	for _, reservationID := range reservationIDs {
+		qrCode, qrCodeError := issuer.QRCode(reservationID)
+
+		if qrCodeError != nil {
+			if firstError == nil {
+				firstError = qrCodeError
+			}
+
+			continue
+		}
+
+		ticketAttachments = append(ticketAttachments, mailer.TicketAttachment{
+			Filename: fmt.Sprintf("ticket-%s.png", reservationID),
+			PNG:      qrCode,
+		})
+	}
+
+	return ticketAttachments, firstError
+}
+
+// Verify returns the reservation ID of a scanned ticket.
+func (issuer *Issuer) Verify(ticketToken string) (uuid.UUID, error) {
+	return issuer.signer.Verify(ticketToken)
+}
\ No newline at end of file
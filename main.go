@@ -1,26 +1,44 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/elorenzorodz/event-mrs/calendar"
 	"github.com/elorenzorodz/event-mrs/config"
+	"github.com/elorenzorodz/event-mrs/email_outbox"
 	"github.com/elorenzorodz/event-mrs/event_details"
+	"github.com/elorenzorodz/event-mrs/event_staff"
 	"github.com/elorenzorodz/event-mrs/events"
 	"github.com/elorenzorodz/event-mrs/internal/auth"
+	"github.com/elorenzorodz/event-mrs/internal/clock"
 	"github.com/elorenzorodz/event-mrs/internal/database"
 	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/elorenzorodz/event-mrs/internal/paymentgateway"
+	"github.com/elorenzorodz/event-mrs/internal/tickets"
 	"github.com/elorenzorodz/event-mrs/middleware"
 	"github.com/elorenzorodz/event-mrs/payments"
+	"github.com/elorenzorodz/event-mrs/promo_codes"
 	"github.com/elorenzorodz/event-mrs/reservations"
+	"github.com/elorenzorodz/event-mrs/show_reminders"
 	"github.com/elorenzorodz/event-mrs/users"
+	"github.com/elorenzorodz/event-mrs/waitlist"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
-	"github.com/stripe/stripe-go/v83"
 )
 
+// fakePaymentGatewayDeliveryInterval is how often the fake payment gateway sends its webhooks and settles refunds.
+const fakePaymentGatewayDeliveryInterval = 5 * time.Second
+
 func main() {
+	// -reconcile runs the payment reconciliation once, emails the report and exits instead of starting the server.
+	reconcileOnly := flag.Bool("reconcile", false, "run the payment reconciliation once and exit")
+	flag.Parse()
+
 	godotenv.Load(".env.dev")
 
 	envConfig, loadEnvironmentVariablesError := config.LoadEnvironmentVariables()
@@ -39,6 +57,18 @@ func main() {
 
 	dbQueries := database.New(dbConnection)
 
+	if len(envConfig.AdminEmails) > 0 {
+		promotedCount, promoteAdminsError := dbQueries.PromoteUsersToAdmin(context.Background(), envConfig.AdminEmails)
+
+		if promoteAdminsError != nil {
+			log.Fatalf("Error promoting ADMIN_EMAILS users to admin: %v", promoteAdminsError)
+		}
+
+		if promotedCount > 0 {
+			log.Printf("Promoted %d user/s from ADMIN_EMAILS to admin", promotedCount)
+		}
+	}
+
 	router := gin.New()
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
@@ -52,8 +82,42 @@ func main() {
 	signingKey, tokenValidator := auth.LoadKeys("private.pem", "public.pem")
 
 	tokenGenerator := auth.NewTokenGenerator(signingKey)
+	ticketIssuer := tickets.NewIssuer(auth.NewTicketSigner(signingKey, tokenValidator))
+
+	var mailTransport mailer.Transport
+
+	switch envConfig.MailTransport {
+	case mailer.TransportSMTP:
+		mailTransport = mailer.NewSMTPTransport(envConfig.SMTPHost, envConfig.SMTPPort, envConfig.SMTPUsername, envConfig.SMTPPassword)
+	case mailer.TransportFile:
+		fileTransport, fileTransportError := mailer.NewFileTransport(envConfig.MailDirectory)
+
+		if fileTransportError != nil {
+			log.Fatalf("Fatal: %v", fileTransportError)
+		}
 
-	userService := users.NewService(dbQueries, tokenGenerator)
+		mailTransport = fileTransport
+	case mailer.TransportLog:
+		mailTransport = mailer.NewLogTransport()
+	default:
+		mailTransport = mailer.NewMailgunTransport(envConfig.MailgunSendingDomain, envConfig.MailgunAPIKey)
+	}
+
+	if envConfig.MailTransport != mailer.TransportMailgun {
+		log.Printf("Sending emails through the %s mail transport", envConfig.MailTransport)
+	}
+
+	mailerConfig := mailer.MailerConfig{
+		SenderName: envConfig.SenderName,
+		SenderEmail: envConfig.SenderEmail,
+		TeamName: envConfig.TeamName,
+		TeamEmail: envConfig.TeamEmail,
+	}
+	// The services write their emails to the outbox, the outbox worker sends them through the mail transport.
+	newMailer := mailer.NewMailer(mailerConfig, mailer.NewOutboxTransport(dbQueries))
+	emailOutboxWorker := email_outbox.NewOutboxWorker(dbQueries, mailTransport, clock.New(), envConfig.EmailOutboxInterval, envConfig.EmailOutboxBatchSize, envConfig.EmailOutboxMaxAttempts)
+
+	userService := users.NewService(dbQueries, tokenGenerator, newMailer, envConfig.AppBaseURL)
 
 	userAPIConfig := users.UserAPIConfig{
 		Service: userService,
@@ -62,37 +126,81 @@ func main() {
 	routerAPIPrefix.POST("/account/register", userAPIConfig.RegisterUser)
 	routerAPIPrefix.POST("/account/login", userAPIConfig.LoginUser)
 
+	routerAPIPrefix.POST("/account/refresh", userAPIConfig.RefreshToken)
+	routerAPIPrefix.GET("/account/verify", userAPIConfig.VerifyEmail)
+	routerAPIPrefix.POST("/account/password/forgot", userAPIConfig.ForgotPassword)
+	routerAPIPrefix.GET("/account/password/reset", userAPIConfig.PasswordResetForm)
+	routerAPIPrefix.POST("/account/password/reset", userAPIConfig.ResetPassword)
+
 	routerWithAuthorization := routerAPIPrefix.Group("")
 	routerWithAuthorization.Use(middleware.AuthorizationMiddleware(dbQueries, tokenValidator))
 
-	mailerConfig := mailer.MailerConfig{
-		Domain: envConfig.MailgunSendingDomain,
-		APIKey: envConfig.MailgunAPIKey,
-		SenderName: envConfig.SenderName,
-		SenderEmail: envConfig.SenderEmail,
-		TeamName: envConfig.TeamName,
-		TeamEmail: envConfig.TeamEmail,
+	routerWithAuthorization.POST("/account/logout", userAPIConfig.Logout)
+	routerWithAuthorization.POST("/account/logout-all", userAPIConfig.LogoutAll)
+	routerWithAuthorization.POST("/account/verify/resend", userAPIConfig.ResendEmailVerification)
+	routerWithAuthorization.GET("/account/preferences", userAPIConfig.GetPreferences)
+	routerWithAuthorization.PUT("/account/preferences", userAPIConfig.UpdatePreferences)
+
+	var (
+		paymentGateway     paymentgateway.Gateway
+		fakePaymentGateway *paymentgateway.Fake
+	)
+
+	if envConfig.PaymentGateway == paymentgateway.ProviderFake {
+		fakePaymentGateway = paymentgateway.NewFake(envConfig.StripeSigningSecret, envConfig.StripeRefundSigningSecret)
+		paymentGateway = fakePaymentGateway
+
+		log.Printf("Using the fake payment gateway, no payments are charged")
+	} else {
+		paymentGateway = paymentgateway.NewStripe(envConfig.StripeSecretKey)
 	}
-	newMailer := mailer.NewMailer(mailerConfig)
-	stripe.Key = envConfig.StripeSecretKey
-	stripeClient := &events.StripeAPIClient{}
 
-	eventService := events.NewService(*dbQueries, newMailer, stripeClient)
+	waitlistService := waitlist.NewService(dbQueries, newMailer, envConfig.AppBaseURL, envConfig.WaitlistOfferTTL)
+
+	eventService := events.NewService(*dbQueries, dbConnection, newMailer, paymentGateway, waitlistService)
 
 	eventAPIConfig := events.EventAPIConfig{
 		Service: eventService,
 	}
 
-	routerWithAuthorization.GET("/events", eventAPIConfig.GetUserEvents)
-	routerWithAuthorization.GET("/events/:eventId", eventAPIConfig.GetUserEventById)
+	routerAPIPrefix.GET("/public/events", eventAPIConfig.GetPublicEvents)
+	routerAPIPrefix.GET("/public/events/:eventId", eventAPIConfig.GetPublicEventById)
+
 	routerWithAuthorization.GET("/events/filter", eventAPIConfig.GetEvents)
-	routerWithAuthorization.POST("/events", eventAPIConfig.CreateEvent)
-	routerWithAuthorization.PUT("/events/:eventId", eventAPIConfig.UpdateEvent)
-	routerWithAuthorization.DELETE("/events/:eventId", eventAPIConfig.DeleteEvent)
+	routerWithAuthorization.POST("/events/:eventId/refund", eventAPIConfig.RequestEventRefund)
+
+	// Event staff can be attendees, so these routes check the event owner or staff role in the service instead.
+	routerWithAuthorization.GET("/events/:eventId", eventAPIConfig.GetUserEventById)
+	routerWithAuthorization.GET("/events/:eventId/attendees", eventAPIConfig.GetEventAttendees)
+
+	// Only organizers and admins manage events, everyone else can browse and book them.
+	routerOrganizer := routerWithAuthorization.Group("")
+	routerOrganizer.Use(middleware.RequireRole(auth.RoleOrganizer, auth.RoleAdmin))
+
+	routerOrganizer.GET("/events", eventAPIConfig.GetUserEvents)
+	routerOrganizer.POST("/events", eventAPIConfig.CreateEvent)
+	routerOrganizer.PUT("/events/:eventId", eventAPIConfig.UpdateEvent)
+	routerOrganizer.DELETE("/events/:eventId", eventAPIConfig.DeleteEvent)
+	routerOrganizer.POST("/events/:eventId/publish", eventAPIConfig.PublishEvent)
+	routerOrganizer.POST("/events/:eventId/cancel", eventAPIConfig.CancelEvent)
+	routerOrganizer.POST("/events/:eventId/postpone", eventAPIConfig.PostponeEvent)
+	routerOrganizer.GET("/events/:eventId/reminders", eventAPIConfig.GetEventReminders)
+	routerOrganizer.PUT("/events/:eventId/reminders", eventAPIConfig.UpdateEventReminders)
+	routerOrganizer.GET("/branding", eventAPIConfig.GetOrganizerBranding)
+	routerOrganizer.PUT("/branding", eventAPIConfig.UpdateOrganizerBranding)
+
+	waitlistAPIConfig := waitlist.WaitlistAPIConfig{
+		Service: waitlistService,
+	}
+
+	routerWithAuthorization.GET("/waitlist", waitlistAPIConfig.GetUserWaitlist)
+	// The claim link of the offer email is opened from the browser, the token in it is enough to see the offer.
+	routerAPIPrefix.GET("/waitlist/claim", waitlistAPIConfig.GetWaitlistOffer)
+	routerWithAuthorization.POST("/event-details/:eventDetailId/waitlist", middleware.RequireVerifiedEmail(), waitlistAPIConfig.JoinWaitlist)
+	routerWithAuthorization.DELETE("/event-details/:eventDetailId/waitlist", waitlistAPIConfig.LeaveWaitlist)
+
+	eventDetailService := event_details.NewService(*dbQueries, dbConnection, newMailer, paymentGateway, waitlistService)
 
-	stripeClientEventDetails := &event_details.StripeAPIClient{}
-	eventDetailService := event_details.NewService(*dbQueries, newMailer, stripeClientEventDetails)
-	
 	eventDetailAPIConfig := event_details.EventDetailAPIConfig{
 		Service: eventDetailService,
 	}
@@ -101,8 +209,29 @@ func main() {
 	routerWithAuthorization.PUT("/events/:eventId/details/:eventDetailId", eventDetailAPIConfig.UpdateEventDetail)
 	routerWithAuthorization.DELETE("/events/:eventId/details/:eventDetailId", eventDetailAPIConfig.DeleteEventDetail)
 
-	stripeClientReservation := &reservations.StripeAPIClient{}
-	reservationService := reservations.NewService(*dbQueries, dbConnection, newMailer, stripeClientReservation)
+	promoCodeService := promo_codes.NewService(*dbQueries)
+
+	promoCodeAPIConfig := promo_codes.PromoCodeAPIConfig{
+		Service: promoCodeService,
+	}
+
+	routerWithAuthorization.GET("/events/:eventId/promo-codes", promoCodeAPIConfig.GetPromoCodes)
+	routerWithAuthorization.POST("/events/:eventId/promo-codes", promoCodeAPIConfig.CreatePromoCode)
+	routerWithAuthorization.PUT("/events/:eventId/promo-codes/:promoCodeId", promoCodeAPIConfig.UpdatePromoCode)
+	routerWithAuthorization.DELETE("/events/:eventId/promo-codes/:promoCodeId", promoCodeAPIConfig.DeletePromoCode)
+
+	eventStaffService := event_staff.NewService(*dbQueries, newMailer)
+
+	eventStaffAPIConfig := event_staff.EventStaffAPIConfig{
+		Service: eventStaffService,
+	}
+
+	routerOrganizer.GET("/events/:eventId/staff", eventStaffAPIConfig.GetEventStaff)
+	routerOrganizer.POST("/events/:eventId/staff", eventStaffAPIConfig.InviteEventStaff)
+	routerOrganizer.PATCH("/events/:eventId/staff/:staffId", eventStaffAPIConfig.UpdateEventStaff)
+	routerOrganizer.DELETE("/events/:eventId/staff/:staffId", eventStaffAPIConfig.RemoveEventStaff)
+
+	reservationService := reservations.NewService(*dbQueries, dbConnection, newMailer, paymentGateway, ticketIssuer, waitlistService)
 	reservationAPIConfig := reservations.ReservationAPIConfig{
 		Service: reservationService,
 
@@ -110,11 +239,22 @@ func main() {
 
 	routerWithAuthorization.GET("/reservations", reservationAPIConfig.GetUserReservations)
 	routerWithAuthorization.GET("/reservations/:reservationId", reservationAPIConfig.GetUserReservationById)
-	routerWithAuthorization.POST("/reservations", reservationAPIConfig.CreateReservation)
+	routerWithAuthorization.POST("/reservations", middleware.RequireVerifiedEmail(), middleware.IdempotencyMiddleware(dbQueries), reservationAPIConfig.CreateReservation)
 	routerWithAuthorization.PATCH("/reservations/:reservationId", reservationAPIConfig.UpdateReservationEmail)
+	routerWithAuthorization.GET("/reservations/:reservationId/ticket", reservationAPIConfig.GetReservationTicket)
+	routerWithAuthorization.POST("/events/:eventId/check-in", reservationAPIConfig.CheckIn)
+
+	calendarService := calendar.NewService(dbQueries, envConfig.AppBaseURL, envConfig.SenderName, envConfig.SenderEmail)
+	calendarAPIConfig := calendar.CalendarAPIConfig{
+		Service: calendarService,
+	}
+
+	// Calendar apps subscribe without an access token, the secret token in the URL authenticates the feed.
+	routerAPIPrefix.GET("/calendar/:token", calendarAPIConfig.GetCalendarFeed)
+	routerWithAuthorization.POST("/account/calendar", calendarAPIConfig.CreateCalendarFeed)
+	routerWithAuthorization.DELETE("/account/calendar", calendarAPIConfig.DeleteCalendarFeed)
 
-	stripeClientPayment := &payments.StripeAPIClient{}
-	paymentService := payments.NewService(dbQueries, stripeClientPayment, newMailer, envConfig.StripeSigningSecret, envConfig.StripeRefundSigningSecret)
+	paymentService := payments.NewService(dbQueries, paymentGateway, newMailer, ticketIssuer, waitlistService, envConfig.StripeSigningSecret, envConfig.StripeRefundSigningSecret)
 	paymentAPIConfig := payments.PaymentAPIConfig{
 		Service: paymentService,
 	}
@@ -124,7 +264,66 @@ func main() {
 	routerWithAuthorization.GET("/payments", paymentAPIConfig.GetUserPayments)
 	routerWithAuthorization.GET("/payments/:paymentId", paymentAPIConfig.GetUserPaymentById)
 	routerWithAuthorization.PATCH("/payments/:paymentId", paymentAPIConfig.UpdatePayment)
-	routerWithAuthorization.POST("/payments/:paymentId/refund", paymentAPIConfig.RefundPayment)
+	routerWithAuthorization.POST("/payments/:paymentId/refund", middleware.IdempotencyMiddleware(dbQueries), paymentAPIConfig.RefundPayment)
+
+	routerAdmin := routerWithAuthorization.Group("/admin")
+	routerAdmin.Use(middleware.RequireRole(auth.RoleAdmin))
+
+	routerAdmin.GET("/users", userAPIConfig.GetUsers)
+	routerAdmin.PATCH("/users/:userId/role", userAPIConfig.UpdateUserRole)
+	routerAdmin.GET("/events/:eventId", eventAPIConfig.GetEventById)
+	routerAdmin.GET("/payments/:paymentId", paymentAPIConfig.GetPaymentById)
+
+	routerAdmin.GET("/webhook-events", paymentAPIConfig.GetWebhookEvents)
+	routerAdmin.GET("/webhook-events/:webhookEventId", paymentAPIConfig.GetWebhookEventById)
+	routerAdmin.POST("/webhook-events/:webhookEventId/replay", paymentAPIConfig.ReplayWebhookEvent)
+
+	emailOutboxService := email_outbox.NewService(*dbQueries)
+	emailOutboxAPIConfig := email_outbox.EmailOutboxAPIConfig{
+		Service: emailOutboxService,
+	}
+
+	routerAdmin.GET("/emails", emailOutboxAPIConfig.GetOutboxEmails)
+	routerAdmin.GET("/emails/:emailId", emailOutboxAPIConfig.GetOutboxEmailById)
+	routerAdmin.POST("/emails/:emailId/resend", emailOutboxAPIConfig.ResendOutboxEmail)
+
+	paymentReconciler := payments.NewPaymentReconciler(paymentService, newMailer, clock.New(), envConfig.ReconciliationTime, envConfig.ReconciliationLookback, envConfig.ReconcileBatchSize)
+
+	if *reconcileOnly {
+		if _, reconcileError := paymentReconciler.Run(context.Background()); reconcileError != nil {
+			log.Fatalf("Payment reconciliation failed: %v", reconcileError)
+		}
+
+		// The server isn't started, so the report is sent from the outbox right away.
+		if _, deliverError := emailOutboxWorker.Deliver(context.Background()); deliverError != nil {
+			log.Fatalf("Sending the reconciliation report failed: %v", deliverError)
+		}
+
+		return
+	}
+
+	go paymentReconciler.Start(context.Background())
+
+	go emailOutboxWorker.Start(context.Background())
+
+	expiredPaymentSweeper := payments.NewExpiredPaymentSweeper(dbQueries, paymentGateway, newMailer, waitlistService, clock.New(), envConfig.PaymentSweepInterval, envConfig.PaymentSweepBatchSize)
+
+	go expiredPaymentSweeper.Start(context.Background())
+
+	expiredOfferSweeper := waitlist.NewExpiredOfferSweeper(waitlistService, clock.New(), envConfig.WaitlistSweepInterval, envConfig.WaitlistSweepBatchSize)
+
+	go expiredOfferSweeper.Start(context.Background())
+
+	showReminderScheduler := show_reminders.NewReminderScheduler(show_reminders.NewStore(dbConnection, dbQueries), newMailer, clock.New(), envConfig.ShowReminderInterval, envConfig.ReminderBatchSize)
+
+	go showReminderScheduler.Start(context.Background())
+
+	if fakePaymentGateway != nil {
+		// The fake has no servers to call the webhook endpoints, so its webhooks are handed to the payment service directly.
+		go fakePaymentGateway.Start(context.Background(), clock.New(), fakePaymentGatewayDeliveryInterval, func(ctx context.Context, delivery paymentgateway.Delivery) error {
+			return paymentService.HandleWebhook(ctx, delivery.Payload, delivery.Signature, delivery.WebhookType)
+		})
+	}
 
 	log.Printf("Server starting on port %s in %s mode", envConfig.Port, envConfig.GinMode)
 
@@ -18,15 +18,23 @@ func AuthorizationMiddleware(dbQueries *database.Queries, tokenValidator *auth.T
 			return
 		}
 		
-		email, extractEmailClaimError := tokenValidator.ValidateAndGetEmailClaim(bearerToken)
+		accessTokenClaims, validateTokenError := tokenValidator.ValidateAccessToken(bearerToken)
 
-		if extractEmailClaimError != nil {
+		if validateTokenError != nil {
 			ginContext.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
 			ginContext.Abort()
 			return
 		}
 
-		getUser, getUserError := dbQueries.GetUserByEmail(ginContext.Request.Context(), email)
+		isTokenRevoked, checkRevokedError := dbQueries.IsAccessTokenRevoked(ginContext.Request.Context(), accessTokenClaims.ID)
+
+		if checkRevokedError != nil || isTokenRevoked {
+			ginContext.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+			ginContext.Abort()
+			return
+		}
+
+		getUser, getUserError := dbQueries.GetUserById(ginContext.Request.Context(), accessTokenClaims.UserID)
 
 		if getUserError != nil {
 			ginContext.JSON(http.StatusUnauthorized, gin.H{"error": "error retrieving user information or invalid session"})
@@ -36,6 +44,9 @@ func AuthorizationMiddleware(dbQueries *database.Queries, tokenValidator *auth.T
 
 		ginContext.Set("userId", getUser.ID)
 		ginContext.Set("email", getUser.Email)
+		ginContext.Set("role", getUser.Role)
+		ginContext.Set("accessTokenClaims", accessTokenClaims)
+		ginContext.Set("emailVerified", getUser.EmailVerifiedAt.Valid)
 		
 		ginContext.Next()
 	}
@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	idempotencyKeyMaxLength = 255
+	idempotencyKeyTTL       = 24 * time.Hour
+
+	// A key still marked in progress after this long belongs to a request that never finished and can be taken over.
+	idempotencyKeyStaleAfter = 5 * time.Minute
+)
+
+type idempotencyResponseWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (writer *idempotencyResponseWriter) Write(data []byte) (int, error) {
+	writer.body.Write(data)
+
+	return writer.ResponseWriter.Write(data)
+}
+
+func (writer *idempotencyResponseWriter) WriteString(data string) (int, error) {
+	writer.body.WriteString(data)
+
+	return writer.ResponseWriter.WriteString(data)
+}
+
+// IdempotencyKeyStore keeps the claimed keys and their responses, *database.Queries in the app.
+type IdempotencyKeyStore interface {
+	ClaimIdempotencyKey(ctx context.Context, arg database.ClaimIdempotencyKeyParams) (database.IdempotencyKey, error)
+	CompleteIdempotencyKey(ctx context.Context, arg database.CompleteIdempotencyKeyParams) error
+	DeleteIdempotencyKey(ctx context.Context, id uuid.UUID) error
+	GetIdempotencyKey(ctx context.Context, arg database.GetIdempotencyKeyParams) (database.IdempotencyKey, error)
+}
+
+// IdempotencyMiddleware replays the stored response when a request is retried with the same Idempotency-Key.
+// It must run after AuthorizationMiddleware because keys are scoped per user.
+func IdempotencyMiddleware(dbQueries IdempotencyKeyStore) gin.HandlerFunc {
+	return func(ginContext *gin.Context) {
+		idempotencyKey := ginContext.GetHeader(IdempotencyKeyHeader)
+
+		// The header is optional, requests without it behave as before.
+		if idempotencyKey == "" {
+			ginContext.Next()
+
+			return
+		}
+
+		if len(idempotencyKey) > idempotencyKeyMaxLength {
+			ginContext.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Idempotency-Key must not exceed %d characters", idempotencyKeyMaxLength)})
+			ginContext.Abort()
+
+			return
+		}
+
+		requestBody, readBodyError := io.ReadAll(ginContext.Request.Body)
+
+		if readBodyError != nil {
+			ginContext.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			ginContext.Abort()
+
+			return
+		}
+
+		ginContext.Request.Body = io.NopCloser(bytes.NewReader(requestBody))
+
+		userID := ginContext.MustGet("userId").(uuid.UUID)
+		requestFingerprint := fingerprintRequest(ginContext.Request.Method, ginContext.Request.URL.Path, requestBody)
+		currentDateTime := time.Now()
+
+		claimedKey, claimKeyError := dbQueries.ClaimIdempotencyKey(ginContext.Request.Context(), database.ClaimIdempotencyKeyParams{
+			ID:                 uuid.New(),
+			IdempotencyKey:     idempotencyKey,
+			RequestMethod:      ginContext.Request.Method,
+			RequestPath:        ginContext.Request.URL.Path,
+			RequestFingerprint: requestFingerprint,
+			ExpiresAt:          currentDateTime.Add(idempotencyKeyTTL),
+			UserID:             userID,
+			StaleBefore:        currentDateTime.Add(-idempotencyKeyStaleAfter),
+		})
+
+		if claimKeyError != nil {
+			if !errors.Is(claimKeyError, sql.ErrNoRows) {
+				log.Printf("Error claiming idempotency key: %v", claimKeyError)
+				ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "error processing Idempotency-Key"})
+				ginContext.Abort()
+
+				return
+			}
+
+			// The key is already held by an earlier request.
+			replayIdempotentResponse(ginContext, dbQueries, userID, idempotencyKey, requestFingerprint)
+
+			return
+		}
+
+		responseWriter := &idempotencyResponseWriter{ResponseWriter: ginContext.Writer, body: &bytes.Buffer{}}
+		ginContext.Writer = responseWriter
+
+		ginContext.Set("idempotencyKey", fmt.Sprintf("%s:%s", userID, idempotencyKey))
+
+		ginContext.Next()
+
+		// Server errors are not cached so the client can retry with the same key. A retry books a new
+		// payment, and gateway calls are keyed by payment so it never collides with the failed attempt.
+		if responseWriter.Status() >= http.StatusInternalServerError {
+			if deleteKeyError := dbQueries.DeleteIdempotencyKey(ginContext.Request.Context(), claimedKey.ID); deleteKeyError != nil {
+				log.Printf("Error releasing idempotency key %s: %v", claimedKey.ID, deleteKeyError)
+			}
+
+			return
+		}
+
+		completeKeyError := dbQueries.CompleteIdempotencyKey(ginContext.Request.Context(), database.CompleteIdempotencyKeyParams{
+			ResponseStatus: sql.NullInt32{Int32: int32(responseWriter.Status()), Valid: true},
+			ResponseBody:   sql.NullString{String: responseWriter.body.String(), Valid: true},
+			ID:             claimedKey.ID,
+		})
+
+		if completeKeyError != nil {
+			log.Printf("Error storing response for idempotency key %s: %v", claimedKey.ID, completeKeyError)
+		}
+	}
+}
+
+// GetIdempotencyKey returns the user scoped key set by IdempotencyMiddleware, or an empty string when none was sent.
+func GetIdempotencyKey(ginContext *gin.Context) string {
+	return ginContext.GetString("idempotencyKey")
+}
+
+func replayIdempotentResponse(ginContext *gin.Context, dbQueries IdempotencyKeyStore, userID uuid.UUID, idempotencyKey string, requestFingerprint string) {
+	existingKey, getKeyError := dbQueries.GetIdempotencyKey(ginContext.Request.Context(), database.GetIdempotencyKeyParams{
+		UserID:         userID,
+		IdempotencyKey: idempotencyKey,
+	})
+
+	if getKeyError != nil {
+		log.Printf("Error retrieving idempotency key: %v", getKeyError)
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "error processing Idempotency-Key"})
+		ginContext.Abort()
+
+		return
+	}
+
+	if existingKey.RequestFingerprint != requestFingerprint {
+		ginContext.JSON(http.StatusConflict, gin.H{"error": "Idempotency-Key was already used with a different request"})
+		ginContext.Abort()
+
+		return
+	}
+
+	if !existingKey.ResponseStatus.Valid {
+		ginContext.JSON(http.StatusConflict, gin.H{"error": "a request with this Idempotency-Key is still being processed"})
+		ginContext.Abort()
+
+		return
+	}
+
+	ginContext.Header("Idempotent-Replayed", "true")
+	ginContext.Data(int(existingKey.ResponseStatus.Int32), "application/json; charset=utf-8", []byte(existingKey.ResponseBody.String))
+	ginContext.Abort()
+}
+
+func fingerprintRequest(method string, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write([]byte(path))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
\ No newline at end of file
@@ -0,0 +1,231 @@
+package middleware_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// MockIdempotencyKeyStore keeps one key per user like the unique index, a claimed key can't be claimed again until
+// it's deleted.
+type MockIdempotencyKeyStore struct {
+	keys map[database.GetIdempotencyKeyParams]database.IdempotencyKey
+}
+
+func NewMockIdempotencyKeyStore() *MockIdempotencyKeyStore {
+	return &MockIdempotencyKeyStore{keys: map[database.GetIdempotencyKeyParams]database.IdempotencyKey{}}
+}
+
+func (mockStore *MockIdempotencyKeyStore) ClaimIdempotencyKey(ctx context.Context, arg database.ClaimIdempotencyKeyParams) (database.IdempotencyKey, error) {
+	keyParams := database.GetIdempotencyKeyParams{UserID: arg.UserID, IdempotencyKey: arg.IdempotencyKey}
+
+	if _, ok := mockStore.keys[keyParams]; ok {
+		return database.IdempotencyKey{}, sql.ErrNoRows
+	}
+
+	claimedKey := database.IdempotencyKey{
+		ID:                 arg.ID,
+		IdempotencyKey:     arg.IdempotencyKey,
+		RequestMethod:      arg.RequestMethod,
+		RequestPath:        arg.RequestPath,
+		RequestFingerprint: arg.RequestFingerprint,
+		ExpiresAt:          arg.ExpiresAt,
+		UserID:             arg.UserID,
+	}
+
+	mockStore.keys[keyParams] = claimedKey
+
+	return claimedKey, nil
+}
+
+func (mockStore *MockIdempotencyKeyStore) CompleteIdempotencyKey(ctx context.Context, arg database.CompleteIdempotencyKeyParams) error {
+	for keyParams, idempotencyKey := range mockStore.keys {
+		if idempotencyKey.ID == arg.ID {
+			idempotencyKey.ResponseStatus = arg.ResponseStatus
+			idempotencyKey.ResponseBody = arg.ResponseBody
+			mockStore.keys[keyParams] = idempotencyKey
+		}
+	}
+
+	return nil
+}
+
+func (mockStore *MockIdempotencyKeyStore) DeleteIdempotencyKey(ctx context.Context, id uuid.UUID) error {
+	for keyParams, idempotencyKey := range mockStore.keys {
+		if idempotencyKey.ID == id {
+			delete(mockStore.keys, keyParams)
+		}
+	}
+
+	return nil
+}
+
+func (mockStore *MockIdempotencyKeyStore) GetIdempotencyKey(ctx context.Context, arg database.GetIdempotencyKeyParams) (database.IdempotencyKey, error) {
+	idempotencyKey, ok := mockStore.keys[arg]
+
+	if !ok {
+		return database.IdempotencyKey{}, sql.ErrNoRows
+	}
+
+	return idempotencyKey, nil
+}
+
+// idempotentTestServer answers POST /reservations with the given status and counts how often the handler ran.
+type idempotentTestServer struct {
+	router         *gin.Engine
+	handlerCalls   int
+	responseStatus int
+	scopedKeys     []string
+	duringHandler  func()
+}
+
+func newIdempotentTestServer(store middleware.IdempotencyKeyStore) *idempotentTestServer {
+	gin.SetMode(gin.TestMode)
+
+	server := &idempotentTestServer{router: gin.New(), responseStatus: http.StatusCreated}
+
+	server.router.Use(func(ginContext *gin.Context) {
+		ginContext.Set("userId", uuid.MustParse(ginContext.GetHeader("X-Test-User")))
+		ginContext.Next()
+	})
+	server.router.POST("/reservations", middleware.IdempotencyMiddleware(store), func(ginContext *gin.Context) {
+		server.handlerCalls++
+		server.scopedKeys = append(server.scopedKeys, middleware.GetIdempotencyKey(ginContext))
+
+		if server.duringHandler != nil {
+			server.duringHandler()
+		}
+
+		ginContext.JSON(server.responseStatus, gin.H{"call": server.handlerCalls})
+	})
+
+	return server
+}
+
+func (server *idempotentTestServer) post(userID uuid.UUID, idempotencyKey string, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/reservations", strings.NewReader(body))
+	request.Header.Set("X-Test-User", userID.String())
+
+	if idempotencyKey != "" {
+		request.Header.Set(middleware.IdempotencyKeyHeader, idempotencyKey)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func TestIdempotencyMiddlewareReplaysResponse(t *testing.T) {
+	userID := uuid.New()
+	server := newIdempotentTestServer(NewMockIdempotencyKeyStore())
+
+	firstResponse := server.post(userID, "reservation-1", `{"quantity":2}`)
+	retriedResponse := server.post(userID, "reservation-1", `{"quantity":2}`)
+
+	if server.handlerCalls != 1 {
+		t.Fatalf("Expected the handler to run once, ran %d times", server.handlerCalls)
+	}
+
+	if retriedResponse.Code != http.StatusCreated || retriedResponse.Body.String() != firstResponse.Body.String() {
+		t.Errorf("Expected the retry to get %d %s, got %d %s", firstResponse.Code, firstResponse.Body.String(), retriedResponse.Code, retriedResponse.Body.String())
+	}
+
+	if retriedResponse.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("Expected the retry to be marked as replayed")
+	}
+
+	if firstResponse.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("Expected the first response not to be marked as replayed")
+	}
+
+	if expectedKey := fmt.Sprintf("%s:reservation-1", userID); len(server.scopedKeys) != 1 || server.scopedKeys[0] != expectedKey {
+		t.Errorf("Expected the handler to get key %s, got %v", expectedKey, server.scopedKeys)
+	}
+}
+
+func TestIdempotencyMiddlewareRejectsDifferentRequest(t *testing.T) {
+	userID := uuid.New()
+	server := newIdempotentTestServer(NewMockIdempotencyKeyStore())
+
+	server.post(userID, "reservation-1", `{"quantity":2}`)
+	conflictResponse := server.post(userID, "reservation-1", `{"quantity":3}`)
+
+	if conflictResponse.Code != http.StatusConflict {
+		t.Errorf("Expected status %d for a different request with the same key, got %d", http.StatusConflict, conflictResponse.Code)
+	}
+
+	if server.handlerCalls != 1 {
+		t.Errorf("Expected the handler to run once, ran %d times", server.handlerCalls)
+	}
+}
+
+func TestIdempotencyMiddlewareRejectsRequestInProgress(t *testing.T) {
+	userID := uuid.New()
+	server := newIdempotentTestServer(NewMockIdempotencyKeyStore())
+
+	var inProgressResponse *httptest.ResponseRecorder
+
+	// The retry arrives while the handler of the first request is still running.
+	server.duringHandler = func() {
+		server.duringHandler = nil
+		inProgressResponse = server.post(userID, "reservation-1", `{"quantity":2}`)
+	}
+
+	server.post(userID, "reservation-1", `{"quantity":2}`)
+
+	if inProgressResponse == nil || inProgressResponse.Code != http.StatusConflict {
+		t.Fatalf("Expected status %d while the first request is processed, got %v", http.StatusConflict, inProgressResponse)
+	}
+
+	if server.handlerCalls != 1 {
+		t.Errorf("Expected the handler to run once, ran %d times", server.handlerCalls)
+	}
+}
+
+func TestIdempotencyMiddlewareRetriesServerErrors(t *testing.T) {
+	userID := uuid.New()
+	server := newIdempotentTestServer(NewMockIdempotencyKeyStore())
+
+	server.responseStatus = http.StatusInternalServerError
+	server.post(userID, "reservation-1", `{"quantity":2}`)
+
+	server.responseStatus = http.StatusCreated
+	retriedResponse := server.post(userID, "reservation-1", `{"quantity":2}`)
+
+	if server.handlerCalls != 2 || retriedResponse.Code != http.StatusCreated {
+		t.Errorf("Expected the retry after a server error to run the handler again, got %d calls and status %d", server.handlerCalls, retriedResponse.Code)
+	}
+}
+
+func TestIdempotencyMiddlewareScopesKeysPerUser(t *testing.T) {
+	server := newIdempotentTestServer(NewMockIdempotencyKeyStore())
+
+	server.post(uuid.New(), "reservation-1", `{"quantity":2}`)
+	otherUserResponse := server.post(uuid.New(), "reservation-1", `{"quantity":2}`)
+
+	if server.handlerCalls != 2 || otherUserResponse.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("Expected another user's request with the same key to run the handler, got %d calls", server.handlerCalls)
+	}
+}
+
+func TestIdempotencyMiddlewareWithoutKey(t *testing.T) {
+	userID := uuid.New()
+	server := newIdempotentTestServer(NewMockIdempotencyKeyStore())
+
+	server.post(userID, "", `{"quantity":2}`)
+	server.post(userID, "", `{"quantity":2}`)
+
+	if server.handlerCalls != 2 {
+		t.Errorf("Expected requests without a key to always run the handler, ran %d times", server.handlerCalls)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole only lets through users that have one of the given roles.
+// It must run after AuthorizationMiddleware, which loads the role from the database so role changes apply right away.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(ginContext *gin.Context) {
+		if !slices.Contains(roles, ginContext.GetString("role")) {
+			ginContext.JSON(http.StatusForbidden, gin.H{"error": "you don't have permission to access this resource"})
+			ginContext.Abort()
+
+			return
+		}
+
+		ginContext.Next()
+	}
+}
\ No newline at end of file
@@ -0,0 +1,57 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/elorenzorodz/event-mrs/internal/auth"
+	"github.com/elorenzorodz/event-mrs/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireRole(tTesting *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		role           string
+		expectedStatus int
+	}{
+		{name: "Allowed_Organizer", role: auth.RoleOrganizer, expectedStatus: http.StatusOK},
+		{name: "Allowed_Admin", role: auth.RoleAdmin, expectedStatus: http.StatusOK},
+		{name: "Forbidden_Attendee", role: auth.RoleAttendee, expectedStatus: http.StatusForbidden},
+		{name: "Forbidden_NoRole", role: "", expectedStatus: http.StatusForbidden},
+		{name: "Forbidden_UnknownRole", role: "superuser", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.name, func(t *testing.T) {
+			handlerCalled := false
+
+			router := gin.New()
+			router.Use(func(ginContext *gin.Context) {
+				if tc.role != "" {
+					ginContext.Set("role", tc.role)
+				}
+
+				ginContext.Next()
+			})
+			router.GET("/events", middleware.RequireRole(auth.RoleOrganizer, auth.RoleAdmin), func(ginContext *gin.Context) {
+				handlerCalled = true
+				ginContext.Status(http.StatusOK)
+			})
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+			if recorder.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatus, recorder.Code)
+			}
+
+			if handlerCalled != (tc.expectedStatus == http.StatusOK) {
+				t.Errorf("Expected handler called to be %t, got %t", tc.expectedStatus == http.StatusOK, handlerCalled)
+			}
+		})
+	}
+}
\ No newline at end of file
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireVerifiedEmail blocks users that haven't verified their email address yet.
+// It must run after AuthorizationMiddleware.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(ginContext *gin.Context) {
+		if !ginContext.GetBool("emailVerified") {
+			ginContext.JSON(http.StatusForbidden, gin.H{"error": "please verify your email address first"})
+			ginContext.Abort()
+
+			return
+		}
+
+		ginContext.Next()
+	}
+}
\ No newline at end of file
@@ -8,6 +8,9 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/elorenzorodz/event-mrs/internal/paymentgateway"
+	"github.com/elorenzorodz/event-mrs/internal/paymentstatus"
+	"github.com/elorenzorodz/event-mrs/middleware"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -45,6 +48,12 @@ func (paymentAPIConfig *PaymentAPIConfig) UpdatePayment(ginContext *gin.Context)
 			return
 		}
 
+		if errors.Is(updateError, paymentstatus.ErrTransition) {
+			ginContext.JSON(http.StatusConflict, gin.H{"error": updateError.Error()})
+
+			return
+		}
+
 		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": updateError.Error()})
 
 		return
@@ -64,7 +73,7 @@ func (paymentAPIConfig *PaymentAPIConfig) RefundPayment(ginContext *gin.Context)
 		return
 	}
 
-	refundResponse, refundError := paymentAPIConfig.Service.RefundPayment(ginContext.Request.Context(), paymentID, userID)
+	refundResponse, refundError := paymentAPIConfig.Service.RefundPayment(ginContext.Request.Context(), paymentID, userID, middleware.GetIdempotencyKey(ginContext))
 
 	if refundError != nil {
 		if errors.Is(refundError, ErrNotFound) {
@@ -72,6 +81,13 @@ func (paymentAPIConfig *PaymentAPIConfig) RefundPayment(ginContext *gin.Context)
 
 			return
 		}
+
+		if errors.Is(refundError, paymentstatus.ErrTransition) {
+			ginContext.JSON(http.StatusConflict, gin.H{"error": refundError.Error()})
+
+			return
+		}
+
 		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": refundError.Error()})
 
 		return
@@ -95,7 +111,7 @@ func (paymentAPIConfig *PaymentAPIConfig) HandleStripeWebhook(ginContext *gin.Co
 	signature := ginContext.GetHeader("Stripe-Signature")
 	
 	if err := paymentAPIConfig.Service.HandleWebhook(ginContext.Request.Context(), payload, signature, "payment"); err != nil {
-		if strings.Contains(err.Error(), "Stripe signature") {
+		if errors.Is(err, paymentgateway.ErrInvalidSignature) {
 			ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid signature"})
 			
 			return
@@ -123,7 +139,7 @@ func (paymentAPIConfig *PaymentAPIConfig) HandleStripeRefundWebhook(ginContext *
 	signature := ginContext.GetHeader("Stripe-Signature")
 	
 	if err := paymentAPIConfig.Service.HandleWebhook(ginContext.Request.Context(), payload, signature, "refund"); err != nil {
-		if strings.Contains(err.Error(), "Stripe signature") {
+		if errors.Is(err, paymentgateway.ErrInvalidSignature) {
 			ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid signature"})
 			
 			return
@@ -175,4 +191,95 @@ func (paymentAPIConfig *PaymentAPIConfig) GetUserPaymentById(ginContext *gin.Con
 	}
 
 	ginContext.JSON(http.StatusOK, payment)
+}
+
+func (paymentAPIConfig *PaymentAPIConfig) GetPaymentById(ginContext *gin.Context) {
+	paymentID, parsePaymentIDError := uuid.Parse(ginContext.Param("paymentId"))
+
+	if parsePaymentIDError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment ID"})
+
+		return
+	}
+
+	payment, err := paymentAPIConfig.Service.GetPaymentById(ginContext.Request.Context(), paymentID)
+
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			ginContext.JSON(http.StatusNotFound, gin.H{"error": "payment not found"})
+
+			return
+		}
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, payment)
+}
+
+func (paymentAPIConfig *PaymentAPIConfig) GetWebhookEvents(ginContext *gin.Context) {
+	webhookEvents, getWebhookEventsError := paymentAPIConfig.Service.GetWebhookEvents(
+		ginContext.Request.Context(),
+		ginContext.Query("status"),
+		ginContext.Query("event_type"),
+		ginContext.Query("limit"),
+		ginContext.Query("offset"),
+	)
+
+	if getWebhookEventsError != nil {
+		if strings.Contains(getWebhookEventsError.Error(), "invalid") {
+			ginContext.JSON(http.StatusBadRequest, gin.H{"error": getWebhookEventsError.Error()})
+
+			return
+		}
+
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "error retrieving webhook events, please try again in a few minutes"})
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"webhook_events": webhookEvents})
+}
+
+func (paymentAPIConfig *PaymentAPIConfig) GetWebhookEventById(ginContext *gin.Context) {
+	webhookEvent, getWebhookEventError := paymentAPIConfig.Service.GetWebhookEventById(ginContext.Request.Context(), ginContext.Param("webhookEventId"))
+
+	if getWebhookEventError != nil {
+		if errors.Is(getWebhookEventError, ErrWebhookEventNotFound) {
+			ginContext.JSON(http.StatusNotFound, gin.H{"error": getWebhookEventError.Error()})
+
+			return
+		}
+
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": getWebhookEventError.Error()})
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, webhookEvent)
+}
+
+func (paymentAPIConfig *PaymentAPIConfig) ReplayWebhookEvent(ginContext *gin.Context) {
+	webhookEvent, replayWebhookEventError := paymentAPIConfig.Service.ReplayWebhookEvent(ginContext.Request.Context(), ginContext.Param("webhookEventId"))
+
+	if replayWebhookEventError != nil {
+		if errors.Is(replayWebhookEventError, ErrWebhookEventNotFound) {
+			ginContext.JSON(http.StatusNotFound, gin.H{"error": replayWebhookEventError.Error()})
+
+			return
+		}
+
+		if errors.Is(replayWebhookEventError, ErrWebhookEventAlreadyProcessed) || errors.Is(replayWebhookEventError, ErrWebhookEventInProgress) {
+			ginContext.JSON(http.StatusConflict, gin.H{"error": replayWebhookEventError.Error()})
+
+			return
+		}
+
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": replayWebhookEventError.Error()})
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, webhookEvent)
 }
\ No newline at end of file
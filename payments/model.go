@@ -2,12 +2,17 @@ package payments
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
+	"github.com/elorenzorodz/event-mrs/config"
+	"github.com/elorenzorodz/event-mrs/internal/clock"
 	"github.com/elorenzorodz/event-mrs/internal/database"
 	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/elorenzorodz/event-mrs/internal/money"
+	"github.com/elorenzorodz/event-mrs/internal/paymentgateway"
+	"github.com/elorenzorodz/event-mrs/internal/tickets"
 	"github.com/google/uuid"
-	"github.com/stripe/stripe-go/v83"
 )
 
 type PaymentAPIConfig struct {
@@ -15,15 +20,25 @@ type PaymentAPIConfig struct {
 }
 
 type Payment struct {
-	ID              uuid.UUID `json:"id"`
-	PaymentIntentID string    `json:"payment_intent_id"`
-	Amount          float64   `json:"amount"`
-	Currency        string    `json:"currency"`
-	Status          string    `json:"status"`
-	ExpiresAt       time.Time `json:"expires_at"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       string    `json:"updated_at"`
-	UserID          uuid.UUID `json:"user_id"`
+	ID              uuid.UUID   `json:"id"`
+	PaymentIntentID string      `json:"payment_intent_id"`
+	Amount          money.Money `json:"amount"`
+	DiscountAmount  money.Money `json:"discount_amount"`
+	PromoCodeID     *uuid.UUID  `json:"promo_code_id"`
+	Currency        string      `json:"currency"`
+	Status          string      `json:"status"`
+	ExpiresAt       time.Time   `json:"expires_at"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       string      `json:"updated_at"`
+	UserID          uuid.UUID   `json:"user_id"`
+	// StatusHistory is only filled in for admins.
+	StatusHistory []PaymentStatusChange `json:"status_history,omitempty"`
+}
+
+type PaymentStatusChange struct {
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ChangedAt  time.Time `json:"changed_at"`
 }
 
 type PaymentResponse struct {
@@ -45,33 +60,127 @@ type PaymentRefundResponse struct {
 }
 
 type PaymentRefunded struct {
-	PaymentID         uuid.UUID `json:"payment_id"`
-	Amount            string    `json:"amount_refunded"`
-	Title             string    `json:"title"`
-	TicketDescription string    `json:"ticket_description"`
-	ShowDate          time.Time `json:"show_date"`
+	PaymentID         uuid.UUID   `json:"payment_id"`
+	Amount            money.Money `json:"amount_refunded"`
+	Title             string      `json:"title"`
+	TicketDescription string      `json:"ticket_description"`
+	ShowDate          time.Time   `json:"show_date"`
 }
 
-type StripeClient interface {
-	UpdatePaymentIntent(paymentIntentID string, params *stripe.PaymentIntentParams) (*stripe.PaymentIntent, error)
-	CreateRefund(params *stripe.RefundParams) (*stripe.Refund, error)
-	ConstructEvent(payload []byte, signature string, secret string) (stripe.Event, error)
+type WebhookEvent struct {
+	ID              string          `json:"id"`
+	EventType       string          `json:"event_type"`
+	WebhookType     string          `json:"webhook_type"`
+	Status          string          `json:"status"`
+	Attempts        int32           `json:"attempts"`
+	ProcessingError string          `json:"processing_error"`
+	ReceivedAt      time.Time       `json:"received_at"`
+	ProcessedAt     string          `json:"processed_at"`
+	Payload         json.RawMessage `json:"payload,omitempty"`
 }
 
 type PaymentService interface {
 	GetUserPayments(ctx context.Context, userID uuid.UUID) ([]*Payment, error)
 	GetUserPaymentById(ctx context.Context, paymentID, userID uuid.UUID) (*Payment, error)
+	GetPaymentById(ctx context.Context, paymentID uuid.UUID) (*Payment, error)
 	UpdatePayment(ctx context.Context, paymentID, userID uuid.UUID, paymentMethodID string) (*PaymentResponse, error)
-	RefundPayment(ctx context.Context, paymentID, userID uuid.UUID) (*PaymentRefundResponse, error)
+	RefundPayment(ctx context.Context, paymentID, userID uuid.UUID, idempotencyKey string) (*PaymentRefundResponse, error)
 	HandleWebhook(ctx context.Context, body []byte, signature string, webhookType string) error
+	GetWebhookEvents(ctx context.Context, status string, eventType string, limitQuery string, offsetQuery string) ([]WebhookEvent, error)
+	GetWebhookEventById(ctx context.Context, eventID string) (*WebhookEvent, error)
+	ReplayWebhookEvent(ctx context.Context, eventID string) (*WebhookEvent, error)
+	Reconcile(ctx context.Context, changedSince time.Time, batchSize int32) (*ReconciliationReport, error)
 }
 
 type Service struct {
-	DB                        *database.Queries
-	Stripe                    StripeClient
-	Mailer                    *mailer.Mailer
-	StripeSigningSecret       string
-	StripeRefundSigningSecret string
+	DB                  *database.Queries
+	WebhookEvents       WebhookEventStore
+	PaymentGateway      paymentgateway.Gateway
+	Mailer              mailer.Mailer
+	Tickets             *tickets.Issuer
+	Waitlist            WaitlistOfferer
+	SigningSecret       string
+	RefundSigningSecret string
+}
+
+// WebhookEventStore keeps the received webhook events, *database.Queries in the app.
+type WebhookEventStore interface {
+	ClaimStripeWebhookEvent(ctx context.Context, arg database.ClaimStripeWebhookEventParams) (string, error)
+	GetStripeWebhookEventById(ctx context.Context, id string) (database.StripeWebhookEvent, error)
+	GetStripeWebhookEvents(ctx context.Context, arg database.GetStripeWebhookEventsParams) ([]database.StripeWebhookEvent, error)
+	MarkStripeWebhookEventFailed(ctx context.Context, arg database.MarkStripeWebhookEventFailedParams) error
+	MarkStripeWebhookEventProcessed(ctx context.Context, id string) error
+	RecordStripeWebhookEvent(ctx context.Context, arg database.RecordStripeWebhookEventParams) (database.StripeWebhookEvent, error)
+}
+
+type ExpiredPaymentMailer interface {
+	SendPaymentExpiredNotification(recipientName string, recipientEmail string, eventDetailsWithEventTitle []database.GetEventDetailsWithTitleByIdsRow) error
+}
+
+// WaitlistOfferer hands tickets that were put back on sale to the people waiting for them.
+type WaitlistOfferer interface {
+	OfferReleasedTickets(ctx context.Context, eventDetailIDs []uuid.UUID)
+}
+
+// ExpiredPaymentSweeper releases tickets held by payments that were not completed before expires_at.
+type ExpiredPaymentSweeper struct {
+	DB             config.DBQueries
+	PaymentGateway paymentgateway.Gateway
+	Mailer         ExpiredPaymentMailer
+	Waitlist       WaitlistOfferer
+	Clock          clock.Clock
+	Interval       time.Duration
+	BatchSize      int32
+}
+
+type ReconciliationReportMailer interface {
+	SendReconciliationReport(subject string, report string) error
+}
+
+// PaymentReconciler checks payments against the payment gateway once a day at RunAt (time of day in UTC) and emails the
+// report to the team. Payments that changed within Lookback are checked along with the ones that are still in progress.
+type PaymentReconciler struct {
+	Payments  PaymentService
+	Mailer    ReconciliationReportMailer
+	Clock     clock.Clock
+	RunAt     time.Duration
+	Lookback  time.Duration
+	BatchSize int32
+}
+
+type ReconciliationAction string
+
+const (
+	ReconciliationActionNone         ReconciliationAction = "none"
+	ReconciliationActionMarkPaid     ReconciliationAction = "mark_paid"
+	ReconciliationActionMarkRefunded ReconciliationAction = "mark_refunded"
+	ReconciliationActionFlag         ReconciliationAction = "flag"
+)
+
+// ReconciliationFinding is what a payment check found, IssueType and Description are empty when the payment matches the gateway.
+type ReconciliationFinding struct {
+	Action      ReconciliationAction
+	IssueType   string
+	Description string
+}
+
+type ReconciliationIssue struct {
+	PaymentID       uuid.UUID
+	PaymentIntentID string
+	IssueType       string
+	Description     string
+	PaymentStatus   string
+	GatewayStatus   string
 }
 
-type StripeAPIClient struct{}
\ No newline at end of file
+type ReconciliationReport struct {
+	ChangedSince time.Time
+	StartedAt    time.Time
+	FinishedAt   time.Time
+	Checked      int
+	// Unchecked counts the payments whose intent couldn't be fetched from the gateway, they're checked again on the next run.
+	Unchecked int
+	Repaired  []ReconciliationIssue
+	Flagged   []ReconciliationIssue
+	Errors    []string
+}
\ No newline at end of file
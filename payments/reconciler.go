@@ -0,0 +1,341 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/clock"
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/money"
+	"github.com/elorenzorodz/event-mrs/internal/paymentgateway"
+	"github.com/elorenzorodz/event-mrs/internal/paymentstatus"
+	"github.com/elorenzorodz/event-mrs/internal/sqlutil"
+	"github.com/google/uuid"
+)
+
+// Issue types recorded in reconciliation_issues.
+const (
+	IssueMissedPaymentSuccess = "missed_payment_success"
+	IssueMissedRefund         = "missed_refund"
+	IssueStatusMismatch       = "status_mismatch"
+	IssueAmountMismatch       = "amount_mismatch"
+	IssueCurrencyMismatch     = "currency_mismatch"
+	IssueIntentMissing        = "intent_missing"
+)
+
+func NewPaymentReconciler(paymentService PaymentService, reportMailer ReconciliationReportMailer, reconcilerClock clock.Clock, runAt time.Duration, lookback time.Duration, batchSize int32) *PaymentReconciler {
+	return &PaymentReconciler{
+		Payments:  paymentService,
+		Mailer:    reportMailer,
+		Clock:     reconcilerClock,
+		RunAt:     runAt,
+		Lookback:  lookback,
+		BatchSize: batchSize,
+	}
+}
+
+// Start runs the reconciliation every day at RunAt until the context is cancelled.
+func (reconciler *PaymentReconciler) Start(ctx context.Context) {
+	log.Printf("Payment reconciler started, runs daily at %s UTC, lookback: %s", time.Time{}.Add(reconciler.RunAt).Format("15:04"), reconciler.Lookback)
+
+	for {
+		now := reconciler.Clock.Now()
+
+		select {
+		case <-ctx.Done():
+			log.Printf("Payment reconciler stopped: %v", ctx.Err())
+
+			return
+		case <-reconciler.Clock.After(NextReconciliationRun(now, reconciler.RunAt).Sub(now)):
+			reconciler.Run(ctx)
+		}
+	}
+}
+
+// Run reconciles once and emails the report to the team. A run that stopped part way still reports what it got through.
+func (reconciler *PaymentReconciler) Run(ctx context.Context) (*ReconciliationReport, error) {
+	startedAt := reconciler.Clock.Now()
+
+	report, reconcileError := reconciler.Payments.Reconcile(ctx, startedAt.Add(-reconciler.Lookback), reconciler.BatchSize)
+
+	report.StartedAt = startedAt
+	report.FinishedAt = reconciler.Clock.Now()
+
+	if reconcileError != nil {
+		log.Printf("Payment reconciler error: %v", reconcileError)
+
+		report.Errors = append(report.Errors, reconcileError.Error())
+	}
+
+	log.Printf("Payment reconciler checked %d payment/s: %d repaired, %d flagged, %d unchecked", report.Checked, len(report.Repaired), len(report.Flagged), report.Unchecked)
+
+	if sendReportError := reconciler.Mailer.SendReconciliationReport(report.Subject(), report.Summary()); sendReportError != nil {
+		log.Printf("Error sending payment reconciliation report: %v", sendReportError)
+	}
+
+	return report, reconcileError
+}
+
+// NextReconciliationRun returns the first time after now that is runAt past midnight UTC.
+func NextReconciliationRun(now time.Time, runAt time.Duration) time.Time {
+	now = now.UTC()
+	nextRun := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).Add(runAt)
+
+	if !nextRun.After(now) {
+		nextRun = nextRun.AddDate(0, 0, 1)
+	}
+
+	return nextRun
+}
+
+// Reconcile checks the payments that are still in progress or changed since changedSince against the payment gateway.
+// Payment and refund webhooks that were missed are applied again, every other mismatch is recorded in
+// reconciliation_issues for the team to look at. The report is returned even when the run stops part way.
+func (service *Service) Reconcile(ctx context.Context, changedSince time.Time, batchSize int32) (*ReconciliationReport, error) {
+	report := &ReconciliationReport{ChangedSince: changedSince}
+	afterID := uuid.Nil
+
+	for {
+		paymentsToReconcile, getPaymentsError := service.DB.GetPaymentsToReconcile(ctx, database.GetPaymentsToReconcileParams{
+			AfterID:      afterID,
+			ChangedSince: changedSince,
+			BatchSize:    batchSize,
+		})
+
+		if getPaymentsError != nil {
+			return report, fmt.Errorf("failed to retrieve payments to reconcile: %w", getPaymentsError)
+		}
+
+		for _, payment := range paymentsToReconcile {
+			service.reconcilePayment(ctx, payment, report)
+		}
+
+		if len(paymentsToReconcile) < int(batchSize) {
+			return report, nil
+		}
+
+		afterID = paymentsToReconcile[len(paymentsToReconcile)-1].ID
+	}
+}
+
+func (service *Service) reconcilePayment(ctx context.Context, payment database.Payment, report *ReconciliationReport) {
+	report.Checked++
+
+	intent, getIntentError := service.PaymentGateway.GetIntent(ctx, payment.PaymentIntentID.String)
+
+	if getIntentError != nil {
+		if gatewayError, ok := paymentgateway.AsError(getIntentError); ok && gatewayError.Code == paymentgateway.ErrorCodeResourceMissing {
+			service.recordReconciliationIssue(ctx, payment, "missing", ReconciliationFinding{
+				Action:      ReconciliationActionFlag,
+				IssueType:   IssueIntentMissing,
+				Description: fmt.Sprintf("the payment gateway has no payment intent %s", payment.PaymentIntentID.String),
+			}, report)
+
+			return
+		}
+
+		report.Unchecked++
+		report.Errors = append(report.Errors, fmt.Sprintf("payment %s: failed to fetch payment intent %s: %v", payment.ID, payment.PaymentIntentID.String, getIntentError))
+
+		return
+	}
+
+	finding := CheckPaymentAgainstIntent(payment, *intent)
+
+	var repairError error
+
+	switch finding.Action {
+	case ReconciliationActionNone:
+		resolveIssuesError := service.DB.ResolveReconciliationIssues(ctx, database.ResolveReconciliationIssuesParams{
+			Resolution: sqlutil.StringToNullString("no longer detected"),
+			PaymentID:  uuid.NullUUID{UUID: payment.ID, Valid: true},
+		})
+
+		if resolveIssuesError != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("payment %s: failed to resolve reconciliation issues: %v", payment.ID, resolveIssuesError))
+		}
+
+		return
+
+	case ReconciliationActionMarkPaid:
+		// Intents created before the payment_id metadata was added can still be matched to their payment.
+		if intent.PaymentID == "" {
+			intent.PaymentID = payment.ID.String()
+		}
+
+		repairError = service.handlePaymentIntentSuccess(ctx, *intent)
+
+	case ReconciliationActionMarkRefunded:
+		repairError = service.handleChargeRefunded(ctx, paymentgateway.Refund{
+			PaymentIntentID: intent.ID,
+			Amount:          intent.AmountRefunded,
+			Status:          paymentgateway.RefundStatusSucceeded,
+		})
+	}
+
+	if repairError != nil {
+		finding.Action = ReconciliationActionFlag
+		finding.Description = fmt.Sprintf("%s, repair failed: %v", finding.Description, repairError)
+	}
+
+	service.recordReconciliationIssue(ctx, payment, string(intent.Status), finding, report)
+}
+
+func (service *Service) recordReconciliationIssue(ctx context.Context, payment database.Payment, gatewayStatus string, finding ReconciliationFinding, report *ReconciliationReport) {
+	issue := ReconciliationIssue{
+		PaymentID:       payment.ID,
+		PaymentIntentID: payment.PaymentIntentID.String,
+		IssueType:       finding.IssueType,
+		Description:     finding.Description,
+		PaymentStatus:   payment.Status,
+		GatewayStatus:   gatewayStatus,
+	}
+
+	createIssueParams := database.CreateReconciliationIssueParams{
+		ID:              uuid.New(),
+		PaymentID:       uuid.NullUUID{UUID: payment.ID, Valid: true},
+		PaymentIntentID: issue.PaymentIntentID,
+		IssueType:       issue.IssueType,
+		Description:     issue.Description,
+		PaymentStatus:   issue.PaymentStatus,
+		GatewayStatus:   issue.GatewayStatus,
+	}
+
+	// Repaired issues are recorded already resolved so there is a trail of what the reconciliation changed.
+	if finding.Action == ReconciliationActionFlag {
+		report.Flagged = append(report.Flagged, issue)
+	} else {
+		report.Repaired = append(report.Repaired, issue)
+
+		createIssueParams.Resolution = sqlutil.StringToNullString("repaired")
+		createIssueParams.ResolvedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+
+	if _, createIssueError := service.DB.CreateReconciliationIssue(ctx, createIssueParams); createIssueError != nil {
+		log.Printf("Reconciliation CRITICAL: Failed to record %s issue for payment %s: %v", issue.IssueType, payment.ID, createIssueError)
+
+		report.Errors = append(report.Errors, fmt.Sprintf("payment %s: failed to record %s issue: %v", payment.ID, issue.IssueType, createIssueError))
+	}
+}
+
+// CheckPaymentAgainstIntent compares a payment with its intent at the payment gateway. Payments the gateway charged or
+// refunded while the webhook was missed are repaired, payments that are still being paid or cancelled are left to the
+// payment flow and the expired payment sweeper, anything else is flagged.
+func CheckPaymentAgainstIntent(payment database.Payment, intent paymentgateway.Intent) ReconciliationFinding {
+	if !strings.EqualFold(payment.Currency, intent.Currency) {
+		return ReconciliationFinding{
+			Action:      ReconciliationActionFlag,
+			IssueType:   IssueCurrencyMismatch,
+			Description: fmt.Sprintf("payment is in %s but the payment gateway charged in %s", payment.Currency, strings.ToUpper(intent.Currency)),
+		}
+	}
+
+	amountCharged := money.New(intent.Amount, payment.Currency)
+	amountRefunded := money.New(intent.AmountRefunded, payment.Currency)
+
+	if intent.Status == paymentgateway.IntentStatusSucceeded && intent.AmountRefunded > 0 {
+		switch paymentstatus.Status(payment.Status) {
+		case paymentstatus.Refunded:
+			return ReconciliationFinding{Action: ReconciliationActionNone}
+		case paymentstatus.RefundPending:
+			return ReconciliationFinding{
+				Action:      ReconciliationActionMarkRefunded,
+				IssueType:   IssueMissedRefund,
+				Description: fmt.Sprintf("payment gateway refunded %s but the payment is still %s", amountRefunded, payment.Status),
+			}
+		}
+
+		return ReconciliationFinding{
+			Action:      ReconciliationActionFlag,
+			IssueType:   IssueStatusMismatch,
+			Description: fmt.Sprintf("payment gateway refunded %s but the payment is %s", amountRefunded, payment.Status),
+		}
+	}
+
+	if intent.Status == paymentgateway.IntentStatusSucceeded {
+		switch paymentstatus.Status(payment.Status) {
+		case paymentstatus.Succeeded:
+			if payment.Amount != intent.Amount {
+				return ReconciliationFinding{
+					Action:      ReconciliationActionFlag,
+					IssueType:   IssueAmountMismatch,
+					Description: fmt.Sprintf("payment is %s but the payment gateway charged %s", money.New(payment.Amount, payment.Currency), amountCharged),
+				}
+			}
+
+			return ReconciliationFinding{Action: ReconciliationActionNone}
+		case paymentstatus.RefundPending, paymentstatus.RefundFailed:
+			// The refund is still with the bank or was declined, either way the charge stands.
+			return ReconciliationFinding{Action: ReconciliationActionNone}
+		case paymentstatus.Refunded, paymentstatus.Cancelled:
+			return ReconciliationFinding{
+				Action:      ReconciliationActionFlag,
+				IssueType:   IssueStatusMismatch,
+				Description: fmt.Sprintf("payment is %s but the payment gateway charged %s and refunded nothing", payment.Status, amountCharged),
+			}
+		}
+
+		return ReconciliationFinding{
+			Action:      ReconciliationActionMarkPaid,
+			IssueType:   IssueMissedPaymentSuccess,
+			Description: fmt.Sprintf("payment gateway charged %s but the payment is still %s", amountCharged, payment.Status),
+		}
+	}
+
+	switch paymentstatus.Status(payment.Status) {
+	case paymentstatus.Succeeded, paymentstatus.RefundPending, paymentstatus.RefundFailed, paymentstatus.Refunded:
+		return ReconciliationFinding{
+			Action:      ReconciliationActionFlag,
+			IssueType:   IssueStatusMismatch,
+			Description: fmt.Sprintf("payment is %s but the payment intent is %s", payment.Status, intent.Status),
+		}
+	}
+
+	return ReconciliationFinding{Action: ReconciliationActionNone}
+}
+
+func (report *ReconciliationReport) Subject() string {
+	return fmt.Sprintf("Payment reconciliation: %d repaired, %d flagged, %d error/s", len(report.Repaired), len(report.Flagged), len(report.Errors))
+}
+
+// Summary is the plain text report emailed to the team.
+func (report *ReconciliationReport) Summary() string {
+	var summary strings.Builder
+
+	fmt.Fprintf(&summary, "Payment reconciliation of payments in progress or changed since %s\n", report.ChangedSince.UTC().Format("2006-01-02 15:04 MST"))
+	fmt.Fprintf(&summary, "Started: %s, finished: %s\n\n", report.StartedAt.UTC().Format("2006-01-02 15:04:05 MST"), report.FinishedAt.UTC().Format("2006-01-02 15:04:05 MST"))
+	fmt.Fprintf(&summary, "Checked: %d\n", report.Checked)
+	fmt.Fprintf(&summary, "Consistent: %d\n", report.Checked-report.Unchecked-len(report.Repaired)-len(report.Flagged))
+	fmt.Fprintf(&summary, "Repaired: %d\n", len(report.Repaired))
+	fmt.Fprintf(&summary, "Flagged: %d\n", len(report.Flagged))
+	fmt.Fprintf(&summary, "Unchecked: %d\n", report.Unchecked)
+
+	writeIssues := func(title string, issues []ReconciliationIssue) {
+		if len(issues) == 0 {
+			return
+		}
+
+		fmt.Fprintf(&summary, "\n%s:\n", title)
+
+		for _, issue := range issues {
+			fmt.Fprintf(&summary, "- Payment %s (%s), %s: %s\n", issue.PaymentID, issue.PaymentIntentID, issue.IssueType, issue.Description)
+		}
+	}
+
+	writeIssues("Repaired", report.Repaired)
+	writeIssues("Flagged, please check", report.Flagged)
+
+	if len(report.Errors) > 0 {
+		summary.WriteString("\nErrors:\n")
+
+		for _, reportError := range report.Errors {
+			fmt.Fprintf(&summary, "- %s\n", reportError)
+		}
+	}
+
+	return summary.String()
+}
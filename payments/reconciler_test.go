@@ -0,0 +1,145 @@
+package payments_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/paymentgateway"
+	"github.com/elorenzorodz/event-mrs/payments"
+)
+
+func TestCheckPaymentAgainstIntent(tTesting *testing.T) {
+	tests := []struct {
+		name              string
+		paymentStatus     string
+		paymentAmount     int64
+		intentStatus      paymentgateway.IntentStatus
+		intentCurrency    string
+		amountRefunded    int64
+		expectedAction    payments.ReconciliationAction
+		expectedIssueType string
+	}{
+		{
+			name:           "Consistent",
+			paymentStatus:  "succeeded",
+			intentStatus:   paymentgateway.IntentStatusSucceeded,
+			expectedAction: payments.ReconciliationActionNone,
+		},
+		{
+			name:              "MissedPaymentSuccess",
+			paymentStatus:     "requires_action",
+			intentStatus:      paymentgateway.IntentStatusSucceeded,
+			expectedAction:    payments.ReconciliationActionMarkPaid,
+			expectedIssueType: payments.IssueMissedPaymentSuccess,
+		},
+		{
+			name:              "MissedRefund",
+			paymentStatus:     "refund_pending",
+			intentStatus:      paymentgateway.IntentStatusSucceeded,
+			amountRefunded:    2500,
+			expectedAction:    payments.ReconciliationActionMarkRefunded,
+			expectedIssueType: payments.IssueMissedRefund,
+		},
+		{
+			name:           "RefundStillPending",
+			paymentStatus:  "refund_pending",
+			intentStatus:   paymentgateway.IntentStatusSucceeded,
+			expectedAction: payments.ReconciliationActionNone,
+		},
+		{
+			name:              "RefundedWithoutRefund",
+			paymentStatus:     "refunded",
+			intentStatus:      paymentgateway.IntentStatusSucceeded,
+			expectedAction:    payments.ReconciliationActionFlag,
+			expectedIssueType: payments.IssueStatusMismatch,
+		},
+		{
+			name:              "AmountMismatch",
+			paymentStatus:     "succeeded",
+			paymentAmount:     2000,
+			intentStatus:      paymentgateway.IntentStatusSucceeded,
+			expectedAction:    payments.ReconciliationActionFlag,
+			expectedIssueType: payments.IssueAmountMismatch,
+		},
+		{
+			name:              "CurrencyMismatch",
+			paymentStatus:     "succeeded",
+			intentStatus:      paymentgateway.IntentStatusSucceeded,
+			intentCurrency:    "EUR",
+			expectedAction:    payments.ReconciliationActionFlag,
+			expectedIssueType: payments.IssueCurrencyMismatch,
+		},
+		{
+			name:              "SucceededButCanceled",
+			paymentStatus:     "succeeded",
+			intentStatus:      paymentgateway.IntentStatusCanceled,
+			expectedAction:    payments.ReconciliationActionFlag,
+			expectedIssueType: payments.IssueStatusMismatch,
+		},
+		{
+			name:           "StillBeingPaid",
+			paymentStatus:  "payment_failed",
+			intentStatus:   paymentgateway.IntentStatusRequiresPaymentMethod,
+			expectedAction: payments.ReconciliationActionNone,
+		},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.name, func(t *testing.T) {
+			payment := database.Payment{Status: tc.paymentStatus, Amount: 2500, Currency: "USD"}
+
+			if tc.paymentAmount != 0 {
+				payment.Amount = tc.paymentAmount
+			}
+
+			intent := paymentgateway.Intent{ID: "pi_test", Status: tc.intentStatus, Amount: 2500, AmountRefunded: tc.amountRefunded, Currency: "USD"}
+
+			if tc.intentCurrency != "" {
+				intent.Currency = tc.intentCurrency
+			}
+
+			finding := payments.CheckPaymentAgainstIntent(payment, intent)
+
+			if finding.Action != tc.expectedAction || finding.IssueType != tc.expectedIssueType {
+				t.Errorf("CheckPaymentAgainstIntent: expected %s %q, got %s %q (%s)", tc.expectedAction, tc.expectedIssueType, finding.Action, finding.IssueType, finding.Description)
+			}
+		})
+	}
+}
+
+func TestNextReconciliationRun(tTesting *testing.T) {
+	runAt := 3 * time.Hour
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		expected time.Time
+	}{
+		{
+			name:     "LaterToday",
+			now:      time.Date(2025, 3, 10, 1, 30, 0, 0, time.UTC),
+			expected: time.Date(2025, 3, 10, 3, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "AlreadyRanToday",
+			now:      time.Date(2025, 3, 10, 3, 0, 0, 0, time.UTC),
+			expected: time.Date(2025, 3, 11, 3, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "OtherTimeZone",
+			now:      time.Date(2025, 3, 10, 21, 0, 0, 0, time.FixedZone("UTC-5", -5*60*60)),
+			expected: time.Date(2025, 3, 11, 3, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.name, func(t *testing.T) {
+			nextRun := payments.NextReconciliationRun(tc.now, runAt)
+
+			if !nextRun.Equal(tc.expected) {
+				t.Errorf("NextReconciliationRun: expected %s, got %s", tc.expected, nextRun)
+			}
+		})
+	}
+}
@@ -7,60 +7,54 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/elorenzorodz/event-mrs/internal/convert"
 	"github.com/elorenzorodz/event-mrs/internal/database"
 	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/elorenzorodz/event-mrs/internal/money"
+	"github.com/elorenzorodz/event-mrs/internal/paymentgateway"
+	"github.com/elorenzorodz/event-mrs/internal/paymentstatus"
 	"github.com/elorenzorodz/event-mrs/internal/sqlutil"
+	"github.com/elorenzorodz/event-mrs/internal/tickets"
 	"github.com/google/uuid"
-	"github.com/stripe/stripe-go/v83"
-	"github.com/stripe/stripe-go/v83/paymentintent"
-	"github.com/stripe/stripe-go/v83/refund"
-	"github.com/stripe/stripe-go/v83/webhook"
 )
 
 var (
-	ErrNotFound = errors.New("payment not found")
+	ErrNotFound                     = errors.New("payment not found")
+	ErrWebhookEventNotFound         = errors.New("webhook event not found")
+	ErrWebhookEventAlreadyProcessed = errors.New("webhook event was already processed")
+	ErrWebhookEventInProgress       = errors.New("webhook event is being processed")
 )
 
-func NewService(dbQueries *database.Queries, stripeClient StripeClient, mMailer *mailer.Mailer, stripeSigningSecret string, stripeRefundSigningSecret string) PaymentService {
-	return &Service{
-		DB:                        dbQueries,
-		Stripe:                    stripeClient,
-		Mailer:                    mMailer,
-		StripeSigningSecret:       stripeSigningSecret,
-		StripeRefundSigningSecret: stripeRefundSigningSecret,
-	}
-}
-
-func (stripeAPIClient *StripeAPIClient) UpdatePaymentIntent(paymentIntentID string, params *stripe.PaymentIntentParams) (*stripe.PaymentIntent, error) {
-	return paymentintent.Update(paymentIntentID, params)
-}
+// webhookEventProcessingTimeout is how long a claimed webhook event is left to the delivery or replay processing it,
+// after that the claim was left by a crashed process and the event can be claimed again.
+const webhookEventProcessingTimeout = 5 * time.Minute
 
-func (stripeAPIClient *StripeAPIClient) CreateRefund(params *stripe.RefundParams) (*stripe.Refund, error) {
-	return refund.New(params)
-}
-
-func (stripeAPIClient *StripeAPIClient) ConstructEvent(payload []byte, signature string, secret string) (stripe.Event, error) {
-	event, err := webhook.ConstructEvent(payload, signature, secret)
+var webhookEventStatuses = []string{"processed", "failed", "pending"}
 
-	if err != nil {
-		return stripe.Event{}, fmt.Errorf("webhook signature verification failed: %w", err)
+func NewService(dbQueries *database.Queries, paymentGateway paymentgateway.Gateway, mMailer mailer.Mailer, ticketIssuer *tickets.Issuer, waitlistOfferer WaitlistOfferer, signingSecret string, refundSigningSecret string) PaymentService {
+	return &Service{
+		DB:                  dbQueries,
+		WebhookEvents:       dbQueries,
+		PaymentGateway:      paymentGateway,
+		Mailer:              mMailer,
+		Tickets:             ticketIssuer,
+		Waitlist:            waitlistOfferer,
+		SigningSecret:       signingSecret,
+		RefundSigningSecret: refundSigningSecret,
 	}
-
-	return event, nil
 }
 
 func DatabasePaymentToPaymentJSON(databasePayment database.Payment) *Payment {
-	amount, _ := convert.StringToFloat32(databasePayment.Amount)
-
-	return &Payment{
+	payment := &Payment{
 		ID:              databasePayment.ID,
 		PaymentIntentID: databasePayment.PaymentIntentID.String,
-		Amount:          float64(amount),
+		Amount:          money.New(databasePayment.Amount, databasePayment.Currency),
+		DiscountAmount:  money.New(databasePayment.DiscountAmount, databasePayment.Currency),
 		Currency:        databasePayment.Currency,
 		Status:          databasePayment.Status,
 		ExpiresAt:       databasePayment.ExpiresAt,
@@ -68,6 +62,12 @@ func DatabasePaymentToPaymentJSON(databasePayment database.Payment) *Payment {
 		UpdatedAt:       sqlutil.NullTimeToString(databasePayment.UpdatedAt),
 		UserID:          databasePayment.UserID,
 	}
+
+	if databasePayment.PromoCodeID.Valid {
+		payment.PromoCodeID = &databasePayment.PromoCodeID.UUID
+	}
+
+	return payment
 }
 
 func DatabasePaymentsToPaymentsJSON(databasePayments []database.Payment) []*Payment {
@@ -107,6 +107,38 @@ func (service *Service) GetUserPaymentById(ctx context.Context, paymentID, userI
 	return DatabasePaymentToPaymentJSON(dbPayment), nil
 }
 
+// GetPaymentById returns a payment regardless of who made it, for admins.
+func (service *Service) GetPaymentById(ctx context.Context, paymentID uuid.UUID) (*Payment, error) {
+	dbPayment, err := service.DB.GetPaymentByIdOnly(ctx, paymentID)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+
+		return nil, fmt.Errorf("failed to retrieve payment: %w", err)
+	}
+
+	statusHistory, getStatusHistoryError := service.DB.GetPaymentStatusHistory(ctx, paymentID)
+
+	if getStatusHistoryError != nil {
+		return nil, fmt.Errorf("failed to retrieve payment status history: %w", getStatusHistoryError)
+	}
+
+	payment := DatabasePaymentToPaymentJSON(dbPayment)
+	payment.StatusHistory = make([]PaymentStatusChange, len(statusHistory))
+
+	for i, statusChange := range statusHistory {
+		payment.StatusHistory[i] = PaymentStatusChange{
+			FromStatus: statusChange.FromStatus.String,
+			ToStatus:   statusChange.ToStatus,
+			ChangedAt:  statusChange.ChangedAt,
+		}
+	}
+
+	return payment, nil
+}
+
 func (service *Service) UpdatePayment(ctx context.Context, paymentID, userID uuid.UUID, paymentMethodID string) (*PaymentResponse, error) {
 	// Get payment record.
 	payment, err := service.DB.GetPaymentById(ctx, database.GetPaymentByIdParams{
@@ -127,21 +159,19 @@ func (service *Service) UpdatePayment(ctx context.Context, paymentID, userID uui
 		return nil, errors.New("payment record has no linked payment intent")
 	}
 
-	params := &stripe.PaymentIntentParams{
-		PaymentMethod: stripe.String(paymentMethodID),
-		Confirm:       stripe.Bool(true),
+	if !paymentstatus.Status(payment.Status).AwaitingPayment() {
+		return nil, fmt.Errorf("%w: %s payment can't be paid again", paymentstatus.ErrTransition, payment.Status)
 	}
 
-	// Call Stripe API using injected client.
-	paymentIntentResult, paymentIntentError := service.Stripe.UpdatePaymentIntent(payment.PaymentIntentID.String, params)
+	paymentIntentResult, paymentIntentError := service.PaymentGateway.ConfirmIntent(ctx, payment.PaymentIntentID.String, paymentMethodID)
 
 	paymentResponse := PaymentResponse{ID: paymentID, ExpiresAt: payment.ExpiresAt}
 
-	// Handle Stripe result.
+	// Handle payment gateway result.
 	if paymentIntentError != nil {
-		if stripeErr, ok := paymentIntentError.(*stripe.Error); ok {
-			paymentResponse.Status = *stripe.String(stripeErr.Code)
-			paymentResponse.Message = *stripe.String(stripeErr.Msg)
+		if gatewayError, ok := paymentgateway.AsError(paymentIntentError); ok {
+			paymentResponse.Status = gatewayError.Code
+			paymentResponse.Message = gatewayError.Message
 		} else {
 			paymentResponse.Status = "error"
 			paymentResponse.Message = "an unknown error occurred with payment update"
@@ -150,21 +180,18 @@ func (service *Service) UpdatePayment(ctx context.Context, paymentID, userID uui
 		paymentResponse.Status = string(paymentIntentResult.Status)
 		paymentResponse.Message = "payment updated"
 
-		if paymentIntentResult.ClientSecret != "" {
-			paymentResponse.ClientSecret = paymentIntentResult.ClientSecret
-		}
-		if paymentIntentResult.NextAction != nil {
-			paymentResponse.NextAction = string(paymentIntentResult.NextAction.Type)
-		}
+		paymentResponse.ClientSecret = paymentIntentResult.ClientSecret
+		paymentResponse.NextAction = paymentIntentResult.NextAction
 	}
 
-	// Update DB record.
-	_, updateDbError := service.DB.UpdatePayment(ctx, database.UpdatePaymentParams{
+	// Update DB record, the response keeps the gateway's status or error code for the client.
+	_, updateDbError := paymentstatus.Update(ctx, service.DB, database.UpdatePaymentParams{
 		Amount:          payment.Amount,
-		Status:          paymentResponse.Status,
+		Status:          string(paymentstatus.FromIntentStatus(paymentResponse.Status)),
 		PaymentIntentID: payment.PaymentIntentID,
 		ID:              paymentID,
 		UserID:          userID,
+		CurrentStatus:   payment.Status,
 	})
 
 	if updateDbError != nil {
@@ -174,7 +201,7 @@ func (service *Service) UpdatePayment(ctx context.Context, paymentID, userID uui
 	return &paymentResponse, nil
 }
 
-func (service *Service) RefundPayment(ctx context.Context, paymentID, userID uuid.UUID) (*PaymentRefundResponse, error) {
+func (service *Service) RefundPayment(ctx context.Context, paymentID, userID uuid.UUID, idempotencyKey string) (*PaymentRefundResponse, error) {
 	// 1. Retrieve payment and reservation details.
 	paymentDetails, err := service.DB.GetPaymentAndReservationDetails(ctx, database.GetPaymentAndReservationDetailsParams{
 		PaymentID: paymentID,
@@ -188,6 +215,10 @@ func (service *Service) RefundPayment(ctx context.Context, paymentID, userID uui
 		return nil, fmt.Errorf("failed to retrieve payment details for refund: %w", err)
 	}
 
+	if !paymentstatus.Status(paymentDetails[0].Status).CanTransitionTo(paymentstatus.RefundPending) {
+		return nil, fmt.Errorf("%w: %s payment can't be refunded", paymentstatus.ErrTransition, paymentDetails[0].Status)
+	}
+
 	type ReservationForRefund struct {
 		EventTitle        string
 		TicketDescription string
@@ -195,7 +226,7 @@ func (service *Service) RefundPayment(ctx context.Context, paymentID, userID uui
 		PaymentID         uuid.UUID
 		ReservationID     uuid.UUID
 		EventDetailID     uuid.UUID
-		Amount            int64
+		Amount            money.Money
 	}
 
 	var (
@@ -237,17 +268,8 @@ func (service *Service) RefundPayment(ctx context.Context, paymentID, userID uui
 
 			// Allow refund for show dates with more than 48 hours difference.
 			if dateTimeDifference.Hours() > 48 {
-				// Convert price string (e.g., "15.00") to amount in cents (1500).
-				amount, amountParseError := convert.PriceStringToCents(paymentReservationDetail.Price.String)
-
-				if amountParseError != nil {
-					mutex.Lock()
-					allErrors = append(allErrors, fmt.Sprintf("error processing price for ticket %s, for event: %s: %s", 
-						paymentReservationDetail.TicketDescription.String, paymentReservationDetail.Title.String, amountParseError))
-					mutex.Unlock()
-
-					return
-				}
+				// The ticket's share of a promo code discount wasn't paid, so it isn't refunded either.
+				amount := money.New(paymentReservationDetail.Price.Int64-paymentReservationDetail.DiscountAmount.Int64, paymentReservationDetail.Currency)
 
 				reservation := ReservationForRefund{
 					EventTitle:        paymentReservationDetail.Title.String,
@@ -277,11 +299,12 @@ func (service *Service) RefundPayment(ctx context.Context, paymentID, userID uui
 	}
 
 	var (
-		paymentRefundResponse PaymentRefundResponse
-		refundMutex           sync.Mutex
-		restoreWaitGroup      sync.WaitGroup
-		totalRefundAmount     int64
-		allRefundErrors       []string
+		paymentRefundResponse  PaymentRefundResponse
+		refundMutex            sync.Mutex
+		restoreWaitGroup       sync.WaitGroup
+		totalRefundAmount      int64
+		allRefundErrors        []string
+		restoredEventDetailIDs []uuid.UUID
 	)
     
 	for _, refundReservation := range reservationsToBeRefunded {
@@ -290,7 +313,7 @@ func (service *Service) RefundPayment(ctx context.Context, paymentID, userID uui
 		restoreWaitGroup.Go(func() { 
 			refundPaymentAndRestoreTicketsParams := database.RefundPaymentAndRestoreTicketsParams{
 				EventDetailID: reservationToBeRefunded.EventDetailID,
-				Amount:        fmt.Sprintf("%.2f", float64(reservationToBeRefunded.Amount)/100.0),
+				Amount:        reservationToBeRefunded.Amount.Amount,
 				PaymentID:     reservationToBeRefunded.PaymentID,
 				UserID:        userID,
 				ReservationID: reservationToBeRefunded.ReservationID,
@@ -309,7 +332,7 @@ func (service *Service) RefundPayment(ctx context.Context, paymentID, userID uui
 
 			paymentRefunded := PaymentRefunded {
 				PaymentID:         reservationToBeRefunded.PaymentID,
-				Amount:            fmt.Sprintf("%.2f", float64(reservationToBeRefunded.Amount)/100.0),
+				Amount:            reservationToBeRefunded.Amount,
 				Title:             reservationToBeRefunded.EventTitle,
 				TicketDescription: reservationToBeRefunded.TicketDescription,
 				ShowDate:          reservationToBeRefunded.ShowDate,
@@ -317,13 +340,16 @@ func (service *Service) RefundPayment(ctx context.Context, paymentID, userID uui
 
 			refundMutex.Lock()
 			paymentRefundResponse.PaymentRefunds = append(paymentRefundResponse.PaymentRefunds, paymentRefunded)
-			totalRefundAmount += reservationToBeRefunded.Amount
+			restoredEventDetailIDs = append(restoredEventDetailIDs, reservationToBeRefunded.EventDetailID)
+			totalRefundAmount += reservationToBeRefunded.Amount.Amount
 			refundMutex.Unlock()
 		})
 	}
 
 	restoreWaitGroup.Wait()
 
+	service.Waitlist.OfferReleasedTickets(ctx, restoredEventDetailIDs)
+
 	if len(allRefundErrors) > 0 {
 		log.Printf("CRITICAL: Partial refund processing error. Check DB for inconsistencies. Errors:\n%s", strings.Join(allRefundErrors, "\n"))
 	}
@@ -332,36 +358,37 @@ func (service *Service) RefundPayment(ctx context.Context, paymentID, userID uui
 		return nil, errors.New("no refund amount calculated after processing reservations")
 	}
 
-	refundParams := &stripe.RefundParams {
-		Amount: stripe.Int64(totalRefundAmount),
-		PaymentIntent: stripe.String(paymentIntentID),
-	}
-
-	refundResult, stripeRefundError := service.Stripe.CreateRefund(refundParams)
+	// The idempotency key lets the payment gateway return the original refund when a client retries the same request.
+	refundResult, gatewayRefundError := service.PaymentGateway.Refund(ctx, paymentgateway.RefundParams{
+		PaymentIntentID: paymentIntentID,
+		Amount:          totalRefundAmount,
+		IdempotencyKey:  idempotencyKey,
+	})
 	
-	finalStatus := "refund pending" 
+	finalStatus := paymentstatus.RefundPending
 	finalMsg := "Refund initiated. Status is pending, confirmation will be sent via webhook."
 	var returnError error = nil
 	
-	if stripeRefundError != nil {
-		finalStatus = "refund_failed" 
-		finalMsg = fmt.Sprintf("Refund initiation failed: %v", stripeRefundError)
-		returnError = fmt.Errorf("failed to initiate Stripe refund: %w", stripeRefundError)
-	} else if refundResult.Status == stripe.RefundStatusFailed {
-        finalStatus = "refund_failed"
-		finalMsg = fmt.Sprintf("Refund failed: %s", string(refundResult.FailureReason))
+	if gatewayRefundError != nil {
+		finalStatus = paymentstatus.RefundFailed
+		finalMsg = fmt.Sprintf("Refund initiation failed: %v", gatewayRefundError)
+		returnError = fmt.Errorf("failed to initiate refund: %w", gatewayRefundError)
+	} else if refundResult.Status == paymentgateway.RefundStatusFailed {
+        finalStatus = paymentstatus.RefundFailed
+		finalMsg = fmt.Sprintf("Refund failed: %s", refundResult.FailureReason)
 		returnError = errors.New("refund failed synchronously")
-    } else if refundResult.Status == stripe.RefundStatusSucceeded {
-        finalStatus = string(stripe.RefundStatusSucceeded)
+    } else if refundResult.Status == paymentgateway.RefundStatusSucceeded {
+        finalStatus = paymentstatus.Refunded
 		finalMsg = "Refund succeeded immediately."
     }
 	
-	_, updateDbError := service.DB.UpdatePayment(ctx, database.UpdatePaymentParams{
+	_, updateDbError := paymentstatus.Update(ctx, service.DB, database.UpdatePaymentParams{
 		Amount:          originalPaymentDetails.Amount,
-		Status:          finalStatus, // Set to "refund pending", "refund_succeeded", or "refund_failed"
+		Status:          string(finalStatus),
 		PaymentIntentID: originalPaymentDetails.PaymentIntentID,
 		ID:              originalPaymentDetails.PaymentID,
 		UserID:          originalPaymentDetails.UserID,
+		CurrentStatus:   originalPaymentDetails.Status,
 	})
 
 	if updateDbError != nil {
@@ -392,80 +419,142 @@ func (service *Service) RefundPayment(ctx context.Context, paymentID, userID uui
 }
 
 func (service *Service) HandleWebhook(ctx context.Context, body []byte, signature string, webhookType string) error {
-	signingSecret := service.StripeSigningSecret
+	signingSecret := service.SigningSecret
 
 	if strings.Contains(strings.ToLower(webhookType), "refund") {
-		signingSecret = service.StripeRefundSigningSecret
+		signingSecret = service.RefundSigningSecret
 	}
 
-	event, err := service.Stripe.ConstructEvent(body, signature, signingSecret)
+	event, err := service.PaymentGateway.VerifyWebhook(body, signature, signingSecret)
 
 	if err != nil {
-		return fmt.Errorf("error verifying Stripe signature: %w", err)
+		return fmt.Errorf("error verifying webhook signature: %w", err)
 	}
 
-	switch event.Type {
-	case "payment_intent.succeeded":
-		var paymentIntent stripe.PaymentIntent
+	// The payment gateway retries deliveries, so every event is stored first and only processed by the delivery that
+	// claims it.
+	_, recordEventError := service.WebhookEvents.RecordStripeWebhookEvent(ctx, database.RecordStripeWebhookEventParams{
+		ID:          event.ID,
+		EventType:   event.Type,
+		WebhookType: webhookType,
+		Payload:     string(body),
+	})
 
-		if err := json.Unmarshal(event.Data.Raw, &paymentIntent); err != nil {
-			return fmt.Errorf("error unmarshaling payment_intent.succeeded: %w", err)
-		}
+	if recordEventError != nil {
+		return fmt.Errorf("failed to record webhook event %s: %w", event.ID, recordEventError)
+	}
 
-		service.handlePaymentIntentSuccess(ctx, paymentIntent)
+	claimed, claimEventError := service.claimWebhookEvent(ctx, event.ID)
 
-	case "payment_intent.payment_failed":
-		var paymentIntent stripe.PaymentIntent
+	if claimEventError != nil {
+		return claimEventError
+	}
 
-		if err := json.Unmarshal(event.Data.Raw, &paymentIntent); err != nil {
-			return fmt.Errorf("error unmarshaling payment_intent.payment_failed: %w", err)
-		}
+	if !claimed {
+		log.Printf("Webhook: Event %s (%s) was already processed or is being processed, skipping.", event.ID, event.Type)
+
+		return nil
+	}
 
-		service.handlePaymentIntentFailure(ctx, paymentIntent)
+	return service.processWebhookEvent(ctx, event)
+}
 
-	case "payment_intent.requires_action":
-		var paymentIntent stripe.PaymentIntent
+// claimWebhookEvent reports whether the event was claimed for processing. It isn't when it was processed already or
+// another delivery or replay is processing it, so concurrent deliveries of an event don't both process it.
+func (service *Service) claimWebhookEvent(ctx context.Context, eventID string) (bool, error) {
+	_, claimEventError := service.WebhookEvents.ClaimStripeWebhookEvent(ctx, database.ClaimStripeWebhookEventParams{
+		ID:          eventID,
+		StaleBefore: time.Now().Add(-webhookEventProcessingTimeout),
+	})
 
-		if err := json.Unmarshal(event.Data.Raw, &paymentIntent); err != nil {
-			return fmt.Errorf("error unmarshaling payment_intent.requires_action: %w", err)
-		}
+	if errors.Is(claimEventError, sql.ErrNoRows) {
+		return false, nil
+	}
+
+	if claimEventError != nil {
+		return false, fmt.Errorf("failed to claim webhook event %s: %w", eventID, claimEventError)
+	}
+
+	return true, nil
+}
+
+func (service *Service) processWebhookEvent(ctx context.Context, event paymentgateway.Event) error {
+	dispatchEventError := service.dispatchWebhookEvent(ctx, event)
 
-		service.handlePaymentIntentRequiresAction(ctx, paymentIntent)
+	if dispatchEventError != nil {
+		markFailedError := service.WebhookEvents.MarkStripeWebhookEventFailed(ctx, database.MarkStripeWebhookEventFailedParams{
+			ProcessingError: sqlutil.StringToNullString(dispatchEventError.Error()),
+			ID:              event.ID,
+		})
 
-	case "charge.refunded":
-		var charge stripe.Charge
-		if err := json.Unmarshal(event.Data.Raw, &charge); err != nil {
-			return fmt.Errorf("error unmarshaling charge.refunded: %w", err)
+		if markFailedError != nil {
+			log.Printf("Webhook CRITICAL: Failed to record processing error for event %s: %v", event.ID, markFailedError)
 		}
 
-		service.handleChargeRefunded(ctx, charge)
+		return fmt.Errorf("error processing webhook event %s: %w", event.ID, dispatchEventError)
+	}
+
+	if markProcessedError := service.WebhookEvents.MarkStripeWebhookEventProcessed(ctx, event.ID); markProcessedError != nil {
+		log.Printf("Webhook CRITICAL: Failed to mark event %s as processed: %v", event.ID, markProcessedError)
+	}
+
+	return nil
+}
 
-	case "refund.failed":
-		var refundEvent stripe.Refund
+func (service *Service) dispatchWebhookEvent(ctx context.Context, event paymentgateway.Event) error {
+	switch event.Type {
+	case paymentgateway.EventIntentSucceeded, paymentgateway.EventIntentPaymentFailed, paymentgateway.EventIntentRequiresAction:
+		if event.Intent == nil {
+			return fmt.Errorf("%s event received without payment intent", event.Type)
+		}
 
-		if err := json.Unmarshal(event.Data.Raw, &refundEvent); err != nil {
-			return fmt.Errorf("error unmarshaling refund.failed: %w", err)
+	case paymentgateway.EventChargeRefunded, paymentgateway.EventRefundFailed:
+		if event.Refund == nil {
+			return fmt.Errorf("%s event received without refund", event.Type)
 		}
+	}
 
-		service.handleRefundFailed(ctx, refundEvent)
+	var handleEventError error
+
+	switch event.Type {
+	case paymentgateway.EventIntentSucceeded:
+		handleEventError = service.handlePaymentIntentSuccess(ctx, *event.Intent)
+
+	case paymentgateway.EventIntentPaymentFailed:
+		handleEventError = service.handlePaymentIntentFailure(ctx, *event.Intent)
+
+	case paymentgateway.EventIntentRequiresAction:
+		handleEventError = service.handlePaymentIntentRequiresAction(ctx, *event.Intent)
+
+	case paymentgateway.EventChargeRefunded:
+		handleEventError = service.handleChargeRefunded(ctx, *event.Refund)
+
+	case paymentgateway.EventRefundFailed:
+		handleEventError = service.handleRefundFailed(ctx, *event.Refund)
 
 	default:
 		log.Printf("Webhook: Unhandled event type: %s", event.Type)
 	}
 
-	return nil
+	// Events can arrive late or out of order, one that would move a payment back, e.g. payment_failed after the
+	// payment was refunded, is stale and processed without changing anything.
+	if errors.Is(handleEventError, paymentstatus.ErrTransition) {
+		log.Printf("Webhook: Ignoring %s event %s: %v", event.Type, event.ID, handleEventError)
+
+		return nil
+	}
+
+	return handleEventError
 }
 
-func (service *Service) handlePaymentIntentSuccess(ctx context.Context, intent stripe.PaymentIntent) error {
+func (service *Service) handlePaymentIntentSuccess(ctx context.Context, intent paymentgateway.Intent) error {
 	payment, user, err := service.getPaymentAndUserFromIntent(ctx, intent)
 
 	if err != nil {
 		return err 
 	}
 
-	amountStr := fmt.Sprintf("%.2f", float64(intent.Amount)/100.0)
-
-	updatedPayment, err := service.updatePaymentStatus(ctx, payment, string(stripe.PaymentIntentStatusSucceeded), intent.ID, amountStr)
+	updatedPayment, err := service.updatePaymentStatus(ctx, payment, paymentstatus.Succeeded, intent.ID, intent.Amount)
 
 	if err != nil {
 		return err
@@ -479,15 +568,23 @@ func (service *Service) handlePaymentIntentSuccess(ctx context.Context, intent s
 
 	if err == nil && len(userReservations) > 0 {
 		eventDetailIds := make([]uuid.UUID, len(userReservations))
+		reservationIds := make([]uuid.UUID, len(userReservations))
 
 		for i, ur := range userReservations {
 			eventDetailIds[i] = ur.EventDetailID
+			reservationIds[i] = ur.ID
 		}
 
 		eventDetails, err := service.DB.GetEventDetailsWithTitleByIds(ctx, eventDetailIds)
 		if err == nil {
+			ticketAttachments, ticketAttachmentsError := service.Tickets.Attachments(reservationIds)
+
+			if ticketAttachmentsError != nil {
+				log.Printf("Error rendering ticket QR codes for payment %s: %v", payment.ID, ticketAttachmentsError)
+			}
+
 			fullName := fmt.Sprintf("%s %s", user.Firstname, user.Lastname)
-			sendEmailError := service.Mailer.SendPaymentConfirmationAndTicketReservation(fullName, user.Email, eventDetails) 
+			sendEmailError := service.Mailer.SendPaymentConfirmationAndTicketReservation(fullName, user.Email, eventDetails, money.New(intent.Amount, updatedPayment.Currency), ticketAttachments)
 
 			if sendEmailError != nil {
 				log.Printf("Error sending confirmation email for payment %s: %v", payment.ID, sendEmailError)
@@ -500,31 +597,30 @@ func (service *Service) handlePaymentIntentSuccess(ctx context.Context, intent s
 	service.createPaymentLog(
 		ctx,
 		updatedPayment,
-		stripe.PaymentIntentStatusSucceeded,
+		string(paymentgateway.IntentStatusSucceeded),
 		"Payment succeeded.",
 		intent.ID,
-		intent.PaymentMethod.ID,
+		intent.PaymentMethodID,
 		intent.Amount,
 	)
 
 	return nil
 }
 
-func (service *Service) handlePaymentIntentFailure(ctx context.Context, intent stripe.PaymentIntent) error {
+func (service *Service) handlePaymentIntentFailure(ctx context.Context, intent paymentgateway.Intent) error {
 	payment, user, err := service.getPaymentAndUserFromIntent(ctx, intent)
 
 	if err != nil {
 		return err
 	}
 
-	amountStr := fmt.Sprintf("%.2f", float64(intent.Amount)/100.0)
 	errorMessage := "Payment failed."
 
-	if intent.LastPaymentError != nil {
-		errorMessage = intent.LastPaymentError.Msg
+	if intent.LastError != "" {
+		errorMessage = intent.LastError
 	}
 
-	updatedPayment, err := service.updatePaymentStatus(ctx, payment, "payment_failed", intent.ID, amountStr)
+	updatedPayment, err := service.updatePaymentStatus(ctx, payment, paymentstatus.Failed, intent.ID, intent.Amount)
 	if err != nil {
 		return err
 	}
@@ -545,7 +641,7 @@ func (service *Service) handlePaymentIntentFailure(ctx context.Context, intent s
 		eventDetails, err := service.DB.GetEventDetailsWithTitleByIds(ctx, eventDetailIds)
 		if err == nil {
 			fullName := fmt.Sprintf("%s %s", user.Firstname, user.Lastname)
-			sendEmailError := service.Mailer.SendPaymentFailedNotification(fullName, user.Email, errorMessage, eventDetails) 
+			sendEmailError := service.Mailer.SendPaymentFailedNotification(fullName, user.Email, errorMessage, eventDetails, money.New(intent.Amount, updatedPayment.Currency))
 
 			if sendEmailError != nil {
 				log.Printf("Error sending payment failed email for payment %s: %v", payment.ID, sendEmailError)
@@ -558,29 +654,27 @@ func (service *Service) handlePaymentIntentFailure(ctx context.Context, intent s
 	service.createPaymentLog(
 		ctx,
 		updatedPayment,
-		"payment_failed",
+		string(paymentstatus.Failed),
 		errorMessage,
 		intent.ID,
-		intent.PaymentMethod.ID,
+		intent.PaymentMethodID,
 		intent.Amount,
 	)
 
 	return nil
 }
 
-func (service *Service) handlePaymentIntentRequiresAction(ctx context.Context, intent stripe.PaymentIntent) error {
+func (service *Service) handlePaymentIntentRequiresAction(ctx context.Context, intent paymentgateway.Intent) error {
 	payment, _, err := service.getPaymentAndUserFromIntent(ctx, intent)
 
 	if err != nil {
 		return err
 	}
 
-	amountStr := fmt.Sprintf("%.2f", float64(intent.Amount)/100.0)
-
 	// Use payment's expiration time from DB for the message.
 	message := fmt.Sprintf("Payment requires action. Please complete the action before %s", payment.ExpiresAt.Format("2006-01-02 15:04:05"))
 
-	updatedPayment, err := service.updatePaymentStatus(ctx, payment, string(stripe.PaymentIntentStatusRequiresAction), intent.ID, amountStr)
+	updatedPayment, err := service.updatePaymentStatus(ctx, payment, paymentstatus.RequiresAction, intent.ID, intent.Amount)
 
 	if err != nil {
 		return err
@@ -589,23 +683,23 @@ func (service *Service) handlePaymentIntentRequiresAction(ctx context.Context, i
 	service.createPaymentLog(
 		ctx,
 		updatedPayment,
-		stripe.PaymentIntentStatusRequiresAction,
+		string(paymentgateway.IntentStatusRequiresAction),
 		message,
 		intent.ID,
-		intent.PaymentMethod.ID,
+		intent.PaymentMethodID,
 		intent.Amount,
 	)
 
 	return nil
 }
 
-func (service *Service) handleChargeRefunded(ctx context.Context, charge stripe.Charge) {
-	paymentIntentID := charge.PaymentIntent.ID
+func (service *Service) handleChargeRefunded(ctx context.Context, refundEvent paymentgateway.Refund) error {
+	paymentIntentID := refundEvent.PaymentIntentID
 
 	if paymentIntentID == "" {
 		log.Printf("Webhook Warning: charge.refunded event received without PaymentIntent ID.")
 
-		return
+		return errors.New("charge.refunded event received without PaymentIntent ID")
 	}
 
 	dbPayment, err := service.DB.GetPaymentByPaymentIntentId(ctx, sqlutil.StringToNullString(paymentIntentID))
@@ -613,49 +707,58 @@ func (service *Service) handleChargeRefunded(ctx context.Context, charge stripe.
 	if err != nil {
 		log.Printf("Webhook Error: Failed to find payment by intent ID %s for refund confirmation: %v", paymentIntentID, err)
 
-		return
+		return fmt.Errorf("failed to find payment by intent ID %s: %w", paymentIntentID, err)
 	}
 
-	if dbPayment.Status != "refund pending" {
+	if dbPayment.Status != string(paymentstatus.RefundPending) {
 		log.Printf("Webhook Warning: charge.refunded received for Payment ID %s but status is %s, skipping final update.", dbPayment.ID, dbPayment.Status)
 
-		return
+		return nil
 	}
 
 	updatePaymentParams := database.UpdatePaymentParams{
-		Amount:          "0.00", 
-		Status:          "refunded",
+		Amount:          0,
+		Status:          string(paymentstatus.Refunded),
 		PaymentIntentID: dbPayment.PaymentIntentID,
 		ID:              dbPayment.ID,
 		UserID:          dbPayment.UserID,
+		CurrentStatus:   dbPayment.Status,
 	}
 
-	_, updatePaymentError := service.DB.UpdatePayment(ctx, updatePaymentParams)
+	_, updatePaymentError := paymentstatus.Update(ctx, service.DB, updatePaymentParams)
+
+	if errors.Is(updatePaymentError, paymentstatus.ErrTransition) {
+		return updatePaymentError
+	}
 
 	if updatePaymentError != nil {
 		log.Printf("Webhook CRITICAL: Failed to update payment status for %s to refunded: %v", dbPayment.ID, updatePaymentError)
+
+		return fmt.Errorf("failed to update payment %s to refunded: %w", dbPayment.ID, updatePaymentError)
 	}
 
 	service.createPaymentLog(
 		ctx,
 		dbPayment,
-		stripe.PaymentIntentStatus(stripe.RefundStatusSucceeded),
-		"Refund confirmed by Stripe webhook. Amount set to 0.00.",
+		string(paymentgateway.RefundStatusSucceeded),
+		"Refund confirmed by webhook. Amount set to 0.",
 		paymentIntentID,
 		"",
-		charge.AmountRefunded,
+		refundEvent.Amount,
 	)
 
 	log.Printf("Webhook: Refund Confirmed for Payment ID: %s (Intent: %s)", dbPayment.ID, paymentIntentID)
+
+	return nil
 }
 
-func (service *Service) handleRefundFailed(ctx context.Context, refundEvent stripe.Refund) {
-	paymentIntentID := refundEvent.PaymentIntent.ID
+func (service *Service) handleRefundFailed(ctx context.Context, refundEvent paymentgateway.Refund) error {
+	paymentIntentID := refundEvent.PaymentIntentID
 
 	if paymentIntentID == "" {
 		log.Printf("Webhook Warning: refund.failed event received without PaymentIntent ID.")
 
-		return
+		return errors.New("refund.failed event received without PaymentIntent ID")
 	}
 
 	dbPayment, err := service.DB.GetPaymentByPaymentIntentId(ctx, sqlutil.StringToNullString(paymentIntentID))
@@ -663,10 +766,10 @@ func (service *Service) handleRefundFailed(ctx context.Context, refundEvent stri
 	if err != nil {
 		log.Printf("Webhook Error: Failed to find payment by intent ID %s for refund failure: %v", paymentIntentID, err)
 
-		return
+		return fmt.Errorf("failed to find payment by intent ID %s: %w", paymentIntentID, err)
 	}
 
-	failureReason := string(refundEvent.FailureReason)
+	failureReason := refundEvent.FailureReason
 	if failureReason == "" {
 		failureReason = "Unknown reason"
 	}
@@ -674,22 +777,29 @@ func (service *Service) handleRefundFailed(ctx context.Context, refundEvent stri
 
 	updatePaymentParams := database.UpdatePaymentParams{
 		Amount:          dbPayment.Amount,
-		Status:          "refund_failed",
+		Status:          string(paymentstatus.RefundFailed),
 		PaymentIntentID: dbPayment.PaymentIntentID,
 		ID:              dbPayment.ID,
 		UserID:          dbPayment.UserID,
+		CurrentStatus:   dbPayment.Status,
 	}
 
-	_, updatePaymentError := service.DB.UpdatePayment(ctx, updatePaymentParams)
+	_, updatePaymentError := paymentstatus.Update(ctx, service.DB, updatePaymentParams)
+
+	if errors.Is(updatePaymentError, paymentstatus.ErrTransition) {
+		return updatePaymentError
+	}
 
 	if updatePaymentError != nil {
 		log.Printf("Webhook CRITICAL: Failed to update payment status for %s to refund_failed: %v", dbPayment.ID, updatePaymentError)
+
+		return fmt.Errorf("failed to update payment %s to refund_failed: %w", dbPayment.ID, updatePaymentError)
 	}
 
 	service.createPaymentLog(
 		ctx,
 		dbPayment,
-		stripe.PaymentIntentStatus(stripe.RefundStatusFailed),
+		string(paymentgateway.RefundStatusFailed),
 		logDescription,
 		paymentIntentID,
 		"",
@@ -703,13 +813,15 @@ func (service *Service) handleRefundFailed(ctx context.Context, refundEvent stri
 	}
 
 	log.Printf("Webhook: Refund Failed for Payment ID: %s (Intent: %s). Reason: %s", dbPayment.ID, paymentIntentID, failureReason)
+
+	return nil
 }
 
-func (service *Service) getPaymentAndUserFromIntent(ctx context.Context, intent stripe.PaymentIntent) (database.Payment, database.User, error) {
-	paymentIDStr := intent.Metadata["payment_id"]
+func (service *Service) getPaymentAndUserFromIntent(ctx context.Context, intent paymentgateway.Intent) (database.Payment, database.User, error) {
+	paymentIDStr := intent.PaymentID
 
 	if paymentIDStr == "" {
-		err := errors.New("stripe payment intent missing 'payment_id' metadata")
+		err := errors.New("payment intent missing 'payment_id' metadata")
 		log.Printf("Webhook Error: %v (Intent: %s)", err, intent.ID)
 
 		return database.Payment{}, database.User{}, err
@@ -742,16 +854,21 @@ func (service *Service) getPaymentAndUserFromIntent(ctx context.Context, intent
 	return payment, user, nil
 }
 
-func (service *Service) updatePaymentStatus(ctx context.Context, currentPayment database.Payment, newStatus, intentID, amount string) (database.Payment, error) {
+func (service *Service) updatePaymentStatus(ctx context.Context, currentPayment database.Payment, newStatus paymentstatus.Status, intentID string, amount int64) (database.Payment, error) {
 	updatePaymentParams := database.UpdatePaymentParams{
 		Amount: amount,
-		Status: newStatus,
+		Status: string(newStatus),
 		PaymentIntentID: sqlutil.StringToNullString(intentID),
 		ID: currentPayment.ID,
 		UserID: currentPayment.UserID,
+		CurrentStatus: currentPayment.Status,
 	}
 
-	updatedPayment, updatePaymentError := service.DB.UpdatePayment(ctx, updatePaymentParams)
+	updatedPayment, updatePaymentError := paymentstatus.Update(ctx, service.DB, updatePaymentParams)
+
+	if errors.Is(updatePaymentError, paymentstatus.ErrTransition) {
+		return database.Payment{}, updatePaymentError
+	}
 
 	if updatePaymentError != nil {
 		log.Printf("Webhook CRITICAL: Failed to update payment status for %s to %s: %v", currentPayment.ID, newStatus, updatePaymentError)
@@ -764,7 +881,7 @@ func (service *Service) updatePaymentStatus(ctx context.Context, currentPayment
 func (service *Service) createPaymentLog(
 	ctx context.Context,
 	dbPayment database.Payment,
-	status stripe.PaymentIntentStatus,
+	status string,
 	description string,
 	intentID string,
 	paymentMethodID string,
@@ -782,16 +899,154 @@ func (service *Service) createPaymentLog(
 
 	params := database.CreatePaymentLogParams{
 		ID:              uuid.New(),
-		Status:          string(status),
+		Status:          status,
 		Description:     sqlutil.StringToNullString(description),
 		PaymentIntentID: intentID,
 		PaymentMethodID: sqlutil.StringToNullString(paymentMethodID),
-		Amount:          fmt.Sprintf("%.2f", float64(amount)/100.0),
+		Amount:          amount,
 		UserEmail:       userEmail,
 		PaymentID:       dbPayment.ID,
+		Currency:        dbPayment.Currency,
 	}
 
 	if _, err := service.DB.CreatePaymentLog(ctx, params); err != nil {
 		log.Printf("Webhook CRITICAL: Failed to create payment log for status %s: %v", status, err)
 	}
+}
+
+func (service *Service) GetWebhookEvents(ctx context.Context, status string, eventType string, limitQuery string, offsetQuery string) ([]WebhookEvent, error) {
+	status = strings.ToLower(strings.TrimSpace(status))
+
+	if status != "" && !slices.Contains(webhookEventStatuses, status) {
+		return nil, fmt.Errorf("invalid status, must be one of: %s", strings.Join(webhookEventStatuses, ", "))
+	}
+
+	limit, offset := int64(50), int64(0)
+
+	if strings.TrimSpace(limitQuery) != "" {
+		parsedLimit, parseLimitError := strconv.ParseInt(limitQuery, 10, 32)
+
+		if parseLimitError != nil || parsedLimit < 1 || parsedLimit > 100 {
+			return nil, errors.New("invalid limit, must be between 1 and 100")
+		}
+
+		limit = parsedLimit
+	}
+
+	if strings.TrimSpace(offsetQuery) != "" {
+		parsedOffset, parseOffsetError := strconv.ParseInt(offsetQuery, 10, 32)
+
+		if parseOffsetError != nil || parsedOffset < 0 {
+			return nil, errors.New("invalid offset, must be zero or greater")
+		}
+
+		offset = parsedOffset
+	}
+
+	storedEvents, getEventsError := service.WebhookEvents.GetStripeWebhookEvents(ctx, database.GetStripeWebhookEventsParams{
+		Status:    status,
+		EventType: strings.TrimSpace(eventType),
+		RowLimit:  int32(limit),
+		RowOffset: int32(offset),
+	})
+
+	if getEventsError != nil {
+		return nil, fmt.Errorf("failed to retrieve webhook events: %w", getEventsError)
+	}
+
+	webhookEvents := make([]WebhookEvent, len(storedEvents))
+
+	for i, storedEvent := range storedEvents {
+		webhookEvents[i] = DatabaseWebhookEventToWebhookEventJSON(storedEvent, false)
+	}
+
+	return webhookEvents, nil
+}
+
+func (service *Service) GetWebhookEventById(ctx context.Context, eventID string) (*WebhookEvent, error) {
+	storedEvent, getEventError := service.WebhookEvents.GetStripeWebhookEventById(ctx, eventID)
+
+	if getEventError != nil {
+		if errors.Is(getEventError, sql.ErrNoRows) {
+			return nil, ErrWebhookEventNotFound
+		}
+
+		return nil, fmt.Errorf("failed to retrieve webhook event: %w", getEventError)
+	}
+
+	webhookEvent := DatabaseWebhookEventToWebhookEventJSON(storedEvent, true)
+
+	return &webhookEvent, nil
+}
+
+// ReplayWebhookEvent runs a stored event through the webhook handlers again. The signature was verified when it was first received.
+// An event that a delivery is processing at the moment can't be replayed.
+func (service *Service) ReplayWebhookEvent(ctx context.Context, eventID string) (*WebhookEvent, error) {
+	storedEvent, getEventError := service.WebhookEvents.GetStripeWebhookEventById(ctx, eventID)
+
+	if getEventError != nil {
+		if errors.Is(getEventError, sql.ErrNoRows) {
+			return nil, ErrWebhookEventNotFound
+		}
+
+		return nil, fmt.Errorf("failed to retrieve webhook event: %w", getEventError)
+	}
+
+	if storedEvent.ProcessedAt.Valid {
+		return nil, ErrWebhookEventAlreadyProcessed
+	}
+
+	event, parseEventError := service.PaymentGateway.ParseEvent([]byte(storedEvent.Payload))
+
+	if parseEventError != nil {
+		return nil, fmt.Errorf("failed to decode stored webhook event %s: %w", eventID, parseEventError)
+	}
+
+	claimed, claimEventError := service.claimWebhookEvent(ctx, eventID)
+
+	if claimEventError != nil {
+		return nil, claimEventError
+	}
+
+	if !claimed {
+		return nil, ErrWebhookEventInProgress
+	}
+
+	log.Printf("Webhook: Replaying event %s (%s), previous attempts: %d", storedEvent.ID, storedEvent.EventType, storedEvent.Attempts)
+
+	processEventError := service.processWebhookEvent(ctx, event)
+
+	if processEventError != nil {
+		log.Printf("Webhook: Replay of event %s failed: %v", storedEvent.ID, processEventError)
+	}
+
+	// Return the stored state either way so the caller can see the recorded processing error.
+	return service.GetWebhookEventById(ctx, eventID)
+}
+
+func DatabaseWebhookEventToWebhookEventJSON(storedEvent database.StripeWebhookEvent, includePayload bool) WebhookEvent {
+	status := "pending"
+
+	if storedEvent.ProcessedAt.Valid {
+		status = "processed"
+	} else if storedEvent.ProcessingError.Valid {
+		status = "failed"
+	}
+
+	webhookEvent := WebhookEvent{
+		ID:              storedEvent.ID,
+		EventType:       storedEvent.EventType,
+		WebhookType:     storedEvent.WebhookType,
+		Status:          status,
+		Attempts:        storedEvent.Attempts,
+		ProcessingError: storedEvent.ProcessingError.String,
+		ReceivedAt:      storedEvent.ReceivedAt,
+		ProcessedAt:     sqlutil.NullTimeToString(storedEvent.ProcessedAt),
+	}
+
+	if includePayload {
+		webhookEvent.Payload = json.RawMessage(storedEvent.Payload)
+	}
+
+	return webhookEvent
 }
\ No newline at end of file
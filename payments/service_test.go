@@ -0,0 +1,314 @@
+package payments_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/paymentgateway"
+	"github.com/elorenzorodz/event-mrs/payments"
+)
+
+const unhandledEventType = "customer.created"
+
+// MockWebhookEventStore keeps the webhook events in memory and claims them like ClaimStripeWebhookEvent.
+type MockWebhookEventStore struct {
+	mutex  sync.Mutex
+	events map[string]*database.StripeWebhookEvent
+	// processingGate, when set, holds MarkStripeWebhookEventProcessed until it is closed, i.e. the event stays claimed.
+	processingGate chan struct{}
+}
+
+func NewMockWebhookEventStore(storedEvents ...database.StripeWebhookEvent) *MockWebhookEventStore {
+	mockStore := &MockWebhookEventStore{events: map[string]*database.StripeWebhookEvent{}}
+
+	for _, storedEvent := range storedEvents {
+		mockStore.events[storedEvent.ID] = &storedEvent
+	}
+
+	return mockStore
+}
+
+func (mockStore *MockWebhookEventStore) event(id string) database.StripeWebhookEvent {
+	mockStore.mutex.Lock()
+	defer mockStore.mutex.Unlock()
+
+	return *mockStore.events[id]
+}
+
+func (mockStore *MockWebhookEventStore) ClaimStripeWebhookEvent(ctx context.Context, arg database.ClaimStripeWebhookEventParams) (string, error) {
+	mockStore.mutex.Lock()
+	defer mockStore.mutex.Unlock()
+
+	storedEvent, found := mockStore.events[arg.ID]
+
+	if !found || storedEvent.ProcessedAt.Valid || (storedEvent.ProcessingStartedAt.Valid && !storedEvent.ProcessingStartedAt.Time.Before(arg.StaleBefore)) {
+		return "", sql.ErrNoRows
+	}
+
+	storedEvent.ProcessingStartedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+	return storedEvent.ID, nil
+}
+
+func (mockStore *MockWebhookEventStore) GetStripeWebhookEventById(ctx context.Context, id string) (database.StripeWebhookEvent, error) {
+	mockStore.mutex.Lock()
+	defer mockStore.mutex.Unlock()
+
+	storedEvent, found := mockStore.events[id]
+
+	if !found {
+		return database.StripeWebhookEvent{}, sql.ErrNoRows
+	}
+
+	return *storedEvent, nil
+}
+
+func (mockStore *MockWebhookEventStore) GetStripeWebhookEvents(ctx context.Context, arg database.GetStripeWebhookEventsParams) ([]database.StripeWebhookEvent, error) {
+	return nil, errors.New("GetStripeWebhookEvents is not used by the webhook handling")
+}
+
+func (mockStore *MockWebhookEventStore) MarkStripeWebhookEventFailed(ctx context.Context, arg database.MarkStripeWebhookEventFailedParams) error {
+	mockStore.mutex.Lock()
+	defer mockStore.mutex.Unlock()
+
+	storedEvent := mockStore.events[arg.ID]
+	storedEvent.Attempts++
+	storedEvent.ProcessingError = arg.ProcessingError
+	storedEvent.ProcessingStartedAt = sql.NullTime{}
+
+	return nil
+}
+
+func (mockStore *MockWebhookEventStore) MarkStripeWebhookEventProcessed(ctx context.Context, id string) error {
+	if mockStore.processingGate != nil {
+		<-mockStore.processingGate
+	}
+
+	mockStore.mutex.Lock()
+	defer mockStore.mutex.Unlock()
+
+	storedEvent := mockStore.events[id]
+	storedEvent.Attempts++
+	storedEvent.ProcessingError = sql.NullString{}
+	storedEvent.ProcessedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	storedEvent.ProcessingStartedAt = sql.NullTime{}
+
+	return nil
+}
+
+func (mockStore *MockWebhookEventStore) RecordStripeWebhookEvent(ctx context.Context, arg database.RecordStripeWebhookEventParams) (database.StripeWebhookEvent, error) {
+	mockStore.mutex.Lock()
+	defer mockStore.mutex.Unlock()
+
+	if storedEvent, found := mockStore.events[arg.ID]; found {
+		return *storedEvent, nil
+	}
+
+	storedEvent := &database.StripeWebhookEvent{
+		ID:          arg.ID,
+		EventType:   arg.EventType,
+		WebhookType: arg.WebhookType,
+		Payload:     arg.Payload,
+		ReceivedAt:  time.Now(),
+	}
+	mockStore.events[arg.ID] = storedEvent
+
+	return *storedEvent, nil
+}
+
+// newWebhookService returns a service whose gateway delivers and parses event, other payment actions aren't expected.
+func newWebhookService(t *testing.T, mockStore *MockWebhookEventStore, event paymentgateway.Event) *payments.Service {
+	return &payments.Service{
+		WebhookEvents: mockStore,
+		PaymentGateway: &MockPaymentGateway{
+			testingType: t,
+			VerifyWebhookFunc: func(payload []byte, signature string, secret string) (paymentgateway.Event, error) {
+				return event, nil
+			},
+			ParseEventFunc: func(payload []byte) (paymentgateway.Event, error) {
+				return event, nil
+			},
+		},
+	}
+}
+
+func TestHandleWebhookProcessesEventOnce(t *testing.T) {
+	mockStore := NewMockWebhookEventStore()
+	service := newWebhookService(t, mockStore, paymentgateway.Event{ID: "evt_1", Type: unhandledEventType})
+
+	for delivery := 1; delivery <= 2; delivery++ {
+		if err := service.HandleWebhook(context.Background(), []byte(`{"id":"evt_1"}`), "signature", "payment"); err != nil {
+			t.Fatalf("Delivery %d: expected no error, got: %v", delivery, err)
+		}
+	}
+
+	if storedEvent := mockStore.event("evt_1"); !storedEvent.ProcessedAt.Valid || storedEvent.Attempts != 1 {
+		t.Errorf("Expected the redelivered event to be processed once, got %d attempt/s, processed: %v", storedEvent.Attempts, storedEvent.ProcessedAt.Valid)
+	}
+}
+
+func TestHandleWebhookSkipsEventBeingProcessed(t *testing.T) {
+	mockStore := NewMockWebhookEventStore()
+	mockStore.processingGate = make(chan struct{})
+	service := newWebhookService(t, mockStore, paymentgateway.Event{ID: "evt_1", Type: unhandledEventType})
+
+	firstDeliveryDone := make(chan error)
+
+	go func() {
+		firstDeliveryDone <- service.HandleWebhook(context.Background(), []byte(`{"id":"evt_1"}`), "signature", "payment")
+	}()
+
+	deadline := time.Now().Add(time.Second)
+
+	for {
+		if storedEvent, err := mockStore.GetStripeWebhookEventById(context.Background(), "evt_1"); err == nil && storedEvent.ProcessingStartedAt.Valid {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("First delivery never claimed the event")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	// The second delivery would wait on the gate too if it processed the event.
+	if err := service.HandleWebhook(context.Background(), []byte(`{"id":"evt_1"}`), "signature", "payment"); err != nil {
+		t.Fatalf("Second delivery: expected no error, got: %v", err)
+	}
+
+	close(mockStore.processingGate)
+
+	if err := <-firstDeliveryDone; err != nil {
+		t.Fatalf("First delivery: expected no error, got: %v", err)
+	}
+
+	if storedEvent := mockStore.event("evt_1"); storedEvent.Attempts != 1 {
+		t.Errorf("Expected the event to be processed by one delivery, got %d attempt/s", storedEvent.Attempts)
+	}
+}
+
+func TestHandleWebhookClaims(t *testing.T) {
+	testCases := []struct {
+		name               string
+		storedEvent        database.StripeWebhookEvent
+		expectedAttempts   int32
+		expectedProcessing bool
+	}{
+		{
+			// The process that claimed it crashed, the delivery takes over.
+			name:             "StaleClaim",
+			storedEvent:      database.StripeWebhookEvent{ID: "evt_1", ProcessingStartedAt: sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true}},
+			expectedAttempts: 1,
+		},
+		{
+			name:               "RecentClaim",
+			storedEvent:        database.StripeWebhookEvent{ID: "evt_1", ProcessingStartedAt: sql.NullTime{Time: time.Now(), Valid: true}},
+			expectedAttempts:   0,
+			expectedProcessing: true,
+		},
+		{
+			// A failed event isn't claimed anymore, the gateway's retry processes it again.
+			name:             "FailedBefore",
+			storedEvent:      database.StripeWebhookEvent{ID: "evt_1", Attempts: 1, ProcessingError: sql.NullString{String: "database unavailable", Valid: true}},
+			expectedAttempts: 2,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockStore := NewMockWebhookEventStore(testCase.storedEvent)
+			service := newWebhookService(t, mockStore, paymentgateway.Event{ID: "evt_1", Type: unhandledEventType})
+
+			if err := service.HandleWebhook(context.Background(), []byte(`{"id":"evt_1"}`), "signature", "payment"); err != nil {
+				t.Fatalf("HandleWebhook: expected no error, got: %v", err)
+			}
+
+			storedEvent := mockStore.event("evt_1")
+
+			if storedEvent.Attempts != testCase.expectedAttempts || storedEvent.ProcessingStartedAt.Valid != testCase.expectedProcessing {
+				t.Errorf("HandleWebhook: expected %d attempt/s and claimed %v, got %d and %v", testCase.expectedAttempts, testCase.expectedProcessing, storedEvent.Attempts, storedEvent.ProcessingStartedAt.Valid)
+			}
+		})
+	}
+}
+
+func TestHandleWebhookReleasesClaimOfFailedEvent(t *testing.T) {
+	mockStore := NewMockWebhookEventStore()
+	// A payment intent event without its intent fails to process.
+	service := newWebhookService(t, mockStore, paymentgateway.Event{ID: "evt_1", Type: paymentgateway.EventIntentSucceeded})
+
+	for delivery := 1; delivery <= 2; delivery++ {
+		if err := service.HandleWebhook(context.Background(), []byte(`{"id":"evt_1"}`), "signature", "payment"); err == nil {
+			t.Fatalf("Delivery %d: expected the processing error", delivery)
+		}
+	}
+
+	storedEvent := mockStore.event("evt_1")
+
+	if storedEvent.Attempts != 2 || !storedEvent.ProcessingError.Valid || storedEvent.ProcessingStartedAt.Valid || storedEvent.ProcessedAt.Valid {
+		t.Errorf("Expected both deliveries to process the event and record the error, got %+v", storedEvent)
+	}
+}
+
+func TestReplayWebhookEvent(t *testing.T) {
+	testCases := []struct {
+		name           string
+		storedEvent    database.StripeWebhookEvent
+		expectedError  error
+		expectedStatus string
+	}{
+		{
+			name:           "Failed",
+			storedEvent:    database.StripeWebhookEvent{ID: "evt_1", Attempts: 1, ProcessingError: sql.NullString{String: "database unavailable", Valid: true}},
+			expectedStatus: "processed",
+		},
+		{
+			name:          "AlreadyProcessed",
+			storedEvent:   database.StripeWebhookEvent{ID: "evt_1", Attempts: 1, ProcessedAt: sql.NullTime{Time: time.Now(), Valid: true}},
+			expectedError: payments.ErrWebhookEventAlreadyProcessed,
+		},
+		{
+			name:          "BeingProcessed",
+			storedEvent:   database.StripeWebhookEvent{ID: "evt_1", ProcessingStartedAt: sql.NullTime{Time: time.Now(), Valid: true}},
+			expectedError: payments.ErrWebhookEventInProgress,
+		},
+		{
+			name:          "NotFound",
+			storedEvent:   database.StripeWebhookEvent{ID: "evt_2"},
+			expectedError: payments.ErrWebhookEventNotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.storedEvent.EventType = unhandledEventType
+			testCase.storedEvent.Payload = `{"id":"evt_1"}`
+			mockStore := NewMockWebhookEventStore(testCase.storedEvent)
+			service := newWebhookService(t, mockStore, paymentgateway.Event{ID: "evt_1", Type: unhandledEventType})
+
+			webhookEvent, err := service.ReplayWebhookEvent(context.Background(), "evt_1")
+
+			if testCase.expectedError != nil {
+				if !errors.Is(err, testCase.expectedError) {
+					t.Fatalf("ReplayWebhookEvent: expected %v, got %v", testCase.expectedError, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ReplayWebhookEvent: expected no error, got: %v", err)
+			}
+
+			if webhookEvent.Status != testCase.expectedStatus || webhookEvent.Attempts != 2 || webhookEvent.ProcessingError != "" {
+				t.Errorf("ReplayWebhookEvent: expected the event %s after 2 attempts, got %+v", testCase.expectedStatus, webhookEvent)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/config"
+	"github.com/elorenzorodz/event-mrs/internal/clock"
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/paymentgateway"
+	"github.com/elorenzorodz/event-mrs/internal/paymentstatus"
+	"github.com/elorenzorodz/event-mrs/internal/sqlutil"
+	"github.com/google/uuid"
+)
+
+func NewExpiredPaymentSweeper(dbQueries config.DBQueries, paymentGateway paymentgateway.Gateway, expiredPaymentMailer ExpiredPaymentMailer, waitlistOfferer WaitlistOfferer, sweeperClock clock.Clock, interval time.Duration, batchSize int32) *ExpiredPaymentSweeper {
+	return &ExpiredPaymentSweeper{
+		DB:             dbQueries,
+		PaymentGateway: paymentGateway,
+		Mailer:         expiredPaymentMailer,
+		Waitlist:       waitlistOfferer,
+		Clock:          sweeperClock,
+		Interval:       interval,
+		BatchSize:      batchSize,
+	}
+}
+
+// Start runs Sweep every Interval until the context is cancelled.
+func (sweeper *ExpiredPaymentSweeper) Start(ctx context.Context) {
+	log.Printf("Expired payment sweeper started, interval: %s, batch size: %d", sweeper.Interval, sweeper.BatchSize)
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("Expired payment sweeper stopped: %v", ctx.Err())
+
+			return
+		case <-sweeper.Clock.After(sweeper.Interval):
+			releasedCount, sweepError := sweeper.Sweep(ctx)
+
+			if sweepError != nil {
+				log.Printf("Expired payment sweeper error: %v", sweepError)
+			}
+
+			if releasedCount > 0 {
+				log.Printf("Expired payment sweeper released %d payment/s", releasedCount)
+			}
+		}
+	}
+}
+
+// Sweep releases one batch of expired payments and returns how many were released.
+func (sweeper *ExpiredPaymentSweeper) Sweep(ctx context.Context) (int, error) {
+	expiredPayments, getExpiredPaymentsError := sweeper.DB.GetExpiredPendingPayments(ctx, database.GetExpiredPendingPaymentsParams{
+		ExpiredBefore: sweeper.Clock.Now(),
+		BatchSize:     sweeper.BatchSize,
+	})
+
+	if getExpiredPaymentsError != nil {
+		return 0, fmt.Errorf("failed to retrieve expired payments: %w", getExpiredPaymentsError)
+	}
+
+	releasedCount := 0
+
+	for _, expiredPayment := range expiredPayments {
+		if releaseError := sweeper.releasePayment(ctx, expiredPayment); releaseError != nil {
+			log.Printf("Expired payment sweeper: skipping payment %s: %v", expiredPayment.ID, releaseError)
+
+			continue
+		}
+
+		releasedCount++
+	}
+
+	return releasedCount, nil
+}
+
+func (sweeper *ExpiredPaymentSweeper) releasePayment(ctx context.Context, expiredPayment database.Payment) error {
+	if expiredPayment.PaymentIntentID.Valid {
+		_, cancelError := sweeper.PaymentGateway.CancelIntent(ctx, expiredPayment.PaymentIntentID.String)
+
+		// An intent that the payment gateway already cancelled can still be released, anything else may have been paid in the meantime.
+		if cancelError != nil && !isPaymentIntentAlreadyCanceled(cancelError) {
+			return fmt.Errorf("failed to cancel payment intent %s: %w", expiredPayment.PaymentIntentID.String, cancelError)
+		}
+	}
+
+	// Collect the reserved event details before the reservations are released.
+	eventDetails := sweeper.getReservedEventDetails(ctx, expiredPayment)
+
+	// The tickets are released before the payment is cancelled, a failed cancellation leaves the payment to the next
+	// sweep and releasing it again is a no-op.
+	releaseReservationsError := sweeper.DB.ReleasePaymentReservations(ctx, database.ReleasePaymentReservationsParams{
+		PaymentID: expiredPayment.ID,
+		UserID:    expiredPayment.UserID,
+	})
+
+	if releaseReservationsError != nil {
+		return fmt.Errorf("failed to release reservations: %w", releaseReservationsError)
+	}
+
+	// Cancelled payments are kept with their status history and aren't picked up by the next sweep.
+	_, cancelPaymentError := paymentstatus.Update(ctx, sweeper.DB, database.UpdatePaymentParams{
+		Amount:          expiredPayment.Amount,
+		Status:          string(paymentstatus.Cancelled),
+		PaymentIntentID: expiredPayment.PaymentIntentID,
+		ID:              expiredPayment.ID,
+		UserID:          expiredPayment.UserID,
+		CurrentStatus:   expiredPayment.Status,
+	})
+
+	if cancelPaymentError != nil {
+		return fmt.Errorf("failed to cancel payment: %w", cancelPaymentError)
+	}
+
+	if len(eventDetails) > 0 {
+		releasedEventDetailIDs := make([]uuid.UUID, len(eventDetails))
+
+		for i, eventDetail := range eventDetails {
+			releasedEventDetailIDs[i] = eventDetail.ID
+		}
+
+		sweeper.Waitlist.OfferReleasedTickets(ctx, releasedEventDetailIDs)
+	}
+
+	user, getUserError := sweeper.DB.GetUserById(ctx, expiredPayment.UserID)
+
+	userEmail := "unknown@example.com"
+
+	if getUserError == nil {
+		userEmail = user.Email
+	} else {
+		log.Printf("Expired payment sweeper: failed to fetch user %s for payment %s: %v", expiredPayment.UserID, expiredPayment.ID, getUserError)
+	}
+
+	_, createPaymentLogError := sweeper.DB.CreatePaymentLog(ctx, database.CreatePaymentLogParams{
+		ID:              uuid.New(),
+		Status:          string(paymentstatus.Cancelled),
+		Description:     sqlutil.StringToNullString(fmt.Sprintf("payment expired at %s while %s, tickets released", expiredPayment.ExpiresAt.Format("2006-01-02 15:04:05"), expiredPayment.Status)),
+		PaymentIntentID: expiredPayment.PaymentIntentID.String,
+		Amount:          expiredPayment.Amount,
+		UserEmail:       userEmail,
+		PaymentID:       expiredPayment.ID,
+		Currency:        expiredPayment.Currency,
+	})
+
+	if createPaymentLogError != nil {
+		log.Printf("error: create payment log - %s", createPaymentLogError)
+	}
+
+	if getUserError == nil {
+		fullName := fmt.Sprintf("%s %s", user.Firstname, user.Lastname)
+
+		if sendEmailError := sweeper.Mailer.SendPaymentExpiredNotification(fullName, user.Email, eventDetails); sendEmailError != nil {
+			log.Printf("Error sending payment expired email for payment %s: %v", expiredPayment.ID, sendEmailError)
+		}
+	}
+
+	return nil
+}
+
+func (sweeper *ExpiredPaymentSweeper) getReservedEventDetails(ctx context.Context, expiredPayment database.Payment) []database.GetEventDetailsWithTitleByIdsRow {
+	userReservations, getReservationsError := sweeper.DB.GetUserReservationsByPaymentId(ctx, database.GetUserReservationsByPaymentIdParams{
+		UserID:    expiredPayment.UserID,
+		PaymentID: expiredPayment.ID,
+	})
+
+	if getReservationsError != nil || len(userReservations) == 0 {
+		return nil
+	}
+
+	eventDetailIds := make([]uuid.UUID, len(userReservations))
+
+	for i, userReservation := range userReservations {
+		eventDetailIds[i] = userReservation.EventDetailID
+	}
+
+	eventDetails, getEventDetailsError := sweeper.DB.GetEventDetailsWithTitleByIds(ctx, eventDetailIds)
+
+	if getEventDetailsError != nil {
+		log.Printf("Expired payment sweeper: failed to fetch event details for payment %s: %v", expiredPayment.ID, getEventDetailsError)
+
+		return nil
+	}
+
+	return eventDetails
+}
+
+func isPaymentIntentAlreadyCanceled(cancelError error) bool {
+	gatewayError, ok := paymentgateway.AsError(cancelError)
+
+	if !ok || gatewayError.Intent == nil {
+		return false
+	}
+
+	return gatewayError.Intent.Status == paymentgateway.IntentStatusCanceled
+}
\ No newline at end of file
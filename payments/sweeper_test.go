@@ -0,0 +1,461 @@
+package payments_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/config"
+	"github.com/elorenzorodz/event-mrs/internal/clock/clocktest"
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/paymentgateway"
+	"github.com/elorenzorodz/event-mrs/internal/paymentstatus"
+	"github.com/elorenzorodz/event-mrs/payments"
+	"github.com/google/uuid"
+)
+
+type MockDBQueries struct {
+	*config.BaseMock
+	testingType                        *testing.T
+	GetExpiredPendingPaymentsFunc      func(ctx context.Context, arg database.GetExpiredPendingPaymentsParams) ([]database.Payment, error)
+	ReleasePaymentReservationsFunc     func(ctx context.Context, arg database.ReleasePaymentReservationsParams) error
+	UpdatePaymentFunc                  func(ctx context.Context, arg database.UpdatePaymentParams) (database.Payment, error)
+	CreatePaymentLogFunc               func(ctx context.Context, arg database.CreatePaymentLogParams) (database.PaymentLog, error)
+	GetUserByIdFunc                    func(ctx context.Context, id uuid.UUID) (database.User, error)
+	GetEventDetailsWithTitleByIdsFunc  func(ctx context.Context, id []uuid.UUID) ([]database.GetEventDetailsWithTitleByIdsRow, error)
+	GetUserReservationsByPaymentIdFunc func(ctx context.Context, arg database.GetUserReservationsByPaymentIdParams) ([]database.Reservation, error)
+}
+
+func (mockDBQueries *MockDBQueries) GetExpiredPendingPayments(ctx context.Context, arg database.GetExpiredPendingPaymentsParams) ([]database.Payment, error) {
+	if mockDBQueries.GetExpiredPendingPaymentsFunc == nil {
+		mockDBQueries.testingType.Fatalf("GetExpiredPendingPayments was called, but no expectation (GetExpiredPendingPaymentsFunc) was set.")
+	}
+
+	return mockDBQueries.GetExpiredPendingPaymentsFunc(ctx, arg)
+}
+
+func (mockDBQueries *MockDBQueries) ReleasePaymentReservations(ctx context.Context, arg database.ReleasePaymentReservationsParams) error {
+	if mockDBQueries.ReleasePaymentReservationsFunc == nil {
+		mockDBQueries.testingType.Fatalf("ReleasePaymentReservations was called, but no expectation (ReleasePaymentReservationsFunc) was set.")
+	}
+
+	return mockDBQueries.ReleasePaymentReservationsFunc(ctx, arg)
+}
+
+func (mockDBQueries *MockDBQueries) UpdatePayment(ctx context.Context, arg database.UpdatePaymentParams) (database.Payment, error) {
+	if mockDBQueries.UpdatePaymentFunc == nil {
+		mockDBQueries.testingType.Fatalf("UpdatePayment was called, but no expectation (UpdatePaymentFunc) was set.")
+	}
+
+	return mockDBQueries.UpdatePaymentFunc(ctx, arg)
+}
+
+func (mockDBQueries *MockDBQueries) CreatePaymentLog(ctx context.Context, arg database.CreatePaymentLogParams) (database.PaymentLog, error) {
+	if mockDBQueries.CreatePaymentLogFunc == nil {
+		mockDBQueries.testingType.Fatalf("CreatePaymentLog was called, but no expectation (CreatePaymentLogFunc) was set.")
+	}
+
+	return mockDBQueries.CreatePaymentLogFunc(ctx, arg)
+}
+
+func (mockDBQueries *MockDBQueries) GetUserById(ctx context.Context, id uuid.UUID) (database.User, error) {
+	if mockDBQueries.GetUserByIdFunc == nil {
+		return database.User{}, sql.ErrNoRows
+	}
+
+	return mockDBQueries.GetUserByIdFunc(ctx, id)
+}
+
+func (mockDBQueries *MockDBQueries) GetUserReservationsByPaymentId(ctx context.Context, arg database.GetUserReservationsByPaymentIdParams) ([]database.Reservation, error) {
+	if mockDBQueries.GetUserReservationsByPaymentIdFunc == nil {
+		return mockDBQueries.BaseMock.GetUserReservationsByPaymentId(ctx, arg)
+	}
+
+	return mockDBQueries.GetUserReservationsByPaymentIdFunc(ctx, arg)
+}
+
+func (mockDBQueries *MockDBQueries) GetEventDetailsWithTitleByIds(ctx context.Context, id []uuid.UUID) ([]database.GetEventDetailsWithTitleByIdsRow, error) {
+	if mockDBQueries.GetEventDetailsWithTitleByIdsFunc == nil {
+		return mockDBQueries.BaseMock.GetEventDetailsWithTitleByIds(ctx, id)
+	}
+
+	return mockDBQueries.GetEventDetailsWithTitleByIdsFunc(ctx, id)
+}
+
+type MockPaymentGateway struct {
+	testingType       *testing.T
+	CancelIntentFunc  func(ctx context.Context, intentID string) (*paymentgateway.Intent, error)
+	VerifyWebhookFunc func(payload []byte, signature string, secret string) (paymentgateway.Event, error)
+	ParseEventFunc    func(payload []byte) (paymentgateway.Event, error)
+}
+
+func (mockPaymentGateway *MockPaymentGateway) CreateIntent(ctx context.Context, params paymentgateway.CreateIntentParams) (*paymentgateway.Intent, error) {
+	mockPaymentGateway.testingType.Fatalf("CreateIntent should not be called by the sweeper.")
+
+	return nil, nil
+}
+
+func (mockPaymentGateway *MockPaymentGateway) ConfirmIntent(ctx context.Context, intentID string, paymentMethodID string) (*paymentgateway.Intent, error) {
+	mockPaymentGateway.testingType.Fatalf("ConfirmIntent should not be called by the sweeper.")
+
+	return nil, nil
+}
+
+func (mockPaymentGateway *MockPaymentGateway) CancelIntent(ctx context.Context, intentID string) (*paymentgateway.Intent, error) {
+	if mockPaymentGateway.CancelIntentFunc == nil {
+		mockPaymentGateway.testingType.Fatalf("CancelIntent was called, but no expectation (CancelIntentFunc) was set.")
+	}
+
+	return mockPaymentGateway.CancelIntentFunc(ctx, intentID)
+}
+
+func (mockPaymentGateway *MockPaymentGateway) GetIntent(ctx context.Context, intentID string) (*paymentgateway.Intent, error) {
+	mockPaymentGateway.testingType.Fatalf("GetIntent should not be called by the sweeper.")
+
+	return nil, nil
+}
+
+func (mockPaymentGateway *MockPaymentGateway) Refund(ctx context.Context, params paymentgateway.RefundParams) (*paymentgateway.Refund, error) {
+	mockPaymentGateway.testingType.Fatalf("Refund should not be called by the sweeper.")
+
+	return nil, nil
+}
+
+func (mockPaymentGateway *MockPaymentGateway) VerifyWebhook(payload []byte, signature string, secret string) (paymentgateway.Event, error) {
+	if mockPaymentGateway.VerifyWebhookFunc == nil {
+		mockPaymentGateway.testingType.Fatalf("VerifyWebhook was called, but no expectation (VerifyWebhookFunc) was set.")
+	}
+
+	return mockPaymentGateway.VerifyWebhookFunc(payload, signature, secret)
+}
+
+func (mockPaymentGateway *MockPaymentGateway) ParseEvent(payload []byte) (paymentgateway.Event, error) {
+	if mockPaymentGateway.ParseEventFunc == nil {
+		mockPaymentGateway.testingType.Fatalf("ParseEvent was called, but no expectation (ParseEventFunc) was set.")
+	}
+
+	return mockPaymentGateway.ParseEventFunc(payload)
+}
+
+type MockExpiredPaymentMailer struct {
+	mutex      sync.Mutex
+	recipients []string
+}
+
+func (mockMailer *MockExpiredPaymentMailer) SendPaymentExpiredNotification(recipientName string, recipientEmail string, eventDetailsWithEventTitle []database.GetEventDetailsWithTitleByIdsRow) error {
+	mockMailer.mutex.Lock()
+	defer mockMailer.mutex.Unlock()
+
+	mockMailer.recipients = append(mockMailer.recipients, recipientEmail)
+
+	return nil
+}
+
+type MockWaitlistOfferer struct {
+	mutex          sync.Mutex
+	eventDetailIDs []uuid.UUID
+}
+
+func (mockWaitlist *MockWaitlistOfferer) OfferReleasedTickets(ctx context.Context, eventDetailIDs []uuid.UUID) {
+	mockWaitlist.mutex.Lock()
+	defer mockWaitlist.mutex.Unlock()
+
+	mockWaitlist.eventDetailIDs = append(mockWaitlist.eventDetailIDs, eventDetailIDs...)
+}
+
+func TestSweep(tTesting *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	testUser := database.User{
+		ID:        uuid.New(),
+		Firstname: "Arthur",
+		Lastname:  "Morgan",
+		Email:     "arthur.morgan@test.com",
+	}
+
+	paymentWithIntent := database.Payment{
+		ID:              uuid.New(),
+		PaymentIntentID: sql.NullString{String: "pi_requires_action", Valid: true},
+		Amount:          2000,
+		Status:          string(paymentgateway.IntentStatusRequiresAction),
+		ExpiresAt:       now.Add(-time.Minute),
+		UserID:          testUser.ID,
+	}
+
+	paymentWithoutIntent := database.Payment{
+		ID:        uuid.New(),
+		Amount:    1500,
+		Status:    "pending",
+		ExpiresAt: now.Add(-time.Hour),
+		UserID:    testUser.ID,
+	}
+
+	tests := []struct {
+		name                      string
+		expiredPayments           []database.Payment
+		cancelIntentFunc          func(ctx context.Context, intentID string) (*paymentgateway.Intent, error)
+		updatePaymentError        error
+		expectedReleasedCount     int
+		expectedRestoredPayments  int
+		expectedCancelledPayments int
+	}{
+		{
+			name:            "Success_ReleasesExpiredPayments",
+			expiredPayments: []database.Payment{paymentWithIntent, paymentWithoutIntent},
+			cancelIntentFunc: func(ctx context.Context, intentID string) (*paymentgateway.Intent, error) {
+				return &paymentgateway.Intent{ID: intentID, Status: paymentgateway.IntentStatusCanceled}, nil
+			},
+			expectedReleasedCount:     2,
+			expectedRestoredPayments:  2,
+			expectedCancelledPayments: 2,
+		},
+		{
+			name:            "Success_PaymentIntentAlreadyCanceled",
+			expiredPayments: []database.Payment{paymentWithIntent},
+			cancelIntentFunc: func(ctx context.Context, intentID string) (*paymentgateway.Intent, error) {
+				return nil, &paymentgateway.Error{
+					Code:   paymentgateway.ErrorCodeIntentUnexpectedState,
+					Intent: &paymentgateway.Intent{ID: intentID, Status: paymentgateway.IntentStatusCanceled},
+				}
+			},
+			expectedReleasedCount:     1,
+			expectedRestoredPayments:  1,
+			expectedCancelledPayments: 1,
+		},
+		{
+			name:            "Skipped_CancelPaymentIntentFailed",
+			expiredPayments: []database.Payment{paymentWithIntent},
+			cancelIntentFunc: func(ctx context.Context, intentID string) (*paymentgateway.Intent, error) {
+				return nil, errors.New("payment gateway unavailable")
+			},
+			expectedReleasedCount:     0,
+			expectedRestoredPayments:  0,
+			expectedCancelledPayments: 0,
+		},
+		{
+			name:                      "Skipped_PaymentStatusChanged",
+			expiredPayments:           []database.Payment{paymentWithoutIntent},
+			updatePaymentError:        sql.ErrNoRows,
+			expectedReleasedCount:     0,
+			expectedRestoredPayments:  1,
+			expectedCancelledPayments: 0,
+		},
+		{
+			name:                      "Success_NothingExpired",
+			expiredPayments:           []database.Payment{},
+			expectedReleasedCount:     0,
+			expectedRestoredPayments:  0,
+			expectedCancelledPayments: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.name, func(t *testing.T) {
+			var (
+				restoredPayments  []uuid.UUID
+				cancelledPayments []database.UpdatePaymentParams
+				paymentLogs       []database.CreatePaymentLogParams
+			)
+
+			mockDB := &MockDBQueries{BaseMock: config.NewBaseMock(), testingType: t}
+			mockDB.GetExpiredPendingPaymentsFunc = func(ctx context.Context, arg database.GetExpiredPendingPaymentsParams) ([]database.Payment, error) {
+				if !arg.ExpiredBefore.Equal(now) {
+					t.Errorf("Expected expiry cut-off %s, got %s", now, arg.ExpiredBefore)
+				}
+
+				if arg.BatchSize != 25 {
+					t.Errorf("Expected batch size 25, got %d", arg.BatchSize)
+				}
+
+				return tc.expiredPayments, nil
+			}
+			mockDB.ReleasePaymentReservationsFunc = func(ctx context.Context, arg database.ReleasePaymentReservationsParams) error {
+				restoredPayments = append(restoredPayments, arg.PaymentID)
+
+				return nil
+			}
+			mockDB.UpdatePaymentFunc = func(ctx context.Context, arg database.UpdatePaymentParams) (database.Payment, error) {
+				if tc.updatePaymentError != nil {
+					return database.Payment{}, tc.updatePaymentError
+				}
+
+				cancelledPayments = append(cancelledPayments, arg)
+
+				return database.Payment{ID: arg.ID, Status: arg.Status}, nil
+			}
+			mockDB.CreatePaymentLogFunc = func(ctx context.Context, arg database.CreatePaymentLogParams) (database.PaymentLog, error) {
+				paymentLogs = append(paymentLogs, arg)
+
+				return database.PaymentLog{}, nil
+			}
+			mockDB.GetUserByIdFunc = func(ctx context.Context, id uuid.UUID) (database.User, error) {
+				return testUser, nil
+			}
+
+			mockPaymentGateway := &MockPaymentGateway{testingType: t, CancelIntentFunc: tc.cancelIntentFunc}
+			mockMailer := &MockExpiredPaymentMailer{}
+
+			sweeper := payments.NewExpiredPaymentSweeper(mockDB, mockPaymentGateway, mockMailer, &MockWaitlistOfferer{}, clocktest.NewFakeClock(now), time.Minute, 25)
+
+			releasedCount, err := sweeper.Sweep(ctx)
+
+			if err != nil {
+				t.Fatalf("Sweep: expected no error, got: %v", err)
+			}
+
+			if releasedCount != tc.expectedReleasedCount {
+				t.Errorf("Expected %d released payments, got %d", tc.expectedReleasedCount, releasedCount)
+			}
+
+			if len(restoredPayments) != tc.expectedRestoredPayments {
+				t.Errorf("Expected %d restored payments, got %d", tc.expectedRestoredPayments, len(restoredPayments))
+			}
+
+			if len(cancelledPayments) != tc.expectedCancelledPayments {
+				t.Errorf("Expected %d cancelled payments, got %d", tc.expectedCancelledPayments, len(cancelledPayments))
+			}
+
+			for i, cancelledPayment := range cancelledPayments {
+				if cancelledPayment.Status != string(paymentstatus.Cancelled) {
+					t.Errorf("Expected payment to be cancelled, got status %s", cancelledPayment.Status)
+				}
+
+				if cancelledPayment.CurrentStatus != tc.expiredPayments[i].Status {
+					t.Errorf("Expected payment to be cancelled from status %s, got %s", tc.expiredPayments[i].Status, cancelledPayment.CurrentStatus)
+				}
+			}
+
+			if len(paymentLogs) != tc.expectedCancelledPayments {
+				t.Errorf("Expected %d payment logs, got %d", tc.expectedCancelledPayments, len(paymentLogs))
+			}
+
+			for _, paymentLog := range paymentLogs {
+				if paymentLog.Status != string(paymentstatus.Cancelled) {
+					t.Errorf("Expected payment log status %q, got %s", paymentstatus.Cancelled, paymentLog.Status)
+				}
+			}
+
+			if len(mockMailer.recipients) != tc.expectedCancelledPayments {
+				t.Errorf("Expected %d expiry emails, got %d", tc.expectedCancelledPayments, len(mockMailer.recipients))
+			}
+		})
+	}
+}
+
+func TestSweepDatabaseError(t *testing.T) {
+	mockDB := &MockDBQueries{BaseMock: config.NewBaseMock(), testingType: t}
+	mockDB.GetExpiredPendingPaymentsFunc = func(ctx context.Context, arg database.GetExpiredPendingPaymentsParams) ([]database.Payment, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	sweeper := payments.NewExpiredPaymentSweeper(mockDB, &MockPaymentGateway{testingType: t}, &MockExpiredPaymentMailer{}, &MockWaitlistOfferer{}, clocktest.NewFakeClock(time.Now()), time.Minute, 25)
+
+	if _, err := sweeper.Sweep(context.Background()); err == nil {
+		t.Fatal("Expected an error when expired payments cannot be retrieved")
+	}
+}
+
+func TestSweepOffersReleasedTicketsToWaitlist(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	eventDetailID := uuid.New()
+
+	expiredPayment := database.Payment{
+		ID:        uuid.New(),
+		Amount:    1500,
+		Status:    "pending",
+		ExpiresAt: now.Add(-time.Hour),
+		UserID:    uuid.New(),
+	}
+
+	mockDB := &MockDBQueries{BaseMock: config.NewBaseMock(), testingType: t}
+	mockDB.GetExpiredPendingPaymentsFunc = func(ctx context.Context, arg database.GetExpiredPendingPaymentsParams) ([]database.Payment, error) {
+		return []database.Payment{expiredPayment}, nil
+	}
+	mockDB.GetUserReservationsByPaymentIdFunc = func(ctx context.Context, arg database.GetUserReservationsByPaymentIdParams) ([]database.Reservation, error) {
+		return []database.Reservation{{ID: uuid.New(), EventDetailID: eventDetailID, PaymentID: expiredPayment.ID, UserID: expiredPayment.UserID}}, nil
+	}
+	mockDB.GetEventDetailsWithTitleByIdsFunc = func(ctx context.Context, id []uuid.UUID) ([]database.GetEventDetailsWithTitleByIdsRow, error) {
+		return []database.GetEventDetailsWithTitleByIdsRow{{ID: eventDetailID, Title: "Test Event"}}, nil
+	}
+	mockDB.ReleasePaymentReservationsFunc = func(ctx context.Context, arg database.ReleasePaymentReservationsParams) error {
+		return nil
+	}
+	mockDB.UpdatePaymentFunc = func(ctx context.Context, arg database.UpdatePaymentParams) (database.Payment, error) {
+		return database.Payment{ID: arg.ID, Status: arg.Status}, nil
+	}
+	mockDB.CreatePaymentLogFunc = func(ctx context.Context, arg database.CreatePaymentLogParams) (database.PaymentLog, error) {
+		return database.PaymentLog{}, nil
+	}
+
+	mockWaitlist := &MockWaitlistOfferer{}
+	sweeper := payments.NewExpiredPaymentSweeper(mockDB, &MockPaymentGateway{testingType: t}, &MockExpiredPaymentMailer{}, mockWaitlist, clocktest.NewFakeClock(now), time.Minute, 25)
+
+	if _, err := sweeper.Sweep(context.Background()); err != nil {
+		t.Fatalf("Sweep: expected no error, got: %v", err)
+	}
+
+	if len(mockWaitlist.eventDetailIDs) != 1 || mockWaitlist.eventDetailIDs[0] != eventDetailID {
+		t.Errorf("Expected released tickets of %s to be offered to the waitlist, got %v", eventDetailID, mockWaitlist.eventDetailIDs)
+	}
+}
+
+func TestStartSweepsOnEveryInterval(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	fakeClock := clocktest.NewFakeClock(start)
+	sweptAt := make(chan time.Time, 3)
+
+	mockDB := &MockDBQueries{BaseMock: config.NewBaseMock(), testingType: t}
+	mockDB.GetExpiredPendingPaymentsFunc = func(ctx context.Context, arg database.GetExpiredPendingPaymentsParams) ([]database.Payment, error) {
+		sweptAt <- arg.ExpiredBefore
+
+		return []database.Payment{}, nil
+	}
+
+	sweeper := payments.NewExpiredPaymentSweeper(mockDB, &MockPaymentGateway{testingType: t}, &MockExpiredPaymentMailer{}, &MockWaitlistOfferer{}, fakeClock, 5*time.Minute, 25)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+
+	go func() {
+		sweeper.Start(ctx)
+		close(stopped)
+	}()
+
+	for sweep := 1; sweep <= 2; sweep++ {
+		clocktest.WaitForWaiter(t, fakeClock)
+
+		// Advancing less than the interval must not trigger a sweep.
+		fakeClock.Advance(4 * time.Minute)
+
+		select {
+		case <-sweptAt:
+			t.Fatalf("Sweep %d ran before the interval elapsed", sweep)
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		fakeClock.Advance(time.Minute)
+
+		select {
+		case cutOff := <-sweptAt:
+			expected := start.Add(time.Duration(sweep) * 5 * time.Minute)
+
+			if !cutOff.Equal(expected) {
+				t.Errorf("Sweep %d: expected cut-off %s, got %s", sweep, expected, cutOff)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Sweep %d did not run after the interval elapsed", sweep)
+		}
+	}
+
+	cancel()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Sweeper did not stop after the context was cancelled")
+	}
+}
\ No newline at end of file
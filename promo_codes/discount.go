@@ -0,0 +1,213 @@
+package promo_codes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/money"
+	"github.com/google/uuid"
+)
+
+const (
+	DiscountTypePercentage  = "percentage"
+	DiscountTypeFixedAmount = "fixed_amount"
+)
+
+var (
+	ErrInvalidPromoCode           = errors.New("invalid promo code")
+	ErrPromoCodeNotFound          = fmt.Errorf("%w: code doesn't exist for the events in this order", ErrInvalidPromoCode)
+	ErrPromoCodeNotActive         = fmt.Errorf("%w: code is not valid at this time", ErrInvalidPromoCode)
+	ErrPromoCodeNotApplicable     = fmt.Errorf("%w: code doesn't apply to the tickets in this order", ErrInvalidPromoCode)
+	ErrPromoCodeMinimumQuantity   = fmt.Errorf("%w: not enough eligible tickets in this order", ErrInvalidPromoCode)
+	ErrPromoCodeUsageLimitReached = fmt.Errorf("%w: code has reached its usage limit", ErrInvalidPromoCode)
+	ErrPromoCodeUserLimitReached  = fmt.Errorf("%w: code was already used the maximum number of times by this account", ErrInvalidPromoCode)
+)
+
+// NormalizeCode makes codes case-insensitive, they are stored upper case.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Apply finds the promo code for the events in the order, checks its limits and calculates the discount.
+// The promo code row stays locked until the transaction of dbQueries ends, so usage limits hold when orders come in at the same time.
+func Apply(ctx context.Context, dbQueries *database.Queries, code string, userID uuid.UUID, lines []TicketLine, currentDateTime time.Time) (Discount, error) {
+	eventIDs := make([]uuid.UUID, 0, len(lines))
+
+	for _, line := range lines {
+		if !slices.Contains(eventIDs, line.EventID) {
+			eventIDs = append(eventIDs, line.EventID)
+		}
+	}
+
+	promoCodes, getPromoCodesError := dbQueries.GetPromoCodesByCode(ctx, database.GetPromoCodesByCodeParams{
+		Code:     NormalizeCode(code),
+		EventIds: eventIDs,
+	})
+
+	if getPromoCodesError != nil {
+		log.Printf("error fetching promo code: %v", getPromoCodesError)
+
+		return Discount{}, errors.New("error checking promo code")
+	}
+
+	if len(promoCodes) == 0 {
+		return Discount{}, ErrPromoCodeNotFound
+	}
+
+	// An order can span events that both have the code, the first one that applies is used.
+	var notApplicableError error
+
+	for _, promoCode := range promoCodes {
+		if checkError := CheckApplicable(promoCode, lines, currentDateTime); checkError != nil {
+			if notApplicableError == nil {
+				notApplicableError = checkError
+			}
+
+			continue
+		}
+
+		if _, lockError := dbQueries.LockPromoCode(ctx, promoCode.ID); lockError != nil {
+			log.Printf("error locking promo code %s: %v", promoCode.ID, lockError)
+
+			return Discount{}, errors.New("error checking promo code")
+		}
+
+		// Cancelled payments don't count, expired and released payments are cancelled and give their use back.
+		promoCodeUsage, getUsageError := dbQueries.GetPromoCodeUsage(ctx, database.GetPromoCodeUsageParams{
+			UserID:      userID,
+			PromoCodeID: uuid.NullUUID{UUID: promoCode.ID, Valid: true},
+		})
+
+		if getUsageError != nil {
+			log.Printf("error fetching usage of promo code %s: %v", promoCode.ID, getUsageError)
+
+			return Discount{}, errors.New("error checking promo code")
+		}
+
+		if promoCode.MaxUses.Valid && promoCodeUsage.TotalUses >= int64(promoCode.MaxUses.Int32) {
+			return Discount{}, ErrPromoCodeUsageLimitReached
+		}
+
+		if promoCode.MaxUsesPerUser.Valid && promoCodeUsage.UserUses >= int64(promoCode.MaxUsesPerUser.Int32) {
+			return Discount{}, ErrPromoCodeUserLimitReached
+		}
+
+		return CalculateDiscount(promoCode, lines)
+	}
+
+	return Discount{}, notApplicableError
+}
+
+// CheckApplicable checks the validity window and that the order has enough tickets the code applies to.
+func CheckApplicable(promoCode database.PromoCode, lines []TicketLine, currentDateTime time.Time) error {
+	if promoCode.ValidFrom.Valid && currentDateTime.Before(promoCode.ValidFrom.Time) {
+		return ErrPromoCodeNotActive
+	}
+
+	if promoCode.ValidUntil.Valid && !currentDateTime.Before(promoCode.ValidUntil.Time) {
+		return ErrPromoCodeNotActive
+	}
+
+	var eligibleQuantity int32
+
+	for _, line := range lines {
+		if isEligible(promoCode, line) {
+			eligibleQuantity += line.Quantity
+		}
+	}
+
+	if eligibleQuantity == 0 {
+		return ErrPromoCodeNotApplicable
+	}
+
+	if eligibleQuantity < promoCode.MinQuantity {
+		return fmt.Errorf("%w, at least %d are required", ErrPromoCodeMinimumQuantity, promoCode.MinQuantity)
+	}
+
+	return nil
+}
+
+// CalculateDiscount splits the discount over the single tickets so a refunded ticket gives back exactly what was paid for it.
+// Percentages are rounded per ticket. A fixed amount is taken off the order once, capped at the eligible subtotal,
+// and split in proportion to the ticket prices with the rounding cents going to the largest remainders.
+func CalculateDiscount(promoCode database.PromoCode, lines []TicketLine) (Discount, error) {
+	discountValue := promoCode.DiscountValue
+
+	discount := Discount{
+		PromoCodeID: promoCode.ID,
+		TicketCents: make([][]int64, len(lines)),
+	}
+
+	var eligibleSubtotalCents int64
+
+	for i, line := range lines {
+		discount.TicketCents[i] = make([]int64, line.Quantity)
+
+		if isEligible(promoCode, line) {
+			eligibleSubtotalCents += line.UnitPriceCents * int64(line.Quantity)
+		}
+	}
+
+	if eligibleSubtotalCents == 0 {
+		return discount, nil
+	}
+
+	switch promoCode.DiscountType {
+	case DiscountTypePercentage:
+		// The percentage is stored as basis points, 12.50% is 1250.
+		for i, line := range lines {
+			if !isEligible(promoCode, line) {
+				continue
+			}
+
+			ticketDiscountCents := min((line.UnitPriceCents*discountValue+5000)/10000, line.UnitPriceCents)
+
+			for ticket := range discount.TicketCents[i] {
+				discount.TicketCents[i][ticket] = ticketDiscountCents
+				discount.TotalCents += ticketDiscountCents
+			}
+		}
+	case DiscountTypeFixedAmount:
+		discount.TotalCents = min(discountValue, eligibleSubtotalCents)
+
+		// Tickets the code doesn't apply to get a weight of 0 and no share of the discount.
+		var ticketPrices []int64
+
+		for _, line := range lines {
+			for range line.Quantity {
+				if isEligible(promoCode, line) {
+					ticketPrices = append(ticketPrices, line.UnitPriceCents)
+				} else {
+					ticketPrices = append(ticketPrices, 0)
+				}
+			}
+		}
+
+		ticketDiscounts := money.New(discount.TotalCents, money.DefaultCurrency).Allocate(ticketPrices)
+
+		for i := range discount.TicketCents {
+			for ticket := range discount.TicketCents[i] {
+				discount.TicketCents[i][ticket] = ticketDiscounts[0].Amount
+				ticketDiscounts = ticketDiscounts[1:]
+			}
+		}
+	default:
+		return Discount{}, fmt.Errorf("unknown discount type %s of promo code %s", promoCode.DiscountType, promoCode.Code)
+	}
+
+	return discount, nil
+}
+
+func isEligible(promoCode database.PromoCode, line TicketLine) bool {
+	if line.EventID != promoCode.EventID {
+		return false
+	}
+
+	return !promoCode.EventDetailID.Valid || promoCode.EventDetailID.UUID == line.EventDetailID
+}
\ No newline at end of file
@@ -0,0 +1,213 @@
+package promo_codes_test
+
+import (
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/promo_codes"
+	"github.com/google/uuid"
+)
+
+func TestCalculateDiscount(tTesting *testing.T) {
+	eventID := uuid.New()
+	otherEventID := uuid.New()
+	generalAdmission := uuid.New()
+	vip := uuid.New()
+
+	lines := []promo_codes.TicketLine{
+		{EventID: eventID, EventDetailID: generalAdmission, UnitPriceCents: 1000, Quantity: 2},
+		{EventID: eventID, EventDetailID: vip, UnitPriceCents: 2500, Quantity: 1},
+		{EventID: otherEventID, EventDetailID: uuid.New(), UnitPriceCents: 5000, Quantity: 1},
+	}
+
+	tests := []struct {
+		name                string
+		promoCode           database.PromoCode
+		expectedTotalCents  int64
+		expectedTicketCents [][]int64
+	}{
+		{
+			name:                "Percentage_WholeEvent",
+			promoCode:           database.PromoCode{DiscountType: promo_codes.DiscountTypePercentage, DiscountValue: 1000, EventID: eventID},
+			expectedTotalCents:  450,
+			expectedTicketCents: [][]int64{{100, 100}, {250}, {0}},
+		},
+		{
+			name:                "Percentage_RoundsPerTicket",
+			promoCode:           database.PromoCode{DiscountType: promo_codes.DiscountTypePercentage, DiscountValue: 1250, EventID: eventID, EventDetailID: uuid.NullUUID{UUID: vip, Valid: true}},
+			expectedTotalCents:  313,
+			expectedTicketCents: [][]int64{{0, 0}, {313}, {0}},
+		},
+		{
+			name:                "FixedAmount_SplitByPrice",
+			promoCode:           database.PromoCode{DiscountType: promo_codes.DiscountTypeFixedAmount, DiscountValue: 1000, EventID: eventID},
+			expectedTotalCents:  1000,
+			expectedTicketCents: [][]int64{{222, 222}, {556}, {0}},
+		},
+		{
+			name:                "FixedAmount_CappedAtSubtotal",
+			promoCode:           database.PromoCode{DiscountType: promo_codes.DiscountTypeFixedAmount, DiscountValue: 5000, EventID: eventID, EventDetailID: uuid.NullUUID{UUID: generalAdmission, Valid: true}},
+			expectedTotalCents:  2000,
+			expectedTicketCents: [][]int64{{1000, 1000}, {0}, {0}},
+		},
+		{
+			name:                "FixedAmount_RemainderCentsSumUp",
+			promoCode:           database.PromoCode{DiscountType: promo_codes.DiscountTypeFixedAmount, DiscountValue: 1, EventID: eventID, EventDetailID: uuid.NullUUID{UUID: generalAdmission, Valid: true}},
+			expectedTotalCents:  1,
+			expectedTicketCents: [][]int64{{1, 0}, {0}, {0}},
+		},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.name, func(t *testing.T) {
+			discount, err := promo_codes.CalculateDiscount(tc.promoCode, lines)
+
+			if err != nil {
+				t.Fatalf("CalculateDiscount: expected no error, got: %v", err)
+			}
+
+			if discount.TotalCents != tc.expectedTotalCents {
+				t.Errorf("Expected total discount %d, got %d", tc.expectedTotalCents, discount.TotalCents)
+			}
+
+			var ticketSum int64
+
+			for line, ticketCents := range discount.TicketCents {
+				for ticket, cents := range ticketCents {
+					ticketSum += cents
+
+					if cents != tc.expectedTicketCents[line][ticket] {
+						t.Errorf("Line %d ticket %d: expected discount %d, got %d", line, ticket, tc.expectedTicketCents[line][ticket], cents)
+					}
+				}
+			}
+
+			if ticketSum != discount.TotalCents {
+				t.Errorf("Expected ticket discounts to add up to %d, got %d", discount.TotalCents, ticketSum)
+			}
+		})
+	}
+}
+
+// TestCalculateDiscountReconcilesProperty checks on random orders that the ticket discounts add up to the total discount
+// and that refunding every ticket for what was paid for it gives back exactly the payment amount.
+func TestCalculateDiscountReconcilesProperty(t *testing.T) {
+	random := rand.New(rand.NewPCG(5, 6))
+	eventID := uuid.New()
+	discountTypes := []string{promo_codes.DiscountTypePercentage, promo_codes.DiscountTypeFixedAmount}
+
+	for i := 0; i < 5000; i++ {
+		lines := make([]promo_codes.TicketLine, 1+random.IntN(5))
+
+		for line := range lines {
+			lines[line] = promo_codes.TicketLine{
+				EventID:        eventID,
+				EventDetailID:  uuid.New(),
+				UnitPriceCents: random.Int64N(100_000),
+				Quantity:       1 + random.Int32N(10),
+			}
+		}
+
+		promoCode := database.PromoCode{
+			DiscountType:  discountTypes[random.IntN(len(discountTypes))],
+			DiscountValue: 1 + random.Int64N(10_000),
+			EventID:       eventID,
+		}
+
+		// Some codes only apply to the first ticket type.
+		if random.IntN(2) == 0 {
+			promoCode.EventDetailID = uuid.NullUUID{UUID: lines[0].EventDetailID, Valid: true}
+		}
+
+		discount, err := promo_codes.CalculateDiscount(promoCode, lines)
+
+		if err != nil {
+			t.Fatalf("CalculateDiscount: expected no error, got: %v", err)
+		}
+
+		var (
+			subtotal      int64
+			ticketSum     int64
+			refundedTotal int64
+		)
+
+		for line, ticketCents := range discount.TicketCents {
+			for _, cents := range ticketCents {
+				if cents < 0 || cents > lines[line].UnitPriceCents {
+					t.Fatalf("Line %d: ticket discount %d is outside 0 and the ticket price %d", line, cents, lines[line].UnitPriceCents)
+				}
+
+				subtotal += lines[line].UnitPriceCents
+				ticketSum += cents
+				refundedTotal += lines[line].UnitPriceCents - cents
+			}
+		}
+
+		if ticketSum != discount.TotalCents {
+			t.Fatalf("Expected ticket discounts to add up to %d, got %d", discount.TotalCents, ticketSum)
+		}
+
+		if paymentAmount := subtotal - discount.TotalCents; refundedTotal != paymentAmount {
+			t.Fatalf("Expected refunds to add up to the payment amount %d, got %d", paymentAmount, refundedTotal)
+		}
+	}
+}
+
+func TestCheckApplicable(tTesting *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	eventID := uuid.New()
+	eventDetailID := uuid.New()
+
+	lines := []promo_codes.TicketLine{
+		{EventID: eventID, EventDetailID: eventDetailID, UnitPriceCents: 1000, Quantity: 2},
+	}
+
+	tests := []struct {
+		name          string
+		promoCode     database.PromoCode
+		expectedError error
+	}{
+		{
+			name:      "Success",
+			promoCode: database.PromoCode{EventID: eventID, MinQuantity: 2, ValidUntil: sql.NullTime{Time: now.Add(time.Hour), Valid: true}},
+		},
+		{
+			name:          "Error_NotStarted",
+			promoCode:     database.PromoCode{EventID: eventID, MinQuantity: 1, ValidFrom: sql.NullTime{Time: now.Add(time.Minute), Valid: true}},
+			expectedError: promo_codes.ErrPromoCodeNotActive,
+		},
+		{
+			name:          "Error_Expired",
+			promoCode:     database.PromoCode{EventID: eventID, MinQuantity: 1, ValidUntil: sql.NullTime{Time: now, Valid: true}},
+			expectedError: promo_codes.ErrPromoCodeNotActive,
+		},
+		{
+			name:          "Error_OtherTicketType",
+			promoCode:     database.PromoCode{EventID: eventID, MinQuantity: 1, EventDetailID: uuid.NullUUID{UUID: uuid.New(), Valid: true}},
+			expectedError: promo_codes.ErrPromoCodeNotApplicable,
+		},
+		{
+			name:          "Error_BelowMinimumQuantity",
+			promoCode:     database.PromoCode{EventID: eventID, MinQuantity: 3},
+			expectedError: promo_codes.ErrPromoCodeMinimumQuantity,
+		},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.name, func(t *testing.T) {
+			err := promo_codes.CheckApplicable(tc.promoCode, lines, now)
+
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("Expected error %v, got: %v", tc.expectedError, err)
+			}
+
+			if tc.expectedError != nil && !errors.Is(err, promo_codes.ErrInvalidPromoCode) {
+				t.Errorf("Expected %v to wrap ErrInvalidPromoCode", err)
+			}
+		})
+	}
+}
\ No newline at end of file
@@ -0,0 +1,145 @@
+package promo_codes
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/elorenzorodz/event-mrs/event_staff"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (promoCodeAPIConfig *PromoCodeAPIConfig) GetPromoCodes(ginContext *gin.Context) {
+	eventID, parseEventIDError := uuid.Parse(ginContext.Param("eventId"))
+
+	if parseEventIDError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+
+		return
+	}
+
+	userID := ginContext.MustGet("userId").(uuid.UUID)
+
+	promoCodes, getPromoCodesError := promoCodeAPIConfig.Service.List(ginContext.Request.Context(), eventID, userID, ginContext.GetString("role"))
+
+	if getPromoCodesError != nil {
+		writePromoCodeError(ginContext, getPromoCodesError, "event not found")
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, promoCodes)
+}
+
+func (promoCodeAPIConfig *PromoCodeAPIConfig) CreatePromoCode(ginContext *gin.Context) {
+	eventID, parseEventIDError := uuid.Parse(ginContext.Param("eventId"))
+
+	if parseEventIDError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+
+		return
+	}
+
+	promoCodeParams := PromoCodeParameters{}
+
+	if parameterBindError := ginContext.ShouldBindJSON(&promoCodeParams); parameterBindError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "error parsing JSON, please check all required fields are present and/or numbers are not be quoted"})
+
+		return
+	}
+
+	userID := ginContext.MustGet("userId").(uuid.UUID)
+
+	createdPromoCode, createPromoCodeError := promoCodeAPIConfig.Service.Create(ginContext.Request.Context(), eventID, userID, ginContext.GetString("role"), promoCodeParams)
+
+	if createPromoCodeError != nil {
+		writePromoCodeError(ginContext, createPromoCodeError, "event not found")
+
+		return
+	}
+
+	ginContext.JSON(http.StatusCreated, createdPromoCode)
+}
+
+func (promoCodeAPIConfig *PromoCodeAPIConfig) UpdatePromoCode(ginContext *gin.Context) {
+	eventID, parseEventIDError := uuid.Parse(ginContext.Param("eventId"))
+
+	if parseEventIDError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+
+		return
+	}
+
+	promoCodeID, parsePromoCodeIDError := uuid.Parse(ginContext.Param("promoCodeId"))
+
+	if parsePromoCodeIDError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid promo code ID"})
+
+		return
+	}
+
+	promoCodeParams := PromoCodeParameters{}
+
+	if parameterBindError := ginContext.ShouldBindJSON(&promoCodeParams); parameterBindError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "error parsing JSON, please check all required fields are present and/or numbers are not be quoted"})
+
+		return
+	}
+
+	userID := ginContext.MustGet("userId").(uuid.UUID)
+
+	updatedPromoCode, updatePromoCodeError := promoCodeAPIConfig.Service.Update(ginContext.Request.Context(), eventID, promoCodeID, userID, ginContext.GetString("role"), promoCodeParams)
+
+	if updatePromoCodeError != nil {
+		writePromoCodeError(ginContext, updatePromoCodeError, "promo code not found")
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, updatedPromoCode)
+}
+
+func (promoCodeAPIConfig *PromoCodeAPIConfig) DeletePromoCode(ginContext *gin.Context) {
+	eventID, parseEventIDError := uuid.Parse(ginContext.Param("eventId"))
+
+	if parseEventIDError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+
+		return
+	}
+
+	promoCodeID, parsePromoCodeIDError := uuid.Parse(ginContext.Param("promoCodeId"))
+
+	if parsePromoCodeIDError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid promo code ID"})
+
+		return
+	}
+
+	userID := ginContext.MustGet("userId").(uuid.UUID)
+
+	if deletePromoCodeError := promoCodeAPIConfig.Service.Delete(ginContext.Request.Context(), eventID, promoCodeID, userID, ginContext.GetString("role")); deletePromoCodeError != nil {
+		writePromoCodeError(ginContext, deletePromoCodeError, "promo code not found")
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"message": "promo code deleted successfully"})
+}
+
+func writePromoCodeError(ginContext *gin.Context, promoCodeError error, notFoundMessage string) {
+	switch {
+	case errors.Is(promoCodeError, sql.ErrNoRows):
+		ginContext.JSON(http.StatusNotFound, gin.H{"error": notFoundMessage})
+	case errors.Is(promoCodeError, event_staff.ErrEventAccessDenied):
+		ginContext.JSON(http.StatusForbidden, gin.H{"error": promoCodeError.Error()})
+	case errors.Is(promoCodeError, ErrPromoCodeAlreadyExists):
+		ginContext.JSON(http.StatusConflict, gin.H{"error": promoCodeError.Error()})
+	case errors.Is(promoCodeError, ErrInvalidCode), errors.Is(promoCodeError, ErrInvalidDiscountType), errors.Is(promoCodeError, ErrInvalidDiscountValue),
+		errors.Is(promoCodeError, ErrInvalidUsageLimit), errors.Is(promoCodeError, ErrInvalidValidityDate), errors.Is(promoCodeError, ErrInvalidValidityWindow), errors.Is(promoCodeError, ErrEventDetailNotFound):
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": promoCodeError.Error()})
+	default:
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": promoCodeError.Error()})
+	}
+}
\ No newline at end of file
@@ -0,0 +1,69 @@
+package promo_codes
+
+import (
+	"context"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/google/uuid"
+)
+
+type PromoCodeAPIConfig struct {
+	Service PromoCodeService
+}
+
+type PromoCodeService interface {
+	List(ctx context.Context, eventID, userID uuid.UUID, userRole string) ([]PromoCode, error)
+	Create(ctx context.Context, eventID, userID uuid.UUID, userRole string, req PromoCodeParameters) (*PromoCode, error)
+	Update(ctx context.Context, eventID, promoCodeID, userID uuid.UUID, userRole string, req PromoCodeParameters) (*PromoCode, error)
+	Delete(ctx context.Context, eventID, promoCodeID, userID uuid.UUID, userRole string) error
+}
+
+type Service struct {
+	DBQueries database.Queries
+}
+
+type PromoCode struct {
+	ID           uuid.UUID `json:"id"`
+	Code         string    `json:"code"`
+	DiscountType string    `json:"discount_type"`
+	// DiscountValue is in basis points for a percentage, 1250 is 12.50%, and in the minor unit of the currency for a fixed amount.
+	DiscountValue  int64      `json:"discount_value"`
+	MaxUses        *int32     `json:"max_uses"`
+	MaxUsesPerUser *int32     `json:"max_uses_per_user"`
+	MinQuantity    int32      `json:"min_quantity"`
+	ValidFrom      *time.Time `json:"valid_from"`
+	ValidUntil     *time.Time `json:"valid_until"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      string     `json:"updated_at"`
+	EventID        uuid.UUID  `json:"event_id"`
+	EventDetailID  *uuid.UUID `json:"event_detail_id"`
+}
+
+// PromoCodeParameters is used to create and update a promo code, the code itself can't be changed after creation.
+type PromoCodeParameters struct {
+	Code           string     `json:"code"`
+	DiscountType   string     `json:"discount_type" binding:"required"`
+	DiscountValue  int64      `json:"discount_value" binding:"required"`
+	MaxUses        *int32     `json:"max_uses"`
+	MaxUsesPerUser *int32     `json:"max_uses_per_user"`
+	MinQuantity    int32      `json:"min_quantity"`
+	ValidFrom      string     `json:"valid_from"`
+	ValidUntil     string     `json:"valid_until"`
+	EventDetailID  *uuid.UUID `json:"event_detail_id"`
+}
+
+// TicketLine is one entry of an order, the tickets of a single ticket type.
+type TicketLine struct {
+	EventID        uuid.UUID
+	EventDetailID  uuid.UUID
+	UnitPriceCents int64
+	Quantity       int32
+}
+
+// Discount is what a promo code takes off an order. TicketCents has one entry per ticket of each line, in line order.
+type Discount struct {
+	PromoCodeID uuid.UUID
+	TotalCents  int64
+	TicketCents [][]int64
+}
\ No newline at end of file
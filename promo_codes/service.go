@@ -0,0 +1,304 @@
+package promo_codes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/elorenzorodz/event-mrs/event_staff"
+	"github.com/elorenzorodz/event-mrs/internal/convert"
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/sqlutil"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidCode            = errors.New("invalid code, use 3 to 32 letters, numbers, dashes or underscores")
+	ErrInvalidDiscountType    = fmt.Errorf("invalid discount_type, must be one of: %s, %s", DiscountTypePercentage, DiscountTypeFixedAmount)
+	ErrInvalidDiscountValue   = errors.New("invalid discount_value, must be greater than 0 and a percentage can't be over 10000 basis points")
+	ErrInvalidUsageLimit      = errors.New("invalid max_uses, max_uses_per_user and min_quantity must be greater than 0")
+	ErrInvalidValidityDate    = errors.New("invalid valid_from/valid_until")
+	ErrInvalidValidityWindow  = errors.New("invalid valid_from/valid_until, valid_from must be before valid_until")
+	ErrEventDetailNotFound    = errors.New("invalid event_detail_id, ticket type not found for this event")
+	ErrPromoCodeAlreadyExists = errors.New("promo code already exists for this event")
+	promoCodePattern          = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+)
+
+func NewService(dbQueries database.Queries) PromoCodeService {
+	return &Service{
+		DBQueries: dbQueries,
+	}
+}
+
+func (service *Service) List(ctx context.Context, eventID, userID uuid.UUID, userRole string) ([]PromoCode, error) {
+	if _, authorizeError := event_staff.AuthorizeEvent(ctx, &service.DBQueries, eventID, userID, userRole, event_staff.PermissionManageTickets); authorizeError != nil {
+		return nil, authorizeError
+	}
+
+	promoCodes, getPromoCodesError := service.DBQueries.GetEventPromoCodes(ctx, eventID)
+
+	if getPromoCodesError != nil {
+		log.Printf("error fetching promo codes: %v", getPromoCodesError)
+
+		return nil, errors.New("error fetching promo codes")
+	}
+
+	return DatabasePromoCodesToPromoCodesJSON(promoCodes), nil
+}
+
+func (service *Service) Create(ctx context.Context, eventID, userID uuid.UUID, userRole string, req PromoCodeParameters) (*PromoCode, error) {
+	code := NormalizeCode(req.Code)
+
+	if !promoCodePattern.MatchString(code) {
+		return nil, ErrInvalidCode
+	}
+
+	promoCodeParams, validateError := validatePromoCodeParameters(req)
+
+	if validateError != nil {
+		return nil, validateError
+	}
+
+	if _, authorizeError := event_staff.AuthorizeEvent(ctx, &service.DBQueries, eventID, userID, userRole, event_staff.PermissionManageTickets); authorizeError != nil {
+		return nil, authorizeError
+	}
+
+	if eventDetailError := service.checkEventDetail(ctx, eventID, promoCodeParams.EventDetailID); eventDetailError != nil {
+		return nil, eventDetailError
+	}
+
+	createdPromoCode, createPromoCodeError := service.DBQueries.CreatePromoCode(ctx, database.CreatePromoCodeParams{
+		ID:             uuid.New(),
+		Code:           code,
+		DiscountType:   promoCodeParams.DiscountType,
+		DiscountValue:  promoCodeParams.DiscountValue,
+		MaxUses:        promoCodeParams.MaxUses,
+		MaxUsesPerUser: promoCodeParams.MaxUsesPerUser,
+		MinQuantity:    promoCodeParams.MinQuantity,
+		ValidFrom:      promoCodeParams.ValidFrom,
+		ValidUntil:     promoCodeParams.ValidUntil,
+		EventID:        eventID,
+		EventDetailID:  promoCodeParams.EventDetailID,
+	})
+
+	if createPromoCodeError != nil {
+		// Nothing is returned when the event already has the code.
+		if errors.Is(createPromoCodeError, sql.ErrNoRows) {
+			return nil, ErrPromoCodeAlreadyExists
+		}
+
+		log.Printf("error creating promo code: %v", createPromoCodeError)
+
+		return nil, errors.New("error creating promo code")
+	}
+
+	promoCode := DatabasePromoCodeToPromoCodeJSON(createdPromoCode)
+
+	return &promoCode, nil
+}
+
+func (service *Service) Update(ctx context.Context, eventID, promoCodeID, userID uuid.UUID, userRole string, req PromoCodeParameters) (*PromoCode, error) {
+	promoCodeParams, validateError := validatePromoCodeParameters(req)
+
+	if validateError != nil {
+		return nil, validateError
+	}
+
+	if _, authorizeError := event_staff.AuthorizeEvent(ctx, &service.DBQueries, eventID, userID, userRole, event_staff.PermissionManageTickets); authorizeError != nil {
+		return nil, authorizeError
+	}
+
+	if eventDetailError := service.checkEventDetail(ctx, eventID, promoCodeParams.EventDetailID); eventDetailError != nil {
+		return nil, eventDetailError
+	}
+
+	// Payments keep the discount they were given, changes only apply to new orders.
+	updatedPromoCode, updatePromoCodeError := service.DBQueries.UpdatePromoCode(ctx, database.UpdatePromoCodeParams{
+		DiscountType:   promoCodeParams.DiscountType,
+		DiscountValue:  promoCodeParams.DiscountValue,
+		MaxUses:        promoCodeParams.MaxUses,
+		MaxUsesPerUser: promoCodeParams.MaxUsesPerUser,
+		MinQuantity:    promoCodeParams.MinQuantity,
+		ValidFrom:      promoCodeParams.ValidFrom,
+		ValidUntil:     promoCodeParams.ValidUntil,
+		EventDetailID:  promoCodeParams.EventDetailID,
+		ID:             promoCodeID,
+		EventID:        eventID,
+	})
+
+	if updatePromoCodeError != nil {
+		if errors.Is(updatePromoCodeError, sql.ErrNoRows) {
+			return nil, updatePromoCodeError
+		}
+
+		log.Printf("error updating promo code: %v", updatePromoCodeError)
+
+		return nil, errors.New("error updating promo code")
+	}
+
+	promoCode := DatabasePromoCodeToPromoCodeJSON(updatedPromoCode)
+
+	return &promoCode, nil
+}
+
+func (service *Service) Delete(ctx context.Context, eventID, promoCodeID, userID uuid.UUID, userRole string) error {
+	if _, authorizeError := event_staff.AuthorizeEvent(ctx, &service.DBQueries, eventID, userID, userRole, event_staff.PermissionManageTickets); authorizeError != nil {
+		return authorizeError
+	}
+
+	deletedRows, deletePromoCodeError := service.DBQueries.DeletePromoCode(ctx, database.DeletePromoCodeParams{
+		ID:      promoCodeID,
+		EventID: eventID,
+	})
+
+	if deletePromoCodeError != nil {
+		log.Printf("error deleting promo code: %v", deletePromoCodeError)
+
+		return errors.New("error deleting promo code")
+	}
+
+	if deletedRows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (service *Service) checkEventDetail(ctx context.Context, eventID uuid.UUID, eventDetailID uuid.NullUUID) error {
+	if !eventDetailID.Valid {
+		return nil
+	}
+
+	eventDetail, getEventDetailError := service.DBQueries.GetEventDetailsById(ctx, eventDetailID.UUID)
+
+	if getEventDetailError != nil {
+		if errors.Is(getEventDetailError, sql.ErrNoRows) {
+			return ErrEventDetailNotFound
+		}
+
+		log.Printf("error fetching event detail for promo code: %v", getEventDetailError)
+
+		return errors.New("error fetching event detail")
+	}
+
+	if eventDetail.EventID != eventID {
+		return ErrEventDetailNotFound
+	}
+
+	return nil
+}
+
+func validatePromoCodeParameters(req PromoCodeParameters) (database.CreatePromoCodeParams, error) {
+	promoCodeParams := database.CreatePromoCodeParams{
+		DiscountType:  strings.ToLower(strings.TrimSpace(req.DiscountType)),
+		DiscountValue: req.DiscountValue,
+		MinQuantity:   req.MinQuantity,
+	}
+
+	if promoCodeParams.DiscountType != DiscountTypePercentage && promoCodeParams.DiscountType != DiscountTypeFixedAmount {
+		return promoCodeParams, ErrInvalidDiscountType
+	}
+
+	if req.DiscountValue <= 0 || (promoCodeParams.DiscountType == DiscountTypePercentage && req.DiscountValue > 10000) {
+		return promoCodeParams, ErrInvalidDiscountValue
+	}
+
+	if promoCodeParams.MinQuantity == 0 {
+		promoCodeParams.MinQuantity = 1
+	}
+
+	if promoCodeParams.MinQuantity < 0 || (req.MaxUses != nil && *req.MaxUses <= 0) || (req.MaxUsesPerUser != nil && *req.MaxUsesPerUser <= 0) {
+		return promoCodeParams, ErrInvalidUsageLimit
+	}
+
+	if req.MaxUses != nil {
+		promoCodeParams.MaxUses = sql.NullInt32{Int32: *req.MaxUses, Valid: true}
+	}
+
+	if req.MaxUsesPerUser != nil {
+		promoCodeParams.MaxUsesPerUser = sql.NullInt32{Int32: *req.MaxUsesPerUser, Valid: true}
+	}
+
+	var parseDateError error
+
+	if promoCodeParams.ValidFrom, parseDateError = parseOptionalDate(req.ValidFrom, "valid_from"); parseDateError != nil {
+		return promoCodeParams, parseDateError
+	}
+
+	if promoCodeParams.ValidUntil, parseDateError = parseOptionalDate(req.ValidUntil, "valid_until"); parseDateError != nil {
+		return promoCodeParams, parseDateError
+	}
+
+	if promoCodeParams.ValidFrom.Valid && promoCodeParams.ValidUntil.Valid && !promoCodeParams.ValidFrom.Time.Before(promoCodeParams.ValidUntil.Time) {
+		return promoCodeParams, ErrInvalidValidityWindow
+	}
+
+	if req.EventDetailID != nil {
+		promoCodeParams.EventDetailID = uuid.NullUUID{UUID: *req.EventDetailID, Valid: true}
+	}
+
+	return promoCodeParams, nil
+}
+
+func parseOptionalDate(value string, field string) (sql.NullTime, error) {
+	if strings.TrimSpace(value) == "" {
+		return sql.NullTime{}, nil
+	}
+
+	parsedDate, dateFormat, parseDateError := convert.StringToTime(strings.TrimSpace(value))
+
+	if parseDateError != nil {
+		return sql.NullTime{}, fmt.Errorf("%w: %s must use the format %s", ErrInvalidValidityDate, field, dateFormat)
+	}
+
+	return sql.NullTime{Time: parsedDate, Valid: true}, nil
+}
+
+func DatabasePromoCodeToPromoCodeJSON(databasePromoCode database.PromoCode) PromoCode {
+	promoCode := PromoCode{
+		ID:            databasePromoCode.ID,
+		Code:          databasePromoCode.Code,
+		DiscountType:  databasePromoCode.DiscountType,
+		DiscountValue: databasePromoCode.DiscountValue,
+		MinQuantity:   databasePromoCode.MinQuantity,
+		CreatedAt:     databasePromoCode.CreatedAt,
+		UpdatedAt:     sqlutil.NullTimeToString(databasePromoCode.UpdatedAt),
+		EventID:       databasePromoCode.EventID,
+	}
+
+	if databasePromoCode.MaxUses.Valid {
+		promoCode.MaxUses = &databasePromoCode.MaxUses.Int32
+	}
+
+	if databasePromoCode.MaxUsesPerUser.Valid {
+		promoCode.MaxUsesPerUser = &databasePromoCode.MaxUsesPerUser.Int32
+	}
+
+	if databasePromoCode.ValidFrom.Valid {
+		promoCode.ValidFrom = &databasePromoCode.ValidFrom.Time
+	}
+
+	if databasePromoCode.ValidUntil.Valid {
+		promoCode.ValidUntil = &databasePromoCode.ValidUntil.Time
+	}
+
+	if databasePromoCode.EventDetailID.Valid {
+		promoCode.EventDetailID = &databasePromoCode.EventDetailID.UUID
+	}
+
+	return promoCode
+}
+
+func DatabasePromoCodesToPromoCodesJSON(databasePromoCodes []database.PromoCode) []PromoCode {
+	promoCodes := make([]PromoCode, len(databasePromoCodes))
+
+	for i, databasePromoCode := range databasePromoCodes {
+		promoCodes[i] = DatabasePromoCodeToPromoCodeJSON(databasePromoCode)
+	}
+
+	return promoCodes
+}
\ No newline at end of file
@@ -6,9 +6,11 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/elorenzorodz/event-mrs/event_details"
+	"github.com/elorenzorodz/event-mrs/internal/paymentgateway"
+	"github.com/elorenzorodz/event-mrs/promo_codes"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/stripe/stripe-go/v83"
 )
 
 func (reservationAPIConfig *ReservationAPIConfig) CreateReservation(ginContext *gin.Context) {
@@ -34,9 +36,11 @@ func (reservationAPIConfig *ReservationAPIConfig) CreateReservation(ginContext *
 	if createError != nil {
 		status := http.StatusInternalServerError
 
-		if errors.Is(createError, ErrInsufficientTickets) || strings.Contains(createError.Error(), "not found") {
+		if errors.Is(createError, promo_codes.ErrInvalidPromoCode) {
+			status = http.StatusUnprocessableEntity
+		} else if errors.Is(createError, ErrInsufficientTickets) || errors.Is(createError, ErrEventNotBookable) || isTicketNotOnSale(createError) || strings.Contains(createError.Error(), "not found") {
 			status = http.StatusConflict
-		} else if strings.Contains(createError.Error(), "required") || strings.Contains(createError.Error(), "invalid") {
+		} else if errors.Is(createError, ErrMixedCurrencies) || strings.Contains(createError.Error(), "required") || strings.Contains(createError.Error(), "invalid") {
 			status = http.StatusBadRequest
 		}
 
@@ -48,7 +52,7 @@ func (reservationAPIConfig *ReservationAPIConfig) CreateReservation(ginContext *
 	responseStatus := http.StatusCreated
 
 	switch paymentResponse.Status {
-	case string(stripe.PaymentIntentStatusRequiresAction), string(stripe.PaymentIntentStatusRequiresPaymentMethod):
+	case string(paymentgateway.IntentStatusRequiresAction), string(paymentgateway.IntentStatusRequiresPaymentMethod):
 		responseStatus = http.StatusAccepted
 	}
 
@@ -142,4 +146,83 @@ func (reservationAPIConfig *ReservationAPIConfig) UpdateReservationEmail(ginCont
 	}
 
 	ginContext.JSON(http.StatusOK, updatedReservation)
+}
+
+func (reservationAPIConfig *ReservationAPIConfig) GetReservationTicket(ginContext *gin.Context) {
+	userID := ginContext.MustGet("userId").(uuid.UUID)
+
+	reservationID, parseReservationIDError := uuid.Parse(ginContext.Param("reservationId"))
+
+	if parseReservationIDError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid reservation ID"})
+
+		return
+	}
+
+	qrCode, getTicketError := reservationAPIConfig.Service.GetReservationTicket(ginContext.Request.Context(), reservationID, userID)
+
+	if getTicketError != nil {
+		if errors.Is(getTicketError, sql.ErrNoRows) {
+			ginContext.JSON(http.StatusNotFound, gin.H{"error": "reservation not found"})
+
+			return
+		}
+
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": getTicketError.Error()})
+
+		return
+	}
+
+	ginContext.Data(http.StatusOK, "image/png", qrCode)
+}
+
+func (reservationAPIConfig *ReservationAPIConfig) CheckIn(ginContext *gin.Context) {
+	userID := ginContext.MustGet("userId").(uuid.UUID)
+
+	eventID, parseEventIDError := uuid.Parse(ginContext.Param("eventId"))
+
+	if parseEventIDError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+
+		return
+	}
+
+	checkInParams := CheckInParameters{}
+
+	if parameterBindError := ginContext.ShouldBindJSON(&checkInParams); parameterBindError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "error parsing JSON, please check all required fields are present"})
+
+		return
+	}
+
+	checkIn, checkInError := reservationAPIConfig.Service.CheckIn(ginContext.Request.Context(), eventID, userID, ginContext.GetString("role"), checkInParams.Ticket)
+
+	if checkInError != nil {
+		if errors.Is(checkInError, sql.ErrNoRows) {
+			ginContext.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+
+			return
+		}
+
+		status := http.StatusInternalServerError
+
+		switch {
+		case errors.Is(checkInError, ErrCheckInNotAllowed):
+			status = http.StatusForbidden
+		case errors.Is(checkInError, ErrInvalidTicket), errors.Is(checkInError, ErrTicketWrongEvent), errors.Is(checkInError, ErrTicketNotPaid):
+			status = http.StatusUnprocessableEntity
+		case errors.Is(checkInError, ErrTicketAlreadyCheckedIn):
+			status = http.StatusConflict
+		}
+
+		ginContext.JSON(status, gin.H{"error": checkInError.Error()})
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, checkIn)
+}
+
+func isTicketNotOnSale(createError error) bool {
+	return errors.Is(createError, event_details.ErrTicketSalesNotStarted) || errors.Is(createError, event_details.ErrTicketSalesEnded) || errors.Is(createError, event_details.ErrTicketTierLocked)
 }
\ No newline at end of file
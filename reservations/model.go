@@ -5,10 +5,13 @@ import (
 	"database/sql"
 	"time"
 
+	"github.com/elorenzorodz/event-mrs/event_staff"
 	"github.com/elorenzorodz/event-mrs/internal/database"
 	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/elorenzorodz/event-mrs/internal/paymentgateway"
+	"github.com/elorenzorodz/event-mrs/internal/tickets"
+	"github.com/elorenzorodz/event-mrs/waitlist"
 	"github.com/google/uuid"
-	"github.com/stripe/stripe-go/v83"
 )
 
 type ReservationAPIConfig struct {
@@ -16,21 +19,25 @@ type ReservationAPIConfig struct {
 }
 
 type Reservation struct {
-	ID            uuid.UUID `json:"id"`
-	Email         string    `json:"email"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     string    `json:"updated_at"`
-	EventDetailID uuid.UUID `json:"event_detail_id"`
-	UserID        uuid.UUID `json:"user_id"`
-	PaymentID     uuid.UUID `json:"payment_id"`
+	ID            uuid.UUID  `json:"id"`
+	Email         string     `json:"email"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     string     `json:"updated_at"`
+	EventDetailID uuid.UUID  `json:"event_detail_id"`
+	UserID        uuid.UUID  `json:"user_id"`
+	PaymentID     uuid.UUID  `json:"payment_id"`
+	CheckedInAt   *time.Time `json:"checked_in_at,omitempty"`
 }
 
 // Note: If email isn't provided here, try to get from current user.
 type ReservationParameters struct {
 	Email                   string                   `json:"email"`
-	Currency                string                   `json:"currency"`
 	PaymentMethodID         string                   `json:"payment_method_id" binding:"required"`
 	EventDetailReservations []EventDetailReservation `json:"event_detail_reservations" binding:"required"`
+	// WaitlistClaimToken books the tickets held by a waitlist offer, it comes from the offer email.
+	WaitlistClaimToken string `json:"waitlist_claim_token"`
+	// PromoCode is optional, its discount is split over the tickets it applies to.
+	PromoCode string `json:"promo_code"`
 }
 
 // Note: If email isn't provided here, try to get from ReservationParameters.
@@ -44,6 +51,18 @@ type PatchReservationParameters struct {
 	Email string `json:"email" binding:"required"`
 }
 
+type CheckInParameters struct {
+	Ticket string `json:"ticket" binding:"required"`
+}
+
+type CheckInResponse struct {
+	ReservationID     uuid.UUID `json:"reservation_id"`
+	Email             string    `json:"email"`
+	TicketDescription string    `json:"ticket_description"`
+	ShowDate          time.Time `json:"show_date"`
+	CheckedInAt       time.Time `json:"checked_in_at"`
+}
+
 type ReservationResponse struct {
 	Reservations   []Reservation `json:"reservations"`
 	PaymentID      uuid.UUID     `json:"payment_id"`
@@ -61,22 +80,28 @@ type PaymentResponse struct {
 	ExpiresAt    time.Time `json:"expires_at"`
 }
 
-type StripeClient interface {
-	CreatePaymentIntent(amount int64, currency string, paymentMethodID string, paymentId uuid.UUID) (*stripe.PaymentIntent, error)
-}
-
 type ReservationService interface {
 	CreateReservations(ctx context.Context, userId uuid.UUID, userEmail string, reservations ReservationParameters) ([]Reservation, PaymentResponse, error)
 	GetUserReservations(ctx context.Context, userID uuid.UUID) ([]Reservation, error)
 	GetUserReservationByID(ctx context.Context, reservationID, userID uuid.UUID) (*Reservation, error)
 	UpdateReservationEmail(ctx context.Context, reservationID, userID uuid.UUID, email string) (*Reservation, error)
+	GetReservationTicket(ctx context.Context, reservationID, userID uuid.UUID) ([]byte, error)
+	CheckIn(ctx context.Context, eventID, staffUserID uuid.UUID, staffRole string, ticketToken string) (*CheckInResponse, error)
 }
 
 type Service struct {
-	DBQueries    database.Queries
-	DBConnection *sql.DB
-	Stripe       StripeClient
-	Mailer       *mailer.Mailer
+	DBQueries      database.Queries
+	DBConnection   *sql.DB
+	PaymentGateway paymentgateway.Gateway
+	Mailer         mailer.Mailer
+	CheckIns       CheckInQueries
+	Tickets        *tickets.Issuer
+	Waitlist       *waitlist.Service
 }
 
-type StripeAPIClient struct{}
\ No newline at end of file
+// CheckInQueries is what CheckIn reads and writes, *database.Queries in the app.
+type CheckInQueries interface {
+	event_staff.EventAccessQueries
+	CheckInReservation(ctx context.Context, arg database.CheckInReservationParams) (database.Reservation, error)
+	GetReservationForCheckIn(ctx context.Context, id uuid.UUID) (database.GetReservationForCheckInRow, error)
+}
\ No newline at end of file
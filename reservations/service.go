@@ -6,17 +6,23 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"slices"
 	"strings"
 	"time"
 
-	"github.com/elorenzorodz/event-mrs/internal/convert"
+	"github.com/elorenzorodz/event-mrs/event_details"
+	"github.com/elorenzorodz/event-mrs/event_staff"
 	"github.com/elorenzorodz/event-mrs/internal/database"
 	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/elorenzorodz/event-mrs/internal/money"
+	"github.com/elorenzorodz/event-mrs/internal/paymentgateway"
+	"github.com/elorenzorodz/event-mrs/internal/paymentstatus"
 	"github.com/elorenzorodz/event-mrs/internal/sqlutil"
+	"github.com/elorenzorodz/event-mrs/internal/tickets"
 	"github.com/elorenzorodz/event-mrs/payments"
+	"github.com/elorenzorodz/event-mrs/promo_codes"
+	"github.com/elorenzorodz/event-mrs/waitlist"
 	"github.com/google/uuid"
-	"github.com/stripe/stripe-go/v83"
-	"github.com/stripe/stripe-go/v83/paymentintent"
 )
 
 var (
@@ -24,33 +30,28 @@ var (
 	ErrTicketNotFound      = errors.New("event detail not found")
 	ErrPaymentFailed       = errors.New("payment failed, please check your method or try again")
 	ErrInternalError       = errors.New("an internal error occurred")
+	ErrEventNotBookable    = errors.New("event is not open for booking")
+	ErrMixedCurrencies     = errors.New("tickets priced in different currencies must be booked separately")
+
+	ErrInvalidTicket          = errors.New("invalid ticket")
+	ErrTicketWrongEvent       = errors.New("ticket is for a different event")
+	ErrTicketNotPaid          = errors.New("ticket has not been paid")
+	ErrTicketAlreadyCheckedIn = errors.New("ticket has already been checked in")
+	ErrCheckInNotAllowed      = errors.New("not allowed to check in tickets for this event")
 )
 
-func NewService(dbQueries database.Queries, dbConn *sql.DB, mMailer *mailer.Mailer, stripeClient StripeClient) ReservationService {
+func NewService(dbQueries database.Queries, dbConn *sql.DB, mMailer mailer.Mailer, paymentGateway paymentgateway.Gateway, ticketIssuer *tickets.Issuer, waitlistService *waitlist.Service) ReservationService {
 	return &Service{
-		DBQueries:    dbQueries,
-		DBConnection: dbConn,
-		Mailer:       mMailer,
-		Stripe:       stripeClient,
+		DBQueries:      dbQueries,
+		DBConnection:   dbConn,
+		Mailer:         mMailer,
+		PaymentGateway: paymentGateway,
+		CheckIns:       &dbQueries,
+		Tickets:        ticketIssuer,
+		Waitlist:       waitlistService,
 	}
 }
 
-func (stripeAPIClient *StripeAPIClient) CreatePaymentIntent(amount int64, currency string, paymentMethodID string, paymentId uuid.UUID) (*stripe.PaymentIntent, error) {
-	paymentIntentParams := &stripe.PaymentIntentParams{
-		Amount:        stripe.Int64(amount),
-		Currency:      stripe.String(strings.ToLower(currency)),
-		Confirm:       stripe.Bool(true),
-		PaymentMethod: stripe.String(paymentMethodID),
-		AutomaticPaymentMethods: &stripe.PaymentIntentAutomaticPaymentMethodsParams{
-			Enabled:        stripe.Bool(true),
-			AllowRedirects: stripe.String("never"),
-		},
-		Metadata: map[string]string{"payment_id": paymentId.String()},
-	}
-
-	return paymentintent.New(paymentIntentParams)
-}
-
 func (service *Service) CreateReservations(ctx context.Context, userId uuid.UUID, userEmail string, reservations ReservationParameters) ([]Reservation, PaymentResponse, error) {
 	var totalTickets int32 = 0
 
@@ -67,19 +68,31 @@ func (service *Service) CreateReservations(ctx context.Context, userId uuid.UUID
 		totalPrice      int64
 	)
 
-	currency := reservations.Currency
+	// Tickets held for a waitlist offer are out of tickets_remaining until the offer is claimed below.
+	heldTickets := map[uuid.UUID]int32{}
+	var waitlistOffer database.WaitlistEntry
+
+	if strings.TrimSpace(reservations.WaitlistClaimToken) != "" {
+		claimableOffer, getOfferError := service.Waitlist.GetClaimableOffer(ctx, strings.TrimSpace(reservations.WaitlistClaimToken), userId)
 
-	if strings.TrimSpace(currency) == "" {
-		currency = "usd"
+		if getOfferError != nil {
+			return nil, PaymentResponse{}, getOfferError
+		}
+
+		waitlistOffer = claimableOffer
+		heldTickets[waitlistOffer.EventDetailID] = waitlistOffer.Quantity
 	}
 
-	eventDetails, totalPrice, priceError := validateAndCalculatePrice(&service.DBQueries, ctx, reservations)
+	eventDetails, totalPrice, priceError := validateAndCalculatePrice(&service.DBQueries, ctx, reservations, heldTickets)
 
 	if priceError != nil {
 		// If validation fails (e.g., tickets sold out), exit immediately with the error.
 		return nil, PaymentResponse{}, priceError
 	}
 
+	// Every ticket in the cart is in the same currency, validateAndCalculatePrice rejects mixed carts.
+	currency := money.NormalizeCurrency(eventDetails[0].Currency)
+
 	var newPayment database.Payment
 	var createPaymentError error
 
@@ -93,14 +106,33 @@ func (service *Service) CreateReservations(ctx context.Context, userId uuid.UUID
 	defer tx.Rollback()
 	qtx := service.DBQueries.WithTx(tx)
 
+	// The promo code is checked in the transaction so its usage limits can't be exceeded by orders at the same time.
+	var discount promo_codes.Discount
+
+	if strings.TrimSpace(reservations.PromoCode) != "" {
+		appliedDiscount, applyPromoCodeError := promo_codes.Apply(ctx, qtx, reservations.PromoCode, userId, buildTicketLines(eventDetails, reservations), time.Now())
+
+		if applyPromoCodeError != nil {
+			return nil, PaymentResponse{}, applyPromoCodeError
+		}
+
+		discount = appliedDiscount
+		totalPrice -= discount.TotalCents
+	}
+
 	// Create PENDING Payment record with the now known FINAL price.
 	createPaymentParams := database.CreatePaymentParams{
-		ID:        uuid.New(),
-		Amount:    fmt.Sprintf("%.2f", float64(totalPrice)/100.0),
-		Currency:  currency,
-		Status:    "pending",
-		UserID:    userId,
-		ExpiresAt: time.Now().Add(15 * time.Minute),
+		ID:             uuid.New(),
+		Amount:         totalPrice,
+		Currency:       currency,
+		Status:         string(paymentstatus.Pending),
+		UserID:         userId,
+		ExpiresAt:      time.Now().Add(15 * time.Minute),
+		DiscountAmount: discount.TotalCents,
+	}
+
+	if discount.PromoCodeID != uuid.Nil {
+		createPaymentParams.PromoCodeID = uuid.NullUUID{UUID: discount.PromoCodeID, Valid: true}
 	}
 
 	// Capture the result to get the final Payment ID.
@@ -112,8 +144,33 @@ func (service *Service) CreateReservations(ctx context.Context, userId uuid.UUID
 		return nil, PaymentResponse{}, fmt.Errorf("internal database error creating payment")
 	}
 
+	// Claiming puts the held tickets back so the reservations below take them, other buyers are blocked by the row lock until commit.
+	if waitlistOffer.ID != uuid.Nil {
+		_, claimOfferError := qtx.ClaimWaitlistOffer(ctx, database.ClaimWaitlistOfferParams{
+			ID:     waitlistOffer.ID,
+			UserID: userId,
+		})
+
+		if claimOfferError != nil {
+			if errors.Is(claimOfferError, sql.ErrNoRows) {
+				return nil, PaymentResponse{}, waitlist.ErrInvalidClaimToken
+			}
+
+			log.Printf("Error claiming waitlist offer %s: %v", waitlistOffer.ID, claimOfferError)
+
+			return nil, PaymentResponse{}, fmt.Errorf("internal database error claiming waitlist offer")
+		}
+	}
+
+	// Reservations keep the price charged now, refunds return it even after the price schedule moved on.
+	ticketPrices := make(map[uuid.UUID]int64)
+
+	for _, eventDetail := range eventDetails {
+		ticketPrices[eventDetail.ID] = eventDetail.Price
+	}
+
 	// Reserve tickets sequentially.
-	for _, edReservation := range reservations.EventDetailReservations {
+	for line, edReservation := range reservations.EventDetailReservations {
 		emailReservation := edReservation.Email
 
 		if strings.TrimSpace(emailReservation) == "" {
@@ -122,12 +179,20 @@ func (service *Service) CreateReservations(ctx context.Context, userId uuid.UUID
 
 		// Loop once for each ticket quantity requested for this event detail.
 		for x := 0; x < int(edReservation.Quantity); x++ {
+			var ticketDiscountCents int64
+
+			if discount.TicketCents != nil {
+				ticketDiscountCents = discount.TicketCents[line][x]
+			}
+
 			reserveTicketParams := database.ReserveTicketParams{
-				EventDetailID: edReservation.EventDetailID,
-				ReservationID: uuid.New(),
-				Email:         emailReservation,
-				UserID:        userId,
-				PaymentID:     newPayment.ID,
+				EventDetailID:  edReservation.EventDetailID,
+				ReservationID:  uuid.New(),
+				Email:          emailReservation,
+				UserID:         userId,
+				PaymentID:      newPayment.ID,
+				DiscountAmount: ticketDiscountCents,
+				Price:          ticketPrices[edReservation.EventDetailID],
 			}
 
 			// The database's ReserveTicket SQL query handles the `tickets_remaining > 0` check.
@@ -149,6 +214,11 @@ func (service *Service) CreateReservations(ctx context.Context, userId uuid.UUID
 		return nil, PaymentResponse{}, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	// Held tickets the claim didn't use go to the next person in line.
+	if waitlistOffer.ID != uuid.Nil {
+		service.Waitlist.OfferReleasedTickets(ctx, []uuid.UUID{waitlistOffer.EventDetailID})
+	}
+
 	// Convert the successfully created database payment record to the external JSON type.
 	userPayment := payments.DatabasePaymentToPaymentJSON(newPayment)
 
@@ -160,24 +230,31 @@ func (service *Service) CreateReservations(ctx context.Context, userId uuid.UUID
 
 	if totalPrice > 0 {
 		// Tickets reserved are not free.
-		// Log creation (before calling Stripe).
+		// Log creation (before calling the payment gateway).
 		createPaymentLogParams := database.CreatePaymentLogParams{
 			ID:              uuid.New(),
 			PaymentMethodID: sqlutil.StringToNullString(reservations.PaymentMethodID),
-			Amount:          fmt.Sprintf("%.2f", float64(totalPrice)/100.0),
+			Amount:          totalPrice,
 			UserEmail:       userEmail,
 			PaymentID:       userPayment.ID,
+			Currency:        currency,
 		}
 
-		paymentIntentResult, paymentIntentError := service.Stripe.CreatePaymentIntent(totalPrice, userPayment.Currency, reservations.PaymentMethodID, userPayment.ID)
+		paymentIntentResult, paymentIntentError := service.PaymentGateway.CreateIntent(ctx, paymentgateway.CreateIntentParams{
+			Amount:          totalPrice,
+			Currency:        currency,
+			PaymentMethodID: reservations.PaymentMethodID,
+			PaymentID:       userPayment.ID,
+			IdempotencyKey:  paymentIntentIdempotencyKey(userPayment.ID),
+		})
 
 		if paymentIntentError != nil {
-			if stripeErr, ok := paymentIntentError.(*stripe.Error); ok {
-				paymentResponse.Status = *stripe.String(stripeErr.Code)
-				paymentResponse.Message = *stripe.String(stripeErr.Msg)
+			if gatewayError, ok := paymentgateway.AsError(paymentIntentError); ok {
+				paymentResponse.Status = gatewayError.Code
+				paymentResponse.Message = gatewayError.Message
 
-				if stripeErr.PaymentIntent != nil {
-					paymentIntentId = stripeErr.PaymentIntent.ID
+				if gatewayError.Intent != nil {
+					paymentIntentId = gatewayError.Intent.ID
 				}
 			}
 		} else {
@@ -186,13 +263,11 @@ func (service *Service) CreateReservations(ctx context.Context, userId uuid.UUID
 				paymentIntentId = paymentIntentResult.ID
 				paymentResponse.Message = "payment successful"
 
-				if paymentResponse.Status == string(stripe.PaymentIntentStatusRequiresAction) {
+				if paymentResponse.Status == string(paymentgateway.IntentStatusRequiresAction) {
 					paymentResponse.ClientSecret = paymentIntentResult.ClientSecret
 
-					if paymentIntentResult.NextAction != nil {
-						paymentResponse.NextAction = string(paymentIntentResult.NextAction.Type)
-					}
-				} else if paymentResponse.Status != string(stripe.PaymentIntentStatusSucceeded) {
+					paymentResponse.NextAction = paymentIntentResult.NextAction
+				} else if paymentResponse.Status != string(paymentgateway.IntentStatusSucceeded) {
 					// Keep the default success message unless the status indicates failure/pending.
 					paymentResponse.Message = "please refer to next action and status"
 				}
@@ -214,40 +289,53 @@ func (service *Service) CreateReservations(ctx context.Context, userId uuid.UUID
 	} else {
 		// For free tickets, explicitly mark the payment record as succeeded.
 		// This status will be used to update the payments table later.
-		paymentResponse.Status = string(stripe.PaymentIntentStatusSucceeded)
+		paymentResponse.Status = string(paymentgateway.IntentStatusSucceeded)
 		paymentResponse.Message = "free reservation successful"
 	}
 
 	// Handle non-succeeded payment statuses
-	if paymentResponse.Status != string(stripe.PaymentIntentStatusSucceeded) {
+	if paymentResponse.Status != string(paymentgateway.IntentStatusSucceeded) {
 		switch paymentResponse.Status {
-		case string(stripe.PaymentIntentStatusRequiresAction):
+		case string(paymentgateway.IntentStatusRequiresAction):
 			paymentResponse.Message = "complete payment within next 15 minutes"
 
-		case string(stripe.PaymentIntentStatusCanceled):
+		case string(paymentgateway.IntentStatusCanceled):
 			paymentResponse.Message = "payment expired, please rebook your tickets"
 
-			// Perform necessary cleanup: Restore tickets and delete the payment record.
-			deletePaymentParams := database.RestoreTicketsAndDeletePaymentParams{
+			// Perform necessary cleanup: Restore tickets and cancel the payment, it's kept with its status history.
+			releaseReservationsError := service.DBQueries.ReleasePaymentReservations(ctx, database.ReleasePaymentReservationsParams{
 				PaymentID: userPayment.ID,
 				UserID:    userId,
-			}
+			})
 
-			deletePaymentError := service.DBQueries.RestoreTicketsAndDeletePayment(ctx, deletePaymentParams)
+			if releaseReservationsError != nil {
+				log.Printf("error restoring tickets after cancellation: %v", releaseReservationsError)
 
-			if deletePaymentError != nil {
-				log.Printf("error restoring tickets and deleting payment after cancellation: %v", deletePaymentError)
+				return nil, paymentResponse, fmt.Errorf("payment canceled, failed to clean up database: %w", releaseReservationsError)
+			}
 
-				return nil, paymentResponse, fmt.Errorf("payment canceled, failed to clean up database: %w", deletePaymentError)
+			_, cancelPaymentError := paymentstatus.Update(ctx, &service.DBQueries, database.UpdatePaymentParams{
+				Amount:          totalPrice,
+				Status:          string(paymentstatus.Cancelled),
+				PaymentIntentID: sqlutil.StringToNullString(paymentIntentId),
+				ID:              userPayment.ID,
+				UserID:          userId,
+				CurrentStatus:   userPayment.Status,
+			})
+
+			if cancelPaymentError != nil {
+				log.Printf("error cancelling payment %s: %v", userPayment.ID, cancelPaymentError)
 			}
 
+			service.Waitlist.OfferReleasedTickets(ctx, reservedEventDetailIDs(reservations))
+
 			// Return immediately on confirmed failure/cancellation after cleanup.
 			return nil, paymentResponse, fmt.Errorf("payment has been canceled, please rebook")
 
-		case string(stripe.PaymentIntentStatusProcessing):
+		case string(paymentgateway.IntentStatusProcessing):
 			paymentResponse.Message = "payment processing, we'll send you an email once payment succeeded"
 
-		case string(stripe.PaymentIntentStatusRequiresPaymentMethod):
+		case string(paymentgateway.IntentStatusRequiresPaymentMethod):
 			paymentResponse.Message = "please submit new payment method"
 		}
 	} else {
@@ -262,7 +350,19 @@ func (service *Service) CreateReservations(ctx context.Context, userId uuid.UUID
 			log.Printf("error fetching user for email: %v", getUserError)
 		}
 
-		sendEmailError := service.Mailer.SendPaymentConfirmationAndTicketReservation(fullName, userEmail, eventDetails)
+		reservationIDs := make([]uuid.UUID, len(newReservations))
+
+		for i, newReservation := range newReservations {
+			reservationIDs[i] = newReservation.ID
+		}
+
+		ticketAttachments, ticketAttachmentsError := service.Tickets.Attachments(reservationIDs)
+
+		if ticketAttachmentsError != nil {
+			log.Printf("error rendering ticket QR codes: %v", ticketAttachmentsError)
+		}
+
+		sendEmailError := service.Mailer.SendPaymentConfirmationAndTicketReservation(fullName, userEmail, eventDetails, money.New(totalPrice, currency), ticketAttachments)
 
 		if sendEmailError != nil {
 			log.Printf("error sending confirmation email: %v", sendEmailError)
@@ -271,14 +371,15 @@ func (service *Service) CreateReservations(ctx context.Context, userId uuid.UUID
 
 	// This ensures the payments table reflects the final status and payment intent ID.
 	updatePaymentParams := database.UpdatePaymentParams{
-		Amount:          fmt.Sprintf("%.2f", float64(totalPrice)/100.0),
-		Status:          paymentResponse.Status,
+		Amount:          totalPrice,
+		Status:          string(paymentstatus.FromIntentStatus(paymentResponse.Status)),
 		PaymentIntentID: sqlutil.StringToNullString(paymentIntentId),
 		ID:              userPayment.ID,
 		UserID:          userId,
+		CurrentStatus:   userPayment.Status,
 	}
 
-	_, updatePaymentError := service.DBQueries.UpdatePayment(ctx, updatePaymentParams)
+	_, updatePaymentError := paymentstatus.Update(ctx, &service.DBQueries, updatePaymentParams)
 
 	if updatePaymentError != nil {
 		// Log this error, but do not fail the function, as tickets are reserved.
@@ -344,7 +445,147 @@ func (service *Service) UpdateReservationEmail(ctx context.Context, reservationI
 	return &updatedReservation, nil
 }
 
-func validateAndCalculatePrice(dbQueries *database.Queries, ctx context.Context, reservationParams ReservationParameters) ([]database.GetEventDetailsWithTitleByIdsRow, int64, error) {
+func (service *Service) GetReservationTicket(ctx context.Context, reservationID, userID uuid.UUID) ([]byte, error) {
+	databaseReservation, err := service.DBQueries.GetUserReservationById(ctx, database.GetUserReservationByIdParams{
+		ID:     reservationID,
+		UserID: userID,
+	})
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+
+		log.Printf("Database error fetching reservation for ticket: %v", err)
+
+		return nil, ErrInternalError
+	}
+
+	qrCode, qrCodeError := service.Tickets.QRCode(databaseReservation.ID)
+
+	if qrCodeError != nil {
+		log.Printf("Error rendering ticket: %v", qrCodeError)
+
+		return nil, ErrInternalError
+	}
+
+	return qrCode, nil
+}
+
+func (service *Service) CheckIn(ctx context.Context, eventID, staffUserID uuid.UUID, staffRole string, ticketToken string) (*CheckInResponse, error) {
+	reservationID, verifyTicketError := service.Tickets.Verify(ticketToken)
+
+	if verifyTicketError != nil {
+		return nil, ErrInvalidTicket
+	}
+
+	_, authorizeError := event_staff.AuthorizeEvent(ctx, service.CheckIns, eventID, staffUserID, staffRole, event_staff.PermissionCheckIn)
+
+	if authorizeError != nil {
+		if errors.Is(authorizeError, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+
+		if errors.Is(authorizeError, event_staff.ErrEventAccessDenied) {
+			return nil, ErrCheckInNotAllowed
+		}
+
+		log.Printf("Database error authorizing check-in: %v", authorizeError)
+
+		return nil, ErrInternalError
+	}
+
+	reservation, getReservationError := service.CheckIns.GetReservationForCheckIn(ctx, reservationID)
+
+	if getReservationError != nil {
+		// A valid signature for a reservation that no longer exists, e.g. the payment expired.
+		if errors.Is(getReservationError, sql.ErrNoRows) {
+			return nil, ErrInvalidTicket
+		}
+
+		log.Printf("Database error fetching reservation for check-in: %v", getReservationError)
+
+		return nil, ErrInternalError
+	}
+
+	if reservation.EventID != eventID {
+		return nil, ErrTicketWrongEvent
+	}
+
+	if reservation.PaymentStatus != string(paymentstatus.Succeeded) {
+		return nil, ErrTicketNotPaid
+	}
+
+	if reservation.CheckedInAt.Valid {
+		return nil, fmt.Errorf("%w at %s", ErrTicketAlreadyCheckedIn, reservation.CheckedInAt.Time.Format("2006-01-02 15:04:05"))
+	}
+
+	checkedInReservation, checkInError := service.CheckIns.CheckInReservation(ctx, database.CheckInReservationParams{
+		CheckedInBy: uuid.NullUUID{UUID: staffUserID, Valid: true},
+		ID:          reservation.ID,
+	})
+
+	if checkInError != nil {
+		// Another scanner checked the ticket in between the read and the update.
+		if errors.Is(checkInError, sql.ErrNoRows) {
+			return nil, ErrTicketAlreadyCheckedIn
+		}
+
+		log.Printf("Database error checking in reservation: %v", checkInError)
+
+		return nil, ErrInternalError
+	}
+
+	return &CheckInResponse{
+		ReservationID:     checkedInReservation.ID,
+		Email:             checkedInReservation.Email,
+		TicketDescription: reservation.TicketDescription,
+		ShowDate:          reservation.ShowDate,
+		CheckedInAt:       checkedInReservation.CheckedInAt.Time,
+	}, nil
+}
+
+// buildTicketLines pairs the requested quantities with the validated ticket prices, in request order.
+func buildTicketLines(eventDetails []database.GetEventDetailsWithTitleByIdsRow, reservationParams ReservationParameters) []promo_codes.TicketLine {
+	detailsMap := make(map[uuid.UUID]database.GetEventDetailsWithTitleByIdsRow)
+
+	for _, eventDetail := range eventDetails {
+		detailsMap[eventDetail.ID] = eventDetail
+	}
+
+	ticketLines := make([]promo_codes.TicketLine, 0, len(reservationParams.EventDetailReservations))
+
+	for _, eventDetailReservation := range reservationParams.EventDetailReservations {
+		detail := detailsMap[eventDetailReservation.EventDetailID]
+
+		ticketLines = append(ticketLines, promo_codes.TicketLine{
+			EventID:        detail.EventID,
+			EventDetailID:  detail.ID,
+			UnitPriceCents: detail.Price,
+			Quantity:       eventDetailReservation.Quantity,
+		})
+	}
+
+	return ticketLines
+}
+
+func reservedEventDetailIDs(reservationParams ReservationParameters) []uuid.UUID {
+	eventDetailIDs := make([]uuid.UUID, 0, len(reservationParams.EventDetailReservations))
+
+	for _, eventDetailReservation := range reservationParams.EventDetailReservations {
+		eventDetailIDs = append(eventDetailIDs, eventDetailReservation.EventDetailID)
+	}
+
+	return eventDetailIDs
+}
+
+// paymentIntentIdempotencyKey keys the intent by payment, a client retry after a failed request books a new
+// payment and must not be answered with the intent of the old one.
+func paymentIntentIdempotencyKey(paymentID uuid.UUID) string {
+	return fmt.Sprintf("payment:%s", paymentID)
+}
+
+func validateAndCalculatePrice(dbQueries *database.Queries, ctx context.Context, reservationParams ReservationParameters, heldTickets map[uuid.UUID]int32) ([]database.GetEventDetailsWithTitleByIdsRow, int64, error) {
 	if len(reservationParams.EventDetailReservations) == 0 {
 		return nil, 0, errors.New("reservations list cannot be empty")
 	}
@@ -364,6 +605,30 @@ func validateAndCalculatePrice(dbQueries *database.Queries, ctx context.Context,
 		return nil, 0, ErrInternalError
 	}
 
+	// Tickets of a ticket type with people waiting are only sold through waitlist offers.
+	waitlistedEventDetailIDs, getWaitlistedError := dbQueries.GetWaitlistedEventDetailIds(ctx, eventDetailIDs)
+
+	if getWaitlistedError != nil {
+		log.Printf("Error checking waitlist for reservation: %v", getWaitlistedError)
+
+		return nil, 0, ErrInternalError
+	}
+
+	currentDateTime := time.Now()
+
+	// The rows carry the price charged right now, so promo codes, reservations and emails all use the same one.
+	for i := range eventDetails {
+		currentPrice, _, currentPriceError := event_details.CurrentPrice(eventDetails[i].Price, eventDetails[i].PriceSchedule, currentDateTime)
+
+		if currentPriceError != nil {
+			log.Printf("Error reading price schedule of event detail %s: %v", eventDetails[i].ID, currentPriceError)
+
+			return nil, 0, fmt.Errorf("error processing price for ticket: %s", eventDetails[i].Title)
+		}
+
+		eventDetails[i].Price = currentPrice
+	}
+
 	detailsMap := make(map[uuid.UUID]database.GetEventDetailsWithTitleByIdsRow)
 
 	for _, eventDetail := range eventDetails {
@@ -371,7 +636,6 @@ func validateAndCalculatePrice(dbQueries *database.Queries, ctx context.Context,
 	}
 
 	var totalCents int64
-	currentDateTime := time.Now()
 
 	// Iterate through requested reservations and perform validation.
 	for _, eventDetailReservation := range reservationParams.EventDetailReservations {
@@ -381,9 +645,34 @@ func validateAndCalculatePrice(dbQueries *database.Queries, ctx context.Context,
 			return nil, 0, fmt.Errorf("event detail with ID %s not found", eventDetailReservation.EventDetailID)
 		}
 
+		// Drafts aren't announced yet and cancelled events are being refunded.
+		if detail.EventStatus != "published" && detail.EventStatus != "postponed" {
+			return nil, 0, fmt.Errorf("%w: %s is %s", ErrEventNotBookable, detail.Title, detail.EventStatus)
+		}
+
+		// One payment is charged in one currency.
+		if detail.Currency != eventDetails[0].Currency {
+			return nil, 0, fmt.Errorf("%w: %s is in %s, %s is in %s", ErrMixedCurrencies, eventDetails[0].Title, eventDetails[0].Currency, detail.Title, detail.Currency)
+		}
+
+		// Ticket types with a sales window or waiting for another ticket type to sell out.
+		if salesWindowError := event_details.CheckSalesWindow(detail.SalesStartAt, detail.SalesEndAt, currentDateTime); salesWindowError != nil {
+			return nil, 0, fmt.Errorf("%w: %s", salesWindowError, detail.TicketDescription)
+		}
+
+		if detail.UnlocksAfterTicketsRemaining.Valid && detail.UnlocksAfterTicketsRemaining.Int32 > 0 {
+			return nil, 0, fmt.Errorf("%w: %s", event_details.ErrTicketTierLocked, detail.TicketDescription)
+		}
+
 		// Ticket availability check.
-		if detail.TicketsRemaining < int32(eventDetailReservation.Quantity) {
-			return nil, 0, fmt.Errorf("%w: only %d tickets remaining for %s", ErrInsufficientTickets, detail.TicketsRemaining, detail.Title)
+		availableTickets := detail.TicketsRemaining + heldTickets[detail.ID]
+
+		if slices.Contains(waitlistedEventDetailIDs, detail.ID) {
+			availableTickets = heldTickets[detail.ID]
+		}
+
+		if availableTickets < int32(eventDetailReservation.Quantity) {
+			return nil, 0, fmt.Errorf("%w: only %d tickets remaining for %s", ErrInsufficientTickets, availableTickets, detail.Title)
 		}
 
 		// Show date check. Must not be currently showing.
@@ -392,15 +681,7 @@ func validateAndCalculatePrice(dbQueries *database.Queries, ctx context.Context,
 		}
 
 		// Price Calculation.
-		priceCents, priceToCentsError := convert.PriceStringToCents(detail.Price)
-
-		if priceToCentsError != nil {
-			log.Printf("Error converting price to cents for %s: %v", detail.Price, priceToCentsError)
-
-			return nil, 0, fmt.Errorf("error processing price for ticket: %s", detail.Title)
-		}
-
-		totalCents += priceCents * int64(eventDetailReservation.Quantity)
+		totalCents += detail.Price * int64(eventDetailReservation.Quantity)
 	}
 
 	if totalCents < 0 {
@@ -412,6 +693,12 @@ func validateAndCalculatePrice(dbQueries *database.Queries, ctx context.Context,
 }
 
 func DatabaseReservationToReservationJSON(databaseReservation database.Reservation) Reservation {
+	var checkedInAt *time.Time
+
+	if databaseReservation.CheckedInAt.Valid {
+		checkedInAt = &databaseReservation.CheckedInAt.Time
+	}
+
 	return Reservation{
 		ID:            databaseReservation.ID,
 		Email:         databaseReservation.Email,
@@ -420,6 +707,7 @@ func DatabaseReservationToReservationJSON(databaseReservation database.Reservati
 		EventDetailID: databaseReservation.EventDetailID,
 		UserID:        databaseReservation.UserID,
 		PaymentID:     databaseReservation.PaymentID,
+		CheckedInAt:   checkedInAt,
 	}
 }
 
@@ -0,0 +1,328 @@
+package reservations_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"database/sql"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/event_staff"
+	"github.com/elorenzorodz/event-mrs/internal/auth"
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/paymentstatus"
+	"github.com/elorenzorodz/event-mrs/internal/tickets"
+	"github.com/elorenzorodz/event-mrs/reservations"
+	"github.com/google/uuid"
+)
+
+// MockCheckInQueries keeps events, their staff and reservations in memory. Checking a reservation in only works once
+// like the query.
+type MockCheckInQueries struct {
+	events       map[uuid.UUID]database.Event
+	staffRoles   map[database.GetEventStaffRoleParams]string
+	reservations map[uuid.UUID]*database.GetReservationForCheckInRow
+	checkedInBy  map[uuid.UUID]uuid.UUID
+}
+
+func NewMockCheckInQueries() *MockCheckInQueries {
+	return &MockCheckInQueries{
+		events:       map[uuid.UUID]database.Event{},
+		staffRoles:   map[database.GetEventStaffRoleParams]string{},
+		reservations: map[uuid.UUID]*database.GetReservationForCheckInRow{},
+		checkedInBy:  map[uuid.UUID]uuid.UUID{},
+	}
+}
+
+func (mockQueries *MockCheckInQueries) addEvent(ownerID uuid.UUID) uuid.UUID {
+	eventID := uuid.New()
+
+	mockQueries.events[eventID] = database.Event{ID: eventID, Title: "Jazz Night", UserID: ownerID}
+
+	return eventID
+}
+
+func (mockQueries *MockCheckInQueries) addStaff(eventID, userID uuid.UUID, staffRole string) {
+	mockQueries.staffRoles[database.GetEventStaffRoleParams{EventID: eventID, UserID: userID}] = staffRole
+}
+
+func (mockQueries *MockCheckInQueries) addReservation(eventID uuid.UUID, paymentStatus paymentstatus.Status) uuid.UUID {
+	reservationID := uuid.New()
+
+	mockQueries.reservations[reservationID] = &database.GetReservationForCheckInRow{
+		ID:                reservationID,
+		Email:             "jane@example.com",
+		EventID:           eventID,
+		ShowDate:          time.Now().Add(time.Hour),
+		TicketDescription: "VIP",
+		PaymentStatus:     string(paymentStatus),
+	}
+
+	return reservationID
+}
+
+func (mockQueries *MockCheckInQueries) GetEventById(ctx context.Context, id uuid.UUID) (database.Event, error) {
+	event, ok := mockQueries.events[id]
+
+	if !ok {
+		return database.Event{}, sql.ErrNoRows
+	}
+
+	return event, nil
+}
+
+func (mockQueries *MockCheckInQueries) GetEventStaffRole(ctx context.Context, arg database.GetEventStaffRoleParams) (string, error) {
+	staffRole, ok := mockQueries.staffRoles[arg]
+
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+
+	return staffRole, nil
+}
+
+func (mockQueries *MockCheckInQueries) GetReservationForCheckIn(ctx context.Context, id uuid.UUID) (database.GetReservationForCheckInRow, error) {
+	reservation, ok := mockQueries.reservations[id]
+
+	if !ok {
+		return database.GetReservationForCheckInRow{}, sql.ErrNoRows
+	}
+
+	return *reservation, nil
+}
+
+func (mockQueries *MockCheckInQueries) CheckInReservation(ctx context.Context, arg database.CheckInReservationParams) (database.Reservation, error) {
+	reservation, ok := mockQueries.reservations[arg.ID]
+
+	if !ok || reservation.CheckedInAt.Valid {
+		return database.Reservation{}, sql.ErrNoRows
+	}
+
+	reservation.CheckedInAt = sql.NullTime{Time: time.Now(), Valid: true}
+	mockQueries.checkedInBy[arg.ID] = arg.CheckedInBy.UUID
+
+	return database.Reservation{
+		ID:          reservation.ID,
+		Email:       reservation.Email,
+		CheckedInAt: reservation.CheckedInAt,
+		CheckedInBy: arg.CheckedInBy,
+	}, nil
+}
+
+// newTicketSigner returns a signer whose public key is written to a temporary file like the one loaded in main.
+func newTicketSigner(t *testing.T) *auth.TicketSigner {
+	t.Helper()
+
+	privateKey, generateKeyError := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if generateKeyError != nil {
+		t.Fatalf("Failed to generate signing key: %v", generateKeyError)
+	}
+
+	publicKeyBytes, marshalKeyError := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+
+	if marshalKeyError != nil {
+		t.Fatalf("Failed to marshal public key: %v", marshalKeyError)
+	}
+
+	publicKeyPath := filepath.Join(t.TempDir(), "public.pem")
+
+	if writeKeyError := os.WriteFile(publicKeyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyBytes}), 0o600); writeKeyError != nil {
+		t.Fatalf("Failed to write public key: %v", writeKeyError)
+	}
+
+	tokenValidator, tokenValidatorError := auth.NewTokenValidator(publicKeyPath)
+
+	if tokenValidatorError != nil {
+		t.Fatalf("Failed to load public key: %v", tokenValidatorError)
+	}
+
+	return auth.NewTicketSigner(privateKey, tokenValidator)
+}
+
+func signTicket(t *testing.T, ticketSigner *auth.TicketSigner, reservationID uuid.UUID) string {
+	t.Helper()
+
+	ticketToken, signError := ticketSigner.Sign(reservationID)
+
+	if signError != nil {
+		t.Fatalf("Failed to sign ticket: %v", signError)
+	}
+
+	return ticketToken
+}
+
+func TestCheckIn(tTesting *testing.T) {
+	ownerID := uuid.New()
+	staffID := uuid.New()
+	attendeeStaffID := uuid.New()
+
+	mockQueries := NewMockCheckInQueries()
+	eventID := mockQueries.addEvent(ownerID)
+	otherEventID := mockQueries.addEvent(ownerID)
+
+	mockQueries.addStaff(eventID, staffID, event_staff.StaffRoleCheckIn)
+	mockQueries.addStaff(eventID, attendeeStaffID, event_staff.StaffRoleViewAttendees)
+
+	ticketSigner := newTicketSigner(tTesting)
+	otherTicketSigner := newTicketSigner(tTesting)
+
+	paidReservationID := mockQueries.addReservation(eventID, paymentstatus.Succeeded)
+	otherEventReservationID := mockQueries.addReservation(otherEventID, paymentstatus.Succeeded)
+	unpaidReservationID := mockQueries.addReservation(eventID, paymentstatus.RequiresAction)
+	refundedReservationID := mockQueries.addReservation(eventID, paymentstatus.Refunded)
+
+	tests := []struct {
+		name          string
+		ticketToken   string
+		eventID       uuid.UUID
+		staffUserID   uuid.UUID
+		expectedError error
+	}{
+		{
+			name:          "InvalidTicket_SignedWithOtherKey",
+			ticketToken:   signTicket(tTesting, otherTicketSigner, paidReservationID),
+			eventID:       eventID,
+			staffUserID:   staffID,
+			expectedError: reservations.ErrInvalidTicket,
+		},
+		{
+			name:          "InvalidTicket_NotAToken",
+			ticketToken:   paidReservationID.String(),
+			eventID:       eventID,
+			staffUserID:   staffID,
+			expectedError: reservations.ErrInvalidTicket,
+		},
+		{
+			name:          "InvalidTicket_ReservationReleased",
+			ticketToken:   signTicket(tTesting, ticketSigner, uuid.New()),
+			eventID:       eventID,
+			staffUserID:   staffID,
+			expectedError: reservations.ErrInvalidTicket,
+		},
+		{
+			name:          "WrongEvent",
+			ticketToken:   signTicket(tTesting, ticketSigner, otherEventReservationID),
+			eventID:       eventID,
+			staffUserID:   staffID,
+			expectedError: reservations.ErrTicketWrongEvent,
+		},
+		{
+			name:          "NotPaid_PaymentRequiresAction",
+			ticketToken:   signTicket(tTesting, ticketSigner, unpaidReservationID),
+			eventID:       eventID,
+			staffUserID:   staffID,
+			expectedError: reservations.ErrTicketNotPaid,
+		},
+		{
+			name:          "NotPaid_PaymentRefunded",
+			ticketToken:   signTicket(tTesting, ticketSigner, refundedReservationID),
+			eventID:       eventID,
+			staffUserID:   staffID,
+			expectedError: reservations.ErrTicketNotPaid,
+		},
+		{
+			name:          "NotFound_StaffOfOtherEvent",
+			ticketToken:   signTicket(tTesting, ticketSigner, otherEventReservationID),
+			eventID:       otherEventID,
+			staffUserID:   staffID,
+			expectedError: sql.ErrNoRows,
+		},
+		{
+			name:          "NotAllowed_StaffWithoutCheckIn",
+			ticketToken:   signTicket(tTesting, ticketSigner, paidReservationID),
+			eventID:       eventID,
+			staffUserID:   attendeeStaffID,
+			expectedError: reservations.ErrCheckInNotAllowed,
+		},
+	}
+
+	for _, tc := range tests {
+		tTesting.Run(tc.name, func(t *testing.T) {
+			service := &reservations.Service{CheckIns: mockQueries, Tickets: tickets.NewIssuer(ticketSigner)}
+
+			checkIn, err := service.CheckIn(context.Background(), tc.eventID, tc.staffUserID, auth.RoleAttendee, tc.ticketToken)
+
+			if !errors.Is(err, tc.expectedError) {
+				t.Fatalf("Expected error %v, got %v", tc.expectedError, err)
+			}
+
+			if checkIn != nil {
+				t.Errorf("Expected no check-in, got %+v", checkIn)
+			}
+		})
+	}
+
+	for reservationID, reservation := range mockQueries.reservations {
+		if reservation.CheckedInAt.Valid {
+			tTesting.Errorf("Expected reservation %s not to be checked in", reservationID)
+		}
+	}
+}
+
+func TestCheckInOnlyOnce(t *testing.T) {
+	staffID := uuid.New()
+
+	mockQueries := NewMockCheckInQueries()
+	eventID := mockQueries.addEvent(uuid.New())
+	mockQueries.addStaff(eventID, staffID, event_staff.StaffRoleCheckIn)
+	reservationID := mockQueries.addReservation(eventID, paymentstatus.Succeeded)
+
+	ticketSigner := newTicketSigner(t)
+	ticketToken := signTicket(t, ticketSigner, reservationID)
+	service := &reservations.Service{CheckIns: mockQueries, Tickets: tickets.NewIssuer(ticketSigner)}
+
+	checkIn, err := service.CheckIn(context.Background(), eventID, staffID, auth.RoleAttendee, ticketToken)
+
+	if err != nil {
+		t.Fatalf("CheckIn: expected no error, got: %v", err)
+	}
+
+	if checkIn.ReservationID != reservationID || checkIn.TicketDescription != "VIP" || checkIn.CheckedInAt.IsZero() {
+		t.Errorf("Unexpected check-in %+v", checkIn)
+	}
+
+	if checkedInBy := mockQueries.checkedInBy[reservationID]; checkedInBy != staffID {
+		t.Errorf("Expected the ticket to be checked in by %s, got %s", staffID, checkedInBy)
+	}
+
+	if _, err := service.CheckIn(context.Background(), eventID, staffID, auth.RoleAttendee, ticketToken); !errors.Is(err, reservations.ErrTicketAlreadyCheckedIn) {
+		t.Fatalf("Expected %v on the second scan, got %v", reservations.ErrTicketAlreadyCheckedIn, err)
+	}
+}
+
+func TestCheckInRaceWithOtherScanner(t *testing.T) {
+	staffID := uuid.New()
+
+	mockQueries := NewMockCheckInQueries()
+	eventID := mockQueries.addEvent(uuid.New())
+	mockQueries.addStaff(eventID, staffID, event_staff.StaffRoleCheckIn)
+	reservationID := mockQueries.addReservation(eventID, paymentstatus.Succeeded)
+
+	ticketSigner := newTicketSigner(t)
+	service := &reservations.Service{CheckIns: &checkedInByOtherScanner{MockCheckInQueries: mockQueries}, Tickets: tickets.NewIssuer(ticketSigner)}
+
+	if _, err := service.CheckIn(context.Background(), eventID, staffID, auth.RoleAttendee, signTicket(t, ticketSigner, reservationID)); !errors.Is(err, reservations.ErrTicketAlreadyCheckedIn) {
+		t.Fatalf("Expected %v, got %v", reservations.ErrTicketAlreadyCheckedIn, err)
+	}
+}
+
+// checkedInByOtherScanner checks the ticket in with another scanner between reading and updating the reservation.
+type checkedInByOtherScanner struct {
+	*MockCheckInQueries
+}
+
+func (queries *checkedInByOtherScanner) CheckInReservation(ctx context.Context, arg database.CheckInReservationParams) (database.Reservation, error) {
+	if _, err := queries.MockCheckInQueries.CheckInReservation(ctx, database.CheckInReservationParams{CheckedInBy: uuid.NullUUID{UUID: uuid.New(), Valid: true}, ID: arg.ID}); err != nil {
+		return database.Reservation{}, err
+	}
+
+	return queries.MockCheckInQueries.CheckInReservation(ctx, arg)
+}
\ No newline at end of file
@@ -0,0 +1,33 @@
+package show_reminders
+
+import (
+	"context"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/clock"
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/mailer"
+)
+
+// ReminderQueries is what the scheduler finds, records and emails the reminders with, *database.Queries in the app.
+type ReminderQueries interface {
+	GetDueShowReminders(ctx context.Context, arg database.GetDueShowRemindersParams) ([]database.GetDueShowRemindersRow, error)
+	CreateShowReminders(ctx context.Context, arg database.CreateShowRemindersParams) (int64, error)
+	mailer.BrandingQuerier
+	mailer.OutboxQuerier
+}
+
+// ReminderStore runs fn with the queries of a transaction that is committed when fn returns nil.
+type ReminderStore interface {
+	ReminderQueries
+	InTx(ctx context.Context, fn func(qtx ReminderQueries) error) error
+}
+
+// ReminderScheduler emails the attendees of a show at the reminder offsets of its event, e.g. 7 days and 24 hours before.
+type ReminderScheduler struct {
+	DB        ReminderStore
+	Mailer    mailer.Mailer
+	Clock     clock.Clock
+	Interval  time.Duration
+	BatchSize int32
+}
@@ -0,0 +1,113 @@
+package show_reminders
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/clock"
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/mailer"
+)
+
+func NewReminderScheduler(store ReminderStore, reminderMailer mailer.Mailer, schedulerClock clock.Clock, interval time.Duration, batchSize int32) *ReminderScheduler {
+	return &ReminderScheduler{
+		DB:        store,
+		Mailer:    reminderMailer,
+		Clock:     schedulerClock,
+		Interval:  interval,
+		BatchSize: batchSize,
+	}
+}
+
+// Start sends the due reminders every Interval until the context is cancelled.
+func (scheduler *ReminderScheduler) Start(ctx context.Context) {
+	log.Printf("Show reminder scheduler started, interval: %s, batch size: %d", scheduler.Interval, scheduler.BatchSize)
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("Show reminder scheduler stopped: %v", ctx.Err())
+
+			return
+		case <-scheduler.Clock.After(scheduler.Interval):
+			sentCount, sweepError := scheduler.Sweep(ctx)
+
+			if sweepError != nil {
+				log.Printf("Show reminder scheduler error: %v", sweepError)
+			}
+
+			if sentCount > 0 {
+				log.Printf("Show reminder scheduler sent %d reminder/s", sentCount)
+			}
+		}
+	}
+}
+
+// Sweep sends one batch of due reminders and returns how many were sent. An email gets one reminder per show and offset
+// however many tickets were booked with it.
+func (scheduler *ReminderScheduler) Sweep(ctx context.Context) (int, error) {
+	dueReminders, getDueRemindersError := scheduler.DB.GetDueShowReminders(ctx, database.GetDueShowRemindersParams{
+		Now:       scheduler.Clock.Now(),
+		BatchSize: scheduler.BatchSize,
+	})
+
+	if getDueRemindersError != nil {
+		return 0, fmt.Errorf("failed to retrieve due show reminders: %w", getDueRemindersError)
+	}
+
+	sentCount := 0
+
+	for _, dueReminder := range dueReminders {
+		sent, sendReminderError := scheduler.sendReminder(ctx, dueReminder)
+
+		if sendReminderError != nil {
+			log.Printf("Show reminder scheduler: skipping reminder of event detail %s to %s: %v", dueReminder.EventDetailID, dueReminder.Email, sendReminderError)
+
+			continue
+		}
+
+		if sent {
+			sentCount++
+		}
+	}
+
+	return sentCount, nil
+}
+
+// sendReminder records the reminder and enqueues its email in one transaction, so a restart can neither lose nor resend
+// it. A reminder that another scheduler recorded in the meantime isn't sent.
+func (scheduler *ReminderScheduler) sendReminder(ctx context.Context, dueReminder database.GetDueShowRemindersRow) (bool, error) {
+	sent := false
+
+	inTxError := scheduler.DB.InTx(ctx, func(qtx ReminderQueries) error {
+		createdCount, createRemindersError := qtx.CreateShowReminders(ctx, database.CreateShowRemindersParams{
+			ReservationIds: dueReminder.ReservationIds,
+			OffsetMinutes:  dueReminder.OffsetMinutes,
+			ShowDate:       dueReminder.ShowDate,
+		})
+
+		if createRemindersError != nil {
+			return fmt.Errorf("failed to record reminder: %w", createRemindersError)
+		}
+
+		if createdCount == 0 {
+			return nil
+		}
+
+		branding := mailer.LoadBranding(ctx, qtx, dueReminder.Organizer.String, dueReminder.OrganizerUserID)
+
+		sendReminderError := scheduler.Mailer.WithQueries(qtx).SendShowReminder(dueReminder.Email, dueReminder.Title, dueReminder.TicketDescription, dueReminder.ShowDate, len(dueReminder.ReservationIds), branding)
+
+		if sendReminderError != nil {
+			return sendReminderError
+		}
+
+		sent = true
+
+		return nil
+	})
+
+	return sent, inTxError
+}
@@ -0,0 +1,299 @@
+package show_reminders_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"maps"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/clock/clocktest"
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/elorenzorodz/event-mrs/show_reminders"
+	"github.com/google/uuid"
+)
+
+var testMailerConfig = mailer.MailerConfig{
+	SenderName:  "Event MRS",
+	SenderEmail: "noreply@event-mrs.test",
+	TeamName:    "Event MRS Team",
+	TeamEmail:   "team@event-mrs.test",
+}
+
+type reminderKey struct {
+	reservationID uuid.UUID
+	offsetMinutes int32
+	showDate      time.Time
+}
+
+// MockReminderStore returns the reminders of its shows once they are due and not recorded yet, a simpler
+// GetDueShowReminders than the query.
+type MockReminderStore struct {
+	mutex          sync.Mutex
+	reminders      []database.GetDueShowRemindersRow
+	recorded       map[reminderKey]bool
+	buyers         map[uuid.UUID]uuid.UUID
+	userIDsByEmail map[string]uuid.UUID
+	showReminders  map[uuid.UUID]bool
+}
+
+func NewMockReminderStore(reminders ...database.GetDueShowRemindersRow) *MockReminderStore {
+	return &MockReminderStore{
+		reminders:      reminders,
+		recorded:       map[reminderKey]bool{},
+		buyers:         map[uuid.UUID]uuid.UUID{},
+		userIDsByEmail: map[string]uuid.UUID{},
+		showReminders:  map[uuid.UUID]bool{},
+	}
+}
+
+// addUser adds an account with its show_reminders preference.
+func (mockStore *MockReminderStore) addUser(email string, showReminders bool) uuid.UUID {
+	userID := uuid.New()
+	mockStore.userIDsByEmail[strings.ToLower(email)] = userID
+	mockStore.showReminders[userID] = showReminders
+
+	return userID
+}
+
+func (mockStore *MockReminderStore) bookedBy(buyerID uuid.UUID, reminders ...database.GetDueShowRemindersRow) {
+	for _, reminder := range reminders {
+		for _, reservationID := range reminder.ReservationIds {
+			mockStore.buyers[reservationID] = buyerID
+		}
+	}
+}
+
+// remindersOn resolves the preference like the query, by the account of the recipient's email or the buyer's when
+// the email has none.
+func (mockStore *MockReminderStore) remindersOn(email string, reservationID uuid.UUID) bool {
+	userID, found := mockStore.userIDsByEmail[strings.ToLower(email)]
+
+	if !found {
+		userID = mockStore.buyers[reservationID]
+	}
+
+	showReminders, found := mockStore.showReminders[userID]
+
+	return !found || showReminders
+}
+
+func (mockStore *MockReminderStore) GetDueShowReminders(ctx context.Context, arg database.GetDueShowRemindersParams) ([]database.GetDueShowRemindersRow, error) {
+	mockStore.mutex.Lock()
+	defer mockStore.mutex.Unlock()
+
+	dueReminders := []database.GetDueShowRemindersRow{}
+
+	for _, reminder := range mockStore.reminders {
+		remindAt := reminder.ShowDate.Add(-time.Duration(reminder.OffsetMinutes) * time.Minute)
+		key := reminderKey{reservationID: reminder.ReservationIds[0], offsetMinutes: reminder.OffsetMinutes, showDate: reminder.ShowDate}
+
+		if !remindAt.After(arg.Now) && reminder.ShowDate.After(arg.Now) && !mockStore.recorded[key] && mockStore.remindersOn(reminder.Email, reminder.ReservationIds[0]) {
+			dueReminders = append(dueReminders, reminder)
+		}
+	}
+
+	return dueReminders, nil
+}
+
+func (mockStore *MockReminderStore) CreateShowReminders(ctx context.Context, arg database.CreateShowRemindersParams) (int64, error) {
+	mockStore.mutex.Lock()
+	defer mockStore.mutex.Unlock()
+
+	createdCount := int64(0)
+
+	for _, reservationID := range arg.ReservationIds {
+		key := reminderKey{reservationID: reservationID, offsetMinutes: arg.OffsetMinutes, showDate: arg.ShowDate}
+
+		if !mockStore.recorded[key] {
+			mockStore.recorded[key] = true
+			createdCount++
+		}
+	}
+
+	return createdCount, nil
+}
+
+func (mockStore *MockReminderStore) GetOrganizerBranding(ctx context.Context, userID uuid.UUID) (database.OrganizerBranding, error) {
+	return database.OrganizerBranding{}, sql.ErrNoRows
+}
+
+func (mockStore *MockReminderStore) EnqueueEmail(ctx context.Context, arg database.EnqueueEmailParams) (database.EmailOutbox, error) {
+	return database.EmailOutbox{}, nil
+}
+
+// InTx forgets the reminders recorded by fn when it fails, like a rolled back transaction.
+func (mockStore *MockReminderStore) InTx(ctx context.Context, fn func(qtx show_reminders.ReminderQueries) error) error {
+	mockStore.mutex.Lock()
+	recordedBefore := maps.Clone(mockStore.recorded)
+	mockStore.mutex.Unlock()
+
+	if fnError := fn(mockStore); fnError != nil {
+		mockStore.mutex.Lock()
+		mockStore.recorded = recordedBefore
+		mockStore.mutex.Unlock()
+
+		return fnError
+	}
+
+	return nil
+}
+
+
+func newShowReminders(showDate time.Time, offsetMinutes ...int32) []database.GetDueShowRemindersRow {
+	reservationIDs := []uuid.UUID{uuid.New(), uuid.New()}
+	reminders := make([]database.GetDueShowRemindersRow, len(offsetMinutes))
+
+	for i, offset := range offsetMinutes {
+		reminders[i] = database.GetDueShowRemindersRow{
+			Email:             "jane@example.com",
+			EventDetailID:     uuid.New(),
+			ShowDate:          showDate,
+			TicketDescription: "VIP",
+			Title:             "Jazz Night",
+			Organizer:         sql.NullString{String: "Blue Note Live", Valid: true},
+			OrganizerUserID:   uuid.New(),
+			OffsetMinutes:     offset,
+			ReservationIds:    reservationIDs,
+		}
+	}
+
+	return reminders
+}
+
+func TestStartSendsRemindersAtOffsets(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	showDate := start.Add(8 * 24 * time.Hour)
+	fakeClock := clocktest.NewFakeClock(start)
+	recorder := mailer.NewRecorder()
+	mockStore := NewMockReminderStore(newShowReminders(showDate, 7*24*60, 24*60)...)
+
+	scheduler := show_reminders.NewReminderScheduler(mockStore, mailer.NewMailer(testMailerConfig, recorder), fakeClock, time.Hour, 25)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+
+	go func() {
+		scheduler.Start(ctx)
+		close(stopped)
+	}()
+
+	steps := []struct {
+		name             string
+		advance          time.Duration
+		expectedMessages int
+	}{
+		{name: "BeforeFirstReminder", advance: 12 * time.Hour, expectedMessages: 0},
+		{name: "SevenDaysBefore", advance: 12 * time.Hour, expectedMessages: 1},
+		{name: "NotResent", advance: time.Hour, expectedMessages: 1},
+		{name: "DayBefore", advance: 6 * 24 * time.Hour, expectedMessages: 2},
+		{name: "AfterShow", advance: 2 * 24 * time.Hour, expectedMessages: 2},
+	}
+
+	for _, step := range steps {
+		clocktest.WaitForWaiter(t, fakeClock)
+		fakeClock.Advance(step.advance)
+		clocktest.WaitForWaiter(t, fakeClock)
+
+		if messages := recorder.Messages(); len(messages) != step.expectedMessages {
+			t.Fatalf("%s: expected %d reminder/s at %s, got %d", step.name, step.expectedMessages, fakeClock.Now(), len(messages))
+		}
+	}
+
+	cancel()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Scheduler did not stop after the context was cancelled")
+	}
+
+	reminder := recorder.Messages()[0]
+
+	if reminder.To != "jane@example.com" || reminder.Subject != "Reminder: Jazz Night is on 2025-06-09 12:00" {
+		t.Errorf("Reminder: expected Jazz Night to jane@example.com, got %q to %q", reminder.Subject, reminder.To)
+	}
+
+	// Both reservations were booked with the same email, it gets one reminder for them.
+	if !strings.Contains(reminder.Text, "You have 2 ticket/s for this show") {
+		t.Errorf("Reminder: expected the 2 tickets in the text, got: %s", reminder.Text)
+	}
+}
+
+func TestSweepRetriesReminderWhenEmailFails(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	recorder := mailer.NewRecorder()
+	recorder.Err = errors.New("outbox unavailable")
+	mockStore := NewMockReminderStore(newShowReminders(now.Add(time.Hour), 24*60)...)
+
+	scheduler := show_reminders.NewReminderScheduler(mockStore, mailer.NewMailer(testMailerConfig, recorder), clocktest.NewFakeClock(now), time.Hour, 25)
+
+	sentCount, err := scheduler.Sweep(context.Background())
+
+	if err != nil || sentCount != 0 {
+		t.Fatalf("Sweep: expected no reminder sent and no error, got %d, %v", sentCount, err)
+	}
+
+	if len(mockStore.recorded) != 0 {
+		t.Fatalf("Sweep: expected the reminder of the failed email to not be recorded, got %v", mockStore.recorded)
+	}
+
+	recorder.Err = nil
+
+	for sweep, expectedSent := range []int{1, 0} {
+		sentCount, err := scheduler.Sweep(context.Background())
+
+		if err != nil || sentCount != expectedSent {
+			t.Errorf("Sweep %d: expected %d reminder/s sent, got %d, %v", sweep+1, expectedSent, sentCount, err)
+		}
+	}
+}
+
+func TestSweepResolvesOptOutByRecipient(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name               string
+		buyerShowReminders bool
+		guestHasAccount    bool
+		guestShowReminders bool
+		expectedSent       int
+	}{
+		{name: "GuestOptedOut_BuyerOptedIn", buyerShowReminders: true, guestHasAccount: true, guestShowReminders: false, expectedSent: 0},
+		{name: "GuestOptedIn_BuyerOptedOut", buyerShowReminders: false, guestHasAccount: true, guestShowReminders: true, expectedSent: 1},
+		{name: "GuestWithoutAccount_BuyerOptedOut", buyerShowReminders: false, guestHasAccount: false, expectedSent: 0},
+		{name: "GuestWithoutAccount_BuyerOptedIn", buyerShowReminders: true, guestHasAccount: false, expectedSent: 1},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			recorder := mailer.NewRecorder()
+			// The tickets were booked by buyer@example.com for jane@example.com.
+			reminders := newShowReminders(now.Add(time.Hour), 24*60)
+			mockStore := NewMockReminderStore(reminders...)
+			mockStore.bookedBy(mockStore.addUser("buyer@example.com", testCase.buyerShowReminders), reminders...)
+
+			if testCase.guestHasAccount {
+				mockStore.addUser("Jane@Example.com", testCase.guestShowReminders)
+			}
+
+			scheduler := show_reminders.NewReminderScheduler(mockStore, mailer.NewMailer(testMailerConfig, recorder), clocktest.NewFakeClock(now), time.Hour, 25)
+
+			sentCount, err := scheduler.Sweep(context.Background())
+
+			if err != nil || sentCount != testCase.expectedSent {
+				t.Fatalf("Sweep: expected %d reminder/s sent, got %d, %v", testCase.expectedSent, sentCount, err)
+			}
+
+			for _, message := range recorder.Messages() {
+				if message.To != "jane@example.com" {
+					t.Errorf("Reminder: expected it to be sent to jane@example.com, got %q", message.To)
+				}
+			}
+		})
+	}
+}
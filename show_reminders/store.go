@@ -0,0 +1,38 @@
+package show_reminders
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/elorenzorodz/event-mrs/internal/database"
+)
+
+// dbStore is the ReminderStore of the app.
+type dbStore struct {
+	*database.Queries
+	dbConnection *sql.DB
+}
+
+func NewStore(dbConn *sql.DB, dbQueries *database.Queries) ReminderStore {
+	return &dbStore{
+		Queries:      dbQueries,
+		dbConnection: dbConn,
+	}
+}
+
+func (store *dbStore) InTx(ctx context.Context, fn func(qtx ReminderQueries) error) error {
+	tx, beginTxError := store.dbConnection.BeginTx(ctx, nil)
+
+	if beginTxError != nil {
+		return fmt.Errorf("failed to start transaction: %w", beginTxError)
+	}
+
+	defer tx.Rollback()
+
+	if fnError := fn(store.Queries.WithTx(tx)); fnError != nil {
+		return fnError
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,39 @@
+-- name: UpsertCalendarFeed :one
+INSERT INTO calendar_feeds (token_hash, user_id)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET token_hash = EXCLUDED.token_hash,
+	created_at = NOW()
+RETURNING *;
+
+-- name: GetCalendarFeedByTokenHash :one
+SELECT * FROM calendar_feeds
+WHERE token_hash = $1;
+
+-- name: DeleteCalendarFeed :execrows
+DELETE FROM calendar_feeds
+WHERE user_id = $1;
+
+-- name: GetCalendarFeedShows :many
+SELECT
+	ed.id AS event_detail_id,
+	ed.show_date,
+	ed.ticket_description,
+	e.title,
+	e.description,
+	e.organizer,
+	e.calendar_sequence,
+	COUNT(r.id) AS tickets
+FROM reservations AS r
+JOIN payments AS p
+	ON p.id = r.payment_id
+JOIN event_details AS ed
+	ON ed.id = r.event_detail_id
+JOIN events AS e
+	ON e.id = ed.event_id
+WHERE r.user_id = $1
+	AND p.status = 'succeeded'
+	AND e.status IN ('published', 'postponed')
+	AND ed.show_date >= NOW()
+GROUP BY ed.id, e.id
+ORDER BY ed.show_date;
\ No newline at end of file
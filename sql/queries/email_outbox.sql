@@ -0,0 +1,44 @@
+-- name: EnqueueEmail :one
+INSERT INTO email_outbox (sender, recipient, subject, text_body, html_body, attachments)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: ClaimDueEmails :many
+-- Claimed emails are leased until lease_until so other workers skip them, the emails of a worker that stopped while
+-- sending are retried once the lease ends.
+UPDATE email_outbox
+SET next_attempt_at = @lease_until::timestamp, updated_at = NOW()
+WHERE id IN (
+	SELECT id FROM email_outbox
+	WHERE status = 'pending' AND next_attempt_at <= @due_before::timestamp
+	ORDER BY next_attempt_at
+	LIMIT @batch_size
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkEmailSent :exec
+UPDATE email_outbox
+SET status = 'sent', attempts = attempts + 1, last_error = NULL, sent_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkEmailFailed :exec
+UPDATE email_outbox
+SET status = @status, attempts = attempts + 1, last_error = @last_error, next_attempt_at = @next_attempt_at, updated_at = NOW()
+WHERE id = @id;
+
+-- name: GetOutboxEmails :many
+SELECT * FROM email_outbox
+WHERE @status::text = '' OR status = @status::text
+ORDER BY created_at DESC
+LIMIT @row_limit OFFSET @row_offset;
+
+-- name: GetOutboxEmailById :one
+SELECT * FROM email_outbox
+WHERE id = $1;
+
+-- name: ResendOutboxEmail :one
+UPDATE email_outbox
+SET status = 'pending', attempts = 0, next_attempt_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND status = 'dead'
+RETURNING *;
\ No newline at end of file
@@ -1,16 +1,16 @@
 -- name: CreateEventDetail :one
-INSERT INTO event_details (id, show_date, price, number_of_tickets, tickets_remaining, ticket_description, event_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING id, show_date, price, number_of_tickets, tickets_remaining, ticket_description, created_at, updated_at, event_id;
+INSERT INTO event_details (id, show_date, price, number_of_tickets, tickets_remaining, ticket_description, event_id, sales_start_at, sales_end_at, price_schedule, unlocks_after_event_detail_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING id, show_date, price, number_of_tickets, tickets_remaining, ticket_description, created_at, updated_at, event_id, sales_start_at, sales_end_at, price_schedule, unlocks_after_event_detail_id;
 
 -- name: GetEventDetailsByEventId :many
 SELECT * FROM event_details WHERE event_id = ANY($1);
 
 -- name: UpdateEventDetail :one
 UPDATE event_details
-SET show_date = $1, price = $2, number_of_tickets = $3, ticket_description = $4, updated_at = NOW()
-WHERE id = $5 AND event_id = $6
-RETURNING id, show_date, price, number_of_tickets, tickets_remaining, ticket_description, created_at, updated_at, event_id;
+SET show_date = $1, price = $2, number_of_tickets = $3, tickets_remaining = tickets_remaining + ($3 - number_of_tickets), ticket_description = $4, sales_start_at = $5, sales_end_at = $6, price_schedule = $7, unlocks_after_event_detail_id = $8, updated_at = NOW()
+WHERE id = $9 AND event_id = $10 AND number_of_tickets - tickets_remaining <= $3
+RETURNING id, show_date, price, number_of_tickets, tickets_remaining, ticket_description, created_at, updated_at, event_id, sales_start_at, sales_end_at, price_schedule, unlocks_after_event_detail_id;
 
 -- name: DeleteEventDetail :exec
 DELETE FROM event_details WHERE id = $1 AND event_id = $2;
@@ -19,7 +19,7 @@ DELETE FROM event_details WHERE id = $1 AND event_id = $2;
 UPDATE event_details
 SET tickets_remaining = $1, updated_at = NOW()
 WHERE id = $2
-RETURNING id, show_date, price, number_of_tickets, tickets_remaining, ticket_description, created_at, updated_at, event_id;
+RETURNING id, show_date, price, number_of_tickets, tickets_remaining, ticket_description, created_at, updated_at, event_id, sales_start_at, sales_end_at, price_schedule, unlocks_after_event_detail_id;
 
 -- name: GetEventDetailsById :one
 SELECT * FROM event_details WHERE id = $1;
@@ -31,21 +31,40 @@ SELECT
 	ed.ticket_description,
 	ed.show_date,
     ed.tickets_remaining,
-    ed.price
+    ed.price,
+	e.status AS event_status,
+	ed.event_id,
+	ed.sales_start_at,
+	ed.sales_end_at,
+	ed.price_schedule,
+	unlocks_after.tickets_remaining AS unlocks_after_tickets_remaining,
+	e.currency,
+	e.organizer,
+	e.user_id AS organizer_user_id,
+	ob.logo_url AS branding_logo_url,
+	ob.accent_color AS branding_accent_color,
+	ob.footer AS branding_footer,
+	e.calendar_sequence
 FROM event_details AS ed
 JOIN events AS e
 	ON e.id = ed.event_id
+LEFT JOIN event_details AS unlocks_after
+	ON unlocks_after.id = ed.unlocks_after_event_detail_id
+LEFT JOIN organizer_brandings AS ob
+	ON ob.user_id = e.user_id
 WHERE ed.id = ANY($1);
 
 -- name: GetPaidEventDetailForRefund :many
 SELECT
     p.id AS payment_id,
     p.payment_intent_id,
+	p.user_id AS payer_user_id,
     p.amount,
     p.status,
+    p.currency,
 	e.title,
     ed.ticket_description,
-	SUM(ed.price) AS ticket_price 
+	SUM(r.price - r.discount_amount)::bigint AS ticket_price 
 FROM events AS e
 JOIN event_details AS ed
     ON ed.event_id = e.id
@@ -56,4 +75,21 @@ JOIN payments AS p
 JOIN users AS u
     ON u.id = p.user_id
 WHERE ed.id = @event_detail_id::uuid AND e.user_id = @user_id::uuid
-GROUP BY p.id, p.payment_intent_id, p.amount, p.status, e.title, ed.ticket_description, ed.price;
\ No newline at end of file
+GROUP BY p.id, p.payment_intent_id, p.user_id, p.amount, p.status, p.currency, e.title, ed.ticket_description;
+
+-- name: GetUpcomingEventDetailsByEventId :many
+-- Ticket types whose sales ended or that wait for another ticket type to sell out aren't listed.
+SELECT ed.* FROM event_details AS ed
+WHERE ed.event_id = $1 AND ed.show_date >= $2
+	AND (ed.sales_end_at IS NULL OR ed.sales_end_at > $2)
+	AND NOT EXISTS (
+		SELECT 1 FROM event_details AS unlocks_after
+		WHERE unlocks_after.id = ed.unlocks_after_event_detail_id AND unlocks_after.tickets_remaining > 0
+	)
+ORDER BY ed.show_date, ed.id;
+
+-- name: UpdateEventDetailShowDate :one
+UPDATE event_details
+SET show_date = $1, updated_at = NOW()
+WHERE id = $2 AND event_id = $3
+RETURNING id, show_date, price, number_of_tickets, tickets_remaining, ticket_description, created_at, updated_at, event_id, sales_start_at, sales_end_at, price_schedule, unlocks_after_event_detail_id;
\ No newline at end of file
@@ -0,0 +1,27 @@
+-- name: CreateEventStaff :one
+INSERT INTO event_staff (id, email, role, event_id, invited_by)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (event_id, email) DO NOTHING
+RETURNING id, email, role, created_at, updated_at, event_id, invited_by;
+
+-- name: GetEventStaff :many
+SELECT *
+FROM event_staff
+WHERE event_id = $1
+ORDER BY created_at, id;
+
+-- name: UpdateEventStaffRole :one
+UPDATE event_staff
+SET role = $1, updated_at = NOW()
+WHERE id = $2 AND event_id = $3
+RETURNING id, email, role, created_at, updated_at, event_id, invited_by;
+
+-- name: DeleteEventStaff :execrows
+DELETE FROM event_staff WHERE id = $1 AND event_id = $2;
+
+-- name: GetEventStaffRole :one
+SELECT es.role
+FROM event_staff AS es
+JOIN users AS u
+	ON LOWER(u.email) = es.email
+WHERE es.event_id = @event_id AND u.id = @user_id AND u.email_verified_at IS NOT NULL;
\ No newline at end of file
@@ -1,7 +1,7 @@
 -- name: CreateEvent :one
-INSERT INTO events (id, title, description, organizer, user_id)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, title, description, organizer, created_at, updated_at, user_id;
+INSERT INTO events (id, title, description, organizer, user_id, currency)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, title, description, organizer, created_at, updated_at, user_id, search_vector, status, refund_deadline, status_updated_at, currency, calendar_sequence, reminder_offset_minutes;
 
 -- name: GetUserEvents :many
 SELECT * 
@@ -15,32 +15,111 @@ WHERE id = $1 AND user_id = $2;
 
 -- name: UpdateEvent :one
 UPDATE events
-SET title = $1, description = $2, organizer = $3, updated_at = NOW()
+SET title = $1, description = $2, organizer = $3, calendar_sequence = calendar_sequence + 1, updated_at = NOW()
 WHERE id = $4 AND user_id= $5
-RETURNING id, title, description, organizer, created_at, updated_at, user_id;
+RETURNING id, title, description, organizer, created_at, updated_at, user_id, search_vector, status, refund_deadline, status_updated_at, currency, calendar_sequence, reminder_offset_minutes;
 
 -- name: DeleteEvent :exec
-DELETE FROM events WHERE id = $1 AND user_id = $2;
+DELETE FROM events WHERE id = $1 AND user_id = $2 AND status = 'draft';
 
 -- name: GetEvents :many
-SELECT 
+SELECT
 	e.id AS event_id,
 	e.title,
 	e.description,
 	e.organizer,
+	e.status,
+	e.currency,
 	ed.id AS event_detail_id,
 	ed.show_date,
-	ed.price,
+	current_price.price,
 	ed.number_of_tickets,
-	ed.ticket_description
+	ed.tickets_remaining,
+	ed.ticket_description,
+	ed.created_at,
+	ts_rank(e.search_vector, websearch_to_tsquery('english', @search::text))::real AS rank,
+	(CASE WHEN @search::text = '' THEN ''
+		ELSE ts_headline('english', e.title, websearch_to_tsquery('english', @search::text), 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true')
+	END)::text AS title_highlight,
+	(CASE WHEN @search::text = '' THEN ''
+		ELSE ts_headline('english', e.description, websearch_to_tsquery('english', @search::text), 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MinWords=5, MaxWords=20')
+	END)::text AS description_snippet
 FROM events AS e
-LEFT JOIN event_details AS ed
-ON ed.event_id = e.id
-WHERE 
-(LOWER(e.title) LIKE $1 
-OR LOWER(e.description) LIKE $2 
-OR LOWER(e.organizer) LIKE $3) 
-AND (ed.show_date >= $4 AND ed.show_date <= $5);
+JOIN event_details AS ed
+	ON ed.event_id = e.id
+-- Ticket types are found and sorted by the price in effect, the first early price that hasn't ended or the list price.
+CROSS JOIN LATERAL (
+	SELECT COALESCE((
+		SELECT (schedule.entry->>'price')::bigint
+		FROM jsonb_array_elements(ed.price_schedule) WITH ORDINALITY AS schedule(entry, position)
+		WHERE ((schedule.entry->>'ends_at')::timestamptz AT TIME ZONE 'UTC') > @current_date_time::timestamp
+		ORDER BY schedule.position
+		LIMIT 1
+	), ed.price)::bigint AS price
+) AS current_price
+WHERE
+	e.status IN ('published', 'postponed')
+	AND (@search::text = '' OR e.search_vector @@ websearch_to_tsquery('english', @search::text))
+	AND (@organizer::text = '' OR LOWER(e.organizer) LIKE @organizer::text)
+	AND (@currency::text = '' OR e.currency = @currency::text)
+	AND ed.show_date >= @start_show_date::timestamp
+	AND (sqlc.narg('end_show_date')::timestamp IS NULL OR ed.show_date <= sqlc.narg('end_show_date')::timestamp)
+	AND (sqlc.narg('min_price')::bigint IS NULL OR current_price.price >= sqlc.narg('min_price')::bigint)
+	AND (sqlc.narg('max_price')::bigint IS NULL OR current_price.price <= sqlc.narg('max_price')::bigint)
+	AND (NOT @has_tickets_remaining::boolean OR ed.tickets_remaining > 0)
+	AND (NOT @free_only::boolean OR current_price.price = 0)
+	AND (ed.sales_end_at IS NULL OR ed.sales_end_at > @current_date_time::timestamp)
+	AND NOT EXISTS (
+		SELECT 1 FROM event_details AS unlocks_after
+		WHERE unlocks_after.id = ed.unlocks_after_event_detail_id AND unlocks_after.tickets_remaining > 0
+	)
+	AND (sqlc.narg('cursor_id')::uuid IS NULL
+		OR (@sort_by::text = 'show_date' AND (ed.show_date, ed.id) > (@cursor_show_date::timestamp, sqlc.narg('cursor_id')::uuid))
+		OR (@sort_by::text = 'price' AND (current_price.price, ed.id) > (@cursor_price::bigint, sqlc.narg('cursor_id')::uuid))
+		OR (@sort_by::text = 'price_desc' AND (current_price.price, ed.id) < (@cursor_price::bigint, sqlc.narg('cursor_id')::uuid))
+		OR (@sort_by::text = 'newest' AND (ed.created_at, ed.id) < (@cursor_created_at::timestamp, sqlc.narg('cursor_id')::uuid))
+		OR (@sort_by::text = 'relevance' AND (ts_rank(e.search_vector, websearch_to_tsquery('english', @search::text)), ed.id) < (@cursor_rank::real, sqlc.narg('cursor_id')::uuid)))
+ORDER BY
+	CASE WHEN @sort_by::text = 'show_date' THEN ed.show_date END ASC,
+	CASE WHEN @sort_by::text = 'price' THEN current_price.price END ASC,
+	CASE WHEN @sort_by::text = 'price_desc' THEN current_price.price END DESC,
+	CASE WHEN @sort_by::text = 'newest' THEN ed.created_at END DESC,
+	CASE WHEN @sort_by::text = 'relevance' THEN ts_rank(e.search_vector, websearch_to_tsquery('english', @search::text)) END DESC,
+	CASE WHEN @sort_by::text IN ('show_date', 'price') THEN ed.id END ASC,
+	ed.id DESC
+LIMIT @row_limit;
+
+-- name: CountEvents :one
+SELECT COUNT(*)
+FROM events AS e
+JOIN event_details AS ed
+	ON ed.event_id = e.id
+-- Ticket types are found and sorted by the price in effect, the first early price that hasn't ended or the list price.
+CROSS JOIN LATERAL (
+	SELECT COALESCE((
+		SELECT (schedule.entry->>'price')::bigint
+		FROM jsonb_array_elements(ed.price_schedule) WITH ORDINALITY AS schedule(entry, position)
+		WHERE ((schedule.entry->>'ends_at')::timestamptz AT TIME ZONE 'UTC') > @current_date_time::timestamp
+		ORDER BY schedule.position
+		LIMIT 1
+	), ed.price)::bigint AS price
+) AS current_price
+WHERE
+	e.status IN ('published', 'postponed')
+	AND (@search::text = '' OR e.search_vector @@ websearch_to_tsquery('english', @search::text))
+	AND (@organizer::text = '' OR LOWER(e.organizer) LIKE @organizer::text)
+	AND (@currency::text = '' OR e.currency = @currency::text)
+	AND ed.show_date >= @start_show_date::timestamp
+	AND (sqlc.narg('end_show_date')::timestamp IS NULL OR ed.show_date <= sqlc.narg('end_show_date')::timestamp)
+	AND (sqlc.narg('min_price')::bigint IS NULL OR current_price.price >= sqlc.narg('min_price')::bigint)
+	AND (sqlc.narg('max_price')::bigint IS NULL OR current_price.price <= sqlc.narg('max_price')::bigint)
+	AND (NOT @has_tickets_remaining::boolean OR ed.tickets_remaining > 0)
+	AND (NOT @free_only::boolean OR current_price.price = 0)
+	AND (ed.sales_end_at IS NULL OR ed.sales_end_at > @current_date_time::timestamp)
+	AND NOT EXISTS (
+		SELECT 1 FROM event_details AS unlocks_after
+		WHERE unlocks_after.id = ed.unlocks_after_event_detail_id AND unlocks_after.tickets_remaining > 0
+	);
 
 -- name: GetPaidEventForRefund :many
 SELECT
@@ -49,8 +128,9 @@ SELECT
 	p.user_id AS payer_user_id,
     p.amount,
     p.status,
+    p.currency,
 	e.title,
-	SUM(ed.price) AS ticket_price 
+	SUM(r.price - r.discount_amount)::bigint AS ticket_price 
 FROM events AS e
 JOIN event_details AS ed
     ON ed.event_id = e.id
@@ -59,7 +139,36 @@ JOIN reservations AS r
 JOIN payments AS p
     ON p.id = r.payment_id
 WHERE e.id = @event_id::uuid AND e.user_id = @user_id::uuid
-GROUP BY p.id, p.payment_intent_id, p.user_id, p.amount, p.status, e.title, ed.price;
+	AND (sqlc.narg('payer_user_id')::uuid IS NULL OR p.user_id = sqlc.narg('payer_user_id')::uuid)
+GROUP BY p.id, p.payment_intent_id, p.user_id, p.amount, p.status, p.currency, e.title;
+
+-- name: ReleaseEventPaymentReservations :many
+WITH released AS (
+	DELETE FROM reservations AS r
+	USING event_details AS ed
+	WHERE r.payment_id = @payment_id::uuid
+		AND r.event_detail_id = ed.id
+		AND ed.event_id = @event_id::uuid
+	RETURNING r.event_detail_id
+),
+counts AS (
+	SELECT event_detail_id, COUNT(*) AS cnt
+	FROM released
+	GROUP BY event_detail_id
+)
+UPDATE event_details AS ed
+SET tickets_remaining = ed.tickets_remaining + c.cnt
+FROM counts AS c
+WHERE ed.id = c.event_detail_id
+RETURNING ed.id;
+
+-- name: CountOtherEventPaymentReservations :one
+SELECT COUNT(*)
+FROM reservations AS r
+JOIN event_details AS ed
+	ON ed.id = r.event_detail_id
+WHERE r.payment_id = @payment_id::uuid
+	AND ed.event_id <> @event_id::uuid;
 
 -- name: GetEventConfirmedUserReservations :many
 SELECT 
@@ -77,4 +186,74 @@ JOIN users AS u
 	ON u.id = p.user_id 
 WHERE e.id = $1
 	AND p.status = 'succeeded'
-GROUP BY p.user_id, u.firstName, u.lastName, u.email;
\ No newline at end of file
+GROUP BY p.user_id, u.firstName, u.lastName, u.email;
+
+-- name: GetEventById :one
+SELECT *
+FROM events
+WHERE id = $1;
+
+-- name: GetPublicEvents :many
+SELECT
+	e.id AS event_id,
+	e.title,
+	e.description,
+	e.organizer,
+	e.status,
+	e.currency,
+	ed.id AS event_detail_id,
+	ed.show_date,
+	ed.price,
+	ed.tickets_remaining,
+	ed.ticket_description,
+	ed.sales_start_at,
+	ed.sales_end_at,
+	ed.price_schedule
+FROM events AS e
+JOIN event_details AS ed
+	ON ed.event_id = e.id
+WHERE
+	e.status IN ('published', 'postponed')
+	AND (@search::text = '' OR e.search_vector @@ websearch_to_tsquery('english', @search::text))
+	AND ed.show_date >= @start_show_date::timestamp
+	AND ed.show_date <= @end_show_date::timestamp
+	AND (ed.sales_end_at IS NULL OR ed.sales_end_at > @current_date_time::timestamp)
+	AND NOT EXISTS (
+		SELECT 1 FROM event_details AS unlocks_after
+		WHERE unlocks_after.id = ed.unlocks_after_event_detail_id AND unlocks_after.tickets_remaining > 0
+	)
+ORDER BY ed.show_date, ed.id;
+
+-- name: UpdateEventStatus :one
+UPDATE events
+SET status = @new_status::text, refund_deadline = sqlc.narg('refund_deadline')::timestamp, status_updated_at = NOW(), calendar_sequence = calendar_sequence + 1, updated_at = NOW()
+WHERE id = @id AND user_id = @user_id AND status = @current_status::text
+RETURNING id, title, description, organizer, created_at, updated_at, user_id, search_vector, status, refund_deadline, status_updated_at, currency, calendar_sequence, reminder_offset_minutes;
+
+-- name: UpdateEventReminderOffsets :one
+UPDATE events
+SET reminder_offset_minutes = $1, updated_at = NOW()
+WHERE id = $2 AND user_id = $3
+RETURNING id, title, description, organizer, created_at, updated_at, user_id, search_vector, status, refund_deadline, status_updated_at, currency, calendar_sequence, reminder_offset_minutes;
+
+-- name: GetEventAttendees :many
+SELECT
+	r.id AS reservation_id,
+	r.email,
+	ed.id AS event_detail_id,
+	ed.ticket_description,
+	ed.show_date,
+	r.checked_in_at
+FROM event_details AS ed
+JOIN reservations AS r
+	ON r.event_detail_id = ed.id
+JOIN payments AS p
+	ON p.id = r.payment_id
+WHERE ed.event_id = $1
+	AND p.status = 'succeeded'
+ORDER BY ed.show_date, r.email, r.id;
+
+-- name: BumpEventCalendarSequence :exec
+UPDATE events
+SET calendar_sequence = calendar_sequence + 1
+WHERE id = $1;
\ No newline at end of file
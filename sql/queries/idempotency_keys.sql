@@ -0,0 +1,29 @@
+-- name: ClaimIdempotencyKey :one
+INSERT INTO idempotency_keys (id, idempotency_key, request_method, request_path, request_fingerprint, expires_at, user_id)
+VALUES (@id, @idempotency_key, @request_method, @request_path, @request_fingerprint, @expires_at, @user_id)
+ON CONFLICT (user_id, idempotency_key) DO UPDATE
+SET id = EXCLUDED.id,
+	request_method = EXCLUDED.request_method,
+	request_path = EXCLUDED.request_path,
+	request_fingerprint = EXCLUDED.request_fingerprint,
+	response_status = NULL,
+	response_body = NULL,
+	created_at = NOW(),
+	completed_at = NULL,
+	expires_at = EXCLUDED.expires_at
+WHERE idempotency_keys.expires_at < NOW()
+	OR (idempotency_keys.completed_at IS NULL AND idempotency_keys.created_at < @stale_before::timestamp)
+RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE user_id = $1 AND idempotency_key = $2;
+
+-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_keys
+SET response_status = $1, response_body = $2, completed_at = NOW()
+WHERE id = $3;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE id = $1;
\ No newline at end of file
@@ -0,0 +1,13 @@
+-- name: GetOrganizerBranding :one
+SELECT * FROM organizer_brandings
+WHERE user_id = $1;
+
+-- name: UpsertOrganizerBranding :one
+INSERT INTO organizer_brandings (logo_url, accent_color, footer, user_id)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id) DO UPDATE
+SET logo_url = EXCLUDED.logo_url,
+	accent_color = EXCLUDED.accent_color,
+	footer = EXCLUDED.footer,
+	updated_at = NOW()
+RETURNING *;
\ No newline at end of file
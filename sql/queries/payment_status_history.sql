@@ -0,0 +1,4 @@
+-- name: GetPaymentStatusHistory :many
+SELECT * FROM payment_status_history
+WHERE payment_id = $1
+ORDER BY changed_at;
\ No newline at end of file
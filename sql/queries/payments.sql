@@ -1,32 +1,30 @@
 -- name: CreatePayment :one
-INSERT INTO payments (id, amount, currency, status, expires_at, user_id)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, payment_intent_id, amount, currency, status, expires_at, created_at, updated_at, user_id;
+INSERT INTO payments (id, amount, currency, status, expires_at, user_id, discount_amount, promo_code_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, payment_intent_id, amount, currency, status, expires_at, created_at, updated_at, user_id, discount_amount, promo_code_id;
 
 -- name: UpdatePayment :one
 UPDATE payments
-SET amount = $1, status = $2, updated_at = NOW(), payment_intent_id = $3
-WHERE id = $4 AND user_id = $5
-RETURNING id, payment_intent_id, amount, currency, status, expires_at, created_at, updated_at, user_id;
+SET amount = @amount, status = @status, updated_at = NOW(), payment_intent_id = @payment_intent_id
+WHERE id = @id AND user_id = @user_id AND status = @current_status::text
+RETURNING id, payment_intent_id, amount, currency, status, expires_at, created_at, updated_at, user_id, discount_amount, promo_code_id;
 
--- name: RestoreTicketsAndDeletePayment :exec
-WITH counts AS (
-  SELECT event_detail_id, COUNT(*) AS cnt
-  FROM reservations
+-- name: ReleasePaymentReservations :exec
+WITH released AS (
+  DELETE FROM reservations
   WHERE payment_id = @payment_id::uuid
-  GROUP BY event_detail_id
+    AND user_id = @user_id::uuid
+  RETURNING event_detail_id
 ),
-updated AS (
-  UPDATE event_details ed
-  SET tickets_remaining = ed.tickets_remaining + c.cnt
-  FROM counts c
-  WHERE ed.id = c.event_detail_id
-  RETURNING ed.id
+counts AS (
+  SELECT event_detail_id, COUNT(*) AS cnt
+  FROM released
+  GROUP BY event_detail_id
 )
-DELETE FROM payments
-WHERE id = @payment_id::uuid
-  AND user_id = @user_id::uuid
-  AND EXISTS (SELECT 1 FROM updated);
+UPDATE event_details ed
+SET tickets_remaining = ed.tickets_remaining + c.cnt
+FROM counts c
+WHERE ed.id = c.event_detail_id;
 
 -- name: GetPaymentById :one
 SELECT * FROM payments WHERE id = $1 AND user_id = $2;
@@ -43,14 +41,16 @@ SELECT
 	p.payment_intent_id,
   	p.user_id, 
 	p.amount,
+	p.currency,
 	p.status,
 	r.id AS reservation_id,
 	r.email,
+	r.discount_amount,
 	ed.id AS event_detail_id,
 	e.title,
 	ed.ticket_description,
 	ed.show_date,
-	ed.price 
+	r.price 
 FROM payments AS p 
 LEFT JOIN reservations AS r
 ON r.payment_id = p.id 
@@ -71,7 +71,7 @@ WITH event_details_update AS (
 ),
 payment_update AS (
 	UPDATE payments AS p
-	SET amount = p.amount - @amount::numeric
+	SET amount = p.amount - @amount::bigint
 	WHERE id = @payment_id::uuid AND user_id = @user_id::uuid
   RETURNING p.id
 )
@@ -84,4 +84,23 @@ WHERE id = @reservation_id::uuid
 SELECT * FROM payments WHERE id = ANY($1);
 
 -- name: GetPaymentByPaymentIntentId :one
-SELECT * FROM payments WHERE payment_intent_id = $1;
\ No newline at end of file
+SELECT * FROM payments WHERE payment_intent_id = $1;
+
+-- name: GetExpiredPendingPayments :many
+SELECT * FROM payments
+WHERE expires_at < @expired_before::timestamp
+	AND status IN ('pending', 'requires_action', 'requires_payment_method', 'payment_failed')
+ORDER BY expires_at
+LIMIT @batch_size;
+
+-- name: GetPaymentsToReconcile :many
+SELECT * FROM payments
+WHERE payment_intent_id IS NOT NULL
+	AND id > @after_id::uuid
+	AND (
+		status IN ('pending', 'requires_action', 'requires_payment_method', 'processing', 'payment_failed', 'refund_pending')
+		OR created_at >= @changed_since::timestamp
+		OR updated_at >= @changed_since::timestamp
+	)
+ORDER BY id
+LIMIT @batch_size;
\ No newline at end of file
@@ -0,0 +1,36 @@
+-- name: CreatePromoCode :one
+INSERT INTO promo_codes (id, code, discount_type, discount_value, max_uses, max_uses_per_user, min_quantity, valid_from, valid_until, event_id, event_detail_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (event_id, code) DO NOTHING
+RETURNING id, code, discount_type, discount_value, max_uses, max_uses_per_user, min_quantity, valid_from, valid_until, created_at, updated_at, event_id, event_detail_id;
+
+-- name: GetEventPromoCodes :many
+SELECT *
+FROM promo_codes
+WHERE event_id = $1
+ORDER BY created_at, id;
+
+-- name: UpdatePromoCode :one
+UPDATE promo_codes
+SET discount_type = $1, discount_value = $2, max_uses = $3, max_uses_per_user = $4, min_quantity = $5, valid_from = $6, valid_until = $7, event_detail_id = $8, updated_at = NOW()
+WHERE id = $9 AND event_id = $10
+RETURNING id, code, discount_type, discount_value, max_uses, max_uses_per_user, min_quantity, valid_from, valid_until, created_at, updated_at, event_id, event_detail_id;
+
+-- name: DeletePromoCode :execrows
+DELETE FROM promo_codes WHERE id = $1 AND event_id = $2;
+
+-- name: GetPromoCodesByCode :many
+SELECT *
+FROM promo_codes
+WHERE code = @code AND event_id = ANY(@event_ids::uuid[])
+ORDER BY created_at, id;
+
+-- name: LockPromoCode :one
+SELECT id FROM promo_codes WHERE id = $1 FOR UPDATE;
+
+-- name: GetPromoCodeUsage :one
+SELECT
+	COUNT(*) AS total_uses,
+	COUNT(*) FILTER (WHERE user_id = @user_id) AS user_uses
+FROM payments
+WHERE promo_code_id = @promo_code_id AND status <> 'cancelled';
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- name: CreateReconciliationIssue :one
+INSERT INTO reconciliation_issues (id, payment_id, payment_intent_id, issue_type, description, payment_status, gateway_status, resolution, resolved_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (payment_id, issue_type) WHERE resolved_at IS NULL DO UPDATE
+SET description = EXCLUDED.description,
+	payment_status = EXCLUDED.payment_status,
+	gateway_status = EXCLUDED.gateway_status,
+	resolution = EXCLUDED.resolution,
+	resolved_at = EXCLUDED.resolved_at,
+	occurrences = reconciliation_issues.occurrences + 1,
+	last_seen_at = NOW()
+RETURNING *;
+
+-- name: ResolveReconciliationIssues :exec
+UPDATE reconciliation_issues
+SET resolution = $1, resolved_at = NOW()
+WHERE payment_id = $2 AND resolved_at IS NULL;
\ No newline at end of file
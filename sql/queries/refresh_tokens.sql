@@ -0,0 +1,48 @@
+-- name: CreateRefreshToken :one
+INSERT INTO refresh_tokens (id, token_hash, family_id, access_token_id, access_token_expires_at, expires_at, user_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetRefreshTokenByHash :one
+SELECT * FROM refresh_tokens
+WHERE token_hash = $1;
+
+-- name: UseRefreshToken :one
+UPDATE refresh_tokens
+SET used_at = NOW()
+WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL
+RETURNING *;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeAccessToken :exec
+INSERT INTO revoked_access_tokens (jti, expires_at, user_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (jti) DO NOTHING;
+
+-- name: RevokeFamilyAccessTokens :exec
+INSERT INTO revoked_access_tokens (jti, expires_at, user_id)
+SELECT access_token_id, access_token_expires_at, user_id
+FROM refresh_tokens
+WHERE family_id = $1 AND access_token_expires_at > NOW()
+ON CONFLICT (jti) DO NOTHING;
+
+-- name: RevokeUserAccessTokens :exec
+INSERT INTO revoked_access_tokens (jti, expires_at, user_id)
+SELECT access_token_id, access_token_expires_at, user_id
+FROM refresh_tokens
+WHERE user_id = $1 AND access_token_expires_at > NOW()
+ON CONFLICT (jti) DO NOTHING;
+
+-- name: IsAccessTokenRevoked :one
+SELECT EXISTS (
+	SELECT 1 FROM revoked_access_tokens WHERE jti = $1
+);
\ No newline at end of file
@@ -5,7 +5,9 @@ WITH params AS (
         @reservation_id::uuid AS reservation_id, 
         @email::text AS email,
         @user_id::uuid AS user_id,
-        @payment_id::uuid AS payment_id), 
+        @payment_id::uuid AS payment_id,
+        @discount_amount::bigint AS discount_amount,
+        @price::bigint AS price), 
 updated_event_detail AS ( 
     UPDATE event_details ed 
     SET tickets_remaining = ed.tickets_remaining - 1 
@@ -13,16 +15,18 @@ updated_event_detail AS (
     WHERE ed.id = p.event_detail_id AND ed.tickets_remaining > 0 
     RETURNING ed.id AS event_detail_id ) 
 
-INSERT INTO reservations (id, email, event_detail_id, user_id, payment_id) 
+INSERT INTO reservations (id, email, event_detail_id, user_id, payment_id, discount_amount, price) 
 SELECT 
     p.reservation_id AS id, 
     p.email AS email, 
     u.event_detail_id,
     p.user_id AS user_id,
-    p.payment_id AS payment_id
+    p.payment_id AS payment_id,
+    p.discount_amount AS discount_amount,
+    p.price AS price
 FROM params p 
 CROSS JOIN updated_event_detail u 
-RETURNING id AS id, email AS email, created_at AS created_at, updated_at AS updated_at, event_detail_id AS event_detail_id, user_id AS user_id, payment_id AS payment_id;
+RETURNING id AS id, email AS email, created_at AS created_at, updated_at AS updated_at, event_detail_id AS event_detail_id, user_id AS user_id, payment_id AS payment_id, checked_in_at AS checked_in_at, checked_in_by AS checked_in_by, discount_amount AS discount_amount, price AS price;
 
 -- name: GetUserReservations :many
 SELECT * FROM reservations WHERE user_id = $1;
@@ -34,7 +38,29 @@ SELECT * FROM reservations WHERE id = $1 AND user_id = $2;
 UPDATE reservations
 SET email = $1, updated_at = NOW()
 WHERE id = $2 AND user_id = $3
-RETURNING id, email, created_at, updated_at, event_detail_id, user_id, payment_id;
+RETURNING id, email, created_at, updated_at, event_detail_id, user_id, payment_id, checked_in_at, checked_in_by, discount_amount, price;
 
 -- name: GetUserReservationsByPaymentId :many
-SELECT * FROM reservations WHERE user_id = $1 AND payment_id = $2;
\ No newline at end of file
+SELECT * FROM reservations WHERE user_id = $1 AND payment_id = $2;
+
+-- name: GetReservationForCheckIn :one
+SELECT
+	r.id,
+	r.email,
+	r.checked_in_at,
+	ed.event_id,
+	ed.show_date,
+	ed.ticket_description,
+	p.status AS payment_status
+FROM reservations AS r
+JOIN event_details AS ed
+	ON ed.id = r.event_detail_id
+JOIN payments AS p
+	ON p.id = r.payment_id
+WHERE r.id = $1;
+
+-- name: CheckInReservation :one
+UPDATE reservations
+SET checked_in_at = NOW(), checked_in_by = $1
+WHERE id = $2 AND checked_in_at IS NULL
+RETURNING id, email, created_at, updated_at, event_detail_id, user_id, payment_id, checked_in_at, checked_in_by, discount_amount, price;
\ No newline at end of file
@@ -0,0 +1,53 @@
+-- name: GetDueShowReminders :many
+SELECT
+	r.email,
+	ed.id AS event_detail_id,
+	ed.show_date,
+	ed.ticket_description,
+	e.title,
+	e.organizer,
+	e.user_id AS organizer_user_id,
+	reminder_offset.minutes::integer AS offset_minutes,
+	array_agg(r.id ORDER BY r.id)::uuid[] AS reservation_ids
+FROM reservations AS r
+JOIN payments AS p
+	ON p.id = r.payment_id
+JOIN event_details AS ed
+	ON ed.id = r.event_detail_id
+JOIN events AS e
+	ON e.id = ed.event_id
+CROSS JOIN LATERAL unnest(e.reminder_offset_minutes) AS reminder_offset(minutes)
+-- The preference is the recipient's, the buyer's when the tickets were booked for an email without an account.
+LEFT JOIN users AS recipient
+	ON LOWER(recipient.email) = LOWER(r.email)
+LEFT JOIN user_preferences AS up
+	ON up.user_id = COALESCE(recipient.id, r.user_id)
+WHERE p.status = 'succeeded'
+	AND e.status IN ('published', 'postponed')
+	AND COALESCE(up.show_reminders, TRUE)
+	AND ed.show_date > @now::timestamp
+	AND ed.show_date - make_interval(mins => reminder_offset.minutes) <= @now::timestamp
+	-- Tickets booked after a reminder was due don't get it, the confirmation email was sent just before.
+	AND r.created_at < ed.show_date - make_interval(mins => reminder_offset.minutes)
+	-- Only the closest due reminder is sent when a few are due at once, e.g. after the scheduler was down.
+	AND NOT EXISTS (
+		SELECT 1
+		FROM unnest(e.reminder_offset_minutes) AS closer_offset(minutes)
+		WHERE closer_offset.minutes < reminder_offset.minutes
+			AND ed.show_date - make_interval(mins => closer_offset.minutes) <= @now::timestamp
+	)
+	AND NOT EXISTS (
+		SELECT 1
+		FROM show_reminders AS sr
+		WHERE sr.reservation_id = r.id
+			AND sr.offset_minutes = reminder_offset.minutes
+			AND sr.show_date = ed.show_date
+	)
+GROUP BY r.email, ed.id, e.id, reminder_offset.minutes
+ORDER BY ed.show_date, r.email
+LIMIT @batch_size;
+
+-- name: CreateShowReminders :execrows
+INSERT INTO show_reminders (reservation_id, offset_minutes, show_date)
+SELECT unnest(@reservation_ids::uuid[]), @offset_minutes, @show_date
+ON CONFLICT DO NOTHING;
\ No newline at end of file
@@ -0,0 +1,38 @@
+-- name: RecordStripeWebhookEvent :one
+INSERT INTO stripe_webhook_events (id, event_type, webhook_type, payload)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (id) DO UPDATE
+SET updated_at = NOW()
+RETURNING *;
+
+-- name: ClaimStripeWebhookEvent :one
+UPDATE stripe_webhook_events
+SET processing_started_at = NOW(), updated_at = NOW()
+WHERE id = @id
+	AND processed_at IS NULL
+	AND (processing_started_at IS NULL OR processing_started_at < @stale_before::timestamp)
+RETURNING id;
+
+-- name: MarkStripeWebhookEventProcessed :exec
+UPDATE stripe_webhook_events
+SET attempts = attempts + 1, processing_error = NULL, processed_at = NOW(), processing_started_at = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkStripeWebhookEventFailed :exec
+UPDATE stripe_webhook_events
+SET attempts = attempts + 1, processing_error = $1, processing_started_at = NULL, updated_at = NOW()
+WHERE id = $2;
+
+-- name: GetStripeWebhookEventById :one
+SELECT * FROM stripe_webhook_events
+WHERE id = $1;
+
+-- name: GetStripeWebhookEvents :many
+SELECT * FROM stripe_webhook_events
+WHERE (@status::text = ''
+		OR (@status::text = 'processed' AND processed_at IS NOT NULL)
+		OR (@status::text = 'failed' AND processed_at IS NULL AND processing_error IS NOT NULL)
+		OR (@status::text = 'pending' AND processed_at IS NULL AND processing_error IS NULL))
+	AND (@event_type::text = '' OR event_type = @event_type::text)
+ORDER BY received_at DESC
+LIMIT @row_limit OFFSET @row_offset;
\ No newline at end of file
@@ -0,0 +1,18 @@
+-- name: CreateUserActionToken :one
+INSERT INTO user_action_tokens (id, token_hash, purpose, expires_at, user_id)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: ConsumeUserActionToken :one
+UPDATE user_action_tokens
+SET used_at = NOW()
+WHERE token_hash = $1
+	AND purpose = $2
+	AND used_at IS NULL
+	AND expires_at > NOW()
+RETURNING *;
+
+-- name: InvalidateUserActionTokens :exec
+UPDATE user_action_tokens
+SET used_at = NOW()
+WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL;
\ No newline at end of file
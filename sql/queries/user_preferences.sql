@@ -0,0 +1,11 @@
+-- name: GetUserPreferences :one
+SELECT * FROM user_preferences
+WHERE user_id = $1;
+
+-- name: UpsertUserPreferences :one
+INSERT INTO user_preferences (show_reminders, user_id)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET show_reminders = EXCLUDED.show_reminders,
+	updated_at = NOW()
+RETURNING *;
\ No newline at end of file
@@ -1,10 +1,38 @@
 -- name: CreateUser :one
 INSERT INTO users (id, firstname, lastname, email, password)
 VALUES ($1, $2, $3, $4, $5)
-RETURNING id, firstname, lastname, email, password, created_at, updated_at;
+RETURNING id, firstname, lastname, email, password, created_at, updated_at, email_verified_at, role;
 
 -- name: GetUserByEmail :one
 SELECT * FROM users WHERE email = $1;
 
 -- name: GetUserById :one
-SELECT * FROM users WHERE id = $1;
\ No newline at end of file
+SELECT * FROM users WHERE id = $1;
+
+-- name: MarkUserEmailVerified :one
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+WHERE id = $1
+RETURNING id, firstname, lastname, email, password, created_at, updated_at, email_verified_at, role;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET password = $1, updated_at = NOW()
+WHERE id = $2;
+
+-- name: GetUsers :many
+SELECT * FROM users
+WHERE (@role::text = '' OR role = @role::text)
+ORDER BY created_at DESC
+LIMIT @row_limit OFFSET @row_offset;
+
+-- name: UpdateUserRole :one
+UPDATE users
+SET role = $1, updated_at = NOW()
+WHERE id = $2
+RETURNING id, firstname, lastname, email, password, created_at, updated_at, email_verified_at, role;
+
+-- name: PromoteUsersToAdmin :execrows
+UPDATE users
+SET role = 'admin', updated_at = NOW()
+WHERE LOWER(email) = ANY(@emails::text[]) AND role <> 'admin';
\ No newline at end of file
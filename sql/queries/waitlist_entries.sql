@@ -0,0 +1,124 @@
+-- name: CreateWaitlistEntry :one
+INSERT INTO waitlist_entries (id, quantity, event_detail_id, user_id)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT DO NOTHING
+RETURNING id, quantity, status, claim_token_hash, offer_expires_at, offered_at, created_at, updated_at, event_detail_id, user_id;
+
+-- name: GetUserWaitlistEntries :many
+SELECT
+	w.id,
+	w.quantity,
+	w.status,
+	w.offer_expires_at,
+	w.created_at,
+	w.event_detail_id,
+	(
+		SELECT COUNT(*)
+		FROM waitlist_entries AS ahead
+		WHERE ahead.event_detail_id = w.event_detail_id
+			AND ahead.status = 'waiting'
+			AND (ahead.created_at, ahead.id) <= (w.created_at, w.id)
+	)::bigint AS position
+FROM waitlist_entries AS w
+WHERE w.user_id = $1 AND w.status IN ('waiting', 'offered')
+ORDER BY w.created_at, w.id;
+
+-- name: CancelWaitlistEntry :one
+WITH cancelled AS (
+	UPDATE waitlist_entries AS w
+	SET status = 'cancelled', claim_token_hash = NULL, updated_at = NOW()
+	FROM (
+		SELECT id, status
+		FROM waitlist_entries
+		WHERE event_detail_id = @event_detail_id AND user_id = @user_id AND status IN ('waiting', 'offered')
+		FOR UPDATE
+	) AS active
+	WHERE w.id = active.id
+	RETURNING w.id, w.event_detail_id, w.quantity, active.status AS previous_status
+),
+released AS (
+	UPDATE event_details AS ed
+	SET tickets_remaining = ed.tickets_remaining + c.quantity
+	FROM cancelled AS c
+	WHERE ed.id = c.event_detail_id AND c.previous_status = 'offered'
+	RETURNING ed.id
+)
+SELECT id, event_detail_id, quantity, previous_status FROM cancelled;
+
+-- name: OfferNextWaitlistEntry :one
+WITH next_entry AS (
+	SELECT w.id, w.quantity, w.event_detail_id
+	FROM waitlist_entries AS w
+	JOIN event_details AS ed
+		ON ed.id = w.event_detail_id
+	JOIN events AS e
+		ON e.id = ed.event_id
+	WHERE w.event_detail_id = @event_detail_id
+		AND w.status = 'waiting'
+		AND e.status IN ('published', 'postponed')
+		AND ed.show_date > NOW()
+	ORDER BY w.created_at, w.id
+	LIMIT 1
+	FOR UPDATE OF w SKIP LOCKED
+),
+held AS (
+	UPDATE event_details AS ed
+	SET tickets_remaining = ed.tickets_remaining - n.quantity
+	FROM next_entry AS n
+	WHERE ed.id = n.event_detail_id AND ed.tickets_remaining >= n.quantity
+	RETURNING ed.id
+)
+UPDATE waitlist_entries AS w
+SET status = 'offered', claim_token_hash = @claim_token_hash, offered_at = NOW(), offer_expires_at = @offer_expires_at, updated_at = NOW()
+FROM next_entry AS n
+WHERE w.id = n.id AND EXISTS (SELECT 1 FROM held)
+RETURNING w.id, w.quantity, w.status, w.claim_token_hash, w.offer_expires_at, w.offered_at, w.created_at, w.updated_at, w.event_detail_id, w.user_id;
+
+-- name: GetWaitlistEntryByClaimTokenHash :one
+SELECT *
+FROM waitlist_entries
+WHERE claim_token_hash = $1;
+
+-- name: ClaimWaitlistOffer :one
+WITH claimed AS (
+	UPDATE waitlist_entries AS w
+	SET status = 'claimed', claim_token_hash = NULL, updated_at = NOW()
+	WHERE w.id = @id AND w.user_id = @user_id AND w.status = 'offered' AND w.offer_expires_at > NOW()
+	RETURNING w.id, w.quantity, w.status, w.claim_token_hash, w.offer_expires_at, w.offered_at, w.created_at, w.updated_at, w.event_detail_id, w.user_id
+),
+released AS (
+	UPDATE event_details AS ed
+	SET tickets_remaining = ed.tickets_remaining + c.quantity
+	FROM claimed AS c
+	WHERE ed.id = c.event_detail_id
+	RETURNING ed.id
+)
+SELECT id, quantity, status, claim_token_hash, offer_expires_at, offered_at, created_at, updated_at, event_detail_id, user_id FROM claimed;
+
+-- name: ExpireWaitlistOffers :many
+WITH expired AS (
+	UPDATE waitlist_entries AS w
+	SET status = 'expired', claim_token_hash = NULL, updated_at = NOW()
+	WHERE w.id IN (
+		SELECT id
+		FROM waitlist_entries
+		WHERE status = 'offered' AND offer_expires_at < @expired_before::timestamp
+		ORDER BY offer_expires_at
+		LIMIT @batch_size
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING w.event_detail_id, w.quantity
+),
+released AS (
+	UPDATE event_details AS ed
+	SET tickets_remaining = ed.tickets_remaining + e.quantity
+	FROM (SELECT event_detail_id, SUM(quantity) AS quantity FROM expired GROUP BY event_detail_id) AS e
+	WHERE ed.id = e.event_detail_id
+	RETURNING ed.id
+)
+SELECT id FROM released;
+
+-- name: GetWaitlistedEventDetailIds :many
+SELECT DISTINCT event_detail_id
+FROM waitlist_entries
+WHERE event_detail_id = ANY(@event_detail_ids::uuid[]) AND status = 'waiting';
\ No newline at end of file
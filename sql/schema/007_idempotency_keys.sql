@@ -0,0 +1,20 @@
+-- +goose Up
+
+CREATE TABLE idempotency_keys (
+    id UUID PRIMARY KEY,
+    idempotency_key TEXT NOT NULL,
+    request_method TEXT NOT NULL,
+    request_path TEXT NOT NULL,
+    request_fingerprint TEXT NOT NULL,
+    response_status INTEGER NULL,
+    response_body TEXT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP NULL,
+    expires_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (user_id, idempotency_key)
+);
+
+-- +goose Down
+
+DROP TABLE idempotency_keys;
\ No newline at end of file
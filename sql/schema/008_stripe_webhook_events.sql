@@ -0,0 +1,19 @@
+-- +goose Up
+
+CREATE TABLE stripe_webhook_events (
+    id TEXT PRIMARY KEY,
+    event_type TEXT NOT NULL,
+    webhook_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    processing_error TEXT NULL,
+    received_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    processed_at TIMESTAMP NULL,
+    updated_at TIMESTAMP NULL
+);
+
+CREATE INDEX idx_stripe_webhook_events_received_at ON stripe_webhook_events (received_at DESC);
+
+-- +goose Down
+
+DROP TABLE stripe_webhook_events;
\ No newline at end of file
@@ -0,0 +1,24 @@
+-- +goose Up
+
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP NULL;
+
+-- Existing accounts were created before verification existed, treat them as verified.
+UPDATE users SET email_verified_at = created_at;
+
+CREATE TABLE user_action_tokens (
+    id UUID PRIMARY KEY,
+    token_hash TEXT NOT NULL UNIQUE,
+    purpose TEXT NOT NULL CHECK (purpose IN ('email_verification', 'password_reset')),
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_user_action_tokens_user_id ON user_action_tokens (user_id, purpose);
+
+-- +goose Down
+
+DROP TABLE user_action_tokens;
+
+ALTER TABLE users DROP COLUMN email_verified_at;
\ No newline at end of file
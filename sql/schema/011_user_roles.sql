@@ -0,0 +1,16 @@
+-- +goose Up
+
+ALTER TABLE users
+ADD COLUMN role TEXT NOT NULL DEFAULT 'attendee' CHECK (role IN ('admin', 'organizer', 'attendee'));
+
+-- Users that already created events keep being able to manage them.
+UPDATE users SET role = 'organizer'
+WHERE id IN (SELECT DISTINCT user_id FROM events);
+
+CREATE INDEX idx_users_role ON users (role);
+
+-- +goose Down
+
+DROP INDEX idx_users_role;
+
+ALTER TABLE users DROP COLUMN role;
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- +goose Up
+
+-- Title matches rank above description matches, which rank above organizer matches.
+ALTER TABLE events
+ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', COALESCE(title, '')), 'A')
+    || setweight(to_tsvector('english', COALESCE(description, '')), 'B')
+    || setweight(to_tsvector('english', COALESCE(organizer, '')), 'C')
+) STORED;
+
+CREATE INDEX idx_events_search_vector ON events USING GIN (search_vector);
+
+-- +goose Down
+
+DROP INDEX idx_events_search_vector;
+
+ALTER TABLE events DROP COLUMN search_vector;
\ No newline at end of file
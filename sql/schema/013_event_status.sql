@@ -0,0 +1,20 @@
+-- +goose Up
+
+-- Events created before statuses existed were already visible, so they start out published.
+ALTER TABLE events
+ADD COLUMN status TEXT NOT NULL DEFAULT 'published' CHECK (status IN ('draft', 'published', 'cancelled', 'postponed')),
+ADD COLUMN refund_deadline TIMESTAMP NULL,
+ADD COLUMN status_updated_at TIMESTAMP NULL;
+
+ALTER TABLE events ALTER COLUMN status SET DEFAULT 'draft';
+
+CREATE INDEX idx_events_status ON events (status);
+
+-- +goose Down
+
+DROP INDEX idx_events_status;
+
+ALTER TABLE events
+DROP COLUMN status_updated_at,
+DROP COLUMN refund_deadline,
+DROP COLUMN status;
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- +goose Up
+
+ALTER TABLE reservations
+ADD COLUMN checked_in_at TIMESTAMP NULL,
+ADD COLUMN checked_in_by UUID NULL REFERENCES users(id) ON DELETE SET NULL;
+
+-- +goose Down
+
+ALTER TABLE reservations
+DROP COLUMN checked_in_by,
+DROP COLUMN checked_in_at;
\ No newline at end of file
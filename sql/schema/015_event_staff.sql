@@ -0,0 +1,19 @@
+-- +goose Up
+
+-- Staff are invited by email, the invite applies once an account with that verified email address exists.
+CREATE TABLE event_staff (
+    id UUID PRIMARY KEY,
+    email TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('check_in', 'view_attendees', 'manage_tickets')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NULL,
+    event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    invited_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (event_id, email)
+);
+
+CREATE INDEX idx_event_staff_email ON event_staff (email);
+
+-- +goose Down
+
+DROP TABLE event_staff;
\ No newline at end of file
@@ -0,0 +1,23 @@
+-- +goose Up
+
+-- An offered entry holds its quantity out of tickets_remaining until it is claimed, cancelled or expires.
+CREATE TABLE waitlist_entries (
+    id UUID PRIMARY KEY,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    status TEXT NOT NULL DEFAULT 'waiting' CHECK (status IN ('waiting', 'offered', 'claimed', 'expired', 'cancelled')),
+    claim_token_hash TEXT NULL UNIQUE,
+    offer_expires_at TIMESTAMP NULL,
+    offered_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NULL,
+    event_detail_id UUID NOT NULL REFERENCES event_details(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_waitlist_entries_active_user ON waitlist_entries (event_detail_id, user_id) WHERE status IN ('waiting', 'offered');
+CREATE INDEX idx_waitlist_entries_queue ON waitlist_entries (event_detail_id, created_at, id) WHERE status = 'waiting';
+CREATE INDEX idx_waitlist_entries_offer_expires_at ON waitlist_entries (offer_expires_at) WHERE status = 'offered';
+
+-- +goose Down
+
+DROP TABLE waitlist_entries;
\ No newline at end of file
@@ -0,0 +1,44 @@
+-- +goose Up
+
+-- Codes are stored upper case. A code without event_detail_id applies to every ticket type of the event.
+CREATE TABLE promo_codes (
+    id UUID PRIMARY KEY,
+    code TEXT NOT NULL,
+    discount_type TEXT NOT NULL CHECK (discount_type IN ('percentage', 'fixed_amount')),
+    discount_value NUMERIC(10, 2) NOT NULL CHECK (discount_value > 0),
+    max_uses INTEGER NULL CHECK (max_uses > 0),
+    max_uses_per_user INTEGER NULL CHECK (max_uses_per_user > 0),
+    min_quantity INTEGER NOT NULL DEFAULT 1 CHECK (min_quantity > 0),
+    valid_from TIMESTAMP NULL,
+    valid_until TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NULL,
+    event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    event_detail_id UUID NULL REFERENCES event_details(id) ON DELETE CASCADE,
+    CHECK (discount_type <> 'percentage' OR discount_value <= 100),
+    CHECK (valid_from IS NULL OR valid_until IS NULL OR valid_from < valid_until),
+    UNIQUE (event_id, code)
+);
+
+-- The discount of a payment is split over its reservations so refunding a single ticket returns what was paid for it.
+ALTER TABLE payments
+ADD COLUMN discount_amount NUMERIC(10, 2) NOT NULL DEFAULT 0.00,
+ADD COLUMN promo_code_id UUID NULL REFERENCES promo_codes(id) ON DELETE SET NULL;
+
+ALTER TABLE reservations
+ADD COLUMN discount_amount NUMERIC(10, 2) NOT NULL DEFAULT 0.00;
+
+CREATE INDEX idx_payments_promo_code ON payments (promo_code_id, user_id) WHERE promo_code_id IS NOT NULL;
+
+-- +goose Down
+
+DROP INDEX idx_payments_promo_code;
+
+ALTER TABLE reservations
+DROP COLUMN discount_amount;
+
+ALTER TABLE payments
+DROP COLUMN promo_code_id,
+DROP COLUMN discount_amount;
+
+DROP TABLE promo_codes;
\ No newline at end of file
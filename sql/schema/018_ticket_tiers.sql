@@ -0,0 +1,33 @@
+-- +goose Up
+
+-- price_schedule lists the early prices of a ticket type ordered by ends_at, price is charged once the last one ended.
+-- A ticket type with unlocks_after_event_detail_id stays hidden until that ticket type sells out.
+ALTER TABLE event_details
+ADD COLUMN sales_start_at TIMESTAMP NULL,
+ADD COLUMN sales_end_at TIMESTAMP NULL,
+ADD COLUMN price_schedule JSONB NOT NULL DEFAULT '[]',
+ADD COLUMN unlocks_after_event_detail_id UUID NULL REFERENCES event_details(id) ON DELETE SET NULL,
+ADD CONSTRAINT event_details_sales_window_check CHECK (sales_start_at IS NULL OR sales_end_at IS NULL OR sales_start_at < sales_end_at),
+ADD CONSTRAINT event_details_unlocks_after_check CHECK (unlocks_after_event_detail_id <> id);
+
+-- Reservations keep the price they were charged, the ticket type's price changes over time.
+ALTER TABLE reservations
+ADD COLUMN price NUMERIC(10, 2) NOT NULL DEFAULT 0.00;
+
+UPDATE reservations AS r
+SET price = ed.price
+FROM event_details AS ed
+WHERE ed.id = r.event_detail_id;
+
+-- +goose Down
+
+ALTER TABLE reservations
+DROP COLUMN price;
+
+ALTER TABLE event_details
+DROP CONSTRAINT event_details_unlocks_after_check,
+DROP CONSTRAINT event_details_sales_window_check,
+DROP COLUMN unlocks_after_event_detail_id,
+DROP COLUMN price_schedule,
+DROP COLUMN sales_end_at,
+DROP COLUMN sales_start_at;
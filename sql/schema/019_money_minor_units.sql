@@ -0,0 +1,83 @@
+-- +goose Up
+
+-- Amounts are stored in the minor unit of the currency. Existing amounts were always sent to Stripe multiplied by 100,
+-- so they are converted the same way whatever their currency. Percentage discounts become basis points, 12.50 is 1250.
+ALTER TABLE promo_codes
+DROP CONSTRAINT promo_codes_check;
+
+ALTER TABLE event_details
+ALTER COLUMN price DROP DEFAULT,
+ALTER COLUMN price TYPE BIGINT USING ROUND(price * 100)::BIGINT,
+ALTER COLUMN price SET DEFAULT 0;
+
+UPDATE event_details
+SET price_schedule = (
+    SELECT COALESCE(jsonb_agg(jsonb_build_object('price', ROUND((entry->>'price')::NUMERIC * 100)::BIGINT, 'ends_at', entry->'ends_at') ORDER BY position), '[]')
+    FROM jsonb_array_elements(price_schedule) WITH ORDINALITY AS schedule(entry, position)
+);
+
+ALTER TABLE payments
+ALTER COLUMN amount DROP DEFAULT,
+ALTER COLUMN amount TYPE BIGINT USING ROUND(amount * 100)::BIGINT,
+ALTER COLUMN amount SET DEFAULT 0,
+ALTER COLUMN discount_amount DROP DEFAULT,
+ALTER COLUMN discount_amount TYPE BIGINT USING ROUND(discount_amount * 100)::BIGINT,
+ALTER COLUMN discount_amount SET DEFAULT 0;
+
+ALTER TABLE payment_logs
+ALTER COLUMN amount DROP DEFAULT,
+ALTER COLUMN amount TYPE BIGINT USING ROUND(amount * 100)::BIGINT,
+ALTER COLUMN amount SET DEFAULT 0;
+
+ALTER TABLE reservations
+ALTER COLUMN price DROP DEFAULT,
+ALTER COLUMN price TYPE BIGINT USING ROUND(price * 100)::BIGINT,
+ALTER COLUMN price SET DEFAULT 0,
+ALTER COLUMN discount_amount DROP DEFAULT,
+ALTER COLUMN discount_amount TYPE BIGINT USING ROUND(discount_amount * 100)::BIGINT,
+ALTER COLUMN discount_amount SET DEFAULT 0;
+
+ALTER TABLE promo_codes
+ALTER COLUMN discount_value TYPE BIGINT USING ROUND(discount_value * 100)::BIGINT,
+ADD CONSTRAINT promo_codes_percentage_check CHECK (discount_type <> 'percentage' OR discount_value <= 10000);
+
+-- +goose Down
+
+ALTER TABLE promo_codes
+DROP CONSTRAINT promo_codes_percentage_check,
+ALTER COLUMN discount_value TYPE NUMERIC(10, 2) USING discount_value / 100.0;
+
+ALTER TABLE promo_codes
+ADD CONSTRAINT promo_codes_check CHECK (discount_type <> 'percentage' OR discount_value <= 100);
+
+ALTER TABLE reservations
+ALTER COLUMN discount_amount DROP DEFAULT,
+ALTER COLUMN discount_amount TYPE NUMERIC(10, 2) USING discount_amount / 100.0,
+ALTER COLUMN discount_amount SET DEFAULT 0.00,
+ALTER COLUMN price DROP DEFAULT,
+ALTER COLUMN price TYPE NUMERIC(10, 2) USING price / 100.0,
+ALTER COLUMN price SET DEFAULT 0.00;
+
+ALTER TABLE payment_logs
+ALTER COLUMN amount DROP DEFAULT,
+ALTER COLUMN amount TYPE NUMERIC(10, 2) USING amount / 100.0,
+ALTER COLUMN amount SET DEFAULT 0.00;
+
+ALTER TABLE payments
+ALTER COLUMN discount_amount DROP DEFAULT,
+ALTER COLUMN discount_amount TYPE NUMERIC(10, 2) USING discount_amount / 100.0,
+ALTER COLUMN discount_amount SET DEFAULT 0.00,
+ALTER COLUMN amount DROP DEFAULT,
+ALTER COLUMN amount TYPE NUMERIC(10, 2) USING amount / 100.0,
+ALTER COLUMN amount SET DEFAULT 0.00;
+
+UPDATE event_details
+SET price_schedule = (
+    SELECT COALESCE(jsonb_agg(jsonb_build_object('price', to_char((entry->>'price')::NUMERIC / 100.0, 'FM9999999990.00'), 'ends_at', entry->'ends_at') ORDER BY position), '[]')
+    FROM jsonb_array_elements(price_schedule) WITH ORDINALITY AS schedule(entry, position)
+);
+
+ALTER TABLE event_details
+ALTER COLUMN price DROP DEFAULT,
+ALTER COLUMN price TYPE NUMERIC(10, 2) USING price / 100.0,
+ALTER COLUMN price SET DEFAULT 0.00;
\ No newline at end of file
@@ -0,0 +1,25 @@
+-- +goose Up
+
+-- Ticket prices are in the currency of their event, the organizer picks it when creating the event.
+ALTER TABLE events
+ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';
+
+-- Payments used the currency the buyer asked for, codes are stored upper case from now on.
+UPDATE payments
+SET currency = UPPER(currency);
+
+ALTER TABLE payment_logs
+ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';
+
+UPDATE payment_logs AS pl
+SET currency = p.currency
+FROM payments AS p
+WHERE p.id = pl.payment_id;
+
+-- +goose Down
+
+ALTER TABLE payment_logs
+DROP COLUMN currency;
+
+ALTER TABLE events
+DROP COLUMN currency;
\ No newline at end of file
@@ -0,0 +1,28 @@
+-- +goose Up
+
+-- Mismatches the nightly reconciliation finds between payments and the payment gateway. An issue stays open until it is
+-- repaired or no longer detected, each run that finds it again bumps occurrences instead of adding a row.
+CREATE TABLE reconciliation_issues (
+    id UUID PRIMARY KEY,
+    payment_intent_id TEXT NOT NULL,
+    issue_type TEXT NOT NULL,
+    description TEXT NOT NULL,
+    payment_status TEXT NOT NULL,
+    gateway_status TEXT NOT NULL,
+    resolution TEXT NULL,
+    occurrences INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP NULL,
+    payment_id UUID NULL REFERENCES payments(id) ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX idx_reconciliation_issues_open ON reconciliation_issues (payment_id, issue_type) WHERE resolved_at IS NULL;
+
+CREATE INDEX idx_payments_updated_at ON payments (updated_at);
+
+-- +goose Down
+
+DROP INDEX idx_payments_updated_at;
+
+DROP TABLE reconciliation_issues;
\ No newline at end of file
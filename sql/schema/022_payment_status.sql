@@ -0,0 +1,58 @@
+-- +goose Up
+
+-- Payments used the gateway's spelling of a few statuses and stored the error code of declined cards, those are all
+-- failed payments now.
+UPDATE payments SET status = 'refund_pending' WHERE status = 'refund pending';
+UPDATE payments SET status = 'cancelled' WHERE status = 'canceled';
+UPDATE payments SET status = 'payment_failed'
+WHERE status NOT IN ('pending', 'requires_action', 'requires_payment_method', 'processing', 'payment_failed', 'succeeded', 'refund_pending', 'refund_failed', 'refunded', 'cancelled');
+
+ALTER TABLE payments
+ADD CONSTRAINT payments_status_check CHECK (status IN ('pending', 'requires_action', 'requires_payment_method', 'processing', 'payment_failed', 'succeeded', 'refund_pending', 'refund_failed', 'refunded', 'cancelled'));
+
+-- Every status a payment went through, written by a trigger so no query can change a status without leaving a trail.
+CREATE TABLE payment_status_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    from_status TEXT NULL,
+    to_status TEXT NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_payment_status_history_payment_id ON payment_status_history (payment_id, changed_at);
+
+INSERT INTO payment_status_history (to_status, changed_at, payment_id)
+SELECT status, COALESCE(updated_at, created_at), id
+FROM payments;
+
+-- +goose StatementBegin
+CREATE FUNCTION record_payment_status_change() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND OLD.status = NEW.status THEN
+        RETURN NEW;
+    END IF;
+
+    INSERT INTO payment_status_history (from_status, to_status, payment_id)
+    VALUES (CASE WHEN TG_OP = 'UPDATE' THEN OLD.status END, NEW.status, NEW.id);
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER payments_status_history
+AFTER INSERT OR UPDATE OF status ON payments
+FOR EACH ROW EXECUTE FUNCTION record_payment_status_change();
+
+-- +goose Down
+
+DROP TRIGGER payments_status_history ON payments;
+
+DROP FUNCTION record_payment_status_change();
+
+DROP TABLE payment_status_history;
+
+ALTER TABLE payments
+DROP CONSTRAINT payments_status_check;
+
+UPDATE payments SET status = 'refund pending' WHERE status = 'refund_pending';
\ No newline at end of file
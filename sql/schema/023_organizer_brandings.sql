@@ -0,0 +1,15 @@
+-- +goose Up
+
+-- How an organizer's emails look to their attendees. Empty values fall back to the default Event - MRS branding.
+CREATE TABLE organizer_brandings (
+    logo_url TEXT NULL,
+    accent_color TEXT NULL CHECK (accent_color ~ '^#[0-9a-fA-F]{6}$'),
+    footer TEXT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NULL,
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+
+DROP TABLE organizer_brandings;
\ No newline at end of file
@@ -0,0 +1,28 @@
+-- +goose Up
+
+-- Emails are written here in the same transaction as the change they are about and sent by the outbox worker, which
+-- retries them with backoff. Emails that still fail after the last attempt are dead until an admin resends them.
+CREATE TABLE email_outbox (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    sender TEXT NOT NULL,
+    recipient TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    text_body TEXT NOT NULL,
+    html_body TEXT NOT NULL DEFAULT '',
+    attachments JSONB NOT NULL DEFAULT '[]',
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'dead')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NULL,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NULL
+);
+
+CREATE INDEX idx_email_outbox_next_attempt_at ON email_outbox (next_attempt_at) WHERE status = 'pending';
+
+CREATE INDEX idx_email_outbox_status_created_at ON email_outbox (status, created_at DESC);
+
+-- +goose Down
+
+DROP TABLE email_outbox;
\ No newline at end of file
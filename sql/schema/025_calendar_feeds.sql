@@ -0,0 +1,18 @@
+-- +goose Up
+
+-- Calendar clients only replace an event they already have when its sequence went up. It is bumped when the event
+-- changes or the date or description of one of its shows does.
+ALTER TABLE events ADD COLUMN calendar_sequence INTEGER NOT NULL DEFAULT 0;
+
+-- The secret token a user's calendar subscribes to their reservations with, only its hash is stored.
+CREATE TABLE calendar_feeds (
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+
+DROP TABLE calendar_feeds;
+
+ALTER TABLE events DROP COLUMN calendar_sequence;
\ No newline at end of file
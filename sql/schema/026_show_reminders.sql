@@ -0,0 +1,30 @@
+-- +goose Up
+
+-- How many minutes before each show of the event its attendees are reminded, 7 days and 24 hours by default.
+ALTER TABLE events ADD COLUMN reminder_offset_minutes INTEGER[] NOT NULL DEFAULT '{10080,1440}';
+
+-- The reminders that were sent. The show date is part of the key so the attendees of a postponed show are reminded of
+-- the new date.
+CREATE TABLE show_reminders (
+    offset_minutes INTEGER NOT NULL,
+    show_date TIMESTAMP NOT NULL,
+    sent_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    reservation_id UUID NOT NULL REFERENCES reservations(id) ON DELETE CASCADE,
+    PRIMARY KEY (reservation_id, offset_minutes, show_date)
+);
+
+-- Users without preferences get the defaults.
+CREATE TABLE user_preferences (
+    show_reminders BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NULL,
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+
+DROP TABLE user_preferences;
+
+DROP TABLE show_reminders;
+
+ALTER TABLE events DROP COLUMN reminder_offset_minutes;
\ No newline at end of file
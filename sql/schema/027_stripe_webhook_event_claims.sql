@@ -0,0 +1,9 @@
+-- +goose Up
+
+-- Set while a delivery or replay processes the event so another one of the same event skips it. A claim older than the
+-- processing timeout was left by a crashed process and can be taken over.
+ALTER TABLE stripe_webhook_events ADD COLUMN processing_started_at TIMESTAMP NULL;
+
+-- +goose Down
+
+ALTER TABLE stripe_webhook_events DROP COLUMN processing_started_at;
\ No newline at end of file
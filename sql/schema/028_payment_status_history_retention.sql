@@ -0,0 +1,13 @@
+-- +goose Up
+
+-- Payments are cancelled instead of deleted, the history is no longer tied to the payment row either so removing one,
+-- e.g. with its user, keeps the trail. payment_id stays as a plain column.
+ALTER TABLE payment_status_history DROP CONSTRAINT payment_status_history_payment_id_fkey;
+
+-- +goose Down
+
+DELETE FROM payment_status_history
+WHERE NOT EXISTS (SELECT 1 FROM payments WHERE payments.id = payment_status_history.payment_id);
+
+ALTER TABLE payment_status_history
+ADD CONSTRAINT payment_status_history_payment_id_fkey FOREIGN KEY (payment_id) REFERENCES payments(id) ON DELETE CASCADE;
\ No newline at end of file
@@ -1,9 +1,16 @@
 package users
 
 import (
+	"bytes"
+	_ "embed"
+	"html/template"
+	"log"
 	"net/http"
+	"strings"
 
+	"github.com/elorenzorodz/event-mrs/internal/auth"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 func (userAPIConfig *UserAPIConfig) RegisterUser(ginContext *gin.Context) {
@@ -64,4 +71,289 @@ func (userAPIConfig *UserAPIConfig) LoginUser(ginContext *gin.Context) {
 	}
 
 	ginContext.JSON(http.StatusOK, gin.H{"user": userAuth})
+}
+
+func (userAPIConfig *UserAPIConfig) RefreshToken(ginContext *gin.Context) {
+	var refreshRequest RefreshRequest
+
+	if parameterBindError := ginContext.ShouldBindJSON(&refreshRequest); parameterBindError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "error parsing JSON, please check all required fields are present"})
+
+		return
+	}
+
+	userAuth, refreshError := userAPIConfig.Service.Refresh(ginContext.Request.Context(), refreshRequest.RefreshToken)
+
+	if refreshError != nil {
+		switch refreshError {
+		case ErrRefreshTokenInvalid, ErrRefreshTokenReused:
+			ginContext.JSON(http.StatusUnauthorized, gin.H{"error": refreshError.Error()})
+
+		default:
+			ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh token, please try again in a few minutes"})
+		}
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"user": userAuth})
+}
+
+func (userAPIConfig *UserAPIConfig) Logout(ginContext *gin.Context) {
+	var logoutRequest LogoutRequest
+
+	// The body is optional, an empty one only revokes the current access token.
+	if ginContext.Request.ContentLength > 0 {
+		if parameterBindError := ginContext.ShouldBindJSON(&logoutRequest); parameterBindError != nil {
+			ginContext.JSON(http.StatusBadRequest, gin.H{"error": "error parsing JSON, please check all required fields are present"})
+
+			return
+		}
+	}
+
+	accessTokenClaims := ginContext.MustGet("accessTokenClaims").(*auth.AccessTokenClaims)
+
+	if logoutError := userAPIConfig.Service.Logout(ginContext.Request.Context(), accessTokenClaims, logoutRequest.RefreshToken); logoutError != nil {
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "failed to logout, please try again in a few minutes"})
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+func (userAPIConfig *UserAPIConfig) LogoutAll(ginContext *gin.Context) {
+	accessTokenClaims := ginContext.MustGet("accessTokenClaims").(*auth.AccessTokenClaims)
+
+	if logoutError := userAPIConfig.Service.LogoutAll(ginContext.Request.Context(), accessTokenClaims); logoutError != nil {
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "failed to logout, please try again in a few minutes"})
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"message": "logged out from all sessions"})
+}
+
+func (userAPIConfig *UserAPIConfig) VerifyEmail(ginContext *gin.Context) {
+	verificationToken := ginContext.Query("token")
+
+	if verificationToken == "" {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "verification token is required"})
+
+		return
+	}
+
+	if verifyEmailError := userAPIConfig.Service.VerifyEmail(ginContext.Request.Context(), verificationToken); verifyEmailError != nil {
+		switch verifyEmailError {
+		case ErrActionTokenInvalid:
+			ginContext.JSON(http.StatusBadRequest, gin.H{"error": verifyEmailError.Error()})
+
+		default:
+			ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify email address, please try again in a few minutes"})
+		}
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"message": "email address verified"})
+}
+
+func (userAPIConfig *UserAPIConfig) ResendEmailVerification(ginContext *gin.Context) {
+	userID := ginContext.MustGet("userId").(uuid.UUID)
+
+	if resendError := userAPIConfig.Service.ResendEmailVerification(ginContext.Request.Context(), userID); resendError != nil {
+		switch resendError {
+		case ErrEmailVerified:
+			ginContext.JSON(http.StatusConflict, gin.H{"error": resendError.Error()})
+
+		default:
+			ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send verification email, please try again in a few minutes"})
+		}
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"message": "verification email sent"})
+}
+
+func (userAPIConfig *UserAPIConfig) ForgotPassword(ginContext *gin.Context) {
+	var forgotPasswordRequest ForgotPasswordRequest
+
+	if parameterBindError := ginContext.ShouldBindJSON(&forgotPasswordRequest); parameterBindError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "error parsing JSON, please check all required fields are present"})
+
+		return
+	}
+
+	if forgotPasswordError := userAPIConfig.Service.ForgotPassword(ginContext.Request.Context(), forgotPasswordRequest.Email); forgotPasswordError != nil {
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send password reset email, please try again in a few minutes"})
+
+		return
+	}
+
+	// Same response whether or not the email is registered.
+	ginContext.JSON(http.StatusOK, gin.H{"message": "if the email address is registered, a password reset link has been sent"})
+}
+
+// passwordResetPage is the form the link of the password reset email opens, it sends the new password to
+// POST /account/password/reset.
+//
+//go:embed templates/password_reset.html
+var passwordResetPageHTML string
+
+var passwordResetPage = template.Must(template.New("password_reset").Parse(passwordResetPageHTML))
+
+func (userAPIConfig *UserAPIConfig) PasswordResetForm(ginContext *gin.Context) {
+	resetToken := ginContext.Query("token")
+
+	if resetToken == "" {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "reset token is required"})
+
+		return
+	}
+
+	var page bytes.Buffer
+
+	if renderPageError := passwordResetPage.Execute(&page, struct{ Token string }{Token: resetToken}); renderPageError != nil {
+		log.Printf("Error rendering password reset page: %v", renderPageError)
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load password reset page, please try again in a few minutes"})
+
+		return
+	}
+
+	// The token is in the URL, it shouldn't be cached or sent along to other sites.
+	ginContext.Header("Cache-Control", "no-store")
+	ginContext.Header("Referrer-Policy", "no-referrer")
+	ginContext.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
+
+func (userAPIConfig *UserAPIConfig) ResetPassword(ginContext *gin.Context) {
+	var resetPasswordRequest ResetPasswordRequest
+
+	if parameterBindError := ginContext.ShouldBindJSON(&resetPasswordRequest); parameterBindError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "error parsing JSON, please check all required fields are present"})
+
+		return
+	}
+
+	if resetPasswordError := userAPIConfig.Service.ResetPassword(ginContext.Request.Context(), resetPasswordRequest); resetPasswordError != nil {
+		switch resetPasswordError {
+		case ErrPasswordWeak, ErrActionTokenInvalid:
+			ginContext.JSON(http.StatusBadRequest, gin.H{"error": resetPasswordError.Error()})
+
+		default:
+			ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reset password, please try again in a few minutes"})
+		}
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"message": "password has been reset, please login again"})
+}
+
+func (userAPIConfig *UserAPIConfig) GetUsers(ginContext *gin.Context) {
+	userList, getUsersError := userAPIConfig.Service.GetUsers(
+		ginContext.Request.Context(),
+		ginContext.Query("role"),
+		ginContext.Query("limit"),
+		ginContext.Query("offset"),
+	)
+
+	if getUsersError != nil {
+		if strings.Contains(getUsersError.Error(), "invalid") {
+			ginContext.JSON(http.StatusBadRequest, gin.H{"error": getUsersError.Error()})
+
+			return
+		}
+
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "error retrieving users, please try again in a few minutes"})
+
+		return
+	}
+
+	userResponses := make([]UserResponse, len(userList))
+
+	for i, user := range userList {
+		userResponses[i] = NewUserResponse(user)
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"users": userResponses})
+}
+
+func (userAPIConfig *UserAPIConfig) UpdateUserRole(ginContext *gin.Context) {
+	userID, parseUserIDError := uuid.Parse(ginContext.Param("userId"))
+
+	if parseUserIDError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+
+		return
+	}
+
+	var updateUserRoleRequest UpdateUserRoleRequest
+
+	if parameterBindError := ginContext.ShouldBindJSON(&updateUserRoleRequest); parameterBindError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "error parsing JSON, please check all required fields are present"})
+
+		return
+	}
+
+	adminID := ginContext.MustGet("userId").(uuid.UUID)
+
+	user, updateRoleError := userAPIConfig.Service.UpdateUserRole(ginContext.Request.Context(), adminID, userID, updateUserRoleRequest.Role)
+
+	if updateRoleError != nil {
+		switch updateRoleError {
+		case ErrRoleInvalid:
+			ginContext.JSON(http.StatusBadRequest, gin.H{"error": updateRoleError.Error()})
+
+		case ErrOwnRoleChange:
+			ginContext.JSON(http.StatusForbidden, gin.H{"error": updateRoleError.Error()})
+
+		case ErrUserNotFound:
+			ginContext.JSON(http.StatusNotFound, gin.H{"error": updateRoleError.Error()})
+
+		default:
+			ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update user role, please try again in a few minutes"})
+		}
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"user": NewUserResponse(user)})
+}
+
+func (userAPIConfig *UserAPIConfig) GetPreferences(ginContext *gin.Context) {
+	userID := ginContext.MustGet("userId").(uuid.UUID)
+
+	preferences, getPreferencesError := userAPIConfig.Service.GetPreferences(ginContext.Request.Context(), userID)
+
+	if getPreferencesError != nil {
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve preferences, please try again in a few minutes"})
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"preferences": preferences})
+}
+
+func (userAPIConfig *UserAPIConfig) UpdatePreferences(ginContext *gin.Context) {
+	var updatePreferencesRequest UpdatePreferencesRequest
+
+	if parameterBindError := ginContext.ShouldBindJSON(&updatePreferencesRequest); parameterBindError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "error parsing JSON, please check all required fields are present"})
+
+		return
+	}
+
+	userID := ginContext.MustGet("userId").(uuid.UUID)
+
+	preferences, updatePreferencesError := userAPIConfig.Service.UpdatePreferences(ginContext.Request.Context(), userID, updatePreferencesRequest)
+
+	if updatePreferencesError != nil {
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update preferences, please try again in a few minutes"})
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"preferences": preferences})
 }
\ No newline at end of file
@@ -8,9 +8,12 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/elorenzorodz/event-mrs/internal/auth"
 	"github.com/elorenzorodz/event-mrs/users"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -20,6 +23,7 @@ type MockUserService struct {
 	TestingType            *testing.T
 	RegisterFunc func(ctx context.Context, req users.RegisterRequest) (*users.User, error)
 	LoginFunc    func(ctx context.Context, req users.LoginRequest) (*users.UserAuthorized, error)
+	RefreshFunc  func(ctx context.Context, refreshToken string) (*users.UserAuthorized, error)
 }
 
 func (mockUserService *MockUserService) Register(ctx context.Context, req users.RegisterRequest) (*users.User, error) {
@@ -38,6 +42,74 @@ func (mockUserService *MockUserService) Login(ctx context.Context, req users.Log
 	return mockUserService.LoginFunc(ctx, req)
 }
 
+func (mockUserService *MockUserService) Refresh(ctx context.Context, refreshToken string) (*users.UserAuthorized, error) {
+	if mockUserService.RefreshFunc == nil {
+		mockUserService.TestingType.Fatal("Refresh was called, but RefreshFunc was not set.")
+	}
+
+	return mockUserService.RefreshFunc(ctx, refreshToken)
+}
+
+func (mockUserService *MockUserService) Logout(ctx context.Context, accessTokenClaims *auth.AccessTokenClaims, refreshToken string) error {
+	mockUserService.TestingType.Fatal("Logout should not be called in these tests.")
+
+	return nil
+}
+
+func (mockUserService *MockUserService) LogoutAll(ctx context.Context, accessTokenClaims *auth.AccessTokenClaims) error {
+	mockUserService.TestingType.Fatal("LogoutAll should not be called in these tests.")
+
+	return nil
+}
+
+func (mockUserService *MockUserService) VerifyEmail(ctx context.Context, token string) error {
+	mockUserService.TestingType.Fatal("VerifyEmail should not be called in these tests.")
+
+	return nil
+}
+
+func (mockUserService *MockUserService) ResendEmailVerification(ctx context.Context, userID uuid.UUID) error {
+	mockUserService.TestingType.Fatal("ResendEmailVerification should not be called in these tests.")
+
+	return nil
+}
+
+func (mockUserService *MockUserService) ForgotPassword(ctx context.Context, email string) error {
+	mockUserService.TestingType.Fatal("ForgotPassword should not be called in these tests.")
+
+	return nil
+}
+
+func (mockUserService *MockUserService) ResetPassword(ctx context.Context, req users.ResetPasswordRequest) error {
+	mockUserService.TestingType.Fatal("ResetPassword should not be called in these tests.")
+
+	return nil
+}
+
+func (mockUserService *MockUserService) GetUsers(ctx context.Context, role string, limitQuery string, offsetQuery string) ([]*users.User, error) {
+	mockUserService.TestingType.Fatal("GetUsers should not be called in these tests.")
+
+	return nil, nil
+}
+
+func (mockUserService *MockUserService) UpdateUserRole(ctx context.Context, adminID uuid.UUID, userID uuid.UUID, role string) (*users.User, error) {
+	mockUserService.TestingType.Fatal("UpdateUserRole should not be called in these tests.")
+
+	return nil, nil
+}
+
+func (mockUserService *MockUserService) GetPreferences(ctx context.Context, userID uuid.UUID) (*users.PreferencesResponse, error) {
+	mockUserService.TestingType.Fatal("GetPreferences should not be called in these tests.")
+
+	return nil, nil
+}
+
+func (mockUserService *MockUserService) UpdatePreferences(ctx context.Context, userID uuid.UUID, req users.UpdatePreferencesRequest) (*users.PreferencesResponse, error) {
+	mockUserService.TestingType.Fatal("UpdatePreferences should not be called in these tests.")
+
+	return nil, nil
+}
+
 func setupTestRouter(service users.UserService) (*gin.Engine, *httptest.ResponseRecorder) {
 	// Set Gin to test mode to suppress debug output
 	gin.SetMode(gin.TestMode)
@@ -52,6 +124,8 @@ func setupTestRouter(service users.UserService) (*gin.Engine, *httptest.Response
 	// Setup the routes for the handlers we are testing.
 	router.POST("/register", apiConfig.RegisterUser)
 	router.POST("/login", apiConfig.LoginUser)
+	router.POST("/refresh", apiConfig.RefreshToken)
+	router.GET("/account/password/reset", apiConfig.PasswordResetForm)
 
 	return router, recorder
 }
@@ -260,8 +334,11 @@ func TestRegisterUser(t *testing.T) {
 func TestLoginUser(t *testing.T) {
 	// Sample success response data.
 	expectedAuthResponse := users.UserAuthorized{
-		Email:       "arthur.morgan@login.com",
-		AccessToken: "mocked_jwt_token_12345",
+		Email:        "arthur.morgan@login.com",
+		Role:         "attendee",
+		AccessToken:  "mocked_jwt_token_12345",
+		ExpiresIn:    3600,
+		RefreshToken: "mocked_refresh_token",
 	}
 
 	tests := []struct {
@@ -286,7 +363,10 @@ func TestLoginUser(t *testing.T) {
 			expectedResponseBody: gin.H{
 				"user": gin.H{
 					"email": "arthur.morgan@login.com",
+					"role": "attendee",
 					"access_token": "mocked_jwt_token_12345",
+					"expires_in": 3600,
+					"refresh_token": "mocked_refresh_token",
 				},
 			},
 		},
@@ -372,4 +452,154 @@ func TestLoginUser(t *testing.T) {
 			}
 		})
 	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	tests := []struct {
+		name                 string
+		requestBody          gin.H
+		setupMock            func(mockService *MockUserService)
+		expectedStatus       int
+		expectedResponseBody gin.H
+	}{
+		{
+			name: "Success_StatusOK",
+			requestBody: gin.H{
+				"refresh_token": "old_refresh_token",
+			},
+			setupMock: func(mockService *MockUserService) {
+				mockService.RefreshFunc = func(ctx context.Context, refreshToken string) (*users.UserAuthorized, error) {
+					return &users.UserAuthorized{
+						Email:        "arthur.morgan@login.com",
+						Role:         "organizer",
+						AccessToken:  "new_access_token",
+						ExpiresIn:    3600,
+						RefreshToken: "new_refresh_token",
+					}, nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedResponseBody: gin.H{
+				"user": gin.H{
+					"email":         "arthur.morgan@login.com",
+					"role":          "organizer",
+					"access_token":  "new_access_token",
+					"expires_in":    3600,
+					"refresh_token": "new_refresh_token",
+				},
+			},
+		},
+		{
+			name:        "Failure_MissingRefreshToken",
+			requestBody: gin.H{},
+			setupMock: func(mockService *MockUserService) {
+				mockService.RefreshFunc = func(ctx context.Context, refreshToken string) (*users.UserAuthorized, error) {
+					t.Fatal("Service Refresh should not be called on binding error")
+					return nil, nil
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedResponseBody: gin.H{
+				"error": "error parsing JSON, please check all required fields are present",
+			},
+		},
+		{
+			name: "Failure_ReusedToken_StatusUnauthorized",
+			requestBody: gin.H{
+				"refresh_token": "already_rotated_token",
+			},
+			setupMock: func(mockService *MockUserService) {
+				mockService.RefreshFunc = func(ctx context.Context, refreshToken string) (*users.UserAuthorized, error) {
+					return nil, users.ErrRefreshTokenReused
+				}
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedResponseBody: gin.H{
+				"error": users.ErrRefreshTokenReused.Error(),
+			},
+		},
+		{
+			name: "Failure_InternalServerError",
+			requestBody: gin.H{
+				"refresh_token": "old_refresh_token",
+			},
+			setupMock: func(mockService *MockUserService) {
+				mockService.RefreshFunc = func(ctx context.Context, refreshToken string) (*users.UserAuthorized, error) {
+					return nil, errors.New("database down")
+				}
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedResponseBody: gin.H{
+				"error": "failed to refresh token, please try again in a few minutes",
+			},
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockService := &MockUserService{TestingType: t}
+			testCase.setupMock(mockService)
+			router, recorder := setupTestRouter(mockService)
+
+			var reqBody bytes.Buffer
+			json.NewEncoder(&reqBody).Encode(testCase.requestBody)
+
+			req, _ := http.NewRequest(http.MethodPost, "/refresh", &reqBody)
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != testCase.expectedStatus {
+				t.Fatalf("Expected status %d, got %d. Response: %s", testCase.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+
+			var actualResponse gin.H
+
+			if err := json.Unmarshal(recorder.Body.Bytes(), &actualResponse); err != nil {
+				t.Fatalf("Could not unmarshal response body: %v. Body: %s", err, recorder.Body.String())
+			}
+
+			if fmt.Sprintf("%v", actualResponse) != fmt.Sprintf("%v", testCase.expectedResponseBody) {
+				t.Errorf("Response body mismatch. \nExpected: %v\nActual: %v", testCase.expectedResponseBody, actualResponse)
+			}
+		})
+	}
+}
+
+func TestPasswordResetForm(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Success_ServesFormWithToken",
+			query:          "?token=" + url.QueryEscape(`reset"<token>`),
+			expectedStatus: http.StatusOK,
+			expectedBody:   `<input type="hidden" name="token" value="reset&#34;&lt;token&gt;">`,
+		},
+		{
+			name:           "Failure_MissingToken",
+			query:          "",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"reset token is required"}`,
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			router, recorder := setupTestRouter(&MockUserService{TestingType: t})
+
+			req, _ := http.NewRequest(http.MethodGet, "/account/password/reset"+testCase.query, nil)
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != testCase.expectedStatus {
+				t.Fatalf("Expected status %d, got %d. Response: %s", testCase.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+
+			if !strings.Contains(recorder.Body.String(), testCase.expectedBody) {
+				t.Errorf("Expected response to contain %s, got: %s", testCase.expectedBody, recorder.Body.String())
+			}
+		})
+	}
 }
\ No newline at end of file
@@ -6,6 +6,7 @@ import (
 
 	"github.com/elorenzorodz/event-mrs/config"
 	"github.com/elorenzorodz/event-mrs/internal/auth"
+	"github.com/google/uuid"
 )
 
 type User struct {
@@ -13,6 +14,7 @@ type User struct {
 	FirstName string
 	LastName  string
 	Email     string
+	Role      string
 	CreatedAt time.Time
 	UpdatedAt *time.Time
 }
@@ -34,12 +36,48 @@ type UserResponse struct {
 	FirstName string    `json:"firstname"`
 	LastName  string    `json:"lastname"`
 	Email     string    `json:"email"`
+	Role      string    `json:"role"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
 type UserAuthorized struct {
-	Email       string `json:"email"`
-	AccessToken string `json:"access_token"`
+	Email        string `json:"email"`
+	Role         string `json:"role"`
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// Note: RefreshToken is optional, without it only the current access token is revoked.
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// UpdatePreferencesRequest takes a pointer so a missing show_reminders isn't read as an opt-out.
+type UpdatePreferencesRequest struct {
+	ShowReminders *bool `json:"show_reminders" binding:"required"`
+}
+
+type PreferencesResponse struct {
+	ShowReminders bool       `json:"show_reminders"`
+	UpdatedAt     *time.Time `json:"updated_at"`
 }
 
 func NewUserResponse(user *User) UserResponse {
@@ -48,6 +86,7 @@ func NewUserResponse(user *User) UserResponse {
 		FirstName: user.FirstName,
 		LastName:  user.LastName,
 		Email:     user.Email,
+		Role:      user.Role,
 		CreatedAt: user.CreatedAt,
 	}
 }
@@ -55,11 +94,29 @@ func NewUserResponse(user *User) UserResponse {
 type UserService interface {
 	Register(ctx context.Context, req RegisterRequest) (*User, error)
 	Login(ctx context.Context, req LoginRequest) (*UserAuthorized, error)
+	Refresh(ctx context.Context, refreshToken string) (*UserAuthorized, error)
+	Logout(ctx context.Context, accessTokenClaims *auth.AccessTokenClaims, refreshToken string) error
+	LogoutAll(ctx context.Context, accessTokenClaims *auth.AccessTokenClaims) error
+	VerifyEmail(ctx context.Context, token string) error
+	ResendEmailVerification(ctx context.Context, userID uuid.UUID) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, req ResetPasswordRequest) error
+	GetUsers(ctx context.Context, role string, limitQuery string, offsetQuery string) ([]*User, error)
+	UpdateUserRole(ctx context.Context, adminID uuid.UUID, userID uuid.UUID, role string) (*User, error)
+	GetPreferences(ctx context.Context, userID uuid.UUID) (*PreferencesResponse, error)
+	UpdatePreferences(ctx context.Context, userID uuid.UUID, req UpdatePreferencesRequest) (*PreferencesResponse, error)
+}
+
+type Mailer interface {
+	SendEmailVerification(recipientName string, recipientEmail string, verificationLink string) error
+	SendPasswordReset(recipientName string, recipientEmail string, resetLink string) error
 }
 
 type Service struct {
-	DBQueries config.DBQueries 
-	TokenGenerator auth.TokenGenerator 
+	DBQueries      config.DBQueries
+	TokenGenerator auth.TokenGenerator
+	Mailer         Mailer
+	AppBaseURL     string
 }
 
 type UserAPIConfig struct {
@@ -2,8 +2,13 @@ package users
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"log"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/elorenzorodz/event-mrs/config"
@@ -14,15 +19,32 @@ import (
 )
 
 var (
-	ErrPasswordInvalid = errors.New("invalid email or password")
-	ErrEmailExists     = errors.New("email address is already registered")
-	ErrPasswordWeak    = errors.New("invalid password, password must contain at least 1 upper case letter, 1 lower case letter, 1 digit and must be 12 to 20 characters long")
+	ErrPasswordInvalid     = errors.New("invalid email or password")
+	ErrEmailExists         = errors.New("email address is already registered")
+	ErrPasswordWeak        = errors.New("invalid password, password must contain at least 1 upper case letter, 1 lower case letter, 1 digit and must be 12 to 20 characters long")
+	ErrRefreshTokenInvalid = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used, all sessions from this login have been revoked")
+	ErrActionTokenInvalid  = errors.New("invalid, expired or already used token")
+	ErrEmailVerified       = errors.New("email address is already verified")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrRoleInvalid         = fmt.Errorf("invalid role, must be one of: %s", strings.Join(auth.Roles, ", "))
+	ErrOwnRoleChange       = errors.New("admins can't change their own role")
 )
 
-func NewService(dbQueries config.DBQueries, tokenGenerator auth.TokenGenerator) UserService {
+const (
+	emailVerificationPurpose = "email_verification"
+	passwordResetPurpose     = "password_reset"
+
+	emailVerificationTTL = 24 * time.Hour
+	passwordResetTTL     = time.Hour
+)
+
+func NewService(dbQueries config.DBQueries, tokenGenerator auth.TokenGenerator, userMailer Mailer, appBaseURL string) UserService {
 	return &Service{
 		DBQueries:      dbQueries,
 		TokenGenerator: tokenGenerator,
+		Mailer:         userMailer,
+		AppBaseURL:     appBaseURL,
 	}
 }
 
@@ -54,6 +76,11 @@ func (service *Service) Register(ctx context.Context, req RegisterRequest) (*Use
 		return nil, ErrEmailExists
 	}
 
+	// The account is created either way, the user can ask for a new link if this email doesn't arrive.
+	if sendVerificationError := service.sendEmailVerification(ctx, newUserDB); sendVerificationError != nil {
+		log.Printf("Error sending verification email to user %s: %v", newUserDB.ID, sendVerificationError)
+	}
+
 	return databaseUserToDomainUser(newUserDB), nil
 }
 
@@ -70,19 +97,418 @@ func (service *Service) Login(ctx context.Context, req LoginRequest) (*UserAutho
 		return nil, ErrPasswordInvalid
 	}
 
-	signedToken, signedTokenError := service.TokenGenerator.Generate(getUserDB.Email)
+	// Every login starts a new refresh token family.
+	return service.issueTokens(ctx, getUserDB, uuid.New())
+}
+
+func (service *Service) Refresh(ctx context.Context, refreshToken string) (*UserAuthorized, error) {
+	storedToken, getTokenError := service.DBQueries.GetRefreshTokenByHash(ctx, auth.HashOpaqueToken(refreshToken))
+
+	if getTokenError != nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	if storedToken.RevokedAt.Valid || time.Now().After(storedToken.ExpiresAt) {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	// A rotated token being presented again means it was copied, so the whole family is revoked.
+	if storedToken.UsedAt.Valid {
+		service.revokeTokenFamily(ctx, storedToken)
+
+		return nil, ErrRefreshTokenReused
+	}
+
+	_, useTokenError := service.DBQueries.UseRefreshToken(ctx, storedToken.ID)
+
+	if useTokenError != nil {
+		if errors.Is(useTokenError, sql.ErrNoRows) {
+			// Another request rotated the same token first.
+			service.revokeTokenFamily(ctx, storedToken)
+
+			return nil, ErrRefreshTokenReused
+		}
+
+		log.Printf("Error rotating refresh token %s: %v", storedToken.ID, useTokenError)
+		return nil, errors.New("internal token generation error")
+	}
+
+	getUserDB, getUserError := service.DBQueries.GetUserById(ctx, storedToken.UserID)
+
+	if getUserError != nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	return service.issueTokens(ctx, getUserDB, storedToken.FamilyID)
+}
+
+func (service *Service) Logout(ctx context.Context, accessTokenClaims *auth.AccessTokenClaims, refreshToken string) error {
+	if strings.TrimSpace(refreshToken) != "" {
+		storedToken, getTokenError := service.DBQueries.GetRefreshTokenByHash(ctx, auth.HashOpaqueToken(refreshToken))
+
+		if getTokenError == nil && storedToken.UserID == accessTokenClaims.UserID {
+			service.revokeTokenFamily(ctx, storedToken)
+		}
+	}
+
+	return service.revokeAccessToken(ctx, accessTokenClaims)
+}
+
+func (service *Service) LogoutAll(ctx context.Context, accessTokenClaims *auth.AccessTokenClaims) error {
+	// Deny the access tokens first, they are looked up through the refresh tokens they were issued with.
+	if revokeAccessTokensError := service.DBQueries.RevokeUserAccessTokens(ctx, accessTokenClaims.UserID); revokeAccessTokensError != nil {
+		log.Printf("Error revoking access tokens for user %s: %v", accessTokenClaims.UserID, revokeAccessTokensError)
+		return errors.New("internal logout error")
+	}
+
+	if revokeRefreshTokensError := service.DBQueries.RevokeUserRefreshTokens(ctx, accessTokenClaims.UserID); revokeRefreshTokensError != nil {
+		log.Printf("Error revoking refresh tokens for user %s: %v", accessTokenClaims.UserID, revokeRefreshTokensError)
+		return errors.New("internal logout error")
+	}
+
+	return service.revokeAccessToken(ctx, accessTokenClaims)
+}
+
+func (service *Service) VerifyEmail(ctx context.Context, token string) error {
+	actionToken, consumeTokenError := service.DBQueries.ConsumeUserActionToken(ctx, database.ConsumeUserActionTokenParams{
+		TokenHash: auth.HashOpaqueToken(token),
+		Purpose:   emailVerificationPurpose,
+	})
+
+	if consumeTokenError != nil {
+		if errors.Is(consumeTokenError, sql.ErrNoRows) {
+			return ErrActionTokenInvalid
+		}
+
+		log.Printf("Error consuming verification token: %v", consumeTokenError)
+		return errors.New("internal verification error")
+	}
+
+	if _, markVerifiedError := service.DBQueries.MarkUserEmailVerified(ctx, actionToken.UserID); markVerifiedError != nil {
+		log.Printf("Error marking user %s as verified: %v", actionToken.UserID, markVerifiedError)
+		return errors.New("internal verification error")
+	}
+
+	return nil
+}
+
+func (service *Service) ResendEmailVerification(ctx context.Context, userID uuid.UUID) error {
+	getUserDB, getUserError := service.DBQueries.GetUserById(ctx, userID)
+
+	if getUserError != nil {
+		log.Printf("Error fetching user %s for verification email: %v", userID, getUserError)
+		return errors.New("internal verification error")
+	}
+
+	if getUserDB.EmailVerifiedAt.Valid {
+		return ErrEmailVerified
+	}
+
+	if sendVerificationError := service.sendEmailVerification(ctx, getUserDB); sendVerificationError != nil {
+		log.Printf("Error sending verification email to user %s: %v", userID, sendVerificationError)
+		return errors.New("internal verification error")
+	}
+
+	return nil
+}
+
+// ForgotPassword always succeeds for unknown emails so the endpoint can't be used to find registered accounts.
+func (service *Service) ForgotPassword(ctx context.Context, email string) error {
+	getUserDB, getUserError := service.DBQueries.GetUserByEmail(ctx, email)
+
+	if getUserError != nil {
+		if !errors.Is(getUserError, sql.ErrNoRows) {
+			log.Printf("Error fetching user for password reset: %v", getUserError)
+		}
+
+		return nil
+	}
+
+	resetToken, issueTokenError := service.issueActionToken(ctx, getUserDB.ID, passwordResetPurpose, passwordResetTTL)
+
+	if issueTokenError != nil {
+		log.Printf("Error issuing password reset token for user %s: %v", getUserDB.ID, issueTokenError)
+		return errors.New("internal password reset error")
+	}
+
+	resetLink := fmt.Sprintf("%s/account/password/reset?token=%s", service.AppBaseURL, url.QueryEscape(resetToken))
+	fullName := fmt.Sprintf("%s %s", getUserDB.Firstname, getUserDB.Lastname)
+
+	if sendEmailError := service.Mailer.SendPasswordReset(fullName, getUserDB.Email, resetLink); sendEmailError != nil {
+		log.Printf("Error sending password reset email to user %s: %v", getUserDB.ID, sendEmailError)
+		return errors.New("internal password reset error")
+	}
+
+	return nil
+}
+
+func (service *Service) ResetPassword(ctx context.Context, req ResetPasswordRequest) error {
+	// Check the new password first so a weak one doesn't use up the token.
+	if !validation.IsPasswordValid(req.Password) {
+		return ErrPasswordWeak
+	}
+
+	hashedPassword, hashPasswordError := auth.HashPassword(req.Password)
+
+	if hashPasswordError != nil {
+		log.Printf("Error hashing password: %s", hashPasswordError)
+		return errors.New("internal server error")
+	}
+
+	actionToken, consumeTokenError := service.DBQueries.ConsumeUserActionToken(ctx, database.ConsumeUserActionTokenParams{
+		TokenHash: auth.HashOpaqueToken(req.Token),
+		Purpose:   passwordResetPurpose,
+	})
+
+	if consumeTokenError != nil {
+		if errors.Is(consumeTokenError, sql.ErrNoRows) {
+			return ErrActionTokenInvalid
+		}
+
+		log.Printf("Error consuming password reset token: %v", consumeTokenError)
+		return errors.New("internal password reset error")
+	}
+
+	updatePasswordError := service.DBQueries.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+		Password: hashedPassword,
+		ID:       actionToken.UserID,
+	})
+
+	if updatePasswordError != nil {
+		log.Printf("Error updating password for user %s: %v", actionToken.UserID, updatePasswordError)
+		return errors.New("internal password reset error")
+	}
+
+	// Whoever knew the old password may still be logged in, so end every session.
+	if revokeAccessTokensError := service.DBQueries.RevokeUserAccessTokens(ctx, actionToken.UserID); revokeAccessTokensError != nil {
+		log.Printf("Error revoking access tokens for user %s after password reset: %v", actionToken.UserID, revokeAccessTokensError)
+	}
+
+	if revokeRefreshTokensError := service.DBQueries.RevokeUserRefreshTokens(ctx, actionToken.UserID); revokeRefreshTokensError != nil {
+		log.Printf("Error revoking refresh tokens for user %s after password reset: %v", actionToken.UserID, revokeRefreshTokensError)
+	}
+
+	return nil
+}
+
+func (service *Service) GetUsers(ctx context.Context, role string, limitQuery string, offsetQuery string) ([]*User, error) {
+	role = strings.ToLower(strings.TrimSpace(role))
+
+	if role != "" && !auth.IsValidRole(role) {
+		return nil, ErrRoleInvalid
+	}
+
+	limit, offset := int64(50), int64(0)
+
+	if strings.TrimSpace(limitQuery) != "" {
+		parsedLimit, parseLimitError := strconv.ParseInt(limitQuery, 10, 32)
+
+		if parseLimitError != nil || parsedLimit < 1 || parsedLimit > 100 {
+			return nil, errors.New("invalid limit, must be between 1 and 100")
+		}
+
+		limit = parsedLimit
+	}
+
+	if strings.TrimSpace(offsetQuery) != "" {
+		parsedOffset, parseOffsetError := strconv.ParseInt(offsetQuery, 10, 32)
+
+		if parseOffsetError != nil || parsedOffset < 0 {
+			return nil, errors.New("invalid offset, must be zero or greater")
+		}
+
+		offset = parsedOffset
+	}
+
+	getUsersDB, getUsersError := service.DBQueries.GetUsers(ctx, database.GetUsersParams{
+		Role:      role,
+		RowLimit:  int32(limit),
+		RowOffset: int32(offset),
+	})
+
+	if getUsersError != nil {
+		log.Printf("Error fetching users: %v", getUsersError)
+		return nil, errors.New("internal error retrieving users")
+	}
+
+	userList := make([]*User, len(getUsersDB))
+
+	for i, dbUser := range getUsersDB {
+		userList[i] = databaseUserToDomainUser(dbUser)
+	}
+
+	return userList, nil
+}
+
+// UpdateUserRole promotes or demotes a user. Admins can't change their own role so there is always at least one admin left.
+func (service *Service) UpdateUserRole(ctx context.Context, adminID uuid.UUID, userID uuid.UUID, role string) (*User, error) {
+	role = strings.ToLower(strings.TrimSpace(role))
+
+	if !auth.IsValidRole(role) {
+		return nil, ErrRoleInvalid
+	}
+
+	if adminID == userID {
+		return nil, ErrOwnRoleChange
+	}
+
+	updatedUserDB, updateRoleError := service.DBQueries.UpdateUserRole(ctx, database.UpdateUserRoleParams{
+		Role: role,
+		ID:   userID,
+	})
+
+	if updateRoleError != nil {
+		if errors.Is(updateRoleError, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+
+		log.Printf("Error updating role of user %s: %v", userID, updateRoleError)
+		return nil, errors.New("internal error updating user role")
+	}
+
+	return databaseUserToDomainUser(updatedUserDB), nil
+}
+
+// GetPreferences returns the user's preferences, users that never changed them get the defaults.
+func (service *Service) GetPreferences(ctx context.Context, userID uuid.UUID) (*PreferencesResponse, error) {
+	userPreferences, getPreferencesError := service.DBQueries.GetUserPreferences(ctx, userID)
+
+	if getPreferencesError != nil {
+		if errors.Is(getPreferencesError, sql.ErrNoRows) {
+			return &PreferencesResponse{ShowReminders: true}, nil
+		}
+
+		log.Printf("Error fetching preferences of user %s: %v", userID, getPreferencesError)
+		return nil, errors.New("internal error fetching preferences")
+	}
+
+	return databasePreferencesToResponse(userPreferences), nil
+}
+
+// UpdatePreferences saves the user's preferences, show_reminders false stops the pre-show reminder emails.
+func (service *Service) UpdatePreferences(ctx context.Context, userID uuid.UUID, req UpdatePreferencesRequest) (*PreferencesResponse, error) {
+	userPreferences, upsertPreferencesError := service.DBQueries.UpsertUserPreferences(ctx, database.UpsertUserPreferencesParams{
+		ShowReminders: *req.ShowReminders,
+		UserID:        userID,
+	})
+
+	if upsertPreferencesError != nil {
+		log.Printf("Error updating preferences of user %s: %v", userID, upsertPreferencesError)
+		return nil, errors.New("internal error updating preferences")
+	}
+
+	return databasePreferencesToResponse(userPreferences), nil
+}
+
+func (service *Service) sendEmailVerification(ctx context.Context, dbUser database.User) error {
+	verificationToken, issueTokenError := service.issueActionToken(ctx, dbUser.ID, emailVerificationPurpose, emailVerificationTTL)
+
+	if issueTokenError != nil {
+		return issueTokenError
+	}
+
+	verificationLink := fmt.Sprintf("%s/account/verify?token=%s", service.AppBaseURL, url.QueryEscape(verificationToken))
+	fullName := fmt.Sprintf("%s %s", dbUser.Firstname, dbUser.Lastname)
+
+	return service.Mailer.SendEmailVerification(fullName, dbUser.Email, verificationLink)
+}
+
+// issueActionToken replaces any unused token of the same purpose so only the latest emailed link works.
+func (service *Service) issueActionToken(ctx context.Context, userID uuid.UUID, purpose string, ttl time.Duration) (string, error) {
+	invalidateTokensError := service.DBQueries.InvalidateUserActionTokens(ctx, database.InvalidateUserActionTokensParams{
+		UserID:  userID,
+		Purpose: purpose,
+	})
+
+	if invalidateTokensError != nil {
+		return "", fmt.Errorf("failed to invalidate previous %s tokens: %w", purpose, invalidateTokensError)
+	}
+
+	actionToken, actionTokenHash, generateTokenError := auth.GenerateOpaqueToken()
+
+	if generateTokenError != nil {
+		return "", fmt.Errorf("failed to generate %s token: %w", purpose, generateTokenError)
+	}
+
+	_, createTokenError := service.DBQueries.CreateUserActionToken(ctx, database.CreateUserActionTokenParams{
+		ID:        uuid.New(),
+		TokenHash: actionTokenHash,
+		Purpose:   purpose,
+		ExpiresAt: time.Now().Add(ttl),
+		UserID:    userID,
+	})
+
+	if createTokenError != nil {
+		return "", fmt.Errorf("failed to store %s token: %w", purpose, createTokenError)
+	}
+
+	return actionToken, nil
+}
+
+func (service *Service) issueTokens(ctx context.Context, dbUser database.User, familyID uuid.UUID) (*UserAuthorized, error) {
+	accessToken, generateTokenError := service.TokenGenerator.Generate(dbUser.ID, dbUser.Email, dbUser.Role)
+
+	if generateTokenError != nil {
+		log.Printf("Token generation error: %v", generateTokenError)
+		return nil, errors.New("internal token generation error")
+	}
+
+	refreshToken, refreshTokenHash, generateRefreshTokenError := auth.GenerateOpaqueToken()
+
+	if generateRefreshTokenError != nil {
+		log.Printf("Refresh token generation error: %v", generateRefreshTokenError)
+		return nil, errors.New("internal token generation error")
+	}
+
+	_, createRefreshTokenError := service.DBQueries.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		ID:                   uuid.New(),
+		TokenHash:            refreshTokenHash,
+		FamilyID:             familyID,
+		AccessTokenID:        accessToken.ID,
+		AccessTokenExpiresAt: accessToken.ExpiresAt,
+		ExpiresAt:            time.Now().Add(auth.RefreshTokenTTL),
+		UserID:               dbUser.ID,
+	})
 
-	if signedTokenError != nil {
-		log.Printf("Token generation error: %v", signedTokenError)
+	if createRefreshTokenError != nil {
+		log.Printf("Error storing refresh token: %v", createRefreshTokenError)
 		return nil, errors.New("internal token generation error")
 	}
 
 	return &UserAuthorized{
-		Email:       getUserDB.Email,
-		AccessToken: signedToken,
+		Email:        dbUser.Email,
+		Role:         dbUser.Role,
+		AccessToken:  accessToken.Token,
+		ExpiresIn:    int64(auth.AccessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
 	}, nil
 }
 
+func (service *Service) revokeTokenFamily(ctx context.Context, storedToken database.RefreshToken) {
+	if revokeAccessTokensError := service.DBQueries.RevokeFamilyAccessTokens(ctx, storedToken.FamilyID); revokeAccessTokensError != nil {
+		log.Printf("Error revoking access tokens for refresh token family %s: %v", storedToken.FamilyID, revokeAccessTokensError)
+	}
+
+	if revokeFamilyError := service.DBQueries.RevokeRefreshTokenFamily(ctx, storedToken.FamilyID); revokeFamilyError != nil {
+		log.Printf("Error revoking refresh token family %s: %v", storedToken.FamilyID, revokeFamilyError)
+	}
+}
+
+func (service *Service) revokeAccessToken(ctx context.Context, accessTokenClaims *auth.AccessTokenClaims) error {
+	revokeAccessTokenError := service.DBQueries.RevokeAccessToken(ctx, database.RevokeAccessTokenParams{
+		Jti:       accessTokenClaims.ID,
+		ExpiresAt: accessTokenClaims.ExpiresAt,
+		UserID:    accessTokenClaims.UserID,
+	})
+
+	if revokeAccessTokenError != nil {
+		log.Printf("Error revoking access token %s: %v", accessTokenClaims.ID, revokeAccessTokenError)
+		return errors.New("internal logout error")
+	}
+
+	return nil
+}
+
 func databaseUserToDomainUser(dbUser database.User) *User {
 	var updatedAt *time.Time
 	if dbUser.UpdatedAt.Valid {
@@ -94,7 +520,21 @@ func databaseUserToDomainUser(dbUser database.User) *User {
 		FirstName: dbUser.Firstname,
 		LastName:  dbUser.Lastname,
 		Email:     dbUser.Email,
+		Role:      dbUser.Role,
 		CreatedAt: dbUser.CreatedAt,
 		UpdatedAt: updatedAt,
 	}
+}
+
+func databasePreferencesToResponse(userPreferences database.UserPreference) *PreferencesResponse {
+	var updatedAt *time.Time
+
+	if userPreferences.UpdatedAt.Valid {
+		updatedAt = &userPreferences.UpdatedAt.Time
+	}
+
+	return &PreferencesResponse{
+		ShowReminders: userPreferences.ShowReminders,
+		UpdatedAt:     updatedAt,
+	}
 }
\ No newline at end of file
@@ -2,11 +2,14 @@ package users_test
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/elorenzorodz/event-mrs/config"
+	"github.com/elorenzorodz/event-mrs/internal/auth"
 	"github.com/elorenzorodz/event-mrs/internal/database"
 	"github.com/elorenzorodz/event-mrs/users"
 	"github.com/google/uuid"
@@ -17,6 +20,23 @@ type MockDBQueries struct {
 	testingType           *testing.T
 	CreateUserFunc     func(ctx context.Context, arg database.CreateUserParams) (database.User, error)
 	GetUserByEmailFunc func(ctx context.Context, email string) (database.User, error)
+	GetUserByIdFunc    func(ctx context.Context, id uuid.UUID) (database.User, error)
+
+	CreateRefreshTokenFunc    func(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error)
+	GetRefreshTokenByHashFunc func(ctx context.Context, tokenHash string) (database.RefreshToken, error)
+	UseRefreshTokenFunc       func(ctx context.Context, id uuid.UUID) (database.RefreshToken, error)
+
+	ConsumeUserActionTokenFunc func(ctx context.Context, arg database.ConsumeUserActionTokenParams) (database.UserActionToken, error)
+
+	UpdateUserRoleFunc func(ctx context.Context, arg database.UpdateUserRoleParams) (database.User, error)
+
+	revokedFamilies    []uuid.UUID
+	revokedAccessToken []string
+	revokedUsers       []uuid.UUID
+
+	createdActionTokens []database.CreateUserActionTokenParams
+	verifiedUsers       []uuid.UUID
+	updatedPasswords    []database.UpdateUserPasswordParams
 }
 
 func (mockDBQueries *MockDBQueries) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
@@ -35,17 +55,132 @@ func (mockDBQueries *MockDBQueries) GetUserByEmail(ctx context.Context, email st
 	return mockDBQueries.GetUserByEmailFunc(ctx, email)
 }
 
+func (mockDBQueries *MockDBQueries) GetUserById(ctx context.Context, id uuid.UUID) (database.User, error) {
+	if mockDBQueries.GetUserByIdFunc == nil {
+		mockDBQueries.testingType.Fatalf("GetUserById was called, but no expectation (GetUserByIdFunc) was set.")
+	}
+
+	return mockDBQueries.GetUserByIdFunc(ctx, id)
+}
+
+func (mockDBQueries *MockDBQueries) CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error) {
+	if mockDBQueries.CreateRefreshTokenFunc == nil {
+		mockDBQueries.testingType.Fatalf("CreateRefreshToken was called, but no expectation (CreateRefreshTokenFunc) was set.")
+	}
+
+	return mockDBQueries.CreateRefreshTokenFunc(ctx, arg)
+}
+
+func (mockDBQueries *MockDBQueries) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (database.RefreshToken, error) {
+	if mockDBQueries.GetRefreshTokenByHashFunc == nil {
+		mockDBQueries.testingType.Fatalf("GetRefreshTokenByHash was called, but no expectation (GetRefreshTokenByHashFunc) was set.")
+	}
+
+	return mockDBQueries.GetRefreshTokenByHashFunc(ctx, tokenHash)
+}
+
+func (mockDBQueries *MockDBQueries) UseRefreshToken(ctx context.Context, id uuid.UUID) (database.RefreshToken, error) {
+	if mockDBQueries.UseRefreshTokenFunc == nil {
+		mockDBQueries.testingType.Fatalf("UseRefreshToken was called, but no expectation (UseRefreshTokenFunc) was set.")
+	}
+
+	return mockDBQueries.UseRefreshTokenFunc(ctx, id)
+}
+
+func (mockDBQueries *MockDBQueries) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	mockDBQueries.revokedFamilies = append(mockDBQueries.revokedFamilies, familyID)
+
+	return nil
+}
+
+func (mockDBQueries *MockDBQueries) RevokeFamilyAccessTokens(ctx context.Context, familyID uuid.UUID) error {
+	return nil
+}
+
+func (mockDBQueries *MockDBQueries) RevokeAccessToken(ctx context.Context, arg database.RevokeAccessTokenParams) error {
+	mockDBQueries.revokedAccessToken = append(mockDBQueries.revokedAccessToken, arg.Jti)
+
+	return nil
+}
+
+func (mockDBQueries *MockDBQueries) RevokeUserAccessTokens(ctx context.Context, userID uuid.UUID) error {
+	return nil
+}
+
+func (mockDBQueries *MockDBQueries) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	mockDBQueries.revokedUsers = append(mockDBQueries.revokedUsers, userID)
+
+	return nil
+}
+
+func (mockDBQueries *MockDBQueries) ConsumeUserActionToken(ctx context.Context, arg database.ConsumeUserActionTokenParams) (database.UserActionToken, error) {
+	if mockDBQueries.ConsumeUserActionTokenFunc == nil {
+		mockDBQueries.testingType.Fatalf("ConsumeUserActionToken was called, but no expectation (ConsumeUserActionTokenFunc) was set.")
+	}
+
+	return mockDBQueries.ConsumeUserActionTokenFunc(ctx, arg)
+}
+
+func (mockDBQueries *MockDBQueries) CreateUserActionToken(ctx context.Context, arg database.CreateUserActionTokenParams) (database.UserActionToken, error) {
+	mockDBQueries.createdActionTokens = append(mockDBQueries.createdActionTokens, arg)
+
+	return database.UserActionToken{ID: arg.ID, TokenHash: arg.TokenHash, Purpose: arg.Purpose, ExpiresAt: arg.ExpiresAt, UserID: arg.UserID}, nil
+}
+
+func (mockDBQueries *MockDBQueries) InvalidateUserActionTokens(ctx context.Context, arg database.InvalidateUserActionTokensParams) error {
+	return nil
+}
+
+func (mockDBQueries *MockDBQueries) MarkUserEmailVerified(ctx context.Context, id uuid.UUID) (database.User, error) {
+	mockDBQueries.verifiedUsers = append(mockDBQueries.verifiedUsers, id)
+
+	return database.User{ID: id, EmailVerifiedAt: sql.NullTime{Time: time.Now(), Valid: true}}, nil
+}
+
+func (mockDBQueries *MockDBQueries) UpdateUserPassword(ctx context.Context, arg database.UpdateUserPasswordParams) error {
+	mockDBQueries.updatedPasswords = append(mockDBQueries.updatedPasswords, arg)
+
+	return nil
+}
+
+func (mockDBQueries *MockDBQueries) UpdateUserRole(ctx context.Context, arg database.UpdateUserRoleParams) (database.User, error) {
+	if mockDBQueries.UpdateUserRoleFunc == nil {
+		mockDBQueries.testingType.Fatalf("UpdateUserRole was called, but no expectation (UpdateUserRoleFunc) was set.")
+	}
+
+	return mockDBQueries.UpdateUserRoleFunc(ctx, arg)
+}
+
+type MockMailer struct {
+	verificationLinks []string
+	resetLinks        []string
+}
+
+func (mockMailer *MockMailer) SendEmailVerification(recipientName string, recipientEmail string, verificationLink string) error {
+	mockMailer.verificationLinks = append(mockMailer.verificationLinks, verificationLink)
+
+	return nil
+}
+
+func (mockMailer *MockMailer) SendPasswordReset(recipientName string, recipientEmail string, resetLink string) error {
+	mockMailer.resetLinks = append(mockMailer.resetLinks, resetLink)
+
+	return nil
+}
+
+const testAppBaseURL = "http://localhost:8080/api/v1"
+
 type MockTokenGenerator struct {
 	tTesting     *testing.T
-	GenerateFunc func(email string) (string, error)
+	GenerateFunc func(userID uuid.UUID, email string, role string) (auth.AccessToken, error)
 }
 
-func (m *MockTokenGenerator) Generate(email string) (string, error) {
+func (m *MockTokenGenerator) Generate(userID uuid.UUID, email string, role string) (auth.AccessToken, error) {
 	if m.GenerateFunc == nil {
 		m.tTesting.Fatalf("Generate was called, but no expectation (GenerateFunc) was set.")
 	}
 
-	return m.GenerateFunc(email)
+	return m.GenerateFunc(userID, email, role)
 }
 
 // assertNoError asserts that the error is nil.
@@ -135,7 +270,7 @@ func TestRegister(tTesting *testing.T) {
 		tTesting.Run(tc.name, func(t *testing.T) {
 			mockDB := &MockDBQueries{testingType: t}
 			mockTokenGen := &MockTokenGenerator{tTesting: t}
-			service := users.NewService(mockDB, mockTokenGen)
+			service := users.NewService(mockDB, mockTokenGen, &MockMailer{}, testAppBaseURL)
 
 			tc.setupMocks(mockDB, mockTokenGen)
 
@@ -182,6 +317,7 @@ func TestLogin(testingType *testing.T) {
 	testUserDB := database.User{
 		Email:    validEmail,
 		Password: testHashedPassword,
+		Role:     "organizer",
 	}
 
 	tests := []struct {
@@ -200,8 +336,15 @@ func TestLogin(testingType *testing.T) {
 				mockDB.GetUserByEmailFunc = func(ctx context.Context, email string) (database.User, error) {
 					return testUserDB, nil
 				}
-				mockTokenGen.GenerateFunc = func(email string) (string, error) {
-					return "mocked_token", nil
+				mockTokenGen.GenerateFunc = func(userID uuid.UUID, email string, role string) (auth.AccessToken, error) {
+					if role != testUserDB.Role {
+						return auth.AccessToken{}, errors.New("role claim doesn't match the user's role")
+					}
+
+					return auth.AccessToken{Token: "mocked_token", ID: "mocked_jti", ExpiresAt: time.Now().Add(time.Hour)}, nil
+				}
+				mockDB.CreateRefreshTokenFunc = func(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error) {
+					return database.RefreshToken{ID: arg.ID, TokenHash: arg.TokenHash, FamilyID: arg.FamilyID}, nil
 				}
 			},
 			expectedError: nil,
@@ -242,8 +385,8 @@ func TestLogin(testingType *testing.T) {
 				mockDB.GetUserByEmailFunc = func(ctx context.Context, email string) (database.User, error) {
 					return testUserDB, nil
 				}
-				mockTokenGen.GenerateFunc = func(email string) (string, error) {
-					return "", errors.New("token failure")
+				mockTokenGen.GenerateFunc = func(userID uuid.UUID, email string, role string) (auth.AccessToken, error) {
+					return auth.AccessToken{}, errors.New("token failure")
 				}
 			},
 			expectedError: errors.New("internal token generation error"),
@@ -254,7 +397,7 @@ func TestLogin(testingType *testing.T) {
 		testingType.Run(tc.name, func(t *testing.T) {
 			mockDB := &MockDBQueries{testingType: t}
 			mockTokenGen := &MockTokenGenerator{tTesting: t}
-			service := users.NewService(mockDB, mockTokenGen)
+			service := users.NewService(mockDB, mockTokenGen, &MockMailer{}, testAppBaseURL)
 
 			tc.setupMocks(mockDB, mockTokenGen)
 
@@ -276,6 +419,484 @@ func TestLogin(testingType *testing.T) {
 				if userAuth.AccessToken != "mocked_token" {
 					t.Errorf("Expected token 'mocked_token', got %s", userAuth.AccessToken)
 				}
+
+				if userAuth.RefreshToken == "" {
+					t.Error("Expected a refresh token to be issued")
+				}
+			}
+		})
+	}
+}
+
+func TestRefresh(testingType *testing.T) {
+	ctx := context.Background()
+
+	refreshToken := "client_refresh_token"
+	familyID := uuid.New()
+
+	testUserDB := database.User{
+		ID:    uuid.New(),
+		Email: "arthur.morgan@refresh.com",
+	}
+
+	activeToken := database.RefreshToken{
+		ID:        uuid.New(),
+		TokenHash: auth.HashOpaqueToken(refreshToken),
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(time.Hour),
+		UserID:    testUserDB.ID,
+	}
+
+	usedToken := activeToken
+	usedToken.UsedAt = sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}
+
+	expiredToken := activeToken
+	expiredToken.ExpiresAt = time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name                  string
+		setupMocks            func(mockDB *MockDBQueries, mockTokenGen *MockTokenGenerator)
+		expectedError         error
+		expectedFamilyRevoked bool
+	}{
+		{
+			name: "Success_RotatesWithinFamily",
+			setupMocks: func(mockDB *MockDBQueries, mockTokenGen *MockTokenGenerator) {
+				mockDB.GetRefreshTokenByHashFunc = func(ctx context.Context, tokenHash string) (database.RefreshToken, error) {
+					return activeToken, nil
+				}
+				mockDB.UseRefreshTokenFunc = func(ctx context.Context, id uuid.UUID) (database.RefreshToken, error) {
+					return activeToken, nil
+				}
+				mockDB.GetUserByIdFunc = func(ctx context.Context, id uuid.UUID) (database.User, error) {
+					return testUserDB, nil
+				}
+				mockDB.CreateRefreshTokenFunc = func(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error) {
+					if arg.FamilyID != familyID {
+						return database.RefreshToken{}, errors.New("rotated token left its family")
+					}
+
+					if arg.TokenHash == activeToken.TokenHash {
+						return database.RefreshToken{}, errors.New("rotated token reused the old hash")
+					}
+
+					return database.RefreshToken{ID: arg.ID, FamilyID: arg.FamilyID}, nil
+				}
+				mockTokenGen.GenerateFunc = func(userID uuid.UUID, email string, role string) (auth.AccessToken, error) {
+					return auth.AccessToken{Token: "rotated_token", ID: "rotated_jti", ExpiresAt: time.Now().Add(time.Hour)}, nil
+				}
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Failure_UnknownToken",
+			setupMocks: func(mockDB *MockDBQueries, _ *MockTokenGenerator) {
+				mockDB.GetRefreshTokenByHashFunc = func(ctx context.Context, tokenHash string) (database.RefreshToken, error) {
+					return database.RefreshToken{}, sql.ErrNoRows
+				}
+			},
+			expectedError: users.ErrRefreshTokenInvalid,
+		},
+		{
+			name: "Failure_ExpiredToken",
+			setupMocks: func(mockDB *MockDBQueries, _ *MockTokenGenerator) {
+				mockDB.GetRefreshTokenByHashFunc = func(ctx context.Context, tokenHash string) (database.RefreshToken, error) {
+					return expiredToken, nil
+				}
+			},
+			expectedError: users.ErrRefreshTokenInvalid,
+		},
+		{
+			name: "Failure_ReusedTokenRevokesFamily",
+			setupMocks: func(mockDB *MockDBQueries, _ *MockTokenGenerator) {
+				mockDB.GetRefreshTokenByHashFunc = func(ctx context.Context, tokenHash string) (database.RefreshToken, error) {
+					return usedToken, nil
+				}
+			},
+			expectedError:         users.ErrRefreshTokenReused,
+			expectedFamilyRevoked: true,
+		},
+		{
+			name: "Failure_ConcurrentRotationRevokesFamily",
+			setupMocks: func(mockDB *MockDBQueries, _ *MockTokenGenerator) {
+				mockDB.GetRefreshTokenByHashFunc = func(ctx context.Context, tokenHash string) (database.RefreshToken, error) {
+					return activeToken, nil
+				}
+				mockDB.UseRefreshTokenFunc = func(ctx context.Context, id uuid.UUID) (database.RefreshToken, error) {
+					return database.RefreshToken{}, sql.ErrNoRows
+				}
+			},
+			expectedError:         users.ErrRefreshTokenReused,
+			expectedFamilyRevoked: true,
+		},
+	}
+
+	for _, tc := range tests {
+		testingType.Run(tc.name, func(t *testing.T) {
+			mockDB := &MockDBQueries{testingType: t}
+			mockTokenGen := &MockTokenGenerator{tTesting: t}
+			service := users.NewService(mockDB, mockTokenGen, &MockMailer{}, testAppBaseURL)
+
+			tc.setupMocks(mockDB, mockTokenGen)
+
+			userAuth, err := service.Refresh(ctx, refreshToken)
+
+			if tc.expectedError != nil {
+				assertError(t, tc.expectedError, err, "Refresh error assertion")
+
+				if userAuth != nil {
+					t.Fatalf("Expected nil userAuth, got %v", userAuth)
+				}
+			} else {
+				assertNoError(t, err, "Refresh success assertion")
+
+				if userAuth.AccessToken != "rotated_token" || userAuth.RefreshToken == refreshToken {
+					t.Errorf("Expected a new token pair, got access token %s", userAuth.AccessToken)
+				}
+			}
+
+			familyRevoked := len(mockDB.revokedFamilies) == 1 && mockDB.revokedFamilies[0] == familyID
+
+			if familyRevoked != tc.expectedFamilyRevoked {
+				t.Errorf("Expected family revoked to be %v, revoked families: %v", tc.expectedFamilyRevoked, mockDB.revokedFamilies)
+			}
+		})
+	}
+}
+
+func TestLogout(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	familyID := uuid.New()
+	accessTokenClaims := &auth.AccessTokenClaims{ID: "current_jti", UserID: userID, ExpiresAt: time.Now().Add(time.Hour)}
+
+	mockDB := &MockDBQueries{testingType: t}
+	mockDB.GetRefreshTokenByHashFunc = func(ctx context.Context, tokenHash string) (database.RefreshToken, error) {
+		return database.RefreshToken{FamilyID: familyID, UserID: userID}, nil
+	}
+
+	service := users.NewService(mockDB, &MockTokenGenerator{tTesting: t}, &MockMailer{}, testAppBaseURL)
+
+	assertNoError(t, service.Logout(ctx, accessTokenClaims, "client_refresh_token"), "Logout")
+
+	if len(mockDB.revokedFamilies) != 1 || mockDB.revokedFamilies[0] != familyID {
+		t.Errorf("Expected refresh token family %s to be revoked, got %v", familyID, mockDB.revokedFamilies)
+	}
+
+	if len(mockDB.revokedAccessToken) != 1 || mockDB.revokedAccessToken[0] != "current_jti" {
+		t.Errorf("Expected current access token to be denylisted, got %v", mockDB.revokedAccessToken)
+	}
+}
+
+func TestLogoutIgnoresOtherUsersRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	accessTokenClaims := &auth.AccessTokenClaims{ID: "current_jti", UserID: uuid.New(), ExpiresAt: time.Now().Add(time.Hour)}
+
+	mockDB := &MockDBQueries{testingType: t}
+	mockDB.GetRefreshTokenByHashFunc = func(ctx context.Context, tokenHash string) (database.RefreshToken, error) {
+		return database.RefreshToken{FamilyID: uuid.New(), UserID: uuid.New()}, nil
+	}
+
+	service := users.NewService(mockDB, &MockTokenGenerator{tTesting: t}, &MockMailer{}, testAppBaseURL)
+
+	assertNoError(t, service.Logout(ctx, accessTokenClaims, "someone_elses_token"), "Logout")
+
+	if len(mockDB.revokedFamilies) != 0 {
+		t.Errorf("Expected no refresh token family to be revoked, got %v", mockDB.revokedFamilies)
+	}
+}
+
+func TestLogoutAll(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	accessTokenClaims := &auth.AccessTokenClaims{ID: "current_jti", UserID: userID, ExpiresAt: time.Now().Add(time.Hour)}
+
+	mockDB := &MockDBQueries{testingType: t}
+	service := users.NewService(mockDB, &MockTokenGenerator{tTesting: t}, &MockMailer{}, testAppBaseURL)
+
+	assertNoError(t, service.LogoutAll(ctx, accessTokenClaims), "LogoutAll")
+
+	if len(mockDB.revokedUsers) != 1 || mockDB.revokedUsers[0] != userID {
+		t.Errorf("Expected all refresh tokens of user %s to be revoked, got %v", userID, mockDB.revokedUsers)
+	}
+
+	if len(mockDB.revokedAccessToken) != 1 || mockDB.revokedAccessToken[0] != "current_jti" {
+		t.Errorf("Expected current access token to be denylisted, got %v", mockDB.revokedAccessToken)
+	}
+}
+
+func TestRegisterSendsVerificationEmail(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	mockDB := &MockDBQueries{testingType: t}
+	mockDB.CreateUserFunc = func(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+		return database.User{ID: userID, Firstname: arg.Firstname, Lastname: arg.Lastname, Email: arg.Email}, nil
+	}
+
+	mockMailer := &MockMailer{}
+	service := users.NewService(mockDB, &MockTokenGenerator{tTesting: t}, mockMailer, testAppBaseURL)
+
+	_, err := service.Register(ctx, users.RegisterRequest{
+		FirstName: "Sadie",
+		LastName:  "Adler",
+		Email:     "sadie.adler@test.com",
+		Password:  "GoodWomanSadie36!",
+	})
+
+	assertNoError(t, err, "Register")
+
+	if len(mockDB.createdActionTokens) != 1 || mockDB.createdActionTokens[0].Purpose != "email_verification" || mockDB.createdActionTokens[0].UserID != userID {
+		t.Fatalf("Expected one email verification token for user %s, got %v", userID, mockDB.createdActionTokens)
+	}
+
+	if len(mockMailer.verificationLinks) != 1 || !strings.HasPrefix(mockMailer.verificationLinks[0], testAppBaseURL+"/account/verify?token=") {
+		t.Errorf("Expected a verification link to be emailed, got %v", mockMailer.verificationLinks)
+	}
+}
+
+func TestVerifyEmail(testingType *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	tests := []struct {
+		name                 string
+		consumeTokenError    error
+		expectedError        error
+		expectedUserVerified bool
+	}{
+		{
+			name:                 "Success",
+			expectedUserVerified: true,
+		},
+		{
+			name:              "Failure_InvalidOrUsedToken",
+			consumeTokenError: sql.ErrNoRows,
+			expectedError:     users.ErrActionTokenInvalid,
+		},
+	}
+
+	for _, tc := range tests {
+		testingType.Run(tc.name, func(t *testing.T) {
+			mockDB := &MockDBQueries{testingType: t}
+			mockDB.ConsumeUserActionTokenFunc = func(ctx context.Context, arg database.ConsumeUserActionTokenParams) (database.UserActionToken, error) {
+				if arg.Purpose != "email_verification" || arg.TokenHash != auth.HashOpaqueToken("verification_token") {
+					t.Errorf("Unexpected token lookup: %+v", arg)
+				}
+
+				return database.UserActionToken{UserID: userID}, tc.consumeTokenError
+			}
+
+			service := users.NewService(mockDB, &MockTokenGenerator{tTesting: t}, &MockMailer{}, testAppBaseURL)
+
+			err := service.VerifyEmail(ctx, "verification_token")
+
+			if tc.expectedError != nil {
+				assertError(t, tc.expectedError, err, "VerifyEmail error assertion")
+			} else {
+				assertNoError(t, err, "VerifyEmail success assertion")
+			}
+
+			userVerified := len(mockDB.verifiedUsers) == 1 && mockDB.verifiedUsers[0] == userID
+
+			if userVerified != tc.expectedUserVerified {
+				t.Errorf("Expected user verified to be %v, verified users: %v", tc.expectedUserVerified, mockDB.verifiedUsers)
+			}
+		})
+	}
+}
+
+func TestForgotPassword(testingType *testing.T) {
+	ctx := context.Background()
+
+	testUserDB := database.User{
+		ID:        uuid.New(),
+		Firstname: "Arthur",
+		Lastname:  "Morgan",
+		Email:     "arthur.morgan@test.com",
+	}
+
+	tests := []struct {
+		name              string
+		getUserError      error
+		expectedEmailSent bool
+	}{
+		{
+			name:              "Success_RegisteredEmail",
+			expectedEmailSent: true,
+		},
+		{
+			name:              "Success_UnknownEmailIsSilent",
+			getUserError:      sql.ErrNoRows,
+			expectedEmailSent: false,
+		},
+	}
+
+	for _, tc := range tests {
+		testingType.Run(tc.name, func(t *testing.T) {
+			mockDB := &MockDBQueries{testingType: t}
+			mockDB.GetUserByEmailFunc = func(ctx context.Context, email string) (database.User, error) {
+				return testUserDB, tc.getUserError
+			}
+
+			mockMailer := &MockMailer{}
+			service := users.NewService(mockDB, &MockTokenGenerator{tTesting: t}, mockMailer, testAppBaseURL)
+
+			assertNoError(t, service.ForgotPassword(ctx, testUserDB.Email), "ForgotPassword")
+
+			emailSent := len(mockMailer.resetLinks) == 1 && strings.HasPrefix(mockMailer.resetLinks[0], testAppBaseURL+"/account/password/reset?token=")
+
+			if emailSent != tc.expectedEmailSent {
+				t.Errorf("Expected reset email sent to be %v, got links %v", tc.expectedEmailSent, mockMailer.resetLinks)
+			}
+
+			if tc.expectedEmailSent && (len(mockDB.createdActionTokens) != 1 || mockDB.createdActionTokens[0].Purpose != "password_reset") {
+				t.Errorf("Expected one password reset token, got %v", mockDB.createdActionTokens)
+			}
+		})
+	}
+}
+
+func TestResetPassword(testingType *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	tests := []struct {
+		name                 string
+		resetPasswordRequest users.ResetPasswordRequest
+		consumeTokenError    error
+		expectedError        error
+		expectedReset        bool
+	}{
+		{
+			name:                 "Success_RevokesSessions",
+			resetPasswordRequest: users.ResetPasswordRequest{Token: "reset_token", Password: "NewPassword12345!"},
+			expectedReset:        true,
+		},
+		{
+			name:                 "Failure_WeakPassword",
+			resetPasswordRequest: users.ResetPasswordRequest{Token: "reset_token", Password: "weak"},
+			expectedError:        users.ErrPasswordWeak,
+		},
+		{
+			name:                 "Failure_InvalidOrUsedToken",
+			resetPasswordRequest: users.ResetPasswordRequest{Token: "reset_token", Password: "NewPassword12345!"},
+			consumeTokenError:    sql.ErrNoRows,
+			expectedError:        users.ErrActionTokenInvalid,
+		},
+	}
+
+	for _, tc := range tests {
+		testingType.Run(tc.name, func(t *testing.T) {
+			mockDB := &MockDBQueries{testingType: t}
+			mockDB.ConsumeUserActionTokenFunc = func(ctx context.Context, arg database.ConsumeUserActionTokenParams) (database.UserActionToken, error) {
+				if arg.Purpose != "password_reset" {
+					t.Errorf("Expected password_reset token lookup, got %s", arg.Purpose)
+				}
+
+				return database.UserActionToken{UserID: userID}, tc.consumeTokenError
+			}
+
+			service := users.NewService(mockDB, &MockTokenGenerator{tTesting: t}, &MockMailer{}, testAppBaseURL)
+
+			err := service.ResetPassword(ctx, tc.resetPasswordRequest)
+
+			if tc.expectedError != nil {
+				assertError(t, tc.expectedError, err, "ResetPassword error assertion")
+			} else {
+				assertNoError(t, err, "ResetPassword success assertion")
+			}
+
+			passwordReset := len(mockDB.updatedPasswords) == 1 && mockDB.updatedPasswords[0].ID == userID
+
+			if passwordReset != tc.expectedReset {
+				t.Fatalf("Expected password reset to be %v, got %v", tc.expectedReset, mockDB.updatedPasswords)
+			}
+
+			if tc.expectedReset {
+				if auth.VerifyPassword(tc.resetPasswordRequest.Password, mockDB.updatedPasswords[0].Password) != nil {
+					t.Error("Expected the new password to be stored hashed")
+				}
+
+				if len(mockDB.revokedUsers) != 1 || mockDB.revokedUsers[0] != userID {
+					t.Errorf("Expected all sessions of user %s to be revoked, got %v", userID, mockDB.revokedUsers)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateUserRole(testingType *testing.T) {
+	ctx := context.Background()
+	adminID := uuid.New()
+	userID := uuid.New()
+
+	tests := []struct {
+		name          string
+		targetUserID  uuid.UUID
+		role          string
+		setupMocks    func(mockDB *MockDBQueries)
+		expectedError error
+	}{
+		{
+			name:         "Success_PromoteToOrganizer",
+			targetUserID: userID,
+			role:         " Organizer ",
+			setupMocks: func(mockDB *MockDBQueries) {
+				mockDB.UpdateUserRoleFunc = func(ctx context.Context, arg database.UpdateUserRoleParams) (database.User, error) {
+					if arg.Role != "organizer" || arg.ID != userID {
+						return database.User{}, errors.New("unexpected role update")
+					}
+
+					return database.User{ID: arg.ID, Role: arg.Role}, nil
+				}
+			},
+			expectedError: nil,
+		},
+		{
+			name:          "Failure_UnknownRole",
+			targetUserID:  userID,
+			role:          "superuser",
+			setupMocks:    func(_ *MockDBQueries) {},
+			expectedError: users.ErrRoleInvalid,
+		},
+		{
+			name:          "Failure_OwnRole",
+			targetUserID:  adminID,
+			role:          "attendee",
+			setupMocks:    func(_ *MockDBQueries) {},
+			expectedError: users.ErrOwnRoleChange,
+		},
+		{
+			name:         "Failure_UserNotFound",
+			targetUserID: userID,
+			role:         "admin",
+			setupMocks: func(mockDB *MockDBQueries) {
+				mockDB.UpdateUserRoleFunc = func(ctx context.Context, arg database.UpdateUserRoleParams) (database.User, error) {
+					return database.User{}, sql.ErrNoRows
+				}
+			},
+			expectedError: users.ErrUserNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		testingType.Run(tc.name, func(t *testing.T) {
+			mockDB := &MockDBQueries{testingType: t}
+			service := users.NewService(mockDB, &MockTokenGenerator{tTesting: t}, &MockMailer{}, testAppBaseURL)
+
+			tc.setupMocks(mockDB)
+
+			user, err := service.UpdateUserRole(ctx, adminID, tc.targetUserID, tc.role)
+
+			if tc.expectedError != nil {
+				assertError(t, tc.expectedError, err, "UpdateUserRole error assertion")
+			} else {
+				assertNoError(t, err, "UpdateUserRole success assertion")
+
+				if user.Role != "organizer" {
+					t.Errorf("Expected role organizer, got %s", user.Role)
+				}
 			}
 		})
 	}
@@ -0,0 +1,112 @@
+package waitlist
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (waitlistAPIConfig *WaitlistAPIConfig) JoinWaitlist(ginContext *gin.Context) {
+	eventDetailID, parseEventDetailIDError := uuid.Parse(ginContext.Param("eventDetailId"))
+
+	if parseEventDetailIDError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid event detail ID"})
+
+		return
+	}
+
+	joinWaitlistParams := JoinWaitlistParameters{}
+
+	// The body is optional, without it one ticket is requested.
+	if ginContext.Request.ContentLength > 0 {
+		if parameterBindError := ginContext.ShouldBindJSON(&joinWaitlistParams); parameterBindError != nil {
+			ginContext.JSON(http.StatusBadRequest, gin.H{"error": "error parsing JSON, please check quantity is a number"})
+
+			return
+		}
+	}
+
+	userID := ginContext.MustGet("userId").(uuid.UUID)
+
+	waitlistEntry, joinError := waitlistAPIConfig.Service.Join(ginContext.Request.Context(), eventDetailID, userID, joinWaitlistParams)
+
+	if joinError != nil {
+		writeWaitlistError(ginContext, joinError)
+
+		return
+	}
+
+	ginContext.JSON(http.StatusCreated, waitlistEntry)
+}
+
+func (waitlistAPIConfig *WaitlistAPIConfig) LeaveWaitlist(ginContext *gin.Context) {
+	eventDetailID, parseEventDetailIDError := uuid.Parse(ginContext.Param("eventDetailId"))
+
+	if parseEventDetailIDError != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid event detail ID"})
+
+		return
+	}
+
+	userID := ginContext.MustGet("userId").(uuid.UUID)
+
+	if leaveError := waitlistAPIConfig.Service.Leave(ginContext.Request.Context(), eventDetailID, userID); leaveError != nil {
+		writeWaitlistError(ginContext, leaveError)
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{"message": "you have left the waitlist"})
+}
+
+func (waitlistAPIConfig *WaitlistAPIConfig) GetUserWaitlist(ginContext *gin.Context) {
+	userID := ginContext.MustGet("userId").(uuid.UUID)
+
+	waitlistEntries, getWaitlistError := waitlistAPIConfig.Service.GetUserWaitlist(ginContext.Request.Context(), userID)
+
+	if getWaitlistError != nil {
+		writeWaitlistError(ginContext, getWaitlistError)
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, waitlistEntries)
+}
+
+func (waitlistAPIConfig *WaitlistAPIConfig) GetWaitlistOffer(ginContext *gin.Context) {
+	claimToken := ginContext.Query("token")
+
+	if claimToken == "" {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+
+		return
+	}
+
+	waitlistOffer, getOfferError := waitlistAPIConfig.Service.GetOffer(ginContext.Request.Context(), claimToken)
+
+	if getOfferError != nil {
+		writeWaitlistError(ginContext, getOfferError)
+
+		return
+	}
+
+	ginContext.JSON(http.StatusOK, gin.H{
+		"offer":   waitlistOffer,
+		"message": "log in and book the offered tickets with POST /reservations and the token as waitlist_claim_token before the offer expires",
+	})
+}
+
+func writeWaitlistError(ginContext *gin.Context, waitlistError error) {
+	switch {
+	case errors.Is(waitlistError, ErrEventDetailNotFound), errors.Is(waitlistError, ErrNotOnWaitlist):
+		ginContext.JSON(http.StatusNotFound, gin.H{"error": waitlistError.Error()})
+	case errors.Is(waitlistError, ErrInvalidQuantity), errors.Is(waitlistError, ErrInvalidClaimToken):
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": waitlistError.Error()})
+	case errors.Is(waitlistError, ErrEventNotWaitlistable), errors.Is(waitlistError, ErrTicketsAvailable), errors.Is(waitlistError, ErrAlreadyOnWaitlist):
+		ginContext.JSON(http.StatusConflict, gin.H{"error": waitlistError.Error()})
+	default:
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": waitlistError.Error()})
+	}
+}
\ No newline at end of file
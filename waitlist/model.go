@@ -0,0 +1,76 @@
+package waitlist
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/clock"
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/google/uuid"
+)
+
+type WaitlistAPIConfig struct {
+	Service WaitlistService
+}
+
+type WaitlistService interface {
+	Join(ctx context.Context, eventDetailID, userID uuid.UUID, req JoinWaitlistParameters) (*WaitlistEntry, error)
+	Leave(ctx context.Context, eventDetailID, userID uuid.UUID) error
+	GetUserWaitlist(ctx context.Context, userID uuid.UUID) ([]WaitlistEntry, error)
+	GetOffer(ctx context.Context, claimToken string) (*WaitlistOffer, error)
+}
+
+// WaitlistQueries is what the waitlist is kept with, *database.Queries in the app.
+type WaitlistQueries interface {
+	CancelWaitlistEntry(ctx context.Context, arg database.CancelWaitlistEntryParams) (database.CancelWaitlistEntryRow, error)
+	CreateWaitlistEntry(ctx context.Context, arg database.CreateWaitlistEntryParams) (database.WaitlistEntry, error)
+	ExpireWaitlistOffers(ctx context.Context, arg database.ExpireWaitlistOffersParams) ([]uuid.UUID, error)
+	GetEventDetailsWithTitleByIds(ctx context.Context, id []uuid.UUID) ([]database.GetEventDetailsWithTitleByIdsRow, error)
+	GetUserById(ctx context.Context, id uuid.UUID) (database.User, error)
+	GetUserWaitlistEntries(ctx context.Context, userID uuid.UUID) ([]database.GetUserWaitlistEntriesRow, error)
+	GetWaitlistEntryByClaimTokenHash(ctx context.Context, claimTokenHash sql.NullString) (database.WaitlistEntry, error)
+	GetWaitlistedEventDetailIds(ctx context.Context, eventDetailIds []uuid.UUID) ([]uuid.UUID, error)
+	OfferNextWaitlistEntry(ctx context.Context, arg database.OfferNextWaitlistEntryParams) (database.WaitlistEntry, error)
+}
+
+type Service struct {
+	DBQueries  WaitlistQueries
+	Mailer     mailer.Mailer
+	AppBaseURL string
+	OfferTTL   time.Duration
+}
+
+// ExpiredOfferSweeper moves unclaimed offers on to the next person in line.
+type ExpiredOfferSweeper struct {
+	Waitlist  *Service
+	Clock     clock.Clock
+	Interval  time.Duration
+	BatchSize int32
+}
+
+type WaitlistEntry struct {
+	ID             uuid.UUID  `json:"id"`
+	EventDetailID  uuid.UUID  `json:"event_detail_id"`
+	Quantity       int32      `json:"quantity"`
+	Status         string     `json:"status"`
+	Position       int64      `json:"position,omitempty"`
+	OfferExpiresAt *time.Time `json:"offer_expires_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type JoinWaitlistParameters struct {
+	Quantity int32 `json:"quantity"`
+}
+
+// WaitlistOffer is what the claim link shows, the tickets are then booked with POST /reservations by the user it was
+// offered to.
+type WaitlistOffer struct {
+	EventDetailID     uuid.UUID `json:"event_detail_id"`
+	Title             string    `json:"title"`
+	TicketDescription string    `json:"ticket_description"`
+	ShowDate          time.Time `json:"show_date"`
+	Quantity          int32     `json:"quantity"`
+	OfferExpiresAt    time.Time `json:"offer_expires_at"`
+}
\ No newline at end of file
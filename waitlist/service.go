@@ -0,0 +1,314 @@
+package waitlist
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/auth"
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/google/uuid"
+)
+
+const maxWaitlistQuantity = 10
+
+var (
+	ErrEventDetailNotFound  = errors.New("event detail not found")
+	ErrInvalidQuantity      = fmt.Errorf("invalid quantity, must be between 1 and %d", maxWaitlistQuantity)
+	ErrEventNotWaitlistable = errors.New("event is not open for booking")
+	ErrTicketsAvailable     = errors.New("tickets are still available, please book them directly")
+	ErrAlreadyOnWaitlist    = errors.New("you are already on the waitlist for this ticket")
+	ErrNotOnWaitlist        = errors.New("you are not on the waitlist for this ticket")
+	ErrInvalidClaimToken    = errors.New("invalid or expired waitlist claim token")
+)
+
+func NewService(dbQueries WaitlistQueries, mMailer mailer.Mailer, appBaseURL string, offerTTL time.Duration) *Service {
+	return &Service{
+		DBQueries:  dbQueries,
+		Mailer:     mMailer,
+		AppBaseURL: appBaseURL,
+		OfferTTL:   offerTTL,
+	}
+}
+
+func (service *Service) Join(ctx context.Context, eventDetailID, userID uuid.UUID, req JoinWaitlistParameters) (*WaitlistEntry, error) {
+	quantity := req.Quantity
+
+	if quantity == 0 {
+		quantity = 1
+	}
+
+	if quantity < 0 || quantity > maxWaitlistQuantity {
+		return nil, ErrInvalidQuantity
+	}
+
+	eventDetails, getEventDetailsError := service.DBQueries.GetEventDetailsWithTitleByIds(ctx, []uuid.UUID{eventDetailID})
+
+	if getEventDetailsError != nil {
+		log.Printf("error fetching event detail for waitlist: %v", getEventDetailsError)
+
+		return nil, errors.New("error joining waitlist")
+	}
+
+	if len(eventDetails) == 0 {
+		return nil, ErrEventDetailNotFound
+	}
+
+	eventDetail := eventDetails[0]
+
+	if (eventDetail.EventStatus != "published" && eventDetail.EventStatus != "postponed") || time.Now().After(eventDetail.ShowDate) {
+		return nil, ErrEventNotWaitlistable
+	}
+
+	// Tickets that are free while nobody is waiting can be booked right away, once people queue up everyone has to queue.
+	if eventDetail.TicketsRemaining >= quantity {
+		waitlistedEventDetailIds, getWaitlistedError := service.DBQueries.GetWaitlistedEventDetailIds(ctx, []uuid.UUID{eventDetailID})
+
+		if getWaitlistedError != nil {
+			log.Printf("error checking waitlist: %v", getWaitlistedError)
+
+			return nil, errors.New("error joining waitlist")
+		}
+
+		if len(waitlistedEventDetailIds) == 0 {
+			return nil, ErrTicketsAvailable
+		}
+	}
+
+	createdEntry, createEntryError := service.DBQueries.CreateWaitlistEntry(ctx, database.CreateWaitlistEntryParams{
+		ID:            uuid.New(),
+		Quantity:      quantity,
+		EventDetailID: eventDetailID,
+		UserID:        userID,
+	})
+
+	if createEntryError != nil {
+		// Nothing is returned when the user already has an active entry for the ticket.
+		if errors.Is(createEntryError, sql.ErrNoRows) {
+			return nil, ErrAlreadyOnWaitlist
+		}
+
+		log.Printf("error creating waitlist entry: %v", createEntryError)
+
+		return nil, errors.New("error joining waitlist")
+	}
+
+	waitlistEntry := WaitlistEntry{
+		ID:            createdEntry.ID,
+		EventDetailID: createdEntry.EventDetailID,
+		Quantity:      createdEntry.Quantity,
+		Status:        createdEntry.Status,
+		CreatedAt:     createdEntry.CreatedAt,
+	}
+
+	if userWaitlist, getUserWaitlistError := service.GetUserWaitlist(ctx, userID); getUserWaitlistError == nil {
+		for _, userWaitlistEntry := range userWaitlist {
+			if userWaitlistEntry.ID == createdEntry.ID {
+				waitlistEntry.Position = userWaitlistEntry.Position
+			}
+		}
+	}
+
+	return &waitlistEntry, nil
+}
+
+func (service *Service) Leave(ctx context.Context, eventDetailID, userID uuid.UUID) error {
+	cancelledEntry, cancelEntryError := service.DBQueries.CancelWaitlistEntry(ctx, database.CancelWaitlistEntryParams{
+		EventDetailID: eventDetailID,
+		UserID:        userID,
+	})
+
+	if cancelEntryError != nil {
+		if errors.Is(cancelEntryError, sql.ErrNoRows) {
+			return ErrNotOnWaitlist
+		}
+
+		log.Printf("error cancelling waitlist entry: %v", cancelEntryError)
+
+		return errors.New("error leaving waitlist")
+	}
+
+	// Declining an offer hands the held tickets to the next person.
+	if cancelledEntry.PreviousStatus == "offered" {
+		service.OfferReleasedTickets(ctx, []uuid.UUID{cancelledEntry.EventDetailID})
+	}
+
+	return nil
+}
+
+func (service *Service) GetUserWaitlist(ctx context.Context, userID uuid.UUID) ([]WaitlistEntry, error) {
+	userWaitlistEntries, getEntriesError := service.DBQueries.GetUserWaitlistEntries(ctx, userID)
+
+	if getEntriesError != nil {
+		log.Printf("error fetching user waitlist: %v", getEntriesError)
+
+		return nil, errors.New("error fetching waitlist")
+	}
+
+	waitlistEntries := make([]WaitlistEntry, len(userWaitlistEntries))
+
+	for i, userWaitlistEntry := range userWaitlistEntries {
+		waitlistEntries[i] = WaitlistEntry{
+			ID:            userWaitlistEntry.ID,
+			EventDetailID: userWaitlistEntry.EventDetailID,
+			Quantity:      userWaitlistEntry.Quantity,
+			Status:        userWaitlistEntry.Status,
+			CreatedAt:     userWaitlistEntry.CreatedAt,
+		}
+
+		// Offered entries are no longer in the queue.
+		if userWaitlistEntry.Status == "waiting" {
+			waitlistEntries[i].Position = userWaitlistEntry.Position
+		}
+
+		if userWaitlistEntry.OfferExpiresAt.Valid {
+			waitlistEntries[i].OfferExpiresAt = &userWaitlistEntry.OfferExpiresAt.Time
+		}
+	}
+
+	return waitlistEntries, nil
+}
+
+// GetOffer shows the offer of the claim link without logging in, the claim token is only sent to the user it was
+// offered to.
+func (service *Service) GetOffer(ctx context.Context, claimToken string) (*WaitlistOffer, error) {
+	offeredEntry, getOfferError := service.getOpenOffer(ctx, claimToken)
+
+	if getOfferError != nil {
+		return nil, getOfferError
+	}
+
+	eventDetails, getEventDetailsError := service.DBQueries.GetEventDetailsWithTitleByIds(ctx, []uuid.UUID{offeredEntry.EventDetailID})
+
+	if getEventDetailsError != nil || len(eventDetails) == 0 {
+		log.Printf("error fetching event detail for waitlist offer %s: %v", offeredEntry.ID, getEventDetailsError)
+
+		return nil, errors.New("error fetching waitlist offer")
+	}
+
+	return &WaitlistOffer{
+		EventDetailID:     offeredEntry.EventDetailID,
+		Title:             eventDetails[0].Title,
+		TicketDescription: eventDetails[0].TicketDescription,
+		ShowDate:          eventDetails[0].ShowDate,
+		Quantity:          offeredEntry.Quantity,
+		OfferExpiresAt:    offeredEntry.OfferExpiresAt.Time,
+	}, nil
+}
+
+// GetClaimableOffer returns the user's open offer for the claim token from the offer email.
+func (service *Service) GetClaimableOffer(ctx context.Context, claimToken string, userID uuid.UUID) (database.WaitlistEntry, error) {
+	offeredEntry, getOfferError := service.getOpenOffer(ctx, claimToken)
+
+	if getOfferError != nil {
+		return database.WaitlistEntry{}, getOfferError
+	}
+
+	if offeredEntry.UserID != userID {
+		return database.WaitlistEntry{}, ErrInvalidClaimToken
+	}
+
+	return offeredEntry, nil
+}
+
+// getOpenOffer returns the offer of the claim token while it can still be claimed.
+func (service *Service) getOpenOffer(ctx context.Context, claimToken string) (database.WaitlistEntry, error) {
+	offeredEntry, getEntryError := service.DBQueries.GetWaitlistEntryByClaimTokenHash(ctx, sql.NullString{String: auth.HashOpaqueToken(claimToken), Valid: true})
+
+	if getEntryError != nil {
+		if errors.Is(getEntryError, sql.ErrNoRows) {
+			return database.WaitlistEntry{}, ErrInvalidClaimToken
+		}
+
+		log.Printf("error fetching waitlist offer: %v", getEntryError)
+
+		return database.WaitlistEntry{}, errors.New("error fetching waitlist offer")
+	}
+
+	if offeredEntry.Status != "offered" || !offeredEntry.OfferExpiresAt.Valid || time.Now().After(offeredEntry.OfferExpiresAt.Time) {
+		return database.WaitlistEntry{}, ErrInvalidClaimToken
+	}
+
+	return offeredEntry, nil
+}
+
+// OfferReleasedTickets holds tickets that went back to inventory for the people waiting for them, in the order they joined.
+// It is called after refunds, released payments, capacity increases and expired or declined offers.
+func (service *Service) OfferReleasedTickets(ctx context.Context, eventDetailIDs []uuid.UUID) {
+	for _, eventDetailID := range eventDetailIDs {
+		for {
+			claimToken, claimTokenHash, generateTokenError := auth.GenerateOpaqueToken()
+
+			if generateTokenError != nil {
+				log.Printf("error generating waitlist claim token: %v", generateTokenError)
+
+				break
+			}
+
+			offeredEntry, offerError := service.DBQueries.OfferNextWaitlistEntry(ctx, database.OfferNextWaitlistEntryParams{
+				EventDetailID:  eventDetailID,
+				ClaimTokenHash: sql.NullString{String: claimTokenHash, Valid: true},
+				OfferExpiresAt: sql.NullTime{Time: time.Now().Add(service.OfferTTL), Valid: true},
+			})
+
+			// No rows means nobody is waiting or there aren't enough tickets for the next person yet.
+			if offerError != nil {
+				if !errors.Is(offerError, sql.ErrNoRows) {
+					log.Printf("error offering tickets for event detail %s to the waitlist: %v", eventDetailID, offerError)
+				}
+
+				break
+			}
+
+			service.sendWaitlistOffer(ctx, offeredEntry, claimToken)
+		}
+	}
+}
+
+// ExpireOffers releases offers that weren't claimed in time and offers the tickets to the next person.
+func (service *Service) ExpireOffers(ctx context.Context, expiredBefore time.Time, batchSize int32) (int, error) {
+	releasedEventDetailIds, expireOffersError := service.DBQueries.ExpireWaitlistOffers(ctx, database.ExpireWaitlistOffersParams{
+		ExpiredBefore: expiredBefore,
+		BatchSize:     batchSize,
+	})
+
+	if expireOffersError != nil {
+		return 0, fmt.Errorf("failed to expire waitlist offers: %w", expireOffersError)
+	}
+
+	service.OfferReleasedTickets(ctx, releasedEventDetailIds)
+
+	return len(releasedEventDetailIds), nil
+}
+
+func (service *Service) sendWaitlistOffer(ctx context.Context, offeredEntry database.WaitlistEntry, claimToken string) {
+	user, getUserError := service.DBQueries.GetUserById(ctx, offeredEntry.UserID)
+
+	if getUserError != nil {
+		log.Printf("error fetching user %s for waitlist offer %s: %v", offeredEntry.UserID, offeredEntry.ID, getUserError)
+
+		return
+	}
+
+	eventDetails, getEventDetailsError := service.DBQueries.GetEventDetailsWithTitleByIds(ctx, []uuid.UUID{offeredEntry.EventDetailID})
+
+	if getEventDetailsError != nil || len(eventDetails) == 0 {
+		log.Printf("error fetching event detail for waitlist offer %s: %v", offeredEntry.ID, getEventDetailsError)
+
+		return
+	}
+
+	claimLink := fmt.Sprintf("%s/waitlist/claim?token=%s", service.AppBaseURL, url.QueryEscape(claimToken))
+	fullName := fmt.Sprintf("%s %s", user.Firstname, user.Lastname)
+
+	// Without the claim token of this email the hold can't be claimed, when it fails the tickets go to the next person
+	// once the offer expires.
+	if sendEmailError := service.Mailer.SendWaitlistOffer(fullName, user.Email, eventDetails[0], offeredEntry.Quantity, claimLink, offeredEntry.OfferExpiresAt.Time); sendEmailError != nil {
+		log.Printf("error sending waitlist offer %s: %v", offeredEntry.ID, sendEmailError)
+	}
+}
\ No newline at end of file
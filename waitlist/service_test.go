@@ -0,0 +1,484 @@
+package waitlist_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/database"
+	"github.com/elorenzorodz/event-mrs/internal/mailer"
+	"github.com/elorenzorodz/event-mrs/waitlist"
+	"github.com/google/uuid"
+)
+
+const testAppBaseURL = "http://localhost:8080/api/v1"
+
+const testOfferTTL = 30 * time.Minute
+
+var testMailerConfig = mailer.MailerConfig{
+	SenderName:  "Event MRS",
+	SenderEmail: "noreply@event-mrs.test",
+	TeamName:    "Event MRS Team",
+	TeamEmail:   "team@event-mrs.test",
+}
+
+var claimLinkPattern = regexp.MustCompile(`/waitlist/claim\?token=(\S+)`)
+
+// MockWaitlistQueries keeps the waitlist in memory, entries are in the order they joined and held tickets come out of
+// the event detail's tickets remaining like in the queries.
+type MockWaitlistQueries struct {
+	eventDetails map[uuid.UUID]*database.GetEventDetailsWithTitleByIdsRow
+	entries      []*database.WaitlistEntry
+	users        map[uuid.UUID]database.User
+}
+
+func NewMockWaitlistQueries() *MockWaitlistQueries {
+	return &MockWaitlistQueries{
+		eventDetails: map[uuid.UUID]*database.GetEventDetailsWithTitleByIdsRow{},
+		users:        map[uuid.UUID]database.User{},
+	}
+}
+
+func (mockQueries *MockWaitlistQueries) addEventDetail(ticketsRemaining int32) uuid.UUID {
+	eventDetailID := uuid.New()
+
+	mockQueries.eventDetails[eventDetailID] = &database.GetEventDetailsWithTitleByIdsRow{
+		ID:                eventDetailID,
+		Title:             "Jazz Night",
+		TicketDescription: "VIP",
+		ShowDate:          time.Now().Add(7 * 24 * time.Hour),
+		TicketsRemaining:  ticketsRemaining,
+		EventStatus:       "published",
+		Currency:          "USD",
+	}
+
+	return eventDetailID
+}
+
+func (mockQueries *MockWaitlistQueries) addUser(email string) uuid.UUID {
+	userID := uuid.New()
+	mockQueries.users[userID] = database.User{ID: userID, Firstname: "Test", Lastname: "User", Email: email}
+
+	return userID
+}
+
+func (mockQueries *MockWaitlistQueries) entryOf(eventDetailID, userID uuid.UUID) *database.WaitlistEntry {
+	var userEntry *database.WaitlistEntry
+
+	for _, entry := range mockQueries.entries {
+		if entry.EventDetailID == eventDetailID && entry.UserID == userID {
+			userEntry = entry
+		}
+	}
+
+	return userEntry
+}
+
+func (mockQueries *MockWaitlistQueries) CancelWaitlistEntry(ctx context.Context, arg database.CancelWaitlistEntryParams) (database.CancelWaitlistEntryRow, error) {
+	for _, entry := range mockQueries.entries {
+		if entry.EventDetailID != arg.EventDetailID || entry.UserID != arg.UserID || (entry.Status != "waiting" && entry.Status != "offered") {
+			continue
+		}
+
+		previousStatus := entry.Status
+		entry.Status = "cancelled"
+		entry.ClaimTokenHash = sql.NullString{}
+
+		if previousStatus == "offered" {
+			mockQueries.eventDetails[entry.EventDetailID].TicketsRemaining += entry.Quantity
+		}
+
+		return database.CancelWaitlistEntryRow{ID: entry.ID, EventDetailID: entry.EventDetailID, Quantity: entry.Quantity, PreviousStatus: previousStatus}, nil
+	}
+
+	return database.CancelWaitlistEntryRow{}, sql.ErrNoRows
+}
+
+func (mockQueries *MockWaitlistQueries) CreateWaitlistEntry(ctx context.Context, arg database.CreateWaitlistEntryParams) (database.WaitlistEntry, error) {
+	if activeEntry := mockQueries.entryOf(arg.EventDetailID, arg.UserID); activeEntry != nil && (activeEntry.Status == "waiting" || activeEntry.Status == "offered") {
+		return database.WaitlistEntry{}, sql.ErrNoRows
+	}
+
+	entry := &database.WaitlistEntry{
+		ID:            arg.ID,
+		Quantity:      arg.Quantity,
+		Status:        "waiting",
+		CreatedAt:     time.Now(),
+		EventDetailID: arg.EventDetailID,
+		UserID:        arg.UserID,
+	}
+	mockQueries.entries = append(mockQueries.entries, entry)
+
+	return *entry, nil
+}
+
+func (mockQueries *MockWaitlistQueries) ExpireWaitlistOffers(ctx context.Context, arg database.ExpireWaitlistOffersParams) ([]uuid.UUID, error) {
+	releasedEventDetailIDs := []uuid.UUID{}
+
+	for _, entry := range mockQueries.entries {
+		if entry.Status == "offered" && entry.OfferExpiresAt.Time.Before(arg.ExpiredBefore) {
+			entry.Status = "expired"
+			entry.ClaimTokenHash = sql.NullString{}
+			mockQueries.eventDetails[entry.EventDetailID].TicketsRemaining += entry.Quantity
+			releasedEventDetailIDs = append(releasedEventDetailIDs, entry.EventDetailID)
+		}
+	}
+
+	return releasedEventDetailIDs, nil
+}
+
+func (mockQueries *MockWaitlistQueries) GetEventDetailsWithTitleByIds(ctx context.Context, id []uuid.UUID) ([]database.GetEventDetailsWithTitleByIdsRow, error) {
+	eventDetails := []database.GetEventDetailsWithTitleByIdsRow{}
+
+	for _, eventDetailID := range id {
+		if eventDetail, found := mockQueries.eventDetails[eventDetailID]; found {
+			eventDetails = append(eventDetails, *eventDetail)
+		}
+	}
+
+	return eventDetails, nil
+}
+
+func (mockQueries *MockWaitlistQueries) GetUserById(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, found := mockQueries.users[id]
+
+	if !found {
+		return database.User{}, sql.ErrNoRows
+	}
+
+	return user, nil
+}
+
+func (mockQueries *MockWaitlistQueries) GetUserWaitlistEntries(ctx context.Context, userID uuid.UUID) ([]database.GetUserWaitlistEntriesRow, error) {
+	userEntries := []database.GetUserWaitlistEntriesRow{}
+
+	for _, entry := range mockQueries.entries {
+		if entry.UserID != userID || (entry.Status != "waiting" && entry.Status != "offered") {
+			continue
+		}
+
+		position := int64(0)
+
+		for _, ahead := range mockQueries.entries {
+			if ahead.EventDetailID == entry.EventDetailID && ahead.Status == "waiting" {
+				position++
+			}
+
+			if ahead == entry {
+				break
+			}
+		}
+
+		userEntries = append(userEntries, database.GetUserWaitlistEntriesRow{
+			ID:             entry.ID,
+			Quantity:       entry.Quantity,
+			Status:         entry.Status,
+			OfferExpiresAt: entry.OfferExpiresAt,
+			CreatedAt:      entry.CreatedAt,
+			EventDetailID:  entry.EventDetailID,
+			Position:       position,
+		})
+	}
+
+	return userEntries, nil
+}
+
+func (mockQueries *MockWaitlistQueries) GetWaitlistEntryByClaimTokenHash(ctx context.Context, claimTokenHash sql.NullString) (database.WaitlistEntry, error) {
+	for _, entry := range mockQueries.entries {
+		if entry.ClaimTokenHash.Valid && entry.ClaimTokenHash == claimTokenHash {
+			return *entry, nil
+		}
+	}
+
+	return database.WaitlistEntry{}, sql.ErrNoRows
+}
+
+func (mockQueries *MockWaitlistQueries) GetWaitlistedEventDetailIds(ctx context.Context, eventDetailIds []uuid.UUID) ([]uuid.UUID, error) {
+	waitlistedEventDetailIDs := []uuid.UUID{}
+
+	for _, eventDetailID := range eventDetailIds {
+		for _, entry := range mockQueries.entries {
+			if entry.EventDetailID == eventDetailID && entry.Status == "waiting" {
+				waitlistedEventDetailIDs = append(waitlistedEventDetailIDs, eventDetailID)
+
+				break
+			}
+		}
+	}
+
+	return waitlistedEventDetailIDs, nil
+}
+
+func (mockQueries *MockWaitlistQueries) OfferNextWaitlistEntry(ctx context.Context, arg database.OfferNextWaitlistEntryParams) (database.WaitlistEntry, error) {
+	eventDetail := mockQueries.eventDetails[arg.EventDetailID]
+
+	for _, entry := range mockQueries.entries {
+		if entry.EventDetailID != arg.EventDetailID || entry.Status != "waiting" {
+			continue
+		}
+
+		// Only the first person in line can be offered tickets, the others wait until there are enough for them.
+		if eventDetail.TicketsRemaining < entry.Quantity {
+			return database.WaitlistEntry{}, sql.ErrNoRows
+		}
+
+		eventDetail.TicketsRemaining -= entry.Quantity
+		entry.Status = "offered"
+		entry.ClaimTokenHash = arg.ClaimTokenHash
+		entry.OfferExpiresAt = arg.OfferExpiresAt
+
+		return *entry, nil
+	}
+
+	return database.WaitlistEntry{}, sql.ErrNoRows
+}
+
+func newTestService(mockQueries *MockWaitlistQueries) (*waitlist.Service, *mailer.Recorder) {
+	recorder := mailer.NewRecorder()
+
+	return waitlist.NewService(mockQueries, mailer.NewMailer(testMailerConfig, recorder), testAppBaseURL, testOfferTTL), recorder
+}
+
+// offeredClaimToken returns the claim token of the last offer email sent to email.
+func offeredClaimToken(t *testing.T, recorder *mailer.Recorder, email string) string {
+	t.Helper()
+
+	messages := recorder.Messages()
+
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].To != "Test User <"+email+">" {
+			continue
+		}
+
+		claimLinkMatch := claimLinkPattern.FindStringSubmatch(messages[i].Text)
+
+		if claimLinkMatch == nil {
+			t.Fatalf("Offer email: expected a claim link, got: %s", messages[i].Text)
+		}
+
+		claimToken, unescapeError := url.QueryUnescape(claimLinkMatch[1])
+
+		if unescapeError != nil {
+			t.Fatalf("Offer email: invalid claim link token %q: %v", claimLinkMatch[1], unescapeError)
+		}
+
+		return claimToken
+	}
+
+	t.Fatalf("Expected an offer email to %s, got %d other email/s", email, len(messages))
+
+	return ""
+}
+
+func TestJoin(t *testing.T) {
+	testCases := []struct {
+		name             string
+		ticketsRemaining int32
+		quantity         int32
+		setup            func(mockQueries *MockWaitlistQueries, eventDetailID, userID uuid.UUID)
+		expectedError    error
+		expectedPosition int64
+	}{
+		{
+			name:             "SoldOut_FirstInLine",
+			ticketsRemaining: 0,
+			quantity:         2,
+			expectedPosition: 1,
+		},
+		{
+			name:             "SoldOut_BehindOthers",
+			ticketsRemaining: 0,
+			setup: func(mockQueries *MockWaitlistQueries, eventDetailID, userID uuid.UUID) {
+				mockQueries.CreateWaitlistEntry(context.Background(), database.CreateWaitlistEntryParams{ID: uuid.New(), Quantity: 1, EventDetailID: eventDetailID, UserID: uuid.New()})
+			},
+			expectedPosition: 2,
+		},
+		{
+			// Once people queue up, tickets that come back are theirs first.
+			name:             "TicketsRemainingWhileOthersWait",
+			ticketsRemaining: 3,
+			setup: func(mockQueries *MockWaitlistQueries, eventDetailID, userID uuid.UUID) {
+				mockQueries.CreateWaitlistEntry(context.Background(), database.CreateWaitlistEntryParams{ID: uuid.New(), Quantity: 4, EventDetailID: eventDetailID, UserID: uuid.New()})
+			},
+			expectedPosition: 2,
+		},
+		{
+			name:             "TicketsAvailable",
+			ticketsRemaining: 3,
+			expectedError:    waitlist.ErrTicketsAvailable,
+		},
+		{
+			name:             "AlreadyOnWaitlist",
+			ticketsRemaining: 0,
+			setup: func(mockQueries *MockWaitlistQueries, eventDetailID, userID uuid.UUID) {
+				mockQueries.CreateWaitlistEntry(context.Background(), database.CreateWaitlistEntryParams{ID: uuid.New(), Quantity: 1, EventDetailID: eventDetailID, UserID: userID})
+			},
+			expectedError: waitlist.ErrAlreadyOnWaitlist,
+		},
+		{
+			name:             "QuantityTooHigh",
+			ticketsRemaining: 0,
+			quantity:         11,
+			expectedError:    waitlist.ErrInvalidQuantity,
+		},
+		{
+			name:             "EventNotPublished",
+			ticketsRemaining: 0,
+			setup: func(mockQueries *MockWaitlistQueries, eventDetailID, userID uuid.UUID) {
+				mockQueries.eventDetails[eventDetailID].EventStatus = "draft"
+			},
+			expectedError: waitlist.ErrEventNotWaitlistable,
+		},
+		{
+			name:             "ShowPassed",
+			ticketsRemaining: 0,
+			setup: func(mockQueries *MockWaitlistQueries, eventDetailID, userID uuid.UUID) {
+				mockQueries.eventDetails[eventDetailID].ShowDate = time.Now().Add(-time.Hour)
+			},
+			expectedError: waitlist.ErrEventNotWaitlistable,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockQueries := NewMockWaitlistQueries()
+			eventDetailID := mockQueries.addEventDetail(testCase.ticketsRemaining)
+			userID := mockQueries.addUser("jane@example.com")
+
+			if testCase.setup != nil {
+				testCase.setup(mockQueries, eventDetailID, userID)
+			}
+
+			service, _ := newTestService(mockQueries)
+
+			waitlistEntry, err := service.Join(context.Background(), eventDetailID, userID, waitlist.JoinWaitlistParameters{Quantity: testCase.quantity})
+
+			if testCase.expectedError != nil {
+				if !errors.Is(err, testCase.expectedError) {
+					t.Fatalf("Join: expected %v, got %v", testCase.expectedError, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Join: expected no error, got: %v", err)
+			}
+
+			expectedQuantity := max(testCase.quantity, 1)
+
+			if waitlistEntry.Status != "waiting" || waitlistEntry.Quantity != expectedQuantity || waitlistEntry.Position != testCase.expectedPosition {
+				t.Errorf("Join: expected %d ticket/s waiting at position %d, got %+v", expectedQuantity, testCase.expectedPosition, waitlistEntry)
+			}
+		})
+	}
+}
+
+func TestLeaveOfferedEntryOffersNextPerson(t *testing.T) {
+	mockQueries := NewMockWaitlistQueries()
+	eventDetailID := mockQueries.addEventDetail(0)
+	firstUserID := mockQueries.addUser("first@example.com")
+	nextUserID := mockQueries.addUser("next@example.com")
+	service, recorder := newTestService(mockQueries)
+
+	for _, userID := range []uuid.UUID{firstUserID, nextUserID} {
+		if _, err := service.Join(context.Background(), eventDetailID, userID, waitlist.JoinWaitlistParameters{Quantity: 2}); err != nil {
+			t.Fatalf("Join: expected no error, got: %v", err)
+		}
+	}
+
+	// A refund puts two tickets back, they are held for the first person in line.
+	mockQueries.eventDetails[eventDetailID].TicketsRemaining = 2
+	service.OfferReleasedTickets(context.Background(), []uuid.UUID{eventDetailID})
+
+	if entry := mockQueries.entryOf(eventDetailID, firstUserID); entry.Status != "offered" {
+		t.Fatalf("OfferReleasedTickets: expected the first person's entry to be offered, got %q", entry.Status)
+	}
+
+	offeredClaimToken(t, recorder, "first@example.com")
+
+	if err := service.Leave(context.Background(), eventDetailID, firstUserID); err != nil {
+		t.Fatalf("Leave: expected no error, got: %v", err)
+	}
+
+	if entry := mockQueries.entryOf(eventDetailID, firstUserID); entry.Status != "cancelled" {
+		t.Errorf("Leave: expected the declined entry to be cancelled, got %q", entry.Status)
+	}
+
+	if entry := mockQueries.entryOf(eventDetailID, nextUserID); entry.Status != "offered" {
+		t.Fatalf("Leave: expected the declined tickets to be offered to the next person, got %q", entry.Status)
+	}
+
+	if ticketsRemaining := mockQueries.eventDetails[eventDetailID].TicketsRemaining; ticketsRemaining != 0 {
+		t.Errorf("Leave: expected the tickets to stay held for the next person, got %d remaining", ticketsRemaining)
+	}
+
+	offeredClaimToken(t, recorder, "next@example.com")
+
+	if err := service.Leave(context.Background(), eventDetailID, firstUserID); !errors.Is(err, waitlist.ErrNotOnWaitlist) {
+		t.Errorf("Leave: expected ErrNotOnWaitlist after leaving, got: %v", err)
+	}
+}
+
+func TestExpireOffersOffersNextPerson(t *testing.T) {
+	mockQueries := NewMockWaitlistQueries()
+	eventDetailID := mockQueries.addEventDetail(0)
+	firstUserID := mockQueries.addUser("first@example.com")
+	nextUserID := mockQueries.addUser("next@example.com")
+	service, recorder := newTestService(mockQueries)
+
+	for _, userID := range []uuid.UUID{firstUserID, nextUserID} {
+		if _, err := service.Join(context.Background(), eventDetailID, userID, waitlist.JoinWaitlistParameters{}); err != nil {
+			t.Fatalf("Join: expected no error, got: %v", err)
+		}
+	}
+
+	mockQueries.eventDetails[eventDetailID].TicketsRemaining = 1
+	service.OfferReleasedTickets(context.Background(), []uuid.UUID{eventDetailID})
+
+	firstClaimToken := offeredClaimToken(t, recorder, "first@example.com")
+
+	// Nothing expires while the offer is still open.
+	if expiredCount, err := service.ExpireOffers(context.Background(), time.Now(), 50); err != nil || expiredCount != 0 {
+		t.Fatalf("ExpireOffers: expected no offer expired yet, got %d, %v", expiredCount, err)
+	}
+
+	expiredCount, err := service.ExpireOffers(context.Background(), time.Now().Add(testOfferTTL+time.Minute), 50)
+
+	if err != nil || expiredCount != 1 {
+		t.Fatalf("ExpireOffers: expected 1 offer expired, got %d, %v", expiredCount, err)
+	}
+
+	if entry := mockQueries.entryOf(eventDetailID, firstUserID); entry.Status != "expired" {
+		t.Errorf("ExpireOffers: expected the unclaimed entry to be expired, got %q", entry.Status)
+	}
+
+	if entry := mockQueries.entryOf(eventDetailID, nextUserID); entry.Status != "offered" {
+		t.Fatalf("ExpireOffers: expected the hold to roll to the next person, got %q", entry.Status)
+	}
+
+	if _, err := service.GetOffer(context.Background(), firstClaimToken); !errors.Is(err, waitlist.ErrInvalidClaimToken) {
+		t.Errorf("GetOffer: expected the expired claim link to stop working, got: %v", err)
+	}
+
+	nextClaimToken := offeredClaimToken(t, recorder, "next@example.com")
+
+	// The claim link works without logging in, booking the tickets with it needs the account they were offered to.
+	waitlistOffer, err := service.GetOffer(context.Background(), nextClaimToken)
+
+	if err != nil || waitlistOffer.EventDetailID != eventDetailID || waitlistOffer.Quantity != 1 {
+		t.Fatalf("GetOffer: expected the offer of 1 ticket, got %+v, %v", waitlistOffer, err)
+	}
+
+	if _, err := service.GetClaimableOffer(context.Background(), nextClaimToken, firstUserID); !errors.Is(err, waitlist.ErrInvalidClaimToken) {
+		t.Errorf("GetClaimableOffer: expected another user's claim token to be rejected, got: %v", err)
+	}
+
+	if _, err := service.GetClaimableOffer(context.Background(), nextClaimToken, nextUserID); err != nil {
+		t.Errorf("GetClaimableOffer: expected the offer to be claimable by its user, got: %v", err)
+	}
+}
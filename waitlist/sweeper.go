@@ -0,0 +1,42 @@
+package waitlist
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/elorenzorodz/event-mrs/internal/clock"
+)
+
+func NewExpiredOfferSweeper(waitlistService *Service, sweeperClock clock.Clock, interval time.Duration, batchSize int32) *ExpiredOfferSweeper {
+	return &ExpiredOfferSweeper{
+		Waitlist:  waitlistService,
+		Clock:     sweeperClock,
+		Interval:  interval,
+		BatchSize: batchSize,
+	}
+}
+
+// Start expires unclaimed offers every Interval until the context is cancelled.
+func (sweeper *ExpiredOfferSweeper) Start(ctx context.Context) {
+	log.Printf("Waitlist offer sweeper started, interval: %s, batch size: %d", sweeper.Interval, sweeper.BatchSize)
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("Waitlist offer sweeper stopped: %v", ctx.Err())
+
+			return
+		case <-sweeper.Clock.After(sweeper.Interval):
+			rolledCount, sweepError := sweeper.Waitlist.ExpireOffers(ctx, sweeper.Clock.Now(), sweeper.BatchSize)
+
+			if sweepError != nil {
+				log.Printf("Waitlist offer sweeper error: %v", sweepError)
+			}
+
+			if rolledCount > 0 {
+				log.Printf("Waitlist offer sweeper released expired offers for %d ticket type/s", rolledCount)
+			}
+		}
+	}
+}
\ No newline at end of file